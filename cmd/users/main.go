@@ -2,21 +2,42 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/site-geav-api/internal/features"
+	"github.com/site-geav-api/internal/geocoding"
 	"github.com/site-geav-api/internal/handlers"
 	"github.com/site-geav-api/internal/logger"
+	"github.com/site-geav-api/internal/metrics"
+	"github.com/site-geav-api/internal/models"
 	"github.com/site-geav-api/internal/repository"
 )
 
+// logTable is the table the API logs are written to and read from
+const logTable = "api_logs"
+
 var (
-	userHandler   *handlers.UserHandler
-	cancaoHandler *handlers.CancaoHandler
-	lugarHandler  *handlers.LugarHandler
-	log           logger.Logger
+	userHandler    *handlers.UserHandler
+	cancaoHandler  *handlers.CancaoHandler
+	lugarHandler   *handlers.LugarHandler
+	tagHandler     *handlers.TagHandler
+	ramoHandler    *handlers.RamoHandler
+	logHandler     *handlers.LogHandler
+	metricsHandler *handlers.MetricsHandler
+	log            logger.Logger
+	dbChecker      *repository.ConnectionChecker
+
+	// requestMetrics counts the requests of this execution environment for GET /metrics
+	requestMetrics = metrics.NewRegistry()
+
+	// retryDetector spots write requests repeated within DUPLICATE_REQUEST_WINDOW
+	retryDetector = handlers.NewRetryDetector()
 )
 
 func init() {
@@ -30,26 +51,94 @@ func init() {
 	cloudWatchLogger := logger.NewCloudWatchLogger(cwClient, "site-geav-api", "SiteGeav/API")
 
 	// Initialize database connection
-	db, err := repository.InitDB()
+	db, err := repository.InitDB(context.Background())
 	if err != nil {
 		panic(err)
 	}
 
+	// Refuse to run against an outdated schema
+	if err := repository.CheckSchemaVersion(context.Background(), db, repository.SchemaVersion); err != nil {
+		panic(err)
+	}
+
+	// Validate reused connections after the container sat idle
+	dbChecker = repository.NewConnectionChecker(db)
+
 	// Create database logger
-	dbLogger := logger.NewDBLogger(db, "site-geav-api", "api_logs")
+	dbLogger := logger.NewDBLogger(db, "site-geav-api", logTable)
 
-	// Create composite logger
-	log = logger.NewCompositeLogger(cloudWatchLogger, dbLogger)
+	// Create composite logger, shipping logs to an external collector when configured
+	loggers := []logger.Logger{cloudWatchLogger, dbLogger}
+	if endpoint := os.Getenv("HTTP_LOG_ENDPOINT"); endpoint != "" {
+		httpLogger := logger.NewHTTPLogger("site-geav-api", logger.HTTPLoggerConfig{
+			Endpoint:     endpoint,
+			APIKey:       os.Getenv("HTTP_LOG_API_KEY"),
+			APIKeyHeader: os.Getenv("HTTP_LOG_API_KEY_HEADER"),
+		})
+		loggers = append(loggers, httpLogger)
+	}
+	log = logger.NewSampledLogger(logger.NewCompositeLogger(loggers...))
 
 	// Create repositories
 	userRepo := repository.NewPostgresUserRepository(db)
 	cancaoRepo := repository.NewPostgresCancaoRepository(db)
 	lugarRepo := repository.NewPostgresLugarRepository(db)
+	tagLugarRepo := repository.NewPostgresTagLugarRepository(db)
+	tagCancaoRepo := repository.NewPostgresTagCancaoRepository(db)
+	ramoRepo := repository.NewPostgresRamoRepository(db)
+	logRepo := repository.NewPostgresLogRepository(db, logTable)
+	auditRepo := repository.NewPostgresAuditRepository(db)
+
+	// Create the first admin on a fresh deployment
+	if err := bootstrapAdmin(context.Background(), userRepo); err != nil {
+		log.Error(context.Background(), "Error bootstrapping admin user", err, map[string]interface{}{
+			"action":   "BootstrapAdmin",
+			"resource": "users",
+		})
+	}
 
 	// Create handlers
-	userHandler = handlers.NewUserHandler(userRepo, log)
+	userHandler = handlers.NewUserHandler(userRepo, lugarRepo, cancaoRepo, log)
 	cancaoHandler = handlers.NewCancaoHandler(cancaoRepo, log)
-	lugarHandler = handlers.NewLugarHandler(lugarRepo, log)
+	lugarHandler = handlers.NewLugarHandler(lugarRepo, ramoRepo, auditRepo, geocoding.NewNominatimGeocoder(), log)
+	tagHandler = handlers.NewTagHandler(tagLugarRepo, tagCancaoRepo, log)
+	ramoHandler = handlers.NewRamoHandler(ramoRepo, log)
+	logHandler = handlers.NewLogHandler(logRepo, log)
+	metricsHandler = handlers.NewMetricsHandler(requestMetrics, log)
+}
+
+// bootstrapAdmin creates a write user from BOOTSTRAP_ADMIN_USER and
+// BOOTSTRAP_ADMIN_PASSWORD when the database has no users yet. It does
+// nothing when the variables are not set or a user already exists.
+func bootstrapAdmin(ctx context.Context, userRepo repository.UserRepository) error {
+	username := os.Getenv("BOOTSTRAP_ADMIN_USER")
+	password := os.Getenv("BOOTSTRAP_ADMIN_PASSWORD")
+	if username == "" || password == "" {
+		return nil
+	}
+
+	// Hash the password
+	hash, err := models.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("error hashing bootstrap admin password: %w", err)
+	}
+
+	// Create the admin only if there are no users
+	admin := models.NewUser(username, hash, models.RoleWrite)
+	created, err := userRepo.CreateIfNoUsers(ctx, admin)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		log.Info(ctx, "Bootstrap admin user created", map[string]interface{}{
+			"action":      "BootstrapAdmin",
+			"resource":    "users",
+			"resource_id": fmt.Sprintf("%d", admin.ID),
+		})
+	}
+
+	return nil
 }
 
 func createCloudWatchClient() (*cloudwatch.Client, error) {
@@ -64,123 +153,377 @@ func createCloudWatchClient() (*cloudwatch.Client, error) {
 }
 
 func router(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Route /lugares/ like /lugares
+	request.Resource = handlers.NormalizeResource(request.Resource)
+	request.Path = handlers.NormalizeResource(request.Path)
+
 	// Add request ID to context
 	if requestID, ok := request.Headers["x-request-id"]; ok {
 		ctx = context.WithValue(ctx, "requestID", requestID)
 	}
 
+	// Add authenticated user to context
+	ctx = handlers.WithAuthenticatedUser(ctx, request)
+
+	// Warn about repeated writes, which are usually client retries or double submissions
+	retryDetector.LogRetries(ctx, log, request, time.Now())
+
+	// Sample the success logs of reads (see LOG_SAMPLE_RATE)
+	if request.HTTPMethod == "GET" || request.HTTPMethod == "HEAD" {
+		ctx = logger.WithSampling(ctx)
+	}
+
+	// HEAD requests run the GET logic and drop the body
+	if request.HTTPMethod == "HEAD" {
+		return headRouter(ctx, request)
+	}
+
+	// Hide routes whose feature is disabled
+	if !features.RouteEnabled(request.Resource) {
+		return notFoundResponse(), nil
+	}
+
+	// Reject request bodies that are not JSON
+	if response, ok := handlers.RequireJSONContentType(request); !ok {
+		return response, nil
+	}
+
+	// Return 404 if no route matches, whatever the query parameters
+	handle := route(request)
+	if handle == nil {
+		return notFoundResponse(), nil
+	}
+
+	// Convert the timestamps of the response to the requested time zone (?tz=)
+	loc, response, ok := handlers.ParseTimezone(request)
+	if !ok {
+		return response, nil
+	}
+
+	response, err := handle(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	if loc != nil {
+		response = handlers.ConvertTimestamps(response, loc)
+	}
+
+	// Compare If-None-Match with the ETag of the body actually sent
+	return handlers.NotModified(request, response), nil
+}
+
+// handlerFunc is a handler method, such as lugarHandler.GetLugar
+type handlerFunc func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// route returns the handler of the request based on the HTTP method and path,
+// or nil when no route matches
+func route(request events.APIGatewayProxyRequest) handlerFunc {
 	// Route request based on HTTP method and path
 	switch request.HTTPMethod {
 	case "GET":
+		// Metrics
+		if request.Resource == "/metrics" {
+			return metricsHandler.GetMetrics
+		}
+
+		// Auth routes
+		if request.Resource == "/auth/permissions" {
+			return userHandler.GetPermissions
+		}
+
 		// User routes
 		if request.Resource == "/users" {
-			return userHandler.ListUsers(ctx, request)
+			return userHandler.ListUsers
 		} else if request.Resource == "/users/{id}" {
-			return userHandler.GetUser(ctx, request)
+			return userHandler.GetUser
+		} else if request.Resource == "/users/{id}/content" {
+			return userHandler.GetUserContent
 		}
 
 		// Cancao routes
 		if request.Resource == "/cancoes" {
-			return cancaoHandler.ListCancoes(ctx, request)
+			return cancaoHandler.ListCancoes
+		} else if request.Resource == "/cancoes/options" {
+			return cancaoHandler.ListCancaoOptions
+		} else if request.Resource == "/cancoes/export" {
+			return cancaoHandler.ExportCancoes
 		} else if request.Resource == "/cancoes/{id}" {
-			return cancaoHandler.GetCancao(ctx, request)
+			return cancaoHandler.GetCancao
+		} else if request.Resource == "/cancoes/{id}/related" {
+			return cancaoHandler.GetRelatedCancoes
 		}
 
 		// Lugar routes
 		if request.Resource == "/lugares" {
-			return lugarHandler.ListLugares(ctx, request)
+			return lugarHandler.ListLugares
+		} else if request.Resource == "/lugares/options" {
+			return lugarHandler.ListLugarOptions
+		} else if request.Resource == "/lugares/stats/daily" {
+			return lugarHandler.GetDailyLugarStats
+		} else if request.Resource == "/lugares/bbox" {
+			return lugarHandler.ListLugaresInBoundingBox
+		} else if request.Resource == "/lugares/near" {
+			return lugarHandler.ListLugaresNearCity
+		} else if request.Resource == "/lugares/duplicates" {
+			return lugarHandler.FindDuplicateLugares
 		} else if request.Resource == "/lugares/{id}" {
-			return lugarHandler.GetLugar(ctx, request)
+			return lugarHandler.GetLugar
 		} else if request.Resource == "/lugares/{id}/ratings" {
-			return lugarHandler.GetRatingsForLugar(ctx, request)
+			return lugarHandler.GetRatingsForLugar
+		} else if request.Resource == "/lugares/{id}/ratings/mine" {
+			return lugarHandler.GetMyRatingForLugar
+		} else if request.Resource == "/lugares/{id}/similar" {
+			return lugarHandler.GetSimilarLugares
+		} else if request.Resource == "/lugares/{id}/history" {
+			return lugarHandler.GetLugarHistory
+		} else if request.Resource == "/lugares/{id}/images/{imageId}" {
+			return lugarHandler.GetImageFromLugar
+		}
+
+		// Image routes
+		if request.Resource == "/images" {
+			return lugarHandler.ListAllImages
+		}
+
+		// Admin routes
+		if request.Resource == "/admin/logs" {
+			return logHandler.ListLogs
+		}
+
+		// Rating routes
+		if request.Resource == "/ratings" {
+			return lugarHandler.ListRatingsByValue
+		} else if request.Resource == "/ratings/distribution" {
+			return lugarHandler.GetRatingDistribution
+		} else if request.Resource == "/ratings/recent" {
+			return lugarHandler.GetRecentRatings
+		}
+
+		// Tag routes
+		if request.Resource == "/tags/lugares" {
+			return tagHandler.ListLugarTags
+		} else if request.Resource == "/tags/lugares/options" {
+			return tagHandler.ListLugarTagOptions
+		} else if request.Resource == "/tags/lugares/suggest" {
+			return tagHandler.SuggestLugarTags
+		} else if request.Resource == "/tags/lugares/{id}" {
+			return tagHandler.GetLugarTag
+		} else if request.Resource == "/tags/cancoes" {
+			return tagHandler.ListCancaoTags
+		} else if request.Resource == "/tags/cancoes/{id}" {
+			return tagHandler.GetCancaoTag
+		} else if request.Resource == "/lugares/{id}/tags/available" {
+			return tagHandler.ListAvailableLugarTags
+		}
+
+		// Ramo routes
+		if request.Resource == "/ramos" {
+			return ramoHandler.ListRamos
+		} else if request.Resource == "/ramos/options" {
+			return ramoHandler.ListRamoOptions
+		} else if request.Resource == "/ramos/coverage" {
+			return ramoHandler.GetRamoCoverage
+		} else if request.Resource == "/ramos/{id}" {
+			return ramoHandler.GetRamo
 		}
 
 	case "POST":
 		// User routes
 		if request.Resource == "/users" {
-			return userHandler.CreateUser(ctx, request)
+			return userHandler.CreateUser
+		} else if request.Resource == "/users/{id}/anonymize" {
+			return userHandler.AnonymizeUser
 		}
 
 		// Cancao routes
 		if request.Resource == "/cancoes" {
-			return cancaoHandler.CreateCancao(ctx, request)
+			return cancaoHandler.CreateCancao
+		} else if request.Resource == "/cancoes/{id}/play" {
+			return cancaoHandler.PlayCancao
 		} else if request.Resource == "/cancoes/{id}/tags" {
-			return cancaoHandler.AddTagToCancao(ctx, request)
+			return cancaoHandler.AddTagToCancao
 		} else if request.Resource == "/cancoes/{id}/ramos" {
-			return cancaoHandler.AddRamoToCancao(ctx, request)
+			return cancaoHandler.AddRamoToCancao
 		}
 
 		// Lugar routes
 		if request.Resource == "/lugares" {
-			return lugarHandler.CreateLugar(ctx, request)
+			return lugarHandler.CreateLugar
+		} else if request.Resource == "/lugares/ratings-summaries" {
+			return lugarHandler.GetRatingSummaries
+		} else if request.Resource == "/lugares/validate" {
+			return lugarHandler.ValidateLugar
+		} else if request.Resource == "/lugares/import" {
+			return lugarHandler.ImportLugares
+		} else if request.Resource == "/lugares/{id}/publish" {
+			return lugarHandler.PublishLugar
+		} else if request.Resource == "/lugares/{id}/touch" {
+			return lugarHandler.TouchLugar
 		} else if request.Resource == "/lugares/{id}/images" {
-			return lugarHandler.AddImageToLugar(ctx, request)
+			return lugarHandler.AddImageToLugar
 		} else if request.Resource == "/lugares/{id}/tags" {
-			return lugarHandler.AddTagToLugar(ctx, request)
+			return lugarHandler.AddTagToLugar
 		} else if request.Resource == "/lugares/{id}/ramos" {
-			return lugarHandler.AddRamoToLugar(ctx, request)
+			return lugarHandler.AddRamoToLugar
 		} else if request.Resource == "/lugares/{id}/ratings" {
-			return lugarHandler.AddRatingToLugar(ctx, request)
+			return lugarHandler.AddRatingToLugar
+		}
+
+		// Tag routes
+		if request.Resource == "/tags/lugares" {
+			return tagHandler.CreateLugarTag
+		} else if request.Resource == "/tags/cancoes" {
+			return tagHandler.CreateCancaoTag
+		}
+
+		// Ramo routes
+		if request.Resource == "/ramos" {
+			return ramoHandler.CreateRamo
 		}
 
 	case "PUT":
 		// User routes
 		if request.Resource == "/users/{id}" {
-			return userHandler.UpdateUser(ctx, request)
+			return userHandler.UpdateUser
 		}
 
 		// Cancao routes
 		if request.Resource == "/cancoes/{id}" {
-			return cancaoHandler.UpdateCancao(ctx, request)
+			return cancaoHandler.UpdateCancao
 		}
 
 		// Lugar routes
 		if request.Resource == "/lugares/{id}" {
-			return lugarHandler.UpdateLugar(ctx, request)
+			return lugarHandler.UpdateLugar
+		} else if request.Resource == "/lugares/{id}/owner" {
+			return lugarHandler.ChangeLugarOwner
 		} else if request.Resource == "/lugares/{id}/ratings/{ratingId}" {
-			return lugarHandler.UpdateRatingForLugar(ctx, request)
+			return lugarHandler.UpdateRatingForLugar
+		}
+
+		// Tag routes
+		if request.Resource == "/tags/lugares/{id}" {
+			return tagHandler.UpdateLugarTag
+		} else if request.Resource == "/tags/cancoes/{id}" {
+			return tagHandler.UpdateCancaoTag
+		}
+
+		// Ramo routes
+		if request.Resource == "/ramos/{id}" {
+			return ramoHandler.UpdateRamo
 		}
 
 	case "DELETE":
 		// User routes
 		if request.Resource == "/users/{id}" {
-			return userHandler.DeleteUser(ctx, request)
+			return userHandler.DeleteUser
 		}
 
 		// Cancao routes
 		if request.Resource == "/cancoes/{id}" {
-			return cancaoHandler.DeleteCancao(ctx, request)
+			return cancaoHandler.DeleteCancao
 		} else if request.Resource == "/cancoes/{id}/tags/{tagId}" {
-			return cancaoHandler.RemoveTagFromCancao(ctx, request)
+			return cancaoHandler.RemoveTagFromCancao
 		} else if request.Resource == "/cancoes/{id}/ramos/{ramoId}" {
-			return cancaoHandler.RemoveRamoFromCancao(ctx, request)
+			return cancaoHandler.RemoveRamoFromCancao
 		}
 
 		// Lugar routes
 		if request.Resource == "/lugares/{id}" {
-			return lugarHandler.DeleteLugar(ctx, request)
+			return lugarHandler.DeleteLugar
 		} else if request.Resource == "/lugares/{id}/images/{imageId}" {
-			return lugarHandler.DeleteImageFromLugar(ctx, request)
+			return lugarHandler.DeleteImageFromLugar
+		} else if request.Resource == "/lugares/{id}/tags" {
+			return lugarHandler.RemoveTagsFromLugar
 		} else if request.Resource == "/lugares/{id}/tags/{tagId}" {
-			return lugarHandler.RemoveTagFromLugar(ctx, request)
+			return lugarHandler.RemoveTagFromLugar
 		} else if request.Resource == "/lugares/{id}/ramos/{ramoId}" {
-			return lugarHandler.RemoveRamoFromLugar(ctx, request)
+			return lugarHandler.RemoveRamoFromLugar
+		} else if request.Resource == "/lugares/{id}/ratings" {
+			return lugarHandler.DeleteUserRatingFromLugar
 		} else if request.Resource == "/lugares/{id}/ratings/{ratingId}" {
-			return lugarHandler.DeleteRatingFromLugar(ctx, request)
+			return lugarHandler.DeleteRatingFromLugar
+		}
+
+		// Tag routes
+		if request.Resource == "/tags/lugares/{id}" {
+			return tagHandler.DeleteLugarTag
+		} else if request.Resource == "/tags/cancoes/{id}" {
+			return tagHandler.DeleteCancaoTag
+		}
+
+		// Ramo routes
+		if request.Resource == "/ramos/{id}" {
+			return ramoHandler.DeleteRamo
+		}
+
+		// Admin routes
+		if request.Resource == "/admin/logs" {
+			return logHandler.PurgeLogs
 		}
 	}
 
-	// Return 404 if no route matches
+	// No route matches
+	return nil
+}
+
+// notFoundResponse creates the response for requests that match no route
+func notFoundResponse() events.APIGatewayProxyResponse {
 	return events.APIGatewayProxyResponse{
 		StatusCode: 404,
 		Headers: map[string]string{
 			"Content-Type": "application/json",
 		},
-		Body: `{"error":"Not Found"}`,
-	}, nil
+		Body: `{"code":"NOT_FOUND","error":"Not Found"}`,
+	}
+}
+
+// headRouter answers a HEAD request with the status and headers of the matching GET request
+func headRouter(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	request.HTTPMethod = "GET"
+	response, err := router(ctx, request)
+	if err != nil {
+		return response, err
+	}
+
+	return handlers.HeadResponse(response), nil
+}
+
+// handleRequest routes the request, adds the X-Response-Time-Ms header and
+// sends the buffered log entries before the invocation ends
+func handleRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	start := time.Now()
+
+	// Send the buffered log entries before the invocation ends, even if the handler panics
+	defer log.Flush(ctx)
+
+	// A failed check is only logged: the request still gets its own error if the database is down
+	if err := dbChecker.Check(ctx); err != nil {
+		log.Warn(ctx, "Database connection check failed", map[string]interface{}{
+			"action": "CheckConnection",
+			"error":  err.Error(),
+		})
+	}
+
+	response, err := router(ctx, request)
+	duration := time.Since(start)
+	response = handlers.WithResponseTime(response, duration)
+
+	// Count the request under its route template; a failed request counts as a 500
+	route := handlers.NormalizeResource(request.Resource)
+	if route == "" {
+		route = "unmatched"
+	}
+	status := response.StatusCode
+	if err != nil {
+		status = 500
+	}
+	requestMetrics.ObserveRequest(request.HTTPMethod, route, status, duration)
+
+	return response, err
 }
 
 func main() {
 	// Start Lambda handler
-	lambda.Start(router)
+	lambda.Start(handleRequest)
 }
@@ -7,7 +7,9 @@ require (
 	github.com/aws/aws-sdk-go-v2 v1.25.3
 	github.com/aws/aws-sdk-go-v2/config v1.27.7
 	github.com/aws/aws-sdk-go-v2/service/cloudwatch v1.32.2
+	github.com/aws/aws-sdk-go-v2/service/secretsmanager v1.28.2
 	github.com/lib/pq v1.10.9
+	golang.org/x/crypto v0.21.0
 )
 
 require (
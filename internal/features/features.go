@@ -0,0 +1,44 @@
+package features
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// offByDefault holds the features that stay off until they are turned on
+// explicitly: those that expose internal data or call external services
+var offByDefault = map[string]bool{
+	"geocoding": true,
+	"metrics":   true,
+}
+
+// routes maps the routes that can be turned off to their feature. A disabled
+// route answers 404.
+var routes = map[string]string{
+	"/lugares/bbox":         "bbox",
+	"/lugares/duplicates":   "duplicates",
+	"/lugares/near":         "geocoding",
+	"/ratings/distribution": "rating_distribution",
+	"/cancoes/{id}/play":    "play_count",
+	"/metrics":              "metrics",
+}
+
+// Enabled checks if a feature is enabled. Features are read from the
+// FEATURE_<NAME> environment variable (e.g. FEATURE_BBOX=false). When the
+// variable is not set or not a boolean, features are enabled except for the
+// ones in offByDefault.
+func Enabled(name string) bool {
+	enabled, err := strconv.ParseBool(os.Getenv("FEATURE_" + strings.ToUpper(name)))
+	if err != nil {
+		return !offByDefault[name]
+	}
+	return enabled
+}
+
+// RouteEnabled reports whether a route (an API Gateway resource such as
+// /lugares/bbox) can be reached: routes without a feature always can
+func RouteEnabled(resource string) bool {
+	feature, ok := routes[resource]
+	return !ok || Enabled(feature)
+}
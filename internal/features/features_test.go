@@ -0,0 +1,59 @@
+package features
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		feature string
+		env     string
+		want    bool
+	}{
+		{"on by default", "bbox", "", true},
+		{"turned off", "bbox", "false", false},
+		{"turned off with 0", "duplicates", "0", false},
+		{"invalid value keeps the default", "bbox", "maybe", true},
+		{"external service is off by default", "geocoding", "", false},
+		{"external service turned on", "geocoding", "true", true},
+		{"metrics are off by default", "metrics", "", false},
+		{"invalid value keeps a feature off", "metrics", "yes please", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FEATURE_"+strings.ToUpper(tt.feature), tt.env)
+			if got := Enabled(tt.feature); got != tt.want {
+				t.Errorf("Enabled(%q) with %q = %v, want %v", tt.feature, tt.env, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		env      map[string]string
+		want     bool
+	}{
+		{name: "route without a feature", resource: "/lugares/{id}", env: map[string]string{"FEATURE_BBOX": "false"}, want: true},
+		{name: "enabled feature", resource: "/lugares/bbox", want: true},
+		{name: "disabled feature", resource: "/lugares/bbox", env: map[string]string{"FEATURE_BBOX": "false"}, want: false},
+		{name: "off-by-default feature", resource: "/lugares/near", want: false},
+		{name: "off-by-default feature turned on", resource: "/metrics", env: map[string]string{"FEATURE_METRICS": "true"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if got := RouteEnabled(tt.resource); got != tt.want {
+				t.Errorf("RouteEnabled(%q) = %v, want %v", tt.resource, got, tt.want)
+			}
+		})
+	}
+}
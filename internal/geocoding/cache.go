@@ -0,0 +1,86 @@
+package geocoding
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// locationCache is a bounded LRU cache of geocoding results. Entries expire
+// after ttl, or after missTTL for names that were not found, so a city added
+// to the service later is not reported missing for long.
+type locationCache struct {
+	size    int
+	ttl     time.Duration
+	missTTL time.Duration
+
+	mu      sync.Mutex
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+}
+
+// cacheEntry is the value of the elements of locationCache.order
+type cacheEntry struct {
+	key      string
+	location *Location
+	expires  time.Time
+}
+
+// newLocationCache creates a cache holding at most size entries
+func newLocationCache(size int, ttl, missTTL time.Duration) *locationCache {
+	return &locationCache{
+		size:    size,
+		ttl:     ttl,
+		missTTL: missTTL,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// get returns the cached location of key, which is nil for a name that was
+// not found, and whether an unexpired entry was found
+func (c *locationCache) get(key string, now time.Time) (*Location, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !now.Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.location, true
+}
+
+// put caches the location of key, evicting the least recently used entry
+// when the cache is full
+func (c *locationCache) put(key string, location *Location, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ttl := c.ttl
+	if location == nil {
+		ttl = c.missTTL
+	}
+	entry := &cacheEntry{key: key, location: location, expires: now.Add(ttl)}
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
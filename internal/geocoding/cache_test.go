@@ -0,0 +1,98 @@
+package geocoding
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocationCache(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	curitiba := &Location{Name: "Curitiba", Latitude: -25.43, Longitude: -49.27}
+
+	tests := []struct {
+		name    string
+		fill    func(c *locationCache)
+		key     string
+		at      time.Duration
+		want    *Location
+		wantHit bool
+	}{
+		{
+			name:    "found location within its TTL",
+			fill:    func(c *locationCache) { c.put("curitiba", curitiba, start) },
+			key:     "curitiba",
+			at:      23 * time.Hour,
+			want:    curitiba,
+			wantHit: true,
+		},
+		{
+			name: "found location after its TTL",
+			fill: func(c *locationCache) { c.put("curitiba", curitiba, start) },
+			key:  "curitiba",
+			at:   24 * time.Hour,
+		},
+		{
+			name:    "miss within the miss TTL",
+			fill:    func(c *locationCache) { c.put("atlantida", nil, start) },
+			key:     "atlantida",
+			at:      59 * time.Minute,
+			wantHit: true,
+		},
+		{
+			name: "miss after the miss TTL",
+			fill: func(c *locationCache) { c.put("atlantida", nil, start) },
+			key:  "atlantida",
+			at:   time.Hour,
+		},
+		{
+			name: "least recently used entry is evicted",
+			fill: func(c *locationCache) {
+				c.put("curitiba", curitiba, start)
+				c.put("londrina", &Location{Name: "Londrina"}, start)
+				c.put("maringa", &Location{Name: "Maringá"}, start)
+			},
+			key: "curitiba",
+		},
+		{
+			name: "a read keeps an entry from being evicted",
+			fill: func(c *locationCache) {
+				c.put("curitiba", curitiba, start)
+				c.put("londrina", &Location{Name: "Londrina"}, start)
+				c.get("curitiba", start)
+				c.put("maringa", &Location{Name: "Maringá"}, start)
+			},
+			key:     "curitiba",
+			want:    curitiba,
+			wantHit: true,
+		},
+		{
+			name: "overwriting an entry does not evict another",
+			fill: func(c *locationCache) {
+				c.put("curitiba", curitiba, start)
+				c.put("londrina", nil, start)
+				c.put("londrina", &Location{Name: "Londrina"}, start)
+			},
+			key:     "curitiba",
+			want:    curitiba,
+			wantHit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newLocationCache(2, 24*time.Hour, time.Hour)
+			tt.fill(c)
+
+			got, hit := c.get(tt.key, start.Add(tt.at))
+			if hit != tt.wantHit {
+				t.Fatalf("hit = %v, want %v", hit, tt.wantHit)
+			}
+			if got != tt.want {
+				t.Errorf("location = %+v, want %+v", got, tt.want)
+			}
+			if c.order.Len() != len(c.entries) || len(c.entries) > 2 {
+				t.Errorf("cache holds %d entries in its order and %d in its map, want at most 2 in both", c.order.Len(), len(c.entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package geocoding
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultNominatimURL is the geocoding service used when GEOCODER_URL is not set
+const defaultNominatimURL = "https://nominatim.openstreetmap.org"
+
+const (
+	// cacheSize is the number of names whose results are cached
+	cacheSize = 1000
+	// cacheTTL is how long a found location is cached
+	cacheTTL = 24 * time.Hour
+	// missTTL is how long a name that was not found is cached
+	missTTL = time.Hour
+	// requestInterval is the shortest time between two requests to the
+	// service; the public Nominatim allows at most one request per second
+	requestInterval = time.Second
+)
+
+// Location is the result of geocoding a place name
+type Location struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Geocoder finds the coordinates of a place name, such as a city. It returns
+// a nil location when the name is not found.
+type Geocoder interface {
+	Geocode(ctx context.Context, query string) (*Location, error)
+}
+
+// NominatimGeocoder geocodes names in Brazil with a Nominatim (OpenStreetMap)
+// service. Results are cached in memory, as the same cities are asked for
+// again and again, and the requests that miss the cache are spaced out to
+// respect the rate limit of the public service.
+type NominatimGeocoder struct {
+	baseURL string
+	client  *http.Client
+	cache   *locationCache
+	now     func() time.Time
+
+	// mu serializes the waits for the next request slot
+	mu              sync.Mutex
+	requestInterval time.Duration
+	lastRequest     time.Time
+}
+
+// NewNominatimGeocoder creates a Nominatim geocoder. The service is read from
+// GEOCODER_URL (default https://nominatim.openstreetmap.org).
+func NewNominatimGeocoder() *NominatimGeocoder {
+	baseURL := os.Getenv("GEOCODER_URL")
+	if baseURL == "" {
+		baseURL = defaultNominatimURL
+	}
+
+	return &NominatimGeocoder{
+		baseURL:         strings.TrimRight(baseURL, "/"),
+		client:          &http.Client{Timeout: 5 * time.Second},
+		cache:           newLocationCache(cacheSize, cacheTTL, missTTL),
+		now:             time.Now,
+		requestInterval: requestInterval,
+	}
+}
+
+// waitForRequestSlot blocks until requestInterval has passed since the last
+// request to the service, or ctx is done
+func (g *NominatimGeocoder) waitForRequestSlot(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if wait := g.lastRequest.Add(g.requestInterval).Sub(g.now()); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	g.lastRequest = g.now()
+	return nil
+}
+
+// Geocode finds the coordinates of a name in Brazil
+func (g *NominatimGeocoder) Geocode(ctx context.Context, query string) (*Location, error) {
+	key := strings.ToLower(strings.TrimSpace(query))
+
+	if location, ok := g.cache.get(key, g.now()); ok {
+		return location, nil
+	}
+
+	if err := g.waitForRequestSlot(ctx); err != nil {
+		return nil, fmt.Errorf("error geocoding %q: %w", query, err)
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("countrycodes", "br")
+	params.Set("format", "json")
+	params.Set("limit", "1")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating geocoding request: %w", err)
+	}
+	// Nominatim requires an identifying User-Agent
+	req.Header.Set("User-Agent", "site-geav-api")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error geocoding %q: %w", query, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error geocoding %q: status %d", query, resp.StatusCode)
+	}
+
+	var results []struct {
+		DisplayName string `json:"display_name"`
+		Lat         string `json:"lat"`
+		Lon         string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("error decoding geocoding response: %w", err)
+	}
+
+	var location *Location
+	if len(results) > 0 {
+		latitude, err := strconv.ParseFloat(results[0].Lat, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing latitude: %w", err)
+		}
+		longitude, err := strconv.ParseFloat(results[0].Lon, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing longitude: %w", err)
+		}
+		location = &Location{
+			Name:      results[0].DisplayName,
+			Latitude:  latitude,
+			Longitude: longitude,
+		}
+	}
+
+	// Names that are not found are cached too, for a shorter time
+	g.cache.put(key, location, g.now())
+
+	return location, nil
+}
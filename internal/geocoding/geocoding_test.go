@@ -0,0 +1,126 @@
+package geocoding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestGeocoder creates a geocoder using a fake Nominatim service that knows
+// Curitiba only, counting the requests it receives
+func newTestGeocoder(t *testing.T, interval time.Duration) (*NominatimGeocoder, *int32) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Query().Get("q") == "Curitiba" {
+			fmt.Fprint(w, `[{"display_name": "Curitiba, Paraná, Brasil", "lat": "-25.4284", "lon": "-49.2733"}]`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("GEOCODER_URL", server.URL)
+	g := NewNominatimGeocoder()
+	g.requestInterval = interval
+	return g, &requests
+}
+
+func TestNominatimGeocoderCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		first        string
+		second       string
+		after        time.Duration
+		wantFound    bool
+		wantRequests int32
+	}{
+		{"found city is cached", "Curitiba", "curitiba ", time.Hour, true, 1},
+		{"found city expires", "Curitiba", "Curitiba", 25 * time.Hour, true, 2},
+		{"unknown city is cached", "Atlântida", "Atlântida", 30 * time.Minute, false, 1},
+		{"unknown city expires sooner", "Atlântida", "Atlântida", 2 * time.Hour, false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, requests := newTestGeocoder(t, 0)
+			now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+			g.now = func() time.Time { return now }
+
+			if _, err := g.Geocode(context.Background(), tt.first); err != nil {
+				t.Fatalf("first Geocode: %v", err)
+			}
+			now = now.Add(tt.after)
+			location, err := g.Geocode(context.Background(), tt.second)
+			if err != nil {
+				t.Fatalf("second Geocode: %v", err)
+			}
+
+			if (location != nil) != tt.wantFound {
+				t.Errorf("location = %+v, want found %v", location, tt.wantFound)
+			}
+			if location != nil && (location.Latitude != -25.4284 || location.Longitude != -49.2733) {
+				t.Errorf("location = %+v, want the coordinates of Curitiba", location)
+			}
+			if got := atomic.LoadInt32(requests); got != tt.wantRequests {
+				t.Errorf("service requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestNominatimGeocoderRateLimit(t *testing.T) {
+	const interval = 50 * time.Millisecond
+
+	tests := []struct {
+		name    string
+		queries []string
+		minTime time.Duration
+	}{
+		{"distinct names are spaced out", []string{"Curitiba", "Londrina", "Maringá"}, 2 * interval},
+		{"cached names are not", []string{"Curitiba", "Curitiba", "Curitiba"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, _ := newTestGeocoder(t, interval)
+
+			start := time.Now()
+			for _, query := range tt.queries {
+				if _, err := g.Geocode(context.Background(), query); err != nil {
+					t.Fatalf("Geocode(%q): %v", query, err)
+				}
+			}
+			elapsed := time.Since(start)
+
+			if elapsed < tt.minTime {
+				t.Errorf("took %v, want at least %v", elapsed, tt.minTime)
+			}
+			if tt.minTime == 0 && elapsed >= interval {
+				t.Errorf("took %v, want cached names answered without waiting", elapsed)
+			}
+		})
+	}
+}
+
+func TestNominatimGeocoderRateLimitCanceled(t *testing.T) {
+	g, requests := newTestGeocoder(t, time.Hour)
+	if _, err := g.Geocode(context.Background(), "Curitiba"); err != nil {
+		t.Fatalf("Geocode: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := g.Geocode(ctx, "Londrina"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context deadline while waiting for the next request", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("service requests = %d, want 1", got)
+	}
+}
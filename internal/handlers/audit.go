@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/site-geav-api/internal/logger"
+	"github.com/site-geav-api/internal/repository"
+)
+
+// withAudit returns a copy of ctx carrying the caller, the request ID and the
+// changes about to be made, which the repository records in the audit log
+// along with the change
+func withAudit(ctx context.Context, changes map[string]interface{}) context.Context {
+	info := repository.AuditInfo{
+		RequestID: logger.GetRequestIDFromContext(ctx),
+		Changes:   changes,
+	}
+	if user := currentUser(ctx); user != nil {
+		info.UserID = &user.ID
+	}
+	return repository.WithAudit(ctx, info)
+}
+
+// changedFields compares the JSON representation of two versions of a record
+// and returns the fields that differ as {"field": {"from": ..., "to": ...}}.
+// A nil before describes a creation. The ignored fields are left out.
+func changedFields(before, after interface{}, ignored ...string) map[string]interface{} {
+	beforeFields := jsonFields(before)
+	afterFields := jsonFields(after)
+
+	skip := make(map[string]bool, len(ignored))
+	for _, name := range ignored {
+		skip[name] = true
+	}
+
+	changes := make(map[string]interface{})
+	for name, to := range afterFields {
+		from := beforeFields[name]
+		if skip[name] || reflect.DeepEqual(from, to) {
+			continue
+		}
+		changes[name] = map[string]interface{}{"from": from, "to": to}
+	}
+	for name, from := range beforeFields {
+		if _, ok := afterFields[name]; !ok && !skip[name] {
+			changes[name] = map[string]interface{}{"from": from, "to": nil}
+		}
+	}
+
+	return changes
+}
+
+// jsonFields returns the fields of the JSON representation of a value
+func jsonFields(value interface{}) map[string]interface{} {
+	var fields map[string]interface{}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	// A nil value is encoded as null, which leaves the map empty
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+
+	return fields
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+func TestChangedFields(t *testing.T) {
+	before := &models.Lugar{ID: 1, NomeLocal: "Sítio", LocalPublico: true, UserID: 1}
+
+	tests := []struct {
+		name   string
+		before interface{}
+		after  interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name:   "no changes",
+			before: before,
+			after:  &models.Lugar{ID: 1, NomeLocal: "Sítio", LocalPublico: true, UserID: 1},
+			want:   map[string]interface{}{},
+		},
+		{
+			name:   "changed fields",
+			before: before,
+			after:  &models.Lugar{ID: 1, NomeLocal: "Sítio Novo", LocalPublico: false, UserID: 1},
+			want: map[string]interface{}{
+				"nome_local":    map[string]interface{}{"from": "Sítio", "to": "Sítio Novo"},
+				"local_publico": map[string]interface{}{"from": true, "to": false},
+			},
+		},
+		{
+			name:   "ignored fields are left out",
+			before: before,
+			after:  &models.Lugar{ID: 2, NomeLocal: "Sítio", LocalPublico: true, UserID: 2},
+			want: map[string]interface{}{
+				"user_id": map[string]interface{}{"from": float64(1), "to": float64(2)},
+			},
+		},
+		{
+			name:   "creation",
+			before: nil,
+			after:  map[string]interface{}{"nome_local": "Sítio"},
+			want: map[string]interface{}{
+				"nome_local": map[string]interface{}{"from": nil, "to": "Sítio"},
+			},
+		},
+		{
+			name:   "removed field",
+			before: map[string]interface{}{"nome_local": "Sítio", "link_site": "https://example.com"},
+			after:  map[string]interface{}{"nome_local": "Sítio"},
+			want: map[string]interface{}{
+				"link_site": map[string]interface{}{"from": "https://example.com", "to": nil},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changedFields(tt.before, tt.after, lugarUntrackedFields...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changedFields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
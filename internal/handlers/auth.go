@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/models"
+	"github.com/site-geav-api/internal/repository"
+)
+
+// WithAuthenticatedUser adds the user identified by the API Gateway authorizer
+// to the context. The authorizer is expected to expose the "user_id" and "role"
+// context values; requests without them are treated as anonymous.
+func WithAuthenticatedUser(ctx context.Context, request events.APIGatewayProxyRequest) context.Context {
+	authorizer := request.RequestContext.Authorizer
+	if authorizer == nil {
+		return ctx
+	}
+
+	userID, err := strconv.Atoi(fmt.Sprintf("%v", authorizer["user_id"]))
+	if err != nil || userID <= 0 {
+		return ctx
+	}
+
+	role, _ := authorizer["role"].(string)
+
+	ctx = context.WithValue(ctx, "userID", userID)
+	return context.WithValue(ctx, "userRole", role)
+}
+
+// currentUser returns the authenticated user, or nil for anonymous requests
+func currentUser(ctx context.Context) *models.User {
+	userID, ok := ctx.Value("userID").(int)
+	if !ok {
+		return nil
+	}
+
+	role, _ := ctx.Value("userRole").(string)
+	return &models.User{
+		ID:   userID,
+		Role: role,
+	}
+}
+
+// requireAdmin returns an error response when the caller is not an admin (a user with write access)
+func requireAdmin(ctx context.Context) (events.APIGatewayProxyResponse, bool) {
+	user := currentUser(ctx)
+	if user == nil {
+		response, _ := createErrorResponse(unauthorizedError("Authentication required"))
+		return response, false
+	}
+
+	if !user.HasWriteAccess() {
+		response, _ := createErrorResponse(forbiddenError("Admin access required"))
+		return response, false
+	}
+
+	return events.APIGatewayProxyResponse{}, true
+}
+
+// requireSelfOrAdmin returns an error response when the caller is neither the given user nor an admin
+func requireSelfOrAdmin(ctx context.Context, userID int) (events.APIGatewayProxyResponse, bool) {
+	user := currentUser(ctx)
+	if user == nil {
+		response, _ := createErrorResponse(unauthorizedError("Authentication required"))
+		return response, false
+	}
+
+	if user.ID != userID && !user.HasWriteAccess() {
+		response, _ := createErrorResponse(forbiddenError("Access denied"))
+		return response, false
+	}
+
+	return events.APIGatewayProxyResponse{}, true
+}
+
+// includeDeleted reports whether soft-deleted content should be returned: only
+// when an admin asks for it with ?include_deleted=true. The parameter is
+// ignored for everyone else.
+func includeDeleted(ctx context.Context, request events.APIGatewayProxyRequest) bool {
+	if request.QueryStringParameters["include_deleted"] != "true" {
+		return false
+	}
+
+	user := currentUser(ctx)
+	return user != nil && user.HasWriteAccess()
+}
+
+// canSeeUnpublished reports whether the caller may see a lugar that was not
+// published yet: admins see every lugar, other users only the ones they own
+func canSeeUnpublished(ctx context.Context, ownerID int) bool {
+	user := currentUser(ctx)
+	return user != nil && (user.HasWriteAccess() || user.ID == ownerID)
+}
+
+// withViewer returns a copy of ctx carrying the caller, so the repository only
+// lists the unpublished lugares the caller may see, as canSeeUnpublished does
+func withViewer(ctx context.Context) context.Context {
+	viewer := repository.Viewer{}
+	if user := currentUser(ctx); user != nil {
+		viewer.UserID = user.ID
+		viewer.Admin = user.HasWriteAccess()
+	}
+	return repository.WithViewer(ctx, viewer)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"mime"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/models"
+)
+
+// RequireJSONContentType returns an error response when a POST, PUT or PATCH
+// request has a body whose Content-Type is not application/json. Parameters
+// such as charset are allowed; requests without a body are not checked.
+func RequireJSONContentType(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, bool) {
+	switch request.HTTPMethod {
+	case "POST", "PUT", "PATCH":
+	default:
+		return events.APIGatewayProxyResponse{}, true
+	}
+
+	if strings.TrimSpace(request.Body) == "" {
+		return events.APIGatewayProxyResponse{}, true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(headerValue(request, "Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		response, _ := createErrorResponse(unsupportedMediaTypeError("Content-Type must be application/json"))
+		return response, false
+	}
+
+	return events.APIGatewayProxyResponse{}, true
+}
+
+// headerValue returns a request header, whatever the case of its name
+func headerValue(request events.APIGatewayProxyRequest, name string) string {
+	for key, value := range request.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	for key, values := range request.MultiValueHeaders {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// decodeJSONBody decodes the JSON request body into v. Bodies that API
+// Gateway delivers base64-encoded are decoded first. An empty or
+// whitespace-only body is reported as a missing body rather than as a
+// JSON syntax error.
+func decodeJSONBody(request events.APIGatewayProxyRequest, v interface{}) *APIError {
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			apiErr := invalidBodyError("Invalid base64 request body")
+			apiErr.cause = err
+			return apiErr
+		}
+		body = decoded
+	}
+
+	if strings.TrimSpace(string(body)) == "" {
+		return invalidBodyError("Request body is required")
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		apiErr := invalidBodyError("Invalid request body")
+		apiErr.cause = err
+		return apiErr
+	}
+
+	return nil
+}
+
+// decodeImagesBody decodes a body holding a single image, an array of images
+// or {"images": [...]}. single reports whether the body was a single image.
+func decodeImagesBody(request events.APIGatewayProxyRequest) (images []*models.LugarImage, single bool, apiErr *APIError) {
+	var raw json.RawMessage
+	if apiErr := decodeJSONBody(request, &raw); apiErr != nil {
+		return nil, false, apiErr
+	}
+
+	invalid := func(err error) *APIError {
+		apiErr := invalidBodyError("Invalid request body")
+		apiErr.cause = err
+		return apiErr
+	}
+
+	// An array of images
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+		if err := json.Unmarshal(raw, &images); err != nil {
+			return nil, false, invalid(err)
+		}
+		return images, false, checkImages(images)
+	}
+
+	// An object wrapping the images
+	var wrapper struct {
+		Images []*models.LugarImage `json:"images"`
+	}
+	if err := json.Unmarshal(raw, &wrapper); err != nil {
+		return nil, false, invalid(err)
+	}
+	if wrapper.Images != nil {
+		return wrapper.Images, false, checkImages(wrapper.Images)
+	}
+
+	// A single image
+	var image models.LugarImage
+	if err := json.Unmarshal(raw, &image); err != nil {
+		return nil, false, invalid(err)
+	}
+	return []*models.LugarImage{&image}, true, nil
+}
+
+// checkImages rejects null entries in a list of images
+func checkImages(images []*models.LugarImage) *APIError {
+	for _, image := range images {
+		if image == nil {
+			return invalidBodyError("Images must be objects")
+		}
+	}
+	return nil
+}
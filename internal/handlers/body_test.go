@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/models"
+)
+
+func TestDecodeJSONBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     events.APIGatewayProxyRequest
+		wantName    string
+		wantMessage string
+	}{
+		{name: "valid body", request: events.APIGatewayProxyRequest{Body: `{"name": "lobinho"}`}, wantName: "lobinho"},
+		{name: "base64 body", request: events.APIGatewayProxyRequest{Body: base64.StdEncoding.EncodeToString([]byte(`{"name": "senior"}`)), IsBase64Encoded: true}, wantName: "senior"},
+		{name: "empty body", request: events.APIGatewayProxyRequest{Body: ""}, wantMessage: "Request body is required"},
+		{name: "whitespace-only body", request: events.APIGatewayProxyRequest{Body: " \n\t "}, wantMessage: "Request body is required"},
+		{name: "base64 whitespace-only body", request: events.APIGatewayProxyRequest{Body: base64.StdEncoding.EncodeToString([]byte("  ")), IsBase64Encoded: true}, wantMessage: "Request body is required"},
+		{name: "malformed JSON", request: events.APIGatewayProxyRequest{Body: `{"name":`}, wantMessage: "Invalid request body"},
+		{name: "invalid base64", request: events.APIGatewayProxyRequest{Body: "%%%", IsBase64Encoded: true}, wantMessage: "Invalid base64 request body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v struct {
+				Name string `json:"name"`
+			}
+			apiErr := decodeJSONBody(tt.request, &v)
+			if tt.wantMessage == "" {
+				if apiErr != nil {
+					t.Fatalf("unexpected error: %v", apiErr)
+				}
+				if v.Name != tt.wantName {
+					t.Errorf("name = %q, want %q", v.Name, tt.wantName)
+				}
+				return
+			}
+
+			if apiErr == nil {
+				t.Fatal("expected an error")
+			}
+			if apiErr.Code != CodeInvalidBody || apiErr.Status != http.StatusBadRequest || apiErr.Message != tt.wantMessage {
+				t.Errorf("got %s %d %q, want %s 400 %q", apiErr.Code, apiErr.Status, apiErr.Message, CodeInvalidBody, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestMutatingHandlersRejectEmptyBodies(t *testing.T) {
+	ramoHandler := NewRamoHandler(&fakeRamoRepo{}, &fakeLogger{})
+	tagHandler := NewTagHandler(&fakeTagLugarRepo{}, nil, &fakeLogger{})
+
+	handlers := []struct {
+		name   string
+		handle func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	}{
+		{"CreateRamo", ramoHandler.CreateRamo},
+		{"CreateLugarTag", tagHandler.CreateLugarTag},
+	}
+	bodies := map[string]string{"empty": "", "whitespace-only": "   \n"}
+
+	for _, h := range handlers {
+		for bodyName, body := range bodies {
+			t.Run(h.name+" with an "+bodyName+" body", func(t *testing.T) {
+				response, err := h.handle(adminContext(), bodyRequest(body, nil))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if response.StatusCode != http.StatusBadRequest {
+					t.Fatalf("status = %d, want 400 (body %s)", response.StatusCode, response.Body)
+				}
+				var apiErr APIError
+				decodeBody(t, response, &apiErr)
+				if apiErr.Message != "Request body is required" {
+					t.Errorf("error = %q, want \"Request body is required\"", apiErr.Message)
+				}
+			})
+		}
+	}
+}
+
+func TestHandlersDecodeBase64Bodies(t *testing.T) {
+	encode := func(body string) string { return base64.StdEncoding.EncodeToString([]byte(body)) }
+
+	tests := []struct {
+		name       string
+		body       string
+		base64     bool
+		wantStatus int
+		wantImages int
+	}{
+		{name: "plain single image", body: `{"image_url": "https://example.com/a.jpg"}`, wantStatus: http.StatusCreated, wantImages: 1},
+		{name: "base64 single image", body: encode(`{"image_url": "https://example.com/a.jpg"}`), base64: true, wantStatus: http.StatusCreated, wantImages: 1},
+		{name: "base64 image array", body: encode(`[{"image_url": "https://example.com/a.jpg"}, {"image_url": "https://example.com/b.jpg"}]`), base64: true, wantStatus: http.StatusCreated, wantImages: 2},
+		{name: "base64 body not flagged as encoded", body: encode(`{"image_url": "https://example.com/a.jpg"}`), wantStatus: http.StatusBadRequest},
+		{name: "plain body flagged as encoded", body: `{"image_url": "https://example.com/a.jpg"}`, base64: true, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := 0
+			repo := &fakeLugarRepo{
+				maxDisplayOrder: func(lugarID int) (int, error) { return 0, nil },
+				addImage: func(image *models.LugarImage, maxImages int) (int, error) {
+					stored++
+					return stored, nil
+				},
+				addImages: func(images []*models.LugarImage, maxImages int) error {
+					stored += len(images)
+					return nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+			request := bodyRequest(tt.body, map[string]string{"id": "1"})
+			request.IsBase64Encoded = tt.base64
+
+			response, err := h.AddImageToLugar(adminContext(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if stored != tt.wantImages {
+				t.Errorf("stored %d images, want %d", stored, tt.wantImages)
+			}
+		})
+	}
+}
+
+func TestDecodeImagesBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantURLs    []string
+		wantSingle  bool
+		wantMessage string
+	}{
+		{name: "single image", body: `{"image_url": "a.jpg", "display_order": 2}`, wantURLs: []string{"a.jpg"}, wantSingle: true},
+		{name: "array of images", body: `[{"image_url": "a.jpg"}, {"image_url": "b.jpg"}]`, wantURLs: []string{"a.jpg", "b.jpg"}},
+		{name: "array with leading whitespace", body: "\n  [{\"image_url\": \"a.jpg\"}]", wantURLs: []string{"a.jpg"}},
+		{name: "images object", body: `{"images": [{"image_url": "a.jpg"}, {"image_url": "b.jpg"}]}`, wantURLs: []string{"a.jpg", "b.jpg"}},
+		{name: "empty array", body: `[]`, wantURLs: []string{}},
+		{name: "empty images object", body: `{"images": []}`, wantURLs: []string{}},
+		{name: "null entry in an array", body: `[{"image_url": "a.jpg"}, null]`, wantMessage: "Images must be objects"},
+		{name: "null entry in an images object", body: `{"images": [null]}`, wantMessage: "Images must be objects"},
+		{name: "array of non-objects", body: `["a.jpg"]`, wantMessage: "Invalid request body"},
+		{name: "malformed JSON", body: `[{"image_url": `, wantMessage: "Invalid request body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			images, single, apiErr := decodeImagesBody(events.APIGatewayProxyRequest{Body: tt.body})
+			if tt.wantMessage != "" {
+				if apiErr == nil || apiErr.Message != tt.wantMessage {
+					t.Fatalf("error = %v, want %q", apiErr, tt.wantMessage)
+				}
+				return
+			}
+			if apiErr != nil {
+				t.Fatalf("unexpected error: %v", apiErr)
+			}
+			if single != tt.wantSingle {
+				t.Errorf("single = %v, want %v", single, tt.wantSingle)
+			}
+			urls := []string{}
+			for _, image := range images {
+				urls = append(urls, image.ImageURL)
+			}
+			if len(urls) != len(tt.wantURLs) {
+				t.Fatalf("image URLs = %v, want %v", urls, tt.wantURLs)
+			}
+			for i := range urls {
+				if urls[i] != tt.wantURLs[i] {
+					t.Fatalf("image URLs = %v, want %v", urls, tt.wantURLs)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireJSONContentType(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		multi   map[string][]string
+		body    string
+		wantOK  bool
+	}{
+		{name: "correct header", method: "POST", headers: map[string]string{"Content-Type": "application/json"}, body: `{}`, wantOK: true},
+		{name: "charset suffix", method: "PUT", headers: map[string]string{"Content-Type": "application/json; charset=utf-8"}, body: `{}`, wantOK: true},
+		{name: "header name in lower case", method: "PATCH", headers: map[string]string{"content-type": "Application/JSON"}, body: `{}`, wantOK: true},
+		{name: "multi-value header", method: "POST", multi: map[string][]string{"Content-Type": {"application/json"}}, body: `{}`, wantOK: true},
+		{name: "missing header", method: "POST", body: `{}`},
+		{name: "form-encoded", method: "POST", headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, body: "a=1"},
+		{name: "plain text", method: "PUT", headers: map[string]string{"Content-Type": "text/plain"}, body: `{}`},
+		{name: "malformed header", method: "PATCH", headers: map[string]string{"Content-Type": "application/json; ="}, body: `{}`},
+		{name: "no body", method: "POST", wantOK: true},
+		{name: "whitespace-only body", method: "POST", body: " \n", wantOK: true},
+		{name: "GET with a body is not checked", method: "GET", headers: map[string]string{"Content-Type": "text/plain"}, body: "x", wantOK: true},
+		{name: "DELETE is not checked", method: "DELETE", body: "x", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, ok := RequireJSONContentType(events.APIGatewayProxyRequest{
+				HTTPMethod:        tt.method,
+				Headers:           tt.headers,
+				MultiValueHeaders: tt.multi,
+				Body:              tt.body,
+			})
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				if response.StatusCode != http.StatusUnsupportedMediaType || errorCode(t, response) != CodeUnsupportedMedia {
+					t.Errorf("response = %d %s, want 415 %s", response.StatusCode, response.Body, CodeUnsupportedMedia)
+				}
+			}
+		})
+	}
+}
+
+func TestBodyErrorStatuses(t *testing.T) {
+	lugarHandler := NewLugarHandler(&fakeLugarRepo{
+		getByID: func(id int) (*models.Lugar, error) { return &models.Lugar{ID: id, NomeLocal: "Sítio", UserID: 1}, nil },
+	}, nil, nil, nil, &fakeLogger{})
+	cancaoHandler := NewCancaoHandler(&fakeCancaoRepo{
+		getByID: func(id int) (*models.Cancao, error) { return &models.Cancao{ID: id, Nome: "Canção", UserID: 1}, nil },
+	}, &fakeLogger{})
+	ramoHandler := NewRamoHandler(&fakeRamoRepo{
+		getByID: func(id int) (*models.Ramo, error) { return &models.Ramo{ID: id, Name: "lobinho"}, nil },
+	}, &fakeLogger{})
+	tagHandler := NewTagHandler(&fakeTagLugarRepo{}, nil, &fakeLogger{})
+
+	handlers := []struct {
+		name        string
+		handle      func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+		missingBody string
+	}{
+		{"CreateLugar", lugarHandler.CreateLugar, `{"nome_local": ""}`},
+		{"UpdateLugar", lugarHandler.UpdateLugar, `{"nome_local": ""}`},
+		{"CreateCancao", cancaoHandler.CreateCancao, `{"letra": "lá lá lá"}`},
+		{"UpdateCancao", cancaoHandler.UpdateCancao, `{"nome": ""}`},
+		{"CreateRamo", ramoHandler.CreateRamo, `{}`},
+		{"UpdateRamo", ramoHandler.UpdateRamo, `{"name": ""}`},
+		{"CreateLugarTag", tagHandler.CreateLugarTag, `{"name": ""}`},
+	}
+
+	tests := []struct {
+		name       string
+		body       func(missingBody string) string
+		wantStatus int
+		wantCode   string
+	}{
+		{"malformed JSON", func(string) string { return `{"nome": ` }, http.StatusBadRequest, CodeInvalidBody},
+		{"wrong field type", func(string) string { return `{"nome_local": 1, "nome": 1, "name": 1}` }, http.StatusBadRequest, CodeInvalidBody},
+		{"missing required field", func(missingBody string) string { return missingBody }, http.StatusUnprocessableEntity, CodeValidationFailed},
+	}
+
+	for _, h := range handlers {
+		for _, tt := range tests {
+			t.Run(h.name+" with "+tt.name, func(t *testing.T) {
+				// The fakes panic if anything is written
+				response, err := h.handle(adminContext(), bodyRequest(tt.body(h.missingBody), map[string]string{"id": "1"}))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if response.StatusCode != tt.wantStatus {
+					t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+				}
+				if code := errorCode(t, response); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+			})
+		}
+	}
+}
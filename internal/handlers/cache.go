@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// cacheMaxAgeDefaults holds the default Cache-Control max-age, in seconds, of
+// GET responses per resource. Each can be overridden with a
+// CACHE_MAX_AGE_<RESOURCE> environment variable; zero disables caching.
+// Responses that depend on the caller are never cached (see markPrivate).
+var cacheMaxAgeDefaults = map[string]int{
+	"lugares": 60,
+	"cancoes": 60,
+	"ratings": 60,
+	"ramos":   3600,
+	"tags":    3600,
+}
+
+// cacheMaxAge returns the configured max-age for a resource
+func cacheMaxAge(resource string) int {
+	return getEnvInt("CACHE_MAX_AGE_"+strings.ToUpper(resource), cacheMaxAgeDefaults[resource])
+}
+
+// createCachedJSONResponse creates a JSON response that clients and CDNs may
+// cache for the max-age configured for the resource
+func createCachedJSONResponse(statusCode int, body interface{}, resource string) (events.APIGatewayProxyResponse, error) {
+	response, err := createJSONResponse(statusCode, body)
+	if err != nil || response.StatusCode != statusCode {
+		return response, err
+	}
+
+	if maxAge := cacheMaxAge(resource); maxAge > 0 {
+		response.Headers["Cache-Control"] = fmt.Sprintf("max-age=%d", maxAge)
+	}
+
+	return response, nil
+}
+
+// privateCacheControl is the Cache-Control of responses that depend on the
+// caller, which neither CDNs nor browsers may store
+const privateCacheControl = "private, no-store"
+
+// createPrivateJSONResponse creates a JSON response that depends on the
+// caller, such as their own data or content only admins can see
+func createPrivateJSONResponse(statusCode int, body interface{}) (events.APIGatewayProxyResponse, error) {
+	return markPrivate(createJSONResponse(statusCode, body))
+}
+
+// markPrivate marks a response built by another helper (e.g.
+// createPaginatedResponse) as depending on the caller, so it is never cached
+func markPrivate(response events.APIGatewayProxyResponse, err error) (events.APIGatewayProxyResponse, error) {
+	if response.Headers != nil {
+		response.Headers["Cache-Control"] = privateCacheControl
+	}
+	return response, err
+}
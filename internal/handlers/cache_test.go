@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/models"
+	"github.com/site-geav-api/internal/repository"
+)
+
+func TestCacheControlHelpers(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		respond func() (events.APIGatewayProxyResponse, error)
+		want    string
+	}{
+		{
+			name: "public resource uses its default max-age",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return createCachedJSONResponse(http.StatusOK, []int{}, "lugares")
+			},
+			want: "max-age=60",
+		},
+		{
+			name: "rarely changing resource is cached longer",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return createCachedJSONResponse(http.StatusOK, []int{}, "ramos")
+			},
+			want: "max-age=3600",
+		},
+		{
+			name: "max-age overridden by the environment",
+			env:  map[string]string{"CACHE_MAX_AGE_LUGARES": "5"},
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return createCachedJSONResponse(http.StatusOK, []int{}, "lugares")
+			},
+			want: "max-age=5",
+		},
+		{
+			name: "zero max-age disables caching",
+			env:  map[string]string{"CACHE_MAX_AGE_TAGS": "0"},
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return createCachedJSONResponse(http.StatusOK, []int{}, "tags")
+			},
+			want: "no-store",
+		},
+		{
+			name: "mutation",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return createJSONResponse(http.StatusCreated, map[string]int{"id": 1})
+			},
+			want: "no-store",
+		},
+		{
+			name: "response that depends on the caller",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return createPrivateJSONResponse(http.StatusOK, []int{})
+			},
+			want: privateCacheControl,
+		},
+		{
+			name: "private list ignores the resource max-age",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return markPrivate(createCachedJSONResponse(http.StatusOK, []int{}, "lugares"))
+			},
+			want: privateCacheControl,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			response, err := tt.respond()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := response.Headers["Cache-Control"]; got != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheControlPerEndpoint(t *testing.T) {
+	lugarRepo := &fakeLugarRepo{
+		list: func(opts repository.LugarListOptions) ([]*models.Lugar, error) {
+			return []*models.Lugar{{ID: 1}}, nil
+		},
+		selectOptions: func(page repository.Pagination) ([]*models.SelectOption, error) {
+			return []*models.SelectOption{{ID: 1, Label: "Sede"}}, nil
+		},
+		recentRatings: func(limit int) ([]*models.RecentRating, error) {
+			return []*models.RecentRating{}, nil
+		},
+		listInBoundingBox: func(minLat, minLng, maxLat, maxLng float64, page repository.Pagination) ([]*models.Lugar, error) {
+			return []*models.Lugar{{ID: 1}}, nil
+		},
+	}
+	lugarHandler := NewLugarHandler(lugarRepo, nil, nil, nil, &fakeLogger{})
+	userRepo := &fakeUserRepo{
+		getByID: func(id int) (*models.User, error) {
+			return &models.User{ID: id, Username: "user"}, nil
+		},
+	}
+	userHandler := NewUserHandler(userRepo, nil, nil, &fakeLogger{})
+	boundingBox := map[string]string{"min_lat": "-24", "min_lng": "-47", "max_lat": "-23", "max_lng": "-46"}
+
+	tests := []struct {
+		name    string
+		respond func() (events.APIGatewayProxyResponse, error)
+		want    string
+	}{
+		{
+			name: "public list",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return lugarHandler.ListLugares(context.Background(), queryRequest(nil))
+			},
+			want: "max-age=60",
+		},
+		{
+			name: "list of the lugares the caller can edit",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return lugarHandler.ListLugares(userContext(2, "read"), queryRequest(map[string]string{"editable": "true"}))
+			},
+			want: privateCacheControl,
+		},
+		{
+			name: "options for an anonymous caller",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return lugarHandler.ListLugarOptions(context.Background(), queryRequest(nil))
+			},
+			want: "max-age=60",
+		},
+		{
+			name: "options including the unpublished lugares of the caller",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return lugarHandler.ListLugarOptions(userContext(2, "read"), queryRequest(nil))
+			},
+			want: privateCacheControl,
+		},
+		{
+			name: "bounding box for an anonymous caller",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return lugarHandler.ListLugaresInBoundingBox(context.Background(), queryRequest(boundingBox))
+			},
+			want: "max-age=60",
+		},
+		{
+			name: "bounding box including the unpublished lugares of the caller",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return lugarHandler.ListLugaresInBoundingBox(adminContext(), queryRequest(boundingBox))
+			},
+			want: privateCacheControl,
+		},
+		{
+			name: "recent ratings for an anonymous caller",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return lugarHandler.GetRecentRatings(context.Background(), queryRequest(nil))
+			},
+			want: "max-age=60",
+		},
+		{
+			name: "recent ratings including the unpublished lugares of the caller",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return lugarHandler.GetRecentRatings(userContext(2, "read"), queryRequest(nil))
+			},
+			want: privateCacheControl,
+		},
+		{
+			name: "user",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return userHandler.GetUser(userContext(2, "read"), pathRequest(map[string]string{"id": "2"}))
+			},
+			want: privateCacheControl,
+		},
+		{
+			name: "permissions of the caller",
+			respond: func() (events.APIGatewayProxyResponse, error) {
+				return userHandler.GetPermissions(userContext(2, "read"), queryRequest(nil))
+			},
+			want: privateCacheControl,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := tt.respond()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", response.StatusCode, response.Body)
+			}
+			if got := response.Headers["Cache-Control"]; got != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
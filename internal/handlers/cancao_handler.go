@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/site-geav-api/internal/logger"
@@ -14,17 +17,22 @@ import (
 	"github.com/site-geav-api/internal/repository"
 )
 
+// defaultMaxLetraLength is used when MAX_LETRA_LENGTH is not set
+const defaultMaxLetraLength = 20000
+
 // CancaoHandler handles song-related requests
 type CancaoHandler struct {
-	cancaoRepo repository.CancaoRepository
-	log        logger.Logger
+	cancaoRepo     repository.CancaoRepository
+	log            logger.Logger
+	maxLetraLength int
 }
 
 // NewCancaoHandler creates a new CancaoHandler
 func NewCancaoHandler(cancaoRepo repository.CancaoRepository, log logger.Logger) *CancaoHandler {
 	return &CancaoHandler{
-		cancaoRepo: cancaoRepo,
-		log:        log,
+		cancaoRepo:     cancaoRepo,
+		log:            log,
+		maxLetraLength: getEnvPositiveInt("MAX_LETRA_LENGTH", defaultMaxLetraLength),
 	}
 }
 
@@ -37,18 +45,23 @@ func (h *CancaoHandler) GetCancao(ctx context.Context, request events.APIGateway
 			"action":   "GetCancao",
 			"resource": "cancoes",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid cancao ID")
+		return createErrorResponse(invalidIDError("Invalid cancao ID"))
 	}
 
-	// Get cancao from repository
-	cancao, err := h.cancaoRepo.GetByID(ctx, cancaoID)
+	// Get cancao from repository; admins may ask for a deleted one
+	getByID := h.cancaoRepo.GetByID
+	withDeleted := includeDeleted(ctx, request)
+	if withDeleted {
+		getByID = h.cancaoRepo.GetByIDIncludingDeleted
+	}
+	cancao, err := getByID(ctx, cancaoID)
 	if err != nil {
 		h.log.Error(ctx, "Error getting cancao", err, map[string]interface{}{
 			"action":      "GetCancao",
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error getting cancao")
+		return createErrorResponse(internalError("Error getting cancao"))
 	}
 
 	// If cancao not found
@@ -58,7 +71,7 @@ func (h *CancaoHandler) GetCancao(ctx context.Context, request events.APIGateway
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 		})
-		return createErrorResponse(http.StatusNotFound, "Cancao not found")
+		return createErrorResponse(notFoundError("Cancao not found"))
 	}
 
 	// Log success
@@ -68,20 +81,74 @@ func (h *CancaoHandler) GetCancao(ctx context.Context, request events.APIGateway
 		"resource_id": fmt.Sprintf("%d", cancaoID),
 	})
 
-	// Return cancao as JSON
-	return createJSONResponse(http.StatusOK, cancao)
+	// Return cancao as JSON; what an admin sees of deleted cancoes must not be cached
+	response, err := createCachedJSONResponse(http.StatusOK, cancao, "cancoes")
+	if withDeleted {
+		return markPrivate(response, err)
+	}
+	return response, err
 }
 
 // ListCancoes handles GET /cancoes requests
 func (h *CancaoHandler) ListCancoes(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Validate sort parameter
+	opts := repository.CancaoListOptions{
+		Sort:           request.QueryStringParameters["sort"],
+		MissingYoutube: request.QueryStringParameters["missing_youtube"] == "true",
+		IncludeDeleted: includeDeleted(ctx, request),
+	}
+	if !repository.IsValidCancaoSort(opts.Sort) {
+		h.log.Warn(ctx, "Invalid sort value", map[string]interface{}{
+			"action":   "ListCancoes",
+			"resource": "cancoes",
+			"sort":     opts.Sort,
+		})
+		return createErrorResponse(validationError("Invalid sort value"))
+	}
+
+	// Parse tag filter
+	tagIDs, err := parseIntListParam(request, "tag_id")
+	if err != nil {
+		h.log.Error(ctx, "Invalid tag ID", err, map[string]interface{}{
+			"action":   "ListCancoes",
+			"resource": "cancoes",
+		})
+		return createErrorResponse(invalidIDError("Invalid tag ID"))
+	}
+	opts.TagIDs = tagIDs
+
+	switch match := request.QueryStringParameters["match"]; match {
+	case "", "any":
+	case "all":
+		opts.MatchAllTags = true
+	default:
+		h.log.Warn(ctx, "Invalid match value", map[string]interface{}{
+			"action":   "ListCancoes",
+			"resource": "cancoes",
+			"match":    match,
+		})
+		return createErrorResponse(validationError("match must be all or any"))
+	}
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListCancoes",
+			"resource": "cancoes",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+	opts.Pagination = repository.Pagination{Limit: limit, Offset: offset}
+
 	// Get cancoes from repository
-	cancoes, err := h.cancaoRepo.List(ctx)
+	cancoes, err := h.cancaoRepo.List(ctx, opts)
 	if err != nil {
 		h.log.Error(ctx, "Error listing cancoes", err, map[string]interface{}{
 			"action":   "ListCancoes",
 			"resource": "cancoes",
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error listing cancoes")
+		return createErrorResponse(internalError("Error listing cancoes"))
 	}
 
 	// Log success
@@ -91,20 +158,222 @@ func (h *CancaoHandler) ListCancoes(ctx context.Context, request events.APIGatew
 		"count":    len(cancoes),
 	})
 
-	// Return cancoes as JSON
-	return createJSONResponse(http.StatusOK, cancoes)
+	// Return cancoes as JSON; the list with deleted cancoes is only for admins, so it is not cached
+	response, err := createPaginatedResponse(ctx, h.log, request, cancoes, len(cancoes), limit, offset, "cancoes", func() (int, error) {
+		return h.cancaoRepo.Count(ctx, opts)
+	})
+	if opts.IncludeDeleted {
+		return markPrivate(response, err)
+	}
+	return response, err
+}
+
+// ListCancaoOptions handles GET /cancoes/options requests, returning only the ID and name of
+// every cancao for select inputs
+func (h *CancaoHandler) ListCancaoOptions(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListCancaoOptions",
+			"resource": "cancoes",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get options from repository
+	options, err := h.cancaoRepo.ListSelectOptions(ctx, repository.Pagination{Limit: limit, Offset: offset})
+	if err != nil {
+		h.log.Error(ctx, "Error listing cancao options", err, map[string]interface{}{
+			"action":   "ListCancaoOptions",
+			"resource": "cancoes",
+		})
+		return createErrorResponse(internalError("Error listing cancao options"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Cancao options listed successfully", map[string]interface{}{
+		"action":   "ListCancaoOptions",
+		"resource": "cancoes",
+		"count":    len(options),
+	})
+
+	// Return options as JSON
+	return createCachedJSONResponse(http.StatusOK, options, "cancoes")
+}
+
+const (
+	// defaultRelatedCancoesLimit is the number of related cancoes returned when the request does not set a limit
+	defaultRelatedCancoesLimit = 5
+	// maxRelatedCancoesLimit is the largest accepted related cancoes limit
+	maxRelatedCancoesLimit = 20
+)
+
+// GetRelatedCancoes handles GET /cancoes/{id}/related requests
+func (h *CancaoHandler) GetRelatedCancoes(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract cancao ID from path parameters
+	cancaoID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid cancao ID", err, map[string]interface{}{
+			"action":   "GetRelatedCancoes",
+			"resource": "cancoes",
+		})
+		return createErrorResponse(invalidIDError("Invalid cancao ID"))
+	}
+
+	// Validate limit
+	limit, err := parseLimitParam(request, defaultRelatedCancoesLimit, maxRelatedCancoesLimit)
+	if err != nil {
+		h.log.Warn(ctx, "Invalid related cancoes limit", map[string]interface{}{
+			"action":      "GetRelatedCancoes",
+			"resource":    "cancoes",
+			"resource_id": fmt.Sprintf("%d", cancaoID),
+			"limit":       request.QueryStringParameters["limit"],
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Check that the cancao exists
+	cancao, err := h.cancaoRepo.GetByID(ctx, cancaoID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting cancao", err, map[string]interface{}{
+			"action":      "GetRelatedCancoes",
+			"resource":    "cancoes",
+			"resource_id": fmt.Sprintf("%d", cancaoID),
+		})
+		return createErrorResponse(internalError("Error listing related cancoes"))
+	}
+
+	// If cancao not found
+	if cancao == nil {
+		h.log.Warn(ctx, "Cancao not found", map[string]interface{}{
+			"action":      "GetRelatedCancoes",
+			"resource":    "cancoes",
+			"resource_id": fmt.Sprintf("%d", cancaoID),
+		})
+		return createErrorResponse(notFoundError("Cancao not found"))
+	}
+
+	// Get related cancoes from repository
+	related, err := h.cancaoRepo.ListRelated(ctx, cancaoID, limit)
+	if err != nil {
+		h.log.Error(ctx, "Error listing related cancoes", err, map[string]interface{}{
+			"action":      "GetRelatedCancoes",
+			"resource":    "cancoes",
+			"resource_id": fmt.Sprintf("%d", cancaoID),
+		})
+		return createErrorResponse(internalError("Error listing related cancoes"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Related cancoes listed successfully", map[string]interface{}{
+		"action":      "GetRelatedCancoes",
+		"resource":    "cancoes",
+		"resource_id": fmt.Sprintf("%d", cancaoID),
+		"count":       len(related),
+	})
+
+	// Return related cancoes as JSON
+	return createCachedJSONResponse(http.StatusOK, related, "cancoes")
+}
+
+// maxExportBytes bounds the body of an export response, below the 6MB limit
+// of Lambda responses; larger exports are split into pages
+var maxExportBytes = 5 << 20
+
+// errExportPageFull stops the export once the page reached maxExportBytes
+var errExportPageFull = errors.New("export page is full")
+
+// ExportCancoes handles GET /cancoes/export?after= requests. The songs,
+// lyrics included, are written as one JSON object per line (NDJSON) in ID
+// order while the rows are read. A page holds at most maxExportBytes; when
+// songs remain, the X-Next-Cursor header holds the ID to pass as after to get
+// the next page.
+func (h *CancaoHandler) ExportCancoes(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized cancoes export request", map[string]interface{}{
+			"action":   "ExportCancoes",
+			"resource": "cancoes",
+		})
+		return response, nil
+	}
+
+	// Parse cursor
+	after := 0
+	if value := request.QueryStringParameters["after"]; value != "" {
+		var err error
+		after, err = strconv.Atoi(value)
+		if err != nil || after < 0 {
+			h.log.Warn(ctx, "Invalid export cursor", map[string]interface{}{
+				"action":   "ExportCancoes",
+				"resource": "cancoes",
+				"after":    value,
+			})
+			return createErrorResponse(validationError("after must be a non-negative integer"))
+		}
+	}
+
+	// Write each cancao as a line of JSON until the page is full
+	var body, line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	count, lastID := 0, 0
+	err := h.cancaoRepo.ForEach(ctx, after, func(cancao *models.Cancao) error {
+		line.Reset()
+		if err := encoder.Encode(cancao); err != nil {
+			return err
+		}
+		if count > 0 && body.Len()+line.Len() > maxExportBytes {
+			return errExportPageFull
+		}
+		body.Write(line.Bytes())
+		count++
+		lastID = cancao.ID
+		return nil
+	})
+	full := errors.Is(err, errExportPageFull)
+	if err != nil && !full {
+		h.log.Error(ctx, "Error exporting cancoes", err, map[string]interface{}{
+			"action":   "ExportCancoes",
+			"resource": "cancoes",
+		})
+		return createErrorResponse(internalError("Error exporting cancoes"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Cancoes exported successfully", map[string]interface{}{
+		"action":   "ExportCancoes",
+		"resource": "cancoes",
+		"count":    count,
+		"after":    after,
+		"more":     full,
+	})
+
+	// Return cancoes as NDJSON
+	headers := map[string]string{
+		"Content-Type":  "application/x-ndjson",
+		"Cache-Control": "no-store",
+	}
+	if full {
+		headers["X-Next-Cursor"] = strconv.Itoa(lastID)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       body.String(),
+	}, nil
 }
 
 // CreateCancao handles POST /cancoes requests
 func (h *CancaoHandler) CreateCancao(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Parse request body
 	var cancao models.Cancao
-	if err := json.Unmarshal([]byte(request.Body), &cancao); err != nil {
+	if err := decodeJSONBody(request, &cancao); err != nil {
 		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
 			"action":   "CreateCancao",
 			"resource": "cancoes",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
+		return createErrorResponse(err)
 	}
 
 	// Validate cancao
@@ -113,11 +382,18 @@ func (h *CancaoHandler) CreateCancao(ctx context.Context, request events.APIGate
 			"action":   "CreateCancao",
 			"resource": "cancoes",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Nome is required")
+		return createErrorResponse(unprocessableError("Nome is required"))
+	}
+	if err := h.validateLetra(cancao.Letra); err != nil {
+		h.log.Warn(ctx, "Invalid cancao data: "+err.Error(), map[string]interface{}{
+			"action":   "CreateCancao",
+			"resource": "cancoes",
+		})
+		return createErrorResponse(unprocessableError(err.Error()))
 	}
 
 	// Set timestamps
-	now := time.Now()
+	now := time.Now().UTC()
 	cancao.CreatedAt = now
 	cancao.UpdatedAt = now
 
@@ -128,7 +404,7 @@ func (h *CancaoHandler) CreateCancao(ctx context.Context, request events.APIGate
 			"action":   "CreateCancao",
 			"resource": "cancoes",
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error creating cancao")
+		return createErrorResponse(internalError("Error creating cancao"))
 	}
 
 	// Set cancao ID
@@ -137,7 +413,7 @@ func (h *CancaoHandler) CreateCancao(ctx context.Context, request events.APIGate
 	// Process related entities if provided
 	if len(cancao.Tags) > 0 {
 		for _, tag := range cancao.Tags {
-			if err := h.cancaoRepo.AddTag(ctx, cancaoID, tag.ID); err != nil {
+			if _, err := h.cancaoRepo.AddTag(ctx, cancaoID, tag.ID); err != nil {
 				h.log.Error(ctx, "Error adding tag to cancao", err, map[string]interface{}{
 					"action":      "CreateCancao",
 					"resource":    "cancoes",
@@ -151,7 +427,7 @@ func (h *CancaoHandler) CreateCancao(ctx context.Context, request events.APIGate
 
 	if len(cancao.Ramos) > 0 {
 		for _, ramo := range cancao.Ramos {
-			if err := h.cancaoRepo.AddRamo(ctx, cancaoID, ramo.ID); err != nil {
+			if _, err := h.cancaoRepo.AddRamo(ctx, cancaoID, ramo.ID); err != nil {
 				h.log.Error(ctx, "Error adding ramo to cancao", err, map[string]interface{}{
 					"action":      "CreateCancao",
 					"resource":    "cancoes",
@@ -183,7 +459,7 @@ func (h *CancaoHandler) UpdateCancao(ctx context.Context, request events.APIGate
 			"action":   "UpdateCancao",
 			"resource": "cancoes",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid cancao ID")
+		return createErrorResponse(invalidIDError("Invalid cancao ID"))
 	}
 
 	// Get existing cancao
@@ -194,7 +470,7 @@ func (h *CancaoHandler) UpdateCancao(ctx context.Context, request events.APIGate
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error getting cancao")
+		return createErrorResponse(internalError("Error getting cancao"))
 	}
 
 	// If cancao not found
@@ -204,18 +480,18 @@ func (h *CancaoHandler) UpdateCancao(ctx context.Context, request events.APIGate
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 		})
-		return createErrorResponse(http.StatusNotFound, "Cancao not found")
+		return createErrorResponse(notFoundError("Cancao not found"))
 	}
 
 	// Parse request body
 	var updatedCancao models.Cancao
-	if err := json.Unmarshal([]byte(request.Body), &updatedCancao); err != nil {
+	if err := decodeJSONBody(request, &updatedCancao); err != nil {
 		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
 			"action":      "UpdateCancao",
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
+		return createErrorResponse(err)
 	}
 
 	// Validate cancao
@@ -225,7 +501,15 @@ func (h *CancaoHandler) UpdateCancao(ctx context.Context, request events.APIGate
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Nome is required")
+		return createErrorResponse(unprocessableError("Nome is required"))
+	}
+	if err := h.validateLetra(updatedCancao.Letra); err != nil {
+		h.log.Warn(ctx, "Invalid cancao data: "+err.Error(), map[string]interface{}{
+			"action":      "UpdateCancao",
+			"resource":    "cancoes",
+			"resource_id": fmt.Sprintf("%d", cancaoID),
+		})
+		return createErrorResponse(unprocessableError(err.Error()))
 	}
 
 	// Update cancao fields
@@ -233,7 +517,7 @@ func (h *CancaoHandler) UpdateCancao(ctx context.Context, request events.APIGate
 	existingCancao.LinkYoutube = updatedCancao.LinkYoutube
 	existingCancao.Letra = updatedCancao.Letra
 	existingCancao.UserID = updatedCancao.UserID
-	existingCancao.UpdatedAt = time.Now()
+	existingCancao.UpdatedAt = time.Now().UTC()
 
 	// Update cancao in repository
 	if err := h.cancaoRepo.Update(ctx, existingCancao); err != nil {
@@ -242,7 +526,7 @@ func (h *CancaoHandler) UpdateCancao(ctx context.Context, request events.APIGate
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error updating cancao")
+		return createErrorResponse(internalError("Error updating cancao"))
 	}
 
 	// Log success
@@ -265,7 +549,7 @@ func (h *CancaoHandler) DeleteCancao(ctx context.Context, request events.APIGate
 			"action":   "DeleteCancao",
 			"resource": "cancoes",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid cancao ID")
+		return createErrorResponse(invalidIDError("Invalid cancao ID"))
 	}
 
 	// Delete cancao from repository
@@ -275,7 +559,7 @@ func (h *CancaoHandler) DeleteCancao(ctx context.Context, request events.APIGate
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error deleting cancao")
+		return createErrorResponse(internalError("Error deleting cancao"))
 	}
 
 	// Log success
@@ -286,12 +570,64 @@ func (h *CancaoHandler) DeleteCancao(ctx context.Context, request events.APIGate
 	})
 
 	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	return createNoContentResponse()
+}
+
+// PlayCancao handles POST /cancoes/{id}/play requests
+func (h *CancaoHandler) PlayCancao(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract cancao ID from path parameters
+	cancaoID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid cancao ID", err, map[string]interface{}{
+			"action":   "PlayCancao",
+			"resource": "cancoes",
+		})
+		return createErrorResponse(invalidIDError("Invalid cancao ID"))
+	}
+
+	// Get existing cancao
+	cancao, err := h.cancaoRepo.GetByID(ctx, cancaoID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting cancao", err, map[string]interface{}{
+			"action":      "PlayCancao",
+			"resource":    "cancoes",
+			"resource_id": fmt.Sprintf("%d", cancaoID),
+		})
+		return createErrorResponse(internalError("Error getting cancao"))
+	}
+
+	// If cancao not found
+	if cancao == nil {
+		h.log.Warn(ctx, "Cancao not found", map[string]interface{}{
+			"action":      "PlayCancao",
+			"resource":    "cancoes",
+			"resource_id": fmt.Sprintf("%d", cancaoID),
+		})
+		return createErrorResponse(notFoundError("Cancao not found"))
+	}
+
+	// Increment play count
+	playCount, err := h.cancaoRepo.IncrementPlayCount(ctx, cancaoID)
+	if err != nil {
+		h.log.Error(ctx, "Error incrementing play count", err, map[string]interface{}{
+			"action":      "PlayCancao",
+			"resource":    "cancoes",
+			"resource_id": fmt.Sprintf("%d", cancaoID),
+		})
+		return createErrorResponse(internalError("Error incrementing play count"))
+	}
+	cancao.PlayCount = playCount
+
+	// Log success
+	h.log.Info(ctx, "Cancao play registered successfully", map[string]interface{}{
+		"action":      "PlayCancao",
+		"resource":    "cancoes",
+		"resource_id": fmt.Sprintf("%d", cancaoID),
+		"play_count":  playCount,
+	})
+
+	// Return cancao with the updated play count as JSON
+	return createJSONResponse(http.StatusOK, cancao)
 }
 
 // AddTagToCancao handles POST /cancoes/{id}/tags requests
@@ -303,31 +639,32 @@ func (h *CancaoHandler) AddTagToCancao(ctx context.Context, request events.APIGa
 			"action":   "AddTagToCancao",
 			"resource": "cancoes",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid cancao ID")
+		return createErrorResponse(invalidIDError("Invalid cancao ID"))
 	}
 
 	// Parse request body
 	var requestBody struct {
 		TagID int `json:"tag_id"`
 	}
-	if err := json.Unmarshal([]byte(request.Body), &requestBody); err != nil {
+	if err := decodeJSONBody(request, &requestBody); err != nil {
 		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
 			"action":      "AddTagToCancao",
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
+		return createErrorResponse(err)
 	}
 
 	// Add tag to cancao
-	if err := h.cancaoRepo.AddTag(ctx, cancaoID, requestBody.TagID); err != nil {
+	added, err := h.cancaoRepo.AddTag(ctx, cancaoID, requestBody.TagID)
+	if err != nil {
 		h.log.Error(ctx, "Error adding tag to cancao", err, map[string]interface{}{
 			"action":      "AddTagToCancao",
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 			"tag_id":      fmt.Sprintf("%d", requestBody.TagID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error adding tag to cancao")
+		return createErrorResponse(internalError("Error adding tag to cancao"))
 	}
 
 	// Log success
@@ -339,12 +676,7 @@ func (h *CancaoHandler) AddTagToCancao(ctx context.Context, request events.APIGa
 	})
 
 	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	return createAssociationResponse(added)
 }
 
 // RemoveTagFromCancao handles DELETE /cancoes/{id}/tags/{tagId} requests
@@ -356,7 +688,7 @@ func (h *CancaoHandler) RemoveTagFromCancao(ctx context.Context, request events.
 			"action":   "RemoveTagFromCancao",
 			"resource": "cancoes",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid cancao ID")
+		return createErrorResponse(invalidIDError("Invalid cancao ID"))
 	}
 
 	tagID, err := strconv.Atoi(request.PathParameters["tagId"])
@@ -366,7 +698,7 @@ func (h *CancaoHandler) RemoveTagFromCancao(ctx context.Context, request events.
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid tag ID")
+		return createErrorResponse(invalidIDError("Invalid tag ID"))
 	}
 
 	// Remove tag from cancao
@@ -377,7 +709,7 @@ func (h *CancaoHandler) RemoveTagFromCancao(ctx context.Context, request events.
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 			"tag_id":      fmt.Sprintf("%d", tagID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error removing tag from cancao")
+		return createErrorResponse(internalError("Error removing tag from cancao"))
 	}
 
 	// Log success
@@ -389,12 +721,7 @@ func (h *CancaoHandler) RemoveTagFromCancao(ctx context.Context, request events.
 	})
 
 	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	return createNoContentResponse()
 }
 
 // AddRamoToCancao handles POST /cancoes/{id}/ramos requests
@@ -406,31 +733,32 @@ func (h *CancaoHandler) AddRamoToCancao(ctx context.Context, request events.APIG
 			"action":   "AddRamoToCancao",
 			"resource": "cancoes",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid cancao ID")
+		return createErrorResponse(invalidIDError("Invalid cancao ID"))
 	}
 
 	// Parse request body
 	var requestBody struct {
 		RamoID int `json:"ramo_id"`
 	}
-	if err := json.Unmarshal([]byte(request.Body), &requestBody); err != nil {
+	if err := decodeJSONBody(request, &requestBody); err != nil {
 		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
 			"action":      "AddRamoToCancao",
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
+		return createErrorResponse(err)
 	}
 
 	// Add ramo to cancao
-	if err := h.cancaoRepo.AddRamo(ctx, cancaoID, requestBody.RamoID); err != nil {
+	added, err := h.cancaoRepo.AddRamo(ctx, cancaoID, requestBody.RamoID)
+	if err != nil {
 		h.log.Error(ctx, "Error adding ramo to cancao", err, map[string]interface{}{
 			"action":      "AddRamoToCancao",
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 			"ramo_id":     fmt.Sprintf("%d", requestBody.RamoID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error adding ramo to cancao")
+		return createErrorResponse(internalError("Error adding ramo to cancao"))
 	}
 
 	// Log success
@@ -442,12 +770,7 @@ func (h *CancaoHandler) AddRamoToCancao(ctx context.Context, request events.APIG
 	})
 
 	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	return createAssociationResponse(added)
 }
 
 // RemoveRamoFromCancao handles DELETE /cancoes/{id}/ramos/{ramoId} requests
@@ -459,7 +782,7 @@ func (h *CancaoHandler) RemoveRamoFromCancao(ctx context.Context, request events
 			"action":   "RemoveRamoFromCancao",
 			"resource": "cancoes",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid cancao ID")
+		return createErrorResponse(invalidIDError("Invalid cancao ID"))
 	}
 
 	ramoID, err := strconv.Atoi(request.PathParameters["ramoId"])
@@ -469,7 +792,7 @@ func (h *CancaoHandler) RemoveRamoFromCancao(ctx context.Context, request events
 			"resource":    "cancoes",
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid ramo ID")
+		return createErrorResponse(invalidIDError("Invalid ramo ID"))
 	}
 
 	// Remove ramo from cancao
@@ -480,7 +803,7 @@ func (h *CancaoHandler) RemoveRamoFromCancao(ctx context.Context, request events
 			"resource_id": fmt.Sprintf("%d", cancaoID),
 			"ramo_id":     fmt.Sprintf("%d", ramoID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error removing ramo from cancao")
+		return createErrorResponse(internalError("Error removing ramo from cancao"))
 	}
 
 	// Log success
@@ -492,10 +815,13 @@ func (h *CancaoHandler) RemoveRamoFromCancao(ctx context.Context, request events
 	})
 
 	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	return createNoContentResponse()
+}
+
+// validateLetra checks that the lyrics have at most maxLetraLength characters, counted as runes
+func (h *CancaoHandler) validateLetra(letra string) error {
+	if utf8.RuneCountInString(letra) > h.maxLetraLength {
+		return fmt.Errorf("letra must have at most %d characters", h.maxLetraLength)
+	}
+	return nil
 }
@@ -0,0 +1,599 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/models"
+	"github.com/site-geav-api/internal/repository"
+)
+
+func TestPlayCancao(t *testing.T) {
+	tests := []struct {
+		name          string
+		id            string
+		cancao        *models.Cancao
+		incrementErr  error
+		wantStatus    int
+		wantPlayCount int
+	}{
+		{name: "increments the count", id: "7", cancao: &models.Cancao{ID: 7, PlayCount: 41}, wantStatus: http.StatusOK, wantPlayCount: 42},
+		{name: "invalid ID", id: "abc", wantStatus: http.StatusBadRequest},
+		{name: "missing cancao", id: "8", wantStatus: http.StatusNotFound},
+		{name: "repository error", id: "7", cancao: &models.Cancao{ID: 7}, incrementErr: errors.New("deadlock"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			increments := 0
+			repo := &fakeCancaoRepo{
+				getByID: func(id int) (*models.Cancao, error) {
+					return tt.cancao, nil
+				},
+				incrementPlayCount: func(id int) (int, error) {
+					increments++
+					if tt.incrementErr != nil {
+						return 0, tt.incrementErr
+					}
+					return tt.cancao.PlayCount + 1, nil
+				},
+			}
+			h := NewCancaoHandler(repo, &fakeLogger{})
+
+			response, err := h.PlayCancao(context.Background(), pathRequest(map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if increments != 1 {
+				t.Errorf("IncrementPlayCount called %d times, want 1", increments)
+			}
+			var cancao models.Cancao
+			decodeBody(t, response, &cancao)
+			if cancao.PlayCount != tt.wantPlayCount {
+				t.Errorf("play_count = %d, want %d", cancao.PlayCount, tt.wantPlayCount)
+			}
+		})
+	}
+}
+
+func TestListCancoesSort(t *testing.T) {
+	tests := []struct {
+		name       string
+		sort       string
+		wantStatus int
+	}{
+		{"default order", "", http.StatusOK},
+		{"most played first", "plays", http.StatusOK},
+		{"unknown sort", "name; DROP TABLE cancoes", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotSort *string
+			repo := &fakeCancaoRepo{
+				list: func(opts repository.CancaoListOptions) ([]*models.Cancao, error) {
+					gotSort = &opts.Sort
+					return []*models.Cancao{}, nil
+				},
+			}
+			h := NewCancaoHandler(repo, &fakeLogger{})
+
+			response, err := h.ListCancoes(context.Background(), queryRequest(map[string]string{"sort": tt.sort}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if gotSort != nil {
+					t.Error("the repository was called with an invalid sort")
+				}
+				return
+			}
+			if gotSort == nil || *gotSort != tt.sort {
+				t.Errorf("repository sort = %v, want %q", gotSort, tt.sort)
+			}
+		})
+	}
+}
+
+func TestExportCancoes(t *testing.T) {
+	// Each song encodes to a line of the same length
+	var songs []*models.Cancao
+	for id := 1; id <= 5; id++ {
+		songs = append(songs, &models.Cancao{ID: id, Nome: fmt.Sprintf("Canção %d", id), Letra: strings.Repeat("la ", 30)})
+	}
+	line, _ := json.Marshal(songs[0])
+	lineSize := len(line) + 1
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		after      string
+		maxBytes   int
+		repoErr    error
+		wantStatus int
+		wantAfter  int
+		wantIDs    []int
+		wantCursor string
+	}{
+		{name: "everything fits", ctx: adminContext(), maxBytes: 10 * lineSize, wantStatus: http.StatusOK, wantIDs: []int{1, 2, 3, 4, 5}},
+		{name: "full page has a cursor", ctx: adminContext(), maxBytes: 2*lineSize + 1, wantStatus: http.StatusOK, wantIDs: []int{1, 2}, wantCursor: "2"},
+		{name: "next page starts after the cursor", ctx: adminContext(), after: "2", maxBytes: 10 * lineSize, wantStatus: http.StatusOK, wantAfter: 2, wantIDs: []int{3, 4, 5}},
+		{name: "last page exactly full has no cursor", ctx: adminContext(), after: "3", maxBytes: 2 * lineSize, wantStatus: http.StatusOK, wantAfter: 3, wantIDs: []int{4, 5}},
+		{name: "a song larger than a page is still exported", ctx: adminContext(), maxBytes: 10, wantStatus: http.StatusOK, wantIDs: []int{1}, wantCursor: "1"},
+		{name: "invalid cursor", ctx: adminContext(), after: "x", maxBytes: 10 * lineSize, wantStatus: http.StatusBadRequest},
+		{name: "negative cursor", ctx: adminContext(), after: "-1", maxBytes: 10 * lineSize, wantStatus: http.StatusBadRequest},
+		{name: "repository error", ctx: adminContext(), maxBytes: 10 * lineSize, repoErr: errors.New("timeout"), wantStatus: http.StatusInternalServerError},
+		{name: "non-admin", ctx: userContext(2, "read"), maxBytes: 10 * lineSize, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldMax := maxExportBytes
+			maxExportBytes = tt.maxBytes
+			defer func() { maxExportBytes = oldMax }()
+
+			gotAfter := -1
+			repo := &fakeCancaoRepo{
+				forEach: func(afterID int, fn func(*models.Cancao) error) error {
+					gotAfter = afterID
+					if tt.repoErr != nil {
+						return tt.repoErr
+					}
+					for _, song := range songs {
+						if song.ID <= afterID {
+							continue
+						}
+						if err := fn(song); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+			}
+			h := NewCancaoHandler(repo, &fakeLogger{})
+			query := map[string]string{}
+			if tt.after != "" {
+				query["after"] = tt.after
+			}
+
+			response, err := h.ExportCancoes(tt.ctx, queryRequest(query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if gotAfter != tt.wantAfter {
+				t.Errorf("ForEach after = %d, want %d", gotAfter, tt.wantAfter)
+			}
+
+			var ids []int
+			for _, line := range strings.Split(strings.TrimSuffix(response.Body, "\n"), "\n") {
+				var cancao models.Cancao
+				if err := json.Unmarshal([]byte(line), &cancao); err != nil {
+					t.Fatalf("decoding line %q: %v", line, err)
+				}
+				ids = append(ids, cancao.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("exported IDs = %v, want %v", ids, tt.wantIDs)
+			}
+			if cursor := response.Headers["X-Next-Cursor"]; cursor != tt.wantCursor {
+				t.Errorf("X-Next-Cursor = %q, want %q", cursor, tt.wantCursor)
+			}
+			if response.Headers["Content-Type"] != "application/x-ndjson" {
+				t.Errorf("Content-Type = %q, want application/x-ndjson", response.Headers["Content-Type"])
+			}
+		})
+	}
+}
+
+func TestIncludeDeletedCancoes(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		query       map[string]string
+		wantDeleted bool
+	}{
+		{name: "admin asking for deleted cancoes", ctx: adminContext(), query: map[string]string{"include_deleted": "true"}, wantDeleted: true},
+		{name: "admin not asking", ctx: adminContext()},
+		{name: "non-admin asking is ignored", ctx: userContext(2, "read"), query: map[string]string{"include_deleted": "true"}},
+		{name: "anonymous asking is ignored", ctx: context.Background(), query: map[string]string{"include_deleted": "true"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active := &models.Cancao{ID: 1, Nome: "Canção da Alegria"}
+			deleted := &models.Cancao{ID: 2, Nome: "Canção Apagada"}
+			var listOpts repository.CancaoListOptions
+			repo := &fakeCancaoRepo{
+				getByID: func(id int) (*models.Cancao, error) {
+					if id == 1 {
+						return active, nil
+					}
+					return nil, nil
+				},
+				getByIDWithDeleted: func(id int) (*models.Cancao, error) {
+					if id == 2 {
+						return deleted, nil
+					}
+					return active, nil
+				},
+				list: func(opts repository.CancaoListOptions) ([]*models.Cancao, error) {
+					listOpts = opts
+					if opts.IncludeDeleted {
+						return []*models.Cancao{active, deleted}, nil
+					}
+					return []*models.Cancao{active}, nil
+				},
+				count: func(opts repository.CancaoListOptions) (int, error) { return 2, nil },
+			}
+			deleted.DeletedAt = &time.Time{}
+			h := NewCancaoHandler(repo, &fakeLogger{})
+
+			// Getting the deleted cancao
+			request := pathRequest(map[string]string{"id": "2"})
+			request.QueryStringParameters = tt.query
+			response, err := h.GetCancao(tt.ctx, request)
+			if err != nil {
+				t.Fatalf("GetCancao: unexpected error: %v", err)
+			}
+			wantStatus := http.StatusNotFound
+			if tt.wantDeleted {
+				wantStatus = http.StatusOK
+			}
+			if response.StatusCode != wantStatus {
+				t.Fatalf("GetCancao: status = %d, want %d (body %s)", response.StatusCode, wantStatus, response.Body)
+			}
+			if tt.wantDeleted && response.Headers["Cache-Control"] != privateCacheControl {
+				t.Errorf("GetCancao: Cache-Control = %q, want %q", response.Headers["Cache-Control"], privateCacheControl)
+			}
+
+			// Listing
+			response, err = h.ListCancoes(tt.ctx, queryRequest(tt.query))
+			if err != nil {
+				t.Fatalf("ListCancoes: unexpected error: %v", err)
+			}
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("ListCancoes: status = %d, want 200 (body %s)", response.StatusCode, response.Body)
+			}
+			if listOpts.IncludeDeleted != tt.wantDeleted {
+				t.Errorf("ListCancoes: IncludeDeleted = %v, want %v", listOpts.IncludeDeleted, tt.wantDeleted)
+			}
+			private := response.Headers["Cache-Control"] == privateCacheControl
+			if private != tt.wantDeleted {
+				t.Errorf("ListCancoes: Cache-Control = %q, want private %v", response.Headers["Cache-Control"], tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestListCancoesByTags(t *testing.T) {
+	tests := []struct {
+		name         string
+		request      events.APIGatewayProxyRequest
+		wantStatus   int
+		wantTagIDs   []int
+		wantMatchAll bool
+	}{
+		{
+			name:       "no tags",
+			request:    queryRequest(nil),
+			wantStatus: http.StatusOK,
+			wantTagIDs: []int{},
+		},
+		{
+			name: "any by default",
+			request: events.APIGatewayProxyRequest{
+				MultiValueQueryStringParameters: map[string][]string{"tag_id": {"1", "2"}},
+			},
+			wantStatus: http.StatusOK,
+			wantTagIDs: []int{1, 2},
+		},
+		{
+			name: "explicit any",
+			request: events.APIGatewayProxyRequest{
+				QueryStringParameters:           map[string]string{"match": "any"},
+				MultiValueQueryStringParameters: map[string][]string{"tag_id": {"1", "2"}},
+			},
+			wantStatus: http.StatusOK,
+			wantTagIDs: []int{1, 2},
+		},
+		{
+			name: "all",
+			request: events.APIGatewayProxyRequest{
+				QueryStringParameters:           map[string]string{"match": "all"},
+				MultiValueQueryStringParameters: map[string][]string{"tag_id": {"1", "2"}},
+			},
+			wantStatus:   http.StatusOK,
+			wantTagIDs:   []int{1, 2},
+			wantMatchAll: true,
+		},
+		{
+			name:       "invalid match",
+			request:    queryRequest(map[string]string{"tag_id": "1", "match": "some"}),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid tag ID",
+			request:    queryRequest(map[string]string{"tag_id": "hino"}),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotOpts *repository.CancaoListOptions
+			repo := &fakeCancaoRepo{
+				list: func(opts repository.CancaoListOptions) ([]*models.Cancao, error) {
+					gotOpts = &opts
+					return []*models.Cancao{}, nil
+				},
+			}
+			h := NewCancaoHandler(repo, &fakeLogger{})
+
+			response, err := h.ListCancoes(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if gotOpts != nil {
+					t.Error("the repository was called with an invalid filter")
+				}
+				return
+			}
+			if !reflect.DeepEqual(gotOpts.TagIDs, tt.wantTagIDs) || gotOpts.MatchAllTags != tt.wantMatchAll {
+				t.Errorf("tags = %v, match all %v; want %v, %v", gotOpts.TagIDs, gotOpts.MatchAllTags, tt.wantTagIDs, tt.wantMatchAll)
+			}
+		})
+	}
+}
+
+func TestGetRelatedCancoes(t *testing.T) {
+	related := []*models.RelatedCancao{
+		{Cancao: &models.Cancao{ID: 4, Nome: "Canção da viagem"}, SharedTags: 2, SharedRamos: 1, Overlap: 3},
+		{Cancao: &models.Cancao{ID: 9, Nome: "Hino"}, SharedTags: 1, Overlap: 1},
+	}
+
+	tests := []struct {
+		name       string
+		id         string
+		query      map[string]string
+		found      bool
+		wantStatus int
+		wantLimit  int
+		wantIDs    []int
+	}{
+		{name: "default limit", id: "3", found: true, wantStatus: http.StatusOK, wantLimit: defaultRelatedCancoesLimit, wantIDs: []int{4, 9}},
+		{name: "custom limit", id: "3", query: map[string]string{"limit": "1"}, found: true, wantStatus: http.StatusOK, wantLimit: 1, wantIDs: []int{4}},
+		{name: "limit above the maximum", id: "3", query: map[string]string{"limit": "21"}, found: true, wantStatus: http.StatusBadRequest},
+		{name: "missing cancao", id: "3", wantStatus: http.StatusNotFound},
+		{name: "invalid ID", id: "abc", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLimit := 0
+			repo := &fakeCancaoRepo{
+				getByID: func(id int) (*models.Cancao, error) {
+					if !tt.found {
+						return nil, nil
+					}
+					return &models.Cancao{ID: id}, nil
+				},
+				listRelated: func(cancaoID, limit int) ([]*models.RelatedCancao, error) {
+					if cancaoID != 3 {
+						t.Errorf("related of cancao %d, want 3", cancaoID)
+					}
+					gotLimit = limit
+					if limit < len(related) {
+						return related[:limit], nil
+					}
+					return related, nil
+				},
+			}
+			h := NewCancaoHandler(repo, &fakeLogger{})
+
+			request := pathRequest(map[string]string{"id": tt.id})
+			request.QueryStringParameters = tt.query
+			response, err := h.GetRelatedCancoes(context.Background(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if gotLimit != 0 {
+					t.Error("related cancoes were listed for a rejected request")
+				}
+				return
+			}
+			if gotLimit != tt.wantLimit {
+				t.Errorf("limit = %d, want %d", gotLimit, tt.wantLimit)
+			}
+
+			var body []models.RelatedCancao
+			decodeBody(t, response, &body)
+			var ids []int
+			for _, item := range body {
+				ids = append(ids, item.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("related IDs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestLetraMaxLength(t *testing.T) {
+	// "canção" has 6 characters but 8 bytes
+	atLimit := strings.Repeat("canção", 2)
+	overLimit := atLimit + "ã"
+
+	tests := []struct {
+		name         string
+		maxLength    string
+		letra        string
+		wantAccepted bool
+	}{
+		{name: "at the limit in characters", maxLength: "12", letra: atLimit, wantAccepted: true},
+		{name: "one character over", maxLength: "12", letra: overLimit},
+		{name: "empty letra", maxLength: "12", letra: "", wantAccepted: true},
+		{name: "zero limit falls back to the default", maxLength: "0", letra: overLimit, wantAccepted: true},
+		{name: "negative limit falls back to the default", maxLength: "-5", letra: overLimit, wantAccepted: true},
+		{name: "over the default limit", maxLength: "", letra: strings.Repeat("ç", defaultMaxLetraLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_LETRA_LENGTH", tt.maxLength)
+
+			written := false
+			repo := &fakeCancaoRepo{
+				getByID: func(id int) (*models.Cancao, error) { return &models.Cancao{ID: id, Nome: "Canção", UserID: 1}, nil },
+				create:  func(cancao *models.Cancao) (int, error) { written = true; return 3, nil },
+				update:  func(cancao *models.Cancao) error { written = true; return nil },
+			}
+			h := NewCancaoHandler(repo, &fakeLogger{})
+
+			body, _ := json.Marshal(map[string]string{"nome": "Canção", "letra": tt.letra})
+			for _, write := range []struct {
+				name       string
+				handle     func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+				params     map[string]string
+				wantStatus int
+			}{
+				{"create", h.CreateCancao, nil, http.StatusCreated},
+				{"update", h.UpdateCancao, map[string]string{"id": "3"}, http.StatusOK},
+			} {
+				written = false
+				response, err := write.handle(adminContext(), bodyRequest(string(body), write.params))
+				if err != nil {
+					t.Fatalf("%s: unexpected error: %v", write.name, err)
+				}
+
+				wantStatus := http.StatusUnprocessableEntity
+				if tt.wantAccepted {
+					wantStatus = write.wantStatus
+				}
+				if response.StatusCode != wantStatus {
+					t.Fatalf("%s: status = %d, want %d (body %s)", write.name, response.StatusCode, wantStatus, response.Body)
+				}
+				if written != tt.wantAccepted {
+					t.Errorf("%s: written = %v, want %v", write.name, written, tt.wantAccepted)
+				}
+			}
+		})
+	}
+}
+
+func TestAddAssociationToCancao(t *testing.T) {
+	tests := []struct {
+		name       string
+		add        func(h *CancaoHandler) (events.APIGatewayProxyResponse, error)
+		added      bool
+		wantStatus int
+		wantResult string
+	}{
+		{"new tag", func(h *CancaoHandler) (events.APIGatewayProxyResponse, error) {
+			return h.AddTagToCancao(adminContext(), bodyRequest(`{"tag_id": 1}`, map[string]string{"id": "1"}))
+		}, true, http.StatusCreated, "added"},
+		{"tag already associated", func(h *CancaoHandler) (events.APIGatewayProxyResponse, error) {
+			return h.AddTagToCancao(adminContext(), bodyRequest(`{"tag_id": 1}`, map[string]string{"id": "1"}))
+		}, false, http.StatusOK, "already_associated"},
+		{"new ramo", func(h *CancaoHandler) (events.APIGatewayProxyResponse, error) {
+			return h.AddRamoToCancao(adminContext(), bodyRequest(`{"ramo_id": 2}`, map[string]string{"id": "1"}))
+		}, true, http.StatusCreated, "added"},
+		{"ramo already associated", func(h *CancaoHandler) (events.APIGatewayProxyResponse, error) {
+			return h.AddRamoToCancao(adminContext(), bodyRequest(`{"ramo_id": 2}`, map[string]string{"id": "1"}))
+		}, false, http.StatusOK, "already_associated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeCancaoRepo{
+				addTag:  func(cancaoID, tagID int) (bool, error) { return tt.added, nil },
+				addRamo: func(cancaoID, ramoID int) (bool, error) { return tt.added, nil },
+			}
+			h := NewCancaoHandler(repo, &fakeLogger{})
+
+			response, err := tt.add(h)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			var body map[string]string
+			decodeBody(t, response, &body)
+			if body["status"] != tt.wantResult {
+				t.Errorf("status field = %q, want %q", body["status"], tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestListCancoesMissingYoutube(t *testing.T) {
+	tests := []struct {
+		name  string
+		query map[string]string
+		want  bool
+	}{
+		{"asked", map[string]string{"missing_youtube": "true"}, true},
+		{"not asked", nil, false},
+		{"false", map[string]string{"missing_youtube": "false"}, false},
+		{"other value", map[string]string{"missing_youtube": "1"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *bool
+			repo := &fakeCancaoRepo{
+				list: func(opts repository.CancaoListOptions) ([]*models.Cancao, error) {
+					got = &opts.MissingYoutube
+					return []*models.Cancao{}, nil
+				},
+				count: func(opts repository.CancaoListOptions) (int, error) {
+					if opts.MissingYoutube != tt.want {
+						t.Errorf("count missing_youtube = %v, want %v", opts.MissingYoutube, tt.want)
+					}
+					return 0, nil
+				},
+			}
+			h := NewCancaoHandler(repo, &fakeLogger{})
+
+			response, err := h.ListCancoes(context.Background(), queryRequest(tt.query))
+			if err != nil || response.StatusCode != http.StatusOK {
+				t.Fatalf("status %d, error %v: %s", response.StatusCode, err, response.Body)
+			}
+			if got == nil || *got != tt.want {
+				t.Errorf("repository missing_youtube = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"os"
+	"strconv"
+)
+
+// getEnvInt gets an integer environment variable or returns a default value
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvPositiveInt gets a positive integer environment variable, such as a
+// limit, or returns a default value when it is not set, not a number, or not
+// positive
+func getEnvPositiveInt(key string, defaultValue int) int {
+	value := getEnvInt(key, defaultValue)
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import "testing"
+
+func TestGetEnvPositiveInt(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{"not set", "", 10},
+		{"positive", "25", 25},
+		{"zero", "0", 10},
+		{"negative", "-3", 10},
+		{"not a number", "ten", 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_POSITIVE_INT", tt.value)
+			if got := getEnvPositiveInt("TEST_POSITIVE_INT", 10); got != tt.want {
+				t.Errorf("getEnvPositiveInt = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
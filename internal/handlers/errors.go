@@ -0,0 +1,97 @@
+package handlers
+
+import "net/http"
+
+// Error codes sent in the "code" field of error responses. Clients should
+// branch on these instead of on the error message.
+const (
+	CodeInvalidID        = "INVALID_ID"
+	CodeInvalidBody      = "INVALID_BODY"
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeNotFound         = "NOT_FOUND"
+	CodeConflict         = "CONFLICT"
+	CodeUnsupportedMedia = "UNSUPPORTED_MEDIA_TYPE"
+	CodeInternal         = "INTERNAL_ERROR"
+	CodeUpstream         = "UPSTREAM_ERROR"
+)
+
+// APIError is an error returned to the client with a stable code, a message and an HTTP status
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"error"`
+	Status  int    `json:"-"`
+
+	// cause is the underlying error, logged but never sent to the client
+	cause error
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e *APIError) Unwrap() error {
+	return e.cause
+}
+
+// invalidIDError creates an error for an ID that could not be parsed
+func invalidIDError(message string) *APIError {
+	return &APIError{Code: CodeInvalidID, Message: message, Status: http.StatusBadRequest}
+}
+
+// invalidBodyError creates an error for a request body that could not be decoded
+func invalidBodyError(message string) *APIError {
+	return &APIError{Code: CodeInvalidBody, Message: message, Status: http.StatusBadRequest}
+}
+
+// validationError creates an error for query or path parameters that failed validation
+func validationError(message string) *APIError {
+	return &APIError{Code: CodeValidationFailed, Message: message, Status: http.StatusBadRequest}
+}
+
+// unprocessableError creates an error for a well-formed request body whose
+// fields fail validation or business rules; a malformed body is an invalidBodyError
+func unprocessableError(message string) *APIError {
+	return &APIError{Code: CodeValidationFailed, Message: message, Status: http.StatusUnprocessableEntity}
+}
+
+// unauthorizedError creates an error for an unauthenticated request
+func unauthorizedError(message string) *APIError {
+	return &APIError{Code: CodeUnauthorized, Message: message, Status: http.StatusUnauthorized}
+}
+
+// forbiddenError creates an error for a user lacking the required access
+func forbiddenError(message string) *APIError {
+	return &APIError{Code: CodeForbidden, Message: message, Status: http.StatusForbidden}
+}
+
+// notFoundError creates an error for a missing resource
+func notFoundError(message string) *APIError {
+	return &APIError{Code: CodeNotFound, Message: message, Status: http.StatusNotFound}
+}
+
+// conflictError creates an error for a request conflicting with the current state
+func conflictError(message string) *APIError {
+	return &APIError{Code: CodeConflict, Message: message, Status: http.StatusConflict}
+}
+
+// unsupportedMediaTypeError creates an error for a request body in an unsupported format
+func unsupportedMediaTypeError(message string) *APIError {
+	return &APIError{Code: CodeUnsupportedMedia, Message: message, Status: http.StatusUnsupportedMediaType}
+}
+
+// upstreamError creates an error for a failure of an external service the request depends on
+func upstreamError(message string) *APIError {
+	return &APIError{Code: CodeUpstream, Message: message, Status: http.StatusBadGateway}
+}
+
+// internalError creates an error for an unexpected server failure
+func internalError(message string) *APIError {
+	return &APIError{Code: CodeInternal, Message: message, Status: http.StatusInternalServerError}
+}
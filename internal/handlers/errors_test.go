@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+func TestErrorConstructors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        *APIError
+		wantCode   string
+		wantStatus int
+	}{
+		{"invalid ID", invalidIDError("Invalid lugar ID"), CodeInvalidID, http.StatusBadRequest},
+		{"invalid body", invalidBodyError("Invalid request body"), CodeInvalidBody, http.StatusBadRequest},
+		{"validation", validationError("limit must be a positive integer"), CodeValidationFailed, http.StatusBadRequest},
+		{"unprocessable", unprocessableError("Nome local is required"), CodeValidationFailed, http.StatusUnprocessableEntity},
+		{"unauthorized", unauthorizedError("Authentication required"), CodeUnauthorized, http.StatusUnauthorized},
+		{"forbidden", forbiddenError("Admin access required"), CodeForbidden, http.StatusForbidden},
+		{"not found", notFoundError("Lugar not found"), CodeNotFound, http.StatusNotFound},
+		{"conflict", conflictError("Username already exists"), CodeConflict, http.StatusConflict},
+		{"unsupported media type", unsupportedMediaTypeError("Content-Type must be application/json"), CodeUnsupportedMedia, http.StatusUnsupportedMediaType},
+		{"upstream", upstreamError("Geocoding service unavailable"), CodeUpstream, http.StatusBadGateway},
+		{"internal", internalError("Error getting lugar"), CodeInternal, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := createErrorResponse(tt.err)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", response.StatusCode, tt.wantStatus)
+			}
+
+			var body APIError
+			decodeBody(t, response, &body)
+			if body.Code != tt.wantCode || body.Message != tt.err.Message {
+				t.Errorf("body = {code %q, error %q}, want {code %q, error %q}", body.Code, body.Message, tt.wantCode, tt.err.Message)
+			}
+		})
+	}
+}
+
+func TestAPIErrorHidesItsCause(t *testing.T) {
+	cause := errors.New("pq: password authentication failed")
+	apiErr := internalError("Error getting lugar")
+	apiErr.cause = cause
+
+	if !errors.Is(apiErr, cause) {
+		t.Error("the cause is not reachable through errors.Is")
+	}
+	response, _ := createErrorResponse(apiErr)
+	if strings.Contains(response.Body, "pq:") {
+		t.Errorf("the cause was sent to the client: %s", response.Body)
+	}
+}
+
+func TestGetLugarErrorCodes(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		getErr      error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{"invalid ID", "abc", nil, http.StatusBadRequest, CodeInvalidID, "Invalid lugar ID"},
+		{"missing lugar", "404", nil, http.StatusNotFound, CodeNotFound, "Lugar not found"},
+		{"repository error", "1", errors.New("pq: connection refused"), http.StatusInternalServerError, CodeInternal, "Error getting lugar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLugarRepo{
+				getByID: func(id int) (*models.Lugar, error) {
+					return nil, tt.getErr
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.GetLugar(context.Background(), pathRequest(map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+
+			var body APIError
+			decodeBody(t, response, &body)
+			if body.Code != tt.wantCode || body.Message != tt.wantMessage {
+				t.Errorf("body = {code %q, error %q}, want {code %q, error %q}", body.Code, body.Message, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
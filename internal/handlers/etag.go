@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// computeETag returns a strong ETag for a JSON response body. The body must
+// come from json.Marshal, which writes map keys in sorted order, so the same
+// content always hashes to the same ETag.
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// NotModified turns the successful response of a GET request into an empty
+// 304 when its ETag matches the If-None-Match header of the request. It must
+// run on the response as sent, after any change to its body.
+func NotModified(request events.APIGatewayProxyRequest, response events.APIGatewayProxyResponse) events.APIGatewayProxyResponse {
+	if request.HTTPMethod != http.MethodGet || response.StatusCode != http.StatusOK {
+		return response
+	}
+
+	etag := response.Headers["ETag"]
+	if etag == "" || !etagMatches(headerValue(request, "If-None-Match"), etag) {
+		return response
+	}
+
+	response.StatusCode = http.StatusNotModified
+	response.Body = ""
+	return response
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using the
+// weak comparison If-None-Match calls for
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
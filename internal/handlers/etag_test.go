@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestCreateJSONResponseETag(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		a, b     interface{}
+		wantSame bool
+		wantETag bool
+	}{
+		{"equal maps", http.StatusOK, map[string]int{"b": 2, "a": 1}, map[string]int{"a": 1, "b": 2}, true, true},
+		{"maps built in different orders", http.StatusOK, insertedInOrder("zeta", "alpha", "mid"), insertedInOrder("mid", "zeta", "alpha"), true, true},
+		{"different content", http.StatusOK, map[string]int{"a": 1}, map[string]int{"a": 2}, false, true},
+		{"no ETag on created", http.StatusCreated, map[string]int{"a": 1}, map[string]int{"a": 1}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := createJSONResponse(tt.status, tt.a)
+			b, _ := createJSONResponse(tt.status, tt.b)
+
+			etag, ok := a.Headers["ETag"]
+			if ok != tt.wantETag {
+				t.Fatalf("ETag present = %v, want %v", ok, tt.wantETag)
+			}
+			if (etag == b.Headers["ETag"]) != tt.wantSame {
+				t.Errorf("ETags %q and %q: same = %v, want %v", etag, b.Headers["ETag"], !tt.wantSame, tt.wantSame)
+			}
+			if ok && etag != computeETag([]byte(a.Body)) {
+				t.Errorf("ETag %q is not the hash of the body %s", etag, a.Body)
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	response, _ := createJSONResponse(http.StatusOK, map[string]int{"a": 1})
+	etag := response.Headers["ETag"]
+
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		response    events.APIGatewayProxyResponse
+		wantStatus  int
+	}{
+		{"matching ETag", "GET", etag, response, http.StatusNotModified},
+		{"ETag among others", "GET", `"other", ` + etag, response, http.StatusNotModified},
+		{"weak ETag", "GET", "W/" + etag, response, http.StatusNotModified},
+		{"any ETag", "GET", "*", response, http.StatusNotModified},
+		{"different ETag", "GET", `"other"`, response, http.StatusOK},
+		{"no If-None-Match", "GET", "", response, http.StatusOK},
+		{"not a GET", "POST", etag, response, http.StatusOK},
+		{"response without ETag", "GET", etag, events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: "{}"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := events.APIGatewayProxyRequest{HTTPMethod: tt.method, Headers: map[string]string{"if-none-match": tt.ifNoneMatch}}
+
+			got := NotModified(request, tt.response)
+			if got.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", got.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusNotModified {
+				if got.Body != "" {
+					t.Errorf("body = %q, want none", got.Body)
+				}
+				if got.Headers["ETag"] != etag {
+					t.Errorf("ETag = %q, want %q", got.Headers["ETag"], etag)
+				}
+			} else if got.Body != tt.response.Body {
+				t.Errorf("body = %q, want %q", got.Body, tt.response.Body)
+			}
+		})
+	}
+}
+
+// insertedInOrder builds a map by inserting the keys in the given order
+func insertedInOrder(keys ...string) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, key := range keys {
+		m[key] = len(key)
+	}
+	return m
+}
@@ -0,0 +1,616 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/geocoding"
+	"github.com/site-geav-api/internal/logger"
+	"github.com/site-geav-api/internal/models"
+	"github.com/site-geav-api/internal/repository"
+)
+
+// fakeLogEntry is a log call recorded by fakeLogger
+type fakeLogEntry struct {
+	level    logger.LogLevel
+	message  string
+	err      error
+	metadata map[string]interface{}
+}
+
+// fakeLogger records the log calls so tests can check what was logged
+type fakeLogger struct {
+	mu      sync.Mutex
+	entries []fakeLogEntry
+}
+
+// record stores a log call, with the fields bound to ctx by logger.With
+// merged into its metadata like the real loggers do
+func (l *fakeLogger) record(ctx context.Context, level logger.LogLevel, message string, err error, metadata []map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := fakeLogEntry{level: level, message: message, err: err, metadata: map[string]interface{}{}}
+	for key, value := range logger.GetFieldsFromContext(ctx) {
+		entry.metadata[key] = value
+	}
+	if len(metadata) > 0 {
+		for key, value := range metadata[0] {
+			entry.metadata[key] = value
+		}
+	}
+	l.entries = append(l.entries, entry)
+}
+
+func (l *fakeLogger) Debug(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	l.record(ctx, logger.DEBUG, message, nil, metadata)
+}
+
+func (l *fakeLogger) Info(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	l.record(ctx, logger.INFO, message, nil, metadata)
+}
+
+func (l *fakeLogger) Warn(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	l.record(ctx, logger.WARN, message, nil, metadata)
+}
+
+func (l *fakeLogger) Error(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
+	l.record(ctx, logger.ERROR, message, err, metadata)
+}
+
+func (l *fakeLogger) Fatal(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
+	l.record(ctx, logger.FATAL, message, err, metadata)
+}
+
+func (l *fakeLogger) Flush(ctx context.Context) {}
+
+// last returns the last recorded entry
+func (l *fakeLogger) last(t *testing.T) fakeLogEntry {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) == 0 {
+		t.Fatal("nothing was logged")
+	}
+	return l.entries[len(l.entries)-1]
+}
+
+// fakeLugarRepo is a LugarRepository whose methods are set per test; calling
+// a method that was not set panics through the nil embedded interface
+type fakeLugarRepo struct {
+	repository.LugarRepository
+
+	getByID            func(id int) (*models.Lugar, error)
+	list               func(opts repository.LugarListOptions) ([]*models.Lugar, error)
+	count              func(opts repository.LugarListOptions) (int, error)
+	listInBoundingBox  func(minLat, minLng, maxLat, maxLng float64, page repository.Pagination) ([]*models.Lugar, error)
+	countInBoundingBox func(minLat, minLng, maxLat, maxLng float64) (int, error)
+	findSimilar        func(nomeLocal, enderecoCompleto string) ([]*models.Lugar, error)
+	addImage           func(image *models.LugarImage, maxImages int) (int, error)
+	addImages          func(images []*models.LugarImage, maxImages int) error
+	countImages        func(lugarID int) (int, error)
+	getImageByID       func(lugarID, imageID int) (*models.LugarImage, error)
+	ratingDistribution func() (*models.RatingDistribution, error)
+	deleteRatingByUser func(lugarID, userID int) error
+	listByUser         func(userID int, page repository.Pagination) ([]*models.Lugar, error)
+	addRating          func(rating *models.LugarRating) (int, error)
+	updateRating       func(rating *models.LugarRating) error
+	changeOwner        func(id, userID int) error
+	recentRatings      func(limit int) ([]*models.RecentRating, error)
+	removeTags         func(lugarID int, tagIDs []int) (int, error)
+	exists             func(id int) (bool, error)
+	getRatings         func(lugarID int, page repository.Pagination) ([]*models.LugarRating, error)
+	countRatings       func(lugarID int) (int, error)
+	listSimilar        func(lugarID, limit int) ([]*models.SimilarLugar, error)
+	maxDisplayOrder    func(lugarID int) (int, error)
+	getByIDWithDeleted func(id int) (*models.Lugar, error)
+	importLugares      func(lugares []*models.Lugar) ([]models.LugarImportResult, error)
+	create             func(lugar *models.Lugar) (int, error)
+	missingTagIDs      func(tagIDs []int) ([]int, error)
+	missingRamoIDs     func(ramoIDs []int) ([]int, error)
+	listAllImages      func(page repository.Pagination) ([]*models.ImageWithLugar, error)
+	countAllImages     func() (int, error)
+	ratingsByValue     func(rating int, page repository.Pagination) ([]*models.RecentRating, error)
+	countByValue       func(rating int) (int, error)
+	touch              func(id int) (*time.Time, error)
+	publish            func(id int) error
+	selectOptions      func(page repository.Pagination) ([]*models.SelectOption, error)
+	getUserRating      func(lugarID, userID int) (*models.LugarRating, error)
+	addTag             func(lugarID, tagID int) (bool, error)
+	addRamo            func(lugarID, ramoID int) (bool, error)
+	ratingSummaries    func(ids []int) (map[int]*models.RatingSummary, error)
+	updateIfUnchanged  func(rating *models.LugarRating, expectedVersion int) error
+	dailyCounts        func(from, to time.Time) ([]*models.DailyCount, error)
+	deleteImage        func(imageID int, compact bool) error
+	listNearest        func(lat, lng float64, page repository.Pagination) ([]*models.Lugar, error)
+	countWithCoords    func() (int, error)
+	update             func(lugar *models.Lugar) error
+}
+
+func (f *fakeLugarRepo) Update(ctx context.Context, lugar *models.Lugar) error {
+	return f.update(lugar)
+}
+
+func (f *fakeLugarRepo) ListNearest(ctx context.Context, lat, lng float64, page repository.Pagination) ([]*models.Lugar, error) {
+	return f.listNearest(lat, lng, page)
+}
+
+func (f *fakeLugarRepo) CountWithCoordinates(ctx context.Context) (int, error) {
+	return f.countWithCoords()
+}
+
+func (f *fakeLugarRepo) DeleteImage(ctx context.Context, imageID int, compact bool) error {
+	return f.deleteImage(imageID, compact)
+}
+
+func (f *fakeLugarRepo) DailyCreationCounts(ctx context.Context, from, to time.Time) ([]*models.DailyCount, error) {
+	return f.dailyCounts(from, to)
+}
+
+func (f *fakeLugarRepo) UpdateRatingIfUnchanged(ctx context.Context, rating *models.LugarRating, expectedVersion int) error {
+	return f.updateIfUnchanged(rating, expectedVersion)
+}
+
+func (f *fakeLugarRepo) RatingSummaries(ctx context.Context, ids []int) (map[int]*models.RatingSummary, error) {
+	return f.ratingSummaries(ids)
+}
+
+func (f *fakeLugarRepo) AddTag(ctx context.Context, lugarID, tagID int) (bool, error) {
+	return f.addTag(lugarID, tagID)
+}
+
+func (f *fakeLugarRepo) AddRamo(ctx context.Context, lugarID, ramoID int) (bool, error) {
+	return f.addRamo(lugarID, ramoID)
+}
+
+func (f *fakeLugarRepo) GetUserRating(ctx context.Context, lugarID, userID int) (*models.LugarRating, error) {
+	return f.getUserRating(lugarID, userID)
+}
+
+func (f *fakeLugarRepo) ListSelectOptions(ctx context.Context, page repository.Pagination) ([]*models.SelectOption, error) {
+	return f.selectOptions(page)
+}
+
+func (f *fakeLugarRepo) Publish(ctx context.Context, id int) error {
+	return f.publish(id)
+}
+
+func (f *fakeLugarRepo) Touch(ctx context.Context, id int) (*time.Time, error) {
+	return f.touch(id)
+}
+
+func (f *fakeLugarRepo) GetByID(ctx context.Context, id int) (*models.Lugar, error) {
+	return f.getByID(id)
+}
+
+func (f *fakeLugarRepo) GetByIDIncludingDeleted(ctx context.Context, id int) (*models.Lugar, error) {
+	return f.getByIDWithDeleted(id)
+}
+
+func (f *fakeLugarRepo) Import(ctx context.Context, lugares []*models.Lugar) ([]models.LugarImportResult, error) {
+	return f.importLugares(lugares)
+}
+
+func (f *fakeLugarRepo) Create(ctx context.Context, lugar *models.Lugar) (int, error) {
+	return f.create(lugar)
+}
+
+func (f *fakeLugarRepo) MissingTagIDs(ctx context.Context, tagIDs []int) ([]int, error) {
+	return f.missingTagIDs(tagIDs)
+}
+
+func (f *fakeLugarRepo) MissingRamoIDs(ctx context.Context, ramoIDs []int) ([]int, error) {
+	return f.missingRamoIDs(ramoIDs)
+}
+
+func (f *fakeLugarRepo) List(ctx context.Context, opts repository.LugarListOptions) ([]*models.Lugar, error) {
+	return f.list(opts)
+}
+
+func (f *fakeLugarRepo) Count(ctx context.Context, opts repository.LugarListOptions) (int, error) {
+	return f.count(opts)
+}
+
+func (f *fakeLugarRepo) ListInBoundingBox(ctx context.Context, minLat, minLng, maxLat, maxLng float64, page repository.Pagination) ([]*models.Lugar, error) {
+	return f.listInBoundingBox(minLat, minLng, maxLat, maxLng, page)
+}
+
+func (f *fakeLugarRepo) CountInBoundingBox(ctx context.Context, minLat, minLng, maxLat, maxLng float64) (int, error) {
+	return f.countInBoundingBox(minLat, minLng, maxLat, maxLng)
+}
+
+func (f *fakeLugarRepo) FindSimilar(ctx context.Context, nomeLocal, enderecoCompleto string) ([]*models.Lugar, error) {
+	return f.findSimilar(nomeLocal, enderecoCompleto)
+}
+
+func (f *fakeLugarRepo) AddImage(ctx context.Context, image *models.LugarImage, maxImages int) (int, error) {
+	return f.addImage(image, maxImages)
+}
+
+func (f *fakeLugarRepo) AddImages(ctx context.Context, images []*models.LugarImage, maxImages int) error {
+	return f.addImages(images, maxImages)
+}
+
+func (f *fakeLugarRepo) CountImages(ctx context.Context, lugarID int) (int, error) {
+	return f.countImages(lugarID)
+}
+
+func (f *fakeLugarRepo) MaxDisplayOrder(ctx context.Context, lugarID int) (int, error) {
+	return f.maxDisplayOrder(lugarID)
+}
+
+func (f *fakeLugarRepo) GetImageByID(ctx context.Context, lugarID, imageID int) (*models.LugarImage, error) {
+	return f.getImageByID(lugarID, imageID)
+}
+
+func (f *fakeLugarRepo) DeleteRatingByUser(ctx context.Context, lugarID, userID int) error {
+	return f.deleteRatingByUser(lugarID, userID)
+}
+
+func (f *fakeLugarRepo) AddRating(ctx context.Context, rating *models.LugarRating) (int, error) {
+	return f.addRating(rating)
+}
+
+func (f *fakeLugarRepo) UpdateRating(ctx context.Context, rating *models.LugarRating) error {
+	return f.updateRating(rating)
+}
+
+func (f *fakeLugarRepo) ChangeOwner(ctx context.Context, id, userID int) error {
+	return f.changeOwner(id, userID)
+}
+
+func (f *fakeLugarRepo) RecentRatings(ctx context.Context, limit int) ([]*models.RecentRating, error) {
+	return f.recentRatings(limit)
+}
+
+func (f *fakeLugarRepo) RemoveTags(ctx context.Context, lugarID int, tagIDs []int) (int, error) {
+	return f.removeTags(lugarID, tagIDs)
+}
+
+func (f *fakeLugarRepo) Exists(ctx context.Context, id int) (bool, error) {
+	return f.exists(id)
+}
+
+func (f *fakeLugarRepo) GetRatings(ctx context.Context, lugarID int, page repository.Pagination) ([]*models.LugarRating, error) {
+	return f.getRatings(lugarID, page)
+}
+
+func (f *fakeLugarRepo) CountRatings(ctx context.Context, lugarID int) (int, error) {
+	return f.countRatings(lugarID)
+}
+
+func (f *fakeLugarRepo) ListSimilar(ctx context.Context, lugarID, limit int) ([]*models.SimilarLugar, error) {
+	return f.listSimilar(lugarID, limit)
+}
+
+func (f *fakeLugarRepo) ListByUser(ctx context.Context, userID int, page repository.Pagination) ([]*models.Lugar, error) {
+	return f.listByUser(userID, page)
+}
+
+func (f *fakeLugarRepo) GlobalRatingDistribution(ctx context.Context) (*models.RatingDistribution, error) {
+	return f.ratingDistribution()
+}
+
+func (f *fakeLugarRepo) ListAllImages(ctx context.Context, page repository.Pagination) ([]*models.ImageWithLugar, error) {
+	return f.listAllImages(page)
+}
+
+func (f *fakeLugarRepo) CountAllImages(ctx context.Context) (int, error) {
+	return f.countAllImages()
+}
+
+func (f *fakeLugarRepo) ListRatingsByValue(ctx context.Context, rating int, page repository.Pagination) ([]*models.RecentRating, error) {
+	return f.ratingsByValue(rating, page)
+}
+
+func (f *fakeLugarRepo) CountRatingsByValue(ctx context.Context, rating int) (int, error) {
+	return f.countByValue(rating)
+}
+
+// fakeCancaoRepo is a CancaoRepository whose methods are set per test
+type fakeCancaoRepo struct {
+	repository.CancaoRepository
+
+	getByID            func(id int) (*models.Cancao, error)
+	list               func(opts repository.CancaoListOptions) ([]*models.Cancao, error)
+	count              func(opts repository.CancaoListOptions) (int, error)
+	incrementPlayCount func(id int) (int, error)
+	listByUser         func(userID int, page repository.Pagination) ([]*models.Cancao, error)
+	forEach            func(afterID int, fn func(*models.Cancao) error) error
+	getByIDWithDeleted func(id int) (*models.Cancao, error)
+	selectOptions      func(page repository.Pagination) ([]*models.SelectOption, error)
+	listRelated        func(cancaoID, limit int) ([]*models.RelatedCancao, error)
+	create             func(cancao *models.Cancao) (int, error)
+	update             func(cancao *models.Cancao) error
+	addTag             func(cancaoID, tagID int) (bool, error)
+	addRamo            func(cancaoID, ramoID int) (bool, error)
+}
+
+func (f *fakeCancaoRepo) AddTag(ctx context.Context, cancaoID, tagID int) (bool, error) {
+	return f.addTag(cancaoID, tagID)
+}
+
+func (f *fakeCancaoRepo) AddRamo(ctx context.Context, cancaoID, ramoID int) (bool, error) {
+	return f.addRamo(cancaoID, ramoID)
+}
+
+func (f *fakeCancaoRepo) Create(ctx context.Context, cancao *models.Cancao) (int, error) {
+	return f.create(cancao)
+}
+
+func (f *fakeCancaoRepo) Update(ctx context.Context, cancao *models.Cancao) error {
+	return f.update(cancao)
+}
+
+func (f *fakeCancaoRepo) ListRelated(ctx context.Context, cancaoID, limit int) ([]*models.RelatedCancao, error) {
+	return f.listRelated(cancaoID, limit)
+}
+
+func (f *fakeCancaoRepo) ListSelectOptions(ctx context.Context, page repository.Pagination) ([]*models.SelectOption, error) {
+	return f.selectOptions(page)
+}
+
+func (f *fakeCancaoRepo) GetByID(ctx context.Context, id int) (*models.Cancao, error) {
+	return f.getByID(id)
+}
+
+func (f *fakeCancaoRepo) GetByIDIncludingDeleted(ctx context.Context, id int) (*models.Cancao, error) {
+	return f.getByIDWithDeleted(id)
+}
+
+func (f *fakeCancaoRepo) List(ctx context.Context, opts repository.CancaoListOptions) ([]*models.Cancao, error) {
+	return f.list(opts)
+}
+
+func (f *fakeCancaoRepo) Count(ctx context.Context, opts repository.CancaoListOptions) (int, error) {
+	return f.count(opts)
+}
+
+func (f *fakeCancaoRepo) IncrementPlayCount(ctx context.Context, id int) (int, error) {
+	return f.incrementPlayCount(id)
+}
+
+func (f *fakeCancaoRepo) ForEach(ctx context.Context, afterID int, fn func(*models.Cancao) error) error {
+	return f.forEach(afterID, fn)
+}
+
+func (f *fakeCancaoRepo) ListByUser(ctx context.Context, userID int, page repository.Pagination) ([]*models.Cancao, error) {
+	return f.listByUser(userID, page)
+}
+
+// fakeRamoRepo is a RamoRepository whose methods are set per test
+type fakeRamoRepo struct {
+	repository.RamoRepository
+
+	getByID       func(id int) (*models.Ramo, error)
+	create        func(ramo *models.Ramo) (int, error)
+	getOrCreate   func(ramo *models.Ramo) (*models.Ramo, bool, error)
+	coverage      func(threshold int) ([]*models.RamoCoverage, error)
+	list          func(page repository.Pagination) ([]*models.Ramo, error)
+	count         func() (int, error)
+	selectOptions func(page repository.Pagination) ([]*models.SelectOption, error)
+}
+
+func (f *fakeRamoRepo) ListSelectOptions(ctx context.Context, page repository.Pagination) ([]*models.SelectOption, error) {
+	return f.selectOptions(page)
+}
+
+func (f *fakeRamoRepo) List(ctx context.Context, page repository.Pagination) ([]*models.Ramo, error) {
+	return f.list(page)
+}
+
+func (f *fakeRamoRepo) Count(ctx context.Context) (int, error) {
+	return f.count()
+}
+
+func (f *fakeRamoRepo) GetByID(ctx context.Context, id int) (*models.Ramo, error) {
+	return f.getByID(id)
+}
+
+func (f *fakeRamoRepo) Create(ctx context.Context, ramo *models.Ramo) (int, error) {
+	return f.create(ramo)
+}
+
+func (f *fakeRamoRepo) GetOrCreate(ctx context.Context, ramo *models.Ramo) (*models.Ramo, bool, error) {
+	return f.getOrCreate(ramo)
+}
+
+func (f *fakeRamoRepo) Coverage(ctx context.Context, threshold int) ([]*models.RamoCoverage, error) {
+	return f.coverage(threshold)
+}
+
+// fakeTagLugarRepo is a TagLugarRepository whose methods are set per test
+type fakeTagLugarRepo struct {
+	repository.TagLugarRepository
+
+	create        func(tag *models.TagLugar) (int, error)
+	getOrCreate   func(tag *models.TagLugar) (*models.TagLugar, bool, error)
+	suggest       func(query string, limit int) ([]*models.TagLugar, error)
+	list          func(page repository.Pagination) ([]*models.TagLugar, error)
+	count         func() (int, error)
+	selectOptions func(page repository.Pagination) ([]*models.SelectOption, error)
+	unassigned    func(lugarID int, page repository.Pagination) ([]*models.TagLugar, error)
+}
+
+func (f *fakeTagLugarRepo) ListUnassigned(ctx context.Context, lugarID int, page repository.Pagination) ([]*models.TagLugar, error) {
+	return f.unassigned(lugarID, page)
+}
+
+func (f *fakeTagLugarRepo) ListSelectOptions(ctx context.Context, page repository.Pagination) ([]*models.SelectOption, error) {
+	return f.selectOptions(page)
+}
+
+func (f *fakeTagLugarRepo) List(ctx context.Context, page repository.Pagination) ([]*models.TagLugar, error) {
+	return f.list(page)
+}
+
+func (f *fakeTagLugarRepo) Count(ctx context.Context) (int, error) {
+	return f.count()
+}
+
+func (f *fakeTagLugarRepo) Create(ctx context.Context, tag *models.TagLugar) (int, error) {
+	return f.create(tag)
+}
+
+func (f *fakeTagLugarRepo) GetOrCreate(ctx context.Context, tag *models.TagLugar) (*models.TagLugar, bool, error) {
+	return f.getOrCreate(tag)
+}
+
+func (f *fakeTagLugarRepo) Suggest(ctx context.Context, query string, limit int) ([]*models.TagLugar, error) {
+	return f.suggest(query, limit)
+}
+
+// fakeTagCancaoRepo is a TagCancaoRepository whose methods are set per test
+type fakeTagCancaoRepo struct {
+	repository.TagCancaoRepository
+
+	list  func(page repository.Pagination) ([]*models.TagCancao, error)
+	count func() (int, error)
+}
+
+func (f *fakeTagCancaoRepo) List(ctx context.Context, page repository.Pagination) ([]*models.TagCancao, error) {
+	return f.list(page)
+}
+
+func (f *fakeTagCancaoRepo) Count(ctx context.Context) (int, error) {
+	return f.count()
+}
+
+// fakeAuditRepo is an AuditRepository whose methods are set per test
+type fakeAuditRepo struct {
+	listHistory func(resource string, resourceID int) ([]*models.AuditEntry, error)
+}
+
+func (f *fakeAuditRepo) ListHistory(ctx context.Context, resource string, resourceID int) ([]*models.AuditEntry, error) {
+	return f.listHistory(resource, resourceID)
+}
+
+// fakeUserRepo is a UserRepository whose methods are set per test
+type fakeUserRepo struct {
+	repository.UserRepository
+
+	getByID            func(id int) (*models.User, error)
+	listCreatedBetween func(from, to time.Time, limit, offset int) ([]*models.User, error)
+	create             func(user *models.User) (int, error)
+	update             func(user *models.User) error
+	getWithCounts      func(id int) (*models.UserWithCounts, error)
+	anonymize          func(id int) (*models.User, error)
+	getByUsername      func(username string) (*models.User, error)
+}
+
+func (f *fakeUserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return f.getByUsername(username)
+}
+
+func (f *fakeUserRepo) Anonymize(ctx context.Context, id int) (*models.User, error) {
+	return f.anonymize(id)
+}
+
+func (f *fakeUserRepo) GetWithCounts(ctx context.Context, id int) (*models.UserWithCounts, error) {
+	return f.getWithCounts(id)
+}
+
+func (f *fakeUserRepo) Create(ctx context.Context, user *models.User) (int, error) {
+	return f.create(user)
+}
+
+func (f *fakeUserRepo) Update(ctx context.Context, user *models.User) error {
+	return f.update(user)
+}
+
+func (f *fakeUserRepo) GetByID(ctx context.Context, id int) (*models.User, error) {
+	return f.getByID(id)
+}
+
+func (f *fakeUserRepo) ListCreatedBetween(ctx context.Context, from, to time.Time, limit, offset int) ([]*models.User, error) {
+	return f.listCreatedBetween(from, to, limit, offset)
+}
+
+// fakeLogRepo is a LogRepository whose methods are set per test
+type fakeLogRepo struct {
+	repository.LogRepository
+
+	listRecent   func(level string, limit, offset int) ([]*models.LogRecord, error)
+	countByLevel func(level string) (int, error)
+	purgeBefore  func(before time.Time) (int, bool, error)
+}
+
+func (f *fakeLogRepo) PurgeBefore(ctx context.Context, before time.Time) (int, bool, error) {
+	return f.purgeBefore(before)
+}
+
+func (f *fakeLogRepo) ListRecent(ctx context.Context, level string, limit, offset int) ([]*models.LogRecord, error) {
+	return f.listRecent(level, limit, offset)
+}
+
+func (f *fakeLogRepo) CountByLevel(ctx context.Context, level string) (int, error) {
+	return f.countByLevel(level)
+}
+
+// fakeGeocoder is a Geocoder returning fixed results
+type fakeGeocoder struct {
+	locations map[string]*geocoding.Location
+	err       error
+}
+
+func (g *fakeGeocoder) Geocode(ctx context.Context, query string) (*geocoding.Location, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	return g.locations[query], nil
+}
+
+// adminContext returns a context authenticated as an admin (a user with write access)
+func adminContext() context.Context {
+	return userContext(1, "write")
+}
+
+// userContext returns a context authenticated as the given user
+func userContext(userID int, role string) context.Context {
+	ctx := context.WithValue(context.Background(), "userID", userID)
+	return context.WithValue(ctx, "userRole", role)
+}
+
+// pathRequest builds a request with the given path parameters
+func pathRequest(params map[string]string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{PathParameters: params}
+}
+
+// bodyRequest builds a request with a JSON body and the given path parameters
+func bodyRequest(body string, params map[string]string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		Headers:        map[string]string{"Content-Type": "application/json"},
+		Body:           body,
+		PathParameters: params,
+	}
+}
+
+// queryRequest builds a request with the given query parameters
+func queryRequest(params map[string]string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{QueryStringParameters: params}
+}
+
+// decodeBody decodes the JSON body of a response into v
+func decodeBody(t *testing.T, response events.APIGatewayProxyResponse, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(response.Body), v); err != nil {
+		t.Fatalf("decoding body %q: %v", response.Body, err)
+	}
+}
+
+// errorCode returns the code of an error response
+func errorCode(t *testing.T, response events.APIGatewayProxyResponse) string {
+	t.Helper()
+	var apiErr APIError
+	decodeBody(t, response, &apiErr)
+	return apiErr.Code
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HeadResponse turns the response of a GET request into the response of the
+// matching HEAD request: same status and headers, with the Content-Length of
+// the GET body and no body
+func HeadResponse(response events.APIGatewayProxyResponse) events.APIGatewayProxyResponse {
+	headers := make(map[string]string, len(response.Headers)+1)
+	for key, value := range response.Headers {
+		headers[key] = value
+	}
+	headers["Content-Length"] = strconv.Itoa(len(response.Body))
+
+	response.Headers = headers
+	response.Body = ""
+	return response
+}
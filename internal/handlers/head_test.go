@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+func TestHeadResponse(t *testing.T) {
+	repo := &fakeLugarRepo{
+		getByID: func(id int) (*models.Lugar, error) {
+			if id != 1 {
+				return nil, nil
+			}
+			return &models.Lugar{ID: 1, NomeLocal: "Sitio Alegre", Published: true}, nil
+		},
+	}
+	h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+	tests := []struct {
+		name       string
+		id         string
+		wantStatus int
+		wantETag   bool
+	}{
+		{"existing lugar", "1", http.StatusOK, true},
+		{"missing lugar", "2", http.StatusNotFound, false},
+		{"invalid ID", "x", http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			get, err := h.GetLugar(context.Background(), pathRequest(map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			getBody := get.Body
+
+			head := HeadResponse(get)
+			if head.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", head.StatusCode, tt.wantStatus)
+			}
+			if head.Body != "" {
+				t.Errorf("HEAD response has a body: %q", head.Body)
+			}
+			if got, want := head.Headers["Content-Length"], strconv.Itoa(len(getBody)); got != want {
+				t.Errorf("Content-Length = %q, want %q", got, want)
+			}
+			if _, ok := head.Headers["ETag"]; ok != tt.wantETag {
+				t.Errorf("ETag present = %v, want %v", ok, tt.wantETag)
+			}
+			if head.Headers["Content-Type"] != get.Headers["Content-Type"] || head.Headers["Cache-Control"] != get.Headers["Cache-Control"] {
+				t.Errorf("headers %v differ from the GET headers %v", head.Headers, get.Headers)
+			}
+			if _, ok := get.Headers["Content-Length"]; ok {
+				t.Error("the GET response headers were changed")
+			}
+		})
+	}
+}
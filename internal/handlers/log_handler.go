@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/logger"
+	"github.com/site-geav-api/internal/repository"
+)
+
+// logLevels are the levels accepted by the level filter of GET /admin/logs
+var logLevels = map[string]bool{
+	string(logger.DEBUG): true,
+	string(logger.INFO):  true,
+	string(logger.WARN):  true,
+	string(logger.ERROR): true,
+	string(logger.FATAL): true,
+}
+
+// minLogPurgeAge is how old the cutoff of DELETE /admin/logs must be at least,
+// so the logs of the last day are kept for investigating recent problems
+const minLogPurgeAge = 24 * time.Hour
+
+// LogHandler handles the admin requests on the API logs stored in the database
+type LogHandler struct {
+	logRepo repository.LogRepository
+	log     logger.Logger
+}
+
+// NewLogHandler creates a new LogHandler
+func NewLogHandler(logRepo repository.LogRepository, log logger.Logger) *LogHandler {
+	return &LogHandler{
+		logRepo: logRepo,
+		log:     log,
+	}
+}
+
+// ListLogs handles GET /admin/logs?level=&limit=&offset= requests
+func (h *LogHandler) ListLogs(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Add the action and resource to every log entry
+	ctx = logger.With(ctx, map[string]interface{}{"action": "ListLogs", "resource": "logs"})
+
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized logs request")
+		return response, nil
+	}
+
+	// Validate level
+	level := strings.ToUpper(strings.TrimSpace(request.QueryStringParameters["level"]))
+	if level != "" && !logLevels[level] {
+		h.log.Warn(ctx, "Invalid log level", map[string]interface{}{
+			"level": level,
+		})
+		return createErrorResponse(validationError("level must be DEBUG, INFO, WARN, ERROR or FATAL"))
+	}
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err)
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get logs from repository
+	records, err := h.logRepo.ListRecent(ctx, level, limit, offset)
+	if err != nil {
+		h.log.Error(ctx, "Error listing logs", err)
+		return createErrorResponse(internalError("Error listing logs"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Logs listed successfully", map[string]interface{}{
+		"level": level,
+		"count": len(records),
+	})
+
+	// Return logs as JSON
+	return markPrivate(createPaginatedResponse(ctx, h.log, request, records, len(records), limit, offset, "logs", func() (int, error) {
+		return h.logRepo.CountByLevel(ctx, level)
+	}))
+}
+
+// PurgeLogs handles DELETE /admin/logs?before= requests, deleting the log
+// entries older than the given time (RFC3339 or YYYY-MM-DD)
+func (h *LogHandler) PurgeLogs(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Add the action and resource to every log entry
+	ctx = logger.With(ctx, map[string]interface{}{"action": "PurgeLogs", "resource": "logs"})
+
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized logs purge request")
+		return response, nil
+	}
+
+	// Validate cutoff
+	value := request.QueryStringParameters["before"]
+	if value == "" {
+		h.log.Warn(ctx, "Invalid logs purge: before is required")
+		return createErrorResponse(validationError("before is required"))
+	}
+	before, err := parseTimeParam(value)
+	if err != nil {
+		h.log.Warn(ctx, "Invalid logs purge cutoff", map[string]interface{}{
+			"before": value,
+		})
+		return createErrorResponse(validationError("before must be an RFC3339 time or a YYYY-MM-DD date"))
+	}
+	if time.Since(before) < minLogPurgeAge {
+		h.log.Warn(ctx, "Logs purge cutoff too recent", map[string]interface{}{
+			"before": before.Format(time.RFC3339),
+		})
+		return createErrorResponse(validationError("before must be at least one day ago"))
+	}
+
+	// Delete old logs
+	removed, more, err := h.logRepo.PurgeBefore(ctx, before)
+	if err != nil {
+		h.log.Error(ctx, "Error purging logs", err, map[string]interface{}{
+			"before":  before.Format(time.RFC3339),
+			"removed": removed,
+		})
+		return createErrorResponse(internalError("Error purging logs"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Logs purged successfully", map[string]interface{}{
+		"before":  before.Format(time.RFC3339),
+		"removed": removed,
+		"more":    more,
+	})
+
+	// Return removed count as JSON, with whether older entries remain
+	return createJSONResponse(http.StatusOK, map[string]interface{}{"removed": removed, "more": more})
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+func TestListLogs(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		query      map[string]string
+		wantStatus int
+		wantLevel  string
+		wantLimit  int
+		wantOffset int
+	}{
+		{"errors", adminContext(), map[string]string{"level": "ERROR", "limit": "50"}, http.StatusOK, "ERROR", 50, 0},
+		{"lowercase level", adminContext(), map[string]string{"level": " warn "}, http.StatusOK, "WARN", 100, 0},
+		{"every level", adminContext(), map[string]string{"offset": "20"}, http.StatusOK, "", 100, 20},
+		{"unknown level", adminContext(), map[string]string{"level": "TRACE"}, http.StatusBadRequest, "", 0, 0},
+		{"invalid limit", adminContext(), map[string]string{"limit": "muitos"}, http.StatusBadRequest, "", 0, 0},
+		{"not an admin", userContext(2, "read"), map[string]string{"level": "ERROR"}, http.StatusForbidden, "", 0, 0},
+		{"anonymous", context.Background(), map[string]string{"level": "ERROR"}, http.StatusUnauthorized, "", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			var gotLevel string
+			var gotLimit, gotOffset int
+			repo := &fakeLogRepo{
+				listRecent: func(level string, limit, offset int) ([]*models.LogRecord, error) {
+					called = true
+					gotLevel, gotLimit, gotOffset = level, limit, offset
+					return []*models.LogRecord{{ID: 2, Level: "ERROR", Message: "boom"}}, nil
+				},
+				countByLevel: func(level string) (int, error) { return 1, nil },
+			}
+			h := NewLogHandler(repo, &fakeLogger{})
+
+			response, err := h.ListLogs(tt.ctx, queryRequest(tt.query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if called {
+					t.Error("the repository was called for a rejected request")
+				}
+				return
+			}
+
+			if gotLevel != tt.wantLevel || gotLimit != tt.wantLimit || gotOffset != tt.wantOffset {
+				t.Errorf("repository called with level %q, limit %d, offset %d; want %q, %d, %d", gotLevel, gotLimit, gotOffset, tt.wantLevel, tt.wantLimit, tt.wantOffset)
+			}
+			if cacheControl := response.Headers["Cache-Control"]; cacheControl != privateCacheControl {
+				t.Errorf("Cache-Control = %q, want %q", cacheControl, privateCacheControl)
+			}
+			var records []models.LogRecord
+			decodeBody(t, response, &records)
+			if len(records) != 1 || records[0].Message != "boom" {
+				t.Errorf("records = %+v, want the repository records", records)
+			}
+		})
+	}
+}
+
+func TestPurgeLogs(t *testing.T) {
+	now := time.Now().UTC()
+	twoDaysAgo := now.Add(-48 * time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		before      string
+		removed     int
+		more        bool
+		repoErr     error
+		wantStatus  int
+		wantRemoved int
+		wantMore    bool
+	}{
+		{name: "old cutoff", ctx: adminContext(), before: twoDaysAgo, removed: 42, wantStatus: http.StatusOK, wantRemoved: 42},
+		{name: "old date", ctx: adminContext(), before: "2024-01-01", removed: 7, wantStatus: http.StatusOK, wantRemoved: 7},
+		{name: "more to purge", ctx: adminContext(), before: twoDaysAgo, removed: 100000, more: true, wantStatus: http.StatusOK, wantRemoved: 100000, wantMore: true},
+		{name: "cutoff of an hour ago", ctx: adminContext(), before: now.Add(-time.Hour).Format(time.RFC3339), wantStatus: http.StatusBadRequest},
+		{name: "cutoff just under a day ago", ctx: adminContext(), before: now.Add(-23 * time.Hour).Format(time.RFC3339), wantStatus: http.StatusBadRequest},
+		{name: "cutoff in the future", ctx: adminContext(), before: now.AddDate(1, 0, 0).Format("2006-01-02"), wantStatus: http.StatusBadRequest},
+		{name: "no cutoff", ctx: adminContext(), wantStatus: http.StatusBadRequest},
+		{name: "invalid cutoff", ctx: adminContext(), before: "ontem", wantStatus: http.StatusBadRequest},
+		{name: "repository error", ctx: adminContext(), before: twoDaysAgo, repoErr: errors.New("boom"), wantStatus: http.StatusInternalServerError},
+		{name: "not an admin", ctx: userContext(2, "read"), before: twoDaysAgo, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			repo := &fakeLogRepo{
+				purgeBefore: func(before time.Time) (int, bool, error) {
+					called = true
+					return tt.removed, tt.more, tt.repoErr
+				},
+			}
+			h := NewLogHandler(repo, &fakeLogger{})
+
+			query := map[string]string{}
+			if tt.before != "" {
+				query["before"] = tt.before
+			}
+			response, err := h.PurgeLogs(tt.ctx, queryRequest(query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus == http.StatusBadRequest || tt.wantStatus == http.StatusForbidden {
+				if called {
+					t.Error("the repository was called for a rejected request")
+				}
+				return
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Removed int  `json:"removed"`
+				More    bool `json:"more"`
+			}
+			decodeBody(t, response, &body)
+			if body.Removed != tt.wantRemoved || body.More != tt.wantMore {
+				t.Errorf("body = %+v, want removed %d and more %v", body, tt.wantRemoved, tt.wantMore)
+			}
+		})
+	}
+}
@@ -2,29 +2,48 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/site-geav-api/internal/geocoding"
 	"github.com/site-geav-api/internal/logger"
 	"github.com/site-geav-api/internal/models"
 	"github.com/site-geav-api/internal/repository"
 )
 
+// defaultMaxImagesPerLugar is used when MAX_IMAGES_PER_LUGAR is not set or not positive
+const defaultMaxImagesPerLugar = 10
+
+// lugarUntrackedFields are the lugar fields left out of the logged and audited changes
+var lugarUntrackedFields = []string{"id", "created_at", "updated_at", "deleted_at", "images", "tags", "ramos", "average_rating", "rating_count"}
+
 // LugarHandler handles place-related requests
 type LugarHandler struct {
-	lugarRepo repository.LugarRepository
-	log       logger.Logger
+	lugarRepo         repository.LugarRepository
+	ramoRepo          repository.RamoRepository
+	auditRepo         repository.AuditRepository
+	geocoder          geocoding.Geocoder
+	log               logger.Logger
+	maxImagesPerLugar int
 }
 
 // NewLugarHandler creates a new LugarHandler
-func NewLugarHandler(lugarRepo repository.LugarRepository, log logger.Logger) *LugarHandler {
+func NewLugarHandler(lugarRepo repository.LugarRepository, ramoRepo repository.RamoRepository, auditRepo repository.AuditRepository, geocoder geocoding.Geocoder, log logger.Logger) *LugarHandler {
 	return &LugarHandler{
-		lugarRepo: lugarRepo,
-		log:       log,
+		lugarRepo:         lugarRepo,
+		ramoRepo:          ramoRepo,
+		auditRepo:         auditRepo,
+		geocoder:          geocoder,
+		log:               log,
+		maxImagesPerLugar: getEnvPositiveInt("MAX_IMAGES_PER_LUGAR", defaultMaxImagesPerLugar),
 	}
 }
 
@@ -37,28 +56,33 @@ func (h *LugarHandler) GetLugar(ctx context.Context, request events.APIGatewayPr
 			"action":   "GetLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
-	// Get lugar from repository
-	lugar, err := h.lugarRepo.GetByID(ctx, lugarID)
+	// Get lugar from repository; admins may ask for a deleted one
+	getByID := h.lugarRepo.GetByID
+	withDeleted := includeDeleted(ctx, request)
+	if withDeleted {
+		getByID = h.lugarRepo.GetByIDIncludingDeleted
+	}
+	lugar, err := getByID(ctx, lugarID)
 	if err != nil {
 		h.log.Error(ctx, "Error getting lugar", err, map[string]interface{}{
 			"action":      "GetLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error getting lugar")
+		return createErrorResponse(internalError("Error getting lugar"))
 	}
 
-	// If lugar not found
-	if lugar == nil {
+	// If lugar not found; a lugar that was not published is only shown to its owner and admins
+	if lugar == nil || (!lugar.Published && !canSeeUnpublished(ctx, lugar.UserID)) {
 		h.log.Warn(ctx, "Lugar not found", map[string]interface{}{
 			"action":      "GetLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusNotFound, "Lugar not found")
+		return createErrorResponse(notFoundError("Lugar not found"))
 	}
 
 	// Log success
@@ -68,307 +92,1577 @@ func (h *LugarHandler) GetLugar(ctx context.Context, request events.APIGatewayPr
 		"resource_id": fmt.Sprintf("%d", lugarID),
 	})
 
-	// Return lugar as JSON
-	return createJSONResponse(http.StatusOK, lugar)
+	// Add the formatted phone when asked to
+	if request.QueryStringParameters["format_phone"] == "true" {
+		formatPhones(lugar)
+	}
+
+	// Return lugar as JSON; deleted and unpublished lugares are only shown to
+	// some callers, so they must not be cached
+	response, err := createCachedJSONResponse(http.StatusOK, lugar, "lugares")
+	if withDeleted || !lugar.Published {
+		return markPrivate(response, err)
+	}
+	return response, err
 }
 
 // ListLugares handles GET /lugares requests
 func (h *LugarHandler) ListLugares(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Validate sort parameter
+	sort := request.QueryStringParameters["sort"]
+	if !repository.IsValidLugarSort(sort) {
+		h.log.Warn(ctx, "Invalid sort value", map[string]interface{}{
+			"action":   "ListLugares",
+			"resource": "lugares",
+			"sort":     sort,
+		})
+		return createErrorResponse(validationError("Invalid sort value"))
+	}
+
+	// Parse ramo filter
+	ramoIDs, err := parseIntListParam(request, "ramo_id")
+	if err != nil {
+		h.log.Error(ctx, "Invalid ramo ID", err, map[string]interface{}{
+			"action":   "ListLugares",
+			"resource": "lugares",
+		})
+		return createErrorResponse(invalidIDError("Invalid ramo ID"))
+	}
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListLugares",
+			"resource": "lugares",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Parse budget filter
+	var maxValorIndividual *float64
+	if value := request.QueryStringParameters["max_valor_individual"]; value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+			h.log.Warn(ctx, "Invalid max_valor_individual", map[string]interface{}{
+				"action":   "ListLugares",
+				"resource": "lugares",
+				"value":    value,
+			})
+			return createErrorResponse(validationError("max_valor_individual must be a non-negative number"))
+		}
+		maxValorIndividual = &parsed
+	}
+
+	// Restrict to the lugares the caller can edit when asked: their own, or all
+	// for admins. Unpublished lugares are only listed for admins and in the
+	// editable list.
+	var ownerID int
+	editable := request.QueryStringParameters["editable"] == "true"
+	user := currentUser(ctx)
+	includeUnpublished := editable || (user != nil && user.HasWriteAccess())
+	if editable {
+		if user == nil {
+			h.log.Warn(ctx, "Unauthenticated editable lugares request", map[string]interface{}{
+				"action":   "ListLugares",
+				"resource": "lugares",
+			})
+			return createErrorResponse(unauthorizedError("Authentication required"))
+		}
+		if !user.HasWriteAccess() {
+			ownerID = user.ID
+		}
+	}
+
 	// Get lugares from repository
-	lugares, err := h.lugarRepo.List(ctx)
+	opts := repository.LugarListOptions{
+		RamoIDs:            ramoIDs,
+		Unrated:            request.QueryStringParameters["unrated"] == "true",
+		Address:            strings.TrimSpace(request.QueryStringParameters["endereco_q"]),
+		MaxValorIndividual: maxValorIndividual,
+		UserID:             ownerID,
+		IncludeDeleted:     includeDeleted(ctx, request),
+		IncludeUnpublished: includeUnpublished,
+		Sort:               sort,
+		Pagination:         repository.Pagination{Limit: limit, Offset: offset},
+	}
+	lugares, err := h.lugarRepo.List(ctx, opts)
 	if err != nil {
 		h.log.Error(ctx, "Error listing lugares", err, map[string]interface{}{
 			"action":   "ListLugares",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error listing lugares")
+		return createErrorResponse(internalError("Error listing lugares"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugares listed successfully", map[string]interface{}{
+		"action":   "ListLugares",
+		"resource": "lugares",
+		"count":    len(lugares),
+	})
+
+	// Add the formatted phones when asked to
+	if request.QueryStringParameters["format_phone"] == "true" {
+		formatPhones(lugares...)
+	}
+
+	// Return lugares as JSON; the editable list and the ones with deleted or
+	// unpublished lugares depend on the caller, so they are not cached
+	response, err := createPaginatedResponse(ctx, h.log, request, lugares, len(lugares), limit, offset, "lugares", func() (int, error) {
+		return h.lugarRepo.Count(ctx, opts)
+	})
+	if editable || opts.IncludeDeleted || opts.IncludeUnpublished {
+		return markPrivate(response, err)
+	}
+	return response, err
+}
+
+// formatPhones sets the telefone_formatado of the given lugares; the stored phone is not changed
+func formatPhones(lugares ...*models.Lugar) {
+	for _, lugar := range lugares {
+		lugar.TelefoneFormatado = models.FormatPhone(lugar.TelefoneParaContato)
+	}
+}
+
+// maxDailyStatsDays is the largest number of days GET /lugares/stats/daily covers
+const maxDailyStatsDays = 366
+
+// GetDailyLugarStats handles GET /lugares/stats/daily?from=&to= requests,
+// returning the number of lugares created on each day of the range
+func (h *LugarHandler) GetDailyLugarStats(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized daily stats request", map[string]interface{}{
+			"action":   "GetDailyLugarStats",
+			"resource": "lugares",
+		})
+		return response, nil
+	}
+
+	// Parse date range, defaulting to the last 30 days
+	to := time.Now().UTC()
+	if value := request.QueryStringParameters["to"]; value != "" {
+		parsed, err := parseTimeParam(value)
+		if err != nil {
+			h.log.Error(ctx, "Invalid to", err, map[string]interface{}{
+				"action":   "GetDailyLugarStats",
+				"resource": "lugares",
+			})
+			return createErrorResponse(validationError("Invalid to"))
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -29)
+	if value := request.QueryStringParameters["from"]; value != "" {
+		parsed, err := parseTimeParam(value)
+		if err != nil {
+			h.log.Error(ctx, "Invalid from", err, map[string]interface{}{
+				"action":   "GetDailyLugarStats",
+				"resource": "lugares",
+			})
+			return createErrorResponse(validationError("Invalid from"))
+		}
+		from = parsed
+	}
+
+	// Validate date range
+	if to.Before(from) {
+		h.log.Warn(ctx, "Invalid date range", map[string]interface{}{
+			"action":   "GetDailyLugarStats",
+			"resource": "lugares",
+		})
+		return createErrorResponse(validationError("from must not be after to"))
+	}
+	if to.Sub(from) >= maxDailyStatsDays*24*time.Hour {
+		h.log.Warn(ctx, "Date range too large", map[string]interface{}{
+			"action":   "GetDailyLugarStats",
+			"resource": "lugares",
+			"from":     from.Format(time.RFC3339),
+			"to":       to.Format(time.RFC3339),
+		})
+		return createErrorResponse(validationError(fmt.Sprintf("The date range must cover at most %d days", maxDailyStatsDays)))
+	}
+
+	// Get daily counts from repository
+	days, err := h.lugarRepo.DailyCreationCounts(ctx, from, to)
+	if err != nil {
+		h.log.Error(ctx, "Error counting lugares per day", err, map[string]interface{}{
+			"action":   "GetDailyLugarStats",
+			"resource": "lugares",
+		})
+		return createErrorResponse(internalError("Error getting daily lugar stats"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Daily lugar stats retrieved successfully", map[string]interface{}{
+		"action":   "GetDailyLugarStats",
+		"resource": "lugares",
+		"count":    len(days),
+	})
+
+	// Return daily counts as JSON
+	return createJSONResponse(http.StatusOK, days)
+}
+
+// ListLugarOptions handles GET /lugares/options requests, returning only the ID and name of
+// every lugar for select inputs
+func (h *LugarHandler) ListLugarOptions(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListLugarOptions",
+			"resource": "lugares",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get options from repository
+	ctx = withViewer(ctx)
+	options, err := h.lugarRepo.ListSelectOptions(ctx, repository.Pagination{Limit: limit, Offset: offset})
+	if err != nil {
+		h.log.Error(ctx, "Error listing lugar options", err, map[string]interface{}{
+			"action":   "ListLugarOptions",
+			"resource": "lugares",
+		})
+		return createErrorResponse(internalError("Error listing lugar options"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugar options listed successfully", map[string]interface{}{
+		"action":   "ListLugarOptions",
+		"resource": "lugares",
+		"count":    len(options),
+	})
+
+	// Return options as JSON; signed in callers also see their unpublished
+	// lugares, so their responses are not cached
+	response, err := createCachedJSONResponse(http.StatusOK, options, "lugares")
+	if currentUser(ctx) != nil {
+		return markPrivate(response, err)
+	}
+	return response, err
+}
+
+// ListLugaresInBoundingBox handles GET /lugares/bbox requests
+func (h *LugarHandler) ListLugaresInBoundingBox(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Parse bounding box from query parameters
+	bounds := make(map[string]float64, 4)
+	for _, name := range []string{"min_lat", "min_lng", "max_lat", "max_lng"} {
+		value, err := strconv.ParseFloat(request.QueryStringParameters[name], 64)
+		if err != nil {
+			h.log.Error(ctx, "Invalid bounding box", err, map[string]interface{}{
+				"action":    "ListLugaresInBoundingBox",
+				"resource":  "lugares",
+				"parameter": name,
+			})
+			return createErrorResponse(validationError(fmt.Sprintf("Invalid %s", name)))
+		}
+		bounds[name] = value
+	}
+
+	// Validate bounding box
+	if bounds["min_lat"] >= bounds["max_lat"] || bounds["min_lng"] >= bounds["max_lng"] {
+		h.log.Warn(ctx, "Invalid bounding box: min must be lower than max", map[string]interface{}{
+			"action":   "ListLugaresInBoundingBox",
+			"resource": "lugares",
+		})
+		return createErrorResponse(validationError("min_lat and min_lng must be lower than max_lat and max_lng"))
+	}
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListLugaresInBoundingBox",
+			"resource": "lugares",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+	page := repository.Pagination{Limit: limit, Offset: offset}
+
+	// Get lugares from repository
+	ctx = withViewer(ctx)
+	lugares, err := h.lugarRepo.ListInBoundingBox(ctx, bounds["min_lat"], bounds["min_lng"], bounds["max_lat"], bounds["max_lng"], page)
+	if err != nil {
+		h.log.Error(ctx, "Error listing lugares in bounding box", err, map[string]interface{}{
+			"action":   "ListLugaresInBoundingBox",
+			"resource": "lugares",
+		})
+		return createErrorResponse(internalError("Error listing lugares"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugares in bounding box listed successfully", map[string]interface{}{
+		"action":   "ListLugaresInBoundingBox",
+		"resource": "lugares",
+		"count":    len(lugares),
+	})
+
+	// Return lugares as JSON; signed in callers also see their unpublished
+	// lugares, so their lists are not cached
+	response, err := createPaginatedResponse(ctx, h.log, request, lugares, len(lugares), limit, offset, "lugares", func() (int, error) {
+		return h.lugarRepo.CountInBoundingBox(ctx, bounds["min_lat"], bounds["min_lng"], bounds["max_lat"], bounds["max_lng"])
+	})
+	if currentUser(ctx) != nil {
+		return markPrivate(response, err)
+	}
+	return response, err
+}
+
+// ListLugaresNearCity handles GET /lugares/near requests
+func (h *LugarHandler) ListLugaresNearCity(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Validate query parameters
+	city := strings.TrimSpace(request.QueryStringParameters["city"])
+	if city == "" {
+		h.log.Warn(ctx, "Invalid near search: city is required", map[string]interface{}{
+			"action":   "ListLugaresNearCity",
+			"resource": "lugares",
+		})
+		return createErrorResponse(validationError("city is required"))
+	}
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListLugaresNearCity",
+			"resource": "lugares",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Find the coordinates of the city
+	location, err := h.geocoder.Geocode(ctx, city)
+	if err != nil {
+		h.log.Error(ctx, "Error geocoding city", err, map[string]interface{}{
+			"action":   "ListLugaresNearCity",
+			"resource": "lugares",
+			"city":     city,
+		})
+		return createErrorResponse(upstreamError("Error finding the city"))
+	}
+	if location == nil {
+		h.log.Warn(ctx, "City not found", map[string]interface{}{
+			"action":   "ListLugaresNearCity",
+			"resource": "lugares",
+			"city":     city,
+		})
+		return createErrorResponse(notFoundError("City not found"))
+	}
+
+	// Get lugares from repository
+	ctx = withViewer(ctx)
+	lugares, err := h.lugarRepo.ListNearest(ctx, location.Latitude, location.Longitude, repository.Pagination{Limit: limit, Offset: offset})
+	if err != nil {
+		h.log.Error(ctx, "Error listing lugares near city", err, map[string]interface{}{
+			"action":   "ListLugaresNearCity",
+			"resource": "lugares",
+			"city":     city,
+		})
+		return createErrorResponse(internalError("Error listing lugares"))
+	}
+
+	// Add the distance to the city
+	for _, lugar := range lugares {
+		distance := models.DistanceKm(location.Latitude, location.Longitude, *lugar.Latitude, *lugar.Longitude)
+		lugar.DistanciaKm = &distance
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugares near city listed successfully", map[string]interface{}{
+		"action":   "ListLugaresNearCity",
+		"resource": "lugares",
+		"city":     city,
+		"count":    len(lugares),
+	})
+
+	// Return lugares as JSON; signed in callers also see their unpublished
+	// lugares, so their lists are not cached
+	response, err := createPaginatedResponse(ctx, h.log, request, lugares, len(lugares), limit, offset, "lugares", func() (int, error) {
+		return h.lugarRepo.CountWithCoordinates(ctx)
+	})
+	if currentUser(ctx) != nil {
+		return markPrivate(response, err)
+	}
+	return response, err
+}
+
+// FindDuplicateLugares handles GET /lugares/duplicates requests
+func (h *LugarHandler) FindDuplicateLugares(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	nomeLocal := strings.TrimSpace(request.QueryStringParameters["nome_local"])
+	enderecoCompleto := strings.TrimSpace(request.QueryStringParameters["endereco_completo"])
+
+	// Validate query parameters
+	if nomeLocal == "" && enderecoCompleto == "" {
+		h.log.Warn(ctx, "Invalid duplicate search: nome_local or endereco_completo is required", map[string]interface{}{
+			"action":   "FindDuplicateLugares",
+			"resource": "lugares",
+		})
+		return createErrorResponse(validationError("Nome local or endereco completo is required"))
+	}
+
+	// Get similar lugares from repository
+	ctx = withViewer(ctx)
+	lugares, err := h.lugarRepo.FindSimilar(ctx, nomeLocal, enderecoCompleto)
+	if err != nil {
+		h.log.Error(ctx, "Error finding similar lugares", err, map[string]interface{}{
+			"action":   "FindDuplicateLugares",
+			"resource": "lugares",
+		})
+		return createErrorResponse(internalError("Error finding similar lugares"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Similar lugares listed successfully", map[string]interface{}{
+		"action":   "FindDuplicateLugares",
+		"resource": "lugares",
+		"count":    len(lugares),
+	})
+
+	// Return lugares as JSON; signed in callers also see their unpublished
+	// lugares, so their responses are not cached
+	response, err := createCachedJSONResponse(http.StatusOK, lugares, "lugares")
+	if currentUser(ctx) != nil {
+		return markPrivate(response, err)
+	}
+	return response, err
+}
+
+// CreateLugar handles POST /lugares requests
+func (h *LugarHandler) CreateLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Parse request body
+	var lugar models.Lugar
+	if err := decodeJSONBody(request, &lugar); err != nil {
+		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
+			"action":   "CreateLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(err)
+	}
+
+	// Validate lugar, including that the referenced tags and ramos exist, before inserting anything
+	if apiErr, err := h.validateNewLugar(ctx, &lugar); err != nil {
+		h.log.Error(ctx, "Error checking lugar references", err, map[string]interface{}{
+			"action":   "CreateLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(internalError("Error creating lugar"))
+	} else if apiErr != nil {
+		h.log.Warn(ctx, "Invalid lugar data: "+apiErr.Message, map[string]interface{}{
+			"action":   "CreateLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(apiErr)
+	}
+
+	// Set timestamps
+	now := time.Now().UTC()
+	lugar.CreatedAt = now
+	lugar.UpdatedAt = now
+
+	// Create lugar in repository
+	changes := changedFields(nil, &lugar, lugarUntrackedFields...)
+	lugarID, err := h.lugarRepo.Create(withAudit(ctx, changes), &lugar)
+	if err != nil {
+		h.log.Error(ctx, "Error creating lugar", err, map[string]interface{}{
+			"action":   "CreateLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(internalError("Error creating lugar"))
+	}
+
+	// Set lugar ID
+	lugar.ID = lugarID
+
+	// Process related entities if provided
+	if len(lugar.Images) > 0 {
+		for i := range lugar.Images {
+			lugar.Images[i].LugarID = lugarID
+			lugar.Images[i].CreatedAt = now
+			imageID, err := h.lugarRepo.AddImage(ctx, lugar.Images[i], h.maxImagesPerLugar)
+			if err != nil {
+				h.log.Error(ctx, "Error adding image to lugar", err, map[string]interface{}{
+					"action":      "CreateLugar",
+					"resource":    "lugares",
+					"resource_id": fmt.Sprintf("%d", lugarID),
+				})
+				// Continue with other images even if one fails
+			} else {
+				lugar.Images[i].ID = imageID
+			}
+		}
+	}
+
+	if len(lugar.Tags) > 0 {
+		for _, tag := range lugar.Tags {
+			if _, err := h.lugarRepo.AddTag(ctx, lugarID, tag.ID); err != nil {
+				h.log.Error(ctx, "Error adding tag to lugar", err, map[string]interface{}{
+					"action":      "CreateLugar",
+					"resource":    "lugares",
+					"resource_id": fmt.Sprintf("%d", lugarID),
+					"tag_id":      fmt.Sprintf("%d", tag.ID),
+				})
+				// Continue with other tags even if one fails
+			}
+		}
+	}
+
+	if len(lugar.Ramos) > 0 {
+		for _, ramo := range lugar.Ramos {
+			if _, err := h.lugarRepo.AddRamo(ctx, lugarID, ramo.ID); err != nil {
+				h.log.Error(ctx, "Error adding ramo to lugar", err, map[string]interface{}{
+					"action":      "CreateLugar",
+					"resource":    "lugares",
+					"resource_id": fmt.Sprintf("%d", lugarID),
+					"ramo_id":     fmt.Sprintf("%d", ramo.ID),
+				})
+				// Continue with other ramos even if one fails
+			}
+		}
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugar created successfully", map[string]interface{}{
+		"action":      "CreateLugar",
+		"resource":    "lugares",
+		"resource_id": fmt.Sprintf("%d", lugarID),
+		"changes":     changes,
+	})
+
+	// Return created lugar as JSON, with the non-fatal issues found in its data
+	return createJSONResponse(http.StatusCreated, lugarWithWarnings{Lugar: &lugar, Warnings: lugar.Warnings()})
+}
+
+// ValidateLugar handles POST /lugares/validate requests. It parses and
+// validates the body exactly like CreateLugar without writing anything.
+func (h *LugarHandler) ValidateLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Parse request body
+	var lugar models.Lugar
+	if err := decodeJSONBody(request, &lugar); err != nil {
+		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
+			"action":   "ValidateLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(err)
+	}
+
+	// Validate lugar
+	if apiErr, err := h.validateNewLugar(ctx, &lugar); err != nil {
+		h.log.Error(ctx, "Error checking lugar references", err, map[string]interface{}{
+			"action":   "ValidateLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(internalError("Error validating lugar"))
+	} else if apiErr != nil {
+		h.log.Info(ctx, "Lugar validation failed", map[string]interface{}{
+			"action":   "ValidateLugar",
+			"resource": "lugares",
+			"error":    apiErr.Message,
+		})
+		return createErrorResponse(apiErr)
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugar validated successfully", map[string]interface{}{
+		"action":   "ValidateLugar",
+		"resource": "lugares",
+	})
+
+	// Return the result with the warnings CreateLugar would report
+	return createJSONResponse(http.StatusOK, lugarValidation{Valid: true, Warnings: lugar.Warnings()})
+}
+
+// lugarValidation is the result of a successful lugar validation
+type lugarValidation struct {
+	Valid    bool     `json:"valid"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// validateNewLugar returns a validation error when a lugar to be created is
+// invalid, or nil when it can be created. Shared by CreateLugar and
+// ValidateLugar so they accept the same bodies.
+func (h *LugarHandler) validateNewLugar(ctx context.Context, lugar *models.Lugar) (*APIError, error) {
+	if apiErr := validateLugarFields(lugar); apiErr != nil {
+		return apiErr, nil
+	}
+	return h.checkLugarReferences(ctx, lugar)
+}
+
+// validateLugarFields returns a validation error when the fields of a new
+// lugar are invalid, leaving its tags and ramos aside. Imported lugares go
+// through the same rules as created ones.
+func validateLugarFields(lugar *models.Lugar) *APIError {
+	if strings.TrimSpace(lugar.NomeLocal) == "" {
+		return unprocessableError("Nome local is required")
+	}
+	if err := validateLugarLinks(lugar); err != nil {
+		return unprocessableError(err.Error())
+	}
+	return nil
+}
+
+// checkLugarReferences returns a validation error listing the tag and ramo IDs
+// of a lugar that do not exist, or nil when they all do
+func (h *LugarHandler) checkLugarReferences(ctx context.Context, lugar *models.Lugar) (*APIError, error) {
+	tagIDs := make([]int, len(lugar.Tags))
+	for i, tag := range lugar.Tags {
+		tagIDs[i] = tag.ID
+	}
+	missingTags, err := h.lugarRepo.MissingTagIDs(ctx, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	ramoIDs := make([]int, len(lugar.Ramos))
+	for i, ramo := range lugar.Ramos {
+		ramoIDs[i] = ramo.ID
+	}
+	missingRamos, err := h.lugarRepo.MissingRamoIDs(ctx, ramoIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	if len(missingTags) > 0 {
+		problems = append(problems, "unknown tag IDs: "+joinInts(missingTags))
+	}
+	if len(missingRamos) > 0 {
+		problems = append(problems, "unknown ramo IDs: "+joinInts(missingRamos))
+	}
+	if len(problems) == 0 {
+		return nil, nil
+	}
+	return unprocessableError(strings.Join(problems, "; ")), nil
+}
+
+// joinInts formats ids as a comma separated list
+func joinInts(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// lugarWithWarnings is a lugar along with the non-fatal issues found in its data
+type lugarWithWarnings struct {
+	*models.Lugar
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// maxLugarImportSize is the maximum number of lugares in one import request
+const maxLugarImportSize = 200
+
+// ImportLugares handles POST /lugares/import requests. The body is an array of
+// lugares whose tags and ramos are given by name; missing tags and ramos are created.
+func (h *LugarHandler) ImportLugares(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized lugares import request", map[string]interface{}{
+			"action":   "ImportLugares",
+			"resource": "lugares",
+		})
+		return response, nil
+	}
+
+	// Parse request body
+	var lugares []*models.Lugar
+	if err := decodeJSONBody(request, &lugares); err != nil {
+		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
+			"action":   "ImportLugares",
+			"resource": "lugares",
+		})
+		return createErrorResponse(err)
+	}
+
+	// Validate batch size
+	if len(lugares) == 0 || len(lugares) > maxLugarImportSize {
+		return createErrorResponse(unprocessableError(fmt.Sprintf("Between 1 and %d lugares must be provided", maxLugarImportSize)))
+	}
+
+	// Validate each lugar; invalid ones are reported without being imported
+	results := make([]models.LugarImportResult, len(lugares))
+	var valid []*models.Lugar
+	var validIndexes []int
+	now := time.Now().UTC()
+	for i, lugar := range lugares {
+		results[i].Index = i
+		if msg := validateImportedLugar(lugar); msg != "" {
+			results[i].Error = msg
+			continue
+		}
+
+		if lugar.UserID == 0 {
+			lugar.UserID = currentUser(ctx).ID
+		}
+		lugar.CreatedAt = now
+		lugar.UpdatedAt = now
+		valid = append(valid, lugar)
+		validIndexes = append(validIndexes, i)
+	}
+
+	// Import valid lugares in repository
+	if len(valid) > 0 {
+		imported, err := h.lugarRepo.Import(withAudit(ctx, nil), valid)
+		if err != nil {
+			h.log.Error(ctx, "Error importing lugares", err, map[string]interface{}{
+				"action":   "ImportLugares",
+				"resource": "lugares",
+			})
+			return createErrorResponse(internalError("Error importing lugares"))
+		}
+
+		for k, result := range imported {
+			result.Index = validIndexes[k]
+			results[result.Index] = result
+			if result.Err != nil {
+				h.log.Warn(ctx, "Lugar not imported", map[string]interface{}{
+					"action":   "ImportLugares",
+					"resource": "lugares",
+					"index":    result.Index,
+					"error":    result.Err.Error(),
+				})
+			}
+		}
+	}
+
+	// Log success
+	created := 0
+	for _, result := range results {
+		if result.ID != 0 {
+			created++
+		}
+	}
+	h.log.Info(ctx, "Lugares imported", map[string]interface{}{
+		"action":   "ImportLugares",
+		"resource": "lugares",
+		"total":    len(lugares),
+		"created":  created,
+	})
+
+	// Return per-item results as JSON
+	return createJSONResponse(http.StatusOK, results)
+}
+
+// validateLugarLinks checks that the links of a lugar, when set, are valid URLs
+func validateLugarLinks(lugar *models.Lugar) error {
+	if err := validateOptionalURL("link_site", lugar.LinkSite); err != nil {
+		return err
+	}
+	return validateOptionalURL("link_google_maps", lugar.LinkGoogleMaps)
+}
+
+// validateImportedLugar returns a message describing why a lugar cannot be
+// imported, or an empty string if it is valid: its fields follow the rules of
+// CreateLugar, but its tags and ramos are given by name. The names are trimmed.
+func validateImportedLugar(lugar *models.Lugar) string {
+	if lugar == nil {
+		return "Lugar is required"
+	}
+	if apiErr := validateLugarFields(lugar); apiErr != nil {
+		return apiErr.Message
+	}
+	for _, tag := range lugar.Tags {
+		if tag == nil {
+			return "Tag name is required"
+		}
+		tag.Name = strings.TrimSpace(tag.Name)
+		if tag.Name == "" || utf8.RuneCountInString(tag.Name) > 50 {
+			return "Tag names must have between 1 and 50 characters"
+		}
+	}
+	for _, ramo := range lugar.Ramos {
+		if ramo == nil {
+			return "Ramo name is required"
+		}
+		ramo.Name = strings.TrimSpace(ramo.Name)
+		if ramo.Name == "" || utf8.RuneCountInString(ramo.Name) > 50 {
+			return "Ramo names must have between 1 and 50 characters"
+		}
+	}
+	return ""
+}
+
+// UpdateLugar handles PUT /lugares/{id} requests
+func (h *LugarHandler) UpdateLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract lugar ID from path parameters
+	lugarID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
+			"action":   "UpdateLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
+	}
+
+	// Get existing lugar
+	existingLugar, err := h.lugarRepo.GetByID(ctx, lugarID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting lugar", err, map[string]interface{}{
+			"action":      "UpdateLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error getting lugar"))
+	}
+
+	// If lugar not found
+	if existingLugar == nil {
+		h.log.Warn(ctx, "Lugar not found", map[string]interface{}{
+			"action":      "UpdateLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(notFoundError("Lugar not found"))
+	}
+
+	// Parse request body
+	var updatedLugar models.Lugar
+	if err := decodeJSONBody(request, &updatedLugar); err != nil {
+		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
+			"action":      "UpdateLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(err)
+	}
+
+	// Validate lugar
+	if updatedLugar.NomeLocal == "" {
+		h.log.Warn(ctx, "Invalid lugar data: nome_local is required", map[string]interface{}{
+			"action":      "UpdateLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(unprocessableError("Nome local is required"))
+	}
+	if err := validateLugarLinks(&updatedLugar); err != nil {
+		h.log.Warn(ctx, "Invalid lugar data: "+err.Error(), map[string]interface{}{
+			"action":      "UpdateLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(unprocessableError(err.Error()))
+	}
+
+	// Keep the current values to log the changes
+	previousLugar := *existingLugar
+
+	// Update lugar fields; the owner is kept, it only changes through
+	// PUT /lugares/{id}/owner
+	existingLugar.NomeLocal = updatedLugar.NomeLocal
+	existingLugar.NomeDonoLocal = updatedLugar.NomeDonoLocal
+	existingLugar.TelefoneParaContato = updatedLugar.TelefoneParaContato
+	existingLugar.LinkGoogleMaps = updatedLugar.LinkGoogleMaps
+	existingLugar.LinkSite = updatedLugar.LinkSite
+	existingLugar.EnderecoCompleto = updatedLugar.EnderecoCompleto
+	existingLugar.LocalPublico = updatedLugar.LocalPublico
+	existingLugar.ValorFixo = updatedLugar.ValorFixo
+	existingLugar.ValorIndividual = updatedLugar.ValorIndividual
+	existingLugar.Latitude = updatedLugar.Latitude
+	existingLugar.Longitude = updatedLugar.Longitude
+	existingLugar.UpdatedAt = time.Now().UTC()
+
+	// Update lugar in repository
+	changes := changedFields(&previousLugar, existingLugar, lugarUntrackedFields...)
+	if err := h.lugarRepo.Update(withAudit(ctx, changes), existingLugar); err != nil {
+		h.log.Error(ctx, "Error updating lugar", err, map[string]interface{}{
+			"action":      "UpdateLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error updating lugar"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugar updated successfully", map[string]interface{}{
+		"action":      "UpdateLugar",
+		"resource":    "lugares",
+		"resource_id": fmt.Sprintf("%d", lugarID),
+		"changes":     changes,
+	})
+
+	// Return updated lugar as JSON
+	return createJSONResponse(http.StatusOK, existingLugar)
+}
+
+// DeleteLugar handles DELETE /lugares/{id} requests
+func (h *LugarHandler) DeleteLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract lugar ID from path parameters
+	lugarID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
+			"action":   "DeleteLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
+	}
+
+	// Delete lugar from repository
+	if err := h.lugarRepo.Delete(withAudit(ctx, nil), lugarID); err != nil {
+		h.log.Error(ctx, "Error deleting lugar", err, map[string]interface{}{
+			"action":      "DeleteLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error deleting lugar"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugar deleted successfully", map[string]interface{}{
+		"action":      "DeleteLugar",
+		"resource":    "lugares",
+		"resource_id": fmt.Sprintf("%d", lugarID),
+	})
+
+	// Return success response
+	return createNoContentResponse()
+}
+
+// TouchLugar handles POST /lugares/{id}/touch requests, bumping updated_at
+// without changing any other field (e.g. to trigger a new sync)
+func (h *LugarHandler) TouchLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Restrict to write users
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized lugar touch request", map[string]interface{}{
+			"action":   "TouchLugar",
+			"resource": "lugares",
+		})
+		return response, nil
+	}
+
+	// Extract lugar ID from path parameters
+	lugarID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
+			"action":   "TouchLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
+	}
+
+	// Touch lugar in repository
+	updatedAt, err := h.lugarRepo.Touch(ctx, lugarID)
+	if err != nil {
+		h.log.Error(ctx, "Error touching lugar", err, map[string]interface{}{
+			"action":      "TouchLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error touching lugar"))
+	}
+
+	// If lugar not found
+	if updatedAt == nil {
+		h.log.Warn(ctx, "Lugar not found", map[string]interface{}{
+			"action":      "TouchLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(notFoundError("Lugar not found"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugar touched successfully", map[string]interface{}{
+		"action":      "TouchLugar",
+		"resource":    "lugares",
+		"resource_id": fmt.Sprintf("%d", lugarID),
+	})
+
+	// Return the new timestamp as JSON
+	return createJSONResponse(http.StatusOK, map[string]interface{}{
+		"id":         lugarID,
+		"updated_at": updatedAt,
+	})
+}
+
+// PublishLugar handles POST /lugares/{id}/publish requests. A lugar is only
+// published once it has at least one image and an address.
+func (h *LugarHandler) PublishLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract lugar ID from path parameters
+	lugarID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
+			"action":   "PublishLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
+	}
+
+	// Get lugar from repository
+	lugar, err := h.lugarRepo.GetByID(ctx, lugarID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting lugar", err, map[string]interface{}{
+			"action":      "PublishLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error getting lugar"))
+	}
+
+	// If lugar not found
+	if lugar == nil {
+		h.log.Warn(ctx, "Lugar not found", map[string]interface{}{
+			"action":      "PublishLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(notFoundError("Lugar not found"))
+	}
+
+	// Restrict to the owner or admins
+	if response, ok := requireSelfOrAdmin(ctx, lugar.UserID); !ok {
+		h.log.Warn(ctx, "Unauthorized lugar publish request", map[string]interface{}{
+			"action":      "PublishLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return response, nil
+	}
+
+	// Validate the lugar is complete
+	if msg := publishRequirementsError(lugar); msg != "" {
+		h.log.Warn(ctx, "Lugar not ready to be published", map[string]interface{}{
+			"action":      "PublishLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"reason":      msg,
+		})
+		return createErrorResponse(unprocessableError(msg))
+	}
+
+	// Publish lugar in repository
+	if !lugar.Published {
+		changes := map[string]interface{}{
+			"published": map[string]interface{}{"from": false, "to": true},
+		}
+		if err := h.lugarRepo.Publish(withAudit(ctx, changes), lugarID); err != nil {
+			h.log.Error(ctx, "Error publishing lugar", err, map[string]interface{}{
+				"action":      "PublishLugar",
+				"resource":    "lugares",
+				"resource_id": fmt.Sprintf("%d", lugarID),
+			})
+			return createErrorResponse(internalError("Error publishing lugar"))
+		}
+		lugar.Published = true
+
+		// Log success
+		h.log.Info(ctx, "Lugar published successfully", map[string]interface{}{
+			"action":      "PublishLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"changes":     changes,
+		})
+	}
+
+	// Return published lugar as JSON
+	return createJSONResponse(http.StatusOK, lugar)
+}
+
+// publishRequirementsError describes what a lugar lacks to be published, or
+// returns an empty string if it can be published
+func publishRequirementsError(lugar *models.Lugar) string {
+	var missing []string
+	if len(lugar.Images) == 0 {
+		missing = append(missing, "at least one image")
+	}
+	if strings.TrimSpace(lugar.EnderecoCompleto) == "" {
+		missing = append(missing, "an endereco_completo")
+	}
+	if len(missing) == 0 {
+		return ""
+	}
+	return "Lugar needs " + strings.Join(missing, " and ") + " to be published"
+}
+
+// GetLugarHistory handles GET /lugares/{id}/history requests
+func (h *LugarHandler) GetLugarHistory(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract lugar ID from path parameters
+	lugarID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
+			"action":   "GetLugarHistory",
+			"resource": "lugares",
+		})
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
+	}
+
+	// Get lugar to find its owner
+	lugar, err := h.lugarRepo.GetByID(ctx, lugarID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting lugar", err, map[string]interface{}{
+			"action":      "GetLugarHistory",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error getting lugar"))
+	}
+
+	// Restrict to the owner or admins; only admins can see the history of a deleted lugar
+	var response events.APIGatewayProxyResponse
+	var ok bool
+	if lugar != nil {
+		response, ok = requireSelfOrAdmin(ctx, lugar.UserID)
+	} else {
+		response, ok = requireAdmin(ctx)
+	}
+	if !ok {
+		h.log.Warn(ctx, "Unauthorized lugar history request", map[string]interface{}{
+			"action":      "GetLugarHistory",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return response, nil
+	}
+
+	// Get history from repository
+	entries, err := h.auditRepo.ListHistory(ctx, "lugares", lugarID)
+	if err != nil {
+		h.log.Error(ctx, "Error listing lugar history", err, map[string]interface{}{
+			"action":      "GetLugarHistory",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error listing lugar history"))
+	}
+
+	// If the lugar never existed
+	if lugar == nil && len(entries) == 0 {
+		h.log.Warn(ctx, "Lugar not found", map[string]interface{}{
+			"action":      "GetLugarHistory",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(notFoundError("Lugar not found"))
 	}
 
 	// Log success
-	h.log.Info(ctx, "Lugares listed successfully", map[string]interface{}{
-		"action":   "ListLugares",
-		"resource": "lugares",
-		"count":    len(lugares),
+	h.log.Info(ctx, "Lugar history retrieved successfully", map[string]interface{}{
+		"action":      "GetLugarHistory",
+		"resource":    "lugares",
+		"resource_id": fmt.Sprintf("%d", lugarID),
+		"count":       len(entries),
 	})
 
-	// Return lugares as JSON
-	return createJSONResponse(http.StatusOK, lugares)
+	// Return history as JSON
+	return createJSONResponse(http.StatusOK, entries)
 }
 
-// CreateLugar handles POST /lugares requests
-func (h *LugarHandler) CreateLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	// Parse request body
-	var lugar models.Lugar
-	if err := json.Unmarshal([]byte(request.Body), &lugar); err != nil {
-		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
-			"action":   "CreateLugar",
-			"resource": "lugares",
-		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
-	}
+const (
+	// defaultSimilarLugaresLimit is the number of similar lugares returned when the request does not set a limit
+	defaultSimilarLugaresLimit = 5
+	// maxSimilarLugaresLimit is the largest accepted similar lugares limit
+	maxSimilarLugaresLimit = 20
+)
 
-	// Validate lugar
-	if lugar.NomeLocal == "" {
-		h.log.Warn(ctx, "Invalid lugar data: nome_local is required", map[string]interface{}{
-			"action":   "CreateLugar",
+// GetSimilarLugares handles GET /lugares/{id}/similar requests
+func (h *LugarHandler) GetSimilarLugares(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract lugar ID from path parameters
+	lugarID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
+			"action":   "GetSimilarLugares",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Nome local is required")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
-	// Set timestamps
-	now := time.Now()
-	lugar.CreatedAt = now
-	lugar.UpdatedAt = now
-
-	// Create lugar in repository
-	lugarID, err := h.lugarRepo.Create(ctx, &lugar)
+	// Validate limit
+	limit, err := parseLimitParam(request, defaultSimilarLugaresLimit, maxSimilarLugaresLimit)
 	if err != nil {
-		h.log.Error(ctx, "Error creating lugar", err, map[string]interface{}{
-			"action":   "CreateLugar",
-			"resource": "lugares",
+		h.log.Warn(ctx, "Invalid similar lugares limit", map[string]interface{}{
+			"action":      "GetSimilarLugares",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"limit":       request.QueryStringParameters["limit"],
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error creating lugar")
+		return createErrorResponse(validationError(err.Error()))
 	}
 
-	// Set lugar ID
-	lugar.ID = lugarID
-
-	// Process related entities if provided
-	if len(lugar.Images) > 0 {
-		for i := range lugar.Images {
-			lugar.Images[i].LugarID = lugarID
-			lugar.Images[i].CreatedAt = now
-			imageID, err := h.lugarRepo.AddImage(ctx, lugar.Images[i])
-			if err != nil {
-				h.log.Error(ctx, "Error adding image to lugar", err, map[string]interface{}{
-					"action":      "CreateLugar",
-					"resource":    "lugares",
-					"resource_id": fmt.Sprintf("%d", lugarID),
-				})
-				// Continue with other images even if one fails
-			} else {
-				lugar.Images[i].ID = imageID
-			}
-		}
+	// Check that the lugar exists
+	exists, err := h.lugarRepo.Exists(ctx, lugarID)
+	if err != nil {
+		h.log.Error(ctx, "Error checking lugar", err, map[string]interface{}{
+			"action":      "GetSimilarLugares",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error listing similar lugares"))
 	}
 
-	if len(lugar.Tags) > 0 {
-		for _, tag := range lugar.Tags {
-			if err := h.lugarRepo.AddTag(ctx, lugarID, tag.ID); err != nil {
-				h.log.Error(ctx, "Error adding tag to lugar", err, map[string]interface{}{
-					"action":      "CreateLugar",
-					"resource":    "lugares",
-					"resource_id": fmt.Sprintf("%d", lugarID),
-					"tag_id":      fmt.Sprintf("%d", tag.ID),
-				})
-				// Continue with other tags even if one fails
-			}
-		}
+	// If lugar not found
+	if !exists {
+		h.log.Warn(ctx, "Lugar not found", map[string]interface{}{
+			"action":      "GetSimilarLugares",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(notFoundError("Lugar not found"))
 	}
 
-	if len(lugar.Ramos) > 0 {
-		for _, ramo := range lugar.Ramos {
-			if err := h.lugarRepo.AddRamo(ctx, lugarID, ramo.ID); err != nil {
-				h.log.Error(ctx, "Error adding ramo to lugar", err, map[string]interface{}{
-					"action":      "CreateLugar",
-					"resource":    "lugares",
-					"resource_id": fmt.Sprintf("%d", lugarID),
-					"ramo_id":     fmt.Sprintf("%d", ramo.ID),
-				})
-				// Continue with other ramos even if one fails
-			}
-		}
+	// Get similar lugares from repository
+	ctx = withViewer(ctx)
+	similar, err := h.lugarRepo.ListSimilar(ctx, lugarID, limit)
+	if err != nil {
+		h.log.Error(ctx, "Error listing similar lugares", err, map[string]interface{}{
+			"action":      "GetSimilarLugares",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error listing similar lugares"))
 	}
 
 	// Log success
-	h.log.Info(ctx, "Lugar created successfully", map[string]interface{}{
-		"action":      "CreateLugar",
+	h.log.Info(ctx, "Similar lugares listed successfully", map[string]interface{}{
+		"action":      "GetSimilarLugares",
 		"resource":    "lugares",
 		"resource_id": fmt.Sprintf("%d", lugarID),
+		"count":       len(similar),
 	})
 
-	// Return created lugar as JSON
-	return createJSONResponse(http.StatusCreated, lugar)
+	// Return similar lugares as JSON; signed in callers also see their
+	// unpublished lugares, so their responses are not cached
+	response, err := createCachedJSONResponse(http.StatusOK, similar, "lugares")
+	if currentUser(ctx) != nil {
+		return markPrivate(response, err)
+	}
+	return response, err
 }
 
-// UpdateLugar handles PUT /lugares/{id} requests
-func (h *LugarHandler) UpdateLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+// ChangeLugarOwner handles PUT /lugares/{id}/owner requests
+func (h *LugarHandler) ChangeLugarOwner(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized owner change request", map[string]interface{}{
+			"action":   "ChangeLugarOwner",
+			"resource": "lugares",
+		})
+		return response, nil
+	}
+
 	// Extract lugar ID from path parameters
 	lugarID, err := strconv.Atoi(request.PathParameters["id"])
 	if err != nil {
 		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
-			"action":   "UpdateLugar",
+			"action":   "ChangeLugarOwner",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
 	// Get existing lugar
-	existingLugar, err := h.lugarRepo.GetByID(ctx, lugarID)
+	lugar, err := h.lugarRepo.GetByID(ctx, lugarID)
 	if err != nil {
 		h.log.Error(ctx, "Error getting lugar", err, map[string]interface{}{
-			"action":      "UpdateLugar",
+			"action":      "ChangeLugarOwner",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error getting lugar")
+		return createErrorResponse(internalError("Error getting lugar"))
 	}
 
 	// If lugar not found
-	if existingLugar == nil {
+	if lugar == nil {
 		h.log.Warn(ctx, "Lugar not found", map[string]interface{}{
-			"action":      "UpdateLugar",
+			"action":      "ChangeLugarOwner",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusNotFound, "Lugar not found")
+		return createErrorResponse(notFoundError("Lugar not found"))
 	}
 
 	// Parse request body
-	var updatedLugar models.Lugar
-	if err := json.Unmarshal([]byte(request.Body), &updatedLugar); err != nil {
+	var requestBody struct {
+		UserID int `json:"user_id"`
+	}
+	if err := decodeJSONBody(request, &requestBody); err != nil {
 		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
-			"action":      "UpdateLugar",
+			"action":      "ChangeLugarOwner",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
+		return createErrorResponse(err)
 	}
 
-	// Validate lugar
-	if updatedLugar.NomeLocal == "" {
-		h.log.Warn(ctx, "Invalid lugar data: nome_local is required", map[string]interface{}{
-			"action":      "UpdateLugar",
+	// Validate user ID
+	if requestBody.UserID <= 0 {
+		h.log.Warn(ctx, "Invalid owner data: user_id is required", map[string]interface{}{
+			"action":      "ChangeLugarOwner",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Nome local is required")
+		return createErrorResponse(unprocessableError("user_id is required"))
 	}
 
-	// Update lugar fields
-	existingLugar.NomeLocal = updatedLugar.NomeLocal
-	existingLugar.NomeDonoLocal = updatedLugar.NomeDonoLocal
-	existingLugar.TelefoneParaContato = updatedLugar.TelefoneParaContato
-	existingLugar.LinkGoogleMaps = updatedLugar.LinkGoogleMaps
-	existingLugar.LinkSite = updatedLugar.LinkSite
-	existingLugar.EnderecoCompleto = updatedLugar.EnderecoCompleto
-	existingLugar.LocalPublico = updatedLugar.LocalPublico
-	existingLugar.ValorFixo = updatedLugar.ValorFixo
-	existingLugar.ValorIndividual = updatedLugar.ValorIndividual
-	existingLugar.UserID = updatedLugar.UserID
-	existingLugar.UpdatedAt = time.Now()
-
-	// Update lugar in repository
-	if err := h.lugarRepo.Update(ctx, existingLugar); err != nil {
-		h.log.Error(ctx, "Error updating lugar", err, map[string]interface{}{
-			"action":      "UpdateLugar",
+	// Change owner in repository
+	changes := map[string]interface{}{
+		"user_id": map[string]interface{}{"from": lugar.UserID, "to": requestBody.UserID},
+	}
+	if err := h.lugarRepo.ChangeOwner(withAudit(ctx, changes), lugarID, requestBody.UserID); err != nil {
+		if errors.Is(err, repository.ErrInvalidReference) {
+			h.log.Warn(ctx, "Target user does not exist", map[string]interface{}{
+				"action":      "ChangeLugarOwner",
+				"resource":    "lugares",
+				"resource_id": fmt.Sprintf("%d", lugarID),
+				"user_id":     fmt.Sprintf("%d", requestBody.UserID),
+			})
+			return createErrorResponse(unprocessableError("User does not exist"))
+		}
+		h.log.Error(ctx, "Error changing lugar owner", err, map[string]interface{}{
+			"action":      "ChangeLugarOwner",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
+			"user_id":     fmt.Sprintf("%d", requestBody.UserID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error updating lugar")
+		return createErrorResponse(internalError("Error changing lugar owner"))
 	}
+	lugar.UserID = requestBody.UserID
 
 	// Log success
-	h.log.Info(ctx, "Lugar updated successfully", map[string]interface{}{
-		"action":      "UpdateLugar",
+	h.log.Info(ctx, "Lugar owner changed successfully", map[string]interface{}{
+		"action":      "ChangeLugarOwner",
 		"resource":    "lugares",
 		"resource_id": fmt.Sprintf("%d", lugarID),
+		"user_id":     fmt.Sprintf("%d", requestBody.UserID),
+		"changes":     changes,
 	})
 
 	// Return updated lugar as JSON
-	return createJSONResponse(http.StatusOK, existingLugar)
+	return createJSONResponse(http.StatusOK, lugar)
 }
 
-// DeleteLugar handles DELETE /lugares/{id} requests
-func (h *LugarHandler) DeleteLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+// AddImageToLugar handles POST /lugares/{id}/images requests. The body is a
+// single image, an array of images or {"images": [...]}; several images are
+// added together or not at all.
+func (h *LugarHandler) AddImageToLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Extract lugar ID from path parameters
 	lugarID, err := strconv.Atoi(request.PathParameters["id"])
 	if err != nil {
 		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
-			"action":   "DeleteLugar",
+			"action":   "AddImageToLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
-	// Delete lugar from repository
-	if err := h.lugarRepo.Delete(ctx, lugarID); err != nil {
-		h.log.Error(ctx, "Error deleting lugar", err, map[string]interface{}{
-			"action":      "DeleteLugar",
+	// Parse request body
+	images, single, apiErr := decodeImagesBody(request)
+	if apiErr != nil {
+		h.log.Error(ctx, "Invalid request body", apiErr, map[string]interface{}{
+			"action":      "AddImageToLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(apiErr)
+	}
+
+	// Validate images
+	if len(images) == 0 {
+		h.log.Warn(ctx, "Invalid image data: no images", map[string]interface{}{
+			"action":      "AddImageToLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(unprocessableError("At least one image is required"))
+	}
+
+	// Validate display orders against the last one in use, which is past the
+	// image count when deleted images left gaps
+	maxOrder, err := h.lugarRepo.MaxDisplayOrder(ctx, lugarID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting max display order for lugar", err, map[string]interface{}{
+			"action":      "AddImageToLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error adding image to lugar"))
+	}
+	if err := validateDisplayOrders(images, maxOrder); err != nil {
+		h.log.Warn(ctx, "Invalid image data: display_order out of range", map[string]interface{}{
+			"action":      "AddImageToLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"error":       err.Error(),
+		})
+		return createErrorResponse(unprocessableError(err.Error()))
+	}
+
+	// Set lugar ID and created at
+	now := time.Now().UTC()
+	for _, image := range images {
+		image.LugarID = lugarID
+		image.CreatedAt = now
+	}
+
+	// Add images to lugar; the repository enforces the maximum number of
+	// images per lugar in the same transaction
+	if single {
+		_, err = h.lugarRepo.AddImage(ctx, images[0], h.maxImagesPerLugar)
+	} else {
+		err = h.lugarRepo.AddImages(ctx, images, h.maxImagesPerLugar)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrImageLimit) {
+			h.log.Warn(ctx, "Image limit reached for lugar", map[string]interface{}{
+				"action":      "AddImageToLugar",
+				"resource":    "lugares",
+				"resource_id": fmt.Sprintf("%d", lugarID),
+				"new_images":  len(images),
+				"error":       err.Error(),
+			})
+			return createErrorResponse(conflictError(fmt.Sprintf("Lugar can have at most %d images", h.maxImagesPerLugar)))
+		}
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			h.log.Warn(ctx, "Display order already used", map[string]interface{}{
+				"action":      "AddImageToLugar",
+				"resource":    "lugares",
+				"resource_id": fmt.Sprintf("%d", lugarID),
+				"error":       err.Error(),
+			})
+			return createErrorResponse(conflictError("Another image of this lugar already has this display order"))
+		}
+		h.log.Error(ctx, "Error adding image to lugar", err, map[string]interface{}{
+			"action":      "AddImageToLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error deleting lugar")
+		return createErrorResponse(internalError("Error adding image to lugar"))
 	}
 
 	// Log success
-	h.log.Info(ctx, "Lugar deleted successfully", map[string]interface{}{
-		"action":      "DeleteLugar",
+	h.log.Info(ctx, "Images added to lugar successfully", map[string]interface{}{
+		"action":      "AddImageToLugar",
 		"resource":    "lugares",
 		"resource_id": fmt.Sprintf("%d", lugarID),
+		"count":       len(images),
 	})
 
-	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	// Return created images as JSON, keeping the shape of the request
+	if single {
+		return createJSONResponse(http.StatusCreated, images[0])
+	}
+	return createJSONResponse(http.StatusCreated, images)
 }
 
-// AddImageToLugar handles POST /lugares/{id}/images requests
-func (h *LugarHandler) AddImageToLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	// Extract lugar ID from path parameters
+// validateDisplayOrders checks the display orders of images added to a lugar
+// whose last image is at lastOrder. Zero takes the next position; an explicit
+// order cannot be negative nor leave a gap after the last image, so it is at
+// most lastOrder plus the number of images being added.
+func validateDisplayOrders(images []*models.LugarImage, lastOrder int) error {
+	maxOrder := lastOrder + len(images)
+	for _, image := range images {
+		if image.DisplayOrder < 0 {
+			return fmt.Errorf("display_order cannot be negative")
+		}
+		if image.DisplayOrder > maxOrder {
+			return fmt.Errorf("display_order must be at most %d", maxOrder)
+		}
+	}
+	return nil
+}
+
+// GetImageFromLugar handles GET /lugares/{id}/images/{imageId} requests
+func (h *LugarHandler) GetImageFromLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract lugar ID and image ID from path parameters
 	lugarID, err := strconv.Atoi(request.PathParameters["id"])
 	if err != nil {
 		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
-			"action":   "AddImageToLugar",
+			"action":   "GetImageFromLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
-	// Parse request body
-	var image models.LugarImage
-	if err := json.Unmarshal([]byte(request.Body), &image); err != nil {
-		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
-			"action":      "AddImageToLugar",
+	imageID, err := strconv.Atoi(request.PathParameters["imageId"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid image ID", err, map[string]interface{}{
+			"action":      "GetImageFromLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
+		return createErrorResponse(invalidIDError("Invalid image ID"))
 	}
 
-	// Set lugar ID and created at
-	image.LugarID = lugarID
-	image.CreatedAt = time.Now()
-
-	// Add image to lugar
-	imageID, err := h.lugarRepo.AddImage(ctx, &image)
+	// Get image from repository
+	image, err := h.lugarRepo.GetImageByID(ctx, lugarID, imageID)
 	if err != nil {
-		h.log.Error(ctx, "Error adding image to lugar", err, map[string]interface{}{
-			"action":      "AddImageToLugar",
+		h.log.Error(ctx, "Error getting image", err, map[string]interface{}{
+			"action":      "GetImageFromLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
+			"image_id":    fmt.Sprintf("%d", imageID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error adding image to lugar")
+		return createErrorResponse(internalError("Error getting image"))
 	}
 
-	// Set image ID
-	image.ID = imageID
+	// If image not found for this lugar
+	if image == nil {
+		h.log.Warn(ctx, "Image not found", map[string]interface{}{
+			"action":      "GetImageFromLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"image_id":    fmt.Sprintf("%d", imageID),
+		})
+		return createErrorResponse(notFoundError("Image not found"))
+	}
 
 	// Log success
-	h.log.Info(ctx, "Image added to lugar successfully", map[string]interface{}{
-		"action":      "AddImageToLugar",
+	h.log.Info(ctx, "Image retrieved successfully", map[string]interface{}{
+		"action":      "GetImageFromLugar",
 		"resource":    "lugares",
 		"resource_id": fmt.Sprintf("%d", lugarID),
 		"image_id":    fmt.Sprintf("%d", imageID),
 	})
 
-	// Return created image as JSON
-	return createJSONResponse(http.StatusCreated, image)
+	// Return image as JSON
+	return createCachedJSONResponse(http.StatusOK, image, "lugares")
+}
+
+// ListAllImages handles GET /images requests, listing the images of every lugar, newest first
+func (h *LugarHandler) ListAllImages(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized image gallery request", map[string]interface{}{
+			"action":   "ListAllImages",
+			"resource": "images",
+		})
+		return response, nil
+	}
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListAllImages",
+			"resource": "images",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get images from repository
+	ctx = withViewer(ctx)
+	images, err := h.lugarRepo.ListAllImages(ctx, repository.Pagination{Limit: limit, Offset: offset})
+	if err != nil {
+		h.log.Error(ctx, "Error listing images", err, map[string]interface{}{
+			"action":   "ListAllImages",
+			"resource": "images",
+		})
+		return createErrorResponse(internalError("Error listing images"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Images listed successfully", map[string]interface{}{
+		"action":   "ListAllImages",
+		"resource": "images",
+		"count":    len(images),
+	})
+
+	// Return images as JSON
+	return markPrivate(createPaginatedResponse(ctx, h.log, request, images, len(images), limit, offset, "images", func() (int, error) {
+		return h.lugarRepo.CountAllImages(ctx)
+	}))
 }
 
 // DeleteImageFromLugar handles DELETE /lugares/{id}/images/{imageId} requests
@@ -380,7 +1674,7 @@ func (h *LugarHandler) DeleteImageFromLugar(ctx context.Context, request events.
 			"action":   "DeleteImageFromLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
 	imageID, err := strconv.Atoi(request.PathParameters["imageId"])
@@ -389,17 +1683,18 @@ func (h *LugarHandler) DeleteImageFromLugar(ctx context.Context, request events.
 			"action":   "DeleteImageFromLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid image ID")
+		return createErrorResponse(invalidIDError("Invalid image ID"))
 	}
 
-	// Delete image from lugar
-	if err := h.lugarRepo.DeleteImage(ctx, imageID); err != nil {
+	// Delete image from lugar, closing the gap left in the display order when asked to
+	compact := request.QueryStringParameters["compact"] == "true"
+	if err := h.lugarRepo.DeleteImage(ctx, imageID, compact); err != nil {
 		h.log.Error(ctx, "Error deleting image from lugar", err, map[string]interface{}{
 			"action":   "DeleteImageFromLugar",
 			"resource": "lugares",
 			"image_id": fmt.Sprintf("%d", imageID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error deleting image from lugar")
+		return createErrorResponse(internalError("Error deleting image from lugar"))
 	}
 
 	// Log success
@@ -407,15 +1702,11 @@ func (h *LugarHandler) DeleteImageFromLugar(ctx context.Context, request events.
 		"action":   "DeleteImageFromLugar",
 		"resource": "lugares",
 		"image_id": fmt.Sprintf("%d", imageID),
+		"compact":  compact,
 	})
 
 	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	return createNoContentResponse()
 }
 
 // AddTagToLugar handles POST /lugares/{id}/tags requests
@@ -427,31 +1718,32 @@ func (h *LugarHandler) AddTagToLugar(ctx context.Context, request events.APIGate
 			"action":   "AddTagToLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
 	// Parse request body
 	var requestBody struct {
 		TagID int `json:"tag_id"`
 	}
-	if err := json.Unmarshal([]byte(request.Body), &requestBody); err != nil {
+	if err := decodeJSONBody(request, &requestBody); err != nil {
 		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
 			"action":      "AddTagToLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
+		return createErrorResponse(err)
 	}
 
 	// Add tag to lugar
-	if err := h.lugarRepo.AddTag(ctx, lugarID, requestBody.TagID); err != nil {
+	added, err := h.lugarRepo.AddTag(ctx, lugarID, requestBody.TagID)
+	if err != nil {
 		h.log.Error(ctx, "Error adding tag to lugar", err, map[string]interface{}{
 			"action":      "AddTagToLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 			"tag_id":      fmt.Sprintf("%d", requestBody.TagID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error adding tag to lugar")
+		return createErrorResponse(internalError("Error adding tag to lugar"))
 	}
 
 	// Log success
@@ -459,66 +1751,114 @@ func (h *LugarHandler) AddTagToLugar(ctx context.Context, request events.APIGate
 		"action":      "AddTagToLugar",
 		"resource":    "lugares",
 		"resource_id": fmt.Sprintf("%d", lugarID),
-		"tag_id":      fmt.Sprintf("%d", requestBody.TagID),
+		"tag_id":      fmt.Sprintf("%d", requestBody.TagID),
+	})
+
+	// Return success response
+	return createAssociationResponse(added)
+}
+
+// RemoveTagFromLugar handles DELETE /lugares/{id}/tags/{tagId} requests
+func (h *LugarHandler) RemoveTagFromLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract lugar ID and tag ID from path parameters
+	lugarID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
+			"action":   "RemoveTagFromLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
+	}
+
+	tagID, err := strconv.Atoi(request.PathParameters["tagId"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid tag ID", err, map[string]interface{}{
+			"action":      "RemoveTagFromLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(invalidIDError("Invalid tag ID"))
+	}
+
+	// Remove tag from lugar
+	if err := h.lugarRepo.RemoveTag(ctx, lugarID, tagID); err != nil {
+		h.log.Error(ctx, "Error removing tag from lugar", err, map[string]interface{}{
+			"action":      "RemoveTagFromLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"tag_id":      fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(internalError("Error removing tag from lugar"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Tag removed from lugar successfully", map[string]interface{}{
+		"action":      "RemoveTagFromLugar",
+		"resource":    "lugares",
+		"resource_id": fmt.Sprintf("%d", lugarID),
+		"tag_id":      fmt.Sprintf("%d", tagID),
 	})
 
 	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	return createNoContentResponse()
 }
 
-// RemoveTagFromLugar handles DELETE /lugares/{id}/tags/{tagId} requests
-func (h *LugarHandler) RemoveTagFromLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	// Extract lugar ID and tag ID from path parameters
+// RemoveTagsFromLugar handles DELETE /lugares/{id}/tags requests
+func (h *LugarHandler) RemoveTagsFromLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract lugar ID from path parameters
 	lugarID, err := strconv.Atoi(request.PathParameters["id"])
 	if err != nil {
 		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
-			"action":   "RemoveTagFromLugar",
+			"action":   "RemoveTagsFromLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
-	tagID, err := strconv.Atoi(request.PathParameters["tagId"])
-	if err != nil {
-		h.log.Error(ctx, "Invalid tag ID", err, map[string]interface{}{
-			"action":      "RemoveTagFromLugar",
+	// Parse request body
+	var requestBody struct {
+		TagIDs []int `json:"tag_ids"`
+	}
+	if err := decodeJSONBody(request, &requestBody); err != nil {
+		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
+			"action":      "RemoveTagsFromLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid tag ID")
+		return createErrorResponse(err)
 	}
 
-	// Remove tag from lugar
-	if err := h.lugarRepo.RemoveTag(ctx, lugarID, tagID); err != nil {
-		h.log.Error(ctx, "Error removing tag from lugar", err, map[string]interface{}{
-			"action":      "RemoveTagFromLugar",
+	// Validate tag IDs
+	if len(requestBody.TagIDs) == 0 {
+		h.log.Warn(ctx, "Invalid tag data: tag_ids is required", map[string]interface{}{
+			"action":      "RemoveTagsFromLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(unprocessableError("tag_ids is required"))
+	}
+
+	// Remove tags from lugar
+	removed, err := h.lugarRepo.RemoveTags(ctx, lugarID, requestBody.TagIDs)
+	if err != nil {
+		h.log.Error(ctx, "Error removing tags from lugar", err, map[string]interface{}{
+			"action":      "RemoveTagsFromLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
-			"tag_id":      fmt.Sprintf("%d", tagID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error removing tag from lugar")
+		return createErrorResponse(internalError("Error removing tags from lugar"))
 	}
 
 	// Log success
-	h.log.Info(ctx, "Tag removed from lugar successfully", map[string]interface{}{
-		"action":      "RemoveTagFromLugar",
+	h.log.Info(ctx, "Tags removed from lugar successfully", map[string]interface{}{
+		"action":      "RemoveTagsFromLugar",
 		"resource":    "lugares",
 		"resource_id": fmt.Sprintf("%d", lugarID),
-		"tag_id":      fmt.Sprintf("%d", tagID),
+		"removed":     removed,
 	})
 
-	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	// Return removed count as JSON
+	return createJSONResponse(http.StatusOK, map[string]int{"removed": removed})
 }
 
 // AddRamoToLugar handles POST /lugares/{id}/ramos requests
@@ -530,31 +1870,82 @@ func (h *LugarHandler) AddRamoToLugar(ctx context.Context, request events.APIGat
 			"action":   "AddRamoToLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
 	// Parse request body
 	var requestBody struct {
 		RamoID int `json:"ramo_id"`
 	}
-	if err := json.Unmarshal([]byte(request.Body), &requestBody); err != nil {
+	if err := decodeJSONBody(request, &requestBody); err != nil {
 		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
 			"action":      "AddRamoToLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
+		return createErrorResponse(err)
+	}
+
+	// Get lugar and ramo to check the association rules
+	lugar, err := h.lugarRepo.GetByID(ctx, lugarID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting lugar", err, map[string]interface{}{
+			"action":      "AddRamoToLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error getting lugar"))
+	}
+	if lugar == nil {
+		h.log.Warn(ctx, "Lugar not found", map[string]interface{}{
+			"action":      "AddRamoToLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(notFoundError("Lugar not found"))
+	}
+
+	ramo, err := h.ramoRepo.GetByID(ctx, requestBody.RamoID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting ramo", err, map[string]interface{}{
+			"action":      "AddRamoToLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"ramo_id":     fmt.Sprintf("%d", requestBody.RamoID),
+		})
+		return createErrorResponse(internalError("Error getting ramo"))
+	}
+	if ramo == nil {
+		h.log.Warn(ctx, "Ramo not found", map[string]interface{}{
+			"action":      "AddRamoToLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"ramo_id":     fmt.Sprintf("%d", requestBody.RamoID),
+		})
+		return createErrorResponse(unprocessableError(fmt.Sprintf("Ramo %d does not exist", requestBody.RamoID)))
+	}
+
+	if err := lugar.CanAddRamo(ramo); err != nil {
+		h.log.Warn(ctx, "Ramo not allowed for lugar", map[string]interface{}{
+			"action":      "AddRamoToLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"ramo_id":     fmt.Sprintf("%d", requestBody.RamoID),
+			"reason":      err.Error(),
+		})
+		return createErrorResponse(unprocessableError(err.Error()))
 	}
 
 	// Add ramo to lugar
-	if err := h.lugarRepo.AddRamo(ctx, lugarID, requestBody.RamoID); err != nil {
+	added, err := h.lugarRepo.AddRamo(ctx, lugarID, requestBody.RamoID)
+	if err != nil {
 		h.log.Error(ctx, "Error adding ramo to lugar", err, map[string]interface{}{
 			"action":      "AddRamoToLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 			"ramo_id":     fmt.Sprintf("%d", requestBody.RamoID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error adding ramo to lugar")
+		return createErrorResponse(internalError("Error adding ramo to lugar"))
 	}
 
 	// Log success
@@ -566,12 +1957,7 @@ func (h *LugarHandler) AddRamoToLugar(ctx context.Context, request events.APIGat
 	})
 
 	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	return createAssociationResponse(added)
 }
 
 // RemoveRamoFromLugar handles DELETE /lugares/{id}/ramos/{ramoId} requests
@@ -583,7 +1969,7 @@ func (h *LugarHandler) RemoveRamoFromLugar(ctx context.Context, request events.A
 			"action":   "RemoveRamoFromLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
 	ramoID, err := strconv.Atoi(request.PathParameters["ramoId"])
@@ -593,7 +1979,7 @@ func (h *LugarHandler) RemoveRamoFromLugar(ctx context.Context, request events.A
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid ramo ID")
+		return createErrorResponse(invalidIDError("Invalid ramo ID"))
 	}
 
 	// Remove ramo from lugar
@@ -604,7 +1990,7 @@ func (h *LugarHandler) RemoveRamoFromLugar(ctx context.Context, request events.A
 			"resource_id": fmt.Sprintf("%d", lugarID),
 			"ramo_id":     fmt.Sprintf("%d", ramoID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error removing ramo from lugar")
+		return createErrorResponse(internalError("Error removing ramo from lugar"))
 	}
 
 	// Log success
@@ -616,12 +2002,18 @@ func (h *LugarHandler) RemoveRamoFromLugar(ctx context.Context, request events.A
 	})
 
 	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	return createNoContentResponse()
+}
+
+// ratingDate returns the date to store for a rating: the current time, or the
+// date supplied by an admin (e.g. when recording past ratings), which must be
+// valid. Other users cannot date their ratings, so their dates are ignored.
+func ratingDate(ctx context.Context, supplied, now time.Time) (time.Time, bool) {
+	user := currentUser(ctx)
+	if supplied.IsZero() || user == nil || !user.HasWriteAccess() {
+		return now, true
+	}
+	return supplied, models.IsValidRatingDate(supplied, now)
 }
 
 // AddRatingToLugar handles POST /lugares/{id}/ratings requests
@@ -633,18 +2025,18 @@ func (h *LugarHandler) AddRatingToLugar(ctx context.Context, request events.APIG
 			"action":   "AddRatingToLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
 	// Parse request body
 	var rating models.LugarRating
-	if err := json.Unmarshal([]byte(request.Body), &rating); err != nil {
+	if err := decodeJSONBody(request, &rating); err != nil {
 		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
 			"action":      "AddRatingToLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
+		return createErrorResponse(err)
 	}
 
 	// Validate rating
@@ -655,12 +2047,24 @@ func (h *LugarHandler) AddRatingToLugar(ctx context.Context, request events.APIG
 			"resource_id": fmt.Sprintf("%d", lugarID),
 			"rating":      rating.Rating,
 		})
-		return createErrorResponse(http.StatusBadRequest, "Rating must be between 1 and 5")
+		return createErrorResponse(unprocessableError("Rating must be between 1 and 5"))
+	}
+
+	// Date the rating now, unless an admin supplies a valid date
+	date, ok := ratingDate(ctx, rating.Date, time.Now().UTC())
+	if !ok {
+		h.log.Warn(ctx, "Invalid rating date", map[string]interface{}{
+			"action":      "AddRatingToLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"date":        rating.Date.Format(time.RFC3339),
+		})
+		return createErrorResponse(unprocessableError("Rating date must not be in the future or before 2000-01-01"))
 	}
+	rating.Date = date
 
-	// Set lugar ID and date
+	// Set lugar ID
 	rating.LugarID = lugarID
-	rating.Date = time.Now()
 
 	// Add rating to lugar
 	ratingID, err := h.lugarRepo.AddRating(ctx, &rating)
@@ -670,7 +2074,7 @@ func (h *LugarHandler) AddRatingToLugar(ctx context.Context, request events.APIG
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error adding rating to lugar")
+		return createErrorResponse(internalError("Error adding rating to lugar"))
 	}
 
 	// Set rating ID
@@ -685,6 +2089,12 @@ func (h *LugarHandler) AddRatingToLugar(ctx context.Context, request events.APIG
 		"rating":      rating.Rating,
 	})
 
+	// Record the star value, so its distribution can be graphed
+	logger.RecordMetric(ctx, h.log, "RatingValue", float64(rating.Rating), types.StandardUnitNone, map[string]string{
+		"Resource": "lugares",
+		"Action":   "AddRatingToLugar",
+	})
+
 	// Return created rating as JSON
 	return createJSONResponse(http.StatusCreated, rating)
 }
@@ -698,7 +2108,7 @@ func (h *LugarHandler) UpdateRatingForLugar(ctx context.Context, request events.
 			"action":   "UpdateRatingForLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
 	ratingID, err := strconv.Atoi(request.PathParameters["ratingId"])
@@ -708,20 +2118,24 @@ func (h *LugarHandler) UpdateRatingForLugar(ctx context.Context, request events.
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid rating ID")
+		return createErrorResponse(invalidIDError("Invalid rating ID"))
 	}
 
-	// Parse request body
-	var rating models.LugarRating
-	if err := json.Unmarshal([]byte(request.Body), &rating); err != nil {
+	// Parse request body; expected_version is the version of the rating as last read by the client
+	var requestBody struct {
+		models.LugarRating
+		ExpectedVersion *int `json:"expected_version"`
+	}
+	if err := decodeJSONBody(request, &requestBody); err != nil {
 		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
 			"action":      "UpdateRatingForLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 			"rating_id":   fmt.Sprintf("%d", ratingID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
+		return createErrorResponse(err)
 	}
+	rating := requestBody.LugarRating
 
 	// Validate rating
 	if rating.Rating < 1 || rating.Rating > 5 {
@@ -732,23 +2146,50 @@ func (h *LugarHandler) UpdateRatingForLugar(ctx context.Context, request events.
 			"rating_id":   fmt.Sprintf("%d", ratingID),
 			"rating":      rating.Rating,
 		})
-		return createErrorResponse(http.StatusBadRequest, "Rating must be between 1 and 5")
+		return createErrorResponse(unprocessableError("Rating must be between 1 and 5"))
+	}
+
+	// Date the rating now, unless an admin supplies a valid date
+	date, ok := ratingDate(ctx, rating.Date, time.Now().UTC())
+	if !ok {
+		h.log.Warn(ctx, "Invalid rating date", map[string]interface{}{
+			"action":      "UpdateRatingForLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"rating_id":   fmt.Sprintf("%d", ratingID),
+			"date":        rating.Date.Format(time.RFC3339),
+		})
+		return createErrorResponse(unprocessableError("Rating date must not be in the future or before 2000-01-01"))
 	}
+	rating.Date = date
 
-	// Set rating ID, lugar ID, and date
+	// Set rating ID and lugar ID
 	rating.ID = ratingID
 	rating.LugarID = lugarID
-	rating.Date = time.Now()
 
-	// Update rating for lugar
-	if err := h.lugarRepo.UpdateRating(ctx, &rating); err != nil {
+	// Update rating for lugar, unless it changed since the client read it
+	if requestBody.ExpectedVersion != nil {
+		err = h.lugarRepo.UpdateRatingIfUnchanged(ctx, &rating, *requestBody.ExpectedVersion)
+	} else {
+		err = h.lugarRepo.UpdateRating(ctx, &rating)
+	}
+	if errors.Is(err, repository.ErrStaleWrite) {
+		h.log.Warn(ctx, "Stale rating update", map[string]interface{}{
+			"action":      "UpdateRatingForLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"rating_id":   fmt.Sprintf("%d", ratingID),
+		})
+		return createErrorResponse(conflictError("Rating was modified since it was read"))
+	}
+	if err != nil {
 		h.log.Error(ctx, "Error updating rating for lugar", err, map[string]interface{}{
 			"action":      "UpdateRatingForLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 			"rating_id":   fmt.Sprintf("%d", ratingID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error updating rating for lugar")
+		return createErrorResponse(internalError("Error updating rating for lugar"))
 	}
 
 	// Log success
@@ -773,7 +2214,7 @@ func (h *LugarHandler) DeleteRatingFromLugar(ctx context.Context, request events
 			"action":   "DeleteRatingFromLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
 	}
 
 	ratingID, err := strconv.Atoi(request.PathParameters["ratingId"])
@@ -783,7 +2224,7 @@ func (h *LugarHandler) DeleteRatingFromLugar(ctx context.Context, request events
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid rating ID")
+		return createErrorResponse(invalidIDError("Invalid rating ID"))
 	}
 
 	// Delete rating from lugar
@@ -794,7 +2235,7 @@ func (h *LugarHandler) DeleteRatingFromLugar(ctx context.Context, request events
 			"resource_id": fmt.Sprintf("%d", lugarID),
 			"rating_id":   fmt.Sprintf("%d", ratingID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error deleting rating from lugar")
+		return createErrorResponse(internalError("Error deleting rating from lugar"))
 	}
 
 	// Log success
@@ -806,12 +2247,70 @@ func (h *LugarHandler) DeleteRatingFromLugar(ctx context.Context, request events
 	})
 
 	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	return createNoContentResponse()
+}
+
+// DeleteUserRatingFromLugar handles DELETE /lugares/{id}/ratings?user_id= requests
+func (h *LugarHandler) DeleteUserRatingFromLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized rating removal request", map[string]interface{}{
+			"action":   "DeleteUserRatingFromLugar",
+			"resource": "lugares",
+		})
+		return response, nil
+	}
+
+	// Extract lugar ID from path parameters and user ID from query parameters
+	lugarID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
+			"action":   "DeleteUserRatingFromLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
+	}
+
+	userID, err := strconv.Atoi(request.QueryStringParameters["user_id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid user ID", err, map[string]interface{}{
+			"action":      "DeleteUserRatingFromLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(invalidIDError("Invalid user ID"))
+	}
+
+	// Delete the user's rating; the rating aggregates are refreshed by the database trigger
+	if err := h.lugarRepo.DeleteRatingByUser(ctx, lugarID, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.log.Warn(ctx, "User rating not found", map[string]interface{}{
+				"action":      "DeleteUserRatingFromLugar",
+				"resource":    "lugares",
+				"resource_id": fmt.Sprintf("%d", lugarID),
+				"user_id":     fmt.Sprintf("%d", userID),
+			})
+			return createErrorResponse(notFoundError("Rating not found"))
+		}
+		h.log.Error(ctx, "Error deleting user rating from lugar", err, map[string]interface{}{
+			"action":      "DeleteUserRatingFromLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"user_id":     fmt.Sprintf("%d", userID),
+		})
+		return createErrorResponse(internalError("Error deleting rating from lugar"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "User rating deleted from lugar successfully", map[string]interface{}{
+		"action":      "DeleteUserRatingFromLugar",
+		"resource":    "lugares",
+		"resource_id": fmt.Sprintf("%d", lugarID),
+		"user_id":     fmt.Sprintf("%d", userID),
+	})
+
+	// Return success response
+	return createNoContentResponse()
 }
 
 // GetRatingsForLugar handles GET /lugares/{id}/ratings requests
@@ -823,18 +2322,53 @@ func (h *LugarHandler) GetRatingsForLugar(ctx context.Context, request events.AP
 			"action":   "GetRatingsForLugar",
 			"resource": "lugares",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid lugar ID")
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
+	}
+
+	// Check that the lugar exists, so a missing lugar is not reported as one without ratings
+	exists, err := h.lugarRepo.Exists(ctx, lugarID)
+	if err != nil {
+		h.log.Error(ctx, "Error checking lugar", err, map[string]interface{}{
+			"action":      "GetRatingsForLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error getting ratings for lugar"))
+	}
+
+	// If lugar not found
+	if !exists {
+		h.log.Warn(ctx, "Lugar not found", map[string]interface{}{
+			"action":      "GetRatingsForLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(notFoundError("Lugar not found"))
+	}
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":      "GetRatingsForLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(validationError(err.Error()))
 	}
 
 	// Get ratings for lugar
-	ratings, err := h.lugarRepo.GetRatings(ctx, lugarID)
+	ratings, err := h.lugarRepo.GetRatings(ctx, lugarID, repository.Pagination{Limit: limit, Offset: offset})
 	if err != nil {
 		h.log.Error(ctx, "Error getting ratings for lugar", err, map[string]interface{}{
 			"action":      "GetRatingsForLugar",
 			"resource":    "lugares",
 			"resource_id": fmt.Sprintf("%d", lugarID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error getting ratings for lugar")
+		return createErrorResponse(internalError("Error getting ratings for lugar"))
+	}
+	if ratings == nil {
+		ratings = []*models.LugarRating{}
 	}
 
 	// Log success
@@ -846,5 +2380,242 @@ func (h *LugarHandler) GetRatingsForLugar(ctx context.Context, request events.AP
 	})
 
 	// Return ratings as JSON
-	return createJSONResponse(http.StatusOK, ratings)
+	return createPaginatedResponse(ctx, h.log, request, ratings, len(ratings), limit, offset, "ratings", func() (int, error) {
+		return h.lugarRepo.CountRatings(ctx, lugarID)
+	})
+}
+
+// GetMyRatingForLugar handles GET /lugares/{id}/ratings/mine requests, returning
+// the authenticated user's rating for the lugar
+func (h *LugarHandler) GetMyRatingForLugar(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Require an authenticated user
+	user := currentUser(ctx)
+	if user == nil {
+		h.log.Warn(ctx, "Unauthenticated rating request", map[string]interface{}{
+			"action":   "GetMyRatingForLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(unauthorizedError("Authentication required"))
+	}
+
+	// Extract lugar ID from path parameters
+	lugarID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
+			"action":   "GetMyRatingForLugar",
+			"resource": "lugares",
+		})
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
+	}
+
+	// Get the user's rating from repository
+	rating, err := h.lugarRepo.GetUserRating(ctx, lugarID, user.ID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting user rating for lugar", err, map[string]interface{}{
+			"action":      "GetMyRatingForLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"user_id":     fmt.Sprintf("%d", user.ID),
+		})
+		return createErrorResponse(internalError("Error getting rating for lugar"))
+	}
+
+	// If the user has not rated the lugar
+	if rating == nil {
+		h.log.Warn(ctx, "Rating not found", map[string]interface{}{
+			"action":      "GetMyRatingForLugar",
+			"resource":    "lugares",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+			"user_id":     fmt.Sprintf("%d", user.ID),
+		})
+		return createErrorResponse(notFoundError("Rating not found"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "User rating retrieved for lugar successfully", map[string]interface{}{
+		"action":      "GetMyRatingForLugar",
+		"resource":    "lugares",
+		"resource_id": fmt.Sprintf("%d", lugarID),
+		"user_id":     fmt.Sprintf("%d", user.ID),
+	})
+
+	// Return rating as JSON; it is specific to the caller, so it is not cached
+	return createPrivateJSONResponse(http.StatusOK, rating)
+}
+
+// maxRatingSummaryIDs is the maximum number of lugar IDs in one rating summaries request
+const maxRatingSummaryIDs = 500
+
+// GetRatingSummaries handles POST /lugares/ratings-summaries requests, returning
+// the average rating and number of ratings of each lugar in {"ids": [...]}, keyed by ID
+func (h *LugarHandler) GetRatingSummaries(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Parse request body
+	var requestBody struct {
+		IDs []int `json:"ids"`
+	}
+	if err := decodeJSONBody(request, &requestBody); err != nil {
+		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
+			"action":   "GetRatingSummaries",
+			"resource": "lugares",
+		})
+		return createErrorResponse(err)
+	}
+
+	// Validate IDs
+	if len(requestBody.IDs) == 0 || len(requestBody.IDs) > maxRatingSummaryIDs {
+		h.log.Warn(ctx, "Invalid rating summaries request: ids count", map[string]interface{}{
+			"action":   "GetRatingSummaries",
+			"resource": "lugares",
+			"count":    len(requestBody.IDs),
+		})
+		return createErrorResponse(unprocessableError(fmt.Sprintf("Between 1 and %d ids must be provided", maxRatingSummaryIDs)))
+	}
+
+	// Get rating summaries from repository
+	summaries, err := h.lugarRepo.RatingSummaries(ctx, requestBody.IDs)
+	if err != nil {
+		h.log.Error(ctx, "Error getting rating summaries", err, map[string]interface{}{
+			"action":   "GetRatingSummaries",
+			"resource": "lugares",
+		})
+		return createErrorResponse(internalError("Error getting rating summaries"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Rating summaries retrieved successfully", map[string]interface{}{
+		"action":   "GetRatingSummaries",
+		"resource": "lugares",
+		"count":    len(summaries),
+	})
+
+	// Return rating summaries as JSON
+	return createJSONResponse(http.StatusOK, summaries)
+}
+
+// GetRatingDistribution handles GET /ratings/distribution requests
+func (h *LugarHandler) GetRatingDistribution(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Get distribution from repository
+	distribution, err := h.lugarRepo.GlobalRatingDistribution(ctx)
+	if err != nil {
+		h.log.Error(ctx, "Error getting rating distribution", err, map[string]interface{}{
+			"action":   "GetRatingDistribution",
+			"resource": "ratings",
+		})
+		return createErrorResponse(internalError("Error getting rating distribution"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Rating distribution retrieved successfully", map[string]interface{}{
+		"action":   "GetRatingDistribution",
+		"resource": "ratings",
+		"total":    distribution.Total,
+	})
+
+	// Return distribution as JSON
+	return createCachedJSONResponse(http.StatusOK, distribution, "ratings")
+}
+
+const (
+	// defaultRecentRatingsLimit is the number of ratings returned when the request does not set a limit
+	defaultRecentRatingsLimit = 10
+	// maxRecentRatingsLimit is the largest accepted recent ratings limit
+	maxRecentRatingsLimit = 50
+)
+
+// ListRatingsByValue handles GET /ratings?rating= requests, listing the ratings
+// with a star value across all lugares, newest first
+func (h *LugarHandler) ListRatingsByValue(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized ratings feed request", map[string]interface{}{
+			"action":   "ListRatingsByValue",
+			"resource": "ratings",
+		})
+		return response, nil
+	}
+
+	// Validate rating value
+	rating, err := strconv.Atoi(request.QueryStringParameters["rating"])
+	if err != nil || rating < 1 || rating > 5 {
+		h.log.Warn(ctx, "Invalid rating value", map[string]interface{}{
+			"action":   "ListRatingsByValue",
+			"resource": "ratings",
+			"rating":   request.QueryStringParameters["rating"],
+		})
+		return createErrorResponse(validationError("rating must be between 1 and 5"))
+	}
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListRatingsByValue",
+			"resource": "ratings",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get ratings from repository
+	ctx = withViewer(ctx)
+	ratings, err := h.lugarRepo.ListRatingsByValue(ctx, rating, repository.Pagination{Limit: limit, Offset: offset})
+	if err != nil {
+		h.log.Error(ctx, "Error listing ratings by value", err, map[string]interface{}{
+			"action":   "ListRatingsByValue",
+			"resource": "ratings",
+		})
+		return createErrorResponse(internalError("Error listing ratings"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Ratings listed successfully", map[string]interface{}{
+		"action":   "ListRatingsByValue",
+		"resource": "ratings",
+		"rating":   rating,
+		"count":    len(ratings),
+	})
+
+	// Return ratings as JSON; the feed is only for admins, so it is not cached
+	return markPrivate(createPaginatedResponse(ctx, h.log, request, ratings, len(ratings), limit, offset, "ratings", func() (int, error) {
+		return h.lugarRepo.CountRatingsByValue(ctx, rating)
+	}))
+}
+
+// GetRecentRatings handles GET /ratings/recent?limit= requests
+func (h *LugarHandler) GetRecentRatings(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Validate limit
+	limit, err := parseLimitParam(request, defaultRecentRatingsLimit, maxRecentRatingsLimit)
+	if err != nil {
+		h.log.Warn(ctx, "Invalid recent ratings limit", map[string]interface{}{
+			"action":   "GetRecentRatings",
+			"resource": "ratings",
+			"limit":    request.QueryStringParameters["limit"],
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get recent ratings from repository
+	ctx = withViewer(ctx)
+	ratings, err := h.lugarRepo.RecentRatings(ctx, limit)
+	if err != nil {
+		h.log.Error(ctx, "Error getting recent ratings", err, map[string]interface{}{
+			"action":   "GetRecentRatings",
+			"resource": "ratings",
+		})
+		return createErrorResponse(internalError("Error getting recent ratings"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Recent ratings retrieved successfully", map[string]interface{}{
+		"action":   "GetRecentRatings",
+		"resource": "ratings",
+		"count":    len(ratings),
+	})
+
+	// Return ratings as JSON; signed in callers also see the ratings of
+	// their unpublished lugares, so their responses are not cached
+	response, err := createCachedJSONResponse(http.StatusOK, ratings, "ratings")
+	if currentUser(ctx) != nil {
+		return markPrivate(response, err)
+	}
+	return response, err
 }
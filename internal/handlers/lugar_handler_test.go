@@ -0,0 +1,2401 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/geocoding"
+	"github.com/site-geav-api/internal/models"
+	"github.com/site-geav-api/internal/repository"
+)
+
+func TestListLugaresInBoundingBox(t *testing.T) {
+	validBox := map[string]string{"min_lat": "-23.6", "min_lng": "-46.8", "max_lat": "-23.4", "max_lng": "-46.5"}
+
+	tests := []struct {
+		name       string
+		params     map[string]string
+		listErr    error
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "valid box", params: validBox, wantStatus: http.StatusOK, wantCount: 2},
+		{name: "missing parameter", params: map[string]string{"min_lat": "-23.6", "min_lng": "-46.8", "max_lat": "-23.4"}, wantStatus: http.StatusBadRequest},
+		{name: "non-numeric parameter", params: map[string]string{"min_lat": "south", "min_lng": "-46.8", "max_lat": "-23.4", "max_lng": "-46.5"}, wantStatus: http.StatusBadRequest},
+		{name: "min latitude above max", params: map[string]string{"min_lat": "-23.4", "min_lng": "-46.8", "max_lat": "-23.6", "max_lng": "-46.5"}, wantStatus: http.StatusBadRequest},
+		{name: "equal longitudes", params: map[string]string{"min_lat": "-23.6", "min_lng": "-46.5", "max_lat": "-23.4", "max_lng": "-46.5"}, wantStatus: http.StatusBadRequest},
+		{name: "repository error", params: validBox, listErr: errors.New("connection refused"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBox [4]float64
+			repo := &fakeLugarRepo{
+				listInBoundingBox: func(minLat, minLng, maxLat, maxLng float64, page repository.Pagination) ([]*models.Lugar, error) {
+					gotBox = [4]float64{minLat, minLng, maxLat, maxLng}
+					if tt.listErr != nil {
+						return nil, tt.listErr
+					}
+					return []*models.Lugar{{ID: 1}, {ID: 2}}, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.ListLugaresInBoundingBox(context.Background(), queryRequest(tt.params))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if want := [4]float64{-23.6, -46.8, -23.4, -46.5}; gotBox != want {
+				t.Errorf("box = %v, want %v", gotBox, want)
+			}
+			var lugares []*models.Lugar
+			decodeBody(t, response, &lugares)
+			if len(lugares) != tt.wantCount {
+				t.Errorf("got %d lugares, want %d", len(lugares), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestFindDuplicateLugares(t *testing.T) {
+	tests := []struct {
+		name         string
+		params       map[string]string
+		wantStatus   int
+		wantNome     string
+		wantEndereco string
+	}{
+		{name: "name only", params: map[string]string{"nome_local": "  Sitio Alegre "}, wantStatus: http.StatusOK, wantNome: "Sitio Alegre"},
+		{name: "address only", params: map[string]string{"endereco_completo": "Rua A, 1"}, wantStatus: http.StatusOK, wantEndereco: "Rua A, 1"},
+		{name: "neither", params: map[string]string{"nome_local": "   "}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotNome, gotEndereco string
+			repo := &fakeLugarRepo{
+				findSimilar: func(nomeLocal, enderecoCompleto string) ([]*models.Lugar, error) {
+					gotNome, gotEndereco = nomeLocal, enderecoCompleto
+					return []*models.Lugar{{ID: 3, NomeLocal: "Sitio Alegre"}}, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.FindDuplicateLugares(context.Background(), queryRequest(tt.params))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus == http.StatusOK && (gotNome != tt.wantNome || gotEndereco != tt.wantEndereco) {
+				t.Errorf("searched (%q, %q), want (%q, %q)", gotNome, gotEndereco, tt.wantNome, tt.wantEndereco)
+			}
+		})
+	}
+}
+
+func TestAddImageToLugarLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       string
+		wantLimit int
+	}{
+		{"configured limit", "3", 3},
+		{"zero falls back to the default", "0", defaultMaxImagesPerLugar},
+		{"negative falls back to the default", "-1", defaultMaxImagesPerLugar},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_IMAGES_PER_LUGAR", tt.env)
+
+			// The fake enforces the limit it is given, as the repository does
+			// in the transaction adding the images
+			var stored []*models.LugarImage
+			repo := &fakeLugarRepo{
+				maxDisplayOrder: func(lugarID int) (int, error) {
+					return len(stored), nil
+				},
+				addImage: func(image *models.LugarImage, maxImages int) (int, error) {
+					if maxImages != tt.wantLimit {
+						t.Errorf("maxImages = %d, want %d", maxImages, tt.wantLimit)
+					}
+					if len(stored) >= maxImages {
+						return 0, fmt.Errorf("lugar 1 already has %d images: %w", len(stored), repository.ErrImageLimit)
+					}
+					stored = append(stored, image)
+					return len(stored), nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+			request := bodyRequest(`{"image_url": "https://example.com/a.jpg"}`, map[string]string{"id": "1"})
+
+			// Adding up to the limit succeeds
+			for i := 0; i < tt.wantLimit; i++ {
+				response, _ := h.AddImageToLugar(context.Background(), request)
+				if response.StatusCode != http.StatusCreated {
+					t.Fatalf("image %d: status = %d, want 201 (body %s)", i+1, response.StatusCode, response.Body)
+				}
+			}
+
+			// The next one is rejected
+			response, _ := h.AddImageToLugar(context.Background(), request)
+			if response.StatusCode != http.StatusConflict {
+				t.Fatalf("image over the limit: status = %d, want 409 (body %s)", response.StatusCode, response.Body)
+			}
+			if len(stored) != tt.wantLimit {
+				t.Errorf("stored %d images, want %d", len(stored), tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestAddImageToLugarDisplayOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantOrder  int
+	}{
+		{name: "omitted order takes the next position", body: `{"image_url": "https://example.com/c.jpg"}`, wantStatus: http.StatusCreated, wantOrder: 5},
+		{name: "zero order takes the next position", body: `{"image_url": "https://example.com/c.jpg", "display_order": 0}`, wantStatus: http.StatusCreated, wantOrder: 5},
+		{name: "free order in a gap is kept", body: `{"image_url": "https://example.com/c.jpg", "display_order": 2}`, wantStatus: http.StatusCreated, wantOrder: 2},
+		{name: "order after the last image is kept", body: `{"image_url": "https://example.com/c.jpg", "display_order": 5}`, wantStatus: http.StatusCreated, wantOrder: 5},
+		{name: "explicit duplicate order is a conflict", body: `{"image_url": "https://example.com/c.jpg", "display_order": 4}`, wantStatus: http.StatusConflict},
+		{name: "negative order", body: `{"image_url": "https://example.com/c.jpg", "display_order": -1}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "order leaving a gap after the last image", body: `{"image_url": "https://example.com/c.jpg", "display_order": 6}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "two images may take the next two positions", body: `[{"image_url": "https://example.com/c.jpg", "display_order": 5}, {"image_url": "https://example.com/d.jpg", "display_order": 6}]`, wantStatus: http.StatusCreated},
+		{name: "two images leaving a gap", body: `[{"image_url": "https://example.com/c.jpg", "display_order": 5}, {"image_url": "https://example.com/d.jpg", "display_order": 7}]`, wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The lugar has images at orders 1 and 4: deleting the others left a gap
+			used := map[int]bool{1: true, 4: true}
+			repo := &fakeLugarRepo{
+				maxDisplayOrder: func(lugarID int) (int, error) {
+					return 4, nil
+				},
+				addImage: func(image *models.LugarImage, maxImages int) (int, error) {
+					if image.DisplayOrder == 0 {
+						image.DisplayOrder = 5
+					}
+					if used[image.DisplayOrder] {
+						return 0, fmt.Errorf("display order %d: %w", image.DisplayOrder, repository.ErrAlreadyExists)
+					}
+					image.ID = 30
+					return image.ID, nil
+				},
+				addImages: func(images []*models.LugarImage, maxImages int) error {
+					return nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.AddImageToLugar(adminContext(), bodyRequest(tt.body, map[string]string{"id": "1"}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantOrder != 0 {
+				var image models.LugarImage
+				decodeBody(t, response, &image)
+				if image.DisplayOrder != tt.wantOrder {
+					t.Errorf("display_order = %d, want %d", image.DisplayOrder, tt.wantOrder)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateDisplayOrders(t *testing.T) {
+	tests := []struct {
+		name      string
+		orders    []int
+		lastOrder int
+		wantErr   bool
+	}{
+		{name: "next position", orders: []int{0}, lastOrder: 3},
+		{name: "in range", orders: []int{2}, lastOrder: 3},
+		{name: "right after the last image", orders: []int{4}, lastOrder: 3},
+		{name: "first image of a lugar", orders: []int{1}, lastOrder: 0},
+		{name: "negative", orders: []int{-1}, lastOrder: 3, wantErr: true},
+		{name: "gap after the last image", orders: []int{5}, lastOrder: 3, wantErr: true},
+		{name: "gap on the first image", orders: []int{2}, lastOrder: 0, wantErr: true},
+		{name: "batch filling the next positions", orders: []int{4, 5}, lastOrder: 3},
+		{name: "batch with a gap", orders: []int{4, 6}, lastOrder: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var images []*models.LugarImage
+			for _, order := range tt.orders {
+				images = append(images, &models.LugarImage{DisplayOrder: order})
+			}
+			err := validateDisplayOrders(images, tt.lastOrder)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDisplayOrders(%v, %d) error = %v, want error %v", tt.orders, tt.lastOrder, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetImageFromLugar(t *testing.T) {
+	images := []*models.LugarImage{
+		{ID: 10, LugarID: 1, ImageURL: "https://example.com/a.jpg", DisplayOrder: 1},
+		{ID: 11, LugarID: 2, ImageURL: "https://example.com/b.jpg", DisplayOrder: 1},
+	}
+	repo := &fakeLugarRepo{
+		getImageByID: func(lugarID, imageID int) (*models.LugarImage, error) {
+			for _, image := range images {
+				if image.ID == imageID && image.LugarID == lugarID {
+					return image, nil
+				}
+			}
+			return nil, nil
+		},
+	}
+	h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+	tests := []struct {
+		name       string
+		lugarID    string
+		imageID    string
+		wantStatus int
+	}{
+		{"found", "1", "10", http.StatusOK},
+		{"image of another lugar", "1", "11", http.StatusNotFound},
+		{"missing image", "1", "99", http.StatusNotFound},
+		{"invalid lugar ID", "x", "10", http.StatusBadRequest},
+		{"invalid image ID", "1", "x", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := h.GetImageFromLugar(context.Background(), pathRequest(map[string]string{"id": tt.lugarID, "imageId": tt.imageID}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				var image models.LugarImage
+				decodeBody(t, response, &image)
+				if image.ID != 10 || image.ImageURL != "https://example.com/a.jpg" {
+					t.Errorf("got image %+v", image)
+				}
+			}
+		})
+	}
+}
+
+func TestGetRatingDistribution(t *testing.T) {
+	tests := []struct {
+		name         string
+		distribution *models.RatingDistribution
+		err          error
+		wantStatus   int
+	}{
+		{"with ratings", &models.RatingDistribution{Counts: map[int]int{1: 0, 2: 1, 3: 0, 4: 2, 5: 1}, Total: 4, Average: 3.75}, nil, http.StatusOK},
+		{"no ratings", &models.RatingDistribution{Counts: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}, nil, http.StatusOK},
+		{"repository error", nil, errors.New("timeout"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLugarRepo{
+				ratingDistribution: func() (*models.RatingDistribution, error) {
+					return tt.distribution, tt.err
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.GetRatingDistribution(context.Background(), queryRequest(nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got models.RatingDistribution
+			decodeBody(t, response, &got)
+			if got.Total != tt.distribution.Total || got.Average != tt.distribution.Average || len(got.Counts) != 5 {
+				t.Errorf("got %+v, want %+v", got, *tt.distribution)
+			}
+		})
+	}
+}
+
+func TestListLugaresByRamos(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     events.APIGatewayProxyRequest
+		wantStatus  int
+		wantRamoIDs []int
+	}{
+		{
+			name:        "several ramos",
+			request:     events.APIGatewayProxyRequest{MultiValueQueryStringParameters: map[string][]string{"ramo_id": {"1", "3"}}},
+			wantStatus:  http.StatusOK,
+			wantRamoIDs: []int{1, 3},
+		},
+		{
+			name:        "one ramo",
+			request:     queryRequest(map[string]string{"ramo_id": "2"}),
+			wantStatus:  http.StatusOK,
+			wantRamoIDs: []int{2},
+		},
+		{
+			name:       "no ramo filter",
+			request:    queryRequest(nil),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid ramo",
+			request:    events.APIGatewayProxyRequest{MultiValueQueryStringParameters: map[string][]string{"ramo_id": {"1", "escoteiro"}}},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			repo := &fakeLugarRepo{
+				list: func(opts repository.LugarListOptions) ([]*models.Lugar, error) {
+					got = opts.RamoIDs
+					return []*models.Lugar{}, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.ListLugares(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus == http.StatusOK && !reflect.DeepEqual(got, tt.wantRamoIDs) && len(got)+len(tt.wantRamoIDs) > 0 {
+				t.Errorf("ramo IDs = %v, want %v", got, tt.wantRamoIDs)
+			}
+		})
+	}
+}
+
+func TestDeleteUserRatingFromLugar(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		lugarID    string
+		userID     string
+		deleteErr  error
+		wantStatus int
+	}{
+		{name: "admin removes the rating", ctx: adminContext(), lugarID: "1", userID: "2", wantStatus: http.StatusNoContent},
+		{name: "missing rating", ctx: adminContext(), lugarID: "1", userID: "2", deleteErr: fmt.Errorf("rating: %w", repository.ErrNotFound), wantStatus: http.StatusNotFound},
+		{name: "repository error", ctx: adminContext(), lugarID: "1", userID: "2", deleteErr: errors.New("connection reset"), wantStatus: http.StatusInternalServerError},
+		{name: "invalid user ID", ctx: adminContext(), lugarID: "1", userID: "x", wantStatus: http.StatusBadRequest},
+		{name: "not an admin", ctx: userContext(2, "read"), lugarID: "1", userID: "2", wantStatus: http.StatusForbidden},
+		{name: "anonymous", ctx: context.Background(), lugarID: "1", userID: "2", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted [2]int
+			repo := &fakeLugarRepo{
+				deleteRatingByUser: func(lugarID, userID int) error {
+					deleted = [2]int{lugarID, userID}
+					return tt.deleteErr
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+			request := events.APIGatewayProxyRequest{
+				PathParameters:        map[string]string{"id": tt.lugarID},
+				QueryStringParameters: map[string]string{"user_id": tt.userID},
+			}
+
+			response, err := h.DeleteUserRatingFromLugar(tt.ctx, request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus == http.StatusNoContent && deleted != [2]int{1, 2} {
+				t.Errorf("deleted the rating of %v, want lugar 1 and user 2", deleted)
+			}
+		})
+	}
+}
+
+func TestRatingDates(t *testing.T) {
+	past := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		date       string
+		wantStatus int
+		wantDate   *time.Time
+		wantNow    bool
+	}{
+		{name: "no date is dated now", ctx: userContext(2, "read"), wantStatus: http.StatusOK, wantNow: true},
+		{name: "admin records a past rating", ctx: adminContext(), date: past.Format(time.RFC3339), wantStatus: http.StatusOK, wantDate: &past},
+		{name: "admin future date", ctx: adminContext(), date: time.Now().Add(time.Hour).UTC().Format(time.RFC3339), wantStatus: http.StatusUnprocessableEntity},
+		{name: "admin date within the clock skew", ctx: adminContext(), date: time.Now().Add(time.Minute).UTC().Format(time.RFC3339), wantStatus: http.StatusOK},
+		{name: "admin absurd past date", ctx: adminContext(), date: "1970-01-01T00:00:00Z", wantStatus: http.StatusUnprocessableEntity},
+		{name: "user past date is ignored", ctx: userContext(2, "read"), date: past.Format(time.RFC3339), wantStatus: http.StatusOK, wantNow: true},
+		{name: "user future date is ignored", ctx: userContext(2, "read"), date: "2999-01-01T00:00:00Z", wantStatus: http.StatusOK, wantNow: true},
+	}
+
+	handlers := []struct {
+		name       string
+		okStatus   int
+		handle     func(h *LugarHandler, ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+		pathParams map[string]string
+	}{
+		{"AddRatingToLugar", http.StatusCreated, (*LugarHandler).AddRatingToLugar, map[string]string{"id": "1"}},
+		{"UpdateRatingForLugar", http.StatusOK, (*LugarHandler).UpdateRatingForLugar, map[string]string{"id": "1", "ratingId": "7"}},
+	}
+
+	for _, handler := range handlers {
+		for _, tt := range tests {
+			t.Run(handler.name+"/"+tt.name, func(t *testing.T) {
+				var stored *models.LugarRating
+				repo := &fakeLugarRepo{
+					addRating: func(rating *models.LugarRating) (int, error) {
+						stored = rating
+						return 7, nil
+					},
+					updateRating: func(rating *models.LugarRating) error {
+						stored = rating
+						return nil
+					},
+				}
+				h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+				body := `{"rating": 4}`
+				if tt.date != "" {
+					body = fmt.Sprintf(`{"rating": 4, "date": %q}`, tt.date)
+				}
+				before := time.Now().UTC()
+				response, err := handler.handle(h, tt.ctx, bodyRequest(body, handler.pathParams))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				wantStatus := tt.wantStatus
+				if wantStatus == http.StatusOK {
+					wantStatus = handler.okStatus
+				}
+				if response.StatusCode != wantStatus {
+					t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, wantStatus, response.Body)
+				}
+				if wantStatus != handler.okStatus {
+					if stored != nil {
+						t.Error("an invalid rating was stored")
+					}
+					return
+				}
+
+				if tt.wantDate != nil && !stored.Date.Equal(*tt.wantDate) {
+					t.Errorf("date = %v, want %v", stored.Date, *tt.wantDate)
+				}
+				if tt.wantNow && (stored.Date.Before(before) || stored.Date.After(time.Now().UTC())) {
+					t.Errorf("date = %v, want the current time", stored.Date)
+				}
+			})
+		}
+	}
+}
+
+func TestListLugaresUnrated(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      map[string]string
+		wantUnrated bool
+		wantPage    repository.Pagination
+	}{
+		{name: "unrated", params: map[string]string{"unrated": "true"}, wantUnrated: true, wantPage: repository.Pagination{Limit: defaultPageLimit}},
+		{name: "unrated with a page", params: map[string]string{"unrated": "true", "limit": "5", "offset": "10"}, wantUnrated: true, wantPage: repository.Pagination{Limit: 5, Offset: 10}},
+		{name: "no filter", params: nil, wantPage: repository.Pagination{Limit: defaultPageLimit}},
+		{name: "unrated=false", params: map[string]string{"unrated": "false"}, wantPage: repository.Pagination{Limit: defaultPageLimit}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got repository.LugarListOptions
+			repo := &fakeLugarRepo{
+				list: func(opts repository.LugarListOptions) ([]*models.Lugar, error) {
+					got = opts
+					return []*models.Lugar{}, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.ListLugares(context.Background(), queryRequest(tt.params))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", response.StatusCode, response.Body)
+			}
+			if got.Unrated != tt.wantUnrated || got.Pagination != tt.wantPage {
+				t.Errorf("got unrated %v and page %+v, want %v and %+v", got.Unrated, got.Pagination, tt.wantUnrated, tt.wantPage)
+			}
+		})
+	}
+}
+
+func TestChangeLugarOwner(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		id         string
+		body       string
+		wantStatus int
+		wantOwner  int
+	}{
+		{name: "valid transfer", ctx: adminContext(), id: "7", body: `{"user_id": 2}`, wantStatus: http.StatusOK, wantOwner: 2},
+		{name: "nonexistent target user", ctx: adminContext(), id: "7", body: `{"user_id": 99}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "missing user_id", ctx: adminContext(), id: "7", body: `{}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "nonexistent lugar", ctx: adminContext(), id: "8", body: `{"user_id": 2}`, wantStatus: http.StatusNotFound},
+		{name: "invalid lugar ID", ctx: adminContext(), id: "x", body: `{"user_id": 2}`, wantStatus: http.StatusBadRequest},
+		{name: "non-admin", ctx: userContext(2, "read"), id: "7", body: `{"user_id": 2}`, wantStatus: http.StatusForbidden},
+		{name: "unauthenticated", ctx: context.Background(), id: "7", body: `{"user_id": 2}`, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := false
+			repo := &fakeLugarRepo{
+				getByID: func(id int) (*models.Lugar, error) {
+					if id != 7 {
+						return nil, nil
+					}
+					return &models.Lugar{ID: 7, NomeLocal: "Sítio", UserID: 1}, nil
+				},
+				changeOwner: func(id, userID int) error {
+					if userID != 1 && userID != 2 {
+						return fmt.Errorf("user with ID %d: %w", userID, repository.ErrInvalidReference)
+					}
+					changed = true
+					return nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.ChangeLugarOwner(tt.ctx, bodyRequest(tt.body, map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if changed != (tt.wantOwner != 0) {
+				t.Errorf("owner changed = %v, want %v", changed, tt.wantOwner != 0)
+			}
+			if tt.wantOwner != 0 {
+				var lugar models.Lugar
+				decodeBody(t, response, &lugar)
+				if lugar.UserID != tt.wantOwner {
+					t.Errorf("user_id = %d, want %d", lugar.UserID, tt.wantOwner)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateLugarKeepsOwner(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "another user_id", body: `{"nome_local": "Sítio Novo", "user_id": 1}`},
+		{name: "unknown user_id", body: `{"nome_local": "Sítio Novo", "user_id": 99}`},
+		{name: "no user_id", body: `{"nome_local": "Sítio Novo"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored *models.Lugar
+			repo := &fakeLugarRepo{
+				getByID: func(id int) (*models.Lugar, error) {
+					return &models.Lugar{ID: id, NomeLocal: "Sítio", UserID: 2}, nil
+				},
+				update: func(lugar *models.Lugar) error {
+					stored = lugar
+					return nil
+				},
+			}
+			log := &fakeLogger{}
+			h := NewLugarHandler(repo, nil, nil, nil, log)
+
+			response, err := h.UpdateLugar(adminContext(), bodyRequest(tt.body, map[string]string{"id": "7"}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", response.StatusCode, response.Body)
+			}
+			if stored == nil || stored.UserID != 2 {
+				t.Fatalf("stored lugar = %+v, want owner 2", stored)
+			}
+			var lugar models.Lugar
+			decodeBody(t, response, &lugar)
+			if lugar.UserID != 2 {
+				t.Errorf("user_id = %d, want 2", lugar.UserID)
+			}
+			changes, _ := log.last(t).metadata["changes"].(map[string]interface{})
+			if _, ok := changes["user_id"]; ok {
+				t.Errorf("changes = %v, want no user_id change", changes)
+			}
+		})
+	}
+}
+
+func TestGetRecentRatings(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      string
+		wantStatus int
+		wantLimit  int
+	}{
+		{name: "default limit", wantStatus: http.StatusOK, wantLimit: defaultRecentRatingsLimit},
+		{name: "explicit limit", limit: "3", wantStatus: http.StatusOK, wantLimit: 3},
+		{name: "largest limit", limit: "50", wantStatus: http.StatusOK, wantLimit: maxRecentRatingsLimit},
+		{name: "limit above the maximum", limit: "51", wantStatus: http.StatusBadRequest},
+		{name: "zero limit", limit: "0", wantStatus: http.StatusBadRequest},
+		{name: "non-numeric limit", limit: "ten", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotLimit int
+			repo := &fakeLugarRepo{
+				recentRatings: func(limit int) ([]*models.RecentRating, error) {
+					gotLimit = limit
+					return []*models.RecentRating{
+						{LugarRating: models.LugarRating{ID: 2, LugarID: 7, Rating: 4}, LugarNome: "Cachoeira"},
+					}, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+			query := map[string]string{}
+			if tt.limit != "" {
+				query["limit"] = tt.limit
+			}
+
+			response, err := h.GetRecentRatings(context.Background(), queryRequest(query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if gotLimit != tt.wantLimit {
+				t.Errorf("RecentRatings limit = %d, want %d", gotLimit, tt.wantLimit)
+			}
+			if tt.wantStatus == http.StatusOK {
+				var ratings []models.RecentRating
+				decodeBody(t, response, &ratings)
+				if len(ratings) != 1 || ratings[0].LugarNome != "Cachoeira" {
+					t.Errorf("ratings = %+v, want the lugar name", ratings)
+				}
+			}
+		})
+	}
+}
+
+func TestGetLugarHistory(t *testing.T) {
+	history := []*models.AuditEntry{
+		{ID: 1, Action: "CreateLugar"},
+		{ID: 2, Action: "UpdateLugar", Changes: map[string]interface{}{"nome_local": map[string]interface{}{"from": "Sítio", "to": "Sítio Novo"}}},
+	}
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		id          string
+		wantStatus  int
+		wantEntries int
+	}{
+		{name: "owner", ctx: userContext(2, "read"), id: "7", wantStatus: http.StatusOK, wantEntries: 2},
+		{name: "admin", ctx: adminContext(), id: "7", wantStatus: http.StatusOK, wantEntries: 2},
+		{name: "another user", ctx: userContext(3, "read"), id: "7", wantStatus: http.StatusForbidden},
+		{name: "unauthenticated", ctx: context.Background(), id: "7", wantStatus: http.StatusUnauthorized},
+		{name: "deleted lugar as admin", ctx: adminContext(), id: "8", wantStatus: http.StatusOK, wantEntries: 2},
+		{name: "deleted lugar as its former owner", ctx: userContext(2, "read"), id: "8", wantStatus: http.StatusForbidden},
+		{name: "lugar that never existed", ctx: adminContext(), id: "9", wantStatus: http.StatusNotFound},
+		{name: "invalid ID", ctx: adminContext(), id: "x", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLugarRepo{
+				getByID: func(id int) (*models.Lugar, error) {
+					if id == 7 {
+						return &models.Lugar{ID: 7, UserID: 2}, nil
+					}
+					return nil, nil
+				},
+			}
+			audit := &fakeAuditRepo{
+				listHistory: func(resource string, resourceID int) ([]*models.AuditEntry, error) {
+					// Only lugares 7 and 8 (deleted) have a history
+					if resource != "lugares" || (resourceID != 7 && resourceID != 8) {
+						return []*models.AuditEntry{}, nil
+					}
+					return history, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, audit, nil, &fakeLogger{})
+
+			response, err := h.GetLugarHistory(tt.ctx, pathRequest(map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				var entries []models.AuditEntry
+				decodeBody(t, response, &entries)
+				if len(entries) != tt.wantEntries || entries[0].Action != "CreateLugar" {
+					t.Errorf("entries = %+v, want the history in order", entries)
+				}
+			}
+		})
+	}
+}
+
+func TestListLugaresByAddress(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       map[string]string
+		wantAddress string
+	}{
+		{name: "address query", query: map[string]string{"endereco_q": "jardim"}, wantAddress: "jardim"},
+		{name: "address query is trimmed", query: map[string]string{"endereco_q": "  são josé "}, wantAddress: "são josé"},
+		{name: "blank address query is ignored", query: map[string]string{"endereco_q": "   "}},
+		{name: "no address query", query: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got repository.LugarListOptions
+			repo := &fakeLugarRepo{
+				list: func(opts repository.LugarListOptions) ([]*models.Lugar, error) {
+					got = opts
+					return []*models.Lugar{}, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.ListLugares(context.Background(), queryRequest(tt.query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", response.StatusCode, response.Body)
+			}
+			if got.Address != tt.wantAddress {
+				t.Errorf("address = %q, want %q", got.Address, tt.wantAddress)
+			}
+		})
+	}
+}
+
+func TestRemoveTagsFromLugar(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		body        string
+		wantStatus  int
+		wantTagIDs  []int
+		wantRemoved int
+	}{
+		{name: "subset of the tags", id: "7", body: `{"tag_ids": [1, 3]}`, wantStatus: http.StatusOK, wantTagIDs: []int{1, 3}, wantRemoved: 2},
+		{name: "nonexistent tag is a no-op", id: "7", body: `{"tag_ids": [99]}`, wantStatus: http.StatusOK, wantTagIDs: []int{99}, wantRemoved: 0},
+		{name: "empty tag_ids", id: "7", body: `{"tag_ids": []}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "missing tag_ids", id: "7", body: `{}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "invalid lugar ID", id: "x", body: `{"tag_ids": [1]}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The place has tags 1, 2 and 3
+			tags := map[int]bool{1: true, 2: true, 3: true}
+			var gotTagIDs []int
+			repo := &fakeLugarRepo{
+				removeTags: func(lugarID int, tagIDs []int) (int, error) {
+					gotTagIDs = tagIDs
+					removed := 0
+					for _, id := range tagIDs {
+						if tags[id] {
+							delete(tags, id)
+							removed++
+						}
+					}
+					return removed, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.RemoveTagsFromLugar(context.Background(), bodyRequest(tt.body, map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if !reflect.DeepEqual(gotTagIDs, tt.wantTagIDs) {
+				t.Errorf("tag IDs = %v, want %v", gotTagIDs, tt.wantTagIDs)
+			}
+			if tt.wantStatus == http.StatusOK {
+				var body map[string]int
+				decodeBody(t, response, &body)
+				if body["removed"] != tt.wantRemoved {
+					t.Errorf("removed = %d, want %d", body["removed"], tt.wantRemoved)
+				}
+			}
+		})
+	}
+}
+
+func TestGetRatingsForLugar(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "lugar with ratings", id: "7", wantStatus: http.StatusOK, wantCount: 2},
+		{name: "lugar without ratings", id: "8", wantStatus: http.StatusOK, wantCount: 0},
+		{name: "nonexistent lugar", id: "9", wantStatus: http.StatusNotFound},
+		{name: "invalid lugar ID", id: "x", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLugarRepo{
+				exists: func(id int) (bool, error) {
+					return id == 7 || id == 8, nil
+				},
+				getRatings: func(lugarID int, page repository.Pagination) ([]*models.LugarRating, error) {
+					if lugarID != 7 {
+						return nil, nil
+					}
+					return []*models.LugarRating{
+						{ID: 1, LugarID: 7, UserID: 1, Rating: 5},
+						{ID: 2, LugarID: 7, UserID: 2, Rating: 3},
+					}, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.GetRatingsForLugar(context.Background(), pathRequest(map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			// A lugar without ratings is an empty array, not null
+			if tt.wantCount == 0 && response.Body != "[]" {
+				t.Errorf("body = %s, want []", response.Body)
+			}
+			var ratings []models.LugarRating
+			decodeBody(t, response, &ratings)
+			if len(ratings) != tt.wantCount {
+				t.Errorf("got %d ratings, want %d", len(ratings), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestGetSimilarLugares(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		query      map[string]string
+		wantStatus int
+		wantLimit  int
+		wantIDs    []int
+	}{
+		{name: "default limit", id: "7", wantStatus: http.StatusOK, wantLimit: defaultSimilarLugaresLimit, wantIDs: []int{9, 8}},
+		{name: "explicit limit", id: "7", query: map[string]string{"limit": "1"}, wantStatus: http.StatusOK, wantLimit: 1, wantIDs: []int{9}},
+		{name: "limit over the maximum", id: "7", query: map[string]string{"limit": fmt.Sprintf("%d", maxSimilarLugaresLimit+1)}, wantStatus: http.StatusBadRequest},
+		{name: "nonexistent lugar", id: "99", wantStatus: http.StatusNotFound},
+		{name: "invalid lugar ID", id: "x", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotLimit int
+			repo := &fakeLugarRepo{
+				exists: func(id int) (bool, error) { return id == 7, nil },
+				listSimilar: func(lugarID, limit int) ([]*models.SimilarLugar, error) {
+					gotLimit = limit
+					similar := []*models.SimilarLugar{
+						{Lugar: &models.Lugar{ID: 9}, SharedTags: []*models.TagLugar{{ID: 1, Name: "rio"}, {ID: 2, Name: "lago"}}, SharedRamos: 1, Overlap: 3},
+						{Lugar: &models.Lugar{ID: 8}, SharedTags: []*models.TagLugar{}, SharedRamos: 1, Overlap: 1},
+					}
+					if limit < len(similar) {
+						similar = similar[:limit]
+					}
+					return similar, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			request := pathRequest(map[string]string{"id": tt.id})
+			request.QueryStringParameters = tt.query
+			response, err := h.GetSimilarLugares(context.Background(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if gotLimit != tt.wantLimit {
+				t.Errorf("limit = %d, want %d", gotLimit, tt.wantLimit)
+			}
+			var similar []models.SimilarLugar
+			decodeBody(t, response, &similar)
+			var ids []int
+			for _, item := range similar {
+				ids = append(ids, item.ID)
+				if item.SharedTags == nil {
+					t.Errorf("lugar %d: shared_tags is null", item.ID)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("IDs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestIncludeDeletedLugares(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		query       map[string]string
+		wantDeleted bool
+		// The lists of admins also hold unpublished lugares, so they are never cached
+		wantPrivateList bool
+	}{
+		{name: "admin asking for deleted lugares", ctx: adminContext(), query: map[string]string{"include_deleted": "true"}, wantDeleted: true, wantPrivateList: true},
+		{name: "admin not asking", ctx: adminContext(), wantPrivateList: true},
+		{name: "non-admin asking is ignored", ctx: userContext(2, "read"), query: map[string]string{"include_deleted": "true"}},
+		{name: "anonymous asking is ignored", ctx: context.Background(), query: map[string]string{"include_deleted": "true"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deletedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+			active := &models.Lugar{ID: 1, NomeLocal: "Sítio", Published: true}
+			deleted := &models.Lugar{ID: 2, NomeLocal: "Chácara", Published: true, DeletedAt: &deletedAt}
+			var listOpts repository.LugarListOptions
+			repo := &fakeLugarRepo{
+				getByID: func(id int) (*models.Lugar, error) {
+					if id == 1 {
+						return active, nil
+					}
+					return nil, nil
+				},
+				getByIDWithDeleted: func(id int) (*models.Lugar, error) {
+					if id == 2 {
+						return deleted, nil
+					}
+					return active, nil
+				},
+				list: func(opts repository.LugarListOptions) ([]*models.Lugar, error) {
+					listOpts = opts
+					if opts.IncludeDeleted {
+						return []*models.Lugar{active, deleted}, nil
+					}
+					return []*models.Lugar{active}, nil
+				},
+				count: func(opts repository.LugarListOptions) (int, error) { return 2, nil },
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			// Getting the deleted lugar
+			request := pathRequest(map[string]string{"id": "2"})
+			request.QueryStringParameters = tt.query
+			response, err := h.GetLugar(tt.ctx, request)
+			if err != nil {
+				t.Fatalf("GetLugar: unexpected error: %v", err)
+			}
+			wantStatus := http.StatusNotFound
+			if tt.wantDeleted {
+				wantStatus = http.StatusOK
+			}
+			if response.StatusCode != wantStatus {
+				t.Fatalf("GetLugar: status = %d, want %d (body %s)", response.StatusCode, wantStatus, response.Body)
+			}
+			if tt.wantDeleted && response.Headers["Cache-Control"] != privateCacheControl {
+				t.Errorf("GetLugar: Cache-Control = %q, want %q", response.Headers["Cache-Control"], privateCacheControl)
+			}
+
+			// Listing
+			response, err = h.ListLugares(tt.ctx, queryRequest(tt.query))
+			if err != nil {
+				t.Fatalf("ListLugares: unexpected error: %v", err)
+			}
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("ListLugares: status = %d, want 200 (body %s)", response.StatusCode, response.Body)
+			}
+			if listOpts.IncludeDeleted != tt.wantDeleted {
+				t.Errorf("ListLugares: IncludeDeleted = %v, want %v", listOpts.IncludeDeleted, tt.wantDeleted)
+			}
+			private := response.Headers["Cache-Control"] == privateCacheControl
+			if private != tt.wantPrivateList {
+				t.Errorf("ListLugares: Cache-Control = %q, want private %v", response.Headers["Cache-Control"], tt.wantPrivateList)
+			}
+		})
+	}
+}
+
+func TestImportLugares(t *testing.T) {
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		body         string
+		wantStatus   int
+		wantImported []string
+		wantResults  []models.LugarImportResult
+	}{
+		{
+			name:         "batch with new tags",
+			ctx:          adminContext(),
+			body:         `[{"nome_local": "Sítio", "tags": [{"name": " tirolesa "}], "ramos": [{"name": "pioneiro"}]}, {"nome_local": "Chácara"}]`,
+			wantStatus:   http.StatusOK,
+			wantImported: []string{"Sítio", "Chácara"},
+			wantResults:  []models.LugarImportResult{{Index: 0, ID: 10}, {Index: 1, ID: 11}},
+		},
+		{
+			name:         "invalid items follow the create rules",
+			ctx:          adminContext(),
+			body:         `[{"nome_local": "   "}, {"nome_local": "Sítio", "link_site": "not a url"}, {"nome_local": "Chácara"}]`,
+			wantStatus:   http.StatusOK,
+			wantImported: []string{"Chácara"},
+			wantResults:  []models.LugarImportResult{{Index: 0, Error: "Nome local is required"}, {Index: 1, Error: "link_site must be an absolute http or https URL"}, {Index: 2, ID: 10}},
+		},
+		{
+			name:        "invalid tag name",
+			ctx:         adminContext(),
+			body:        `[{"nome_local": "Sítio", "tags": [{"name": ""}]}]`,
+			wantStatus:  http.StatusOK,
+			wantResults: []models.LugarImportResult{{Index: 0, Error: "Tag names must have between 1 and 50 characters"}},
+		},
+		{
+			name:         "repository failure keeps the fixed message",
+			ctx:          adminContext(),
+			body:         `[{"nome_local": "Duplicado"}]`,
+			wantStatus:   http.StatusOK,
+			wantImported: []string{"Duplicado"},
+			wantResults:  []models.LugarImportResult{{Index: 0, Error: "Duplicate lugar"}},
+		},
+		{name: "empty batch", ctx: adminContext(), body: `[]`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "non-admin", ctx: userContext(2, "read"), body: `[{"nome_local": "Sítio"}]`, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var imported []string
+			repo := &fakeLugarRepo{
+				importLugares: func(lugares []*models.Lugar) ([]models.LugarImportResult, error) {
+					results := make([]models.LugarImportResult, len(lugares))
+					for i, lugar := range lugares {
+						imported = append(imported, lugar.NomeLocal)
+						if lugar.UserID != 1 {
+							t.Errorf("lugar %q: user_id = %d, want the caller", lugar.NomeLocal, lugar.UserID)
+						}
+						for _, tag := range lugar.Tags {
+							if tag.Name != strings.TrimSpace(tag.Name) {
+								t.Errorf("tag name %q was not trimmed", tag.Name)
+							}
+						}
+						if lugar.NomeLocal == "Duplicado" {
+							results[i] = models.LugarImportResult{Index: i, Error: "Duplicate lugar", Err: errors.New(`pq: duplicate key value violates unique constraint`)}
+							continue
+						}
+						results[i] = models.LugarImportResult{Index: i, ID: 10 + i}
+					}
+					return results, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.ImportLugares(tt.ctx, bodyRequest(tt.body, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if !reflect.DeepEqual(imported, tt.wantImported) {
+				t.Errorf("imported = %v, want %v", imported, tt.wantImported)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if strings.Contains(response.Body, "pq:") {
+				t.Errorf("body leaks a database error: %s", response.Body)
+			}
+			var results []models.LugarImportResult
+			decodeBody(t, response, &results)
+			if !reflect.DeepEqual(results, tt.wantResults) {
+				t.Errorf("results = %+v, want %+v", results, tt.wantResults)
+			}
+		})
+	}
+}
+
+func TestCreateLugarWarnings(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantWarnings int
+	}{
+		{name: "sparse lugar", body: `{"nome_local": "Sítio"}`, wantWarnings: 3},
+		{name: "lugar without contact", body: `{"nome_local": "Sítio", "nome_dono_local": "Maria", "endereco_completo": "Estrada do Sítio, 100"}`, wantWarnings: 1},
+		{name: "complete lugar", body: `{"nome_local": "Sítio", "nome_dono_local": "Maria", "telefone_para_contato": 11987654321, "endereco_completo": "Estrada do Sítio, 100"}`, wantWarnings: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLugarRepo{
+				create:         func(lugar *models.Lugar) (int, error) { return 7, nil },
+				missingTagIDs:  func(tagIDs []int) ([]int, error) { return nil, nil },
+				missingRamoIDs: func(ramoIDs []int) ([]int, error) { return nil, nil },
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			for _, validate := range []bool{false, true} {
+				handle, wantStatus := h.CreateLugar, http.StatusCreated
+				if validate {
+					handle, wantStatus = h.ValidateLugar, http.StatusOK
+				}
+
+				response, err := handle(adminContext(), bodyRequest(tt.body, nil))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				// Warnings never block the request
+				if response.StatusCode != wantStatus {
+					t.Fatalf("validate %v: status = %d, want %d (body %s)", validate, response.StatusCode, wantStatus, response.Body)
+				}
+				var body struct {
+					Warnings *[]string `json:"warnings"`
+				}
+				decodeBody(t, response, &body)
+				got := 0
+				if body.Warnings != nil {
+					got = len(*body.Warnings)
+				}
+				if got != tt.wantWarnings {
+					t.Errorf("validate %v: %d warnings, want %d (body %s)", validate, got, tt.wantWarnings, response.Body)
+				}
+				// A complete lugar has no warnings field at all
+				if tt.wantWarnings == 0 && body.Warnings != nil {
+					t.Errorf("validate %v: warnings = %v, want the field omitted", validate, *body.Warnings)
+				}
+			}
+		})
+	}
+}
+
+func TestListAllImages(t *testing.T) {
+	images := []*models.ImageWithLugar{
+		{LugarImage: models.LugarImage{ID: 9, LugarID: 2, ImageURL: "https://example.com/b.jpg"}, LugarNome: "Sítio"},
+		{LugarImage: models.LugarImage{ID: 4, LugarID: 1, ImageURL: "https://example.com/a.jpg"}, LugarNome: "Chácara"},
+	}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		query      map[string]string
+		wantStatus int
+		wantPage   repository.Pagination
+	}{
+		{name: "read user", ctx: userContext(2, "read"), wantStatus: http.StatusForbidden},
+		{name: "default page", ctx: adminContext(), wantStatus: http.StatusOK, wantPage: repository.Pagination{Limit: defaultPageLimit}},
+		{name: "explicit page", ctx: adminContext(), query: map[string]string{"limit": "2", "offset": "4"}, wantStatus: http.StatusOK, wantPage: repository.Pagination{Limit: 2, Offset: 4}},
+		{name: "invalid offset", ctx: adminContext(), query: map[string]string{"offset": "-1"}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPage *repository.Pagination
+			repo := &fakeLugarRepo{
+				listAllImages: func(page repository.Pagination) ([]*models.ImageWithLugar, error) {
+					gotPage = &page
+					return images, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.ListAllImages(tt.ctx, queryRequest(tt.query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if gotPage != nil {
+					t.Errorf("repository called with %+v, want no call", *gotPage)
+				}
+				return
+			}
+
+			if gotPage == nil || *gotPage != tt.wantPage {
+				t.Errorf("page = %+v, want %+v", gotPage, tt.wantPage)
+			}
+			if got := response.Headers["Cache-Control"]; got != privateCacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, privateCacheControl)
+			}
+
+			var body []struct {
+				ID        int    `json:"id"`
+				LugarNome string `json:"lugar_nome"`
+			}
+			decodeBody(t, response, &body)
+			if len(body) != 2 || body[0].ID != 9 || body[0].LugarNome != "Sítio" || body[1].LugarNome != "Chácara" {
+				t.Errorf("body = %s, want the repository order with lugar names", response.Body)
+			}
+		})
+	}
+}
+
+func TestListRatingsByValue(t *testing.T) {
+	ratings := []*models.RecentRating{
+		{LugarRating: models.LugarRating{ID: 8, LugarID: 2, UserID: 3, Rating: 1}, LugarNome: "Sítio"},
+	}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		query      map[string]string
+		wantStatus int
+		wantRating int
+		wantPage   repository.Pagination
+	}{
+		{name: "read user", ctx: userContext(2, "read"), query: map[string]string{"rating": "1"}, wantStatus: http.StatusForbidden},
+		{name: "missing rating", ctx: adminContext(), wantStatus: http.StatusBadRequest},
+		{name: "rating below range", ctx: adminContext(), query: map[string]string{"rating": "0"}, wantStatus: http.StatusBadRequest},
+		{name: "rating above range", ctx: adminContext(), query: map[string]string{"rating": "6"}, wantStatus: http.StatusBadRequest},
+		{name: "invalid limit", ctx: adminContext(), query: map[string]string{"rating": "1", "limit": "0"}, wantStatus: http.StatusBadRequest},
+		{name: "one star", ctx: adminContext(), query: map[string]string{"rating": "1"}, wantStatus: http.StatusOK, wantRating: 1, wantPage: repository.Pagination{Limit: defaultPageLimit}},
+		{name: "paged", ctx: adminContext(), query: map[string]string{"rating": "5", "limit": "10", "offset": "20"}, wantStatus: http.StatusOK, wantRating: 5, wantPage: repository.Pagination{Limit: 10, Offset: 20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			repo := &fakeLugarRepo{
+				ratingsByValue: func(rating int, page repository.Pagination) ([]*models.RecentRating, error) {
+					called = true
+					if rating != tt.wantRating || page != tt.wantPage {
+						t.Errorf("rating %d, page %+v; want %d, %+v", rating, page, tt.wantRating, tt.wantPage)
+					}
+					return ratings, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.ListRatingsByValue(tt.ctx, queryRequest(tt.query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if called != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("repository called = %v", called)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			// The feed is admin-only, so no shared cache may keep it
+			if got := response.Headers["Cache-Control"]; got != privateCacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, privateCacheControl)
+			}
+			var body []struct {
+				ID        int    `json:"id"`
+				LugarNome string `json:"lugar_nome"`
+			}
+			decodeBody(t, response, &body)
+			if len(body) != 1 || body[0].ID != 8 || body[0].LugarNome != "Sítio" {
+				t.Errorf("body = %s, want the rating with its lugar name", response.Body)
+			}
+		})
+	}
+}
+
+func TestTouchLugar(t *testing.T) {
+	touchedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		id         string
+		updatedAt  *time.Time
+		repoErr    error
+		wantStatus int
+		wantCalled bool
+	}{
+		{name: "read user", ctx: userContext(2, "read"), id: "1", wantStatus: http.StatusForbidden},
+		{name: "invalid id", ctx: adminContext(), id: "abc", wantStatus: http.StatusBadRequest},
+		{name: "missing lugar", ctx: adminContext(), id: "1", wantStatus: http.StatusNotFound, wantCalled: true},
+		{name: "repository failure", ctx: adminContext(), id: "1", repoErr: errors.New("boom"), wantStatus: http.StatusInternalServerError, wantCalled: true},
+		{name: "touched", ctx: adminContext(), id: "1", updatedAt: &touchedAt, wantStatus: http.StatusOK, wantCalled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			repo := &fakeLugarRepo{
+				touch: func(id int) (*time.Time, error) {
+					called = true
+					return tt.updatedAt, tt.repoErr
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.TouchLugar(tt.ctx, pathRequest(map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("repository called = %v, want %v", called, tt.wantCalled)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var body struct {
+				ID        int       `json:"id"`
+				UpdatedAt time.Time `json:"updated_at"`
+			}
+			decodeBody(t, response, &body)
+			if body.ID != 1 || !body.UpdatedAt.Equal(touchedAt) {
+				t.Errorf("body = %s, want id 1 updated at %s", response.Body, touchedAt)
+			}
+		})
+	}
+}
+
+func TestPublishLugar(t *testing.T) {
+	image := []*models.LugarImage{{ID: 1, ImageURL: "https://example.com/a.jpg"}}
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		lugar       *models.Lugar
+		wantStatus  int
+		wantMessage string
+		wantPublish bool
+	}{
+		{
+			name:        "without image nor address",
+			ctx:         adminContext(),
+			lugar:       &models.Lugar{ID: 1, UserID: 3},
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantMessage: "Lugar needs at least one image and an endereco_completo to be published",
+		},
+		{
+			name:        "without image",
+			ctx:         adminContext(),
+			lugar:       &models.Lugar{ID: 1, UserID: 3, EnderecoCompleto: "Estrada do Sítio, 100"},
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantMessage: "Lugar needs at least one image to be published",
+		},
+		{
+			name:        "blank address",
+			ctx:         adminContext(),
+			lugar:       &models.Lugar{ID: 1, UserID: 3, EnderecoCompleto: "  ", Images: image},
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantMessage: "Lugar needs an endereco_completo to be published",
+		},
+		{
+			name:       "another user",
+			ctx:        userContext(2, "read"),
+			lugar:      &models.Lugar{ID: 1, UserID: 3, EnderecoCompleto: "Estrada do Sítio, 100", Images: image},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:        "owner",
+			ctx:         userContext(3, "read"),
+			lugar:       &models.Lugar{ID: 1, UserID: 3, EnderecoCompleto: "Estrada do Sítio, 100", Images: image},
+			wantStatus:  http.StatusOK,
+			wantPublish: true,
+		},
+		{
+			name:       "already published",
+			ctx:        adminContext(),
+			lugar:      &models.Lugar{ID: 1, UserID: 3, EnderecoCompleto: "Estrada do Sítio, 100", Images: image, Published: true},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing lugar",
+			ctx:        adminContext(),
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			published := false
+			repo := &fakeLugarRepo{
+				getByID: func(id int) (*models.Lugar, error) { return tt.lugar, nil },
+				publish: func(id int) error {
+					published = true
+					return nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.PublishLugar(tt.ctx, pathRequest(map[string]string{"id": "1"}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if published != tt.wantPublish {
+				t.Errorf("published = %v, want %v", published, tt.wantPublish)
+			}
+			if tt.wantMessage != "" {
+				var apiErr APIError
+				decodeBody(t, response, &apiErr)
+				if apiErr.Message != tt.wantMessage {
+					t.Errorf("error = %q, want %q", apiErr.Message, tt.wantMessage)
+				}
+			}
+			if tt.wantStatus == http.StatusOK {
+				var body models.Lugar
+				decodeBody(t, response, &body)
+				if !body.Published {
+					t.Errorf("body = %s, want the lugar published", response.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestUnpublishedLugarVisibility(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		query       map[string]string
+		wantGet     int
+		wantListAll bool
+	}{
+		{name: "anonymous", ctx: context.Background(), wantGet: http.StatusNotFound},
+		{name: "another user", ctx: userContext(2, "read"), wantGet: http.StatusNotFound},
+		{name: "owner", ctx: userContext(3, "read"), wantGet: http.StatusOK},
+		{name: "owner editable list", ctx: userContext(3, "read"), query: map[string]string{"editable": "true"}, wantGet: http.StatusOK, wantListAll: true},
+		{name: "admin", ctx: adminContext(), wantGet: http.StatusOK, wantListAll: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			draft := &models.Lugar{ID: 1, NomeLocal: "Rascunho", UserID: 3}
+			var listOpts *repository.LugarListOptions
+			repo := &fakeLugarRepo{
+				getByID: func(id int) (*models.Lugar, error) { return draft, nil },
+				list: func(opts repository.LugarListOptions) ([]*models.Lugar, error) {
+					listOpts = &opts
+					return []*models.Lugar{}, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			request := pathRequest(map[string]string{"id": "1"})
+			request.QueryStringParameters = tt.query
+			response, err := h.GetLugar(tt.ctx, request)
+			if err != nil {
+				t.Fatalf("GetLugar: unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantGet {
+				t.Fatalf("GetLugar: status = %d, want %d (body %s)", response.StatusCode, tt.wantGet, response.Body)
+			}
+			// Only some callers see the draft, so no shared cache may keep it
+			if tt.wantGet == http.StatusOK && response.Headers["Cache-Control"] != privateCacheControl {
+				t.Errorf("GetLugar: Cache-Control = %q, want %q", response.Headers["Cache-Control"], privateCacheControl)
+			}
+
+			response, err = h.ListLugares(tt.ctx, queryRequest(tt.query))
+			if err != nil {
+				t.Fatalf("ListLugares: unexpected error: %v", err)
+			}
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("ListLugares: status = %d, want 200 (body %s)", response.StatusCode, response.Body)
+			}
+			if listOpts.IncludeUnpublished != tt.wantListAll {
+				t.Errorf("ListLugares: IncludeUnpublished = %v, want %v", listOpts.IncludeUnpublished, tt.wantListAll)
+			}
+			private := response.Headers["Cache-Control"] == privateCacheControl
+			if private != tt.wantListAll {
+				t.Errorf("ListLugares: Cache-Control = %q, want private %v", response.Headers["Cache-Control"], tt.wantListAll)
+			}
+		})
+	}
+}
+
+func TestGetMyRatingForLugar(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		id         string
+		rating     *models.LugarRating
+		repoErr    error
+		wantStatus int
+		wantUserID int
+	}{
+		{name: "anonymous", ctx: context.Background(), id: "1", wantStatus: http.StatusUnauthorized},
+		{name: "invalid id", ctx: userContext(3, "read"), id: "abc", wantStatus: http.StatusBadRequest},
+		{name: "not rated", ctx: userContext(3, "read"), id: "1", wantStatus: http.StatusNotFound, wantUserID: 3},
+		{name: "rated", ctx: userContext(3, "read"), id: "1", rating: &models.LugarRating{ID: 8, LugarID: 1, UserID: 3, Rating: 4}, wantStatus: http.StatusOK, wantUserID: 3},
+		{name: "admin only sees their own rating", ctx: adminContext(), id: "1", wantStatus: http.StatusNotFound, wantUserID: 1},
+		{name: "repository failure", ctx: userContext(3, "read"), id: "1", repoErr: errors.New("boom"), wantStatus: http.StatusInternalServerError, wantUserID: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID int
+			repo := &fakeLugarRepo{
+				getUserRating: func(lugarID, userID int) (*models.LugarRating, error) {
+					gotUserID = userID
+					return tt.rating, tt.repoErr
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.GetMyRatingForLugar(tt.ctx, pathRequest(map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if gotUserID != tt.wantUserID {
+				t.Errorf("repository asked for user %d, want %d", gotUserID, tt.wantUserID)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			// The rating depends on the caller, so no shared cache may keep it
+			if got := response.Headers["Cache-Control"]; got != privateCacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, privateCacheControl)
+			}
+			var body models.LugarRating
+			decodeBody(t, response, &body)
+			if body.ID != 8 || body.Rating != 4 {
+				t.Errorf("body = %s, want rating 8", response.Body)
+			}
+		})
+	}
+}
+
+func TestCreateLugarUnknownReferences(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		missingTags  []int
+		missingRamos []int
+		wantStatus   int
+		wantMessage  string
+	}{
+		{
+			name:       "all references exist",
+			body:       `{"nome_local": "Sítio", "tags": [{"id": 1}, {"id": 2}], "ramos": [{"id": 3}]}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:        "some unknown tags",
+			body:        `{"nome_local": "Sítio", "tags": [{"id": 1}, {"id": 98}, {"id": 99}], "ramos": [{"id": 3}]}`,
+			missingTags: []int{98, 99},
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantMessage: "unknown tag IDs: 98, 99",
+		},
+		{
+			name:         "unknown tags and ramos",
+			body:         `{"nome_local": "Sítio", "tags": [{"id": 1}, {"id": 98}], "ramos": [{"id": 3}, {"id": 9}]}`,
+			missingTags:  []int{98},
+			missingRamos: []int{9},
+			wantStatus:   http.StatusUnprocessableEntity,
+			wantMessage:  "unknown tag IDs: 98; unknown ramo IDs: 9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			repo := &fakeLugarRepo{
+				create: func(lugar *models.Lugar) (int, error) {
+					created = true
+					return 7, nil
+				},
+				missingTagIDs:  func(tagIDs []int) ([]int, error) { return tt.missingTags, nil },
+				missingRamoIDs: func(ramoIDs []int) ([]int, error) { return tt.missingRamos, nil },
+				addTag:         func(lugarID, tagID int) (bool, error) { return true, nil },
+				addRamo:        func(lugarID, ramoID int) (bool, error) { return true, nil },
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.CreateLugar(adminContext(), bodyRequest(tt.body, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			// Nothing is written when a reference is unknown
+			if wantCreated := tt.wantStatus == http.StatusCreated; created != wantCreated {
+				t.Errorf("created = %v, want %v", created, wantCreated)
+			}
+			if tt.wantMessage != "" {
+				var apiErr APIError
+				decodeBody(t, response, &apiErr)
+				if apiErr.Code != CodeValidationFailed || apiErr.Message != tt.wantMessage {
+					t.Errorf("error = %s %q, want %s %q", apiErr.Code, apiErr.Message, CodeValidationFailed, tt.wantMessage)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateLugar(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		missingTags []int
+		wantStatus  int
+		wantCode    string
+	}{
+		{name: "valid lugar", body: `{"nome_local": "Sítio", "tags": [{"id": 1}], "ramos": [{"id": 3}]}`, wantStatus: http.StatusOK},
+		{name: "missing nome local", body: `{"nome_local": ""}`, wantStatus: http.StatusUnprocessableEntity, wantCode: CodeValidationFailed},
+		{name: "malformed link", body: `{"nome_local": "Sítio", "link_site": "not a url"}`, wantStatus: http.StatusUnprocessableEntity, wantCode: CodeValidationFailed},
+		{name: "unknown tag", body: `{"nome_local": "Sítio", "tags": [{"id": 99}]}`, missingTags: []int{99}, wantStatus: http.StatusUnprocessableEntity, wantCode: CodeValidationFailed},
+		{name: "malformed JSON", body: `{"nome_local": `, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			written := false
+			write := func() { written = true }
+			repo := &fakeLugarRepo{
+				missingTagIDs:  func(tagIDs []int) ([]int, error) { return tt.missingTags, nil },
+				missingRamoIDs: func(ramoIDs []int) ([]int, error) { return nil, nil },
+				create:         func(lugar *models.Lugar) (int, error) { write(); return 7, nil },
+				addTag:         func(lugarID, tagID int) (bool, error) { write(); return true, nil },
+				addRamo:        func(lugarID, ramoID int) (bool, error) { write(); return true, nil },
+				addImage:       func(image *models.LugarImage, maxImages int) (int, error) { write(); return 1, nil },
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.ValidateLugar(adminContext(), bodyRequest(tt.body, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if written {
+				t.Error("validation wrote to the repository")
+			}
+
+			if tt.wantCode != "" {
+				if code := errorCode(t, response); code != tt.wantCode {
+					t.Errorf("code = %s, want %s", code, tt.wantCode)
+				}
+				return
+			}
+			var body lugarValidation
+			decodeBody(t, response, &body)
+			if !body.Valid {
+				t.Errorf("body = %s, want valid", response.Body)
+			}
+		})
+	}
+}
+
+func TestListLugaresSort(t *testing.T) {
+	tests := []struct {
+		name       string
+		sort       string
+		wantStatus int
+	}{
+		{"default order", "", http.StatusOK},
+		{"most tagged first", "tag_count", http.StatusOK},
+		{"unknown sort", "nome_local; DROP TABLE lugares", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotSort *string
+			repo := &fakeLugarRepo{
+				list: func(opts repository.LugarListOptions) ([]*models.Lugar, error) {
+					gotSort = &opts.Sort
+					return []*models.Lugar{}, nil
+				},
+				count: func(opts repository.LugarListOptions) (int, error) { return 0, nil },
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.ListLugares(context.Background(), queryRequest(map[string]string{"sort": tt.sort}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if gotSort != nil {
+					t.Error("the repository was called with an invalid sort")
+				}
+				return
+			}
+			if gotSort == nil || *gotSort != tt.sort {
+				t.Errorf("repository sort = %v, want %q", gotSort, tt.sort)
+			}
+		})
+	}
+}
+
+func TestListEditableLugares(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		query      map[string]string
+		wantStatus int
+		wantUserID int
+	}{
+		{name: "owner sees only theirs", ctx: userContext(3, "read"), query: map[string]string{"editable": "true"}, wantStatus: http.StatusOK, wantUserID: 3},
+		{name: "admin sees all", ctx: adminContext(), query: map[string]string{"editable": "true"}, wantStatus: http.StatusOK, wantUserID: 0},
+		{name: "anonymous caller", ctx: context.Background(), query: map[string]string{"editable": "true"}, wantStatus: http.StatusUnauthorized},
+		{name: "not asked", ctx: userContext(3, "read"), query: map[string]string{}, wantStatus: http.StatusOK, wantUserID: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotOpts *repository.LugarListOptions
+			repo := &fakeLugarRepo{
+				list: func(opts repository.LugarListOptions) ([]*models.Lugar, error) {
+					gotOpts = &opts
+					return []*models.Lugar{}, nil
+				},
+				count: func(opts repository.LugarListOptions) (int, error) { return 0, nil },
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.ListLugares(tt.ctx, queryRequest(tt.query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if gotOpts != nil {
+					t.Error("the repository was called for a rejected request")
+				}
+				return
+			}
+			if gotOpts.UserID != tt.wantUserID {
+				t.Errorf("owner filter = %d, want %d", gotOpts.UserID, tt.wantUserID)
+			}
+			// The editable list depends on the caller
+			if editable := tt.query["editable"] == "true"; editable != (response.Headers["Cache-Control"] == privateCacheControl) {
+				t.Errorf("Cache-Control = %q for editable %v", response.Headers["Cache-Control"], editable)
+			}
+		})
+	}
+}
+
+func TestGetRatingSummaries(t *testing.T) {
+	tooMany := make([]string, maxRatingSummaryIDs+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprint(i + 1)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantIDs    []int
+	}{
+		{name: "rated and unrated lugares", body: `{"ids": [1, 2]}`, wantStatus: http.StatusOK, wantIDs: []int{1, 2}},
+		{name: "no ids", body: `{"ids": []}`, wantStatus: http.StatusUnprocessableEntity, wantCode: CodeValidationFailed},
+		{name: "too many ids", body: `{"ids": [` + strings.Join(tooMany, ",") + `]}`, wantStatus: http.StatusUnprocessableEntity, wantCode: CodeValidationFailed},
+		{name: "malformed body", body: `{"ids": "1"}`, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotIDs []int
+			repo := &fakeLugarRepo{
+				ratingSummaries: func(ids []int) (map[int]*models.RatingSummary, error) {
+					gotIDs = ids
+					return map[int]*models.RatingSummary{
+						1: {LugarID: 1, AverageRating: 4.25, RatingCount: 4},
+						2: {LugarID: 2},
+					}, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.GetRatingSummaries(context.Background(), bodyRequest(tt.body, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantCode != "" {
+				if code := errorCode(t, response); code != tt.wantCode {
+					t.Errorf("code = %s, want %s", code, tt.wantCode)
+				}
+				if gotIDs != nil {
+					t.Error("the repository was called for a rejected request")
+				}
+				return
+			}
+			if !reflect.DeepEqual(gotIDs, tt.wantIDs) {
+				t.Errorf("repository ids = %v, want %v", gotIDs, tt.wantIDs)
+			}
+
+			// Summaries are keyed by ID, with the average rounded to one decimal
+			var body map[string]map[string]interface{}
+			decodeBody(t, response, &body)
+			want := map[string]map[string]interface{}{
+				"1": {"lugar_id": float64(1), "average_rating": 4.3, "rating_count": float64(4)},
+				"2": {"lugar_id": float64(2), "average_rating": float64(0), "rating_count": float64(0)},
+			}
+			if !reflect.DeepEqual(body, want) {
+				t.Errorf("body = %v, want %v", body, want)
+			}
+		})
+	}
+}
+
+func TestUpdateRatingVersionConflict(t *testing.T) {
+	// The stored rating is at version 3
+	const currentVersion = 3
+
+	tests := []struct {
+		name            string
+		body            string
+		wantStatus      int
+		wantConditional bool
+		wantVersion     int
+	}{
+		{name: "current version", body: `{"rating": 4, "expected_version": 3}`, wantStatus: http.StatusOK, wantConditional: true, wantVersion: 4},
+		{name: "stale version", body: `{"rating": 4, "expected_version": 2}`, wantStatus: http.StatusConflict, wantConditional: true},
+		{name: "no expected version", body: `{"rating": 4}`, wantStatus: http.StatusOK, wantVersion: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditional := false
+			repo := &fakeLugarRepo{
+				updateIfUnchanged: func(rating *models.LugarRating, expectedVersion int) error {
+					conditional = true
+					if expectedVersion != currentVersion {
+						return fmt.Errorf("rating with ID %d: %w", rating.ID, repository.ErrStaleWrite)
+					}
+					rating.Version = currentVersion + 1
+					return nil
+				},
+				updateRating: func(rating *models.LugarRating) error {
+					rating.Version = currentVersion + 1
+					return nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.UpdateRatingForLugar(userContext(2, "read"), bodyRequest(tt.body, map[string]string{"id": "1", "ratingId": "5"}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if conditional != tt.wantConditional {
+				t.Errorf("conditional update = %v, want %v", conditional, tt.wantConditional)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if code := errorCode(t, response); code != CodeConflict {
+					t.Errorf("code = %s, want %s", code, CodeConflict)
+				}
+				return
+			}
+			var rating models.LugarRating
+			decodeBody(t, response, &rating)
+			if rating.Version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", rating.Version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestFormatPhoneParam(t *testing.T) {
+	tests := []struct {
+		name  string
+		query map[string]string
+		want  string
+	}{
+		{"asked", map[string]string{"format_phone": "true"}, "(11) 98765-4321"},
+		{"not asked", nil, ""},
+		{"other value", map[string]string{"format_phone": "1"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newLugar := func() *models.Lugar {
+				return &models.Lugar{ID: 1, NomeLocal: "Sítio", TelefoneParaContato: 5511987654321, Published: true}
+			}
+			repo := &fakeLugarRepo{
+				getByID: func(id int) (*models.Lugar, error) { return newLugar(), nil },
+				list: func(opts repository.LugarListOptions) ([]*models.Lugar, error) {
+					return []*models.Lugar{newLugar()}, nil
+				},
+				count: func(opts repository.LugarListOptions) (int, error) { return 1, nil },
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			getRequest := pathRequest(map[string]string{"id": "1"})
+			getRequest.QueryStringParameters = tt.query
+			response, err := h.GetLugar(context.Background(), getRequest)
+			if err != nil || response.StatusCode != http.StatusOK {
+				t.Fatalf("GetLugar: status %d, error %v", response.StatusCode, err)
+			}
+			var lugar map[string]interface{}
+			decodeBody(t, response, &lugar)
+			checkFormattedPhone(t, lugar, tt.want)
+
+			response, err = h.ListLugares(context.Background(), queryRequest(tt.query))
+			if err != nil || response.StatusCode != http.StatusOK {
+				t.Fatalf("ListLugares: status %d, error %v", response.StatusCode, err)
+			}
+			var list []map[string]interface{}
+			decodeBody(t, response, &list)
+			if len(list) != 1 {
+				t.Fatalf("listed %d lugares, want 1", len(list))
+			}
+			checkFormattedPhone(t, list[0], tt.want)
+		})
+	}
+}
+
+// checkFormattedPhone checks the telefone_formatado field of an encoded lugar,
+// which is omitted when empty, and that the stored digits are sent unchanged
+func checkFormattedPhone(t *testing.T, lugar map[string]interface{}, want string) {
+	t.Helper()
+	got, ok := lugar["telefone_formatado"]
+	if want == "" {
+		if ok {
+			t.Errorf("telefone_formatado = %v, want the field omitted", got)
+		}
+	} else if got != want {
+		t.Errorf("telefone_formatado = %v, want %q", got, want)
+	}
+	if phone, _ := lugar["telefone_para_contato"].(float64); phone != 5511987654321 {
+		t.Errorf("telefone_para_contato = %v, want the stored digits", lugar["telefone_para_contato"])
+	}
+}
+
+func TestGetDailyLugarStats(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		query      map[string]string
+		wantStatus int
+		wantFrom   string
+		wantTo     string
+	}{
+		{"explicit range", adminContext(), map[string]string{"from": "2024-05-01", "to": "2024-05-03"}, http.StatusOK, "2024-05-01", "2024-05-03"},
+		{"default to the 30 days before to", adminContext(), map[string]string{"to": "2024-05-30"}, http.StatusOK, "2024-05-01", "2024-05-30"},
+		{"largest range", adminContext(), map[string]string{"from": "2024-01-01", "to": "2024-12-31"}, http.StatusOK, "2024-01-01", "2024-12-31"},
+		{"range too large", adminContext(), map[string]string{"from": "2024-01-01", "to": "2025-01-01"}, http.StatusBadRequest, "", ""},
+		{"from after to", adminContext(), map[string]string{"from": "2024-05-03", "to": "2024-05-01"}, http.StatusBadRequest, "", ""},
+		{"invalid from", adminContext(), map[string]string{"from": "ontem"}, http.StatusBadRequest, "", ""},
+		{"not an admin", userContext(2, "read"), nil, http.StatusForbidden, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotFrom, gotTo time.Time
+			repo := &fakeLugarRepo{
+				dailyCounts: func(from, to time.Time) ([]*models.DailyCount, error) {
+					gotFrom, gotTo = from, to
+					return []*models.DailyCount{{Date: "2024-05-01", Count: 2}, {Date: "2024-05-02", Count: 0}}, nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.GetDailyLugarStats(tt.ctx, queryRequest(tt.query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if !gotFrom.IsZero() {
+					t.Error("the repository was called for a rejected request")
+				}
+				return
+			}
+
+			if from := gotFrom.Format("2006-01-02"); from != tt.wantFrom {
+				t.Errorf("from = %s, want %s", from, tt.wantFrom)
+			}
+			if to := gotTo.Format("2006-01-02"); to != tt.wantTo {
+				t.Errorf("to = %s, want %s", to, tt.wantTo)
+			}
+			var days []models.DailyCount
+			decodeBody(t, response, &days)
+			if len(days) != 2 || days[0].Count != 2 || days[1].Count != 0 {
+				t.Errorf("days = %+v, want the repository counts with the zero day", days)
+			}
+		})
+	}
+}
+
+func TestAddAssociationToLugar(t *testing.T) {
+	tests := []struct {
+		name       string
+		add        func(h *LugarHandler) (events.APIGatewayProxyResponse, error)
+		added      bool
+		wantStatus int
+		wantResult string
+	}{
+		{"new tag", func(h *LugarHandler) (events.APIGatewayProxyResponse, error) {
+			return h.AddTagToLugar(adminContext(), bodyRequest(`{"tag_id": 3}`, map[string]string{"id": "1"}))
+		}, true, http.StatusCreated, "added"},
+		{"tag already associated", func(h *LugarHandler) (events.APIGatewayProxyResponse, error) {
+			return h.AddTagToLugar(adminContext(), bodyRequest(`{"tag_id": 3}`, map[string]string{"id": "1"}))
+		}, false, http.StatusOK, "already_associated"},
+		{"new ramo", func(h *LugarHandler) (events.APIGatewayProxyResponse, error) {
+			return h.AddRamoToLugar(adminContext(), bodyRequest(`{"ramo_id": 2}`, map[string]string{"id": "1"}))
+		}, true, http.StatusCreated, "added"},
+		{"ramo already associated", func(h *LugarHandler) (events.APIGatewayProxyResponse, error) {
+			return h.AddRamoToLugar(adminContext(), bodyRequest(`{"ramo_id": 2}`, map[string]string{"id": "1"}))
+		}, false, http.StatusOK, "already_associated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeLugarRepo{
+				getByID: func(id int) (*models.Lugar, error) { return &models.Lugar{ID: id, NomeLocal: "Sítio"}, nil },
+				addTag:  func(lugarID, tagID int) (bool, error) { return tt.added, nil },
+				addRamo: func(lugarID, ramoID int) (bool, error) { return tt.added, nil },
+			}
+			ramoRepo := &fakeRamoRepo{
+				getByID: func(id int) (*models.Ramo, error) { return &models.Ramo{ID: id, Name: "escoteiro"}, nil },
+			}
+			h := NewLugarHandler(repo, ramoRepo, nil, nil, &fakeLogger{})
+
+			response, err := tt.add(h)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			var body map[string]string
+			decodeBody(t, response, &body)
+			if body["status"] != tt.wantResult {
+				t.Errorf("status field = %q, want %q", body["status"], tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestListLugaresMaxValorIndividual(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		wantStatus int
+		want       *float64
+	}{
+		{"not given", "", http.StatusOK, nil},
+		{"budget", "50", http.StatusOK, float64Ptr(50)},
+		{"cents", "49.90", http.StatusOK, float64Ptr(49.9)},
+		{"free only", "0", http.StatusOK, float64Ptr(0)},
+		{"negative", "-1", http.StatusBadRequest, nil},
+		{"not a number", "cinquenta", http.StatusBadRequest, nil},
+		{"NaN", "NaN", http.StatusBadRequest, nil},
+		{"infinite", "Inf", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			var got *float64
+			repo := &fakeLugarRepo{
+				list: func(opts repository.LugarListOptions) ([]*models.Lugar, error) {
+					called = true
+					got = opts.MaxValorIndividual
+					return []*models.Lugar{}, nil
+				},
+				count: func(opts repository.LugarListOptions) (int, error) { return 0, nil },
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			query := map[string]string{}
+			if tt.value != "" {
+				query["max_valor_individual"] = tt.value
+			}
+			response, err := h.ListLugares(context.Background(), queryRequest(query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if called {
+					t.Error("the repository was called with an invalid budget")
+				}
+				if code := errorCode(t, response); code != CodeValidationFailed {
+					t.Errorf("code = %q, want %q", code, CodeValidationFailed)
+				}
+				return
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("repository budget = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteImageFromLugar(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       map[string]string
+		err         error
+		wantStatus  int
+		wantCompact bool
+	}{
+		{"compacted", map[string]string{"compact": "true"}, nil, http.StatusNoContent, true},
+		{"gap kept", nil, nil, http.StatusNoContent, false},
+		{"other compact value", map[string]string{"compact": "1"}, nil, http.StatusNoContent, false},
+		{"repository error", map[string]string{"compact": "true"}, errors.New("boom"), http.StatusInternalServerError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var gotCompact bool
+			repo := &fakeLugarRepo{
+				deleteImage: func(imageID int, compact bool) error {
+					calls++
+					gotCompact = compact
+					return tt.err
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			request := pathRequest(map[string]string{"id": "1", "imageId": "7"})
+			request.QueryStringParameters = tt.query
+			response, err := h.DeleteImageFromLugar(adminContext(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if calls != 1 {
+				t.Fatalf("DeleteImage called %d times, want once", calls)
+			}
+			if gotCompact != tt.wantCompact {
+				t.Errorf("compact = %v, want %v", gotCompact, tt.wantCompact)
+			}
+		})
+	}
+}
+
+func TestAddRamoToLugarRules(t *testing.T) {
+	blockLobinho := func(l *models.Lugar, ramo *models.Ramo) error {
+		if ramo.ID == 1 {
+			return errors.New("lobinho is not allowed at this place")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name       string
+		rules      []models.LugarRamoRule
+		ramoID     int
+		wantStatus int
+		wantAdded  bool
+	}{
+		{"no rules", nil, 1, http.StatusCreated, true},
+		{"allowed by the rule", []models.LugarRamoRule{blockLobinho}, 2, http.StatusCreated, true},
+		{"blocked by the rule", []models.LugarRamoRule{blockLobinho}, 1, http.StatusUnprocessableEntity, false},
+		{"unknown ramo", nil, 99, http.StatusUnprocessableEntity, false},
+		{"unknown lugar", nil, 1, http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := models.LugarRamoRules
+			models.LugarRamoRules = tt.rules
+			defer func() { models.LugarRamoRules = previous }()
+
+			added := false
+			repo := &fakeLugarRepo{
+				getByID: func(id int) (*models.Lugar, error) {
+					if tt.wantStatus == http.StatusNotFound {
+						return nil, nil
+					}
+					return &models.Lugar{ID: id, NomeLocal: "Sítio"}, nil
+				},
+				addRamo: func(lugarID, ramoID int) (bool, error) {
+					added = true
+					return true, nil
+				},
+			}
+			ramoRepo := &fakeRamoRepo{
+				getByID: func(id int) (*models.Ramo, error) {
+					if id > 5 {
+						return nil, nil
+					}
+					return &models.Ramo{ID: id, Name: "ramo"}, nil
+				},
+			}
+			h := NewLugarHandler(repo, ramoRepo, nil, nil, &fakeLogger{})
+
+			response, err := h.AddRamoToLugar(adminContext(), bodyRequest(fmt.Sprintf(`{"ramo_id": %d}`, tt.ramoID), map[string]string{"id": "1"}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if added != tt.wantAdded {
+				t.Errorf("ramo added = %v, want %v", added, tt.wantAdded)
+			}
+			if tt.wantStatus == http.StatusUnprocessableEntity && tt.rules != nil {
+				var apiErr APIError
+				decodeBody(t, response, &apiErr)
+				if !strings.Contains(apiErr.Message, "lobinho is not allowed") {
+					t.Errorf("message = %q, want the reason of the rule", apiErr.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestListLugaresNearCity(t *testing.T) {
+	curitiba := &geocoding.Location{Name: "Curitiba, Paraná, Brasil", Latitude: -25.4284, Longitude: -49.2733}
+
+	tests := []struct {
+		name        string
+		city        string
+		geocoderErr error
+		wantStatus  int
+		wantCode    string
+		wantSearch  bool
+	}{
+		{"known city", "Curitiba", nil, http.StatusOK, "", true},
+		{"surrounding spaces", "  Curitiba ", nil, http.StatusOK, "", true},
+		{"unknown city", "Atlântida", nil, http.StatusNotFound, CodeNotFound, false},
+		{"geocoder failure", "Curitiba", errors.New("timeout"), http.StatusBadGateway, CodeUpstream, false},
+		{"no city", " ", nil, http.StatusBadRequest, CodeValidationFailed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var searchedLat, searchedLng float64
+			searched := false
+			repo := &fakeLugarRepo{
+				listNearest: func(lat, lng float64, page repository.Pagination) ([]*models.Lugar, error) {
+					searched = true
+					searchedLat, searchedLng = lat, lng
+					return []*models.Lugar{
+						{ID: 1, NomeLocal: "No centro", Latitude: float64Ptr(-25.4284), Longitude: float64Ptr(-49.2733)},
+						{ID: 2, NomeLocal: "Em Ponta Grossa", Latitude: float64Ptr(-25.0916), Longitude: float64Ptr(-50.1668)},
+					}, nil
+				},
+				countWithCoords: func() (int, error) { return 2, nil },
+			}
+			geocoder := &fakeGeocoder{locations: map[string]*geocoding.Location{"Curitiba": curitiba}, err: tt.geocoderErr}
+			h := NewLugarHandler(repo, nil, nil, geocoder, &fakeLogger{})
+
+			response, err := h.ListLugaresNearCity(context.Background(), queryRequest(map[string]string{"city": tt.city}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if searched != tt.wantSearch {
+				t.Fatalf("searched = %v, want %v", searched, tt.wantSearch)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if code := errorCode(t, response); code != tt.wantCode {
+					t.Errorf("code = %q, want %q", code, tt.wantCode)
+				}
+				return
+			}
+
+			if searchedLat != curitiba.Latitude || searchedLng != curitiba.Longitude {
+				t.Errorf("searched near (%v, %v), want the coordinates of the city", searchedLat, searchedLng)
+			}
+			var lugares []struct {
+				ID          int      `json:"id"`
+				DistanciaKm *float64 `json:"distancia_km"`
+			}
+			decodeBody(t, response, &lugares)
+			if len(lugares) != 2 || lugares[0].DistanciaKm == nil || lugares[1].DistanciaKm == nil {
+				t.Fatalf("lugares = %s, want both with their distance", response.Body)
+			}
+			if *lugares[0].DistanciaKm != 0 {
+				t.Errorf("distance of a place in the city = %v, want 0", *lugares[0].DistanciaKm)
+			}
+			if d := *lugares[1].DistanciaKm; d < 90 || d > 110 {
+				t.Errorf("distance to Ponta Grossa = %v km, want about 100", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/logger"
+	"github.com/site-geav-api/internal/metrics"
+)
+
+// MetricsHandler handles the requests on the in-process request metrics
+type MetricsHandler struct {
+	registry *metrics.Registry
+	log      logger.Logger
+}
+
+// NewMetricsHandler creates a new MetricsHandler
+func NewMetricsHandler(registry *metrics.Registry, log logger.Logger) *MetricsHandler {
+	return &MetricsHandler{
+		registry: registry,
+		log:      log,
+	}
+}
+
+// GetMetrics handles GET /metrics requests, returning the metrics in the
+// Prometheus text format. The routes, statuses and latencies describe the
+// internals of the API, so only admins may read them.
+func (h *MetricsHandler) GetMetrics(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized metrics request", map[string]interface{}{
+			"action":   "GetMetrics",
+			"resource": "metrics",
+		})
+		return response, nil
+	}
+
+	// Write metrics
+	var body strings.Builder
+	if err := h.registry.WriteText(&body); err != nil {
+		h.log.Error(ctx, "Error writing metrics", err, map[string]interface{}{
+			"action":   "GetMetrics",
+			"resource": "metrics",
+		})
+		return createErrorResponse(internalError("Error getting metrics"))
+	}
+
+	// Return metrics as text; they belong to this execution environment, so they are not cached
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":  "text/plain; version=0.0.4",
+			"Cache-Control": privateCacheControl,
+		},
+		Body: body.String(),
+	}, nil
+}
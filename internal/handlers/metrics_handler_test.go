@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/metrics"
+)
+
+func TestGetMetrics(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantStatus int
+	}{
+		{name: "admin", ctx: adminContext(), wantStatus: http.StatusOK},
+		{name: "non-admin", ctx: userContext(2, "read"), wantStatus: http.StatusForbidden},
+		{name: "unauthenticated", ctx: context.Background(), wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := metrics.NewRegistry()
+			registry.ObserveRequest("GET", "/lugares", 200, 10*time.Millisecond)
+			h := NewMetricsHandler(registry, &fakeLogger{})
+
+			response, err := h.GetMetrics(tt.ctx, events.APIGatewayProxyRequest{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			hasMetrics := strings.Contains(response.Body, `http_requests_total{method="GET",route="/lugares",status="200"} 1`)
+			if hasMetrics != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("body has metrics = %v, want %v:\n%s", hasMetrics, tt.wantStatus == http.StatusOK, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := response.Headers["Content-Type"]; got != "text/plain; version=0.0.4" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			if got := response.Headers["Cache-Control"]; got != privateCacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, privateCacheControl)
+			}
+		})
+	}
+}
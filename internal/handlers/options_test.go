@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/models"
+	"github.com/site-geav-api/internal/repository"
+)
+
+func TestSelectOptionsEndpoints(t *testing.T) {
+	// Every handler lists the options returned by list
+	var list func() ([]*models.SelectOption, error)
+	selectOptions := func(page repository.Pagination) ([]*models.SelectOption, error) { return list() }
+
+	handlers := []struct {
+		name   string
+		handle func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	}{
+		{"lugares", NewLugarHandler(&fakeLugarRepo{selectOptions: selectOptions}, nil, nil, nil, &fakeLogger{}).ListLugarOptions},
+		{"cancoes", NewCancaoHandler(&fakeCancaoRepo{selectOptions: selectOptions}, &fakeLogger{}).ListCancaoOptions},
+		{"ramos", NewRamoHandler(&fakeRamoRepo{selectOptions: selectOptions}, &fakeLogger{}).ListRamoOptions},
+		{"lugar tags", NewTagHandler(&fakeTagLugarRepo{selectOptions: selectOptions}, nil, &fakeLogger{}).ListLugarTagOptions},
+	}
+
+	tests := []struct {
+		name       string
+		options    []*models.SelectOption
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "only id and label, in the repository order",
+			options:    []*models.SelectOption{{ID: 2, Label: "Acampamento"}, {ID: 1, Label: "Sítio"}},
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"id":2,"label":"Acampamento"},{"id":1,"label":"Sítio"}]`,
+		},
+		{name: "empty", options: []*models.SelectOption{}, wantStatus: http.StatusOK, wantBody: `[]`},
+		{name: "repository failure", err: errors.New("boom"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, h := range handlers {
+		for _, tt := range tests {
+			t.Run(h.name+" "+tt.name, func(t *testing.T) {
+				list = func() ([]*models.SelectOption, error) { return tt.options, tt.err }
+
+				response, err := h.handle(context.Background(), queryRequest(nil))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if response.StatusCode != tt.wantStatus {
+					t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+				}
+				if tt.wantBody != "" && response.Body != tt.wantBody {
+					t.Errorf("body = %s, want %s", response.Body, tt.wantBody)
+				}
+			})
+		}
+	}
+}
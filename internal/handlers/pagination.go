@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/logger"
+)
+
+// The page limits must be positive: a zero or negative limit would return
+// every row, so such values fall back to the defaults
+var (
+	// defaultPageLimit is used when the request does not set a limit
+	defaultPageLimit = getEnvPositiveInt("DEFAULT_PAGE_LIMIT", 100)
+	// maxPageLimit caps the limit a request can ask for
+	maxPageLimit = getEnvPositiveInt("MAX_PAGE_LIMIT", 500)
+)
+
+// parsePagination reads the limit and offset query parameters, capping the limit at maxPageLimit
+func parsePagination(request events.APIGatewayProxyRequest) (limit, offset int, err error) {
+	limit = defaultPageLimit
+	if value := request.QueryStringParameters["limit"]; value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return 0, 0, fmt.Errorf("limit must be a positive integer")
+		}
+	}
+
+	if value := request.QueryStringParameters["offset"]; value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	return limit, offset, nil
+}
+
+// pageLinks holds the navigation links of a page of a list
+type pageLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// pagedList is the body of paginated list responses when the client asks for version 2
+type pagedList struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+	Links  pageLinks   `json:"links"`
+}
+
+// wantsPagedList checks if the client asked for the version 2 list response,
+// either with ?v=2 or with a version=2 parameter in the Accept header
+// (e.g. Accept: application/json; version=2)
+func wantsPagedList(request events.APIGatewayProxyRequest) bool {
+	if request.QueryStringParameters["v"] == "2" {
+		return true
+	}
+
+	for _, accepted := range strings.Split(headerValue(request, "Accept"), ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err == nil && params["version"] == "2" {
+			return true
+		}
+	}
+	return false
+}
+
+// buildPageLinks builds the links to the current, next and previous pages from
+// the request path and query. A page with fewer than limit items, or reaching
+// the total, is the last one and has no next link; the first page has no prev
+// link.
+func buildPageLinks(request events.APIGatewayProxyRequest, count, total, limit, offset int) pageLinks {
+	query := url.Values{}
+	for name, values := range request.MultiValueQueryStringParameters {
+		query[name] = append([]string(nil), values...)
+	}
+	for name, value := range request.QueryStringParameters {
+		if _, ok := query[name]; !ok {
+			query.Set(name, value)
+		}
+	}
+
+	pageURL := func(offset int) string {
+		query.Set("limit", strconv.Itoa(limit))
+		query.Set("offset", strconv.Itoa(offset))
+		return request.Path + "?" + query.Encode()
+	}
+
+	links := pageLinks{Self: pageURL(offset)}
+	if count >= limit && offset+count < total {
+		links.Next = pageURL(offset + limit)
+	}
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		links.Prev = pageURL(prev)
+	}
+
+	return links
+}
+
+// createPaginatedResponse creates the response of a paginated list: the bare
+// items by default, or for version 2 clients the items with the total number
+// of matching items, the page and navigation links. countTotal is only called
+// for version 2 clients, so the others do not pay for the count query.
+func createPaginatedResponse(ctx context.Context, log logger.Logger, request events.APIGatewayProxyRequest, items interface{}, count, limit, offset int, resource string, countTotal func() (int, error)) (events.APIGatewayProxyResponse, error) {
+	if !wantsPagedList(request) {
+		return createCachedJSONResponse(http.StatusOK, items, resource)
+	}
+
+	total, err := countTotal()
+	if err != nil {
+		log.Error(ctx, "Error counting "+resource, err, map[string]interface{}{
+			"resource": resource,
+		})
+		return createErrorResponse(internalError("Error counting " + resource))
+	}
+
+	return createCachedJSONResponse(http.StatusOK, pagedList{
+		Items:  emptyIfNilSlice(items),
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+		Links:  buildPageLinks(request, count, total, limit, offset),
+	}, resource)
+}
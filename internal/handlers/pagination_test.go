@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/models"
+	"github.com/site-geav-api/internal/repository"
+)
+
+// setPageLimits replaces the configured page limits for the duration of a test
+func setPageLimits(t *testing.T, defaultLimit, maxLimit int) {
+	t.Helper()
+	oldDefault, oldMax := defaultPageLimit, maxPageLimit
+	defaultPageLimit, maxPageLimit = defaultLimit, maxLimit
+	t.Cleanup(func() { defaultPageLimit, maxPageLimit = oldDefault, oldMax })
+}
+
+func TestParsePagination(t *testing.T) {
+	setPageLimits(t, 100, 500)
+
+	tests := []struct {
+		name       string
+		params     map[string]string
+		wantLimit  int
+		wantOffset int
+		wantErr    bool
+	}{
+		{name: "no parameters uses the default limit", params: nil, wantLimit: 100},
+		{name: "limit under the cap", params: map[string]string{"limit": "20", "offset": "40"}, wantLimit: 20, wantOffset: 40},
+		{name: "huge limit is clamped", params: map[string]string{"limit": "1000000"}, wantLimit: 500},
+		{name: "zero limit", params: map[string]string{"limit": "0"}, wantErr: true},
+		{name: "negative offset", params: map[string]string{"offset": "-1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, offset, err := parsePagination(queryRequest(tt.params))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if limit != tt.wantLimit || offset != tt.wantOffset {
+				t.Errorf("got limit %d offset %d, want %d and %d", limit, offset, tt.wantLimit, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestListLugaresPageLimits(t *testing.T) {
+	setPageLimits(t, 3, 5)
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		wantLimit int
+	}{
+		{"unparameterized list gets the default limit", nil, 3},
+		{"huge limit is clamped to the cap", map[string]string{"limit": "100000"}, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := make([]*models.Lugar, 10)
+			for i := range rows {
+				rows[i] = &models.Lugar{ID: i + 1}
+			}
+			var gotLimit int
+			repo := &fakeLugarRepo{
+				list: func(opts repository.LugarListOptions) ([]*models.Lugar, error) {
+					gotLimit = opts.Pagination.Limit
+					return rows[:opts.Pagination.Limit], nil
+				},
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.ListLugares(context.Background(), queryRequest(tt.params))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", response.StatusCode, response.Body)
+			}
+			if gotLimit != tt.wantLimit {
+				t.Errorf("repository limit = %d, want %d", gotLimit, tt.wantLimit)
+			}
+			var lugares []*models.Lugar
+			decodeBody(t, response, &lugares)
+			if len(lugares) > tt.wantLimit {
+				t.Errorf("got %d lugares, want at most %d", len(lugares), tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestUnparameterizedListsAreCapped(t *testing.T) {
+	setPageLimits(t, 3, 5)
+
+	// Every fake records the page it was asked for and returns at most its limit of 10 rows
+	var gotPage repository.Pagination
+	record := func(page repository.Pagination) int {
+		gotPage = page
+		if page.Limit < 10 {
+			return page.Limit
+		}
+		return 10
+	}
+	options := func(page repository.Pagination) ([]*models.SelectOption, error) {
+		return make([]*models.SelectOption, record(page)), nil
+	}
+	lugarRepo := &fakeLugarRepo{
+		exists:        func(id int) (bool, error) { return true, nil },
+		selectOptions: options,
+		getRatings: func(lugarID int, page repository.Pagination) ([]*models.LugarRating, error) {
+			return make([]*models.LugarRating, record(page)), nil
+		},
+		listByUser: func(userID int, page repository.Pagination) ([]*models.Lugar, error) {
+			return make([]*models.Lugar, record(page)), nil
+		},
+	}
+	cancaoRepo := &fakeCancaoRepo{
+		selectOptions: options,
+		listByUser: func(userID int, page repository.Pagination) ([]*models.Cancao, error) {
+			return make([]*models.Cancao, record(page)), nil
+		},
+	}
+	ramoRepo := &fakeRamoRepo{
+		selectOptions: options,
+		list: func(page repository.Pagination) ([]*models.Ramo, error) {
+			return make([]*models.Ramo, record(page)), nil
+		},
+	}
+	tagLugarRepo := &fakeTagLugarRepo{
+		selectOptions: options,
+		list: func(page repository.Pagination) ([]*models.TagLugar, error) {
+			return make([]*models.TagLugar, record(page)), nil
+		},
+		unassigned: func(lugarID int, page repository.Pagination) ([]*models.TagLugar, error) {
+			return make([]*models.TagLugar, record(page)), nil
+		},
+	}
+	tagCancaoRepo := &fakeTagCancaoRepo{
+		list: func(page repository.Pagination) ([]*models.TagCancao, error) {
+			return make([]*models.TagCancao, record(page)), nil
+		},
+	}
+	lugarHandler := NewLugarHandler(lugarRepo, nil, nil, nil, &fakeLogger{})
+	cancaoHandler := NewCancaoHandler(cancaoRepo, &fakeLogger{})
+	ramoHandler := NewRamoHandler(ramoRepo, &fakeLogger{})
+	tagHandler := NewTagHandler(tagLugarRepo, tagCancaoRepo, &fakeLogger{})
+	userHandler := NewUserHandler(&fakeUserRepo{}, lugarRepo, cancaoRepo, &fakeLogger{})
+
+	handlers := []struct {
+		name   string
+		handle func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	}{
+		{"ListLugarOptions", lugarHandler.ListLugarOptions},
+		{"ListCancaoOptions", cancaoHandler.ListCancaoOptions},
+		{"ListRamoOptions", ramoHandler.ListRamoOptions},
+		{"ListLugarTagOptions", tagHandler.ListLugarTagOptions},
+		{"GetRatingsForLugar", lugarHandler.GetRatingsForLugar},
+		{"ListRamos", ramoHandler.ListRamos},
+		{"ListLugarTags", tagHandler.ListLugarTags},
+		{"ListCancaoTags", tagHandler.ListCancaoTags},
+		{"ListAvailableLugarTags", tagHandler.ListAvailableLugarTags},
+		{"GetUserContent", userHandler.GetUserContent},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		wantLimit int
+	}{
+		{"unparameterized list gets the default limit", nil, 3},
+		{"huge limit is clamped to the cap", map[string]string{"limit": "100000"}, 5},
+	}
+
+	for _, h := range handlers {
+		for _, tt := range tests {
+			t.Run(h.name+" "+tt.name, func(t *testing.T) {
+				gotPage = repository.Pagination{}
+				request := queryRequest(tt.params)
+				request.PathParameters = map[string]string{"id": "1"}
+
+				response, err := h.handle(userContext(1, "write"), request)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if response.StatusCode != http.StatusOK {
+					t.Fatalf("status = %d, want 200 (body %s)", response.StatusCode, response.Body)
+				}
+				if gotPage.Limit != tt.wantLimit {
+					t.Errorf("repository limit = %d, want %d", gotPage.Limit, tt.wantLimit)
+				}
+			})
+		}
+	}
+}
+
+func TestBuildPageLinks(t *testing.T) {
+	request := events.APIGatewayProxyRequest{
+		Path:                  "/lugares",
+		QueryStringParameters: map[string]string{"v": "2", "sort": "name", "limit": "10", "offset": "10"},
+	}
+
+	tests := []struct {
+		name   string
+		count  int
+		total  int
+		offset int
+		want   pageLinks
+	}{
+		{
+			name: "first page", count: 10, total: 25, offset: 0,
+			want: pageLinks{
+				Self: "/lugares?limit=10&offset=0&sort=name&v=2",
+				Next: "/lugares?limit=10&offset=10&sort=name&v=2",
+			},
+		},
+		{
+			name: "middle page", count: 10, total: 25, offset: 10,
+			want: pageLinks{
+				Self: "/lugares?limit=10&offset=10&sort=name&v=2",
+				Next: "/lugares?limit=10&offset=20&sort=name&v=2",
+				Prev: "/lugares?limit=10&offset=0&sort=name&v=2",
+			},
+		},
+		{
+			name: "last page", count: 5, total: 25, offset: 20,
+			want: pageLinks{
+				Self: "/lugares?limit=10&offset=20&sort=name&v=2",
+				Prev: "/lugares?limit=10&offset=10&sort=name&v=2",
+			},
+		},
+		{
+			name: "full last page", count: 10, total: 30, offset: 20,
+			want: pageLinks{
+				Self: "/lugares?limit=10&offset=20&sort=name&v=2",
+				Prev: "/lugares?limit=10&offset=10&sort=name&v=2",
+			},
+		},
+		{
+			name: "offset not on a page boundary", count: 10, total: 25, offset: 5,
+			want: pageLinks{
+				Self: "/lugares?limit=10&offset=5&sort=name&v=2",
+				Next: "/lugares?limit=10&offset=15&sort=name&v=2",
+				Prev: "/lugares?limit=10&offset=0&sort=name&v=2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildPageLinks(request, tt.count, tt.total, 10, tt.offset)
+			if got != tt.want {
+				t.Errorf("links = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginatedResponseLinks(t *testing.T) {
+	repo := &fakeLugarRepo{
+		list: func(opts repository.LugarListOptions) ([]*models.Lugar, error) {
+			return []*models.Lugar{{ID: 21}, {ID: 22}}, nil
+		},
+		count: func(opts repository.LugarListOptions) (int, error) {
+			return 22, nil
+		},
+	}
+	h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+	request := queryRequest(map[string]string{"v": "2", "limit": "10", "offset": "20"})
+	request.Path = "/lugares"
+
+	response, err := h.ListLugares(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var body struct {
+		Total int       `json:"total"`
+		Links pageLinks `json:"links"`
+	}
+	decodeBody(t, response, &body)
+	want := pageLinks{
+		Self: "/lugares?limit=10&offset=20&v=2",
+		Prev: "/lugares?limit=10&offset=10&v=2",
+	}
+	if body.Total != 22 || body.Links != want {
+		t.Errorf("total %d links %+v, want total 22 links %+v", body.Total, body.Links, want)
+	}
+}
+
+func TestSmallListsEnvelope(t *testing.T) {
+	setPageLimits(t, 100, 500)
+
+	ramoHandler := NewRamoHandler(&fakeRamoRepo{
+		list: func(page repository.Pagination) ([]*models.Ramo, error) {
+			return []*models.Ramo{{ID: 1, Name: "filhotes"}, {ID: 2, Name: "lobinho"}}, nil
+		},
+		count: func() (int, error) { return 2, nil },
+	}, &fakeLogger{})
+	tagHandler := NewTagHandler(&fakeTagLugarRepo{
+		list: func(page repository.Pagination) ([]*models.TagLugar, error) {
+			return []*models.TagLugar{{ID: 1, Name: "rio"}, {ID: 2, Name: "lago"}, {ID: 3, Name: "cachoeira"}}, nil
+		},
+		count: func() (int, error) { return 3, nil },
+	}, nil, &fakeLogger{})
+	lugarHandler := NewLugarHandler(&fakeLugarRepo{
+		exists:       func(id int) (bool, error) { return true, nil },
+		getRatings:   func(lugarID int, page repository.Pagination) ([]*models.LugarRating, error) { return nil, nil },
+		countRatings: func(lugarID int) (int, error) { return 0, nil },
+	}, nil, nil, nil, &fakeLogger{})
+
+	handlers := []struct {
+		name      string
+		handle    func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+		wantCount int
+	}{
+		{"ListRamos", ramoHandler.ListRamos, 2},
+		{"ListLugarTags", tagHandler.ListLugarTags, 3},
+		{"GetRatingsForLugar", lugarHandler.GetRatingsForLugar, 0},
+	}
+
+	tests := []struct {
+		name      string
+		query     map[string]string
+		accept    string
+		wantPaged bool
+	}{
+		{name: "legacy array"},
+		{name: "v=2", query: map[string]string{"v": "2"}, wantPaged: true},
+		{name: "Accept version", accept: "application/json; version=2", wantPaged: true},
+	}
+
+	for _, h := range handlers {
+		for _, tt := range tests {
+			t.Run(h.name+" "+tt.name, func(t *testing.T) {
+				request := queryRequest(tt.query)
+				request.PathParameters = map[string]string{"id": "1"}
+				if tt.accept != "" {
+					request.Headers = map[string]string{"Accept": tt.accept}
+				}
+
+				response, err := h.handle(context.Background(), request)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if response.StatusCode != http.StatusOK {
+					t.Fatalf("status = %d, want 200 (body %s)", response.StatusCode, response.Body)
+				}
+
+				if !tt.wantPaged {
+					var items []map[string]interface{}
+					decodeBody(t, response, &items)
+					if items == nil || len(items) != h.wantCount {
+						t.Errorf("body = %s, want an array of %d items", response.Body, h.wantCount)
+					}
+					return
+				}
+
+				var body struct {
+					Items  *[]map[string]interface{} `json:"items"`
+					Total  int                       `json:"total"`
+					Limit  int                       `json:"limit"`
+					Offset int                       `json:"offset"`
+					Links  pageLinks                 `json:"links"`
+				}
+				decodeBody(t, response, &body)
+				if body.Items == nil || len(*body.Items) != h.wantCount {
+					t.Fatalf("body = %s, want %d items", response.Body, h.wantCount)
+				}
+				// Every item fits in the first page
+				if body.Total != h.wantCount || body.Limit != 100 || body.Offset != 0 {
+					t.Errorf("total %d, limit %d, offset %d; want %d, 100, 0", body.Total, body.Limit, body.Offset, h.wantCount)
+				}
+				if body.Links.Next != "" || body.Links.Prev != "" {
+					t.Errorf("links = %+v, want no next or prev", body.Links)
+				}
+			})
+		}
+	}
+}
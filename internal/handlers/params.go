@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// parseLimitParam reads the limit query parameter of endpoints returning a
+// short top-N list, which must be between 1 and maxLimit
+func parseLimitParam(request events.APIGatewayProxyRequest, defaultLimit, maxLimit int) (int, error) {
+	value := request.QueryStringParameters["limit"]
+	if value == "" {
+		return defaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+
+	return limit, nil
+}
+
+// parseIntListParam reads a repeatable integer query parameter (e.g. ?id=1&id=2)
+func parseIntListParam(request events.APIGatewayProxyRequest, name string) ([]int, error) {
+	values := request.MultiValueQueryStringParameters[name]
+	if len(values) == 0 {
+		if value, ok := request.QueryStringParameters[name]; ok {
+			values = []string{value}
+		}
+	}
+
+	ids := make([]int, 0, len(values))
+	for _, value := range values {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", name, value)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// parseTimeParam parses a query parameter given either as RFC3339 or as a plain date (YYYY-MM-DD)
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// parseEndTimeParam parses the exclusive upper bound of a time range, given
+// like parseTimeParam. A plain date includes that whole day, so it returns the
+// start of the next day.
+func parseEndTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return day.AddDate(0, 0, 1), nil
+}
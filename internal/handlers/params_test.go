@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEndTimeParam(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "plain date includes the whole day", value: "2026-03-31", want: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "RFC3339 is kept", value: "2026-03-31T12:30:00Z", want: time.Date(2026, 3, 31, 12, 30, 0, 0, time.UTC)},
+		{name: "invalid", value: "31/03/2026", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEndTimeParam(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/logger"
+	"github.com/site-geav-api/internal/models"
+	"github.com/site-geav-api/internal/repository"
+)
+
+// defaultCoverageThreshold is the minimum number of lugares and cancoes of a
+// ramo when GET /ramos/coverage is called without a threshold
+const defaultCoverageThreshold = 3
+
+// RamoHandler handles ramo-related requests
+type RamoHandler struct {
+	ramoRepo repository.RamoRepository
+	log      logger.Logger
+}
+
+// NewRamoHandler creates a new RamoHandler
+func NewRamoHandler(ramoRepo repository.RamoRepository, log logger.Logger) *RamoHandler {
+	return &RamoHandler{
+		ramoRepo: ramoRepo,
+		log:      log,
+	}
+}
+
+// ListRamos handles GET /ramos requests
+func (h *RamoHandler) ListRamos(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Add the action and resource to every log entry
+	ctx = logger.With(ctx, map[string]interface{}{"action": "ListRamos", "resource": "ramos"})
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err)
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get ramos from repository
+	ramos, err := h.ramoRepo.List(ctx, repository.Pagination{Limit: limit, Offset: offset})
+	if err != nil {
+		h.log.Error(ctx, "Error listing ramos", err)
+		return createErrorResponse(internalError("Error listing ramos"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Ramos listed successfully", map[string]interface{}{
+		"count": len(ramos),
+	})
+
+	// Return ramos as JSON
+	return createPaginatedResponse(ctx, h.log, request, ramos, len(ramos), limit, offset, "ramos", func() (int, error) {
+		return h.ramoRepo.Count(ctx)
+	})
+}
+
+// ListRamoOptions handles GET /ramos/options requests, returning only the ID and name of
+// every ramo for select inputs
+func (h *RamoHandler) ListRamoOptions(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Add the action and resource to every log entry
+	ctx = logger.With(ctx, map[string]interface{}{"action": "ListRamoOptions", "resource": "ramos"})
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err)
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get options from repository
+	options, err := h.ramoRepo.ListSelectOptions(ctx, repository.Pagination{Limit: limit, Offset: offset})
+	if err != nil {
+		h.log.Error(ctx, "Error listing ramo options", err)
+		return createErrorResponse(internalError("Error listing ramo options"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Ramo options listed successfully", map[string]interface{}{
+		"count": len(options),
+	})
+
+	// Return options as JSON
+	return createCachedJSONResponse(http.StatusOK, options, "ramos")
+}
+
+// GetRamoCoverage handles GET /ramos/coverage requests
+func (h *RamoHandler) GetRamoCoverage(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Add the action and resource to every log entry
+	ctx = logger.With(ctx, map[string]interface{}{"action": "GetRamoCoverage", "resource": "ramos"})
+
+	// Parse threshold
+	threshold := defaultCoverageThreshold
+	if value := request.QueryStringParameters["threshold"]; value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			h.log.Warn(ctx, "Invalid coverage threshold", map[string]interface{}{
+				"threshold": value,
+			})
+			return createErrorResponse(validationError("threshold must be a non-negative integer"))
+		}
+		threshold = parsed
+	}
+
+	// Get coverage from repository
+	coverage, err := h.ramoRepo.Coverage(ctx, threshold)
+	if err != nil {
+		h.log.Error(ctx, "Error getting ramo coverage", err)
+		return createErrorResponse(internalError("Error getting ramo coverage"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Ramo coverage retrieved successfully", map[string]interface{}{
+		"count":     len(coverage),
+		"threshold": threshold,
+	})
+
+	// Return coverage as JSON
+	return createCachedJSONResponse(http.StatusOK, coverage, "ramos")
+}
+
+// GetRamo handles GET /ramos/{id} requests
+func (h *RamoHandler) GetRamo(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Add the action and resource to every log entry
+	ctx = logger.With(ctx, map[string]interface{}{"action": "GetRamo", "resource": "ramos"})
+
+	// Extract ramo ID from path parameters
+	ramoID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid ramo ID", err)
+		return createErrorResponse(invalidIDError("Invalid ramo ID"))
+	}
+	ctx = logger.With(ctx, map[string]interface{}{"resource_id": fmt.Sprintf("%d", ramoID)})
+
+	// Get ramo from repository
+	ramo, err := h.ramoRepo.GetByID(ctx, ramoID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting ramo", err)
+		return createErrorResponse(internalError("Error getting ramo"))
+	}
+
+	// If ramo not found
+	if ramo == nil {
+		h.log.Warn(ctx, "Ramo not found")
+		return createErrorResponse(notFoundError("Ramo not found"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Ramo retrieved successfully")
+
+	// Return ramo as JSON
+	return createCachedJSONResponse(http.StatusOK, ramo, "ramos")
+}
+
+// CreateRamo handles POST /ramos requests. With ?get_or_create=true an
+// existing ramo with the same name is returned with 200 instead of a 409.
+func (h *RamoHandler) CreateRamo(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Add the action and resource to every log entry
+	ctx = logger.With(ctx, map[string]interface{}{"action": "CreateRamo", "resource": "ramos"})
+
+	// Parse request body
+	var ramo models.Ramo
+	if err := decodeJSONBody(request, &ramo); err != nil {
+		h.log.Error(ctx, "Invalid request body", err)
+		return createErrorResponse(err)
+	}
+
+	// Validate ramo
+	ramo.Name = strings.TrimSpace(ramo.Name)
+	if ramo.Name == "" {
+		h.log.Warn(ctx, "Invalid ramo data: name is required")
+		return createErrorResponse(unprocessableError("Name is required"))
+	}
+
+	// Set timestamps
+	ramo.CreatedAt = time.Now().UTC()
+
+	// Return the existing ramo when asked to
+	if request.QueryStringParameters["get_or_create"] == "true" {
+		result, created, err := h.ramoRepo.GetOrCreate(ctx, &ramo)
+		if err != nil {
+			h.log.Error(ctx, "Error creating ramo", err)
+			return createErrorResponse(internalError("Error creating ramo"))
+		}
+
+		// Log success
+		h.log.Info(ctx, "Ramo retrieved or created successfully", map[string]interface{}{
+			"resource_id": fmt.Sprintf("%d", result.ID),
+			"created":     created,
+		})
+
+		// Return ramo as JSON
+		if created {
+			return createJSONResponse(http.StatusCreated, result)
+		}
+		return createJSONResponse(http.StatusOK, result)
+	}
+
+	// Create ramo in repository
+	ramoID, err := h.ramoRepo.Create(ctx, &ramo)
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			h.log.Warn(ctx, "Ramo already exists")
+			return createErrorResponse(conflictError("Ramo already exists"))
+		}
+		h.log.Error(ctx, "Error creating ramo", err)
+		return createErrorResponse(internalError("Error creating ramo"))
+	}
+
+	// Set ramo ID
+	ramo.ID = ramoID
+
+	// Log success
+	h.log.Info(ctx, "Ramo created successfully")
+
+	// Return created ramo as JSON
+	return createJSONResponse(http.StatusCreated, ramo)
+}
+
+// UpdateRamo handles PUT /ramos/{id} requests
+func (h *RamoHandler) UpdateRamo(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Add the action and resource to every log entry
+	ctx = logger.With(ctx, map[string]interface{}{"action": "UpdateRamo", "resource": "ramos"})
+
+	// Extract ramo ID from path parameters
+	ramoID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid ramo ID", err)
+		return createErrorResponse(invalidIDError("Invalid ramo ID"))
+	}
+	ctx = logger.With(ctx, map[string]interface{}{"resource_id": fmt.Sprintf("%d", ramoID)})
+
+	// Get existing ramo
+	existing, err := h.ramoRepo.GetByID(ctx, ramoID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting ramo", err)
+		return createErrorResponse(internalError("Error getting ramo"))
+	}
+
+	// If ramo not found
+	if existing == nil {
+		h.log.Warn(ctx, "Ramo not found")
+		return createErrorResponse(notFoundError("Ramo not found"))
+	}
+
+	// Parse request body
+	var updated models.Ramo
+	if err := decodeJSONBody(request, &updated); err != nil {
+		h.log.Error(ctx, "Invalid request body", err)
+		return createErrorResponse(err)
+	}
+
+	// Validate ramo
+	existing.Name = strings.TrimSpace(updated.Name)
+	if existing.Name == "" {
+		h.log.Warn(ctx, "Invalid ramo data: name is required")
+		return createErrorResponse(unprocessableError("Name is required"))
+	}
+
+	// Update ramo in repository
+	if err := h.ramoRepo.Update(ctx, existing); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			h.log.Warn(ctx, "Ramo already exists")
+			return createErrorResponse(conflictError("Ramo already exists"))
+		}
+		h.log.Error(ctx, "Error updating ramo", err)
+		return createErrorResponse(internalError("Error updating ramo"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Ramo updated successfully")
+
+	// Return updated ramo as JSON
+	return createJSONResponse(http.StatusOK, existing)
+}
+
+// DeleteRamo handles DELETE /ramos/{id} requests
+func (h *RamoHandler) DeleteRamo(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Add the action and resource to every log entry
+	ctx = logger.With(ctx, map[string]interface{}{"action": "DeleteRamo", "resource": "ramos"})
+
+	// Extract ramo ID from path parameters
+	ramoID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid ramo ID", err)
+		return createErrorResponse(invalidIDError("Invalid ramo ID"))
+	}
+	ctx = logger.With(ctx, map[string]interface{}{"resource_id": fmt.Sprintf("%d", ramoID)})
+
+	// Delete ramo from repository
+	if err := h.ramoRepo.Delete(ctx, ramoID); err != nil {
+		h.log.Error(ctx, "Error deleting ramo", err)
+		return createErrorResponse(internalError("Error deleting ramo"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Ramo deleted successfully")
+
+	// Return success response
+	return createNoContentResponse()
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/site-geav-api/internal/logger"
+	"github.com/site-geav-api/internal/models"
+	"github.com/site-geav-api/internal/repository"
+)
+
+func TestGetRamoLogsBoundFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		ramo         *models.Ramo
+		err          error
+		wantStatus   int
+		wantLevel    logger.LogLevel
+		wantResource string
+	}{
+		{name: "found", id: "2", ramo: &models.Ramo{ID: 2, Name: "lobinho"}, wantStatus: http.StatusOK, wantLevel: logger.INFO, wantResource: "2"},
+		{name: "not found", id: "9", wantStatus: http.StatusNotFound, wantLevel: logger.WARN, wantResource: "9"},
+		{name: "repository error", id: "2", err: errors.New("timeout"), wantStatus: http.StatusInternalServerError, wantLevel: logger.ERROR, wantResource: "2"},
+		{name: "invalid ID", id: "x", wantStatus: http.StatusBadRequest, wantLevel: logger.ERROR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &fakeLogger{}
+			repo := &fakeRamoRepo{
+				getByID: func(id int) (*models.Ramo, error) {
+					return tt.ramo, tt.err
+				},
+			}
+			h := NewRamoHandler(repo, log)
+
+			response, err := h.GetRamo(context.Background(), pathRequest(map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+
+			entry := log.last(t)
+			if entry.level != tt.wantLevel {
+				t.Errorf("level = %s, want %s", entry.level, tt.wantLevel)
+			}
+			if entry.metadata["action"] != "GetRamo" || entry.metadata["resource"] != "ramos" {
+				t.Errorf("bound fields missing from %v", entry.metadata)
+			}
+			if got, _ := entry.metadata["resource_id"].(string); got != tt.wantResource {
+				t.Errorf("resource_id = %q, want %q", got, tt.wantResource)
+			}
+		})
+	}
+}
+
+func TestCreateRamoGetOrCreate(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		query      map[string]string
+		wantStatus int
+		wantID     int
+	}{
+		{name: "new ramo", body: `{"name": "pioneiro"}`, wantStatus: http.StatusCreated, wantID: 6},
+		{name: "existing ramo", body: `{"name": "lobinho"}`, wantStatus: http.StatusConflict},
+		{name: "get_or_create with a new ramo", body: `{"name": "pioneiro"}`, query: map[string]string{"get_or_create": "true"}, wantStatus: http.StatusCreated, wantID: 6},
+		{name: "get_or_create with an existing ramo", body: `{"name": " lobinho "}`, query: map[string]string{"get_or_create": "true"}, wantStatus: http.StatusOK, wantID: 2},
+		{name: "missing name", body: `{"name": " "}`, query: map[string]string{"get_or_create": "true"}, wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := map[string]int{"lobinho": 2}
+			repo := &fakeRamoRepo{
+				create: func(ramo *models.Ramo) (int, error) {
+					if _, ok := existing[ramo.Name]; ok {
+						return 0, repository.ErrAlreadyExists
+					}
+					return 6, nil
+				},
+				getOrCreate: func(ramo *models.Ramo) (*models.Ramo, bool, error) {
+					if id, ok := existing[ramo.Name]; ok {
+						return &models.Ramo{ID: id, Name: ramo.Name}, false, nil
+					}
+					ramo.ID = 6
+					return ramo, true, nil
+				},
+			}
+			h := NewRamoHandler(repo, &fakeLogger{})
+			request := bodyRequest(tt.body, nil)
+			request.QueryStringParameters = tt.query
+
+			response, err := h.CreateRamo(context.Background(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantID != 0 {
+				var ramo models.Ramo
+				decodeBody(t, response, &ramo)
+				if ramo.ID != tt.wantID {
+					t.Errorf("ramo ID = %d, want %d", ramo.ID, tt.wantID)
+				}
+			}
+		})
+	}
+}
+
+func TestGetRamoCoverage(t *testing.T) {
+	tests := []struct {
+		name          string
+		threshold     string
+		wantStatus    int
+		wantThreshold int
+	}{
+		{name: "default threshold", wantStatus: http.StatusOK, wantThreshold: defaultCoverageThreshold},
+		{name: "explicit threshold", threshold: "5", wantStatus: http.StatusOK, wantThreshold: 5},
+		{name: "zero threshold", threshold: "0", wantStatus: http.StatusOK, wantThreshold: 0},
+		{name: "negative threshold", threshold: "-1", wantStatus: http.StatusBadRequest},
+		{name: "non-numeric threshold", threshold: "many", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotThreshold := -1
+			repo := &fakeRamoRepo{
+				coverage: func(threshold int) ([]*models.RamoCoverage, error) {
+					gotThreshold = threshold
+					return []*models.RamoCoverage{{Ramo: models.Ramo{ID: 5, Name: "clã"}, LugarCount: 0, CancaoCount: 4, UnderCovered: true}}, nil
+				},
+			}
+			h := NewRamoHandler(repo, &fakeLogger{})
+			query := map[string]string{}
+			if tt.threshold != "" {
+				query["threshold"] = tt.threshold
+			}
+
+			response, err := h.GetRamoCoverage(context.Background(), queryRequest(query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if gotThreshold != tt.wantThreshold {
+				t.Errorf("threshold = %d, want %d", gotThreshold, tt.wantThreshold)
+			}
+			var coverage []models.RamoCoverage
+			decodeBody(t, response, &coverage)
+			if len(coverage) != 1 || !coverage[0].UnderCovered {
+				t.Errorf("coverage = %+v, want the under-covered flag", coverage)
+			}
+		})
+	}
+}
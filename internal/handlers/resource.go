@@ -0,0 +1,13 @@
+package handlers
+
+import "strings"
+
+// NormalizeResource strips the trailing slashes of a resource or path,
+// keeping "/" as is, so API Gateway's /lugares/ routes like /lugares
+func NormalizeResource(resource string) string {
+	trimmed := strings.TrimRight(resource, "/")
+	if trimmed == "" && resource != "" {
+		return "/"
+	}
+	return trimmed
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import "testing"
+
+func TestNormalizeResource(t *testing.T) {
+	tests := []struct {
+		resource string
+		want     string
+	}{
+		{"/lugares", "/lugares"},
+		{"/lugares/", "/lugares"},
+		{"/lugares//", "/lugares"},
+		{"/lugares/{id}/", "/lugares/{id}"},
+		{"/lugares/{id}/images", "/lugares/{id}/images"},
+		{"/", "/"},
+		{"//", "/"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			if got := NormalizeResource(tt.resource); got != tt.want {
+				t.Errorf("NormalizeResource(%q) = %q, want %q", tt.resource, got, tt.want)
+			}
+		})
+	}
+}
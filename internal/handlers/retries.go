@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/logger"
+)
+
+// defaultRetryWindow is how long a request is remembered when
+// DUPLICATE_REQUEST_WINDOW is not set
+const defaultRetryWindow = 10 * time.Second
+
+// RetryDetector counts the write requests seen with the same Idempotency-Key
+// header, or else the same method, path, user and body, within a short window,
+// so retries and duplicate submissions can be logged. Like the request
+// metrics, it only sees the requests of its own execution environment.
+type RetryDetector struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	seen map[string][]time.Time
+}
+
+// NewRetryDetector creates a retry detector. The window is read from
+// DUPLICATE_REQUEST_WINDOW as a duration (default 10s); 0 turns it off.
+func NewRetryDetector() *RetryDetector {
+	window := defaultRetryWindow
+	if value := os.Getenv("DUPLICATE_REQUEST_WINDOW"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			window = parsed
+		}
+	}
+
+	return &RetryDetector{
+		window: window,
+		seen:   map[string][]time.Time{},
+	}
+}
+
+// Observe records a POST, PUT, PATCH or DELETE request and returns its
+// signature and how many times it was seen within the window, this one
+// included. Other requests return a count of 0.
+func (d *RetryDetector) Observe(ctx context.Context, request events.APIGatewayProxyRequest, now time.Time) (string, int) {
+	switch request.HTTPMethod {
+	case "POST", "PUT", "PATCH", "DELETE":
+	default:
+		return "", 0
+	}
+	if d.window <= 0 {
+		return "", 0
+	}
+
+	signature := requestSignature(ctx, request)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Forget the requests that left the window, so the map stays small
+	cutoff := now.Add(-d.window)
+	for key, times := range d.seen {
+		recent := times[:0]
+		for _, t := range times {
+			if t.After(cutoff) {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) == 0 {
+			delete(d.seen, key)
+		} else {
+			d.seen[key] = recent
+		}
+	}
+
+	d.seen[signature] = append(d.seen[signature], now)
+	return signature, len(d.seen[signature])
+}
+
+// LogRetries observes a request and logs a warning with its count when it was
+// already seen within the window: repeated writes are usually client retries
+// or double submissions
+func (d *RetryDetector) LogRetries(ctx context.Context, log logger.Logger, request events.APIGatewayProxyRequest, now time.Time) {
+	signature, count := d.Observe(ctx, request, now)
+	if count <= 1 {
+		return
+	}
+
+	log.Warn(ctx, "Repeated request detected", map[string]interface{}{
+		"action":    "DetectRetry",
+		"method":    request.HTTPMethod,
+		"path":      request.Path,
+		"signature": signature,
+		"count":     count,
+	})
+}
+
+// requestSignature identifies a request by its Idempotency-Key header, or by a
+// hash of its method, path, caller and body
+func requestSignature(ctx context.Context, request events.APIGatewayProxyRequest) string {
+	var userID int
+	if user := currentUser(ctx); user != nil {
+		userID = user.ID
+	}
+
+	if key := headerValue(request, "Idempotency-Key"); key != "" {
+		return fmt.Sprintf("key:%d:%s", userID, key)
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s %s %d\n%s", request.HTTPMethod, request.Path, userID, request.Body)))
+	return "body:" + hex.EncodeToString(sum[:])
+}
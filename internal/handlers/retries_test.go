@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/logger"
+)
+
+func TestLogRetries(t *testing.T) {
+	post := func(body string, headers map[string]string) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{HTTPMethod: "POST", Path: "/lugares", Body: body, Headers: headers}
+	}
+	withKey := func(body, key string) events.APIGatewayProxyRequest {
+		return post(body, map[string]string{"Idempotency-Key": key})
+	}
+
+	// step is a request made after seconds, by the given user
+	type step struct {
+		request events.APIGatewayProxyRequest
+		ctx     context.Context
+		seconds int
+	}
+
+	tests := []struct {
+		name   string
+		window string
+		steps  []step
+		// wantCounts are the counts of the warnings logged, in order
+		wantCounts []int
+	}{
+		{
+			name:       "repeated signature",
+			steps:      []step{{request: post(`{"nome_local":"Sítio"}`, nil)}, {request: post(`{"nome_local":"Sítio"}`, nil), seconds: 1}, {request: post(`{"nome_local":"Sítio"}`, nil), seconds: 2}},
+			wantCounts: []int{2, 3},
+		},
+		{
+			name:       "different bodies",
+			steps:      []step{{request: post(`{"nome_local":"Sítio"}`, nil)}, {request: post(`{"nome_local":"Chácara"}`, nil), seconds: 1}},
+			wantCounts: nil,
+		},
+		{
+			name:       "same idempotency key with another body",
+			steps:      []step{{request: withKey(`{"nome_local":"Sítio"}`, "abc")}, {request: withKey(`{"nome_local":"Sitio"}`, "abc"), seconds: 1}},
+			wantCounts: []int{2},
+		},
+		{
+			name:       "same body from another user",
+			steps:      []step{{request: post(`{}`, nil), ctx: userContext(2, "read")}, {request: post(`{}`, nil), ctx: userContext(3, "read"), seconds: 1}},
+			wantCounts: nil,
+		},
+		{
+			name:       "repeated after the window",
+			steps:      []step{{request: post(`{}`, nil)}, {request: post(`{}`, nil), seconds: 11}},
+			wantCounts: nil,
+		},
+		{
+			name:       "reads are not tracked",
+			steps:      []step{{request: events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/lugares"}}, {request: events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/lugares"}, seconds: 1}},
+			wantCounts: nil,
+		},
+		{
+			name:       "detection off",
+			window:     "0",
+			steps:      []step{{request: post(`{}`, nil)}, {request: post(`{}`, nil), seconds: 1}},
+			wantCounts: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DUPLICATE_REQUEST_WINDOW", tt.window)
+			detector := NewRetryDetector()
+			log := &fakeLogger{}
+
+			start := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+			for _, s := range tt.steps {
+				ctx := s.ctx
+				if ctx == nil {
+					ctx = context.Background()
+				}
+				detector.LogRetries(ctx, log, s.request, start.Add(time.Duration(s.seconds)*time.Second))
+			}
+
+			var counts []int
+			for _, entry := range log.entries {
+				if entry.level != logger.WARN || entry.message != "Repeated request detected" {
+					t.Errorf("unexpected log entry %s %q", entry.level, entry.message)
+					continue
+				}
+				counts = append(counts, entry.metadata["count"].(int))
+			}
+			if len(counts) != len(tt.wantCounts) {
+				t.Fatalf("warning counts = %v, want %v", counts, tt.wantCounts)
+			}
+			for i := range counts {
+				if counts[i] != tt.wantCounts[i] {
+					t.Errorf("warning counts = %v, want %v", counts, tt.wantCounts)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,732 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/logger"
+	"github.com/site-geav-api/internal/models"
+	"github.com/site-geav-api/internal/repository"
+)
+
+// TagHandler handles requests for the place and song tags
+type TagHandler struct {
+	tagLugarRepo  repository.TagLugarRepository
+	tagCancaoRepo repository.TagCancaoRepository
+	log           logger.Logger
+}
+
+// NewTagHandler creates a new TagHandler
+func NewTagHandler(tagLugarRepo repository.TagLugarRepository, tagCancaoRepo repository.TagCancaoRepository, log logger.Logger) *TagHandler {
+	return &TagHandler{
+		tagLugarRepo:  tagLugarRepo,
+		tagCancaoRepo: tagCancaoRepo,
+		log:           log,
+	}
+}
+
+// ListLugarTags handles GET /tags/lugares requests
+func (h *TagHandler) ListLugarTags(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListLugarTags",
+			"resource": "tags",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get lugar tags from repository
+	tags, err := h.tagLugarRepo.List(ctx, repository.Pagination{Limit: limit, Offset: offset})
+	if err != nil {
+		h.log.Error(ctx, "Error listing lugar tags", err, map[string]interface{}{
+			"action":   "ListLugarTags",
+			"resource": "tags",
+		})
+		return createErrorResponse(internalError("Error listing lugar tags"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugar tags listed successfully", map[string]interface{}{
+		"action":   "ListLugarTags",
+		"resource": "tags",
+		"count":    len(tags),
+	})
+
+	// Return lugar tags as JSON
+	return createPaginatedResponse(ctx, h.log, request, tags, len(tags), limit, offset, "tags", func() (int, error) {
+		return h.tagLugarRepo.Count(ctx)
+	})
+}
+
+// ListAvailableLugarTags handles GET /lugares/{id}/tags/available requests,
+// returning the lugar tags the lugar does not have yet
+func (h *TagHandler) ListAvailableLugarTags(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract lugar ID from path parameters
+	lugarID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid lugar ID", err, map[string]interface{}{
+			"action":   "ListAvailableLugarTags",
+			"resource": "tags",
+		})
+		return createErrorResponse(invalidIDError("Invalid lugar ID"))
+	}
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":      "ListAvailableLugarTags",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get unassigned lugar tags from repository
+	tags, err := h.tagLugarRepo.ListUnassigned(ctx, lugarID, repository.Pagination{Limit: limit, Offset: offset})
+	if err != nil {
+		h.log.Error(ctx, "Error listing available lugar tags", err, map[string]interface{}{
+			"action":      "ListAvailableLugarTags",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(internalError("Error listing available lugar tags"))
+	}
+
+	// If lugar not found
+	if tags == nil {
+		h.log.Warn(ctx, "Lugar not found", map[string]interface{}{
+			"action":      "ListAvailableLugarTags",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", lugarID),
+		})
+		return createErrorResponse(notFoundError("Lugar not found"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Available lugar tags listed successfully", map[string]interface{}{
+		"action":      "ListAvailableLugarTags",
+		"resource":    "tags",
+		"resource_id": fmt.Sprintf("%d", lugarID),
+		"count":       len(tags),
+	})
+
+	// Return available lugar tags as JSON; they change with the lugar's tags, so they are not cached
+	return createJSONResponse(http.StatusOK, tags)
+}
+
+// ListLugarTagOptions handles GET /tags/lugares/options requests, returning only the ID and name of
+// every lugar tag for select inputs
+func (h *TagHandler) ListLugarTagOptions(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListLugarTagOptions",
+			"resource": "tags",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get options from repository
+	options, err := h.tagLugarRepo.ListSelectOptions(ctx, repository.Pagination{Limit: limit, Offset: offset})
+	if err != nil {
+		h.log.Error(ctx, "Error listing lugar tag options", err, map[string]interface{}{
+			"action":   "ListLugarTagOptions",
+			"resource": "tags",
+		})
+		return createErrorResponse(internalError("Error listing lugar tag options"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugar tag options listed successfully", map[string]interface{}{
+		"action":   "ListLugarTagOptions",
+		"resource": "tags",
+		"count":    len(options),
+	})
+
+	// Return options as JSON
+	return createCachedJSONResponse(http.StatusOK, options, "tags")
+}
+
+const (
+	// defaultSuggestLimit is the number of suggestions returned when the request does not set a limit
+	defaultSuggestLimit = 5
+	// maxSuggestLimit is the largest accepted suggestion limit
+	maxSuggestLimit = 20
+)
+
+// SuggestLugarTags handles GET /tags/lugares/suggest?q=&limit= requests
+func (h *TagHandler) SuggestLugarTags(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Validate query
+	query := strings.TrimSpace(request.QueryStringParameters["q"])
+	if query == "" {
+		h.log.Warn(ctx, "Missing suggestion query", map[string]interface{}{
+			"action":   "SuggestLugarTags",
+			"resource": "tags",
+		})
+		return createErrorResponse(validationError("q is required"))
+	}
+
+	// Validate limit
+	limit, err := parseLimitParam(request, defaultSuggestLimit, maxSuggestLimit)
+	if err != nil {
+		h.log.Warn(ctx, "Invalid suggestion limit", map[string]interface{}{
+			"action":   "SuggestLugarTags",
+			"resource": "tags",
+			"limit":    request.QueryStringParameters["limit"],
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get suggestions from repository
+	tags, err := h.tagLugarRepo.Suggest(ctx, query, limit)
+	if err != nil {
+		h.log.Error(ctx, "Error suggesting lugar tags", err, map[string]interface{}{
+			"action":   "SuggestLugarTags",
+			"resource": "tags",
+		})
+		return createErrorResponse(internalError("Error suggesting tags"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugar tags suggested successfully", map[string]interface{}{
+		"action":   "SuggestLugarTags",
+		"resource": "tags",
+		"count":    len(tags),
+	})
+
+	// Return suggestions as JSON
+	return createCachedJSONResponse(http.StatusOK, tags, "tags")
+}
+
+// GetLugarTag handles GET /tags/lugares/{id} requests
+func (h *TagHandler) GetLugarTag(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract lugar tag ID from path parameters
+	tagID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid tag ID", err, map[string]interface{}{
+			"action":   "GetLugarTag",
+			"resource": "tags",
+		})
+		return createErrorResponse(invalidIDError("Invalid tag ID"))
+	}
+
+	// Get lugar tag from repository
+	tag, err := h.tagLugarRepo.GetByID(ctx, tagID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting lugar tag", err, map[string]interface{}{
+			"action":      "GetLugarTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(internalError("Error getting tag"))
+	}
+
+	// If lugar tag not found
+	if tag == nil {
+		h.log.Warn(ctx, "Tag not found", map[string]interface{}{
+			"action":      "GetLugarTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(notFoundError("Tag not found"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugar tag retrieved successfully", map[string]interface{}{
+		"action":      "GetLugarTag",
+		"resource":    "tags",
+		"resource_id": fmt.Sprintf("%d", tagID),
+	})
+
+	// Return lugar tag as JSON
+	return createCachedJSONResponse(http.StatusOK, tag, "tags")
+}
+
+// CreateLugarTag handles POST /tags/lugares requests. With ?get_or_create=true an
+// existing tag with the same name is returned with 200 instead of a 409.
+func (h *TagHandler) CreateLugarTag(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Parse request body
+	var tag models.TagLugar
+	if err := decodeJSONBody(request, &tag); err != nil {
+		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
+			"action":   "CreateLugarTag",
+			"resource": "tags",
+		})
+		return createErrorResponse(err)
+	}
+
+	// Validate lugar tag
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		h.log.Warn(ctx, "Invalid lugar tag data: name is required", map[string]interface{}{
+			"action":   "CreateLugarTag",
+			"resource": "tags",
+		})
+		return createErrorResponse(unprocessableError("Name is required"))
+	}
+
+	// Set timestamps
+	tag.CreatedAt = time.Now().UTC()
+
+	// Return the existing lugar tag when asked to
+	if request.QueryStringParameters["get_or_create"] == "true" {
+		result, created, err := h.tagLugarRepo.GetOrCreate(ctx, &tag)
+		if err != nil {
+			h.log.Error(ctx, "Error creating lugar tag", err, map[string]interface{}{
+				"action":   "CreateLugarTag",
+				"resource": "tags",
+			})
+			return createErrorResponse(internalError("Error creating tag"))
+		}
+
+		// Log success
+		h.log.Info(ctx, "Lugar tag retrieved or created successfully", map[string]interface{}{
+			"action":      "CreateLugarTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", result.ID),
+			"created":     created,
+		})
+
+		// Return lugar tag as JSON
+		if created {
+			return createJSONResponse(http.StatusCreated, result)
+		}
+		return createJSONResponse(http.StatusOK, result)
+	}
+
+	// Create lugar tag in repository
+	tagID, err := h.tagLugarRepo.Create(ctx, &tag)
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			h.log.Warn(ctx, "Lugar tag already exists", map[string]interface{}{
+				"action":   "CreateLugarTag",
+				"resource": "tags",
+			})
+			return createErrorResponse(conflictError("Tag already exists"))
+		}
+		h.log.Error(ctx, "Error creating lugar tag", err, map[string]interface{}{
+			"action":   "CreateLugarTag",
+			"resource": "tags",
+		})
+		return createErrorResponse(internalError("Error creating tag"))
+	}
+
+	// Set lugar tag ID
+	tag.ID = tagID
+
+	// Log success
+	h.log.Info(ctx, "Lugar tag created successfully", map[string]interface{}{
+		"action":      "CreateLugarTag",
+		"resource":    "tags",
+		"resource_id": fmt.Sprintf("%d", tagID),
+	})
+
+	// Return created lugar tag as JSON
+	return createJSONResponse(http.StatusCreated, tag)
+}
+
+// UpdateLugarTag handles PUT /tags/lugares/{id} requests
+func (h *TagHandler) UpdateLugarTag(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract lugar tag ID from path parameters
+	tagID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid tag ID", err, map[string]interface{}{
+			"action":   "UpdateLugarTag",
+			"resource": "tags",
+		})
+		return createErrorResponse(invalidIDError("Invalid tag ID"))
+	}
+
+	// Get existing lugar tag
+	existing, err := h.tagLugarRepo.GetByID(ctx, tagID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting lugar tag", err, map[string]interface{}{
+			"action":      "UpdateLugarTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(internalError("Error getting tag"))
+	}
+
+	// If lugar tag not found
+	if existing == nil {
+		h.log.Warn(ctx, "Tag not found", map[string]interface{}{
+			"action":      "UpdateLugarTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(notFoundError("Tag not found"))
+	}
+
+	// Parse request body
+	var updated models.TagLugar
+	if err := decodeJSONBody(request, &updated); err != nil {
+		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
+			"action":      "UpdateLugarTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(err)
+	}
+
+	// Validate lugar tag
+	existing.Name = strings.TrimSpace(updated.Name)
+	if existing.Name == "" {
+		h.log.Warn(ctx, "Invalid lugar tag data: name is required", map[string]interface{}{
+			"action":      "UpdateLugarTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(unprocessableError("Name is required"))
+	}
+
+	// Update lugar tag in repository
+	if err := h.tagLugarRepo.Update(ctx, existing); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			h.log.Warn(ctx, "Lugar tag already exists", map[string]interface{}{
+				"action":      "UpdateLugarTag",
+				"resource":    "tags",
+				"resource_id": fmt.Sprintf("%d", tagID),
+			})
+			return createErrorResponse(conflictError("Tag already exists"))
+		}
+		h.log.Error(ctx, "Error updating lugar tag", err, map[string]interface{}{
+			"action":      "UpdateLugarTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(internalError("Error updating tag"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugar tag updated successfully", map[string]interface{}{
+		"action":      "UpdateLugarTag",
+		"resource":    "tags",
+		"resource_id": fmt.Sprintf("%d", tagID),
+	})
+
+	// Return updated lugar tag as JSON
+	return createJSONResponse(http.StatusOK, existing)
+}
+
+// DeleteLugarTag handles DELETE /tags/lugares/{id} requests
+func (h *TagHandler) DeleteLugarTag(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract lugar tag ID from path parameters
+	tagID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid tag ID", err, map[string]interface{}{
+			"action":   "DeleteLugarTag",
+			"resource": "tags",
+		})
+		return createErrorResponse(invalidIDError("Invalid tag ID"))
+	}
+
+	// Delete lugar tag from repository
+	if err := h.tagLugarRepo.Delete(ctx, tagID); err != nil {
+		h.log.Error(ctx, "Error deleting lugar tag", err, map[string]interface{}{
+			"action":      "DeleteLugarTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(internalError("Error deleting tag"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Lugar tag deleted successfully", map[string]interface{}{
+		"action":      "DeleteLugarTag",
+		"resource":    "tags",
+		"resource_id": fmt.Sprintf("%d", tagID),
+	})
+
+	// Return success response
+	return createNoContentResponse()
+}
+
+// ListCancaoTags handles GET /tags/cancoes requests
+func (h *TagHandler) ListCancaoTags(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListCancaoTags",
+			"resource": "tags",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get cancao tags from repository
+	tags, err := h.tagCancaoRepo.List(ctx, repository.Pagination{Limit: limit, Offset: offset})
+	if err != nil {
+		h.log.Error(ctx, "Error listing cancao tags", err, map[string]interface{}{
+			"action":   "ListCancaoTags",
+			"resource": "tags",
+		})
+		return createErrorResponse(internalError("Error listing cancao tags"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Cancao tags listed successfully", map[string]interface{}{
+		"action":   "ListCancaoTags",
+		"resource": "tags",
+		"count":    len(tags),
+	})
+
+	// Return cancao tags as JSON
+	return createPaginatedResponse(ctx, h.log, request, tags, len(tags), limit, offset, "tags", func() (int, error) {
+		return h.tagCancaoRepo.Count(ctx)
+	})
+}
+
+// GetCancaoTag handles GET /tags/cancoes/{id} requests
+func (h *TagHandler) GetCancaoTag(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract cancao tag ID from path parameters
+	tagID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid tag ID", err, map[string]interface{}{
+			"action":   "GetCancaoTag",
+			"resource": "tags",
+		})
+		return createErrorResponse(invalidIDError("Invalid tag ID"))
+	}
+
+	// Get cancao tag from repository
+	tag, err := h.tagCancaoRepo.GetByID(ctx, tagID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting cancao tag", err, map[string]interface{}{
+			"action":      "GetCancaoTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(internalError("Error getting tag"))
+	}
+
+	// If cancao tag not found
+	if tag == nil {
+		h.log.Warn(ctx, "Tag not found", map[string]interface{}{
+			"action":      "GetCancaoTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(notFoundError("Tag not found"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Cancao tag retrieved successfully", map[string]interface{}{
+		"action":      "GetCancaoTag",
+		"resource":    "tags",
+		"resource_id": fmt.Sprintf("%d", tagID),
+	})
+
+	// Return cancao tag as JSON
+	return createCachedJSONResponse(http.StatusOK, tag, "tags")
+}
+
+// CreateCancaoTag handles POST /tags/cancoes requests. With ?get_or_create=true an
+// existing tag with the same name is returned with 200 instead of a 409.
+func (h *TagHandler) CreateCancaoTag(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Parse request body
+	var tag models.TagCancao
+	if err := decodeJSONBody(request, &tag); err != nil {
+		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
+			"action":   "CreateCancaoTag",
+			"resource": "tags",
+		})
+		return createErrorResponse(err)
+	}
+
+	// Validate cancao tag
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		h.log.Warn(ctx, "Invalid cancao tag data: name is required", map[string]interface{}{
+			"action":   "CreateCancaoTag",
+			"resource": "tags",
+		})
+		return createErrorResponse(unprocessableError("Name is required"))
+	}
+
+	// Set timestamps
+	tag.CreatedAt = time.Now().UTC()
+
+	// Return the existing cancao tag when asked to
+	if request.QueryStringParameters["get_or_create"] == "true" {
+		result, created, err := h.tagCancaoRepo.GetOrCreate(ctx, &tag)
+		if err != nil {
+			h.log.Error(ctx, "Error creating cancao tag", err, map[string]interface{}{
+				"action":   "CreateCancaoTag",
+				"resource": "tags",
+			})
+			return createErrorResponse(internalError("Error creating tag"))
+		}
+
+		// Log success
+		h.log.Info(ctx, "Cancao tag retrieved or created successfully", map[string]interface{}{
+			"action":      "CreateCancaoTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", result.ID),
+			"created":     created,
+		})
+
+		// Return cancao tag as JSON
+		if created {
+			return createJSONResponse(http.StatusCreated, result)
+		}
+		return createJSONResponse(http.StatusOK, result)
+	}
+
+	// Create cancao tag in repository
+	tagID, err := h.tagCancaoRepo.Create(ctx, &tag)
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			h.log.Warn(ctx, "Cancao tag already exists", map[string]interface{}{
+				"action":   "CreateCancaoTag",
+				"resource": "tags",
+			})
+			return createErrorResponse(conflictError("Tag already exists"))
+		}
+		h.log.Error(ctx, "Error creating cancao tag", err, map[string]interface{}{
+			"action":   "CreateCancaoTag",
+			"resource": "tags",
+		})
+		return createErrorResponse(internalError("Error creating tag"))
+	}
+
+	// Set cancao tag ID
+	tag.ID = tagID
+
+	// Log success
+	h.log.Info(ctx, "Cancao tag created successfully", map[string]interface{}{
+		"action":      "CreateCancaoTag",
+		"resource":    "tags",
+		"resource_id": fmt.Sprintf("%d", tagID),
+	})
+
+	// Return created cancao tag as JSON
+	return createJSONResponse(http.StatusCreated, tag)
+}
+
+// UpdateCancaoTag handles PUT /tags/cancoes/{id} requests
+func (h *TagHandler) UpdateCancaoTag(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract cancao tag ID from path parameters
+	tagID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid tag ID", err, map[string]interface{}{
+			"action":   "UpdateCancaoTag",
+			"resource": "tags",
+		})
+		return createErrorResponse(invalidIDError("Invalid tag ID"))
+	}
+
+	// Get existing cancao tag
+	existing, err := h.tagCancaoRepo.GetByID(ctx, tagID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting cancao tag", err, map[string]interface{}{
+			"action":      "UpdateCancaoTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(internalError("Error getting tag"))
+	}
+
+	// If cancao tag not found
+	if existing == nil {
+		h.log.Warn(ctx, "Tag not found", map[string]interface{}{
+			"action":      "UpdateCancaoTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(notFoundError("Tag not found"))
+	}
+
+	// Parse request body
+	var updated models.TagCancao
+	if err := decodeJSONBody(request, &updated); err != nil {
+		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
+			"action":      "UpdateCancaoTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(err)
+	}
+
+	// Validate cancao tag
+	existing.Name = strings.TrimSpace(updated.Name)
+	if existing.Name == "" {
+		h.log.Warn(ctx, "Invalid cancao tag data: name is required", map[string]interface{}{
+			"action":      "UpdateCancaoTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(unprocessableError("Name is required"))
+	}
+
+	// Update cancao tag in repository
+	if err := h.tagCancaoRepo.Update(ctx, existing); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			h.log.Warn(ctx, "Cancao tag already exists", map[string]interface{}{
+				"action":      "UpdateCancaoTag",
+				"resource":    "tags",
+				"resource_id": fmt.Sprintf("%d", tagID),
+			})
+			return createErrorResponse(conflictError("Tag already exists"))
+		}
+		h.log.Error(ctx, "Error updating cancao tag", err, map[string]interface{}{
+			"action":      "UpdateCancaoTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(internalError("Error updating tag"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Cancao tag updated successfully", map[string]interface{}{
+		"action":      "UpdateCancaoTag",
+		"resource":    "tags",
+		"resource_id": fmt.Sprintf("%d", tagID),
+	})
+
+	// Return updated cancao tag as JSON
+	return createJSONResponse(http.StatusOK, existing)
+}
+
+// DeleteCancaoTag handles DELETE /tags/cancoes/{id} requests
+func (h *TagHandler) DeleteCancaoTag(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract cancao tag ID from path parameters
+	tagID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid tag ID", err, map[string]interface{}{
+			"action":   "DeleteCancaoTag",
+			"resource": "tags",
+		})
+		return createErrorResponse(invalidIDError("Invalid tag ID"))
+	}
+
+	// Delete cancao tag from repository
+	if err := h.tagCancaoRepo.Delete(ctx, tagID); err != nil {
+		h.log.Error(ctx, "Error deleting cancao tag", err, map[string]interface{}{
+			"action":      "DeleteCancaoTag",
+			"resource":    "tags",
+			"resource_id": fmt.Sprintf("%d", tagID),
+		})
+		return createErrorResponse(internalError("Error deleting tag"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Cancao tag deleted successfully", map[string]interface{}{
+		"action":      "DeleteCancaoTag",
+		"resource":    "tags",
+		"resource_id": fmt.Sprintf("%d", tagID),
+	})
+
+	// Return success response
+	return createNoContentResponse()
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/site-geav-api/internal/models"
+	"github.com/site-geav-api/internal/repository"
+)
+
+func TestCreateLugarTagGetOrCreate(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		query      map[string]string
+		repoErr    error
+		wantStatus int
+		wantID     int
+	}{
+		{name: "new tag", body: `{"name": "trilha"}`, wantStatus: http.StatusCreated, wantID: 20},
+		{name: "existing tag", body: `{"name": "rio"}`, wantStatus: http.StatusConflict},
+		{name: "get_or_create with a new tag", body: `{"name": "trilha"}`, query: map[string]string{"get_or_create": "true"}, wantStatus: http.StatusCreated, wantID: 20},
+		{name: "get_or_create with an existing tag", body: `{"name": "rio"}`, query: map[string]string{"get_or_create": "true"}, wantStatus: http.StatusOK, wantID: 1},
+		{name: "get_or_create repository error", body: `{"name": "rio"}`, query: map[string]string{"get_or_create": "true"}, repoErr: errors.New("timeout"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := map[string]int{"rio": 1}
+			repo := &fakeTagLugarRepo{
+				create: func(tag *models.TagLugar) (int, error) {
+					if _, ok := existing[tag.Name]; ok {
+						return 0, repository.ErrAlreadyExists
+					}
+					return 20, nil
+				},
+				getOrCreate: func(tag *models.TagLugar) (*models.TagLugar, bool, error) {
+					if tt.repoErr != nil {
+						return nil, false, tt.repoErr
+					}
+					if id, ok := existing[tag.Name]; ok {
+						return &models.TagLugar{ID: id, Name: tag.Name}, false, nil
+					}
+					tag.ID = 20
+					return tag, true, nil
+				},
+			}
+			h := NewTagHandler(repo, nil, &fakeLogger{})
+			request := bodyRequest(tt.body, nil)
+			request.QueryStringParameters = tt.query
+
+			response, err := h.CreateLugarTag(context.Background(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantID != 0 {
+				var tag models.TagLugar
+				decodeBody(t, response, &tag)
+				if tag.ID != tt.wantID {
+					t.Errorf("tag ID = %d, want %d", tag.ID, tt.wantID)
+				}
+			}
+		})
+	}
+}
+
+func TestSuggestLugarTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      map[string]string
+		wantStatus int
+		wantQuery  string
+		wantLimit  int
+	}{
+		{name: "default limit", query: map[string]string{"q": "pa"}, wantStatus: http.StatusOK, wantQuery: "pa", wantLimit: defaultSuggestLimit},
+		{name: "query is trimmed", query: map[string]string{"q": "  pa ", "limit": "3"}, wantStatus: http.StatusOK, wantQuery: "pa", wantLimit: 3},
+		{name: "largest limit", query: map[string]string{"q": "pa", "limit": "20"}, wantStatus: http.StatusOK, wantQuery: "pa", wantLimit: maxSuggestLimit},
+		{name: "missing query", query: map[string]string{"limit": "5"}, wantStatus: http.StatusBadRequest},
+		{name: "blank query", query: map[string]string{"q": "   "}, wantStatus: http.StatusBadRequest},
+		{name: "zero limit", query: map[string]string{"q": "pa", "limit": "0"}, wantStatus: http.StatusBadRequest},
+		{name: "limit above the maximum", query: map[string]string{"q": "pa", "limit": "21"}, wantStatus: http.StatusBadRequest},
+		{name: "non-numeric limit", query: map[string]string{"q": "pa", "limit": "five"}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotQuery string
+			var gotLimit int
+			repo := &fakeTagLugarRepo{
+				suggest: func(query string, limit int) ([]*models.TagLugar, error) {
+					gotQuery, gotLimit = query, limit
+					return []*models.TagLugar{{ID: 4, Name: "pantano"}, {ID: 9, Name: "trampa"}}, nil
+				},
+			}
+			h := NewTagHandler(repo, nil, &fakeLogger{})
+
+			response, err := h.SuggestLugarTags(context.Background(), queryRequest(tt.query))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if gotLimit != 0 {
+					t.Error("repository was called for an invalid request")
+				}
+				return
+			}
+			if gotQuery != tt.wantQuery || gotLimit != tt.wantLimit {
+				t.Errorf("Suggest(%q, %d), want Suggest(%q, %d)", gotQuery, gotLimit, tt.wantQuery, tt.wantLimit)
+			}
+			var tags []models.TagLugar
+			decodeBody(t, response, &tags)
+			if len(tags) != 2 || tags[0].Name != "pantano" {
+				t.Errorf("suggestions = %+v, want the repository order", tags)
+			}
+		})
+	}
+}
+
+func TestListAvailableLugarTags(t *testing.T) {
+	available := []*models.TagLugar{{ID: 2, Name: "lago"}, {ID: 1, Name: "rio"}}
+
+	tests := []struct {
+		name       string
+		id         string
+		tags       []*models.TagLugar
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "lugar with tags left", id: "7", tags: available, wantStatus: http.StatusOK, wantCount: 2},
+		{name: "lugar with every tag", id: "7", tags: []*models.TagLugar{}, wantStatus: http.StatusOK, wantCount: 0},
+		{name: "missing lugar", id: "7", tags: nil, wantStatus: http.StatusNotFound},
+		{name: "invalid ID", id: "abc", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			repo := &fakeTagLugarRepo{
+				unassigned: func(lugarID int, page repository.Pagination) ([]*models.TagLugar, error) {
+					called = true
+					if lugarID != 7 {
+						t.Errorf("lugar ID = %d, want 7", lugarID)
+					}
+					return tt.tags, nil
+				},
+			}
+			h := NewTagHandler(repo, nil, &fakeLogger{})
+
+			response, err := h.ListAvailableLugarTags(context.Background(), pathRequest(map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus == http.StatusBadRequest && called {
+				t.Error("the repository was called with an invalid ID")
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			// An empty list is [], not null
+			var tags []models.TagLugar
+			decodeBody(t, response, &tags)
+			if tags == nil || len(tags) != tt.wantCount {
+				t.Errorf("body = %s, want %d tags", response.Body, tt.wantCount)
+			}
+			if response.Headers["Cache-Control"] != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", response.Headers["Cache-Control"])
+			}
+		})
+	}
+}
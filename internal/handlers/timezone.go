@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+	// Embed the time zone database, which the Lambda runtime may not provide
+	_ "time/tzdata"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// timestampFields are the JSON fields converted by ConvertTimestamps
+var timestampFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// ParseTimezone reads the tz query parameter (an IANA zone such as
+// America/Sao_Paulo). It returns a nil location when the parameter is not
+// set, so timestamps stay in UTC, and an error response for an unknown zone.
+func ParseTimezone(request events.APIGatewayProxyRequest) (*time.Location, events.APIGatewayProxyResponse, bool) {
+	name := strings.TrimSpace(request.QueryStringParameters["tz"])
+	if name == "" {
+		return nil, events.APIGatewayProxyResponse{}, true
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		response, _ := createErrorResponse(validationError("Unknown time zone " + name))
+		return nil, response, false
+	}
+
+	return loc, events.APIGatewayProxyResponse{}, true
+}
+
+// ConvertTimestamps rewrites the created_at and updated_at fields of a
+// successful JSON response in the given location, at any depth, keeping the
+// order of the fields. Responses that are not JSON objects or arrays are
+// returned unchanged.
+func ConvertTimestamps(response events.APIGatewayProxyResponse, loc *time.Location) events.APIGatewayProxyResponse {
+	if response.StatusCode < 200 || response.StatusCode >= 300 || response.Headers["Content-Type"] != "application/json" {
+		return response
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(response.Body))
+	decoder.UseNumber()
+
+	// The body is copied token by token rather than decoded into maps, which
+	// would sort the fields of every object
+	var buf bytes.Buffer
+	if err := convertTimestampValue(decoder, &buf, "", loc); err != nil {
+		return response
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return response
+	}
+	response.Body = buf.String()
+
+	// The body changed, so its ETag does too
+	if _, ok := response.Headers["ETag"]; ok {
+		response.Headers["ETag"] = computeETag([]byte(response.Body))
+	}
+
+	return response
+}
+
+// convertTimestampValue copies the next JSON value of decoder to buf,
+// converting it when it is the string of a timestamp field named key
+func convertTimestampValue(decoder *json.Decoder, buf *bytes.Buffer, key string, loc *time.Location) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch v := token.(type) {
+	case json.Delim:
+		return convertTimestampContainer(decoder, buf, v, loc)
+	case string:
+		if timestampFields[key] {
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				v = t.In(loc).Format(time.RFC3339Nano)
+			}
+		}
+		return writeJSON(buf, v)
+	case nil:
+		buf.WriteString("null")
+		return nil
+	default:
+		// json.Number and bool
+		return writeJSON(buf, v)
+	}
+}
+
+// convertTimestampContainer copies the object or array opened by delim to buf,
+// converting the timestamp fields found in it
+func convertTimestampContainer(decoder *json.Decoder, buf *bytes.Buffer, delim json.Delim, loc *time.Location) error {
+	buf.WriteString(delim.String())
+
+	for i := 0; decoder.More(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key := ""
+		if delim == '{' {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key, _ = token.(string)
+			if err := writeJSON(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+		}
+
+		if err := convertTimestampValue(decoder, buf, key, loc); err != nil {
+			return err
+		}
+	}
+
+	// Consume the closing delimiter
+	end, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	buf.WriteString(end.(json.Delim).String())
+	return nil
+}
+
+// writeJSON appends the JSON encoding of a scalar value to buf
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	buf.Write(encoded)
+	return nil
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestParseTimezone(t *testing.T) {
+	tests := []struct {
+		name     string
+		tz       string
+		wantOK   bool
+		wantName string
+	}{
+		{"not set", "", true, ""},
+		{"IANA zone", "America/Sao_Paulo", true, "America/Sao_Paulo"},
+		{"UTC", "UTC", true, "UTC"},
+		{"unknown zone", "America/Atlantida", false, ""},
+		{"server zone", "Local", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, response, ok := ParseTimezone(queryRequest(map[string]string{"tz": tt.tz}))
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				if response.StatusCode != http.StatusBadRequest || errorCode(t, response) != CodeValidationFailed {
+					t.Errorf("response = %d %s, want 400 %s", response.StatusCode, response.Body, CodeValidationFailed)
+				}
+				return
+			}
+			if tt.wantName == "" {
+				if loc != nil {
+					t.Errorf("location = %v, want nil", loc)
+				}
+				return
+			}
+			if loc == nil || loc.String() != tt.wantName {
+				t.Errorf("location = %v, want %s", loc, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestConvertTimestamps(t *testing.T) {
+	saoPaulo, _, _ := ParseTimezone(queryRequest(map[string]string{"tz": "America/Sao_Paulo"}))
+
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantBody string
+	}{
+		{
+			name:     "fields keep their order",
+			status:   http.StatusOK,
+			body:     `{"id":7,"nome":"Sítio","created_at":"2024-03-10T15:00:00Z","published":true,"updated_at":"2024-03-11T02:30:00.5Z","deleted_at":null}`,
+			wantBody: `{"id":7,"nome":"Sítio","created_at":"2024-03-10T12:00:00-03:00","published":true,"updated_at":"2024-03-10T23:30:00.5-03:00","deleted_at":null}`,
+		},
+		{
+			name:     "nested fields",
+			status:   http.StatusOK,
+			body:     `{"data":[{"z":1,"created_at":"2024-03-10T15:00:00Z"},{"created_at":"2024-03-10T16:00:00Z","a":[]}],"meta":{"total":2}}`,
+			wantBody: `{"data":[{"z":1,"created_at":"2024-03-10T12:00:00-03:00"},{"created_at":"2024-03-10T13:00:00-03:00","a":[]}],"meta":{"total":2}}`,
+		},
+		{
+			name:     "large numbers and other strings are kept",
+			status:   http.StatusOK,
+			body:     `{"count":12345678901234567890,"date":"2024-03-10T15:00:00Z","created_at":"not a time"}`,
+			wantBody: `{"count":12345678901234567890,"date":"2024-03-10T15:00:00Z","created_at":"not a time"}`,
+		},
+		{
+			name:     "error responses are unchanged",
+			status:   http.StatusNotFound,
+			body:     `{"created_at":"2024-03-10T15:00:00Z"}`,
+			wantBody: `{"created_at":"2024-03-10T15:00:00Z"}`,
+		},
+		{
+			name:     "malformed JSON is unchanged",
+			status:   http.StatusOK,
+			body:     `{"created_at":"2024-03-10T15:00:00Z"`,
+			wantBody: `{"created_at":"2024-03-10T15:00:00Z"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := events.APIGatewayProxyResponse{
+				StatusCode: tt.status,
+				Headers:    map[string]string{"Content-Type": "application/json", "ETag": computeETag([]byte(tt.body))},
+				Body:       tt.body,
+			}
+
+			got := ConvertTimestamps(response, saoPaulo)
+			if got.Body != tt.wantBody {
+				t.Errorf("body =\n%s\nwant\n%s", got.Body, tt.wantBody)
+			}
+			if etag := computeETag([]byte(got.Body)); got.Headers["ETag"] != etag {
+				t.Errorf("ETag = %q, want the hash of the sent body %q", got.Headers["ETag"], etag)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ResponseTimeHeader is the header carrying how long the request took, in milliseconds
+const ResponseTimeHeader = "X-Response-Time-Ms"
+
+// WithResponseTime adds the ResponseTimeHeader to a response, in milliseconds
+// with microsecond precision
+func WithResponseTime(response events.APIGatewayProxyResponse, elapsed time.Duration) events.APIGatewayProxyResponse {
+	headers := make(map[string]string, len(response.Headers)+1)
+	for key, value := range response.Headers {
+		headers[key] = value
+	}
+	ms := float64(elapsed.Microseconds()) / 1000
+	headers[ResponseTimeHeader] = strconv.FormatFloat(ms, 'f', 3, 64)
+
+	response.Headers = headers
+	return response
+}
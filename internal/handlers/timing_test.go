@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestWithResponseTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		response events.APIGatewayProxyResponse
+		elapsed  time.Duration
+		want     string
+	}{
+		{
+			name:     "response with headers",
+			response: events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Headers: map[string]string{"Content-Type": "application/json"}},
+			elapsed:  12345 * time.Microsecond,
+			want:     "12.345",
+		},
+		{
+			name:     "response without headers",
+			response: events.APIGatewayProxyResponse{StatusCode: http.StatusNotFound},
+			elapsed:  2 * time.Second,
+			want:     "2000.000",
+		},
+		{
+			name:     "sub-microsecond request",
+			response: events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent},
+			elapsed:  500 * time.Nanosecond,
+			want:     "0.000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := len(tt.response.Headers)
+
+			response := WithResponseTime(tt.response, tt.elapsed)
+			got, ok := response.Headers[ResponseTimeHeader]
+			if !ok {
+				t.Fatalf("%s header missing from %v", ResponseTimeHeader, response.Headers)
+			}
+			if _, err := strconv.ParseFloat(got, 64); err != nil {
+				t.Errorf("%s = %q is not numeric: %v", ResponseTimeHeader, got, err)
+			}
+			if got != tt.want {
+				t.Errorf("%s = %q, want %q", ResponseTimeHeader, got, tt.want)
+			}
+			if len(response.Headers) != original+1 {
+				t.Errorf("headers = %v, want the %d original headers plus the response time", response.Headers, original)
+			}
+			if len(tt.response.Headers) != original {
+				t.Error("the original response headers were changed")
+			}
+		})
+	}
+}
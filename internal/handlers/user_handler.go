@@ -3,9 +3,12 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -16,15 +19,19 @@ import (
 
 // UserHandler handles user-related requests
 type UserHandler struct {
-	userRepo repository.UserRepository
-	log      logger.Logger
+	userRepo   repository.UserRepository
+	lugarRepo  repository.LugarRepository
+	cancaoRepo repository.CancaoRepository
+	log        logger.Logger
 }
 
 // NewUserHandler creates a new UserHandler
-func NewUserHandler(userRepo repository.UserRepository, log logger.Logger) *UserHandler {
+func NewUserHandler(userRepo repository.UserRepository, lugarRepo repository.LugarRepository, cancaoRepo repository.CancaoRepository, log logger.Logger) *UserHandler {
 	return &UserHandler{
-		userRepo: userRepo,
-		log:      log,
+		userRepo:   userRepo,
+		lugarRepo:  lugarRepo,
+		cancaoRepo: cancaoRepo,
+		log:        log,
 	}
 }
 
@@ -37,7 +44,12 @@ func (h *UserHandler) GetUser(ctx context.Context, request events.APIGatewayProx
 			"action":   "GetUser",
 			"resource": "users",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid user ID")
+		return createErrorResponse(invalidIDError("Invalid user ID"))
+	}
+
+	// Return the user with their content counts when asked to
+	if request.QueryStringParameters["with_counts"] == "true" {
+		return h.getUserWithCounts(ctx, userID)
 	}
 
 	// Get user from repository
@@ -48,7 +60,7 @@ func (h *UserHandler) GetUser(ctx context.Context, request events.APIGatewayProx
 			"resource":    "users",
 			"resource_id": fmt.Sprintf("%d", userID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error getting user")
+		return createErrorResponse(internalError("Error getting user"))
 	}
 
 	// If user not found
@@ -58,7 +70,7 @@ func (h *UserHandler) GetUser(ctx context.Context, request events.APIGatewayProx
 			"resource":    "users",
 			"resource_id": fmt.Sprintf("%d", userID),
 		})
-		return createErrorResponse(http.StatusNotFound, "User not found")
+		return createErrorResponse(notFoundError("User not found"))
 	}
 
 	// Log success
@@ -69,19 +81,156 @@ func (h *UserHandler) GetUser(ctx context.Context, request events.APIGatewayProx
 	})
 
 	// Return user as JSON
-	return createJSONResponse(http.StatusOK, user)
+	return createPrivateJSONResponse(http.StatusOK, user)
+}
+
+// getUserWithCounts answers GET /users/{id}?with_counts=true with the user and
+// the number of places, songs and ratings they created
+func (h *UserHandler) getUserWithCounts(ctx context.Context, userID int) (events.APIGatewayProxyResponse, error) {
+	// Get user with counts from repository
+	user, err := h.userRepo.GetWithCounts(ctx, userID)
+	if err != nil {
+		h.log.Error(ctx, "Error getting user with counts", err, map[string]interface{}{
+			"action":      "GetUser",
+			"resource":    "users",
+			"resource_id": fmt.Sprintf("%d", userID),
+		})
+		return createErrorResponse(internalError("Error getting user"))
+	}
+
+	// If user not found
+	if user == nil {
+		h.log.Warn(ctx, "User not found", map[string]interface{}{
+			"action":      "GetUser",
+			"resource":    "users",
+			"resource_id": fmt.Sprintf("%d", userID),
+		})
+		return createErrorResponse(notFoundError("User not found"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "User with counts retrieved successfully", map[string]interface{}{
+		"action":      "GetUser",
+		"resource":    "users",
+		"resource_id": fmt.Sprintf("%d", userID),
+	})
+
+	// Return user with counts as JSON
+	return createPrivateJSONResponse(http.StatusOK, user)
+}
+
+// GetUserContent handles GET /users/{id}/content requests
+func (h *UserHandler) GetUserContent(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Extract user ID from path parameters
+	userID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid user ID", err, map[string]interface{}{
+			"action":   "GetUserContent",
+			"resource": "users",
+		})
+		return createErrorResponse(invalidIDError("Invalid user ID"))
+	}
+
+	// Restrict to the user themselves or admins
+	if response, ok := requireSelfOrAdmin(ctx, userID); !ok {
+		h.log.Warn(ctx, "Unauthorized user content request", map[string]interface{}{
+			"action":      "GetUserContent",
+			"resource":    "users",
+			"resource_id": fmt.Sprintf("%d", userID),
+		})
+		return response, nil
+	}
+
+	// Parse pagination; the page applies to both lists
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":      "GetUserContent",
+			"resource":    "users",
+			"resource_id": fmt.Sprintf("%d", userID),
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+	page := repository.Pagination{Limit: limit, Offset: offset}
+
+	// Get lugares created by the user
+	ctx = withViewer(ctx)
+	lugares, err := h.lugarRepo.ListByUser(ctx, userID, page)
+	if err != nil {
+		h.log.Error(ctx, "Error listing lugares for user", err, map[string]interface{}{
+			"action":      "GetUserContent",
+			"resource":    "users",
+			"resource_id": fmt.Sprintf("%d", userID),
+		})
+		return createErrorResponse(internalError("Error getting user content"))
+	}
+
+	// Get cancoes created by the user
+	cancoes, err := h.cancaoRepo.ListByUser(ctx, userID, page)
+	if err != nil {
+		h.log.Error(ctx, "Error listing cancoes for user", err, map[string]interface{}{
+			"action":      "GetUserContent",
+			"resource":    "users",
+			"resource_id": fmt.Sprintf("%d", userID),
+		})
+		return createErrorResponse(internalError("Error getting user content"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "User content retrieved successfully", map[string]interface{}{
+		"action":        "GetUserContent",
+		"resource":      "users",
+		"resource_id":   fmt.Sprintf("%d", userID),
+		"lugares_count": len(lugares),
+		"cancoes_count": len(cancoes),
+	})
+
+	// Send a user without content as empty collections, not null
+	if lugares == nil {
+		lugares = []*models.Lugar{}
+	}
+	if cancoes == nil {
+		cancoes = []*models.Cancao{}
+	}
+
+	// Return user content as JSON
+	return createPrivateJSONResponse(http.StatusOK, models.UserContent{
+		Lugares: lugares,
+		Cancoes: cancoes,
+	})
 }
 
 // ListUsers handles GET /users requests
 func (h *UserHandler) ListUsers(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Looking a user up by username is an admin lookup
+	if _, ok := request.QueryStringParameters["username"]; ok {
+		return h.getUserByUsername(ctx, request)
+	}
+
+	// Filtering by creation date is an admin report
+	if request.QueryStringParameters["created_after"] != "" || request.QueryStringParameters["created_before"] != "" {
+		return h.listUsersCreatedBetween(ctx, request)
+	}
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListUsers",
+			"resource": "users",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+	page := repository.Pagination{Limit: limit, Offset: offset}
+
 	// Get users from repository
-	users, err := h.userRepo.List(ctx)
+	users, err := h.userRepo.List(ctx, page)
 	if err != nil {
 		h.log.Error(ctx, "Error listing users", err, map[string]interface{}{
 			"action":   "ListUsers",
 			"resource": "users",
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error listing users")
+		return createErrorResponse(internalError("Error listing users"))
 	}
 
 	// Log success
@@ -92,20 +241,196 @@ func (h *UserHandler) ListUsers(ctx context.Context, request events.APIGatewayPr
 	})
 
 	// Return users as JSON
-	return createJSONResponse(http.StatusOK, users)
+	return markPrivate(createPaginatedResponse(ctx, h.log, request, users, len(users), limit, offset, "users", func() (int, error) {
+		return h.userRepo.Count(ctx)
+	}))
+}
+
+// GetPermissions handles GET /auth/permissions requests, returning the
+// capabilities of the authenticated user's role
+func (h *UserHandler) GetPermissions(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Require an authenticated user
+	user := currentUser(ctx)
+	if user == nil {
+		h.log.Warn(ctx, "Unauthenticated permissions request", map[string]interface{}{
+			"action":   "GetPermissions",
+			"resource": "users",
+		})
+		return createErrorResponse(unauthorizedError("Authentication required"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Permissions retrieved successfully", map[string]interface{}{
+		"action":      "GetPermissions",
+		"resource":    "users",
+		"resource_id": fmt.Sprintf("%d", user.ID),
+	})
+
+	// Return permissions as JSON
+	return createPrivateJSONResponse(http.StatusOK, models.UserPermissions{
+		UserID:      user.ID,
+		Role:        user.Role,
+		Permissions: user.Permissions(),
+	})
+}
+
+// getUserByUsername handles GET /users?username= requests
+func (h *UserHandler) getUserByUsername(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized user lookup request", map[string]interface{}{
+			"action":   "GetUserByUsername",
+			"resource": "users",
+		})
+		return response, nil
+	}
+
+	// Validate username
+	username := strings.TrimSpace(request.QueryStringParameters["username"])
+	if username == "" {
+		h.log.Warn(ctx, "Invalid username: empty", map[string]interface{}{
+			"action":   "GetUserByUsername",
+			"resource": "users",
+		})
+		return createErrorResponse(validationError("username must not be empty"))
+	}
+
+	// Get user from repository
+	user, err := h.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		h.log.Error(ctx, "Error getting user by username", err, map[string]interface{}{
+			"action":   "GetUserByUsername",
+			"resource": "users",
+		})
+		return createErrorResponse(internalError("Error getting user"))
+	}
+
+	// If user not found
+	if user == nil {
+		h.log.Warn(ctx, "User not found", map[string]interface{}{
+			"action":   "GetUserByUsername",
+			"resource": "users",
+		})
+		return createErrorResponse(notFoundError("User not found"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "User retrieved by username successfully", map[string]interface{}{
+		"action":      "GetUserByUsername",
+		"resource":    "users",
+		"resource_id": fmt.Sprintf("%d", user.ID),
+	})
+
+	// Return user as JSON; the password is never serialized
+	return createJSONResponse(http.StatusOK, user)
+}
+
+// listUsersCreatedBetween handles GET /users?created_after=&created_before= requests
+func (h *UserHandler) listUsersCreatedBetween(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized user report request", map[string]interface{}{
+			"action":   "ListUsersCreatedBetween",
+			"resource": "users",
+		})
+		return response, nil
+	}
+
+	// Parse date range, defaulting to an open interval; a plain created_before
+	// date includes that day
+	from := time.Time{}
+	to := time.Now().UTC()
+	if value := request.QueryStringParameters["created_after"]; value != "" {
+		parsed, err := parseTimeParam(value)
+		if err != nil {
+			h.log.Error(ctx, "Invalid created_after", err, map[string]interface{}{
+				"action":   "ListUsersCreatedBetween",
+				"resource": "users",
+			})
+			return createErrorResponse(validationError("Invalid created_after"))
+		}
+		from = parsed
+	}
+	if value := request.QueryStringParameters["created_before"]; value != "" {
+		parsed, err := parseEndTimeParam(value)
+		if err != nil {
+			h.log.Error(ctx, "Invalid created_before", err, map[string]interface{}{
+				"action":   "ListUsersCreatedBetween",
+				"resource": "users",
+			})
+			return createErrorResponse(validationError("Invalid created_before"))
+		}
+		to = parsed
+	}
+
+	// Validate date range
+	if !from.Before(to) {
+		h.log.Warn(ctx, "Invalid date range", map[string]interface{}{
+			"action":   "ListUsersCreatedBetween",
+			"resource": "users",
+		})
+		return createErrorResponse(validationError("created_after must be before created_before"))
+	}
+
+	// Parse pagination
+	limit, offset, err := parsePagination(request)
+	if err != nil {
+		h.log.Error(ctx, "Invalid pagination", err, map[string]interface{}{
+			"action":   "ListUsersCreatedBetween",
+			"resource": "users",
+		})
+		return createErrorResponse(validationError(err.Error()))
+	}
+
+	// Get users from repository
+	users, err := h.userRepo.ListCreatedBetween(ctx, from, to, limit, offset)
+	if err != nil {
+		h.log.Error(ctx, "Error listing users by creation date", err, map[string]interface{}{
+			"action":   "ListUsersCreatedBetween",
+			"resource": "users",
+		})
+		return createErrorResponse(internalError("Error listing users"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "Users listed by creation date successfully", map[string]interface{}{
+		"action":   "ListUsersCreatedBetween",
+		"resource": "users",
+		"count":    len(users),
+	})
+
+	// Return users as JSON
+	return markPrivate(createPaginatedResponse(ctx, h.log, request, users, len(users), limit, offset, "users", func() (int, error) {
+		return h.userRepo.CountCreatedBetween(ctx, from, to)
+	}))
+}
+
+// userRequest is the body of POST /users and PUT /users/{id}. models.User
+// never reads nor writes the password in JSON, so it is decoded here.
+type userRequest struct {
+	models.User
+	Password string `json:"password"`
+}
+
+// toUser returns the user of the request with its plain text password
+func (r userRequest) toUser() models.User {
+	user := r.User
+	user.Password = r.Password
+	return user
 }
 
 // CreateUser handles POST /users requests
 func (h *UserHandler) CreateUser(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Parse request body
-	var user models.User
-	if err := json.Unmarshal([]byte(request.Body), &user); err != nil {
+	var body userRequest
+	if err := decodeJSONBody(request, &body); err != nil {
 		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
 			"action":   "CreateUser",
 			"resource": "users",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
+		return createErrorResponse(err)
 	}
+	user := body.toUser()
 
 	// Validate user
 	if user.Username == "" || user.Password == "" || !models.IsValidRole(user.Role) {
@@ -113,11 +438,32 @@ func (h *UserHandler) CreateUser(ctx context.Context, request events.APIGatewayP
 			"action":   "CreateUser",
 			"resource": "users",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid user data")
+		return createErrorResponse(unprocessableError("Invalid user data"))
+	}
+
+	// Validate username
+	if err := models.ValidateUsername(user.Username); err != nil {
+		h.log.Warn(ctx, "Invalid username", map[string]interface{}{
+			"action":   "CreateUser",
+			"resource": "users",
+			"reason":   err.Error(),
+		})
+		return createErrorResponse(unprocessableError(err.Error()))
 	}
 
+	// Store the password as a bcrypt hash
+	hash, err := models.HashPassword(user.Password)
+	if err != nil {
+		h.log.Error(ctx, "Error hashing password", err, map[string]interface{}{
+			"action":   "CreateUser",
+			"resource": "users",
+		})
+		return createErrorResponse(internalError("Error creating user"))
+	}
+	user.Password = hash
+
 	// Set timestamps
-	now := time.Now()
+	now := time.Now().UTC()
 	user.CreatedAt = now
 	user.UpdatedAt = now
 
@@ -128,7 +474,7 @@ func (h *UserHandler) CreateUser(ctx context.Context, request events.APIGatewayP
 			"action":   "CreateUser",
 			"resource": "users",
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error creating user")
+		return createErrorResponse(internalError("Error creating user"))
 	}
 
 	// Set user ID
@@ -154,7 +500,7 @@ func (h *UserHandler) UpdateUser(ctx context.Context, request events.APIGatewayP
 			"action":   "UpdateUser",
 			"resource": "users",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid user ID")
+		return createErrorResponse(invalidIDError("Invalid user ID"))
 	}
 
 	// Get existing user
@@ -165,7 +511,7 @@ func (h *UserHandler) UpdateUser(ctx context.Context, request events.APIGatewayP
 			"resource":    "users",
 			"resource_id": fmt.Sprintf("%d", userID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error getting user")
+		return createErrorResponse(internalError("Error getting user"))
 	}
 
 	// If user not found
@@ -175,19 +521,20 @@ func (h *UserHandler) UpdateUser(ctx context.Context, request events.APIGatewayP
 			"resource":    "users",
 			"resource_id": fmt.Sprintf("%d", userID),
 		})
-		return createErrorResponse(http.StatusNotFound, "User not found")
+		return createErrorResponse(notFoundError("User not found"))
 	}
 
 	// Parse request body
-	var updatedUser models.User
-	if err := json.Unmarshal([]byte(request.Body), &updatedUser); err != nil {
+	var body userRequest
+	if err := decodeJSONBody(request, &body); err != nil {
 		h.log.Error(ctx, "Invalid request body", err, map[string]interface{}{
 			"action":      "UpdateUser",
 			"resource":    "users",
 			"resource_id": fmt.Sprintf("%d", userID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid request body")
+		return createErrorResponse(err)
 	}
+	updatedUser := body.toUser()
 
 	// Validate user
 	if updatedUser.Username == "" || updatedUser.Password == "" || !models.IsValidRole(updatedUser.Role) {
@@ -196,14 +543,38 @@ func (h *UserHandler) UpdateUser(ctx context.Context, request events.APIGatewayP
 			"resource":    "users",
 			"resource_id": fmt.Sprintf("%d", userID),
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid user data")
+		return createErrorResponse(unprocessableError("Invalid user data"))
+	}
+
+	// Validate username when it changes, so users created before the rule can still be updated
+	if updatedUser.Username != existingUser.Username {
+		if err := models.ValidateUsername(updatedUser.Username); err != nil {
+			h.log.Warn(ctx, "Invalid username", map[string]interface{}{
+				"action":      "UpdateUser",
+				"resource":    "users",
+				"resource_id": fmt.Sprintf("%d", userID),
+				"reason":      err.Error(),
+			})
+			return createErrorResponse(unprocessableError(err.Error()))
+		}
+	}
+
+	// Store the password as a bcrypt hash
+	hash, err := models.HashPassword(updatedUser.Password)
+	if err != nil {
+		h.log.Error(ctx, "Error hashing password", err, map[string]interface{}{
+			"action":      "UpdateUser",
+			"resource":    "users",
+			"resource_id": fmt.Sprintf("%d", userID),
+		})
+		return createErrorResponse(internalError("Error updating user"))
 	}
 
 	// Update user fields
 	existingUser.Username = updatedUser.Username
-	existingUser.Password = updatedUser.Password
+	existingUser.Password = hash
 	existingUser.Role = updatedUser.Role
-	existingUser.UpdatedAt = time.Now()
+	existingUser.UpdatedAt = time.Now().UTC()
 
 	// Update user in repository
 	if err := h.userRepo.Update(ctx, existingUser); err != nil {
@@ -212,7 +583,7 @@ func (h *UserHandler) UpdateUser(ctx context.Context, request events.APIGatewayP
 			"resource":    "users",
 			"resource_id": fmt.Sprintf("%d", userID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error updating user")
+		return createErrorResponse(internalError("Error updating user"))
 	}
 
 	// Log success
@@ -235,7 +606,7 @@ func (h *UserHandler) DeleteUser(ctx context.Context, request events.APIGatewayP
 			"action":   "DeleteUser",
 			"resource": "users",
 		})
-		return createErrorResponse(http.StatusBadRequest, "Invalid user ID")
+		return createErrorResponse(invalidIDError("Invalid user ID"))
 	}
 
 	// Delete user from repository
@@ -245,7 +616,7 @@ func (h *UserHandler) DeleteUser(ctx context.Context, request events.APIGatewayP
 			"resource":    "users",
 			"resource_id": fmt.Sprintf("%d", userID),
 		})
-		return createErrorResponse(http.StatusInternalServerError, "Error deleting user")
+		return createErrorResponse(internalError("Error deleting user"))
 	}
 
 	// Log success
@@ -256,35 +627,124 @@ func (h *UserHandler) DeleteUser(ctx context.Context, request events.APIGatewayP
 	})
 
 	// Return success response
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusNoContent,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-	}, nil
+	return createNoContentResponse()
+}
+
+// AnonymizeUser handles POST /users/{id}/anonymize requests, scrubbing the
+// personal data of a user instead of deleting them and their content
+func (h *UserHandler) AnonymizeUser(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Restrict to admins
+	if response, ok := requireAdmin(ctx); !ok {
+		h.log.Warn(ctx, "Unauthorized user anonymization request", map[string]interface{}{
+			"action":   "AnonymizeUser",
+			"resource": "users",
+		})
+		return response, nil
+	}
+
+	// Extract user ID from path parameters
+	userID, err := strconv.Atoi(request.PathParameters["id"])
+	if err != nil {
+		h.log.Error(ctx, "Invalid user ID", err, map[string]interface{}{
+			"action":   "AnonymizeUser",
+			"resource": "users",
+		})
+		return createErrorResponse(invalidIDError("Invalid user ID"))
+	}
+
+	// Anonymize user in repository
+	user, err := h.userRepo.Anonymize(ctx, userID)
+	if err != nil {
+		h.log.Error(ctx, "Error anonymizing user", err, map[string]interface{}{
+			"action":      "AnonymizeUser",
+			"resource":    "users",
+			"resource_id": fmt.Sprintf("%d", userID),
+		})
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return createErrorResponse(conflictError("The anonymized username is already in use"))
+		}
+		return createErrorResponse(internalError("Error anonymizing user"))
+	}
+
+	// If user not found
+	if user == nil {
+		h.log.Warn(ctx, "User not found", map[string]interface{}{
+			"action":      "AnonymizeUser",
+			"resource":    "users",
+			"resource_id": fmt.Sprintf("%d", userID),
+		})
+		return createErrorResponse(notFoundError("User not found"))
+	}
+
+	// Log success
+	h.log.Info(ctx, "User anonymized successfully", map[string]interface{}{
+		"action":      "AnonymizeUser",
+		"resource":    "users",
+		"resource_id": fmt.Sprintf("%d", userID),
+	})
+
+	// Return anonymized user as JSON
+	return createJSONResponse(http.StatusOK, user)
 }
 
 // Helper functions
 
-// createJSONResponse creates a JSON response
+// createJSONResponse creates a JSON response. A nil slice is sent as an empty
+// array, so an empty list is [] rather than null.
 func createJSONResponse(statusCode int, body interface{}) (events.APIGatewayProxyResponse, error) {
+	body = emptyIfNilSlice(body)
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
-		return createErrorResponse(http.StatusInternalServerError, "Error creating response")
+		return createErrorResponse(internalError("Error creating response"))
+	}
+
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		"Cache-Control": "no-store",
+	}
+
+	// Tag successful responses so clients can detect unchanged content
+	if statusCode == http.StatusOK {
+		headers["ETag"] = computeETag(jsonBody)
 	}
 
 	return events.APIGatewayProxyResponse{
 		StatusCode: statusCode,
+		Headers:    headers,
+		Body:       string(jsonBody),
+	}, nil
+}
+
+// emptyIfNilSlice returns an empty slice of the same type for a nil slice, and v otherwise
+func emptyIfNilSlice(v interface{}) interface{} {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Slice && value.IsNil() {
+		return reflect.MakeSlice(value.Type(), 0, 0).Interface()
+	}
+	return v
+}
+
+// createNoContentResponse creates an empty 204 response
+func createNoContentResponse() (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNoContent,
 		Headers: map[string]string{
-			"Content-Type": "application/json",
+			"Content-Type":  "application/json",
+			"Cache-Control": "no-store",
 		},
-		Body: string(jsonBody),
 	}, nil
 }
 
+// createAssociationResponse creates the response of a request that associates
+// a tag or ramo: 201 when the association was added, 200 when it already existed
+func createAssociationResponse(added bool) (events.APIGatewayProxyResponse, error) {
+	if added {
+		return createJSONResponse(http.StatusCreated, map[string]string{"status": "added"})
+	}
+	return createJSONResponse(http.StatusOK, map[string]string{"status": "already_associated"})
+}
+
 // createErrorResponse creates an error response
-func createErrorResponse(statusCode int, message string) (events.APIGatewayProxyResponse, error) {
-	return createJSONResponse(statusCode, map[string]string{
-		"error": message,
-	})
+func createErrorResponse(apiErr *APIError) (events.APIGatewayProxyResponse, error) {
+	return createJSONResponse(apiErr.Status, apiErr)
 }
\ No newline at end of file
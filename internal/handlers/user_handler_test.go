@@ -0,0 +1,497 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/site-geav-api/internal/models"
+	"github.com/site-geav-api/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestListUsersCreatedBetween(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	users := []*models.User{
+		{ID: 1, Username: "ana", Password: "secret-hash", CreatedAt: day(1)},
+		{ID: 2, Username: "bia", Password: "secret-hash", CreatedAt: day(10).Add(15 * time.Hour)},
+		{ID: 3, Username: "caio", Password: "secret-hash", CreatedAt: day(20)},
+		{ID: 4, Username: "duda", Password: "secret-hash", CreatedAt: day(31).Add(23 * time.Hour)},
+	}
+	repo := &fakeUserRepo{
+		listCreatedBetween: func(from, to time.Time, limit, offset int) ([]*models.User, error) {
+			var matched []*models.User
+			for _, user := range users {
+				if !user.CreatedAt.Before(from) && user.CreatedAt.Before(to) {
+					matched = append(matched, user)
+				}
+			}
+			if offset >= len(matched) {
+				return nil, nil
+			}
+			matched = matched[offset:]
+			if len(matched) > limit {
+				matched = matched[:limit]
+			}
+			return matched, nil
+		},
+	}
+	h := NewUserHandler(repo, nil, nil, &fakeLogger{})
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		params     map[string]string
+		wantStatus int
+		wantIDs    []int
+	}{
+		{name: "date range includes the last day", ctx: adminContext(), params: map[string]string{"created_after": "2026-03-02", "created_before": "2026-03-31"}, wantStatus: http.StatusOK, wantIDs: []int{2, 3, 4}},
+		{name: "RFC3339 upper bound is exclusive", ctx: adminContext(), params: map[string]string{"created_after": "2026-03-01", "created_before": "2026-03-20T00:00:00Z"}, wantStatus: http.StatusOK, wantIDs: []int{1, 2}},
+		{name: "pagination", ctx: adminContext(), params: map[string]string{"created_after": "2026-03-01", "created_before": "2026-03-31", "limit": "2", "offset": "1"}, wantStatus: http.StatusOK, wantIDs: []int{2, 3}},
+		{name: "only a lower bound", ctx: adminContext(), params: map[string]string{"created_after": "2026-03-15"}, wantStatus: http.StatusOK, wantIDs: []int{3, 4}},
+		{name: "inverted range", ctx: adminContext(), params: map[string]string{"created_after": "2026-03-10", "created_before": "2026-03-01"}, wantStatus: http.StatusBadRequest},
+		{name: "invalid date", ctx: adminContext(), params: map[string]string{"created_after": "March"}, wantStatus: http.StatusBadRequest},
+		{name: "anonymous", ctx: context.Background(), params: map[string]string{"created_after": "2026-03-01"}, wantStatus: http.StatusUnauthorized},
+		{name: "not an admin", ctx: userContext(2, "read"), params: map[string]string{"created_after": "2026-03-01"}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := h.ListUsers(tt.ctx, queryRequest(tt.params))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got []map[string]interface{}
+			decodeBody(t, response, &got)
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("got %d users, want %v", len(got), tt.wantIDs)
+			}
+			for i, user := range got {
+				if int(user["id"].(float64)) != tt.wantIDs[i] {
+					t.Errorf("user %d: id = %v, want %d", i, user["id"], tt.wantIDs[i])
+				}
+				if _, ok := user["password"]; ok {
+					t.Errorf("user %v: password was serialized", user["id"])
+				}
+			}
+		})
+	}
+}
+
+func TestGetUserContent(t *testing.T) {
+	lugares := []*models.Lugar{{ID: 1, UserID: 2}, {ID: 2, UserID: 3}, {ID: 3, UserID: 2}}
+	cancoes := []*models.Cancao{{ID: 1, UserID: 3}, {ID: 2, UserID: 2}}
+	lugarRepo := &fakeLugarRepo{
+		listByUser: func(userID int, page repository.Pagination) ([]*models.Lugar, error) {
+			var owned []*models.Lugar
+			for _, lugar := range lugares {
+				if lugar.UserID == userID {
+					owned = append(owned, lugar)
+				}
+			}
+			return owned, nil
+		},
+	}
+	cancaoRepo := &fakeCancaoRepo{
+		listByUser: func(userID int, page repository.Pagination) ([]*models.Cancao, error) {
+			var owned []*models.Cancao
+			for _, cancao := range cancoes {
+				if cancao.UserID == userID {
+					owned = append(owned, cancao)
+				}
+			}
+			return owned, nil
+		},
+	}
+	h := NewUserHandler(&fakeUserRepo{}, lugarRepo, cancaoRepo, &fakeLogger{})
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		id          string
+		wantStatus  int
+		wantLugares []int
+		wantCancoes []int
+	}{
+		{name: "the user themselves", ctx: userContext(2, "read"), id: "2", wantStatus: http.StatusOK, wantLugares: []int{1, 3}, wantCancoes: []int{2}},
+		{name: "admin", ctx: adminContext(), id: "3", wantStatus: http.StatusOK, wantLugares: []int{2}, wantCancoes: []int{1}},
+		{name: "user without content", ctx: userContext(4, "read"), id: "4", wantStatus: http.StatusOK, wantLugares: []int{}, wantCancoes: []int{}},
+		{name: "another user", ctx: userContext(2, "read"), id: "3", wantStatus: http.StatusForbidden},
+		{name: "anonymous", ctx: context.Background(), id: "2", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := h.GetUserContent(tt.ctx, pathRequest(map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var content models.UserContent
+			decodeBody(t, response, &content)
+			if content.Lugares == nil || content.Cancoes == nil {
+				t.Fatalf("collections must be arrays, got %s", response.Body)
+			}
+			gotLugares := []int{}
+			for _, lugar := range content.Lugares {
+				gotLugares = append(gotLugares, lugar.ID)
+			}
+			gotCancoes := []int{}
+			for _, cancao := range content.Cancoes {
+				gotCancoes = append(gotCancoes, cancao.ID)
+			}
+			if !reflect.DeepEqual(gotLugares, tt.wantLugares) || !reflect.DeepEqual(gotCancoes, tt.wantCancoes) {
+				t.Errorf("got lugares %v and cancoes %v, want %v and %v", gotLugares, gotCancoes, tt.wantLugares, tt.wantCancoes)
+			}
+		})
+	}
+}
+
+func TestUsernameValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		username    string
+		existing    string
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "valid username", username: "ana.souza", existing: "ana", wantStatus: http.StatusOK},
+		{name: "space", username: "ana souza", existing: "ana", wantStatus: http.StatusUnprocessableEntity, wantMessage: "invalid character ' ' at position 4"},
+		{name: "too short", username: "an", existing: "ana", wantStatus: http.StatusUnprocessableEntity, wantMessage: "between 3 and 50 characters"},
+		{name: "trailing punctuation", username: "ana.", existing: "ana", wantStatus: http.StatusUnprocessableEntity, wantMessage: "start and end with a letter or digit"},
+		// Users created before the rule keep their username when updated
+		{name: "unchanged legacy username", username: "ana souza", existing: "ana souza", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		for _, action := range []string{"create", "update"} {
+			t.Run(tt.name+"/"+action, func(t *testing.T) {
+				saved := false
+				var savedPassword string
+				repo := &fakeUserRepo{
+					getByID: func(id int) (*models.User, error) {
+						return &models.User{ID: id, Username: tt.existing, Role: "read"}, nil
+					},
+					create: func(user *models.User) (int, error) {
+						saved = true
+						savedPassword = user.Password
+						return 5, nil
+					},
+					update: func(user *models.User) error {
+						saved = true
+						savedPassword = user.Password
+						return nil
+					},
+				}
+				h := NewUserHandler(repo, nil, nil, &fakeLogger{})
+
+				body := fmt.Sprintf(`{"username": %q, "password": "s3cret", "role": "read"}`, tt.username)
+				wantStatus := tt.wantStatus
+				var response events.APIGatewayProxyResponse
+				var err error
+				if action == "create" {
+					if wantStatus == http.StatusOK {
+						wantStatus = http.StatusCreated
+					}
+					// A new user has no legacy username to keep
+					if tt.existing == tt.username && models.ValidateUsername(tt.username) != nil {
+						wantStatus = http.StatusUnprocessableEntity
+					}
+					response, err = h.CreateUser(adminContext(), bodyRequest(body, nil))
+				} else {
+					response, err = h.UpdateUser(adminContext(), bodyRequest(body, map[string]string{"id": "5"}))
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if response.StatusCode != wantStatus {
+					t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, wantStatus, response.Body)
+				}
+				if saved != (wantStatus < 300) {
+					t.Errorf("saved = %v, want %v", saved, wantStatus < 300)
+				}
+				// The password of the body is read and stored as a bcrypt hash
+				if saved && bcrypt.CompareHashAndPassword([]byte(savedPassword), []byte("s3cret")) != nil {
+					t.Errorf("saved password %q is not the hash of the request password", savedPassword)
+				}
+				if tt.wantMessage != "" && wantStatus == tt.wantStatus {
+					var apiErr APIError
+					decodeBody(t, response, &apiErr)
+					if !strings.Contains(apiErr.Message, tt.wantMessage) {
+						t.Errorf("message = %q, want it to contain %q", apiErr.Message, tt.wantMessage)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestGetUserWithCounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		query      map[string]string
+		wantStatus int
+		wantCounts bool
+	}{
+		{name: "with counts", id: "2", query: map[string]string{"with_counts": "true"}, wantStatus: http.StatusOK, wantCounts: true},
+		{name: "without counts", id: "2", wantStatus: http.StatusOK},
+		{name: "missing user", id: "9", query: map[string]string{"with_counts": "true"}, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := models.User{ID: 2, Username: "bia", Password: "secret-hash", Role: "read"}
+			repo := &fakeUserRepo{
+				getByID: func(id int) (*models.User, error) {
+					if id != 2 {
+						return nil, nil
+					}
+					return &user, nil
+				},
+				getWithCounts: func(id int) (*models.UserWithCounts, error) {
+					if id != 2 {
+						return nil, nil
+					}
+					return &models.UserWithCounts{User: user, LugarCount: 3, CancaoCount: 1, RatingCount: 4}, nil
+				},
+			}
+			h := NewUserHandler(repo, nil, nil, &fakeLogger{})
+
+			request := pathRequest(map[string]string{"id": tt.id})
+			request.QueryStringParameters = tt.query
+			response, err := h.GetUser(adminContext(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if strings.Contains(response.Body, "secret-hash") || strings.Contains(response.Body, "password") {
+				t.Errorf("body exposes the password: %s", response.Body)
+			}
+			var body map[string]interface{}
+			decodeBody(t, response, &body)
+			wantFields := map[string]float64{"lugar_count": 3, "cancao_count": 1, "rating_count": 4}
+			for field, want := range wantFields {
+				got, ok := body[field]
+				if ok != tt.wantCounts {
+					t.Errorf("%s present = %v, want %v", field, ok, tt.wantCounts)
+				} else if ok && got != want {
+					t.Errorf("%s = %v, want %v", field, got, want)
+				}
+			}
+			if body["username"] != "bia" {
+				t.Errorf("username = %v, want bia", body["username"])
+			}
+		})
+	}
+}
+
+func TestAnonymizeUser(t *testing.T) {
+	anonymized := &models.User{ID: 3, Username: models.AnonymizedUsername(3), Password: "!", Role: "read"}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		id         string
+		user       *models.User
+		repoErr    error
+		wantStatus int
+		wantCalled bool
+	}{
+		{name: "read user", ctx: userContext(3, "read"), id: "3", wantStatus: http.StatusForbidden},
+		{name: "invalid id", ctx: adminContext(), id: "abc", wantStatus: http.StatusBadRequest},
+		{name: "missing user", ctx: adminContext(), id: "3", wantStatus: http.StatusNotFound, wantCalled: true},
+		{name: "tombstone taken", ctx: adminContext(), id: "3", repoErr: fmt.Errorf("username: %w", repository.ErrAlreadyExists), wantStatus: http.StatusConflict, wantCalled: true},
+		{name: "repository failure", ctx: adminContext(), id: "3", repoErr: errors.New("boom"), wantStatus: http.StatusInternalServerError, wantCalled: true},
+		{name: "anonymized", ctx: adminContext(), id: "3", user: anonymized, wantStatus: http.StatusOK, wantCalled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			repo := &fakeUserRepo{
+				anonymize: func(id int) (*models.User, error) {
+					called = true
+					return tt.user, tt.repoErr
+				},
+			}
+			h := NewUserHandler(repo, nil, nil, &fakeLogger{})
+
+			response, err := h.AnonymizeUser(tt.ctx, pathRequest(map[string]string{"id": tt.id}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("repository called = %v, want %v", called, tt.wantCalled)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var body map[string]interface{}
+			decodeBody(t, response, &body)
+			if body["username"] != "deleted_user_3" {
+				t.Errorf("username = %v, want deleted_user_3", body["username"])
+			}
+			if _, ok := body["password"]; ok {
+				t.Errorf("body %s exposes the password", response.Body)
+			}
+		})
+	}
+}
+
+func TestGetUserByUsername(t *testing.T) {
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		username     string
+		wantStatus   int
+		wantUsername string
+	}{
+		{name: "found", ctx: adminContext(), username: "maria", wantStatus: http.StatusOK, wantUsername: "maria"},
+		{name: "surrounding spaces are trimmed", ctx: adminContext(), username: "  maria ", wantStatus: http.StatusOK, wantUsername: "maria"},
+		{name: "not found", ctx: adminContext(), username: "ninguem", wantStatus: http.StatusNotFound},
+		{name: "empty username", ctx: adminContext(), username: " ", wantStatus: http.StatusBadRequest},
+		{name: "not an admin", ctx: userContext(2, "read"), username: "maria", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var looked string
+			repo := &fakeUserRepo{
+				getByUsername: func(username string) (*models.User, error) {
+					looked = username
+					if username != "maria" {
+						return nil, nil
+					}
+					return &models.User{ID: 5, Username: "maria", Password: "hash", Role: "read"}, nil
+				},
+			}
+			h := NewUserHandler(repo, nil, nil, &fakeLogger{})
+
+			response, err := h.ListUsers(tt.ctx, queryRequest(map[string]string{"username": tt.username}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if (tt.wantStatus == http.StatusBadRequest || tt.wantStatus == http.StatusForbidden) && looked != "" {
+				t.Errorf("the repository was called for a rejected request")
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var body map[string]interface{}
+			decodeBody(t, response, &body)
+			if body["username"] != tt.wantUsername {
+				t.Errorf("username = %v, want %s", body["username"], tt.wantUsername)
+			}
+			if _, ok := body["password"]; ok {
+				t.Errorf("the password is in the response: %s", response.Body)
+			}
+		})
+	}
+}
+
+func TestGetPermissions(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantStatus int
+		want       models.UserPermissions
+	}{
+		{"read user", userContext(2, "read"), http.StatusOK, models.UserPermissions{UserID: 2, Role: "read", Permissions: []string{"read"}}},
+		{"write user", adminContext(), http.StatusOK, models.UserPermissions{UserID: 1, Role: "write", Permissions: []string{"read", "write"}}},
+		{"anonymous", context.Background(), http.StatusUnauthorized, models.UserPermissions{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewUserHandler(&fakeUserRepo{}, nil, nil, &fakeLogger{})
+
+			response, err := h.GetPermissions(tt.ctx, queryRequest(nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if cacheControl := response.Headers["Cache-Control"]; !strings.Contains(cacheControl, "private") {
+				t.Errorf("Cache-Control = %q, want a private response", cacheControl)
+			}
+			var got models.UserPermissions
+			decodeBody(t, response, &got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("permissions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListUsersCreatedBetweenBoundsAreUTC(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+	}{
+		{"default upper bound", map[string]string{"created_after": "2026-03-01"}},
+		{"plain dates", map[string]string{"created_after": "2026-03-01", "created_before": "2026-03-31"}},
+		{"RFC3339 times", map[string]string{"created_after": "2026-03-01T00:00:00Z", "created_before": "2026-03-31T12:00:00Z"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotFrom, gotTo time.Time
+			repo := &fakeUserRepo{
+				listCreatedBetween: func(from, to time.Time, limit, offset int) ([]*models.User, error) {
+					gotFrom, gotTo = from, to
+					return []*models.User{}, nil
+				},
+			}
+			h := NewUserHandler(repo, nil, nil, &fakeLogger{})
+
+			response, err := h.ListUsers(adminContext(), queryRequest(tt.params))
+			if err != nil || response.StatusCode != http.StatusOK {
+				t.Fatalf("status %d, error %v: %s", response.StatusCode, err, response.Body)
+			}
+			if gotFrom.Location() != time.UTC {
+				t.Errorf("from = %v, want a UTC time", gotFrom)
+			}
+			if gotTo.Location() != time.UTC {
+				t.Errorf("to = %v, want a UTC time", gotTo)
+			}
+		})
+	}
+}
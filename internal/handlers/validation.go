@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// validateOptionalURL checks that a field is either empty or an absolute
+// http or https URL with a host
+func validateOptionalURL(field, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%s must be an absolute http or https URL", field)
+	}
+
+	return nil
+}
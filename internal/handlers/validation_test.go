@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+func TestValidateOptionalURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "empty", value: ""},
+		{name: "https", value: "https://example.com/sitio"},
+		{name: "http with query", value: "http://example.com/?q=sitio"},
+		{name: "no scheme", value: "example.com", wantErr: true},
+		{name: "relative path", value: "/sitio", wantErr: true},
+		{name: "other scheme", value: "ftp://example.com", wantErr: true},
+		{name: "no host", value: "https://", wantErr: true},
+		{name: "malformed", value: "http://exa mple.com/%zz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOptionalURL("link_site", tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && err.Error() != "link_site must be an absolute http or https URL" {
+				t.Errorf("err = %q, want it to name the field", err)
+			}
+		})
+	}
+}
+
+func TestCreateLugarLinkValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "valid links", body: `{"nome_local": "Sítio", "link_site": "https://sitio.example.com", "link_google_maps": "https://maps.google.com/?q=sitio"}`, wantStatus: http.StatusCreated},
+		{name: "empty links", body: `{"nome_local": "Sítio", "link_site": "", "link_google_maps": ""}`, wantStatus: http.StatusCreated},
+		{name: "malformed link_site", body: `{"nome_local": "Sítio", "link_site": "sitio.example.com"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "malformed link_google_maps", body: `{"nome_local": "Sítio", "link_google_maps": "javascript:alert(1)"}`, wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			repo := &fakeLugarRepo{
+				create: func(lugar *models.Lugar) (int, error) {
+					created = true
+					return 7, nil
+				},
+				missingTagIDs:  func(tagIDs []int) ([]int, error) { return nil, nil },
+				missingRamoIDs: func(ramoIDs []int) ([]int, error) { return nil, nil },
+			}
+			h := NewLugarHandler(repo, nil, nil, nil, &fakeLogger{})
+
+			response, err := h.CreateLugar(adminContext(), bodyRequest(tt.body, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", response.StatusCode, tt.wantStatus, response.Body)
+			}
+			if created != (tt.wantStatus == http.StatusCreated) {
+				t.Errorf("created = %v, want %v", created, !created)
+			}
+		})
+	}
+}
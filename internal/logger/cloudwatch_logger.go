@@ -11,9 +11,14 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
 )
 
+// metricsClient is the part of the CloudWatch client used by CloudWatchLogger
+type metricsClient interface {
+	PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error)
+}
+
 // CloudWatchLogger implements the Logger interface for AWS CloudWatch
 type CloudWatchLogger struct {
-	client      *cloudwatch.Client
+	client      metricsClient
 	serviceName string
 	namespace   string
 }
@@ -27,39 +32,6 @@ func NewCloudWatchLogger(client *cloudwatch.Client, serviceName, namespace strin
 	}
 }
 
-// createLogEntry creates a log entry with common fields
-func (l *CloudWatchLogger) createLogEntry(ctx context.Context, level LogLevel, message string, err error, metadata ...map[string]interface{}) LogEntry {
-	entry := LogEntry{
-		Timestamp:   time.Now(),
-		Level:       level,
-		Message:     message,
-		ServiceName: l.serviceName,
-		RequestID:   GetRequestIDFromContext(ctx),
-		UserID:      GetUserIDFromContext(ctx),
-		Error:       err,
-	}
-
-	// Merge metadata
-	if len(metadata) > 0 {
-		entry.Metadata = metadata[0]
-	}
-
-	// Extract action, resource, and resourceID from metadata if available
-	if entry.Metadata != nil {
-		if action, ok := entry.Metadata["action"].(string); ok {
-			entry.Action = action
-		}
-		if resource, ok := entry.Metadata["resource"].(string); ok {
-			entry.Resource = resource
-		}
-		if resourceID, ok := entry.Metadata["resource_id"].(string); ok {
-			entry.ResourceID = resourceID
-		}
-	}
-
-	return entry
-}
-
 // putMetricData sends a metric to CloudWatch
 func (l *CloudWatchLogger) putMetricData(ctx context.Context, entry LogEntry) error {
 	// Create metric name based on log level and resource
@@ -90,53 +62,83 @@ func (l *CloudWatchLogger) putMetricData(ctx context.Context, entry LogEntry) er
 		})
 	}
 
-	// Create metric data
-	_, err := l.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
-		Namespace: aws.String(l.namespace),
-		MetricData: []types.MetricDatum{
-			{
-				MetricName: aws.String(metricName),
-				Dimensions: dimensions,
-				Timestamp:  aws.Time(entry.Timestamp),
-				Value:      aws.Float64(1.0),
-				Unit:       types.StandardUnitCount,
-			},
+	return l.putDatum(ctx, metricDatum(metricName, 1.0, types.StandardUnitCount, dimensions, entry.Timestamp))
+}
+
+// RecordMetric sends a metric with the given value and unit to CloudWatch,
+// with the ServiceName dimension and the given ones
+func (l *CloudWatchLogger) RecordMetric(ctx context.Context, name string, value float64, unit types.StandardUnit, dims map[string]string) {
+	dimensions := []types.Dimension{
+		{
+			Name:  aws.String("ServiceName"),
+			Value: aws.String(l.serviceName),
 		},
-	})
+	}
+	for dimName, dimValue := range dims {
+		dimensions = append(dimensions, types.Dimension{
+			Name:  aws.String(dimName),
+			Value: aws.String(dimValue),
+		})
+	}
+
+	if err := l.putDatum(ctx, metricDatum(name, value, unit, dimensions, time.Now().UTC())); err != nil {
+		fmt.Printf("Error sending metric to CloudWatch: %v\n", err)
+	}
+}
+
+// metricDatum builds a single CloudWatch datum
+func metricDatum(name string, value float64, unit types.StandardUnit, dimensions []types.Dimension, timestamp time.Time) types.MetricDatum {
+	return types.MetricDatum{
+		MetricName: aws.String(name),
+		Dimensions: dimensions,
+		Timestamp:  aws.Time(timestamp),
+		Value:      aws.Float64(value),
+		Unit:       unit,
+	}
+}
 
+// putDatum sends a datum to the logger's namespace
+func (l *CloudWatchLogger) putDatum(ctx context.Context, datum types.MetricDatum) error {
+	_, err := l.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+		Namespace:  aws.String(l.namespace),
+		MetricData: []types.MetricDatum{datum},
+	})
 	return err
 }
 
 // Debug logs a debug message to CloudWatch
 func (l *CloudWatchLogger) Debug(ctx context.Context, message string, metadata ...map[string]interface{}) {
-	entry := l.createLogEntry(ctx, DEBUG, message, nil, metadata...)
+	entry := buildLogEntry(ctx, l.serviceName, DEBUG, message, nil, metadata...)
 	l.logToCloudWatch(ctx, entry)
 }
 
 // Info logs an info message to CloudWatch
 func (l *CloudWatchLogger) Info(ctx context.Context, message string, metadata ...map[string]interface{}) {
-	entry := l.createLogEntry(ctx, INFO, message, nil, metadata...)
+	entry := buildLogEntry(ctx, l.serviceName, INFO, message, nil, metadata...)
 	l.logToCloudWatch(ctx, entry)
 }
 
 // Warn logs a warning message to CloudWatch
 func (l *CloudWatchLogger) Warn(ctx context.Context, message string, metadata ...map[string]interface{}) {
-	entry := l.createLogEntry(ctx, WARN, message, nil, metadata...)
+	entry := buildLogEntry(ctx, l.serviceName, WARN, message, nil, metadata...)
 	l.logToCloudWatch(ctx, entry)
 }
 
 // Error logs an error message to CloudWatch
 func (l *CloudWatchLogger) Error(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
-	entry := l.createLogEntry(ctx, ERROR, message, err, metadata...)
+	entry := buildLogEntry(ctx, l.serviceName, ERROR, message, err, metadata...)
 	l.logToCloudWatch(ctx, entry)
 }
 
 // Fatal logs a fatal message to CloudWatch
 func (l *CloudWatchLogger) Fatal(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
-	entry := l.createLogEntry(ctx, FATAL, message, err, metadata...)
+	entry := buildLogEntry(ctx, l.serviceName, FATAL, message, err, metadata...)
 	l.logToCloudWatch(ctx, entry)
 }
 
+// Flush does nothing: entries are sent to CloudWatch as they are logged
+func (l *CloudWatchLogger) Flush(ctx context.Context) {}
+
 // logToCloudWatch sends the log entry to CloudWatch
 func (l *CloudWatchLogger) logToCloudWatch(ctx context.Context, entry LogEntry) {
 	// Convert entry to JSON
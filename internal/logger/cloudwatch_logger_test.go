@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// fakeMetricsClient records the metric data sent to CloudWatch
+type fakeMetricsClient struct {
+	inputs []*cloudwatch.PutMetricDataInput
+}
+
+func (c *fakeMetricsClient) PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error) {
+	c.inputs = append(c.inputs, params)
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+func TestCloudWatchLoggerRecordMetric(t *testing.T) {
+	tests := []struct {
+		name     string
+		metric   string
+		value    float64
+		unit     types.StandardUnit
+		dims     map[string]string
+		wantDims map[string]string
+	}{
+		{
+			name:     "rating value",
+			metric:   "RatingValue",
+			value:    4,
+			unit:     types.StandardUnitNone,
+			dims:     map[string]string{"Resource": "lugares", "Action": "AddRatingToLugar"},
+			wantDims: map[string]string{"ServiceName": "site-geav-api", "Resource": "lugares", "Action": "AddRatingToLugar"},
+		},
+		{
+			name:     "payload size",
+			metric:   "PayloadSize",
+			value:    2048.5,
+			unit:     types.StandardUnitBytes,
+			wantDims: map[string]string{"ServiceName": "site-geav-api"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeMetricsClient{}
+			log := &CloudWatchLogger{client: client, serviceName: "site-geav-api", namespace: "SiteGeav/API"}
+
+			// Record through the helper, as the handlers do
+			RecordMetric(context.Background(), log, tt.metric, tt.value, tt.unit, tt.dims)
+
+			if len(client.inputs) != 1 || len(client.inputs[0].MetricData) != 1 {
+				t.Fatalf("got %d requests, want one with a single datum", len(client.inputs))
+			}
+			input := client.inputs[0]
+			if *input.Namespace != "SiteGeav/API" {
+				t.Errorf("namespace = %s, want SiteGeav/API", *input.Namespace)
+			}
+			datum := input.MetricData[0]
+			if *datum.MetricName != tt.metric || *datum.Value != tt.value || datum.Unit != tt.unit {
+				t.Errorf("datum = %s %v %s, want %s %v %s", *datum.MetricName, *datum.Value, datum.Unit, tt.metric, tt.value, tt.unit)
+			}
+			gotDims := map[string]string{}
+			for _, dim := range datum.Dimensions {
+				gotDims[*dim.Name] = *dim.Value
+			}
+			if len(gotDims) != len(tt.wantDims) {
+				t.Errorf("dimensions = %v, want %v", gotDims, tt.wantDims)
+			}
+			for name, value := range tt.wantDims {
+				if gotDims[name] != value {
+					t.Errorf("dimension %s = %q, want %q", name, gotDims[name], value)
+				}
+			}
+		})
+	}
+}
+
+// recordingMetricLogger is a Logger recording the names of the metrics it is given
+type recordingMetricLogger struct {
+	countingLogger
+	metrics []string
+}
+
+func (l *recordingMetricLogger) RecordMetric(ctx context.Context, name string, value float64, unit types.StandardUnit, dims map[string]string) {
+	l.metrics = append(l.metrics, name)
+}
+
+func TestRecordMetricReachesMetricLoggers(t *testing.T) {
+	first, second := &recordingMetricLogger{}, &recordingMetricLogger{}
+
+	tests := []struct {
+		name string
+		log  Logger
+		want []*recordingMetricLogger
+	}{
+		{"metric logger", first, []*recordingMetricLogger{first}},
+		{"composite with a logger without metrics", NewCompositeLogger(&countingLogger{}, first, second), []*recordingMetricLogger{first, second}},
+		{"behind the sampler", NewSampledLogger(NewCompositeLogger(first)), []*recordingMetricLogger{first}},
+		{"logger without metrics", &countingLogger{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first.metrics, second.metrics = nil, nil
+
+			RecordMetric(context.Background(), tt.log, "RatingValue", 5, types.StandardUnitNone, nil)
+
+			var got []string
+			for _, l := range []*recordingMetricLogger{first, second} {
+				got = append(got, l.metrics...)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("metric recorded %d times, want %d", len(got), len(tt.want))
+			}
+			for _, l := range tt.want {
+				if len(l.metrics) != 1 || l.metrics[0] != "RatingValue" {
+					t.Errorf("metrics = %v, want [RatingValue]", l.metrics)
+				}
+			}
+		})
+	}
+}
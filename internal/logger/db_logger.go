@@ -5,22 +5,36 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"time"
+	"os"
+	"strconv"
 )
 
+// defaultMaxConcurrentInserts is the default number of log inserts that may run at the same time
+const defaultMaxConcurrentInserts = 2
+
 // DBLogger implements the Logger interface for database logging
 type DBLogger struct {
 	db          *sql.DB
 	serviceName string
 	tableName   string
+	// inserts bounds the concurrent log inserts so logging cannot take
+	// every connection of the pool away from request handlers
+	inserts chan struct{}
 }
 
-// NewDBLogger creates a new database logger
+// NewDBLogger creates a new database logger. The number of concurrent
+// inserts is read from LOG_DB_MAX_CONCURRENCY (default 2).
 func NewDBLogger(db *sql.DB, serviceName, tableName string) *DBLogger {
+	maxConcurrent, err := strconv.Atoi(os.Getenv("LOG_DB_MAX_CONCURRENCY"))
+	if err != nil || maxConcurrent <= 0 {
+		maxConcurrent = defaultMaxConcurrentInserts
+	}
+
 	return &DBLogger{
 		db:          db,
 		serviceName: serviceName,
 		tableName:   tableName,
+		inserts:     make(chan struct{}, maxConcurrent),
 	}
 }
 
@@ -29,6 +43,7 @@ func (l *DBLogger) ensureLogTable(ctx context.Context) error {
 	query := fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
 			id SERIAL PRIMARY KEY,
+			entry_id TEXT,
 			timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
 			level TEXT NOT NULL,
 			message TEXT NOT NULL,
@@ -48,71 +63,50 @@ func (l *DBLogger) ensureLogTable(ctx context.Context) error {
 	return err
 }
 
-// createLogEntry creates a log entry with common fields
-func (l *DBLogger) createLogEntry(ctx context.Context, level LogLevel, message string, err error, metadata ...map[string]interface{}) LogEntry {
-	entry := LogEntry{
-		Timestamp:   time.Now(),
-		Level:       level,
-		Message:     message,
-		ServiceName: l.serviceName,
-		RequestID:   GetRequestIDFromContext(ctx),
-		UserID:      GetUserIDFromContext(ctx),
-		Error:       err,
-	}
-
-	// Merge metadata
-	if len(metadata) > 0 {
-		entry.Metadata = metadata[0]
-	}
-
-	// Extract action, resource, and resourceID from metadata if available
-	if entry.Metadata != nil {
-		if action, ok := entry.Metadata["action"].(string); ok {
-			entry.Action = action
-		}
-		if resource, ok := entry.Metadata["resource"].(string); ok {
-			entry.Resource = resource
-		}
-		if resourceID, ok := entry.Metadata["resource_id"].(string); ok {
-			entry.ResourceID = resourceID
-		}
-	}
-
-	return entry
-}
-
 // Debug logs a debug message to the database
 func (l *DBLogger) Debug(ctx context.Context, message string, metadata ...map[string]interface{}) {
-	entry := l.createLogEntry(ctx, DEBUG, message, nil, metadata...)
+	entry := buildLogEntry(ctx, l.serviceName, DEBUG, message, nil, metadata...)
 	l.logToDB(ctx, entry)
 }
 
 // Info logs an info message to the database
 func (l *DBLogger) Info(ctx context.Context, message string, metadata ...map[string]interface{}) {
-	entry := l.createLogEntry(ctx, INFO, message, nil, metadata...)
+	entry := buildLogEntry(ctx, l.serviceName, INFO, message, nil, metadata...)
 	l.logToDB(ctx, entry)
 }
 
 // Warn logs a warning message to the database
 func (l *DBLogger) Warn(ctx context.Context, message string, metadata ...map[string]interface{}) {
-	entry := l.createLogEntry(ctx, WARN, message, nil, metadata...)
+	entry := buildLogEntry(ctx, l.serviceName, WARN, message, nil, metadata...)
 	l.logToDB(ctx, entry)
 }
 
 // Error logs an error message to the database
 func (l *DBLogger) Error(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
-	entry := l.createLogEntry(ctx, ERROR, message, err, metadata...)
+	entry := buildLogEntry(ctx, l.serviceName, ERROR, message, err, metadata...)
 	l.logToDB(ctx, entry)
 }
 
 // Fatal logs a fatal message to the database
 func (l *DBLogger) Fatal(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
-	entry := l.createLogEntry(ctx, FATAL, message, err, metadata...)
+	entry := buildLogEntry(ctx, l.serviceName, FATAL, message, err, metadata...)
 	l.logToDB(ctx, entry)
 }
 
+// Flush does nothing: entries are written to the database as they are logged
+func (l *DBLogger) Flush(ctx context.Context) {}
+
 // logToDB sends the log entry to the database
 func (l *DBLogger) logToDB(ctx context.Context, entry LogEntry) {
+	// Wait for an insert slot, dropping the entry if the request is done first
+	select {
+	case l.inserts <- struct{}{}:
+		defer func() { <-l.inserts }()
+	case <-ctx.Done():
+		fmt.Printf("Dropping log entry: %v\n", ctx.Err())
+		return
+	}
+
 	// Ensure log table exists
 	if err := l.ensureLogTable(ctx); err != nil {
 		fmt.Printf("Error ensuring log table: %v\n", err)
@@ -139,14 +133,15 @@ func (l *DBLogger) logToDB(ctx context.Context, entry LogEntry) {
 	// Insert log entry into database
 	query := fmt.Sprintf(`
 		INSERT INTO %s (
-			timestamp, level, message, service_name, request_id, user_id,
+			entry_id, timestamp, level, message, service_name, request_id, user_id,
 			action, resource, resource_id, metadata, error_message
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
 		)
 	`, l.tableName)
 
 	_, err = l.db.ExecContext(ctx, query,
+		entry.EntryID,
 		entry.Timestamp,
 		string(entry.Level),
 		entry.Message,
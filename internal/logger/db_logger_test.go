@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingDriver is a database/sql driver whose statements only record how
+// many of them run at the same time
+type countingDriver struct {
+	delay    time.Duration
+	inFlight int32
+	maxSeen  int32
+	inserts  int32
+}
+
+func (d *countingDriver) Open(name string) (driver.Conn, error) {
+	return &countingConn{driver: d}, nil
+}
+
+type countingConn struct {
+	driver *countingDriver
+}
+
+func (c *countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	d := c.driver
+	current := atomic.AddInt32(&d.inFlight, 1)
+	defer atomic.AddInt32(&d.inFlight, -1)
+	for {
+		seen := atomic.LoadInt32(&d.maxSeen)
+		if current <= seen || atomic.CompareAndSwapInt32(&d.maxSeen, seen, current) {
+			break
+		}
+	}
+	if len(args) > 0 {
+		atomic.AddInt32(&d.inserts, 1)
+	}
+	time.Sleep(d.delay)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *countingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *countingConn) Close() error { return nil }
+
+func (c *countingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+var driverCount int32
+
+// newCountingDB opens a database backed by a new countingDriver
+func newCountingDB(t *testing.T, delay time.Duration) (*sql.DB, *countingDriver) {
+	t.Helper()
+	d := &countingDriver{delay: delay}
+	name := "counting" + strconv.Itoa(int(atomic.AddInt32(&driverCount, 1)))
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func TestDBLoggerConcurrency(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           string
+		wantMaxInsert int32
+	}{
+		{"default limit", "", defaultMaxConcurrentInserts},
+		{"configured limit", "3", 3},
+		{"single insert at a time", "1", 1},
+		{"invalid value uses the default", "-4", defaultMaxConcurrentInserts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LOG_DB_MAX_CONCURRENCY", tt.env)
+			db, d := newCountingDB(t, 5*time.Millisecond)
+			log := NewDBLogger(db, "test", "api_logs")
+
+			const calls = 20
+			var wg sync.WaitGroup
+			for i := 0; i < calls; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					log.Info(context.Background(), "concurrent entry")
+				}()
+			}
+			wg.Wait()
+
+			if d.maxSeen > tt.wantMaxInsert {
+				t.Errorf("%d statements ran at once, want at most %d", d.maxSeen, tt.wantMaxInsert)
+			}
+			if d.inserts != calls {
+				t.Errorf("inserted %d entries, want %d", d.inserts, calls)
+			}
+		})
+	}
+}
+
+func TestDBLoggerDropsEntriesOfFinishedRequests(t *testing.T) {
+	t.Setenv("LOG_DB_MAX_CONCURRENCY", "1")
+	db, d := newCountingDB(t, 0)
+	log := NewDBLogger(db, "test", "api_logs")
+
+	// Take the only insert slot, as a slow insert would
+	log.inserts <- struct{}{}
+	defer func() { <-log.inserts }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		log.Error(ctx, "waiting entry", errors.New("boom"))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the log call kept waiting after its context was done")
+	}
+	if d.inserts != 0 {
+		t.Errorf("inserted %d entries, want the entry dropped", d.inserts)
+	}
+}
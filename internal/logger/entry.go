@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+)
+
+// buildLogEntry creates a log entry with the fields common to every logger.
+// The entry ID is taken from the context when a CompositeLogger already
+// assigned one, so every destination of a log call records the same ID.
+func buildLogEntry(ctx context.Context, serviceName string, level LogLevel, message string, err error, metadata ...map[string]interface{}) LogEntry {
+	entryID := getEntryIDFromContext(ctx)
+	if entryID == "" {
+		entryID = newEntryID()
+	}
+
+	entry := LogEntry{
+		EntryID:     entryID,
+		Timestamp:   time.Now().UTC(),
+		Level:       level,
+		Message:     message,
+		ServiceName: serviceName,
+		RequestID:   GetRequestIDFromContext(ctx),
+		UserID:      GetUserIDFromContext(ctx),
+		Error:       err,
+	}
+
+	// Merge metadata with the fields bound to the context
+	entry.Metadata = mergeMetadata(ctx, metadata...)
+
+	// Extract action, resource, and resourceID from metadata if available
+	if entry.Metadata != nil {
+		if action, ok := entry.Metadata["action"].(string); ok {
+			entry.Action = action
+		}
+		if resource, ok := entry.Metadata["resource"].(string); ok {
+			entry.Resource = resource
+		}
+		if resourceID, ok := entry.Metadata["resource_id"].(string); ok {
+			entry.ResourceID = resourceID
+		}
+	}
+
+	return entry
+}
+
+// withEntryID returns a context carrying a new entry ID for a single log call
+func withEntryID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, "logEntryID", newEntryID())
+}
+
+// getEntryIDFromContext extracts the entry ID set by withEntryID from the context
+func getEntryIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	if entryID, ok := ctx.Value("logEntryID").(string); ok {
+		return entryID
+	}
+
+	return ""
+}
+
+// newEntryID generates a random (version 4) UUID
+func newEntryID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// Fall back to a time-based ID; uniqueness matters more than the format
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// uuidV4 matches the IDs generated by newEntryID
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestCompositeLoggerSharesEntryIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l Logger, ctx context.Context)
+	}{
+		{"debug", func(l Logger, ctx context.Context) { l.Debug(ctx, "entry") }},
+		{"info", func(l Logger, ctx context.Context) { l.Info(ctx, "entry") }},
+		{"warn", func(l Logger, ctx context.Context) { l.Warn(ctx, "entry") }},
+		{"error", func(l Logger, ctx context.Context) { l.Error(ctx, "entry", errors.New("boom")) }},
+		{"fatal", func(l Logger, ctx context.Context) { l.Fatal(ctx, "entry", errors.New("boom")) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Two destinations, like CloudWatch and the database in production
+			first, second := &stubCollector{}, &stubCollector{}
+			firstServer, secondServer := httptest.NewServer(first), httptest.NewServer(second)
+			defer firstServer.Close()
+			defer secondServer.Close()
+
+			composite := NewCompositeLogger(
+				NewHTTPLogger("test", HTTPLoggerConfig{Endpoint: firstServer.URL, BatchSize: 1}),
+				NewHTTPLogger("test", HTTPLoggerConfig{Endpoint: secondServer.URL, BatchSize: 1}),
+			)
+
+			// Two calls, each reaching both destinations
+			tt.log(composite, context.Background())
+			tt.log(composite, context.Background())
+
+			if len(first.batches) != 2 || len(second.batches) != 2 {
+				t.Fatalf("destinations got %d and %d entries, want 2 each", len(first.batches), len(second.batches))
+			}
+			for i := 0; i < 2; i++ {
+				firstID, _ := first.batches[i][0]["entry_id"].(string)
+				secondID, _ := second.batches[i][0]["entry_id"].(string)
+				if !uuidV4.MatchString(firstID) {
+					t.Errorf("call %d: entry_id %q is not a UUID", i+1, firstID)
+				}
+				if firstID != secondID {
+					t.Errorf("call %d: destinations got entry IDs %q and %q, want the same", i+1, firstID, secondID)
+				}
+			}
+			if first.batches[0][0]["entry_id"] == first.batches[1][0]["entry_id"] {
+				t.Error("two log calls got the same entry ID")
+			}
+		})
+	}
+}
+
+func TestBuildLogEntryID(t *testing.T) {
+	// Without a CompositeLogger, every entry gets its own ID
+	a := buildLogEntry(context.Background(), "test", INFO, "a", nil)
+	b := buildLogEntry(context.Background(), "test", INFO, "b", nil)
+	if !uuidV4.MatchString(a.EntryID) || a.EntryID == b.EntryID {
+		t.Errorf("entry IDs %q and %q, want two different UUIDs", a.EntryID, b.EntryID)
+	}
+
+	// With one, the ID of the context is kept
+	ctx := withEntryID(context.Background())
+	c := buildLogEntry(ctx, "test", INFO, "c", nil)
+	d := buildLogEntry(ctx, "test", ERROR, "d", errors.New("boom"))
+	if c.EntryID != getEntryIDFromContext(ctx) || c.EntryID != d.EntryID {
+		t.Errorf("entry IDs %q and %q, want the context's %q", c.EntryID, d.EntryID, getEntryIDFromContext(ctx))
+	}
+}
+
+func TestBuildLogEntryMetadata(t *testing.T) {
+	tests := []struct {
+		name           string
+		metadata       []map[string]interface{}
+		wantAction     string
+		wantResource   string
+		wantResourceID string
+		wantMetadata   bool
+	}{
+		{
+			name:           "action, resource and resource_id are extracted",
+			metadata:       []map[string]interface{}{{"action": "GetLugar", "resource": "lugares", "resource_id": "7", "count": 2}},
+			wantAction:     "GetLugar",
+			wantResource:   "lugares",
+			wantResourceID: "7",
+			wantMetadata:   true,
+		},
+		{
+			name:         "missing fields stay empty",
+			metadata:     []map[string]interface{}{{"action": "ListRamos"}},
+			wantAction:   "ListRamos",
+			wantMetadata: true,
+		},
+		{
+			name:         "non-string fields are not extracted",
+			metadata:     []map[string]interface{}{{"action": 1, "resource": nil, "resource_id": 7}},
+			wantMetadata: true,
+		},
+		{
+			name: "no metadata",
+		},
+		{
+			name:         "only the first metadata map is used",
+			metadata:     []map[string]interface{}{{"action": "First"}, {"action": "Second", "resource": "ramos"}},
+			wantAction:   "First",
+			wantMetadata: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), "requestID", "req-1")
+			ctx = context.WithValue(ctx, "userID", 2)
+			cause := errors.New("boom")
+
+			entry := buildLogEntry(ctx, "site-geav-api", WARN, "message", cause, tt.metadata...)
+
+			if entry.Action != tt.wantAction || entry.Resource != tt.wantResource || entry.ResourceID != tt.wantResourceID {
+				t.Errorf("action, resource, resource_id = %q, %q, %q, want %q, %q, %q",
+					entry.Action, entry.Resource, entry.ResourceID, tt.wantAction, tt.wantResource, tt.wantResourceID)
+			}
+			if (entry.Metadata != nil) != tt.wantMetadata {
+				t.Errorf("metadata = %v, want metadata %v", entry.Metadata, tt.wantMetadata)
+			}
+			if entry.ServiceName != "site-geav-api" || entry.Level != WARN || entry.Message != "message" || entry.Error != cause {
+				t.Errorf("entry = %+v, want the given service, level, message and error", entry)
+			}
+			if entry.RequestID != "req-1" || entry.UserID != 2 {
+				t.Errorf("request ID, user ID = %q, %d, want the context's req-1, 2", entry.RequestID, entry.UserID)
+			}
+			if entry.Timestamp.IsZero() {
+				t.Error("entry has no timestamp")
+			}
+		})
+	}
+}
+
+func TestBuildLogEntryTimestampIsUTC(t *testing.T) {
+	tests := []struct {
+		name  string
+		level LogLevel
+		err   error
+	}{
+		{"info", INFO, nil},
+		{"error", ERROR, errors.New("boom")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := buildLogEntry(context.Background(), "test", tt.level, "entry", tt.err)
+			if entry.Timestamp.Location() != time.UTC {
+				t.Errorf("timestamp = %v, want a UTC time", entry.Timestamp)
+			}
+		})
+	}
+}
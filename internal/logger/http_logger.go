@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultHTTPBatchSize is the default number of entries sent in one request
+	defaultHTTPBatchSize = 20
+	// defaultHTTPMaxAttempts is the default number of attempts before a batch is dropped
+	defaultHTTPMaxAttempts = 3
+	// defaultHTTPTimeout bounds each request of the default client, so a slow
+	// collector cannot hold a batch (and the invocation) indefinitely
+	defaultHTTPTimeout = 5 * time.Second
+)
+
+// httpRetryDelay is the delay before the first retry; it grows with each attempt
+var httpRetryDelay = 100 * time.Millisecond
+
+// HTTPLoggerConfig holds the configuration of an HTTPLogger
+type HTTPLoggerConfig struct {
+	// Endpoint is the URL the batches are POSTed to
+	Endpoint string
+	// APIKey is sent in the APIKeyHeader header when set
+	APIKey       string
+	APIKeyHeader string
+	// BatchSize is the number of entries buffered before a batch is sent
+	BatchSize int
+	// MaxAttempts is the number of attempts to send a batch before dropping it
+	MaxAttempts int
+	// Client is the HTTP client used to send batches; a client with a
+	// defaultHTTPTimeout timeout when nil
+	Client *http.Client
+}
+
+// HTTPLogger implements the Logger interface by POSTing batches of JSON
+// entries to an external log collector
+type HTTPLogger struct {
+	config      HTTPLoggerConfig
+	serviceName string
+
+	mu      sync.Mutex
+	pending []httpLogEntry
+}
+
+// httpLogEntry is the JSON representation of a log entry sent to the collector
+type httpLogEntry struct {
+	LogEntry
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// NewHTTPLogger creates a new HTTP logger
+func NewHTTPLogger(serviceName string, config HTTPLoggerConfig) *HTTPLogger {
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultHTTPBatchSize
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultHTTPMaxAttempts
+	}
+	if config.APIKeyHeader == "" {
+		config.APIKeyHeader = "X-API-Key"
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	return &HTTPLogger{
+		config:      config,
+		serviceName: serviceName,
+	}
+}
+
+// Debug logs a debug message to the HTTP endpoint
+func (l *HTTPLogger) Debug(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	entry := buildLogEntry(ctx, l.serviceName, DEBUG, message, nil, metadata...)
+	l.logToHTTP(ctx, entry)
+}
+
+// Info logs an info message to the HTTP endpoint
+func (l *HTTPLogger) Info(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	entry := buildLogEntry(ctx, l.serviceName, INFO, message, nil, metadata...)
+	l.logToHTTP(ctx, entry)
+}
+
+// Warn logs a warning message to the HTTP endpoint
+func (l *HTTPLogger) Warn(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	entry := buildLogEntry(ctx, l.serviceName, WARN, message, nil, metadata...)
+	l.logToHTTP(ctx, entry)
+}
+
+// Error logs an error message to the HTTP endpoint
+func (l *HTTPLogger) Error(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
+	entry := buildLogEntry(ctx, l.serviceName, ERROR, message, err, metadata...)
+	l.logToHTTP(ctx, entry)
+}
+
+// Fatal logs a fatal message to the HTTP endpoint
+func (l *HTTPLogger) Fatal(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
+	entry := buildLogEntry(ctx, l.serviceName, FATAL, message, err, metadata...)
+	l.logToHTTP(ctx, entry)
+}
+
+// logToHTTP buffers the log entry and sends the batch once it is full
+func (l *HTTPLogger) logToHTTP(ctx context.Context, entry LogEntry) {
+	httpEntry := httpLogEntry{LogEntry: entry}
+	if entry.Error != nil {
+		httpEntry.ErrorMessage = entry.Error.Error()
+	}
+
+	l.mu.Lock()
+	l.pending = append(l.pending, httpEntry)
+	var batch []httpLogEntry
+	if len(l.pending) >= l.config.BatchSize {
+		batch = l.pending
+		l.pending = nil
+	}
+	l.mu.Unlock()
+
+	if batch != nil {
+		l.send(ctx, batch)
+	}
+}
+
+// Flush sends the buffered entries, if any. Call it before the process
+// may be frozen or stopped so the last entries are not lost.
+func (l *HTTPLogger) Flush(ctx context.Context) {
+	l.mu.Lock()
+	batch := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	if len(batch) > 0 {
+		l.send(ctx, batch)
+	}
+}
+
+// send POSTs a batch, retrying failed attempts and dropping the batch after MaxAttempts
+func (l *HTTPLogger) send(ctx context.Context, batch []httpLogEntry) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		fmt.Printf("Error marshaling log batch: %v\n", err)
+		return
+	}
+
+	for attempt := 1; attempt <= l.config.MaxAttempts; attempt++ {
+		err = l.post(ctx, body)
+		if err == nil {
+			return
+		}
+
+		if attempt < l.config.MaxAttempts {
+			// Give up when the context ends before the next attempt could start
+			delay := time.Duration(attempt) * httpRetryDelay
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+				fmt.Printf("Dropping %d log entries: no time left to retry: %v\n", len(batch), err)
+				return
+			}
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				fmt.Printf("Dropping %d log entries: %v\n", len(batch), ctx.Err())
+				return
+			}
+		}
+	}
+
+	fmt.Printf("Dropping %d log entries after %d attempts: %v\n", len(batch), l.config.MaxAttempts, err)
+}
+
+// post sends a single request with the encoded batch
+func (l *HTTPLogger) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating log request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.config.APIKey != "" {
+		req.Header.Set(l.config.APIKeyHeader, l.config.APIKey)
+	}
+
+	resp, err := l.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending log batch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("log endpoint returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubCollector is a log collector recording the batches it receives
+type stubCollector struct {
+	mu       sync.Mutex
+	batches  [][]map[string]interface{}
+	apiKeys  []string
+	requests int
+	// failures is the number of requests answered with 500 before succeeding;
+	// a negative value fails every request
+	failures int
+}
+
+func (c *stubCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests++
+	if c.failures < 0 || c.requests <= c.failures {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	var batch []map[string]interface{}
+	if err := json.Unmarshal(body, &batch); err != nil || r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.batches = append(c.batches, batch)
+	c.apiKeys = append(c.apiKeys, r.Header.Get("X-Test-Key"))
+}
+
+// setRetryDelay replaces httpRetryDelay for the duration of a test
+func setRetryDelay(t *testing.T, delay time.Duration) {
+	t.Helper()
+	old := httpRetryDelay
+	httpRetryDelay = delay
+	t.Cleanup(func() { httpRetryDelay = old })
+}
+
+func TestHTTPLoggerBatches(t *testing.T) {
+	setRetryDelay(t, time.Millisecond)
+
+	tests := []struct {
+		name            string
+		batchSize       int
+		logs            int
+		failures        int
+		flush           bool
+		wantBatchSizes  []int
+		wantRequests    int
+		wantPendingLeft int
+	}{
+		{name: "full batches are sent as they fill", batchSize: 2, logs: 5, wantBatchSizes: []int{2, 2}, wantRequests: 2, wantPendingLeft: 1},
+		{name: "flush sends the rest", batchSize: 2, logs: 5, flush: true, wantBatchSizes: []int{2, 2, 1}, wantRequests: 3},
+		{name: "failed attempt is retried", batchSize: 3, logs: 3, failures: 1, wantBatchSizes: []int{3}, wantRequests: 2},
+		{name: "batch is dropped after the last attempt", batchSize: 1, logs: 1, failures: -1, wantRequests: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &stubCollector{failures: tt.failures}
+			server := httptest.NewServer(collector)
+			defer server.Close()
+
+			log := NewHTTPLogger("test", HTTPLoggerConfig{
+				Endpoint:     server.URL,
+				APIKey:       "secret",
+				APIKeyHeader: "X-Test-Key",
+				BatchSize:    tt.batchSize,
+			})
+			ctx := context.Background()
+			for i := 0; i < tt.logs; i++ {
+				log.Info(ctx, "entry", map[string]interface{}{"action": "Test", "i": i})
+			}
+			if tt.flush {
+				log.Flush(ctx)
+			}
+
+			if collector.requests != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", collector.requests, tt.wantRequests)
+			}
+			var sizes []int
+			for _, batch := range collector.batches {
+				sizes = append(sizes, len(batch))
+			}
+			if len(sizes) != len(tt.wantBatchSizes) {
+				t.Fatalf("batch sizes = %v, want %v", sizes, tt.wantBatchSizes)
+			}
+			for i := range sizes {
+				if sizes[i] != tt.wantBatchSizes[i] {
+					t.Fatalf("batch sizes = %v, want %v", sizes, tt.wantBatchSizes)
+				}
+			}
+			if len(log.pending) != tt.wantPendingLeft {
+				t.Errorf("%d entries pending, want %d", len(log.pending), tt.wantPendingLeft)
+			}
+			for _, key := range collector.apiKeys {
+				if key != "secret" {
+					t.Errorf("API key header = %q, want %q", key, "secret")
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPLoggerPayload(t *testing.T) {
+	collector := &stubCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	log := NewHTTPLogger("site-geav-api", HTTPLoggerConfig{Endpoint: server.URL, BatchSize: 1})
+	log.Error(context.Background(), "Error getting lugar", errors.New("timeout"), map[string]interface{}{
+		"action":      "GetLugar",
+		"resource":    "lugares",
+		"resource_id": "7",
+	})
+
+	if len(collector.batches) != 1 || len(collector.batches[0]) != 1 {
+		t.Fatalf("got batches %v, want one entry", collector.batches)
+	}
+	entry := collector.batches[0][0]
+	want := map[string]interface{}{
+		"level":         "ERROR",
+		"message":       "Error getting lugar",
+		"service_name":  "site-geav-api",
+		"action":        "GetLugar",
+		"resource":      "lugares",
+		"resource_id":   "7",
+		"error_message": "timeout",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+	for _, key := range []string{"entry_id", "timestamp", "metadata"} {
+		if _, ok := entry[key]; !ok {
+			t.Errorf("entry has no %s: %v", key, entry)
+		}
+	}
+}
+
+func TestHTTPLoggerRetriesEndWithTheContext(t *testing.T) {
+	setRetryDelay(t, 200*time.Millisecond)
+
+	collector := &stubCollector{failures: -1}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	log := NewHTTPLogger("test", HTTPLoggerConfig{Endpoint: server.URL, BatchSize: 1, MaxAttempts: 5})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	log.Info(ctx, "entry")
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("logging took %v, want it to give up before the context deadline", elapsed)
+	}
+	if collector.requests != 1 {
+		t.Errorf("got %d requests, want a single attempt", collector.requests)
+	}
+}
+
+func TestHTTPLoggerDefaultClientHasATimeout(t *testing.T) {
+	log := NewHTTPLogger("test", HTTPLoggerConfig{Endpoint: "http://collector.invalid"})
+	if log.config.Client == http.DefaultClient || log.config.Client.Timeout != defaultHTTPTimeout {
+		t.Errorf("default client timeout = %v, want %v", log.config.Client.Timeout, defaultHTTPTimeout)
+	}
+}
@@ -3,6 +3,8 @@ package logger
 import (
 	"context"
 	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
 )
 
 // LogLevel represents the severity level of a log message
@@ -23,6 +25,8 @@ const (
 
 // LogEntry represents a log entry
 type LogEntry struct {
+	// EntryID identifies a single log call across all the loggers it reached
+	EntryID     string                 `json:"entry_id"`
 	Timestamp   time.Time              `json:"timestamp"`
 	Level       LogLevel               `json:"level"`
 	Message     string                 `json:"message"`
@@ -43,6 +47,23 @@ type Logger interface {
 	Warn(ctx context.Context, message string, metadata ...map[string]interface{})
 	Error(ctx context.Context, message string, err error, metadata ...map[string]interface{})
 	Fatal(ctx context.Context, message string, err error, metadata ...map[string]interface{})
+	// Flush sends the buffered entries, if any. It is called at the end of
+	// every invocation, before the Lambda environment may be frozen.
+	Flush(ctx context.Context)
+}
+
+// MetricLogger is implemented by loggers that can record a metric with a
+// value and unit, e.g. a gauge, instead of counting log entries
+type MetricLogger interface {
+	RecordMetric(ctx context.Context, name string, value float64, unit types.StandardUnit, dims map[string]string)
+}
+
+// RecordMetric records a metric with the given logger when it is a
+// MetricLogger, and does nothing otherwise
+func RecordMetric(ctx context.Context, l Logger, name string, value float64, unit types.StandardUnit, dims map[string]string) {
+	if metricLogger, ok := l.(MetricLogger); ok {
+		metricLogger.RecordMetric(ctx, name, value, unit, dims)
+	}
 }
 
 // CompositeLogger combines multiple loggers
@@ -59,6 +80,7 @@ func NewCompositeLogger(loggers ...Logger) *CompositeLogger {
 
 // Debug logs a debug message to all loggers
 func (l *CompositeLogger) Debug(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	ctx = withEntryID(ctx)
 	for _, logger := range l.loggers {
 		logger.Debug(ctx, message, metadata...)
 	}
@@ -66,6 +88,7 @@ func (l *CompositeLogger) Debug(ctx context.Context, message string, metadata ..
 
 // Info logs an info message to all loggers
 func (l *CompositeLogger) Info(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	ctx = withEntryID(ctx)
 	for _, logger := range l.loggers {
 		logger.Info(ctx, message, metadata...)
 	}
@@ -73,6 +96,7 @@ func (l *CompositeLogger) Info(ctx context.Context, message string, metadata ...
 
 // Warn logs a warning message to all loggers
 func (l *CompositeLogger) Warn(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	ctx = withEntryID(ctx)
 	for _, logger := range l.loggers {
 		logger.Warn(ctx, message, metadata...)
 	}
@@ -80,6 +104,7 @@ func (l *CompositeLogger) Warn(ctx context.Context, message string, metadata ...
 
 // Error logs an error message to all loggers
 func (l *CompositeLogger) Error(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
+	ctx = withEntryID(ctx)
 	for _, logger := range l.loggers {
 		logger.Error(ctx, message, err, metadata...)
 	}
@@ -87,11 +112,76 @@ func (l *CompositeLogger) Error(ctx context.Context, message string, err error,
 
 // Fatal logs a fatal message to all loggers
 func (l *CompositeLogger) Fatal(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
+	ctx = withEntryID(ctx)
 	for _, logger := range l.loggers {
 		logger.Fatal(ctx, message, err, metadata...)
 	}
 }
 
+// Flush flushes all loggers
+func (l *CompositeLogger) Flush(ctx context.Context) {
+	for _, logger := range l.loggers {
+		logger.Flush(ctx)
+	}
+}
+
+// RecordMetric records the metric with every logger that supports metrics
+func (l *CompositeLogger) RecordMetric(ctx context.Context, name string, value float64, unit types.StandardUnit, dims map[string]string) {
+	for _, logger := range l.loggers {
+		RecordMetric(ctx, logger, name, value, unit, dims)
+	}
+}
+
+// With returns a context carrying fields that are added to every log entry
+// emitted with it, so a handler can bind "action" and "resource" once.
+// Fields already bound to ctx are kept unless overridden.
+func With(ctx context.Context, fields map[string]interface{}) context.Context {
+	bound := make(map[string]interface{}, len(fields))
+	for key, value := range GetFieldsFromContext(ctx) {
+		bound[key] = value
+	}
+	for key, value := range fields {
+		bound[key] = value
+	}
+	return context.WithValue(ctx, "logFields", bound)
+}
+
+// GetFieldsFromContext extracts the fields bound with With from the context
+func GetFieldsFromContext(ctx context.Context) map[string]interface{} {
+	if ctx == nil {
+		return nil
+	}
+
+	if fields, ok := ctx.Value("logFields").(map[string]interface{}); ok {
+		return fields
+	}
+
+	return nil
+}
+
+// mergeMetadata combines the fields bound to the context with the per-call
+// metadata, the latter taking precedence
+func mergeMetadata(ctx context.Context, metadata ...map[string]interface{}) map[string]interface{} {
+	bound := GetFieldsFromContext(ctx)
+	if len(bound) == 0 {
+		if len(metadata) > 0 {
+			return metadata[0]
+		}
+		return nil
+	}
+
+	merged := make(map[string]interface{}, len(bound))
+	for key, value := range bound {
+		merged[key] = value
+	}
+	if len(metadata) > 0 {
+		for key, value := range metadata[0] {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
 // GetRequestIDFromContext extracts the request ID from the context
 func GetRequestIDFromContext(ctx context.Context) string {
 	if ctx == nil {
@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWithBindsFieldsToEntries(t *testing.T) {
+	tests := []struct {
+		name         string
+		ctx          func() context.Context
+		metadata     []map[string]interface{}
+		wantMetadata map[string]interface{}
+		wantAction   string
+		wantResource string
+	}{
+		{
+			name: "bound fields only",
+			ctx: func() context.Context {
+				return With(context.Background(), map[string]interface{}{"action": "GetRamo", "resource": "ramos"})
+			},
+			wantMetadata: map[string]interface{}{"action": "GetRamo", "resource": "ramos"},
+			wantAction:   "GetRamo",
+			wantResource: "ramos",
+		},
+		{
+			name: "merged with per-call metadata",
+			ctx: func() context.Context {
+				return With(context.Background(), map[string]interface{}{"action": "ListRamos", "resource": "ramos"})
+			},
+			metadata:     []map[string]interface{}{{"count": 3}},
+			wantMetadata: map[string]interface{}{"action": "ListRamos", "resource": "ramos", "count": 3},
+			wantAction:   "ListRamos",
+			wantResource: "ramos",
+		},
+		{
+			name: "per-call metadata wins",
+			ctx: func() context.Context {
+				return With(context.Background(), map[string]interface{}{"action": "ListRamos", "resource": "ramos"})
+			},
+			metadata:     []map[string]interface{}{{"resource": "logs"}},
+			wantMetadata: map[string]interface{}{"action": "ListRamos", "resource": "logs"},
+			wantAction:   "ListRamos",
+			wantResource: "logs",
+		},
+		{
+			name: "nested With keeps the outer fields",
+			ctx: func() context.Context {
+				ctx := With(context.Background(), map[string]interface{}{"action": "GetRamo", "resource": "ramos"})
+				return With(ctx, map[string]interface{}{"resource_id": "7"})
+			},
+			wantMetadata: map[string]interface{}{"action": "GetRamo", "resource": "ramos", "resource_id": "7"},
+			wantAction:   "GetRamo",
+			wantResource: "ramos",
+		},
+		{
+			name:         "nothing bound",
+			ctx:          context.Background,
+			metadata:     []map[string]interface{}{{"action": "Bootstrap"}},
+			wantMetadata: map[string]interface{}{"action": "Bootstrap"},
+			wantAction:   "Bootstrap",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := buildLogEntry(tt.ctx(), "test", INFO, "message", nil, tt.metadata...)
+
+			if !reflect.DeepEqual(entry.Metadata, tt.wantMetadata) {
+				t.Errorf("metadata = %v, want %v", entry.Metadata, tt.wantMetadata)
+			}
+			if entry.Action != tt.wantAction || entry.Resource != tt.wantResource {
+				t.Errorf("action, resource = %q, %q, want %q, %q", entry.Action, entry.Resource, tt.wantAction, tt.wantResource)
+			}
+		})
+	}
+}
+
+func TestWithDoesNotChangeTheParentContext(t *testing.T) {
+	parent := With(context.Background(), map[string]interface{}{"action": "A"})
+	child := With(parent, map[string]interface{}{"action": "B"})
+
+	if got := GetFieldsFromContext(parent)["action"]; got != "A" {
+		t.Errorf("parent action = %v, want A", got)
+	}
+	if got := GetFieldsFromContext(child)["action"]; got != "B" {
+		t.Errorf("child action = %v, want B", got)
+	}
+}
+
+func TestCompositeLoggerFlushDrainsBufferedChildren(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(buffered *HTTPLogger) Logger
+		panic bool
+	}{
+		{"buffered child", func(buffered *HTTPLogger) Logger { return NewCompositeLogger(buffered) }, false},
+		{"alongside a non-buffered logger", func(buffered *HTTPLogger) Logger {
+			return NewCompositeLogger(&countingLogger{}, buffered)
+		}, false},
+		{"behind the sampler", func(buffered *HTTPLogger) Logger { return NewCompositeLogger(NewSampledLogger(buffered)) }, false},
+		{"handler panics", func(buffered *HTTPLogger) Logger { return NewCompositeLogger(buffered) }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &stubCollector{}
+			server := httptest.NewServer(collector)
+			defer server.Close()
+
+			buffered := NewHTTPLogger("test", HTTPLoggerConfig{Endpoint: server.URL, BatchSize: 100})
+			log := tt.build(buffered)
+
+			// invoke mirrors the Lambda handler wrapper, which defers the flush
+			invoke := func() {
+				ctx := context.Background()
+				defer log.Flush(ctx)
+				for i := 0; i < 3; i++ {
+					log.Info(ctx, "entry", map[string]interface{}{"action": "Test", "i": i})
+				}
+				if collector.requests != 0 {
+					t.Errorf("got %d requests before the end of the invocation, want the entries buffered", collector.requests)
+				}
+				if tt.panic {
+					panic("handler failed")
+				}
+			}
+			func() {
+				defer func() { recover() }()
+				invoke()
+			}()
+
+			if len(collector.batches) != 1 || len(collector.batches[0]) != 3 {
+				t.Fatalf("got batches %v, want the 3 entries sent once", collector.batches)
+			}
+			if len(buffered.pending) != 0 {
+				t.Errorf("%d entries still pending", len(buffered.pending))
+			}
+		})
+	}
+}
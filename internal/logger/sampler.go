@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// SampledLogger wraps a Logger and keeps only one in every Rate debug and
+// info entries logged with a context marked by WithSampling. Warnings,
+// errors and fatal entries are always kept, as are entries logged with an
+// unmarked context.
+type SampledLogger struct {
+	next    Logger
+	rate    uint64
+	counter uint64
+}
+
+// NewSampledLogger creates a sampled logger. The rate is read from
+// LOG_SAMPLE_RATE (default 1, which keeps every entry).
+func NewSampledLogger(next Logger) *SampledLogger {
+	rate, err := strconv.Atoi(os.Getenv("LOG_SAMPLE_RATE"))
+	if err != nil || rate < 1 {
+		rate = 1
+	}
+
+	return &SampledLogger{
+		next: next,
+		rate: uint64(rate),
+	}
+}
+
+// WithSampling returns a context whose debug and info entries may be sampled
+// out, for requests whose success logs are not needed in full (e.g. reads)
+func WithSampling(ctx context.Context) context.Context {
+	return context.WithValue(ctx, "logSampling", true)
+}
+
+// isSampled checks if the context was marked by WithSampling
+func isSampled(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+
+	sampled, _ := ctx.Value("logSampling").(bool)
+	return sampled
+}
+
+// keep decides whether a debug or info entry is logged. The first of every
+// Rate sampled entries is kept.
+func (l *SampledLogger) keep(ctx context.Context) bool {
+	if l.rate <= 1 || !isSampled(ctx) {
+		return true
+	}
+	return (atomic.AddUint64(&l.counter, 1)-1)%l.rate == 0
+}
+
+// Debug logs a debug message unless it is sampled out
+func (l *SampledLogger) Debug(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	if l.keep(ctx) {
+		l.next.Debug(ctx, message, metadata...)
+	}
+}
+
+// Info logs an info message unless it is sampled out
+func (l *SampledLogger) Info(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	if l.keep(ctx) {
+		l.next.Info(ctx, message, metadata...)
+	}
+}
+
+// Warn logs a warning message; warnings are never sampled out
+func (l *SampledLogger) Warn(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	l.next.Warn(ctx, message, metadata...)
+}
+
+// Error logs an error message; errors are never sampled out
+func (l *SampledLogger) Error(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
+	l.next.Error(ctx, message, err, metadata...)
+}
+
+// Fatal logs a fatal message; fatal entries are never sampled out
+func (l *SampledLogger) Fatal(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
+	l.next.Fatal(ctx, message, err, metadata...)
+}
+
+// RecordMetric records the metric with the wrapped logger; metrics are never sampled out
+func (l *SampledLogger) RecordMetric(ctx context.Context, name string, value float64, unit types.StandardUnit, dims map[string]string) {
+	RecordMetric(ctx, l.next, name, value, unit, dims)
+}
+
+// Flush flushes the wrapped logger
+func (l *SampledLogger) Flush(ctx context.Context) {
+	l.next.Flush(ctx)
+}
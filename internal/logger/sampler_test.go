@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// countingLogger is a Logger counting the entries logged at each level
+type countingLogger struct {
+	mu     sync.Mutex
+	counts map[LogLevel]int
+}
+
+func (l *countingLogger) count(level LogLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts == nil {
+		l.counts = map[LogLevel]int{}
+	}
+	l.counts[level]++
+}
+
+func (l *countingLogger) Debug(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	l.count(DEBUG)
+}
+
+func (l *countingLogger) Info(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	l.count(INFO)
+}
+
+func (l *countingLogger) Warn(ctx context.Context, message string, metadata ...map[string]interface{}) {
+	l.count(WARN)
+}
+
+func (l *countingLogger) Error(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
+	l.count(ERROR)
+}
+
+func (l *countingLogger) Fatal(ctx context.Context, message string, err error, metadata ...map[string]interface{}) {
+	l.count(FATAL)
+}
+
+func (l *countingLogger) Flush(ctx context.Context) {}
+
+func TestSampledLogger(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      string
+		sampled   bool
+		wantInfo  int
+		wantDebug int
+	}{
+		{name: "default rate keeps everything", rate: "", sampled: true, wantInfo: 100, wantDebug: 100},
+		{name: "invalid rate keeps everything", rate: "abc", sampled: true, wantInfo: 100, wantDebug: 100},
+		{name: "zero rate keeps everything", rate: "0", sampled: true, wantInfo: 100, wantDebug: 100},
+		{name: "one in ten", rate: "10", sampled: true, wantInfo: 20, wantDebug: 0},
+		{name: "one in three", rate: "3", sampled: true, wantInfo: 34, wantDebug: 33},
+		{name: "unmarked context keeps everything", rate: "10", sampled: false, wantInfo: 100, wantDebug: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LOG_SAMPLE_RATE", tt.rate)
+			next := &countingLogger{}
+			log := NewSampledLogger(next)
+
+			ctx := context.Background()
+			if tt.sampled {
+				ctx = WithSampling(ctx)
+			}
+
+			// Info and debug entries share one counter: of the 200 entries,
+			// the first of every rate is kept, whatever its level
+			for i := 0; i < 100; i++ {
+				log.Info(ctx, "listed")
+				log.Debug(ctx, "details")
+				log.Warn(ctx, "odd request")
+				log.Error(ctx, "failed", errors.New("boom"))
+				log.Fatal(ctx, "crashed", errors.New("boom"))
+			}
+
+			if got := next.counts[INFO]; got != tt.wantInfo {
+				t.Errorf("info entries = %d, want %d", got, tt.wantInfo)
+			}
+			if got := next.counts[DEBUG]; got != tt.wantDebug {
+				t.Errorf("debug entries = %d, want %d", got, tt.wantDebug)
+			}
+			// Warnings, errors and fatal entries are never sampled out
+			for _, level := range []LogLevel{WARN, ERROR, FATAL} {
+				if got := next.counts[level]; got != 100 {
+					t.Errorf("%s entries = %d, want 100", level, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSampledLoggerConcurrentRate(t *testing.T) {
+	t.Setenv("LOG_SAMPLE_RATE", "4")
+	next := &countingLogger{}
+	log := NewSampledLogger(next)
+	ctx := WithSampling(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				log.Info(ctx, "listed")
+				log.Error(ctx, "failed", errors.New("boom"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := next.counts[INFO]; got != 200 {
+		t.Errorf("info entries = %d, want 200", got)
+	}
+	if got := next.counts[ERROR]; got != 800 {
+		t.Errorf("error entries = %d, want 800", got)
+	}
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the request duration histogram
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry keeps request counters and a request duration histogram in memory
+// and writes them in the Prometheus text exposition format.
+//
+// The values live in the process: on Lambda each execution environment has
+// its own registry, which starts from zero on a cold start and disappears
+// when the environment is recycled. A scrape only sees the environment that
+// served it.
+type Registry struct {
+	buckets []float64
+
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	durations map[durationKey]*histogram
+}
+
+// requestKey identifies a request counter
+type requestKey struct {
+	method string
+	route  string
+	status int
+}
+
+// durationKey identifies a request duration histogram
+type durationKey struct {
+	method string
+	route  string
+}
+
+// histogram holds the cumulative bucket counts, the sum and the count of observations
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewRegistry creates an empty registry using DefaultBuckets
+func NewRegistry() *Registry {
+	return &Registry{
+		buckets:   DefaultBuckets,
+		requests:  map[requestKey]uint64{},
+		durations: map[durationKey]*histogram{},
+	}
+}
+
+// ObserveRequest counts a request to a route and records its duration.
+// route should be the route template (e.g. /lugares/{id}) to keep the
+// number of series small.
+func (r *Registry) ObserveRequest(method, route string, status int, duration time.Duration) {
+	seconds := duration.Seconds()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests[requestKey{method: method, route: route, status: status}]++
+
+	key := durationKey{method: method, route: route}
+	h, ok := r.durations[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(r.buckets))}
+		r.durations[key] = h
+	}
+	for i, upper := range r.buckets {
+		if seconds <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+
+	// Request counters, sorted so the output is stable
+	requestKeys := make([]requestKey, 0, len(r.requests))
+	for key := range r.requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		a, c := requestKeys[i], requestKeys[j]
+		if a.route != c.route {
+			return a.route < c.route
+		}
+		if a.method != c.method {
+			return a.method < c.method
+		}
+		return a.status < c.status
+	})
+
+	b.WriteString("# HELP http_requests_total Number of HTTP requests handled.\n")
+	b.WriteString("# TYPE http_requests_total counter\n")
+	for _, key := range requestKeys {
+		fmt.Fprintf(&b, "http_requests_total{method=%s,route=%s,status=\"%d\"} %d\n",
+			quote(key.method), quote(key.route), key.status, r.requests[key])
+	}
+
+	// Request duration histograms
+	durationKeys := make([]durationKey, 0, len(r.durations))
+	for key := range r.durations {
+		durationKeys = append(durationKeys, key)
+	}
+	sort.Slice(durationKeys, func(i, j int) bool {
+		a, c := durationKeys[i], durationKeys[j]
+		if a.route != c.route {
+			return a.route < c.route
+		}
+		return a.method < c.method
+	})
+
+	b.WriteString("# HELP http_request_duration_seconds Duration of HTTP requests in seconds.\n")
+	b.WriteString("# TYPE http_request_duration_seconds histogram\n")
+	for _, key := range durationKeys {
+		h := r.durations[key]
+		labels := fmt.Sprintf("method=%s,route=%s", quote(key.method), quote(key.route))
+		for i, upper := range r.buckets {
+			fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				labels, strconv.FormatFloat(upper, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(&b, "http_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quote quotes a label value, escaping backslashes, double quotes and newlines
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return `"` + value + `"`
+}
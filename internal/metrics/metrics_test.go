@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteText(t *testing.T) {
+	registry := NewRegistry()
+	registry.ObserveRequest("GET", "/lugares/{id}", 200, 20*time.Millisecond)
+	registry.ObserveRequest("GET", "/lugares/{id}", 200, 300*time.Millisecond)
+	registry.ObserveRequest("GET", "/lugares/{id}", 404, 3*time.Millisecond)
+	registry.ObserveRequest("POST", "/cancoes", 201, 12*time.Second)
+	registry.ObserveRequest("GET", `/odd"route`, 200, time.Millisecond)
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	text := out.String()
+
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "counter help", line: "# HELP http_requests_total Number of HTTP requests handled."},
+		{name: "counter type", line: "# TYPE http_requests_total counter"},
+		{name: "counter by status", line: `http_requests_total{method="GET",route="/lugares/{id}",status="200"} 2`},
+		{name: "counter of another status", line: `http_requests_total{method="GET",route="/lugares/{id}",status="404"} 1`},
+		{name: "escaped label", line: `http_requests_total{method="GET",route="/odd\"route",status="200"} 1`},
+		{name: "histogram type", line: "# TYPE http_request_duration_seconds histogram"},
+		{name: "cumulative bucket", line: `http_request_duration_seconds_bucket{method="GET",route="/lugares/{id}",le="0.025"} 2`},
+		{name: "larger bucket", line: `http_request_duration_seconds_bucket{method="GET",route="/lugares/{id}",le="0.5"} 3`},
+		{name: "infinite bucket", line: `http_request_duration_seconds_bucket{method="POST",route="/cancoes",le="+Inf"} 1`},
+		{name: "observation over the largest bucket", line: `http_request_duration_seconds_bucket{method="POST",route="/cancoes",le="10"} 0`},
+		{name: "histogram count", line: `http_request_duration_seconds_count{method="GET",route="/lugares/{id}"} 3`},
+		{name: "histogram sum", line: `http_request_duration_seconds_sum{method="POST",route="/cancoes"} 12`},
+	}
+
+	lines := map[string]bool{}
+	for _, line := range strings.Split(text, "\n") {
+		lines[line] = true
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !lines[tt.line] {
+				t.Errorf("missing line %q in:\n%s", tt.line, text)
+			}
+		})
+	}
+}
+
+func TestWriteTextEmpty(t *testing.T) {
+	var out strings.Builder
+	if err := NewRegistry().WriteText(&out); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	for _, name := range []string{"http_requests_total", "http_request_duration_seconds"} {
+		if !strings.Contains(out.String(), "# TYPE "+name+" ") {
+			t.Errorf("empty registry output lacks the %s type:\n%s", name, out.String())
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditEntry represents a change made to a resource, as recorded in the audit log
+type AuditEntry struct {
+	ID        int       `json:"id" db:"id"`
+	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+	Action    string    `json:"action" db:"action"`
+	UserID    *int      `json:"user_id,omitempty" db:"user_id"`
+	RequestID string    `json:"request_id,omitempty" db:"request_id"`
+
+	// Changes maps each changed field to its "from" and "to" values, when recorded
+	Changes map[string]interface{} `json:"changes,omitempty" db:"changes"`
+}
+
+// LogRecord is an entry of the API logs table, as written by the database logger
+type LogRecord struct {
+	ID           int                    `json:"id" db:"id"`
+	EntryID      string                 `json:"entry_id,omitempty" db:"entry_id"`
+	Timestamp    time.Time              `json:"timestamp" db:"timestamp"`
+	Level        string                 `json:"level" db:"level"`
+	Message      string                 `json:"message" db:"message"`
+	ServiceName  string                 `json:"service_name" db:"service_name"`
+	RequestID    string                 `json:"request_id,omitempty" db:"request_id"`
+	UserID       *int                   `json:"user_id,omitempty" db:"user_id"`
+	Action       string                 `json:"action,omitempty" db:"action"`
+	Resource     string                 `json:"resource,omitempty" db:"resource"`
+	ResourceID   string                 `json:"resource_id,omitempty" db:"resource_id"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty" db:"metadata"`
+	ErrorMessage string                 `json:"error_message,omitempty" db:"error_message"`
+}
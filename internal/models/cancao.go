@@ -6,22 +6,33 @@ import (
 
 // Cancao represents a song in the system
 type Cancao struct {
-	ID          int       `json:"id" db:"id"`
-	Nome        string    `json:"nome" db:"nome"`
-	LinkYoutube string    `json:"link_youtube" db:"link_youtube"`
-	Letra       string    `json:"letra" db:"letra"`
-	UserID      int       `json:"user_id" db:"user_id"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          int        `json:"id" db:"id"`
+	Nome        string     `json:"nome" db:"nome"`
+	LinkYoutube string     `json:"link_youtube" db:"link_youtube"`
+	Letra       string     `json:"letra" db:"letra"`
+	PlayCount   int        `json:"play_count" db:"play_count"`
+	UserID      int        `json:"user_id" db:"user_id"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 
 	// Related entities (not stored in the database directly)
 	Tags  []*TagCancao `json:"tags,omitempty" db:"-"`
 	Ramos []*Ramo      `json:"ramos,omitempty" db:"-"`
 }
 
+// RelatedCancao is a song sharing tags or ramos with another one
+type RelatedCancao struct {
+	*Cancao
+	SharedTags  int `json:"shared_tags"`
+	SharedRamos int `json:"shared_ramos"`
+	// Overlap is the number of shared tags plus shared ramos
+	Overlap int `json:"overlap"`
+}
+
 // NewCancao creates a new song with default values
 func NewCancao(nome, linkYoutube, letra string, userID int) *Cancao {
-	now := time.Now()
+	now := time.Now().UTC()
 	return &Cancao{
 		Nome:        nome,
 		LinkYoutube: linkYoutube,
@@ -1,24 +1,30 @@
 package models
 
 import (
+	"math"
+	"strconv"
 	"time"
 )
 
 // Lugar represents a place in the system
 type Lugar struct {
-	ID                  int       `json:"id" db:"id"`
-	NomeLocal           string    `json:"nome_local" db:"nome_local"`
-	NomeDonoLocal       string    `json:"nome_dono_local" db:"nome_dono_local"`
-	TelefoneParaContato int64     `json:"telefone_para_contato" db:"telefone_para_contato"`
-	LinkGoogleMaps      string    `json:"link_google_maps" db:"link_google_maps"`
-	LinkSite            string    `json:"link_site" db:"link_site"`
-	EnderecoCompleto    string    `json:"endereco_completo" db:"endereco_completo"`
-	LocalPublico        bool      `json:"local_publico" db:"local_publico"`
-	ValorFixo           float64   `json:"valor_fixo" db:"valor_fixo"`
-	ValorIndividual     float64   `json:"valor_individual" db:"valor_individual"`
-	UserID              int       `json:"user_id" db:"user_id"`
-	CreatedAt           time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+	ID                  int        `json:"id" db:"id"`
+	NomeLocal           string     `json:"nome_local" db:"nome_local"`
+	NomeDonoLocal       string     `json:"nome_dono_local" db:"nome_dono_local"`
+	TelefoneParaContato int64      `json:"telefone_para_contato" db:"telefone_para_contato"`
+	LinkGoogleMaps      string     `json:"link_google_maps" db:"link_google_maps"`
+	LinkSite            string     `json:"link_site" db:"link_site"`
+	EnderecoCompleto    string     `json:"endereco_completo" db:"endereco_completo"`
+	LocalPublico        bool       `json:"local_publico" db:"local_publico"`
+	ValorFixo           *float64   `json:"valor_fixo" db:"valor_fixo"`             // nil when not specified; 0 means free
+	ValorIndividual     *float64   `json:"valor_individual" db:"valor_individual"` // nil when not specified; 0 means free
+	Latitude            *float64   `json:"latitude,omitempty" db:"latitude"`
+	Longitude           *float64   `json:"longitude,omitempty" db:"longitude"`
+	Published           bool       `json:"published" db:"published"`
+	UserID              int        `json:"user_id" db:"user_id"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt           *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 
 	// Related entities (not stored in the database directly)
 	Images []*LugarImage `json:"images,omitempty" db:"-"`
@@ -26,8 +32,24 @@ type Lugar struct {
 	Ramos  []*Ramo       `json:"ramos,omitempty" db:"-"`
 
 	// Calculated fields from the materialized view
-	AverageRating float64 `json:"average_rating,omitempty" db:"average_rating"`
-	RatingCount   int     `json:"rating_count,omitempty" db:"rating_count"`
+	AverageRating AverageRating `json:"average_rating,omitempty" db:"average_rating"`
+	RatingCount   int           `json:"rating_count,omitempty" db:"rating_count"`
+
+	// TelefoneFormatado is the phone formatted by FormatPhone, set only when a client asks for it
+	TelefoneFormatado string `json:"telefone_formatado,omitempty" db:"-"`
+
+	// DistanciaKm is the distance to a searched point, set only by distance searches
+	DistanciaKm *float64 `json:"distancia_km,omitempty" db:"-"`
+}
+
+// AverageRating is an average rating. It keeps its full precision, but is
+// written to JSON rounded to one decimal place so every client shows the same value.
+type AverageRating float64
+
+// MarshalJSON writes the average rounded to one decimal place
+func (a AverageRating) MarshalJSON() ([]byte, error) {
+	rounded := math.Round(float64(a)*10) / 10
+	return []byte(strconv.FormatFloat(rounded, 'f', -1, 64)), nil
 }
 
 // LugarImage represents an image associated with a place
@@ -46,6 +68,43 @@ type LugarRating struct {
 	UserID  int       `json:"user_id" db:"user_id"`
 	Rating  int       `json:"rating" db:"rating"`
 	Date    time.Time `json:"date" db:"date"`
+	// Version is incremented on every update, for optimistic concurrency
+	Version int `json:"version" db:"version"`
+}
+
+// RecentRating is a rating along with the name of the rated place
+type RecentRating struct {
+	LugarRating
+	LugarNome string `json:"lugar_nome" db:"nome_local"`
+}
+
+// ImageWithLugar is an image along with the name of its place
+type ImageWithLugar struct {
+	LugarImage
+	LugarNome string `json:"lugar_nome" db:"nome_local"`
+}
+
+// SimilarLugar is a place sharing tags or ramos with another one
+type SimilarLugar struct {
+	*Lugar
+	SharedTags  []*TagLugar `json:"shared_tags"`
+	SharedRamos int         `json:"shared_ramos"`
+	// Overlap is the number of shared tags plus shared ramos
+	Overlap int `json:"overlap"`
+}
+
+// RatingDistribution represents how many ratings were given for each star value
+type RatingDistribution struct {
+	Counts  map[int]int `json:"counts"`
+	Total   int         `json:"total"`
+	Average float64     `json:"average"`
+}
+
+// RatingSummary is the average rating and number of ratings of a place
+type RatingSummary struct {
+	LugarID       int           `json:"lugar_id"`
+	AverageRating AverageRating `json:"average_rating"`
+	RatingCount   int           `json:"rating_count"`
 }
 
 // NewLugar creates a new place with default values
@@ -54,10 +113,10 @@ func NewLugar(
 	telefoneParaContato int64,
 	linkGoogleMaps, linkSite, enderecoCompleto string,
 	localPublico bool,
-	valorFixo, valorIndividual float64,
+	valorFixo, valorIndividual *float64,
 	userID int,
 ) *Lugar {
-	now := time.Now()
+	now := time.Now().UTC()
 	return &Lugar{
 		NomeLocal:           nomeLocal,
 		NomeDonoLocal:       nomeDonoLocal,
@@ -80,7 +139,7 @@ func NewLugarImage(lugarID int, imageURL string, displayOrder int) *LugarImage {
 		LugarID:      lugarID,
 		ImageURL:     imageURL,
 		DisplayOrder: displayOrder,
-		CreatedAt:    time.Now(),
+		CreatedAt:    time.Now().UTC(),
 	}
 }
 
@@ -90,6 +149,106 @@ func NewLugarRating(lugarID, userID, rating int) *LugarRating {
 		LugarID: lugarID,
 		UserID:  userID,
 		Rating:  rating,
-		Date:    time.Now(),
+		Date:    time.Now().UTC(),
+	}
+}
+
+// RatingDateClockSkew is how far in the future a client-supplied rating date may be
+const RatingDateClockSkew = 5 * time.Minute
+
+// MinRatingDate is the earliest accepted rating date
+var MinRatingDate = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// IsValidRatingDate checks that a rating date is neither in the future nor before MinRatingDate
+func IsValidRatingDate(date, now time.Time) bool {
+	return !date.Before(MinRatingDate) && !date.After(now.Add(RatingDateClockSkew))
+}
+
+// Warnings describes non-fatal issues with the data of a place: it is
+// accepted, but missing information users are likely to need
+func (l *Lugar) Warnings() []string {
+	warnings := []string{}
+	if l.TelefoneParaContato == 0 && l.LinkSite == "" {
+		warnings = append(warnings, "No contact information: telefone_para_contato and link_site are empty")
 	}
+	if l.EnderecoCompleto == "" && l.LinkGoogleMaps == "" && (l.Latitude == nil || l.Longitude == nil) {
+		warnings = append(warnings, "No location: endereco_completo, link_google_maps and coordinates are empty")
+	}
+	if !l.LocalPublico && l.NomeDonoLocal == "" {
+		warnings = append(warnings, "Private place without nome_dono_local")
+	}
+	return warnings
+}
+
+// LugarRamoRule decides whether a ramo may be added to a place, returning an
+// error that explains why when it may not
+type LugarRamoRule func(l *Lugar, ramo *Ramo) error
+
+// LugarRamoRules are the rules checked by CanAddRamo. There are none by
+// default, so every ramo is allowed; a deployment restricting the ramos of
+// its places appends its rules at startup.
+var LugarRamoRules []LugarRamoRule
+
+// CanAddRamo checks a ramo against LugarRamoRules and returns the error of
+// the first rule disallowing it
+func (l *Lugar) CanAddRamo(ramo *Ramo) error {
+	for _, rule := range LugarRamoRules {
+		if err := rule(l, ramo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// earthRadiusKm is the mean radius of the Earth used by DistanceKm
+const earthRadiusKm = 6371.0
+
+// DistanceKm returns the great-circle (haversine) distance in kilometers
+// between two points given in degrees
+func DistanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLng := toRadians(lng2 - lng1)
+	a := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// FormatPhone formats a Brazilian phone stored as digits as (DD) XXXXX-XXXX
+// for mobiles or (DD) XXXX-XXXX for landlines, dropping a leading 55 country
+// code. Numbers of any other length are returned as plain digits, and 0 as "".
+func FormatPhone(phone int64) string {
+	if phone <= 0 {
+		return ""
+	}
+
+	digits := strconv.FormatInt(phone, 10)
+	if (len(digits) == 12 || len(digits) == 13) && digits[:2] == "55" {
+		digits = digits[2:]
+	}
+
+	switch len(digits) {
+	case 11:
+		return "(" + digits[:2] + ") " + digits[2:7] + "-" + digits[7:]
+	case 10:
+		return "(" + digits[:2] + ") " + digits[2:6] + "-" + digits[6:]
+	default:
+		return digits
+	}
+}
+
+// DailyCount is the number of items created on a day (YYYY-MM-DD, UTC)
+type DailyCount struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// LugarImportResult is the outcome of importing one place of a batch
+type LugarImportResult struct {
+	Index int    `json:"index"`
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Err is the underlying error behind Error, for logging; it is never sent to clients
+	Err error `json:"-"`
 }
@@ -0,0 +1,266 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLugarWarnings(t *testing.T) {
+	lat, lng := -23.5, -46.6
+	complete := func() *Lugar {
+		return &Lugar{
+			NomeLocal:           "Sítio Alegre",
+			NomeDonoLocal:       "Maria",
+			TelefoneParaContato: 11987654321,
+			LinkSite:            "https://sitioalegre.com.br",
+			EnderecoCompleto:    "Estrada do Sítio, 100",
+		}
+	}
+
+	tests := []struct {
+		name  string
+		lugar func() *Lugar
+		want  []string
+	}{
+		{name: "complete lugar", lugar: complete, want: []string{}},
+		{
+			name:  "sparse lugar",
+			lugar: func() *Lugar { return &Lugar{NomeLocal: "Sítio"} },
+			want: []string{
+				"No contact information: telefone_para_contato and link_site are empty",
+				"No location: endereco_completo, link_google_maps and coordinates are empty",
+				"Private place without nome_dono_local",
+			},
+		},
+		{
+			name:  "phone is enough contact",
+			lugar: func() *Lugar { l := complete(); l.LinkSite = ""; return l },
+			want:  []string{},
+		},
+		{
+			name: "coordinates are enough location",
+			lugar: func() *Lugar {
+				l := complete()
+				l.EnderecoCompleto = ""
+				l.Latitude, l.Longitude = &lat, &lng
+				return l
+			},
+			want: []string{},
+		},
+		{
+			name:  "latitude alone is no location",
+			lugar: func() *Lugar { l := complete(); l.EnderecoCompleto = ""; l.Latitude = &lat; return l },
+			want:  []string{"No location: endereco_completo, link_google_maps and coordinates are empty"},
+		},
+		{
+			name:  "public place needs no owner",
+			lugar: func() *Lugar { l := complete(); l.NomeDonoLocal = ""; l.LocalPublico = true; return l },
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.lugar().Warnings(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Warnings() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLugarValorJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantFixo *float64
+		wantJSON string
+	}{
+		{name: "omitted", body: `{"nome_local": "Sítio"}`, wantFixo: nil, wantJSON: `"valor_fixo":null`},
+		{name: "null", body: `{"nome_local": "Sítio", "valor_fixo": null}`, wantFixo: nil, wantJSON: `"valor_fixo":null`},
+		{name: "explicit zero", body: `{"nome_local": "Sítio", "valor_fixo": 0}`, wantFixo: float64Ptr(0), wantJSON: `"valor_fixo":0`},
+		{name: "price", body: `{"nome_local": "Sítio", "valor_fixo": 25.5}`, wantFixo: float64Ptr(25.5), wantJSON: `"valor_fixo":25.5`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lugar Lugar
+			if err := json.Unmarshal([]byte(tt.body), &lugar); err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			if !reflect.DeepEqual(lugar.ValorFixo, tt.wantFixo) {
+				t.Errorf("valor_fixo = %v, want %v", lugar.ValorFixo, tt.wantFixo)
+			}
+
+			encoded, err := json.Marshal(&lugar)
+			if err != nil {
+				t.Fatalf("encoding: %v", err)
+			}
+			if !strings.Contains(string(encoded), tt.wantJSON) {
+				t.Errorf("encoded %s, want it to contain %s", encoded, tt.wantJSON)
+			}
+		})
+	}
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func TestAverageRatingJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		average AverageRating
+		want    string
+	}{
+		{name: "repeating decimal", average: 13.0 / 3, want: "4.3"},
+		{name: "rounds up", average: 4.96, want: "5"},
+		{name: "half rounds away from zero", average: 4.25, want: "4.3"},
+		{name: "already one decimal", average: 3.5, want: "3.5"},
+		{name: "whole number", average: 4, want: "4"},
+		{name: "zero", average: 0, want: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := json.Marshal(tt.average)
+			if err != nil {
+				t.Fatalf("encoding: %v", err)
+			}
+			if string(encoded) != tt.want {
+				t.Errorf("%v encoded as %s, want %s", float64(tt.average), encoded, tt.want)
+			}
+		})
+	}
+
+	// The lugar JSON carries the rounded average, and the value keeps its precision for sorting
+	lugar := Lugar{NomeLocal: "Sítio", AverageRating: 13.0 / 3, RatingCount: 3}
+	encoded, err := json.Marshal(&lugar)
+	if err != nil {
+		t.Fatalf("encoding lugar: %v", err)
+	}
+	if !strings.Contains(string(encoded), `"average_rating":4.3,`) {
+		t.Errorf("lugar encoded as %s, want average_rating 4.3", encoded)
+	}
+	if lugar.AverageRating <= 4.33 {
+		t.Errorf("average = %v, want the full precision kept", float64(lugar.AverageRating))
+	}
+	if encoded, _ := json.Marshal(&Lugar{NomeLocal: "Sítio"}); strings.Contains(string(encoded), "average_rating") {
+		t.Errorf("unrated lugar encoded as %s, want average_rating omitted", encoded)
+	}
+}
+
+func TestFormatPhone(t *testing.T) {
+	tests := []struct {
+		name  string
+		phone int64
+		want  string
+	}{
+		{"mobile", 11987654321, "(11) 98765-4321"},
+		{"landline", 1133334444, "(11) 3333-4444"},
+		{"mobile with country code", 5511987654321, "(11) 98765-4321"},
+		{"landline with country code", 551133334444, "(11) 3333-4444"},
+		{"twelve digits without country code", 121133334444, "121133334444"},
+		{"too short", 987654321, "987654321"},
+		{"too long", 12345678901234, "12345678901234"},
+		{"not set", 0, ""},
+		{"negative", -11987654321, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatPhone(tt.phone); got != tt.want {
+				t.Errorf("FormatPhone(%d) = %q, want %q", tt.phone, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTimestampsAreUTC(t *testing.T) {
+	tests := []struct {
+		name  string
+		times func() []time.Time
+	}{
+		{"lugar", func() []time.Time {
+			lugar := NewLugar("Sítio", "Ana", 0, "", "", "", false, nil, nil, 1)
+			return []time.Time{lugar.CreatedAt, lugar.UpdatedAt}
+		}},
+		{"lugar image", func() []time.Time { return []time.Time{NewLugarImage(1, "https://example.com/1.jpg", 1).CreatedAt} }},
+		{"lugar rating", func() []time.Time { return []time.Time{NewLugarRating(1, 1, 5).Date} }},
+		{"cancao", func() []time.Time {
+			cancao := NewCancao("Canção", "", "", 1)
+			return []time.Time{cancao.CreatedAt, cancao.UpdatedAt}
+		}},
+		{"ramo", func() []time.Time { return []time.Time{NewRamo("lobinho").CreatedAt} }},
+		{"lugar tag", func() []time.Time { return []time.Time{NewTagLugar("rio").CreatedAt} }},
+		{"cancao tag", func() []time.Time { return []time.Time{NewTagCancao("fogo").CreatedAt} }},
+		{"user", func() []time.Time {
+			user := NewUser("ana", "hash", RoleRead)
+			return []time.Time{user.CreatedAt, user.UpdatedAt}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, got := range tt.times() {
+				if got.Location() != time.UTC {
+					t.Errorf("timestamp %d = %v, want a UTC time", i, got)
+				}
+				if got.IsZero() {
+					t.Errorf("timestamp %d was not set", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCanAddRamo(t *testing.T) {
+	blockLobinho := func(l *Lugar, ramo *Ramo) error {
+		if ramo.Name == "lobinho" {
+			return errors.New("lobinho is not allowed at this place")
+		}
+		return nil
+	}
+	blockPrivate := func(l *Lugar, ramo *Ramo) error {
+		if !l.LocalPublico {
+			return errors.New("only public places")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		rules   []LugarRamoRule
+		lugar   *Lugar
+		ramo    *Ramo
+		wantErr string
+	}{
+		{name: "no rules allow every ramo", lugar: &Lugar{}, ramo: &Ramo{Name: "lobinho"}},
+		{name: "ramo allowed by the rule", rules: []LugarRamoRule{blockLobinho}, lugar: &Lugar{}, ramo: &Ramo{Name: "pioneiro"}},
+		{name: "ramo blocked by the rule", rules: []LugarRamoRule{blockLobinho}, lugar: &Lugar{}, ramo: &Ramo{Name: "lobinho"}, wantErr: "lobinho is not allowed"},
+		{name: "first failing rule wins", rules: []LugarRamoRule{blockPrivate, blockLobinho}, lugar: &Lugar{}, ramo: &Ramo{Name: "lobinho"}, wantErr: "only public places"},
+		{name: "every rule must pass", rules: []LugarRamoRule{blockPrivate, blockLobinho}, lugar: &Lugar{LocalPublico: true}, ramo: &Ramo{Name: "lobinho"}, wantErr: "lobinho is not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := LugarRamoRules
+			LugarRamoRules = tt.rules
+			defer func() { LugarRamoRules = previous }()
+
+			err := tt.lugar.CanAddRamo(tt.ramo)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CanAddRamo() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CanAddRamo() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
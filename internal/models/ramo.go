@@ -15,10 +15,19 @@ type Ramo struct {
 func NewRamo(name string) *Ramo {
 	return &Ramo{
 		Name:      name,
-		CreatedAt: time.Now(),
+		CreatedAt: time.Now().UTC(),
 	}
 }
 
+// RamoCoverage represents the amount of content associated with a ramo
+type RamoCoverage struct {
+	Ramo
+	LugarCount  int `json:"lugar_count" db:"lugar_count"`
+	CancaoCount int `json:"cancao_count" db:"cancao_count"`
+	// UnderCovered is set when the ramo has fewer lugares or cancoes than the requested threshold
+	UnderCovered bool `json:"under_covered" db:"under_covered"`
+}
+
 // LugarRamo represents the many-to-many relationship between lugares and ramos
 type LugarRamo struct {
 	LugarID int `json:"lugar_id" db:"lugar_id"`
@@ -0,0 +1,7 @@
+package models
+
+// SelectOption is the ID and display name of an item, for select inputs
+type SelectOption struct {
+	ID    int    `json:"id" db:"id"`
+	Label string `json:"label" db:"label"`
+}
@@ -22,7 +22,7 @@ type TagCancao struct {
 func NewTagLugar(name string) *TagLugar {
 	return &TagLugar{
 		Name:      name,
-		CreatedAt: time.Now(),
+		CreatedAt: time.Now().UTC(),
 	}
 }
 
@@ -30,7 +30,7 @@ func NewTagLugar(name string) *TagLugar {
 func NewTagCancao(name string) *TagCancao {
 	return &TagCancao{
 		Name:      name,
-		CreatedAt: time.Now(),
+		CreatedAt: time.Now().UTC(),
 	}
 }
 
@@ -1,7 +1,11 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // User represents a user in the system
@@ -26,7 +30,7 @@ const (
 
 // NewUser creates a new user with default values
 func NewUser(username, password string, role UserRole) *User {
-	now := time.Now()
+	now := time.Now().UTC()
 	return &User{
 		Username:  username,
 		Password:  password, // Note: In a real application, this should be hashed
@@ -36,12 +40,98 @@ func NewUser(username, password string, role UserRole) *User {
 	}
 }
 
+// HashPassword hashes a plain text password with bcrypt
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
 // IsValidRole checks if the role is valid
 func IsValidRole(role string) bool {
 	return role == string(RoleRead) || role == string(RoleWrite)
 }
 
+const (
+	// MinUsernameLength is the minimum number of characters of a username
+	MinUsernameLength = 3
+	// MaxUsernameLength is the maximum number of characters of a username
+	MaxUsernameLength = 50
+)
+
+// ValidateUsername checks that a username has between MinUsernameLength and
+// MaxUsernameLength characters, only uses ASCII letters, digits, '_', '.' and '-',
+// and starts and ends with a letter or digit. The error describes the first problem found.
+func ValidateUsername(username string) error {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return fmt.Errorf("username must have between %d and %d characters", MinUsernameLength, MaxUsernameLength)
+	}
+
+	// Count characters for the position, as the index of a range over a string is a byte offset
+	position := 0
+	for _, c := range username {
+		position++
+		if !isUsernameChar(c) {
+			return fmt.Errorf("username contains invalid character %q at position %d; only letters, digits, '_', '.' and '-' are allowed", c, position)
+		}
+	}
+
+	if !isAlphanumeric(rune(username[0])) || !isAlphanumeric(rune(username[len(username)-1])) {
+		return errors.New("username must start and end with a letter or digit")
+	}
+
+	return nil
+}
+
+// isUsernameChar checks if a character is allowed in a username
+func isUsernameChar(c rune) bool {
+	return isAlphanumeric(c) || c == '_' || c == '.' || c == '-'
+}
+
+// isAlphanumeric checks if a character is an ASCII letter or digit
+func isAlphanumeric(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
 // HasWriteAccess checks if the user has write access
 func (u *User) HasWriteAccess() bool {
 	return u.Role == string(RoleWrite)
-}
\ No newline at end of file
+}
+
+// Permissions lists the capabilities of the user's role: "read" for every
+// user, plus "write" for users with write access
+func (u *User) Permissions() []string {
+	permissions := []string{string(RoleRead)}
+	if u.HasWriteAccess() {
+		permissions = append(permissions, string(RoleWrite))
+	}
+	return permissions
+}
+
+// UserPermissions is the authenticated user's role and capabilities
+type UserPermissions struct {
+	UserID      int      `json:"user_id"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+// UserContent holds the places and songs created by a user
+type UserContent struct {
+	Lugares []*Lugar  `json:"lugares"`
+	Cancoes []*Cancao `json:"cancoes"`
+}
+
+// AnonymizedUsername returns the username given to an anonymized user
+func AnonymizedUsername(id int) string {
+	return fmt.Sprintf("deleted_user_%d", id)
+}
+
+// UserWithCounts is a user along with the number of places, songs and ratings they created
+type UserWithCounts struct {
+	User
+	LugarCount  int `json:"lugar_count" db:"lugar_count"`
+	CancaoCount int `json:"cancao_count" db:"cancao_count"`
+	RatingCount int `json:"rating_count" db:"rating_count"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		wantErr  string
+	}{
+		{name: "letters", username: "ana"},
+		{name: "all allowed characters", username: "ana.maria_souza-2"},
+		{name: "digits at both ends", username: "1ana9"},
+		{name: "minimum length", username: strings.Repeat("a", MinUsernameLength)},
+		{name: "maximum length", username: strings.Repeat("a", MaxUsernameLength)},
+		{name: "empty", username: "", wantErr: "between 3 and 50 characters"},
+		{name: "too short", username: "ab", wantErr: "between 3 and 50 characters"},
+		{name: "too long", username: strings.Repeat("a", MaxUsernameLength+1), wantErr: "between 3 and 50 characters"},
+		{name: "space", username: "ana maria", wantErr: `invalid character ' ' at position 4`},
+		{name: "control character", username: "ana\tmaria", wantErr: `invalid character '\t' at position 4`},
+		{name: "accented letter", username: "joão", wantErr: `invalid character 'ã' at position 3`},
+		{name: "accented letter before punctuation", username: "joão!", wantErr: `invalid character 'ã' at position 3`},
+		{name: "multi-byte letter first", username: "élise", wantErr: `invalid character 'é' at position 1`},
+		{name: "emoji in the middle", username: "ana🙂maria", wantErr: `invalid character '🙂' at position 4`},
+		{name: "at sign", username: "ana@geav", wantErr: `invalid character '@'`},
+		{name: "leading punctuation", username: ".ana", wantErr: "start and end with a letter or digit"},
+		{name: "trailing punctuation", username: "ana_", wantErr: "start and end with a letter or digit"},
+		{name: "leading hyphen", username: "-ana", wantErr: "start and end with a letter or digit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateUsername(tt.username)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateUsername(%q) = %v, want nil", tt.username, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateUsername(%q) = %v, want an error containing %q", tt.username, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	tests := []struct {
+		role string
+		want []string
+	}{
+		{role: "read", want: []string{"read"}},
+		{role: "write", want: []string{"read", "write"}},
+		{role: "", want: []string{"read"}},
+		{role: "WRITE", want: []string{"read"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			user := &User{Role: tt.role}
+			if got := user.Permissions(); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Permissions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+// auditContextKey is the context key of the AuditInfo of a change
+type auditContextKey struct{}
+
+// AuditInfo describes who made a change and what it changed. Handlers add it
+// to the context with WithAudit; the repositories record it in the audit log
+// in the same transaction as the change.
+type AuditInfo struct {
+	UserID    *int
+	RequestID string
+	// Changes maps each changed field to its "from" and "to" values
+	Changes map[string]interface{}
+}
+
+// WithAudit returns a copy of ctx carrying the audit information of the change
+// about to be made
+func WithAudit(ctx context.Context, info AuditInfo) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, info)
+}
+
+// recordAudit writes the audit log entry of a change within its transaction,
+// with the AuditInfo of the context
+func recordAudit(ctx context.Context, tx *sql.Tx, resource string, resourceID int, action string) error {
+	info, _ := ctx.Value(auditContextKey{}).(AuditInfo)
+
+	var changes []byte
+	if len(info.Changes) > 0 {
+		var err error
+		if changes, err = json.Marshal(info.Changes); err != nil {
+			return fmt.Errorf("error encoding audit changes: %w", err)
+		}
+	}
+
+	var requestID sql.NullString
+	if info.RequestID != "" {
+		requestID = sql.NullString{String: info.RequestID, Valid: true}
+	}
+
+	query := `
+		INSERT INTO audit_log (resource, resource_id, action, user_id, request_id, changes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	if _, err := tx.ExecContext(ctx, query, resource, resourceID, action, info.UserID, requestID, changes); err != nil {
+		return fmt.Errorf("error recording audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// PostgresAuditRepository is an implementation of AuditRepository using PostgreSQL
+type PostgresAuditRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresAuditRepository creates a new PostgresAuditRepository
+func NewPostgresAuditRepository(db *sql.DB) *PostgresAuditRepository {
+	return &PostgresAuditRepository{db: db}
+}
+
+// ListHistory retrieves the audit log entries of a resource, oldest first
+func (r *PostgresAuditRepository) ListHistory(ctx context.Context, resource string, resourceID int) ([]*models.AuditEntry, error) {
+	query := `
+		SELECT id, timestamp, action, user_id, COALESCE(request_id, ''), changes
+		FROM audit_log
+		WHERE resource = $1 AND resource_id = $2
+		ORDER BY timestamp, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, resource, resourceID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var userID sql.NullInt64
+		var changes []byte
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Timestamp,
+			&entry.Action,
+			&userID,
+			&entry.RequestID,
+			&changes,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning history entry: %w", err)
+		}
+
+		if userID.Valid {
+			id := int(userID.Int64)
+			entry.UserID = &id
+		}
+
+		if len(changes) > 0 {
+			if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+				return nil, fmt.Errorf("error decoding history changes: %w", err)
+			}
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating history rows: %w", err)
+	}
+
+	return entries, nil
+}
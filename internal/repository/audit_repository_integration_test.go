@@ -0,0 +1,197 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+func TestChangesAreAudited(t *testing.T) {
+	adminID := 1
+	info := AuditInfo{
+		UserID:    &adminID,
+		RequestID: "req-1",
+		Changes:   map[string]interface{}{"published": map[string]interface{}{"from": false, "to": true}},
+	}
+
+	tests := []struct {
+		name       string
+		change     func(ctx context.Context, repo *PostgresLugarRepository, id int) error
+		wantAction string
+		wantErr    bool
+	}{
+		{
+			name: "update",
+			change: func(ctx context.Context, repo *PostgresLugarRepository, id int) error {
+				return repo.Update(ctx, &models.Lugar{ID: id, NomeLocal: "Sítio Novo", UserID: 1})
+			},
+			wantAction: "UpdateLugar",
+		},
+		{
+			name: "owner change",
+			change: func(ctx context.Context, repo *PostgresLugarRepository, id int) error {
+				return repo.ChangeOwner(ctx, id, 2)
+			},
+			wantAction: "ChangeLugarOwner",
+		},
+		{
+			name: "publication",
+			change: func(ctx context.Context, repo *PostgresLugarRepository, id int) error {
+				return repo.Publish(ctx, id)
+			},
+			wantAction: "PublishLugar",
+		},
+		{
+			name: "deletion",
+			change: func(ctx context.Context, repo *PostgresLugarRepository, id int) error {
+				return repo.Delete(ctx, id)
+			},
+			wantAction: "DeleteLugar",
+		},
+		{
+			name: "failed change is not audited",
+			change: func(ctx context.Context, repo *PostgresLugarRepository, id int) error {
+				return repo.ChangeOwner(ctx, id, 999)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresLugarRepository(db)
+			id := insertTestLugar(t, db, "Sítio")
+
+			err := tt.change(WithAudit(context.Background(), info), repo, id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("change error = %v, want error %v", err, tt.wantErr)
+			}
+
+			entries, err := NewPostgresAuditRepository(db).ListHistory(context.Background(), "lugares", id)
+			if err != nil {
+				t.Fatalf("ListHistory: %v", err)
+			}
+			if tt.wantErr {
+				if len(entries) != 0 {
+					t.Errorf("failed change left %d audit entries", len(entries))
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d audit entries, want 1", len(entries))
+			}
+			entry := entries[0]
+			if entry.Action != tt.wantAction || entry.UserID == nil || *entry.UserID != adminID || entry.RequestID != "req-1" {
+				t.Errorf("entry = %s by %v in %q, want %s by %d in req-1", entry.Action, entry.UserID, entry.RequestID, tt.wantAction, adminID)
+			}
+			if _, ok := entry.Changes["published"]; !ok {
+				t.Errorf("changes = %v, want the changes of the context", entry.Changes)
+			}
+		})
+	}
+}
+
+func TestCreateIsAuditedWithoutAuditInfo(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+
+	id, err := repo.Create(context.Background(), &models.Lugar{NomeLocal: "Sítio", UserID: 1})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	entries, err := NewPostgresAuditRepository(db).ListHistory(context.Background(), "lugares", id)
+	if err != nil {
+		t.Fatalf("ListHistory: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != "CreateLugar" || entries[0].UserID != nil || entries[0].Changes != nil {
+		t.Errorf("entries = %+v, want one anonymous CreateLugar entry without changes", entries)
+	}
+}
+
+func TestListHistory(t *testing.T) {
+	db := newTestDB(t)
+	audit := NewPostgresAuditRepository(db)
+
+	insert := func(resource string, resourceID int, action, timestamp string) {
+		mustExec(t, db, `INSERT INTO audit_log (resource, resource_id, action, timestamp) VALUES ($1, $2, $3, $4)`, resource, resourceID, action, timestamp)
+	}
+	insert("lugares", 7, "UpdateLugar", "2024-03-02")
+	insert("lugares", 7, "CreateLugar", "2024-03-01")
+	insert("lugares", 8, "CreateLugar", "2024-03-01")
+	insert("cancoes", 7, "CreateCancao", "2024-03-01")
+	insert("lugares", 7, "DeleteLugar", "2024-03-03")
+
+	tests := []struct {
+		name       string
+		resource   string
+		resourceID int
+		want       []string
+	}{
+		{name: "scoped to the lugar and oldest first", resource: "lugares", resourceID: 7, want: []string{"CreateLugar", "UpdateLugar", "DeleteLugar"}},
+		{name: "another lugar", resource: "lugares", resourceID: 8, want: []string{"CreateLugar"}},
+		{name: "no history", resource: "lugares", resourceID: 9, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := audit.ListHistory(context.Background(), tt.resource, tt.resourceID)
+			if err != nil {
+				t.Fatalf("ListHistory: %v", err)
+			}
+			got := []string{}
+			for _, entry := range entries {
+				got = append(got, entry.Action)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("actions = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("actions = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// auditCount returns the number of audit log entries of a lugar
+func auditCount(t *testing.T, db *sql.DB, lugarID int) int {
+	t.Helper()
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE resource = 'lugares' AND resource_id = $1`, lugarID).Scan(&count); err != nil {
+		t.Fatalf("counting audit entries: %v", err)
+	}
+	return count
+}
+
+func TestImportIsAuditedPerLugar(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+
+	results, err := repo.Import(context.Background(), []*models.Lugar{
+		{NomeLocal: "Sítio", UserID: 1},
+		{NomeLocal: "Sem dono", UserID: 999},
+	})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if results[0].ID == 0 || results[1].Error == "" {
+		t.Fatalf("results = %+v, want the first imported and the second failed", results)
+	}
+	if got := auditCount(t, db, results[0].ID); got != 1 {
+		t.Errorf("imported lugar has %d audit entries, want 1", got)
+	}
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM audit_log`).Scan(&total); err != nil {
+		t.Fatalf("counting audit entries: %v", err)
+	}
+	if total != 1 {
+		t.Errorf("audit log has %d entries, want only the imported lugar's", total)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/site-geav-api/internal/models"
 )
 
@@ -19,25 +20,23 @@ func NewPostgresCancaoRepository(db *sql.DB) *PostgresCancaoRepository {
 	return &PostgresCancaoRepository{db: db}
 }
 
-// GetByID retrieves a song by ID
+// GetByID retrieves a song by ID, unless it was deleted
 func (r *PostgresCancaoRepository) GetByID(ctx context.Context, id int) (*models.Cancao, error) {
-	query := `
-		SELECT id, nome, link_youtube, letra, user_id, created_at, updated_at
-		FROM cancoes
-		WHERE id = $1
-	`
+	return r.getByID(ctx, id, false)
+}
 
-	var cancao models.Cancao
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&cancao.ID,
-		&cancao.Nome,
-		&cancao.LinkYoutube,
-		&cancao.Letra,
-		&cancao.UserID,
-		&cancao.CreatedAt,
-		&cancao.UpdatedAt,
-	)
+// GetByIDIncludingDeleted retrieves a song by ID, even if it was deleted
+func (r *PostgresCancaoRepository) GetByIDIncludingDeleted(ctx context.Context, id int) (*models.Cancao, error) {
+	return r.getByID(ctx, id, true)
+}
+
+// getByID retrieves a song by ID, optionally including deleted songs
+func (r *PostgresCancaoRepository) getByID(ctx context.Context, id int, includeDeleted bool) (*models.Cancao, error) {
+	query := cancaoSelect + `
+		WHERE id = $1 AND ($2 OR deleted_at IS NULL)
+	`
 
+	cancao, err := scanCancao(r.db.QueryRowContext(ctx, query, id, includeDeleted))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Return nil without error to indicate not found
@@ -59,38 +58,258 @@ func (r *PostgresCancaoRepository) GetByID(ctx context.Context, id int) (*models
 	}
 	cancao.Ramos = ramos
 
-	return &cancao, nil
+	return cancao, nil
+}
+
+// cancaoSelect is the base query for listing songs
+const cancaoSelect = `
+		SELECT id, nome,
+		       COALESCE(link_youtube, '') AS link_youtube,
+		       COALESCE(letra, '') AS letra,
+		       play_count, user_id, created_at, updated_at, deleted_at
+		FROM cancoes`
+
+// cancaoSortOrders maps the accepted sort values to their ORDER BY clauses
+var cancaoSortOrders = map[string]string{
+	"":      "id",
+	"plays": "play_count DESC, id",
+}
+
+// IsValidCancaoSort checks if the sort value is accepted when listing songs
+func IsValidCancaoSort(sort string) bool {
+	_, ok := cancaoSortOrders[sort]
+	return ok
 }
 
 // List retrieves all songs
-func (r *PostgresCancaoRepository) List(ctx context.Context) ([]*models.Cancao, error) {
+func (r *PostgresCancaoRepository) List(ctx context.Context, opts CancaoListOptions) ([]*models.Cancao, error) {
+	builder := listCancoesBuilder(opts)
+	if err := builder.OrderBy(opts.Sort, cancaoSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(opts.Pagination).Build()
+
+	return r.queryCancoes(ctx, query, args...)
+}
+
+// Count counts the songs matching the options, ignoring their sort and pagination
+func (r *PostgresCancaoRepository) Count(ctx context.Context, opts CancaoListOptions) (int, error) {
+	query, args := listCancoesBuilder(opts).BuildCount()
+	return countRows(ctx, r.db, query, args...)
+}
+
+// listCancoesBuilder builds the query selecting the songs matching the options
+func listCancoesBuilder(opts CancaoListOptions) *queryBuilder {
+	builder := newQueryBuilder(cancaoSelect)
+	if !opts.IncludeDeleted {
+		builder.Where("deleted_at IS NULL")
+	}
+	if len(opts.TagIDs) > 0 {
+		if opts.MatchAllTags {
+			tagIDs := uniqueInts(opts.TagIDs)
+			builder.Where(`(
+				SELECT COUNT(*)
+				FROM cancoes_tags ct
+				WHERE ct.cancao_id = cancoes.id AND ct.tag_id = ANY(?)
+			) = ?`, pq.Array(tagIDs), len(tagIDs))
+		} else {
+			builder.Where(`EXISTS (
+				SELECT 1
+				FROM cancoes_tags ct
+				WHERE ct.cancao_id = cancoes.id AND ct.tag_id = ANY(?)
+			)`, pq.Array(opts.TagIDs))
+		}
+	}
+	if opts.MissingYoutube {
+		builder.Where("TRIM(COALESCE(link_youtube, '')) = ''")
+	}
+	return builder
+}
+
+// ListByTags retrieves the songs with any of the given tags, or with all of them when matchAll is set
+func (r *PostgresCancaoRepository) ListByTags(ctx context.Context, tagIDs []int, matchAll bool, page Pagination) ([]*models.Cancao, error) {
+	return r.List(ctx, CancaoListOptions{TagIDs: tagIDs, MatchAllTags: matchAll, Pagination: page})
+}
+
+// uniqueInts returns the distinct values of ids, keeping their first occurrence order
+func uniqueInts(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// cancaoOptionOrders maps the accepted sort values of the song options to their ORDER BY clauses
+var cancaoOptionOrders = map[string]string{
+	"": "nome, id",
+}
+
+// ListSelectOptions lists the ID and name of the songs that were not deleted, by name
+func (r *PostgresCancaoRepository) ListSelectOptions(ctx context.Context, page Pagination) ([]*models.SelectOption, error) {
+	builder := newQueryBuilder(`
+		SELECT id, nome
+		FROM cancoes`).
+		Where("deleted_at IS NULL")
+	if err := builder.OrderBy("", cancaoOptionOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
+
+	return querySelectOptions(ctx, r.db, query, args...)
+}
+
+// ListByUser retrieves a page of the songs created by a user
+func (r *PostgresCancaoRepository) ListByUser(ctx context.Context, userID int, page Pagination) ([]*models.Cancao, error) {
+	builder := newQueryBuilder(cancaoSelect).
+		Where("user_id = ?", userID).
+		Where("deleted_at IS NULL")
+	if err := builder.OrderBy("", cancaoSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
+
+	return r.queryCancoes(ctx, query, args...)
+}
+
+// scanCancao scans a row selected with cancaoSelect into a song
+func scanCancao(row rowScanner) (*models.Cancao, error) {
+	var cancao models.Cancao
+	if err := row.Scan(
+		&cancao.ID,
+		&cancao.Nome,
+		&cancao.LinkYoutube,
+		&cancao.Letra,
+		&cancao.PlayCount,
+		&cancao.UserID,
+		&cancao.CreatedAt,
+		&cancao.UpdatedAt,
+		&cancao.DeletedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &cancao, nil
+}
+
+// ForEach calls fn for every song with an ID greater than afterID, in ID
+// order, one row at a time so the whole table is never held in memory. Tags
+// and ramos are not loaded. An error returned by fn stops the iteration and
+// is returned.
+func (r *PostgresCancaoRepository) ForEach(ctx context.Context, afterID int, fn func(*models.Cancao) error) error {
+	rows, err := r.db.QueryContext(ctx, cancaoSelect+`
+		WHERE deleted_at IS NULL AND id > $1
+		ORDER BY id`, afterID)
+	if err != nil {
+		return fmt.Errorf("error querying cancoes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		cancao, err := scanCancao(rows)
+		if err != nil {
+			return fmt.Errorf("error scanning cancao row: %w", err)
+		}
+		if err := fn(cancao); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating cancao rows: %w", err)
+	}
+
+	return nil
+}
+
+// ListRelated retrieves the songs sharing the most tags and ramos with the
+// given one, most shared first
+func (r *PostgresCancaoRepository) ListRelated(ctx context.Context, cancaoID, limit int) ([]*models.RelatedCancao, error) {
 	query := `
-		SELECT id, nome, link_youtube, letra, user_id, created_at, updated_at
-		FROM cancoes
-		ORDER BY id
+		SELECT shared.cancao_id, SUM(shared.tags) AS shared_tags, SUM(shared.ramos) AS shared_ramos
+		FROM (
+			SELECT ct2.cancao_id, 1 AS tags, 0 AS ramos
+			FROM cancoes_tags ct1
+			JOIN cancoes_tags ct2 ON ct2.tag_id = ct1.tag_id AND ct2.cancao_id <> ct1.cancao_id
+			WHERE ct1.cancao_id = $1
+			UNION ALL
+			SELECT cr2.cancao_id, 0 AS tags, 1 AS ramos
+			FROM cancoes_ramos cr1
+			JOIN cancoes_ramos cr2 ON cr2.ramo_id = cr1.ramo_id AND cr2.cancao_id <> cr1.cancao_id
+			WHERE cr1.cancao_id = $1
+		) shared
+		JOIN cancoes c ON c.id = shared.cancao_id AND c.deleted_at IS NULL
+		GROUP BY shared.cancao_id
+		ORDER BY SUM(shared.tags) + SUM(shared.ramos) DESC, SUM(shared.tags) DESC, shared.cancao_id
+		LIMIT $2
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, cancaoID, limit)
 	if err != nil {
-		return nil, fmt.Errorf("error listing cancoes: %w", err)
+		return nil, fmt.Errorf("error listing related cancoes: %w", err)
 	}
 	defer rows.Close()
 
-	var cancoes []*models.Cancao
+	related := []*models.RelatedCancao{}
+	var ids []int
 	for rows.Next() {
-		var cancao models.Cancao
-		if err := rows.Scan(
-			&cancao.ID,
-			&cancao.Nome,
-			&cancao.LinkYoutube,
-			&cancao.Letra,
-			&cancao.UserID,
-			&cancao.CreatedAt,
-			&cancao.UpdatedAt,
-		); err != nil {
+		item := &models.RelatedCancao{}
+		var id int
+		if err := rows.Scan(&id, &item.SharedTags, &item.SharedRamos); err != nil {
+			return nil, fmt.Errorf("error scanning related cancao row: %w", err)
+		}
+		item.Cancao = &models.Cancao{ID: id}
+		item.Overlap = item.SharedTags + item.SharedRamos
+		related = append(related, item)
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating related cancao rows: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return related, nil
+	}
+
+	// Load the songs, keeping the ranking
+	cancoes, err := r.queryCancoes(ctx, cancaoSelect+`
+		WHERE id = ANY($1)
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*models.Cancao, len(cancoes))
+	for _, cancao := range cancoes {
+		byID[cancao.ID] = cancao
+	}
+	for _, item := range related {
+		if cancao, ok := byID[item.ID]; ok {
+			item.Cancao = cancao
+		}
+	}
+
+	return related, nil
+}
+
+// queryCancoes runs a query selecting cancaoSelect columns and loads the related entities of each song
+func (r *PostgresCancaoRepository) queryCancoes(ctx context.Context, query string, args ...interface{}) ([]*models.Cancao, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying cancoes: %w", err)
+	}
+	defer rows.Close()
+
+	cancoes := []*models.Cancao{}
+	for rows.Next() {
+		cancao, err := scanCancao(rows)
+		if err != nil {
 			return nil, fmt.Errorf("error scanning cancao row: %w", err)
 		}
-		cancoes = append(cancoes, &cancao)
+		cancoes = append(cancoes, cancao)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -147,10 +366,10 @@ func (r *PostgresCancaoRepository) Update(ctx context.Context, cancao *models.Ca
 	query := `
 		UPDATE cancoes
 		SET nome = $1, link_youtube = $2, letra = $3, user_id = $4, updated_at = $5
-		WHERE id = $6
+		WHERE id = $6 AND deleted_at IS NULL
 	`
 
-	cancao.UpdatedAt = time.Now()
+	cancao.UpdatedAt = time.Now().UTC()
 
 	result, err := r.db.ExecContext(ctx, query,
 		cancao.Nome,
@@ -177,11 +396,13 @@ func (r *PostgresCancaoRepository) Update(ctx context.Context, cancao *models.Ca
 	return nil
 }
 
-// Delete deletes a song by ID
+// Delete soft-deletes a song by ID: it is kept, with deleted_at set, but
+// left out of every query unless deleted songs are explicitly requested
 func (r *PostgresCancaoRepository) Delete(ctx context.Context, id int) error {
 	query := `
-		DELETE FROM cancoes
-		WHERE id = $1
+		UPDATE cancoes
+		SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	result, err := r.db.ExecContext(ctx, query, id)
@@ -201,20 +422,46 @@ func (r *PostgresCancaoRepository) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
-// AddTag adds a tag to a song
-func (r *PostgresCancaoRepository) AddTag(ctx context.Context, cancaoID, tagID int) error {
+// IncrementPlayCount atomically increments the play count of a song and returns the new count
+func (r *PostgresCancaoRepository) IncrementPlayCount(ctx context.Context, id int) (int, error) {
+	query := `
+		UPDATE cancoes
+		SET play_count = play_count + 1
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING play_count
+	`
+
+	var playCount int
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&playCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("cancao with ID %d not found", id)
+		}
+		return 0, fmt.Errorf("error incrementing play count: %w", err)
+	}
+
+	return playCount, nil
+}
+
+// AddTag adds a tag to a song, reporting whether it was added or the association already existed
+func (r *PostgresCancaoRepository) AddTag(ctx context.Context, cancaoID, tagID int) (bool, error) {
 	query := `
 		INSERT INTO cancoes_tags (cancao_id, tag_id)
 		VALUES ($1, $2)
 		ON CONFLICT (cancao_id, tag_id) DO NOTHING
 	`
 
-	_, err := r.db.ExecContext(ctx, query, cancaoID, tagID)
+	result, err := r.db.ExecContext(ctx, query, cancaoID, tagID)
 	if err != nil {
-		return fmt.Errorf("error adding tag to cancao: %w", err)
+		return false, fmt.Errorf("error adding tag to cancao: %w", err)
 	}
 
-	return nil
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
 }
 
 // RemoveTag removes a tag from a song
@@ -248,7 +495,7 @@ func (r *PostgresCancaoRepository) GetTags(ctx context.Context, cancaoID int) ([
 	}
 	defer rows.Close()
 
-	var tags []*models.TagCancao
+	tags := []*models.TagCancao{}
 	for rows.Next() {
 		tag := &models.TagCancao{}
 		if err := rows.Scan(
@@ -268,20 +515,25 @@ func (r *PostgresCancaoRepository) GetTags(ctx context.Context, cancaoID int) ([
 	return tags, nil
 }
 
-// AddRamo adds a ramo to a song
-func (r *PostgresCancaoRepository) AddRamo(ctx context.Context, cancaoID, ramoID int) error {
+// AddRamo adds a ramo to a song, reporting whether it was added or the association already existed
+func (r *PostgresCancaoRepository) AddRamo(ctx context.Context, cancaoID, ramoID int) (bool, error) {
 	query := `
 		INSERT INTO cancoes_ramos (cancao_id, ramo_id)
 		VALUES ($1, $2)
 		ON CONFLICT (cancao_id, ramo_id) DO NOTHING
 	`
 
-	_, err := r.db.ExecContext(ctx, query, cancaoID, ramoID)
+	result, err := r.db.ExecContext(ctx, query, cancaoID, ramoID)
 	if err != nil {
-		return fmt.Errorf("error adding ramo to cancao: %w", err)
+		return false, fmt.Errorf("error adding ramo to cancao: %w", err)
 	}
 
-	return nil
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
 }
 
 // RemoveRamo removes a ramo from a song
@@ -315,7 +567,7 @@ func (r *PostgresCancaoRepository) GetRamos(ctx context.Context, cancaoID int) (
 	}
 	defer rows.Close()
 
-	var ramos []*models.Ramo
+	ramos := []*models.Ramo{}
 	for rows.Next() {
 		ramo := &models.Ramo{}
 		if err := rows.Scan(
@@ -0,0 +1,342 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+func TestIncrementPlayCountConcurrently(t *testing.T) {
+	db := newTestDB(t)
+	db.SetMaxOpenConns(10)
+	repo := NewPostgresCancaoRepository(db)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		workers int
+		plays   int
+	}{
+		{"single caller", 1, 5},
+		{"concurrent callers", 10, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := insertTestCancao(t, db, tt.name)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, tt.workers*tt.plays)
+			for w := 0; w < tt.workers; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < tt.plays; i++ {
+						if _, err := repo.IncrementPlayCount(ctx, id); err != nil {
+							errs <- err
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Fatalf("IncrementPlayCount: %v", err)
+			}
+
+			cancao, err := repo.GetByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if want := tt.workers * tt.plays; cancao.PlayCount != want {
+				t.Errorf("play_count = %d, want %d", cancao.PlayCount, want)
+			}
+		})
+	}
+}
+
+func TestIncrementPlayCountMissingCancao(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresCancaoRepository(db)
+
+	if _, err := repo.IncrementPlayCount(context.Background(), 9999); err == nil {
+		t.Fatal("expected an error for a missing cancao")
+	}
+}
+
+func TestListCancoesSortedByPlays(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresCancaoRepository(db)
+	ctx := context.Background()
+
+	plays := map[string]int{"Pouco tocada": 1, "Mais tocada": 30, "Nunca tocada": 0, "Media": 12}
+	for nome, count := range plays {
+		id := insertTestCancao(t, db, nome)
+		mustExec(t, db, `UPDATE cancoes SET play_count = $1 WHERE id = $2`, count, id)
+	}
+
+	tests := []struct {
+		name string
+		sort string
+		want []string
+	}{
+		{"plays descending", "plays", []string{"Mais tocada", "Media", "Pouco tocada", "Nunca tocada"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cancoes, err := repo.List(ctx, CancaoListOptions{Sort: tt.sort, Pagination: Pagination{Limit: 10}})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(cancoes) != len(tt.want) {
+				t.Fatalf("got %d cancoes, want %d", len(cancoes), len(tt.want))
+			}
+			for i, cancao := range cancoes {
+				if cancao.Nome != tt.want[i] {
+					t.Errorf("position %d: got %q, want %q", i, cancao.Nome, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := repo.List(ctx, CancaoListOptions{Sort: "nome"}); err == nil {
+		t.Error("expected an error for a sort value outside the whitelist")
+	}
+}
+
+func TestScanCancaoWithNullColumns(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresCancaoRepository(db)
+	ctx := context.Background()
+
+	// link_youtube and letra are NULL
+	id := insertTestCancao(t, db, "Sem letra")
+
+	cancao, err := repo.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if cancao == nil || cancao.LinkYoutube != "" || cancao.Letra != "" {
+		t.Errorf("got %+v, want empty link_youtube and letra", cancao)
+	}
+
+	cancoes, err := repo.List(ctx, CancaoListOptions{Pagination: Pagination{Limit: 10}})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(cancoes) != 1 {
+		t.Errorf("got %d cancoes, want 1", len(cancoes))
+	}
+}
+
+func TestForEach(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresCancaoRepository(db)
+
+	var ids []int
+	for _, nome := range []string{"Canção 1", "Canção 2", "Removida", "Canção 3"} {
+		ids = append(ids, insertTestCancao(t, db, nome))
+	}
+	mustExec(t, db, `UPDATE cancoes SET deleted_at = NOW() WHERE id = $1`, ids[2])
+	stop := errors.New("stop")
+
+	tests := []struct {
+		name      string
+		afterID   int
+		stopAfter int
+		wantNames []string
+		wantErr   error
+	}{
+		{name: "every song once, in ID order", wantNames: []string{"Canção 1", "Canção 2", "Canção 3"}},
+		{name: "after a cursor", afterID: ids[0], wantNames: []string{"Canção 2", "Canção 3"}},
+		{name: "after the last song", afterID: ids[3], wantNames: nil},
+		{name: "an error from the callback stops the iteration", stopAfter: 1, wantNames: []string{"Canção 1"}, wantErr: stop},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			err := repo.ForEach(context.Background(), tt.afterID, func(cancao *models.Cancao) error {
+				names = append(names, cancao.Nome)
+				if tt.stopAfter > 0 && len(names) == tt.stopAfter {
+					return stop
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ForEach error = %v, want %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.wantNames) {
+				t.Errorf("ForEach visited %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestListByTags(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresCancaoRepository(db)
+	ctx := context.Background()
+
+	// Seeded cancao tags: 1 hino, 2 viagem, 3 cerimonia
+	both := insertTestCancao(t, db, "A hino e viagem")
+	hino := insertTestCancao(t, db, "B só hino")
+	viagem := insertTestCancao(t, db, "C só viagem")
+	insertTestCancao(t, db, "D sem tags")
+	all := insertTestCancao(t, db, "E todas")
+	mustExec(t, db, `INSERT INTO cancoes_tags (cancao_id, tag_id) VALUES ($1, 1), ($1, 2), ($2, 1), ($3, 2), ($4, 1), ($4, 2), ($4, 3)`, both, hino, viagem, all)
+
+	tests := []struct {
+		name     string
+		tagIDs   []int
+		matchAll bool
+		want     []int
+	}{
+		{"any of one tag", []int{1}, false, []int{both, hino, all}},
+		{"any of overlapping tags", []int{1, 2}, false, []int{both, hino, viagem, all}},
+		{"all of overlapping tags", []int{1, 2}, true, []int{both, all}},
+		{"all of three tags", []int{1, 2, 3}, true, []int{all}},
+		{"all with a repeated tag", []int{1, 1}, true, []int{both, hino, all}},
+		{"all with an unused tag", []int{1, 4}, true, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cancoes, err := repo.ListByTags(ctx, tt.tagIDs, tt.matchAll, Pagination{Limit: 50})
+			if err != nil {
+				t.Fatalf("ListByTags: %v", err)
+			}
+			got := []int{}
+			for _, cancao := range cancoes {
+				got = append(got, cancao.ID)
+			}
+			sort.Ints(got)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListRelated(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresCancaoRepository(db)
+	ctx := context.Background()
+
+	// linkCancao gives a song the given tags and ramos
+	linkCancao := func(id int, tagIDs, ramoIDs []int) {
+		for _, tagID := range tagIDs {
+			mustExec(t, db, `INSERT INTO cancoes_tags (cancao_id, tag_id) VALUES ($1, $2)`, id, tagID)
+		}
+		for _, ramoID := range ramoIDs {
+			mustExec(t, db, `INSERT INTO cancoes_ramos (cancao_id, ramo_id) VALUES ($1, $2)`, id, ramoID)
+		}
+	}
+
+	source := insertTestCancao(t, db, "Origem")
+	linkCancao(source, []int{1, 2}, []int{1, 2})
+	linkCancao(insertTestCancao(t, db, "Só ramos"), nil, []int{1, 2})
+	linkCancao(insertTestCancao(t, db, "Quase igual"), []int{1, 2}, []int{1})
+	linkCancao(insertTestCancao(t, db, "Uma de cada"), []int{1}, []int{1})
+	linkCancao(insertTestCancao(t, db, "Sem relação"), []int{3}, []int{5})
+	deleted := insertTestCancao(t, db, "Apagada")
+	linkCancao(deleted, []int{1, 2}, []int{1, 2})
+	mustExec(t, db, `UPDATE cancoes SET deleted_at = NOW() WHERE id = $1`, deleted)
+
+	tests := []struct {
+		name        string
+		cancaoID    int
+		limit       int
+		want        []string
+		wantOverlap []int
+	}{
+		// Equal overlaps rank the songs sharing more tags first
+		{"ranked by overlap", source, 10, []string{"Quase igual", "Uma de cada", "Só ramos"}, []int{3, 2, 2}},
+		{"limited", source, 1, []string{"Quase igual"}, []int{3}},
+		{"without tags or ramos", insertTestCancao(t, db, "Sozinha"), 10, []string{}, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			related, err := repo.ListRelated(ctx, tt.cancaoID, tt.limit)
+			if err != nil {
+				t.Fatalf("ListRelated: %v", err)
+			}
+			names := []string{}
+			overlaps := []int{}
+			for _, item := range related {
+				if item.ID == tt.cancaoID {
+					t.Error("the source cancao is listed as related to itself")
+				}
+				names = append(names, item.Nome)
+				overlaps = append(overlaps, item.Overlap)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.want) || fmt.Sprint(overlaps) != fmt.Sprint(tt.wantOverlap) {
+				t.Errorf("related = %v with overlaps %v, want %v with %v", names, overlaps, tt.want, tt.wantOverlap)
+			}
+		})
+	}
+}
+
+func TestListMissingYoutube(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresCancaoRepository(db)
+	ctx := context.Background()
+
+	withLink := insertTestCancao(t, db, "Com vídeo")
+	mustExec(t, db, `UPDATE cancoes SET link_youtube = 'https://youtu.be/abc' WHERE id = $1`, withLink)
+	nullLink := insertTestCancao(t, db, "Sem vídeo")
+	mustExec(t, db, `UPDATE cancoes SET link_youtube = NULL WHERE id = $1`, nullLink)
+	emptyLink := insertTestCancao(t, db, "Link vazio")
+	mustExec(t, db, `UPDATE cancoes SET link_youtube = '' WHERE id = $1`, emptyLink)
+	blankLink := insertTestCancao(t, db, "Link em branco")
+	mustExec(t, db, `UPDATE cancoes SET link_youtube = '   ' WHERE id = $1`, blankLink)
+	deleted := insertTestCancao(t, db, "Apagada sem vídeo")
+	mustExec(t, db, `UPDATE cancoes SET link_youtube = NULL, deleted_at = NOW() WHERE id = $1`, deleted)
+
+	tests := []struct {
+		name      string
+		opts      CancaoListOptions
+		want      []int
+		wantCount int
+	}{
+		{"missing links", CancaoListOptions{MissingYoutube: true}, []int{nullLink, emptyLink, blankLink}, 3},
+		{"missing links with deleted songs", CancaoListOptions{MissingYoutube: true, IncludeDeleted: true}, []int{nullLink, emptyLink, blankLink, deleted}, 4},
+		{"no filter", CancaoListOptions{}, []int{withLink, nullLink, emptyLink, blankLink}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Pagination = Pagination{Limit: 50}
+			cancoes, err := repo.List(ctx, opts)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			got := []int{}
+			for _, cancao := range cancoes {
+				got = append(got, cancao.ID)
+			}
+			sort.Ints(got)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+
+			count, err := repo.Count(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("Count: %v", err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("count = %d, want %d", count, tt.wantCount)
+			}
+		})
+	}
+}
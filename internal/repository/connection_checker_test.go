@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// staleDriver is a database/sql driver whose connections only answer SELECT 1
+// and can be made stale, like connections closed by the server while a Lambda
+// container was frozen
+type staleDriver struct {
+	mu      sync.Mutex
+	conns   []*staleConn
+	opened  int
+	queries int
+	down    bool
+}
+
+func (d *staleDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down {
+		return nil, errors.New("connection refused")
+	}
+	conn := &staleConn{driver: d}
+	d.conns = append(d.conns, conn)
+	d.opened++
+	return conn, nil
+}
+
+// killConnections makes every open connection stale
+func (d *staleDriver) killConnections() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, conn := range d.conns {
+		conn.stale = true
+	}
+}
+
+type staleConn struct {
+	driver *staleDriver
+	stale  bool
+}
+
+func (c *staleConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.driver.queries++
+	if c.stale {
+		return nil, driver.ErrBadConn
+	}
+	return &oneRow{}, nil
+}
+
+func (c *staleConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *staleConn) Close() error { return nil }
+
+func (c *staleConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+// oneRow is the result of SELECT 1
+type oneRow struct {
+	done bool
+}
+
+func (r *oneRow) Columns() []string { return []string{"?column?"} }
+
+func (r *oneRow) Close() error { return nil }
+
+func (r *oneRow) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+var staleDriverCount int32
+
+// newStaleDB opens a database backed by a new staleDriver and opens its first connection
+func newStaleDB(t *testing.T) (*sql.DB, *staleDriver) {
+	t.Helper()
+	d := &staleDriver{}
+	name := "stale" + strconv.Itoa(int(atomic.AddInt32(&staleDriverCount, 1)))
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatalf("opening the first connection: %v", err)
+	}
+	return db, d
+}
+
+func TestConnectionChecker(t *testing.T) {
+	tests := []struct {
+		name        string
+		checkAfter  string
+		idle        time.Duration
+		setup       func(d *staleDriver)
+		wantQueries int
+		wantOpened  int
+		wantErr     bool
+	}{
+		{name: "recently used", idle: time.Minute, wantQueries: 0, wantOpened: 1},
+		{name: "idle with a live connection", idle: 10 * time.Minute, wantQueries: 1, wantOpened: 1},
+		{
+			name:        "stale connection is detected and re-established",
+			idle:        10 * time.Minute,
+			setup:       func(d *staleDriver) { d.killConnections() },
+			wantQueries: 2,
+			wantOpened:  2,
+		},
+		{
+			name: "database down",
+			idle: 10 * time.Minute,
+			setup: func(d *staleDriver) {
+				d.killConnections()
+				d.down = true
+			},
+			wantQueries: 1,
+			wantOpened:  1,
+			wantErr:     true,
+		},
+		{
+			name:        "check turned off",
+			checkAfter:  "0",
+			idle:        time.Hour,
+			setup:       func(d *staleDriver) { d.killConnections() },
+			wantQueries: 0,
+			wantOpened:  1,
+		},
+		{name: "configured idle period", checkAfter: "30s", idle: time.Minute, wantQueries: 1, wantOpened: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DB_IDLE_CHECK_AFTER", tt.checkAfter)
+			db, d := newStaleDB(t)
+			checker := NewConnectionChecker(db)
+			checker.lastUse = time.Now().Add(-tt.idle)
+			if tt.setup != nil {
+				tt.setup(d)
+			}
+
+			err := checker.Check(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check: err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			// A stale connection fails its query and is replaced by a new one
+			if d.queries != tt.wantQueries {
+				t.Errorf("queries = %d, want %d", d.queries, tt.wantQueries)
+			}
+			if d.opened != tt.wantOpened {
+				t.Errorf("connections opened = %d, want %d", d.opened, tt.wantOpened)
+			}
+		})
+	}
+}
+
+func TestConnectionCheckerOnlyChecksOncePerIdlePeriod(t *testing.T) {
+	db, d := newStaleDB(t)
+	checker := NewConnectionChecker(db)
+	checker.lastUse = time.Now().Add(-time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if err := checker.Check(context.Background()); err != nil {
+			t.Fatalf("Check %d: %v", i, err)
+		}
+	}
+
+	// Only the first request after the idle period validates the pool
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.queries != 1 {
+		t.Errorf("queries = %d, want 1", d.queries)
+	}
+}
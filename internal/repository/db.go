@@ -1,14 +1,33 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"sync"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	_ "github.com/lib/pq"
 )
 
+// SchemaVersion is the database schema version this code expects.
+// Bump it together with scripts/init-db.sql whenever the schema changes.
+const SchemaVersion = 11
+
+const (
+	// defaultIdleCheckAfter is how long the pool may go unused before the next
+	// request validates it
+	defaultIdleCheckAfter = 5 * time.Minute
+	// connectionCheckTimeout bounds a single validation query
+	connectionCheckTimeout = 2 * time.Second
+)
+
 // DBConfig holds the configuration for the database connection
 type DBConfig struct {
 	Host     string
@@ -19,9 +38,26 @@ type DBConfig struct {
 	SSLMode  string
 }
 
-// NewDBConfigFromEnv creates a new DBConfig from environment variables
-func NewDBConfigFromEnv() *DBConfig {
-	return &DBConfig{
+// SecretsClient is the part of the Secrets Manager client used to read the database credentials
+type SecretsClient interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// dbSecret is the JSON stored in a database secret, as created by RDS
+type dbSecret struct {
+	Host     string      `json:"host"`
+	Port     interface{} `json:"port"`
+	Username string      `json:"username"`
+	User     string      `json:"user"`
+	Password string      `json:"password"`
+	DBName   string      `json:"dbname"`
+}
+
+// NewDBConfigFromEnv creates a new DBConfig from environment variables. When
+// DB_SECRET_ARN is set, the fields present in that Secrets Manager secret
+// override the environment variables; secrets may be nil otherwise.
+func NewDBConfigFromEnv(ctx context.Context, secrets SecretsClient) (*DBConfig, error) {
+	dbConfig := &DBConfig{
 		Host:     getEnv("DB_HOST", "localhost"),
 		Port:     getEnv("DB_PORT", "5432"),
 		User:     getEnv("DB_USER", "postgres"),
@@ -29,12 +65,50 @@ func NewDBConfigFromEnv() *DBConfig {
 		DBName:   getEnv("DB_NAME", "geav"),
 		SSLMode:  getEnv("DB_SSL_MODE", "disable"),
 	}
+
+	secretARN := os.Getenv("DB_SECRET_ARN")
+	if secretARN == "" {
+		return dbConfig, nil
+	}
+
+	output, err := secrets.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretARN),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting database secret: %w", err)
+	}
+
+	var secret dbSecret
+	if err := json.Unmarshal([]byte(aws.ToString(output.SecretString)), &secret); err != nil {
+		return nil, fmt.Errorf("error decoding database secret: %w", err)
+	}
+
+	if secret.Host != "" {
+		dbConfig.Host = secret.Host
+	}
+	if secret.Port != nil {
+		dbConfig.Port = fmt.Sprint(secret.Port)
+	}
+	if secret.Username != "" {
+		dbConfig.User = secret.Username
+	} else if secret.User != "" {
+		dbConfig.User = secret.User
+	}
+	if secret.Password != "" {
+		dbConfig.Password = secret.Password
+	}
+	if secret.DBName != "" {
+		dbConfig.DBName = secret.DBName
+	}
+
+	return dbConfig, nil
 }
 
-// ConnectionString returns the connection string for the database
+// ConnectionString returns the connection string for the database. The
+// session time zone is UTC, so scanned timestamps are returned in UTC.
 func (c *DBConfig) ConnectionString() string {
 	return fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s timezone=UTC",
 		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode,
 	)
 }
@@ -64,12 +138,110 @@ func getEnv(key, defaultValue string) string {
 }
 
 // InitDB initializes the database connection
-func InitDB() (*sql.DB, error) {
-	config := NewDBConfigFromEnv()
-	db, err := NewDB(config)
+func InitDB(ctx context.Context) (*sql.DB, error) {
+	var secrets SecretsClient
+	if os.Getenv("DB_SECRET_ARN") != "" {
+		awsConfig, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error loading AWS configuration: %w", err)
+		}
+		secrets = secretsmanager.NewFromConfig(awsConfig)
+	}
+
+	dbConfig, err := NewDBConfigFromEnv(ctx, secrets)
+	if err != nil {
+		log.Printf("Failed to load database configuration: %v", err)
+		return nil, err
+	}
+
+	db, err := NewDB(dbConfig)
 	if err != nil {
 		log.Printf("Failed to connect to database: %v", err)
 		return nil, err
 	}
 	return db, nil
 }
+
+// CheckSchemaVersion returns an error when the current schema version, the
+// highest version in schema_migrations, is not the expected one
+func CheckSchemaVersion(ctx context.Context, db *sql.DB, expected int) error {
+	query := `
+		SELECT COALESCE(MAX(version), 0)
+		FROM schema_migrations
+	`
+
+	var version int
+	if err := db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return fmt.Errorf("error reading schema version: %w", err)
+	}
+
+	if version != expected {
+		return fmt.Errorf("database schema version is %d but version %d is expected; apply the pending migrations", version, expected)
+	}
+
+	return nil
+}
+
+// ConnectionChecker validates the connection pool before it is reused after a
+// long idle period. A warm Lambda container keeps its *sql.DB between
+// invocations, and a connection that sat idle while the container was frozen
+// may have been closed by the server or a proxy.
+type ConnectionChecker struct {
+	db         *sql.DB
+	checkAfter time.Duration
+
+	mu      sync.Mutex
+	lastUse time.Time
+}
+
+// NewConnectionChecker creates a connection checker. The idle period is read
+// from DB_IDLE_CHECK_AFTER as a duration (e.g. 5m, default 5 minutes); 0
+// turns the check off.
+func NewConnectionChecker(db *sql.DB) *ConnectionChecker {
+	checkAfter := defaultIdleCheckAfter
+	if value := os.Getenv("DB_IDLE_CHECK_AFTER"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			checkAfter = parsed
+		}
+	}
+
+	return &ConnectionChecker{
+		db:         db,
+		checkAfter: checkAfter,
+		lastUse:    time.Now(),
+	}
+}
+
+// Check runs SELECT 1 when the pool was last used more than the idle period
+// ago. A dead connection makes database/sql discard it and retry on a new
+// one, so a failed check is retried once before the error is returned.
+func (c *ConnectionChecker) Check(ctx context.Context) error {
+	c.mu.Lock()
+	idle := time.Since(c.lastUse)
+	c.lastUse = time.Now()
+	c.mu.Unlock()
+
+	if c.checkAfter <= 0 || idle < c.checkAfter {
+		return nil
+	}
+
+	err := c.validate(ctx)
+	if err != nil {
+		log.Printf("Stale database connection detected after %s idle, reconnecting: %v", idle.Round(time.Second), err)
+		err = c.validate(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("error validating database connection: %w", err)
+	}
+
+	return nil
+}
+
+// validate runs the validation query with a short timeout
+func (c *ConnectionChecker) validate(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, connectionCheckTimeout)
+	defer cancel()
+
+	var one int
+	return c.db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
@@ -0,0 +1,178 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    string
+		expected int
+		wantErr  bool
+	}{
+		{name: "fresh schema matches the code", expected: SchemaVersion},
+		{name: "code expects a newer schema", expected: SchemaVersion + 1, wantErr: true},
+		{name: "schema is newer than the code", setup: `INSERT INTO schema_migrations (version) VALUES (100)`, expected: SchemaVersion, wantErr: true},
+		{name: "no version recorded", setup: `DELETE FROM schema_migrations`, expected: SchemaVersion, wantErr: true},
+		{name: "no schema_migrations table", setup: `DROP TABLE schema_migrations`, expected: SchemaVersion, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			if tt.setup != "" {
+				mustExec(t, db, tt.setup)
+			}
+
+			err := CheckSchemaVersion(context.Background(), db, tt.expected)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEmptyResultsAreNotNil(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	lugarID := insertTestLugar(t, db, "Sem nada")
+	cancaoID := insertTestCancao(t, db, "Sem nada")
+	userID := insertTestUser(t, db, "sem_conteudo")
+
+	lugarRepo := NewPostgresLugarRepository(db)
+	cancaoRepo := NewPostgresCancaoRepository(db)
+	past := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		get  func() (interface{}, error)
+	}{
+		{"lugares of a user without content", func() (interface{}, error) { return lugarRepo.ListByUser(ctx, userID, Pagination{Limit: 10}) }},
+		{"lugares matching no filter", func() (interface{}, error) {
+			return lugarRepo.List(ctx, LugarListOptions{Address: "nenhum endereço assim", Pagination: Pagination{Limit: 10}})
+		}},
+		{"images of a lugar", func() (interface{}, error) { return lugarRepo.GetImages(ctx, lugarID) }},
+		{"tags of a lugar", func() (interface{}, error) { return lugarRepo.GetTags(ctx, lugarID) }},
+		{"ramos of a lugar", func() (interface{}, error) { return lugarRepo.GetRamos(ctx, lugarID) }},
+		{"ratings of a lugar", func() (interface{}, error) { return lugarRepo.GetRatings(ctx, lugarID, Pagination{Limit: 10}) }},
+		{"recent ratings", func() (interface{}, error) { return lugarRepo.RecentRatings(ctx, 10) }},
+		{"cancoes of a user without content", func() (interface{}, error) { return cancaoRepo.ListByUser(ctx, userID, Pagination{Limit: 10}) }},
+		{"tags of a cancao", func() (interface{}, error) { return cancaoRepo.GetTags(ctx, cancaoID) }},
+		{"ramos of a cancao", func() (interface{}, error) { return cancaoRepo.GetRamos(ctx, cancaoID) }},
+		{"tag suggestions without a match", func() (interface{}, error) {
+			return NewPostgresTagLugarRepository(db).Suggest(ctx, "zzz", 10)
+		}},
+		{"users created in an empty range", func() (interface{}, error) {
+			return NewPostgresUserRepository(db).ListCreatedBetween(ctx, past, past.AddDate(0, 0, 1), 10, 0)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.get()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v := reflect.ValueOf(got); v.Len() != 0 || v.IsNil() {
+				t.Errorf("got %#v, want a non-nil empty slice", got)
+			}
+			body, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("encoding: %v", err)
+			}
+			if string(body) != "[]" {
+				t.Errorf("encoded as %s, want []", body)
+			}
+		})
+	}
+}
+
+func TestAddAssociationReportsDuplicates(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	lugarID := insertTestLugar(t, db, "Sítio")
+	cancaoID := insertTestCancao(t, db, "Canção")
+
+	lugarRepo := NewPostgresLugarRepository(db)
+	cancaoRepo := NewPostgresCancaoRepository(db)
+
+	tests := []struct {
+		name string
+		add  func() (bool, error)
+	}{
+		{"lugar tag", func() (bool, error) { return lugarRepo.AddTag(ctx, lugarID, 1) }},
+		{"lugar ramo", func() (bool, error) { return lugarRepo.AddRamo(ctx, lugarID, 1) }},
+		{"cancao tag", func() (bool, error) { return cancaoRepo.AddTag(ctx, cancaoID, 1) }},
+		{"cancao ramo", func() (bool, error) { return cancaoRepo.AddRamo(ctx, cancaoID, 1) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range []bool{true, false} {
+				added, err := tt.add()
+				if err != nil {
+					t.Fatalf("add %d: %v", i+1, err)
+				}
+				if added != want {
+					t.Errorf("add %d reported added = %v, want %v", i+1, added, want)
+				}
+			}
+		})
+	}
+}
+
+func TestScannedTimestampsAreUTC(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	// Use the session time zone ConnectionString sets, whatever the server default
+	db.SetMaxOpenConns(1)
+	mustExec(t, db, `SET TIME ZONE 'UTC'`)
+	lugarID := insertTestLugar(t, db, "Sítio")
+	cancaoID := insertTestCancao(t, db, "Canção")
+
+	tests := []struct {
+		name string
+		get  func() (time.Time, error)
+	}{
+		{"lugar", func() (time.Time, error) {
+			lugar, err := NewPostgresLugarRepository(db).GetByID(ctx, lugarID)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return lugar.CreatedAt, nil
+		}},
+		{"cancao", func() (time.Time, error) {
+			cancao, err := NewPostgresCancaoRepository(db).GetByID(ctx, cancaoID)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return cancao.CreatedAt, nil
+		}},
+		{"user", func() (time.Time, error) {
+			user, err := NewPostgresUserRepository(db).GetByID(ctx, 1)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return user.CreatedAt, nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.get()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, offset := got.Zone(); offset != 0 {
+				t.Errorf("created_at = %v, want a UTC time", got)
+			}
+		})
+	}
+}
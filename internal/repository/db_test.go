@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// TestSchemaVersionMatchesScripts checks that SchemaVersion, init-db.sql and
+// the migrations were bumped together
+func TestSchemaVersionMatchesScripts(t *testing.T) {
+	insertVersion := regexp.MustCompile(`INSERT INTO schema_migrations \(version\) VALUES ([^;]+);`)
+
+	script, err := os.ReadFile("../../scripts/init-db.sql")
+	if err != nil {
+		t.Fatalf("reading init-db.sql: %v", err)
+	}
+	match := insertVersion.FindSubmatch(script)
+	if match == nil {
+		t.Fatal("init-db.sql does not record its schema versions")
+	}
+	var want string
+	for version := 1; version <= SchemaVersion; version++ {
+		if version > 1 {
+			want += ", "
+		}
+		want += fmt.Sprintf("(%d)", version)
+	}
+	if string(match[1]) != want {
+		t.Errorf("init-db.sql records %s, want %s", match[1], want)
+	}
+
+	migrations, err := filepath.Glob("../../scripts/migrations/*.sql")
+	if err != nil {
+		t.Fatalf("listing migrations: %v", err)
+	}
+	if len(migrations) != SchemaVersion {
+		t.Fatalf("got %d migrations, want one per version up to %d", len(migrations), SchemaVersion)
+	}
+	for i, path := range migrations {
+		version := i + 1
+		if prefix := fmt.Sprintf("%03d_", version); filepath.Base(path)[:4] != prefix {
+			t.Errorf("migration %s, want the prefix %s", filepath.Base(path), prefix)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("reading %s: %v", path, err)
+		}
+		match := insertVersion.FindSubmatch(content)
+		if match == nil || string(match[1]) != "("+strconv.Itoa(version)+")" {
+			t.Errorf("%s does not record version %d", filepath.Base(path), version)
+		}
+	}
+}
+
+// fakeSecretsClient is a SecretsClient returning a fixed secret
+type fakeSecretsClient struct {
+	secret    string
+	err       error
+	requested string
+}
+
+func (c *fakeSecretsClient) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	c.requested = aws.ToString(params.SecretId)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(c.secret)}, nil
+}
+
+func TestNewDBConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		"DB_HOST":     "env-host",
+		"DB_PORT":     "5433",
+		"DB_USER":     "env-user",
+		"DB_PASSWORD": "env-password",
+		"DB_NAME":     "env-db",
+		"DB_SSL_MODE": "require",
+	}
+	fromEnv := DBConfig{Host: "env-host", Port: "5433", User: "env-user", Password: "env-password", DBName: "env-db", SSLMode: "require"}
+	const arn = "arn:aws:secretsmanager:us-east-1:123456789012:secret:geav-db"
+
+	tests := []struct {
+		name      string
+		secretARN string
+		secret    string
+		secretErr error
+		want      DBConfig
+		wantErr   bool
+	}{
+		{name: "no secret", want: fromEnv},
+		{
+			name:      "RDS secret",
+			secretARN: arn,
+			secret:    `{"host": "rds-host", "port": 5432, "username": "rds-user", "password": "s3cret", "dbname": "geav"}`,
+			want:      DBConfig{Host: "rds-host", Port: "5432", User: "rds-user", Password: "s3cret", DBName: "geav", SSLMode: "require"},
+		},
+		{
+			name:      "port as a string and user instead of username",
+			secretARN: arn,
+			secret:    `{"host": "rds-host", "port": "6543", "user": "other-user", "password": "s3cret"}`,
+			want:      DBConfig{Host: "rds-host", Port: "6543", User: "other-user", Password: "s3cret", DBName: "env-db", SSLMode: "require"},
+		},
+		{
+			name:      "partial secret keeps the environment for the rest",
+			secretARN: arn,
+			secret:    `{"password": "s3cret"}`,
+			want:      DBConfig{Host: "env-host", Port: "5433", User: "env-user", Password: "s3cret", DBName: "env-db", SSLMode: "require"},
+		},
+		{name: "secret that is not JSON", secretARN: arn, secret: "s3cret", wantErr: true},
+		{name: "secret that cannot be read", secretARN: arn, secretErr: errors.New("access denied"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range env {
+				t.Setenv(key, value)
+			}
+			t.Setenv("DB_SECRET_ARN", tt.secretARN)
+			secrets := &fakeSecretsClient{secret: tt.secret, err: tt.secretErr}
+
+			config, err := NewDBConfigFromEnv(context.Background(), secrets)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got config %+v, want an error", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewDBConfigFromEnv: %v", err)
+			}
+			if *config != tt.want {
+				t.Errorf("config = %+v, want %+v", *config, tt.want)
+			}
+			if secrets.requested != tt.secretARN {
+				t.Errorf("requested secret %q, want %q", secrets.requested, tt.secretARN)
+			}
+		})
+	}
+}
+
+func TestConnectionStringUsesUTC(t *testing.T) {
+	tests := []struct {
+		name   string
+		config DBConfig
+	}{
+		{"defaults", DBConfig{Host: "localhost", Port: "5432", User: "postgres", DBName: "geav", SSLMode: "disable"}},
+		{"with password", DBConfig{Host: "db", Port: "5433", User: "geav", Password: "secret", DBName: "geav", SSLMode: "require"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.ConnectionString()
+			if !strings.HasSuffix(got, " timezone=UTC") {
+				t.Errorf("ConnectionString() = %q, want the session time zone set to UTC", got)
+			}
+			if !strings.Contains(got, "dbname="+tt.config.DBName+" ") {
+				t.Errorf("ConnectionString() = %q, want dbname=%s", got, tt.config.DBName)
+			}
+		})
+	}
+}
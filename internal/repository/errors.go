@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrAlreadyExists is returned when a write violates a unique constraint
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrInvalidReference is returned when a write references a row that does not exist
+	ErrInvalidReference = errors.New("referenced row does not exist")
+	// ErrStaleWrite is returned when a conditional write finds the row changed since it was read
+	ErrStaleWrite = errors.New("row was modified since it was read")
+	// ErrNotFound is returned when a write targets a row that does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrImageLimit is returned when adding images would take a place past its maximum number of images
+	ErrImageLimit = errors.New("image limit reached")
+)
+
+const (
+	// uniqueViolation is the PostgreSQL error code for unique constraint violations
+	uniqueViolation = "23505"
+	// foreignKeyViolation is the PostgreSQL error code for foreign key violations
+	foreignKeyViolation = "23503"
+)
+
+// isUniqueViolation checks if the error was caused by a unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// isForeignKeyViolation checks if the error was caused by a foreign key violation
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestImportErrorMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "unique violation", err: fmt.Errorf("error creating lugar: %w", &pq.Error{Code: uniqueViolation, Message: `duplicate key value violates unique constraint "lugares_pkey"`}), want: "Duplicate lugar"},
+		{name: "foreign key violation", err: &pq.Error{Code: foreignKeyViolation, Message: `insert or update on table "lugares_tags" violates foreign key constraint`}, want: "Invalid reference"},
+		{name: "invalid reference", err: fmt.Errorf("user 99: %w", ErrInvalidReference), want: "Invalid reference"},
+		{name: "other database error", err: &pq.Error{Code: "22001", Message: "value too long for type character varying(255)"}, want: "Internal error"},
+		{name: "other error", err: errors.New("connection reset by peer"), want: "Internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := importErrorMessage(tt.err); got != tt.want {
+				t.Errorf("importErrorMessage(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,61 +2,156 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/site-geav-api/internal/models"
 )
 
+// Pagination limits the rows returned by a list query; a zero Limit returns every row
+type Pagination struct {
+	Limit  int
+	Offset int
+}
+
 // UserRepository defines the interface for user operations
 type UserRepository interface {
 	GetByID(ctx context.Context, id int) (*models.User, error)
+	GetWithCounts(ctx context.Context, id int) (*models.UserWithCounts, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
-	List(ctx context.Context) ([]*models.User, error)
+	List(ctx context.Context, page Pagination) ([]*models.User, error)
+	ListCreatedBetween(ctx context.Context, from, to time.Time, limit, offset int) ([]*models.User, error)
+	Count(ctx context.Context) (int, error)
+	CountCreatedBetween(ctx context.Context, from, to time.Time) (int, error)
 	Create(ctx context.Context, user *models.User) (int, error)
+	CreateIfNoUsers(ctx context.Context, user *models.User) (bool, error)
 	Update(ctx context.Context, user *models.User) error
 	Delete(ctx context.Context, id int) error
+	Anonymize(ctx context.Context, id int) (*models.User, error)
+}
+
+// LugarListOptions holds the optional parameters for listing lugares
+type LugarListOptions struct {
+	// RamoIDs keeps only the places associated with any of the given ramos
+	RamoIDs []int
+	// Unrated keeps only the places that have never been rated
+	Unrated bool
+	// Address keeps only the places whose address contains this text
+	Address string
+	// MaxValorIndividual keeps only the public places and the places whose
+	// individual price is at most this value when not nil
+	MaxValorIndividual *float64
+	// UserID keeps only the places created by this user when not zero
+	UserID int
+	// IncludeDeleted also returns the soft-deleted places
+	IncludeDeleted bool
+	// IncludeUnpublished also returns the places that were not published yet
+	IncludeUnpublished bool
+	// Sort selects a whitelisted ordering (see IsValidLugarSort); empty keeps the default order
+	Sort string
+	Pagination
 }
 
 // LugarRepository defines the interface for lugar operations
 type LugarRepository interface {
 	GetByID(ctx context.Context, id int) (*models.Lugar, error)
-	List(ctx context.Context) ([]*models.Lugar, error)
+	GetByIDIncludingDeleted(ctx context.Context, id int) (*models.Lugar, error)
+	Exists(ctx context.Context, id int) (bool, error)
+	List(ctx context.Context, opts LugarListOptions) ([]*models.Lugar, error)
+	Count(ctx context.Context, opts LugarListOptions) (int, error)
+	DailyCreationCounts(ctx context.Context, from, to time.Time) ([]*models.DailyCount, error)
+	ListSelectOptions(ctx context.Context, page Pagination) ([]*models.SelectOption, error)
+	ListByRamos(ctx context.Context, ramoIDs []int, page Pagination) ([]*models.Lugar, error)
+	ListByUser(ctx context.Context, userID int, page Pagination) ([]*models.Lugar, error)
+	SearchByAddress(ctx context.Context, address string, page Pagination) ([]*models.Lugar, error)
+	ListInBoundingBox(ctx context.Context, minLat, minLng, maxLat, maxLng float64, page Pagination) ([]*models.Lugar, error)
+	CountInBoundingBox(ctx context.Context, minLat, minLng, maxLat, maxLng float64) (int, error)
+	ListNearest(ctx context.Context, lat, lng float64, page Pagination) ([]*models.Lugar, error)
+	CountWithCoordinates(ctx context.Context) (int, error)
+	FindSimilar(ctx context.Context, nomeLocal, enderecoCompleto string) ([]*models.Lugar, error)
 	Create(ctx context.Context, lugar *models.Lugar) (int, error)
+	Import(ctx context.Context, lugares []*models.Lugar) ([]models.LugarImportResult, error)
 	Update(ctx context.Context, lugar *models.Lugar) error
 	Delete(ctx context.Context, id int) error
+	ChangeOwner(ctx context.Context, id, userID int) error
+	Touch(ctx context.Context, id int) (*time.Time, error)
+	Publish(ctx context.Context, id int) error
 	
 	// Related operations
-	AddImage(ctx context.Context, image *models.LugarImage) (int, error)
-	DeleteImage(ctx context.Context, imageID int) error
+	AddImage(ctx context.Context, image *models.LugarImage, maxImages int) (int, error)
+	AddImages(ctx context.Context, images []*models.LugarImage, maxImages int) error
+	DeleteImage(ctx context.Context, imageID int, compact bool) error
 	GetImages(ctx context.Context, lugarID int) ([]*models.LugarImage, error)
+	GetImageByID(ctx context.Context, lugarID, imageID int) (*models.LugarImage, error)
+	ListAllImages(ctx context.Context, page Pagination) ([]*models.ImageWithLugar, error)
+	CountAllImages(ctx context.Context) (int, error)
+	CountImages(ctx context.Context, lugarID int) (int, error)
+	MaxDisplayOrder(ctx context.Context, lugarID int) (int, error)
 	
-	AddTag(ctx context.Context, lugarID, tagID int) error
+	AddTag(ctx context.Context, lugarID, tagID int) (bool, error)
 	RemoveTag(ctx context.Context, lugarID, tagID int) error
+	RemoveTags(ctx context.Context, lugarID int, tagIDs []int) (int, error)
 	GetTags(ctx context.Context, lugarID int) ([]*models.TagLugar, error)
+	MissingTagIDs(ctx context.Context, tagIDs []int) ([]int, error)
+	SharedTags(ctx context.Context, lugarID, otherID int) ([]*models.TagLugar, error)
+	ListSimilar(ctx context.Context, lugarID, limit int) ([]*models.SimilarLugar, error)
 	
-	AddRamo(ctx context.Context, lugarID, ramoID int) error
+	AddRamo(ctx context.Context, lugarID, ramoID int) (bool, error)
 	RemoveRamo(ctx context.Context, lugarID, ramoID int) error
 	GetRamos(ctx context.Context, lugarID int) ([]*models.Ramo, error)
+	MissingRamoIDs(ctx context.Context, ramoIDs []int) ([]int, error)
 	
 	AddRating(ctx context.Context, rating *models.LugarRating) (int, error)
 	UpdateRating(ctx context.Context, rating *models.LugarRating) error
+	UpdateRatingIfUnchanged(ctx context.Context, rating *models.LugarRating, expectedVersion int) error
 	DeleteRating(ctx context.Context, ratingID int) error
-	GetRatings(ctx context.Context, lugarID int) ([]*models.LugarRating, error)
+	DeleteRatingByUser(ctx context.Context, lugarID, userID int) error
+	GetRatings(ctx context.Context, lugarID int, page Pagination) ([]*models.LugarRating, error)
+	CountRatings(ctx context.Context, lugarID int) (int, error)
+	GetUserRating(ctx context.Context, lugarID, userID int) (*models.LugarRating, error)
+	RatingSummaries(ctx context.Context, ids []int) (map[int]*models.RatingSummary, error)
+	GlobalRatingDistribution(ctx context.Context) (*models.RatingDistribution, error)
+	RecentRatings(ctx context.Context, limit int) ([]*models.RecentRating, error)
+	ListRatingsByValue(ctx context.Context, rating int, page Pagination) ([]*models.RecentRating, error)
+	CountRatingsByValue(ctx context.Context, rating int) (int, error)
+}
+
+// CancaoListOptions holds the optional parameters for listing cancoes
+type CancaoListOptions struct {
+	// Sort selects a whitelisted ordering (see IsValidCancaoSort); empty keeps the default order
+	Sort string
+	// TagIDs keeps only the songs with the given tags
+	TagIDs []int
+	// MatchAllTags keeps only the songs with every tag in TagIDs instead of any of them
+	MatchAllTags bool
+	// MissingYoutube keeps only the songs without a link_youtube
+	MissingYoutube bool
+	// IncludeDeleted also returns the soft-deleted songs
+	IncludeDeleted bool
+	Pagination
 }
 
 // CancaoRepository defines the interface for cancao operations
 type CancaoRepository interface {
 	GetByID(ctx context.Context, id int) (*models.Cancao, error)
-	List(ctx context.Context) ([]*models.Cancao, error)
+	GetByIDIncludingDeleted(ctx context.Context, id int) (*models.Cancao, error)
+	List(ctx context.Context, opts CancaoListOptions) ([]*models.Cancao, error)
+	Count(ctx context.Context, opts CancaoListOptions) (int, error)
+	ListSelectOptions(ctx context.Context, page Pagination) ([]*models.SelectOption, error)
+	ListByTags(ctx context.Context, tagIDs []int, matchAll bool, page Pagination) ([]*models.Cancao, error)
+	ListByUser(ctx context.Context, userID int, page Pagination) ([]*models.Cancao, error)
+	ListRelated(ctx context.Context, cancaoID, limit int) ([]*models.RelatedCancao, error)
+	ForEach(ctx context.Context, afterID int, fn func(*models.Cancao) error) error
 	Create(ctx context.Context, cancao *models.Cancao) (int, error)
 	Update(ctx context.Context, cancao *models.Cancao) error
 	Delete(ctx context.Context, id int) error
+	IncrementPlayCount(ctx context.Context, id int) (int, error)
 	
 	// Related operations
-	AddTag(ctx context.Context, cancaoID, tagID int) error
+	AddTag(ctx context.Context, cancaoID, tagID int) (bool, error)
 	RemoveTag(ctx context.Context, cancaoID, tagID int) error
 	GetTags(ctx context.Context, cancaoID int) ([]*models.TagCancao, error)
 	
-	AddRamo(ctx context.Context, cancaoID, ramoID int) error
+	AddRamo(ctx context.Context, cancaoID, ramoID int) (bool, error)
 	RemoveRamo(ctx context.Context, cancaoID, ramoID int) error
 	GetRamos(ctx context.Context, cancaoID int) ([]*models.Ramo, error)
 }
@@ -64,8 +159,13 @@ type CancaoRepository interface {
 // TagLugarRepository defines the interface for tag_lugar operations
 type TagLugarRepository interface {
 	GetByID(ctx context.Context, id int) (*models.TagLugar, error)
-	List(ctx context.Context) ([]*models.TagLugar, error)
+	List(ctx context.Context, page Pagination) ([]*models.TagLugar, error)
+	Count(ctx context.Context) (int, error)
+	ListUnassigned(ctx context.Context, lugarID int, page Pagination) ([]*models.TagLugar, error)
+	ListSelectOptions(ctx context.Context, page Pagination) ([]*models.SelectOption, error)
 	Create(ctx context.Context, tag *models.TagLugar) (int, error)
+	GetOrCreate(ctx context.Context, tag *models.TagLugar) (*models.TagLugar, bool, error)
+	Suggest(ctx context.Context, query string, limit int) ([]*models.TagLugar, error)
 	Update(ctx context.Context, tag *models.TagLugar) error
 	Delete(ctx context.Context, id int) error
 }
@@ -73,8 +173,10 @@ type TagLugarRepository interface {
 // TagCancaoRepository defines the interface for tag_cancao operations
 type TagCancaoRepository interface {
 	GetByID(ctx context.Context, id int) (*models.TagCancao, error)
-	List(ctx context.Context) ([]*models.TagCancao, error)
+	List(ctx context.Context, page Pagination) ([]*models.TagCancao, error)
+	Count(ctx context.Context) (int, error)
 	Create(ctx context.Context, tag *models.TagCancao) (int, error)
+	GetOrCreate(ctx context.Context, tag *models.TagCancao) (*models.TagCancao, bool, error)
 	Update(ctx context.Context, tag *models.TagCancao) error
 	Delete(ctx context.Context, id int) error
 }
@@ -82,8 +184,25 @@ type TagCancaoRepository interface {
 // RamoRepository defines the interface for ramo operations
 type RamoRepository interface {
 	GetByID(ctx context.Context, id int) (*models.Ramo, error)
-	List(ctx context.Context) ([]*models.Ramo, error)
+	List(ctx context.Context, page Pagination) ([]*models.Ramo, error)
+	Count(ctx context.Context) (int, error)
+	ListSelectOptions(ctx context.Context, page Pagination) ([]*models.SelectOption, error)
 	Create(ctx context.Context, ramo *models.Ramo) (int, error)
+	GetOrCreate(ctx context.Context, ramo *models.Ramo) (*models.Ramo, bool, error)
+	Coverage(ctx context.Context, threshold int) ([]*models.RamoCoverage, error)
 	Update(ctx context.Context, ramo *models.Ramo) error
 	Delete(ctx context.Context, id int) error
-}
\ No newline at end of file
+}
+
+// AuditRepository defines the interface for reading the audit log, which the
+// other repositories write along with each change
+type AuditRepository interface {
+	ListHistory(ctx context.Context, resource string, resourceID int) ([]*models.AuditEntry, error)
+}
+
+// LogRepository defines the interface for reading the API logs
+type LogRepository interface {
+	ListRecent(ctx context.Context, level string, limit, offset int) ([]*models.LogRecord, error)
+	CountByLevel(ctx context.Context, level string) (int, error)
+	PurgeBefore(ctx context.Context, before time.Time) (removed int, more bool, err error)
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+const (
+	// purgeBatchSize is the number of log entries deleted by each statement of PurgeBefore
+	purgeBatchSize = 5000
+	// purgeMaxBatches is the number of batches deleted by one call of PurgeBefore
+	purgeMaxBatches = 20
+)
+
+// auditTable is the table of the audit log, which PurgeBefore never deletes from
+const auditTable = "audit_log"
+
+// PostgresLogRepository is an implementation of LogRepository using PostgreSQL.
+// It reads the table written by logger.DBLogger.
+type PostgresLogRepository struct {
+	db        *sql.DB
+	tableName string
+
+	batchSize  int
+	maxBatches int
+}
+
+// NewPostgresLogRepository creates a new PostgresLogRepository
+func NewPostgresLogRepository(db *sql.DB, tableName string) *PostgresLogRepository {
+	return &PostgresLogRepository{
+		db:         db,
+		tableName:  tableName,
+		batchSize:  purgeBatchSize,
+		maxBatches: purgeMaxBatches,
+	}
+}
+
+// ListRecent retrieves the log entries of a level, newest first. An empty
+// level lists the entries of every level.
+func (r *PostgresLogRepository) ListRecent(ctx context.Context, level string, limit, offset int) ([]*models.LogRecord, error) {
+	query := fmt.Sprintf(`
+		SELECT id, COALESCE(entry_id, ''), timestamp, level, message, service_name,
+		       COALESCE(request_id, ''), user_id, COALESCE(action, ''), COALESCE(resource, ''),
+		       COALESCE(resource_id, ''), metadata, COALESCE(error_message, '')
+		FROM %s
+		WHERE ($1 = '' OR level = $1)
+		ORDER BY timestamp DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`, r.tableName)
+
+	rows, err := r.db.QueryContext(ctx, query, level, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error listing logs: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*models.LogRecord{}
+	for rows.Next() {
+		var record models.LogRecord
+		var userID sql.NullInt64
+		var metadata []byte
+		if err := rows.Scan(
+			&record.ID,
+			&record.EntryID,
+			&record.Timestamp,
+			&record.Level,
+			&record.Message,
+			&record.ServiceName,
+			&record.RequestID,
+			&userID,
+			&record.Action,
+			&record.Resource,
+			&record.ResourceID,
+			&metadata,
+			&record.ErrorMessage,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning log entry: %w", err)
+		}
+
+		if userID.Valid {
+			id := int(userID.Int64)
+			record.UserID = &id
+		}
+
+		if len(metadata) > 0 {
+			if err := json.Unmarshal(metadata, &record.Metadata); err != nil {
+				return nil, fmt.Errorf("error decoding log metadata: %w", err)
+			}
+		}
+
+		records = append(records, &record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating log rows: %w", err)
+	}
+
+	return records, nil
+}
+
+// CountByLevel counts the log entries of a level, or of every level when it is empty
+func (r *PostgresLogRepository) CountByLevel(ctx context.Context, level string) (int, error) {
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE ($1 = '' OR level = $1)`, r.tableName)
+	return countRows(ctx, r.db, query, level)
+}
+
+// PurgeBefore deletes the log entries older than a cutoff and returns how many
+// were deleted. Entries are deleted in batches of purgeBatchSize, each in its
+// own statement, so the table is never locked for long and the logs written
+// meanwhile are not held up. At most purgeMaxBatches batches are deleted per
+// call; more reports that the limit was reached and older entries may remain,
+// so the caller should call again. The audit log is kept in its own table and
+// is never purged.
+func (r *PostgresLogRepository) PurgeBefore(ctx context.Context, before time.Time) (removed int, more bool, err error) {
+	if r.tableName == auditTable {
+		return 0, false, fmt.Errorf("refusing to purge the audit log")
+	}
+
+	query := fmt.Sprintf(`
+		DELETE FROM %[1]s
+		WHERE id IN (
+			SELECT id
+			FROM %[1]s
+			WHERE timestamp < $1
+			LIMIT $2
+		)
+	`, r.tableName)
+
+	for batch := 0; batch < r.maxBatches; batch++ {
+		result, err := r.db.ExecContext(ctx, query, before, r.batchSize)
+		if err != nil {
+			return removed, false, fmt.Errorf("error purging logs: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return removed, false, fmt.Errorf("error getting rows affected: %w", err)
+		}
+
+		removed += int(rowsAffected)
+		if rowsAffected < int64(r.batchSize) {
+			return removed, false, nil
+		}
+	}
+
+	return removed, true, nil
+}
@@ -0,0 +1,169 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// insertTestLog inserts an API log entry of a level at the given time and returns its ID
+func insertTestLog(t *testing.T, db *sql.DB, level string, timestamp time.Time) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`INSERT INTO api_logs (timestamp, level, message, service_name) VALUES ($1, $2, 'entry', 'test') RETURNING id`, timestamp, level).Scan(&id)
+	if err != nil {
+		t.Fatalf("inserting %s log: %v", level, err)
+	}
+	return id
+}
+
+func TestListRecentLogs(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLogRepository(db, "api_logs")
+	ctx := context.Background()
+
+	at := func(minute int) time.Time { return time.Date(2026, 10, 1, 12, minute, 0, 0, time.UTC) }
+	oldError := insertTestLog(t, db, "ERROR", at(1))
+	info := insertTestLog(t, db, "INFO", at(2))
+	newError := insertTestLog(t, db, "ERROR", at(3))
+	warn := insertTestLog(t, db, "WARN", at(4))
+	// Same time as newError: the higher ID comes first
+	tiedError := insertTestLog(t, db, "ERROR", at(3))
+
+	tests := []struct {
+		name          string
+		level         string
+		limit, offset int
+		want          []int
+		wantCount     int
+	}{
+		{"errors, newest first", "ERROR", 50, 0, []int{tiedError, newError, oldError}, 3},
+		{"every level", "", 50, 0, []int{warn, tiedError, newError, info, oldError}, 5},
+		{"one level only", "WARN", 50, 0, []int{warn}, 1},
+		{"level without entries", "FATAL", 50, 0, []int{}, 0},
+		{"first page", "ERROR", 2, 0, []int{tiedError, newError}, 3},
+		{"second page", "ERROR", 2, 2, []int{oldError}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := repo.ListRecent(ctx, tt.level, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("ListRecent: %v", err)
+			}
+			got := []int{}
+			for _, record := range records {
+				got = append(got, record.ID)
+				if tt.level != "" && record.Level != tt.level {
+					t.Errorf("record %d has level %s, want %s", record.ID, record.Level, tt.level)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+
+			count, err := repo.CountByLevel(ctx, tt.level)
+			if err != nil {
+				t.Fatalf("CountByLevel: %v", err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("count = %d, want %d", count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestPurgeBefore(t *testing.T) {
+	cutoff := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		old, recent int
+		batchSize   int
+		maxBatches  int
+		wantRemoved int
+		wantMore    bool
+	}{
+		{name: "only old entries", old: 3, recent: 2, batchSize: 10, maxBatches: 5, wantRemoved: 3},
+		{name: "several batches", old: 7, recent: 2, batchSize: 2, maxBatches: 5, wantRemoved: 7},
+		{name: "batch limit reached", old: 7, recent: 2, batchSize: 2, maxBatches: 2, wantRemoved: 4, wantMore: true},
+		{name: "nothing old", old: 0, recent: 2, batchSize: 2, maxBatches: 2, wantRemoved: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresLogRepository(db, "api_logs")
+			repo.batchSize, repo.maxBatches = tt.batchSize, tt.maxBatches
+			ctx := context.Background()
+
+			for i := 0; i < tt.old; i++ {
+				insertTestLog(t, db, "INFO", cutoff.Add(-time.Duration(i+1)*time.Hour))
+			}
+			var recent []int
+			for i := 0; i < tt.recent; i++ {
+				recent = append(recent, insertTestLog(t, db, "INFO", cutoff.Add(time.Duration(i)*time.Hour)))
+			}
+			// An audit entry older than the cutoff, which must be kept
+			mustExec(t, db, `INSERT INTO audit_log (timestamp, resource, resource_id, action) VALUES ($1, 'lugares', 1, 'update')`, cutoff.AddDate(-1, 0, 0))
+
+			removed, more, err := repo.PurgeBefore(ctx, cutoff)
+			if err != nil {
+				t.Fatalf("PurgeBefore: %v", err)
+			}
+			if removed != tt.wantRemoved || more != tt.wantMore {
+				t.Errorf("PurgeBefore = (%d, %v), want (%d, %v)", removed, more, tt.wantRemoved, tt.wantMore)
+			}
+
+			var remainingOld int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM api_logs WHERE timestamp < $1`, cutoff).Scan(&remainingOld); err != nil {
+				t.Fatalf("counting old entries: %v", err)
+			}
+			if want := tt.old - tt.wantRemoved; remainingOld != want {
+				t.Errorf("%d old entries remain, want %d", remainingOld, want)
+			}
+			records, err := repo.ListRecent(ctx, "", 50, 0)
+			if err != nil {
+				t.Fatalf("ListRecent: %v", err)
+			}
+			kept := map[int]bool{}
+			for _, record := range records {
+				kept[record.ID] = true
+			}
+			for _, id := range recent {
+				if !kept[id] {
+					t.Errorf("recent entry %d was deleted", id)
+				}
+			}
+			var audits int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM audit_log`).Scan(&audits); err != nil {
+				t.Fatalf("counting audit entries: %v", err)
+			}
+			if audits != 1 {
+				t.Errorf("%d audit entries remain, want 1", audits)
+			}
+		})
+	}
+}
+
+func TestPurgeBeforeRefusesTheAuditLog(t *testing.T) {
+	db := newTestDB(t)
+	mustExec(t, db, `INSERT INTO audit_log (timestamp, resource, resource_id, action) VALUES ('2020-01-01', 'lugares', 1, 'update')`)
+
+	_, _, err := NewPostgresLogRepository(db, "audit_log").PurgeBefore(context.Background(), time.Now())
+	if err == nil {
+		t.Fatal("purging the audit log succeeded")
+	}
+
+	var audits int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM audit_log`).Scan(&audits); err != nil {
+		t.Fatalf("counting audit entries: %v", err)
+	}
+	if audits != 1 {
+		t.Errorf("%d audit entries remain, want 1", audits)
+	}
+}
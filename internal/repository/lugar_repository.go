@@ -3,15 +3,24 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/site-geav-api/internal/models"
 )
 
 // PostgresLugarRepository is an implementation of LugarRepository using PostgreSQL
 type PostgresLugarRepository struct {
 	db *sql.DB
+
+	// unaccent reports whether the unaccent extension is installed; it is
+	// checked on the first address search that reaches the database
+	unaccentMu      sync.Mutex
+	unaccentChecked bool
+	unaccent        bool
 }
 
 // NewPostgresLugarRepository creates a new PostgresLugarRepository
@@ -19,22 +28,33 @@ func NewPostgresLugarRepository(db *sql.DB) *PostgresLugarRepository {
 	return &PostgresLugarRepository{db: db}
 }
 
-// GetByID retrieves a place by ID
-func (r *PostgresLugarRepository) GetByID(ctx context.Context, id int) (*models.Lugar, error) {
-	query := `
-		SELECT l.id, l.nome_local, l.nome_dono_local, l.telefone_para_contato, 
-		       l.link_google_maps, l.link_site, l.endereco_completo, 
+// lugarSelect is the base query shared by the methods that return full places.
+// Optional columns are coalesced so partially filled rows scan into empty values.
+const lugarSelect = `
+		SELECT l.id, l.nome_local,
+		       COALESCE(l.nome_dono_local, '') as nome_dono_local,
+		       COALESCE(l.telefone_para_contato, 0) as telefone_para_contato,
+		       COALESCE(l.link_google_maps, '') as link_google_maps,
+		       COALESCE(l.link_site, '') as link_site,
+		       COALESCE(l.endereco_completo, '') as endereco_completo,
 		       l.local_publico, l.valor_fixo, l.valor_individual, 
-		       l.user_id, l.created_at, l.updated_at,
+		       l.latitude, l.longitude, l.published,
+		       l.user_id, l.created_at, l.updated_at, l.deleted_at,
 		       COALESCE(lwr.average_rating, 0) as average_rating,
 		       COALESCE(lwr.rating_count, 0) as rating_count
 		FROM lugares l
 		LEFT JOIN lugares_with_ratings lwr ON l.id = lwr.id
-		WHERE l.id = $1
-	`
+`
 
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanLugar scans a row selected with lugarSelect into a place
+func scanLugar(row rowScanner) (*models.Lugar, error) {
 	var lugar models.Lugar
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&lugar.ID,
 		&lugar.NomeLocal,
 		&lugar.NomeDonoLocal,
@@ -45,87 +65,63 @@ func (r *PostgresLugarRepository) GetByID(ctx context.Context, id int) (*models.
 		&lugar.LocalPublico,
 		&lugar.ValorFixo,
 		&lugar.ValorIndividual,
+		&lugar.Latitude,
+		&lugar.Longitude,
+		&lugar.Published,
 		&lugar.UserID,
 		&lugar.CreatedAt,
 		&lugar.UpdatedAt,
+		&lugar.DeletedAt,
 		&lugar.AverageRating,
 		&lugar.RatingCount,
 	)
-
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Return nil without error to indicate not found
-		}
-		return nil, fmt.Errorf("error getting lugar by ID: %w", err)
+		return nil, err
 	}
+	return &lugar, nil
+}
 
+// loadRelated fills the images, tags and ramos of a place
+func (r *PostgresLugarRepository) loadRelated(ctx context.Context, lugar *models.Lugar) error {
 	// Get images
 	images, err := r.GetImages(ctx, lugar.ID)
 	if err != nil {
-		return nil, fmt.Errorf("error getting images for lugar: %w", err)
+		return fmt.Errorf("error getting images for lugar: %w", err)
 	}
 	lugar.Images = images
 
 	// Get tags
 	tags, err := r.GetTags(ctx, lugar.ID)
 	if err != nil {
-		return nil, fmt.Errorf("error getting tags for lugar: %w", err)
+		return fmt.Errorf("error getting tags for lugar: %w", err)
 	}
 	lugar.Tags = tags
 
 	// Get ramos
 	ramos, err := r.GetRamos(ctx, lugar.ID)
 	if err != nil {
-		return nil, fmt.Errorf("error getting ramos for lugar: %w", err)
+		return fmt.Errorf("error getting ramos for lugar: %w", err)
 	}
 	lugar.Ramos = ramos
 
-	return &lugar, nil
+	return nil
 }
 
-// List retrieves all places
-func (r *PostgresLugarRepository) List(ctx context.Context) ([]*models.Lugar, error) {
-	query := `
-		SELECT l.id, l.nome_local, l.nome_dono_local, l.telefone_para_contato, 
-		       l.link_google_maps, l.link_site, l.endereco_completo, 
-		       l.local_publico, l.valor_fixo, l.valor_individual, 
-		       l.user_id, l.created_at, l.updated_at,
-		       COALESCE(lwr.average_rating, 0) as average_rating,
-		       COALESCE(lwr.rating_count, 0) as rating_count
-		FROM lugares l
-		LEFT JOIN lugares_with_ratings lwr ON l.id = lwr.id
-		ORDER BY l.id
-	`
-
-	rows, err := r.db.QueryContext(ctx, query)
+// queryLugares runs a query built on lugarSelect and returns the places with their related entities
+func (r *PostgresLugarRepository) queryLugares(ctx context.Context, query string, args ...interface{}) ([]*models.Lugar, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error listing lugares: %w", err)
 	}
 	defer rows.Close()
 
-	var lugares []*models.Lugar
+	lugares := []*models.Lugar{}
 	for rows.Next() {
-		var lugar models.Lugar
-		if err := rows.Scan(
-			&lugar.ID,
-			&lugar.NomeLocal,
-			&lugar.NomeDonoLocal,
-			&lugar.TelefoneParaContato,
-			&lugar.LinkGoogleMaps,
-			&lugar.LinkSite,
-			&lugar.EnderecoCompleto,
-			&lugar.LocalPublico,
-			&lugar.ValorFixo,
-			&lugar.ValorIndividual,
-			&lugar.UserID,
-			&lugar.CreatedAt,
-			&lugar.UpdatedAt,
-			&lugar.AverageRating,
-			&lugar.RatingCount,
-		); err != nil {
+		lugar, err := scanLugar(rows)
+		if err != nil {
 			return nil, fmt.Errorf("error scanning lugar row: %w", err)
 		}
-		lugares = append(lugares, &lugar)
+		lugares = append(lugares, lugar)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -134,46 +130,395 @@ func (r *PostgresLugarRepository) List(ctx context.Context) ([]*models.Lugar, er
 
 	// Get related entities for each lugar
 	for _, lugar := range lugares {
-		// Get images
-		images, err := r.GetImages(ctx, lugar.ID)
-		if err != nil {
-			return nil, fmt.Errorf("error getting images for lugar: %w", err)
+		if err := r.loadRelated(ctx, lugar); err != nil {
+			return nil, err
 		}
-		lugar.Images = images
+	}
 
-		// Get tags
-		tags, err := r.GetTags(ctx, lugar.ID)
-		if err != nil {
-			return nil, fmt.Errorf("error getting tags for lugar: %w", err)
+	return lugares, nil
+}
+
+// GetByID retrieves a place by ID, unless it was deleted
+func (r *PostgresLugarRepository) GetByID(ctx context.Context, id int) (*models.Lugar, error) {
+	return r.getByID(ctx, id, false)
+}
+
+// GetByIDIncludingDeleted retrieves a place by ID, even if it was deleted
+func (r *PostgresLugarRepository) GetByIDIncludingDeleted(ctx context.Context, id int) (*models.Lugar, error) {
+	return r.getByID(ctx, id, true)
+}
+
+// getByID retrieves a place by ID, optionally including deleted places
+func (r *PostgresLugarRepository) getByID(ctx context.Context, id int, includeDeleted bool) (*models.Lugar, error) {
+	query := lugarSelect + `
+		WHERE l.id = $1 AND ($2 OR l.deleted_at IS NULL)
+	`
+
+	lugar, err := scanLugar(r.db.QueryRowContext(ctx, query, id, includeDeleted))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Return nil without error to indicate not found
 		}
-		lugar.Tags = tags
+		return nil, fmt.Errorf("error getting lugar by ID: %w", err)
+	}
 
-		// Get ramos
-		ramos, err := r.GetRamos(ctx, lugar.ID)
-		if err != nil {
-			return nil, fmt.Errorf("error getting ramos for lugar: %w", err)
+	if err := r.loadRelated(ctx, lugar); err != nil {
+		return nil, err
+	}
+
+	return lugar, nil
+}
+
+// lugarSortOrders maps the accepted sort values to their ORDER BY clauses
+var lugarSortOrders = map[string]string{
+	"": "l.id",
+	"tag_count": `(
+		SELECT COUNT(*)
+		FROM lugares_tags lt
+		WHERE lt.lugar_id = l.id
+	) DESC, l.id`,
+}
+
+// IsValidLugarSort checks if the sort value is accepted when listing places
+func IsValidLugarSort(sort string) bool {
+	_, ok := lugarSortOrders[sort]
+	return ok
+}
+
+// List retrieves all places matching the options
+func (r *PostgresLugarRepository) List(ctx context.Context, opts LugarListOptions) ([]*models.Lugar, error) {
+	builder := r.listBuilder(ctx, opts)
+	if err := builder.OrderBy(opts.Sort, lugarSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(opts.Pagination).Build()
+
+	return r.queryLugares(ctx, query, args...)
+}
+
+// Count counts the places matching the options, ignoring their pagination
+func (r *PostgresLugarRepository) Count(ctx context.Context, opts LugarListOptions) (int, error) {
+	query, args := r.listBuilder(ctx, opts).BuildCount()
+	return countRows(ctx, r.db, query, args...)
+}
+
+// DailyCreationCounts counts the places created on each day from the day of
+// from to the day of to, both included, in UTC, leaving out deleted places.
+// Days without places have a count of 0, so every day of the range is returned.
+func (r *PostgresLugarRepository) DailyCreationCounts(ctx context.Context, from, to time.Time) ([]*models.DailyCount, error) {
+	from = truncateToDay(from)
+	to = truncateToDay(to)
+
+	query := `
+		SELECT date_trunc('day', created_at AT TIME ZONE 'UTC') AS day, COUNT(*)
+		FROM lugares
+		WHERE created_at >= $1 AND created_at < $2 AND deleted_at IS NULL
+		GROUP BY day
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("error counting lugares per day: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var day time.Time
+		var count int
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, fmt.Errorf("error scanning daily count row: %w", err)
 		}
-		lugar.Ramos = ramos
+		counts[day.Format("2006-01-02")] = count
 	}
 
-	return lugares, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily count rows: %w", err)
+	}
+
+	// Fill the days without places with zeros
+	days := []*models.DailyCount{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		days = append(days, &models.DailyCount{Date: date, Count: counts[date]})
+	}
+
+	return days, nil
 }
 
-// Create creates a new place
-func (r *PostgresLugarRepository) Create(ctx context.Context, lugar *models.Lugar) (int, error) {
+// truncateToDay returns the start of the UTC day of t
+func truncateToDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// viewerContextKey is the context key of the Viewer of a listing
+type viewerContextKey struct{}
+
+// Viewer is the caller a listing of places is made for. Handlers add it to the
+// context with WithViewer; places that were not published yet are only listed
+// for admins and for their owners. Without a Viewer, only published places are
+// listed.
+type Viewer struct {
+	UserID int
+	Admin  bool
+}
+
+// WithViewer returns a copy of ctx carrying the caller of the listings made with it
+func WithViewer(ctx context.Context, viewer Viewer) context.Context {
+	return context.WithValue(ctx, viewerContextKey{}, viewer)
+}
+
+// publishedCondition returns the condition keeping the places of alias that
+// the Viewer of ctx may see, along with its arguments. placeholder stands for
+// the user ID of the viewer: "?" in a queryBuilder, or a numbered parameter.
+func publishedCondition(ctx context.Context, alias, placeholder string) (string, []interface{}) {
+	viewer, _ := ctx.Value(viewerContextKey{}).(Viewer)
+	switch {
+	case viewer.Admin:
+		return "TRUE", nil
+	case viewer.UserID != 0:
+		return fmt.Sprintf("(%s.published OR %s.user_id = %s)", alias, alias, placeholder), []interface{}{viewer.UserID}
+	default:
+		return alias + ".published", nil
+	}
+}
+
+// listBuilder builds the query selecting the places matching the options
+func (r *PostgresLugarRepository) listBuilder(ctx context.Context, opts LugarListOptions) *queryBuilder {
+	builder := newQueryBuilder(lugarSelect)
+	if !opts.IncludeDeleted {
+		builder.Where("l.deleted_at IS NULL")
+	}
+	if !opts.IncludeUnpublished {
+		builder.Where("l.published")
+	}
+	if len(opts.RamoIDs) > 0 {
+		builder.Where(`EXISTS (
+			SELECT 1
+			FROM lugares_ramos lr
+			WHERE lr.lugar_id = l.id AND lr.ramo_id = ANY(?)
+		)`, pq.Array(opts.RamoIDs))
+	}
+	if opts.Unrated {
+		builder.Where("COALESCE(lwr.rating_count, 0) = 0")
+	}
+	if opts.UserID != 0 {
+		builder.Where("l.user_id = ?", opts.UserID)
+	}
+	if opts.MaxValorIndividual != nil {
+		// A place without an individual price is only kept when it is public
+		builder.Where("(l.local_publico OR (l.valor_individual IS NOT NULL AND l.valor_individual <= ?))", *opts.MaxValorIndividual)
+	}
+	if opts.Address != "" {
+		pattern := "%" + escapeLike(opts.Address) + "%"
+		if r.hasUnaccent(ctx) {
+			builder.Where("unaccent(COALESCE(l.endereco_completo, '')) ILIKE unaccent(?)", pattern)
+		} else {
+			builder.Where("COALESCE(l.endereco_completo, '') ILIKE ?", pattern)
+		}
+	}
+	return builder
+}
+
+// ListByRamos retrieves the places associated with any of the given ramos
+func (r *PostgresLugarRepository) ListByRamos(ctx context.Context, ramoIDs []int, page Pagination) ([]*models.Lugar, error) {
+	return r.List(ctx, LugarListOptions{RamoIDs: ramoIDs, Pagination: page})
+}
+
+// SearchByAddress retrieves the places whose address contains the given text,
+// ignoring case, and accents when the unaccent extension is installed
+func (r *PostgresLugarRepository) SearchByAddress(ctx context.Context, address string, page Pagination) ([]*models.Lugar, error) {
+	return r.List(ctx, LugarListOptions{Address: address, Pagination: page})
+}
+
+// hasUnaccent checks whether the unaccent extension is installed, caching the answer
+func (r *PostgresLugarRepository) hasUnaccent(ctx context.Context) bool {
+	r.unaccentMu.Lock()
+	defer r.unaccentMu.Unlock()
+
+	if !r.unaccentChecked {
+		query := `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'unaccent')`
+		if err := r.db.QueryRowContext(ctx, query).Scan(&r.unaccent); err != nil {
+			// Search without unaccent this time and check again next time
+			return false
+		}
+		r.unaccentChecked = true
+	}
+
+	return r.unaccent
+}
+
+// lugarOptionOrders maps the accepted sort values of the place options to their ORDER BY clauses
+var lugarOptionOrders = map[string]string{
+	"": "l.nome_local, l.id",
+}
+
+// ListSelectOptions lists the ID and name of the places that were not deleted
+// and that the viewer may see, by name
+func (r *PostgresLugarRepository) ListSelectOptions(ctx context.Context, page Pagination) ([]*models.SelectOption, error) {
+	published, publishedArgs := publishedCondition(ctx, "l", "?")
+	builder := newQueryBuilder(`
+		SELECT l.id, l.nome_local
+		FROM lugares l`).
+		Where("l.deleted_at IS NULL").
+		Where(published, publishedArgs...)
+	if err := builder.OrderBy("", lugarOptionOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
+
+	return querySelectOptions(ctx, r.db, query, args...)
+}
+
+// ListByUser retrieves a page of the places created by a user that the viewer may see
+func (r *PostgresLugarRepository) ListByUser(ctx context.Context, userID int, page Pagination) ([]*models.Lugar, error) {
+	published, publishedArgs := publishedCondition(ctx, "l", "?")
+	builder := newQueryBuilder(lugarSelect).
+		Where("l.user_id = ?", userID).
+		Where("l.deleted_at IS NULL").
+		Where(published, publishedArgs...)
+	if err := builder.OrderBy("", lugarSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
+
+	return r.queryLugares(ctx, query, args...)
+}
+
+// ListInBoundingBox retrieves the places whose coordinates fall inside the given box
+func (r *PostgresLugarRepository) ListInBoundingBox(ctx context.Context, minLat, minLng, maxLat, maxLng float64, page Pagination) ([]*models.Lugar, error) {
+	builder := boundingBoxBuilder(ctx, minLat, minLng, maxLat, maxLng)
+	if err := builder.OrderBy("", lugarSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
+
+	return r.queryLugares(ctx, query, args...)
+}
+
+// CountInBoundingBox counts the places whose coordinates fall inside a bounding box
+func (r *PostgresLugarRepository) CountInBoundingBox(ctx context.Context, minLat, minLng, maxLat, maxLng float64) (int, error) {
+	query, args := boundingBoxBuilder(ctx, minLat, minLng, maxLat, maxLng).BuildCount()
+	return countRows(ctx, r.db, query, args...)
+}
+
+// boundingBoxBuilder builds the query selecting the places inside a bounding
+// box that the viewer may see
+func boundingBoxBuilder(ctx context.Context, minLat, minLng, maxLat, maxLng float64) *queryBuilder {
+	published, publishedArgs := publishedCondition(ctx, "l", "?")
+	return newQueryBuilder(lugarSelect).
+		Where("l.deleted_at IS NULL").
+		Where(published, publishedArgs...).
+		Where("l.latitude BETWEEN ? AND ?", minLat, maxLat).
+		Where("l.longitude BETWEEN ? AND ?", minLng, maxLng)
+}
+
+// ListNearest retrieves the places with coordinates, nearest to a point first.
+// The distance is the great-circle (haversine) distance.
+func (r *PostgresLugarRepository) ListNearest(ctx context.Context, lat, lng float64, page Pagination) ([]*models.Lugar, error) {
+	published, publishedArgs := publishedCondition(ctx, "l", "$5")
+	query := lugarSelect + `
+		WHERE l.deleted_at IS NULL AND ` + published + `
+		  AND l.latitude IS NOT NULL AND l.longitude IS NOT NULL
+		ORDER BY ASIN(LEAST(1, SQRT(
+		           POWER(SIN(RADIANS(l.latitude - $1) / 2), 2) +
+		           COS(RADIANS($1)) * COS(RADIANS(l.latitude)) * POWER(SIN(RADIANS(l.longitude - $2) / 2), 2)
+		         ))), l.id
+		LIMIT $3 OFFSET $4
+	`
+
+	args := append([]interface{}{lat, lng, page.Limit, page.Offset}, publishedArgs...)
+	return r.queryLugares(ctx, query, args...)
+}
+
+// CountWithCoordinates counts the places that have coordinates and that the viewer may see
+func (r *PostgresLugarRepository) CountWithCoordinates(ctx context.Context) (int, error) {
+	published, args := publishedCondition(ctx, "l", "$1")
 	query := `
-		INSERT INTO lugares (
-			nome_local, nome_dono_local, telefone_para_contato, 
-			link_google_maps, link_site, endereco_completo, 
-			local_publico, valor_fixo, valor_individual, 
-			user_id, created_at, updated_at
-		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		RETURNING id
+		SELECT COUNT(*)
+		FROM lugares l
+		WHERE l.deleted_at IS NULL AND ` + published + `
+		  AND l.latitude IS NOT NULL AND l.longitude IS NOT NULL
 	`
+	return countRows(ctx, r.db, query, args...)
+}
+
+// similarityThreshold is the minimum trigram similarity for two places to be considered alike
+const similarityThreshold = 0.3
+
+// FindSimilar retrieves the places whose name or address look like the given ones, most similar first
+func (r *PostgresLugarRepository) FindSimilar(ctx context.Context, nomeLocal, enderecoCompleto string) ([]*models.Lugar, error) {
+	published, publishedArgs := publishedCondition(ctx, "l", "$4")
+	query := lugarSelect + `
+		WHERE l.deleted_at IS NULL AND ` + published + `
+		  AND (similarity(l.nome_local, $1) > $3
+		       OR similarity(COALESCE(l.endereco_completo, ''), $2) > $3)
+		ORDER BY GREATEST(
+		           similarity(l.nome_local, $1),
+		           similarity(COALESCE(l.endereco_completo, ''), $2)
+		         ) DESC, l.id
+		LIMIT 10
+	`
+
+	args := append([]interface{}{nomeLocal, enderecoCompleto, similarityThreshold}, publishedArgs...)
+	return r.queryLugares(ctx, query, args...)
+}
+
+// Exists checks if a place exists without loading it
+func (r *PostgresLugarRepository) Exists(ctx context.Context, id int) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM lugares WHERE id = $1 AND deleted_at IS NULL)`
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return false, fmt.Errorf("error checking lugar existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+// Create creates a new place and records it in the audit log
+func (r *PostgresLugarRepository) Create(ctx context.Context, lugar *models.Lugar) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
 
 	var id int
-	err := r.db.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, createLugarQuery, createLugarArgs(lugar)...).Scan(&id)
+
+	if err != nil {
+		return 0, fmt.Errorf("error creating lugar: %w", err)
+	}
+
+	if err := recordAudit(ctx, tx, "lugares", id, "CreateLugar"); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing lugar: %w", err)
+	}
+
+	return id, nil
+}
+
+// createLugarQuery inserts a place; its arguments are built by createLugarArgs
+const createLugarQuery = `
+	INSERT INTO lugares (
+		nome_local, nome_dono_local, telefone_para_contato, 
+		link_google_maps, link_site, endereco_completo, 
+		local_publico, valor_fixo, valor_individual, 
+		latitude, longitude,
+		user_id, created_at, updated_at
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	RETURNING id
+`
+
+// createLugarArgs returns the arguments of createLugarQuery for a place
+func createLugarArgs(lugar *models.Lugar) []interface{} {
+	return []interface{}{
 		lugar.NomeLocal,
 		lugar.NomeDonoLocal,
 		lugar.TelefoneParaContato,
@@ -183,32 +528,151 @@ func (r *PostgresLugarRepository) Create(ctx context.Context, lugar *models.Luga
 		lugar.LocalPublico,
 		lugar.ValorFixo,
 		lugar.ValorIndividual,
+		lugar.Latitude,
+		lugar.Longitude,
 		lugar.UserID,
 		lugar.CreatedAt,
 		lugar.UpdatedAt,
-	).Scan(&id)
+	}
+}
+
+// Import creates several places in one transaction, creating their tags and
+// ramos by name when they do not exist yet and linking them. Each place is
+// imported under its own savepoint, so a failing place is reported in its
+// result and does not prevent the others from being imported.
+func (r *PostgresLugarRepository) Import(ctx context.Context, lugares []*models.Lugar) ([]models.LugarImportResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]models.LugarImportResult, len(lugares))
+	for i, lugar := range lugares {
+		results[i].Index = i
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT import_lugar"); err != nil {
+			return nil, fmt.Errorf("error creating savepoint: %w", err)
+		}
+
+		id, err := importLugar(ctx, tx, lugar)
+		if err != nil {
+			if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_lugar"); rbErr != nil {
+				return nil, fmt.Errorf("error rolling back to savepoint: %w", rbErr)
+			}
+			results[i].Error = importErrorMessage(err)
+			results[i].Err = err
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT import_lugar"); err != nil {
+			return nil, fmt.Errorf("error releasing savepoint: %w", err)
+		}
+		results[i].ID = id
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing import: %w", err)
+	}
+
+	return results, nil
+}
+
+// importErrorMessage returns the message reported for a place that could not
+// be imported. Database errors are never reported as is, since they describe
+// the schema rather than the data.
+func importErrorMessage(err error) string {
+	switch {
+	case isUniqueViolation(err):
+		return "Duplicate lugar"
+	case errors.Is(err, ErrInvalidReference), isForeignKeyViolation(err):
+		return "Invalid reference"
+	default:
+		return "Internal error"
+	}
+}
 
+// importLugar creates a place and links its tags and ramos, creating them by name if needed
+func importLugar(ctx context.Context, tx *sql.Tx, lugar *models.Lugar) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx, createLugarQuery, createLugarArgs(lugar)...).Scan(&id)
 	if err != nil {
+		if isForeignKeyViolation(err) {
+			return 0, fmt.Errorf("user %d: %w", lugar.UserID, ErrInvalidReference)
+		}
 		return 0, fmt.Errorf("error creating lugar: %w", err)
 	}
 
+	if err := recordAudit(ctx, tx, "lugares", id, "ImportLugares"); err != nil {
+		return 0, err
+	}
+
+	for _, tag := range lugar.Tags {
+		tagID, err := getOrCreateByName(ctx, tx, "tags_lugares", tag.Name)
+		if err != nil {
+			return 0, fmt.Errorf("error importing tag %q: %w", tag.Name, err)
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO lugares_tags (lugar_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			id, tagID)
+		if err != nil {
+			return 0, fmt.Errorf("error adding tag %q to lugar: %w", tag.Name, err)
+		}
+	}
+
+	for _, ramo := range lugar.Ramos {
+		ramoID, err := getOrCreateByName(ctx, tx, "ramos", ramo.Name)
+		if err != nil {
+			return 0, fmt.Errorf("error importing ramo %q: %w", ramo.Name, err)
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO lugares_ramos (lugar_id, ramo_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			id, ramoID)
+		if err != nil {
+			return 0, fmt.Errorf("error adding ramo %q to lugar: %w", ramo.Name, err)
+		}
+	}
+
+	return id, nil
+}
+
+// getOrCreateByName returns the ID of the row of table with the given name,
+// inserting it first if it does not exist. table must be a trusted constant.
+func getOrCreateByName(ctx context.Context, tx *sql.Tx, table, name string) (int, error) {
+	query := `
+		INSERT INTO ` + table + ` (name) VALUES ($1)
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id
+	`
+
+	var id int
+	if err := tx.QueryRowContext(ctx, query, name).Scan(&id); err != nil {
+		return 0, err
+	}
 	return id, nil
 }
 
-// Update updates an existing place
+// Update updates an existing place and records the change in the audit log.
+// The owner is left unchanged; it only changes through ChangeOwner.
 func (r *PostgresLugarRepository) Update(ctx context.Context, lugar *models.Lugar) error {
 	query := `
 		UPDATE lugares
 		SET nome_local = $1, nome_dono_local = $2, telefone_para_contato = $3, 
 		    link_google_maps = $4, link_site = $5, endereco_completo = $6, 
 		    local_publico = $7, valor_fixo = $8, valor_individual = $9, 
-		    user_id = $10, updated_at = $11
-		WHERE id = $12
+		    latitude = $10, longitude = $11, updated_at = $12
+		WHERE id = $13 AND deleted_at IS NULL
 	`
 
-	lugar.UpdatedAt = time.Now()
+	lugar.UpdatedAt = time.Now().UTC()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	result, err := r.db.ExecContext(ctx, query,
+	result, err := tx.ExecContext(ctx, query,
 		lugar.NomeLocal,
 		lugar.NomeDonoLocal,
 		lugar.TelefoneParaContato,
@@ -218,7 +682,8 @@ func (r *PostgresLugarRepository) Update(ctx context.Context, lugar *models.Luga
 		lugar.LocalPublico,
 		lugar.ValorFixo,
 		lugar.ValorIndividual,
-		lugar.UserID,
+		lugar.Latitude,
+		lugar.Longitude,
 		lugar.UpdatedAt,
 		lugar.ID,
 	)
@@ -236,17 +701,34 @@ func (r *PostgresLugarRepository) Update(ctx context.Context, lugar *models.Luga
 		return fmt.Errorf("lugar with ID %d not found", lugar.ID)
 	}
 
+	if err := recordAudit(ctx, tx, "lugares", lugar.ID, "UpdateLugar"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing lugar update: %w", err)
+	}
+
 	return nil
 }
 
-// Delete deletes a place by ID
+// Delete soft-deletes a place by ID: it is kept, with deleted_at set, but
+// left out of every query unless deleted places are explicitly requested. The
+// deletion is recorded in the audit log.
 func (r *PostgresLugarRepository) Delete(ctx context.Context, id int) error {
 	query := `
-		DELETE FROM lugares
-		WHERE id = $1
+		UPDATE lugares
+		SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("error deleting lugar: %w", err)
 	}
@@ -260,151 +742,738 @@ func (r *PostgresLugarRepository) Delete(ctx context.Context, id int) error {
 		return fmt.Errorf("lugar with ID %d not found", id)
 	}
 
+	if err := recordAudit(ctx, tx, "lugares", id, "DeleteLugar"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing lugar deletion: %w", err)
+	}
+
 	return nil
 }
 
-// AddImage adds an image to a place
-func (r *PostgresLugarRepository) AddImage(ctx context.Context, image *models.LugarImage) (int, error) {
+// Publish marks a place as published and records it in the audit log. The
+// caller checks that the place is complete.
+func (r *PostgresLugarRepository) Publish(ctx context.Context, id int) error {
 	query := `
-		INSERT INTO lugares_images (lugar_id, image_url, display_order, created_at)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id
+		UPDATE lugares
+		SET published = true, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
-	var id int
-	err := r.db.QueryRowContext(ctx, query,
-		image.LugarID,
-		image.ImageURL,
-		image.DisplayOrder,
-		image.CreatedAt,
-	).Scan(&id)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
 
+	result, err := tx.ExecContext(ctx, query, id)
 	if err != nil {
-		return 0, fmt.Errorf("error adding image to lugar: %w", err)
+		return fmt.Errorf("error publishing lugar: %w", err)
 	}
 
-	return id, nil
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("lugar with ID %d not found", id)
+	}
+
+	if err := recordAudit(ctx, tx, "lugares", id, "PublishLugar"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing lugar publication: %w", err)
+	}
+
+	return nil
 }
 
-// DeleteImage deletes an image from a place
-func (r *PostgresLugarRepository) DeleteImage(ctx context.Context, imageID int) error {
+// Touch sets the updated_at of a place to the current time without changing
+// anything else, and returns the new timestamp. It returns nil if the place
+// does not exist or was deleted.
+func (r *PostgresLugarRepository) Touch(ctx context.Context, id int) (*time.Time, error) {
 	query := `
-		DELETE FROM lugares_images
-		WHERE id = $1
+		UPDATE lugares
+		SET updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING updated_at
+	`
+
+	var updatedAt time.Time
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&updatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error touching lugar: %w", err)
+	}
+
+	return &updatedAt, nil
+}
+
+// ChangeOwner transfers a place to another user and records it in the audit
+// log, returning ErrInvalidReference when the user does not exist
+func (r *PostgresLugarRepository) ChangeOwner(ctx context.Context, id, userID int) error {
+	query := `
+		UPDATE lugares
+		SET user_id = $1, updated_at = $2
+		WHERE id = $3 AND deleted_at IS NULL
+	`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, userID, time.Now().UTC(), id)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("user with ID %d: %w", userID, ErrInvalidReference)
+		}
+		return fmt.Errorf("error changing lugar owner: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("lugar with ID %d not found", id)
+	}
+
+	if err := recordAudit(ctx, tx, "lugares", id, "ChangeLugarOwner"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing lugar owner change: %w", err)
+	}
+
+	return nil
+}
+
+// AddImage adds an image to a place, see AddImages for maxImages
+func (r *PostgresLugarRepository) AddImage(ctx context.Context, image *models.LugarImage, maxImages int) (int, error) {
+	if err := r.AddImages(ctx, []*models.LugarImage{image}, maxImages); err != nil {
+		return 0, err
+	}
+
+	return image.ID, nil
+}
+
+// AddImages adds several images in one transaction, in the given order; either
+// all of them are added or none is. The IDs and display orders are set on the images.
+// When maxImages is positive, ErrImageLimit is returned if a place would end up
+// with more images; the images are counted under the lock of lockImageOrder, so
+// concurrent uploads cannot pass the limit together.
+func (r *PostgresLugarRepository) AddImages(ctx context.Context, images []*models.LugarImage, maxImages int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Number of images of each locked place
+	counts := map[int]int{}
+	for _, image := range images {
+		if _, locked := counts[image.LugarID]; !locked {
+			if err := lockImageOrder(ctx, tx, image.LugarID); err != nil {
+				return err
+			}
+			var count int
+			err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM lugares_images WHERE lugar_id = $1`, image.LugarID).Scan(&count)
+			if err != nil {
+				return fmt.Errorf("error counting lugar images: %w", err)
+			}
+			counts[image.LugarID] = count
+		}
+		if maxImages > 0 && counts[image.LugarID] >= maxImages {
+			return fmt.Errorf("lugar %d already has %d images: %w", image.LugarID, counts[image.LugarID], ErrImageLimit)
+		}
+
+		err := tx.QueryRowContext(ctx, addImageQuery,
+			image.LugarID,
+			image.ImageURL,
+			image.DisplayOrder,
+			image.CreatedAt,
+		).Scan(&image.ID, &image.DisplayOrder)
+
+		if err != nil {
+			return addImageError(err, image)
+		}
+		counts[image.LugarID]++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing images: %w", err)
+	}
+
+	return nil
+}
+
+// lockImageOrder locks the row of a place until the end of the transaction, so
+// concurrent transactions changing the display order of its images run one
+// after the other. Without it, two images added at the same time could both
+// read the same last order and be given the same next one.
+func lockImageOrder(ctx context.Context, tx *sql.Tx, lugarID int) error {
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM lugares WHERE id = $1 FOR UPDATE`, lugarID); err != nil {
+		return fmt.Errorf("error locking lugar images: %w", err)
+	}
+	return nil
+}
+
+// addImageQuery inserts an image. A display order of zero takes the position
+// after the last image; an explicit order already used by another image
+// violates the unique constraint.
+const addImageQuery = `
+	INSERT INTO lugares_images (lugar_id, image_url, display_order, created_at)
+	SELECT $1, $2,
+	       CASE WHEN $3::integer > 0 THEN $3::integer ELSE COALESCE(MAX(display_order), 0) + 1 END,
+	       $4
+	FROM lugares_images
+	WHERE lugar_id = $1
+	RETURNING id, display_order
+`
+
+// addImageError wraps an error from addImageQuery, returning ErrAlreadyExists for a display order in use
+func addImageError(err error, image *models.LugarImage) error {
+	if isUniqueViolation(err) {
+		return fmt.Errorf("display order %d: %w", image.DisplayOrder, ErrAlreadyExists)
+	}
+	return fmt.Errorf("error adding image to lugar: %w", err)
+}
+
+// DeleteImage deletes an image from a place. With compact, the remaining
+// images of the place are then renumbered 1, 2, 3... in the same transaction,
+// so the display order has no gap.
+func (r *PostgresLugarRepository) DeleteImage(ctx context.Context, imageID int, compact bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lugarID int
+	err = tx.QueryRowContext(ctx, `SELECT lugar_id FROM lugares_images WHERE id = $1`, imageID).Scan(&lugarID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("image with ID %d not found", imageID)
+	}
+	if err != nil {
+		return fmt.Errorf("error getting image: %w", err)
+	}
+
+	// Lock the order before deleting, so an image added meanwhile does not
+	// take a position the compaction is about to give out
+	if compact {
+		if err := lockImageOrder(ctx, tx, lugarID); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM lugares_images WHERE id = $1`, imageID)
+	if err != nil {
+		return fmt.Errorf("error deleting image: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("image with ID %d not found", imageID)
+	}
+
+	if compact {
+		if err := compactImageOrder(ctx, tx, lugarID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing image deletion: %w", err)
+	}
+
+	return nil
+}
+
+// compactImageOrder renumbers the images of a place 1, 2, 3... keeping their
+// order. The caller holds the lock of lockImageOrder.
+func compactImageOrder(ctx context.Context, tx *sql.Tx, lugarID int) error {
+	// The unique (lugar_id, display_order) constraint is checked row by row,
+	// so the orders are first moved out of the way by negating them
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE lugares_images
+		SET display_order = -display_order
+		WHERE lugar_id = $1
+	`, lugarID); err != nil {
+		return fmt.Errorf("error compacting image order: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE lugares_images li
+		SET display_order = ranked.position
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY display_order DESC, id) AS position
+			FROM lugares_images
+			WHERE lugar_id = $1
+		) ranked
+		WHERE li.id = ranked.id
+	`, lugarID); err != nil {
+		return fmt.Errorf("error compacting image order: %w", err)
+	}
+
+	return nil
+}
+
+// GetImages gets all images for a place
+func (r *PostgresLugarRepository) GetImages(ctx context.Context, lugarID int) ([]*models.LugarImage, error) {
+	query := `
+		SELECT id, lugar_id, image_url, display_order, created_at
+		FROM lugares_images
+		WHERE lugar_id = $1
+		ORDER BY display_order
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, lugarID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting images for lugar: %w", err)
+	}
+	defer rows.Close()
+
+	images := []*models.LugarImage{}
+	for rows.Next() {
+		image := &models.LugarImage{}
+		if err := rows.Scan(
+			&image.ID,
+			&image.LugarID,
+			&image.ImageURL,
+			&image.DisplayOrder,
+			&image.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning image row: %w", err)
+		}
+		images = append(images, image)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating image rows: %w", err)
+	}
+
+	return images, nil
+}
+
+// GetImageByID retrieves an image of a place by ID
+func (r *PostgresLugarRepository) GetImageByID(ctx context.Context, lugarID, imageID int) (*models.LugarImage, error) {
+	query := `
+		SELECT id, lugar_id, image_url, display_order, created_at
+		FROM lugares_images
+		WHERE id = $1 AND lugar_id = $2
+	`
+
+	image := &models.LugarImage{}
+	err := r.db.QueryRowContext(ctx, query, imageID, lugarID).Scan(
+		&image.ID,
+		&image.LugarID,
+		&image.ImageURL,
+		&image.DisplayOrder,
+		&image.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Return nil without error to indicate not found
+		}
+		return nil, fmt.Errorf("error getting image by ID: %w", err)
+	}
+
+	return image, nil
+}
+
+// ListAllImages lists the images of every place the viewer may see, newest
+// first, along with the place names
+func (r *PostgresLugarRepository) ListAllImages(ctx context.Context, page Pagination) ([]*models.ImageWithLugar, error) {
+	published, publishedArgs := publishedCondition(ctx, "l", "$3")
+	query := `
+		SELECT li.id, li.lugar_id, li.image_url, li.display_order, li.created_at, l.nome_local
+		FROM lugares_images li
+		JOIN lugares l ON l.id = li.lugar_id
+		WHERE l.deleted_at IS NULL AND ` + published + `
+		ORDER BY li.created_at DESC, li.id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	args := append([]interface{}{page.Limit, page.Offset}, publishedArgs...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing images: %w", err)
+	}
+	defer rows.Close()
+
+	images := []*models.ImageWithLugar{}
+	for rows.Next() {
+		image := &models.ImageWithLugar{}
+		if err := rows.Scan(
+			&image.ID,
+			&image.LugarID,
+			&image.ImageURL,
+			&image.DisplayOrder,
+			&image.CreatedAt,
+			&image.LugarNome,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning image row: %w", err)
+		}
+		images = append(images, image)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating image rows: %w", err)
+	}
+
+	return images, nil
+}
+
+// CountAllImages counts the images of every place that was not deleted and
+// that the viewer may see
+func (r *PostgresLugarRepository) CountAllImages(ctx context.Context) (int, error) {
+	published, args := publishedCondition(ctx, "l", "$1")
+	query := `
+		SELECT COUNT(*)
+		FROM lugares_images li
+		JOIN lugares l ON l.id = li.lugar_id
+		WHERE l.deleted_at IS NULL AND ` + published + `
+	`
+	return countRows(ctx, r.db, query, args...)
+}
+
+// CountImages counts the images of a place
+func (r *PostgresLugarRepository) CountImages(ctx context.Context, lugarID int) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM lugares_images
+		WHERE lugar_id = $1
+	`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, lugarID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting images for lugar: %w", err)
+	}
+
+	return count, nil
+}
+
+// MaxDisplayOrder gets the highest display order of the images of a place,
+// or 0 if it has none
+func (r *PostgresLugarRepository) MaxDisplayOrder(ctx context.Context, lugarID int) (int, error) {
+	query := `
+		SELECT COALESCE(MAX(display_order), 0)
+		FROM lugares_images
+		WHERE lugar_id = $1
+	`
+
+	var maxOrder int
+	if err := r.db.QueryRowContext(ctx, query, lugarID).Scan(&maxOrder); err != nil {
+		return 0, fmt.Errorf("error getting max display order for lugar: %w", err)
+	}
+
+	return maxOrder, nil
+}
+
+// MissingTagIDs returns the given place tag IDs that do not exist, in the order given
+func (r *PostgresLugarRepository) MissingTagIDs(ctx context.Context, tagIDs []int) ([]int, error) {
+	return missingIDs(ctx, r.db, "tags_lugares", tagIDs)
+}
+
+// MissingRamoIDs returns the given ramo IDs that do not exist, in the order given
+func (r *PostgresLugarRepository) MissingRamoIDs(ctx context.Context, ramoIDs []int) ([]int, error) {
+	return missingIDs(ctx, r.db, "ramos", ramoIDs)
+}
+
+// missingIDs returns the distinct ids that have no row in table with a single query.
+// table is never user input.
+func missingIDs(ctx context.Context, db *sql.DB, table string, ids []int) ([]int, error) {
+	ids = uniqueInts(ids)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf("SELECT id FROM %s WHERE id = ANY($1)", table)
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("error checking %s IDs: %w", table, err)
+	}
+	defer rows.Close()
+
+	found := make(map[int]bool, len(ids))
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning %s ID: %w", table, err)
+		}
+		found[id] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating %s IDs: %w", table, err)
+	}
+
+	var missing []int
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// AddTag adds a tag to a place, reporting whether it was added or the association already existed
+func (r *PostgresLugarRepository) AddTag(ctx context.Context, lugarID, tagID int) (bool, error) {
+	query := `
+		INSERT INTO lugares_tags (lugar_id, tag_id)
+		VALUES ($1, $2)
+		ON CONFLICT (lugar_id, tag_id) DO NOTHING
+	`
+
+	result, err := r.db.ExecContext(ctx, query, lugarID, tagID)
+	if err != nil {
+		return false, fmt.Errorf("error adding tag to lugar: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RemoveTag removes a tag from a place
+func (r *PostgresLugarRepository) RemoveTag(ctx context.Context, lugarID, tagID int) error {
+	query := `
+		DELETE FROM lugares_tags
+		WHERE lugar_id = $1 AND tag_id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, lugarID, tagID)
+	if err != nil {
+		return fmt.Errorf("error removing tag from lugar: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveTags removes several tags from a place, returning how many were
+// removed; tags the place does not have are ignored
+func (r *PostgresLugarRepository) RemoveTags(ctx context.Context, lugarID int, tagIDs []int) (int, error) {
+	query := `
+		DELETE FROM lugares_tags
+		WHERE lugar_id = $1 AND tag_id = ANY($2)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, lugarID, pq.Array(tagIDs))
+	if err != nil {
+		return 0, fmt.Errorf("error removing tags from lugar: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+// GetTags gets all tags for a place
+func (r *PostgresLugarRepository) GetTags(ctx context.Context, lugarID int) ([]*models.TagLugar, error) {
+	query := `
+		SELECT t.id, t.name, t.created_at
+		FROM tags_lugares t
+		JOIN lugares_tags lt ON t.id = lt.tag_id
+		WHERE lt.lugar_id = $1
+		ORDER BY t.name
 	`
 
-	result, err := r.db.ExecContext(ctx, query, imageID)
+	rows, err := r.db.QueryContext(ctx, query, lugarID)
 	if err != nil {
-		return fmt.Errorf("error deleting image: %w", err)
+		return nil, fmt.Errorf("error getting tags for lugar: %w", err)
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("error getting rows affected: %w", err)
+	tags := []*models.TagLugar{}
+	for rows.Next() {
+		tag := &models.TagLugar{}
+		if err := rows.Scan(
+			&tag.ID,
+			&tag.Name,
+			&tag.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning tag row: %w", err)
+		}
+		tags = append(tags, tag)
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("image with ID %d not found", imageID)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag rows: %w", err)
 	}
 
-	return nil
+	return tags, nil
 }
 
-// GetImages gets all images for a place
-func (r *PostgresLugarRepository) GetImages(ctx context.Context, lugarID int) ([]*models.LugarImage, error) {
+// SharedTags gets the tags two places have in common
+func (r *PostgresLugarRepository) SharedTags(ctx context.Context, lugarID, otherID int) ([]*models.TagLugar, error) {
 	query := `
-		SELECT id, lugar_id, image_url, display_order, created_at
-		FROM lugares_images
-		WHERE lugar_id = $1
-		ORDER BY display_order
+		SELECT t.id, t.name, t.created_at
+		FROM tags_lugares t
+		JOIN lugares_tags lt1 ON lt1.tag_id = t.id AND lt1.lugar_id = $1
+		JOIN lugares_tags lt2 ON lt2.tag_id = t.id AND lt2.lugar_id = $2
+		ORDER BY t.name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, lugarID)
+	rows, err := r.db.QueryContext(ctx, query, lugarID, otherID)
 	if err != nil {
-		return nil, fmt.Errorf("error getting images for lugar: %w", err)
+		return nil, fmt.Errorf("error getting shared tags: %w", err)
 	}
 	defer rows.Close()
 
-	var images []*models.LugarImage
+	tags := []*models.TagLugar{}
 	for rows.Next() {
-		image := &models.LugarImage{}
+		tag := &models.TagLugar{}
 		if err := rows.Scan(
-			&image.ID,
-			&image.LugarID,
-			&image.ImageURL,
-			&image.DisplayOrder,
-			&image.CreatedAt,
+			&tag.ID,
+			&tag.Name,
+			&tag.CreatedAt,
 		); err != nil {
-			return nil, fmt.Errorf("error scanning image row: %w", err)
+			return nil, fmt.Errorf("error scanning tag row: %w", err)
 		}
-		images = append(images, image)
+		tags = append(tags, tag)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating image rows: %w", err)
+		return nil, fmt.Errorf("error iterating tag rows: %w", err)
 	}
 
-	return images, nil
+	return tags, nil
 }
 
-// AddTag adds a tag to a place
-func (r *PostgresLugarRepository) AddTag(ctx context.Context, lugarID, tagID int) error {
+// ListSimilar retrieves the places the viewer may see sharing the most tags
+// and ramos with the given one, most shared first
+func (r *PostgresLugarRepository) ListSimilar(ctx context.Context, lugarID, limit int) ([]*models.SimilarLugar, error) {
+	published, publishedArgs := publishedCondition(ctx, "l", "$3")
 	query := `
-		INSERT INTO lugares_tags (lugar_id, tag_id)
-		VALUES ($1, $2)
-		ON CONFLICT (lugar_id, tag_id) DO NOTHING
+		SELECT shared.lugar_id, SUM(shared.tags) AS shared_tags, SUM(shared.ramos) AS shared_ramos
+		FROM (
+			SELECT lt2.lugar_id, 1 AS tags, 0 AS ramos
+			FROM lugares_tags lt1
+			JOIN lugares_tags lt2 ON lt2.tag_id = lt1.tag_id AND lt2.lugar_id <> lt1.lugar_id
+			WHERE lt1.lugar_id = $1
+			UNION ALL
+			SELECT lr2.lugar_id, 0 AS tags, 1 AS ramos
+			FROM lugares_ramos lr1
+			JOIN lugares_ramos lr2 ON lr2.ramo_id = lr1.ramo_id AND lr2.lugar_id <> lr1.lugar_id
+			WHERE lr1.lugar_id = $1
+		) shared
+		JOIN lugares l ON l.id = shared.lugar_id AND l.deleted_at IS NULL AND ` + published + `
+		GROUP BY shared.lugar_id
+		ORDER BY SUM(shared.tags) + SUM(shared.ramos) DESC, SUM(shared.tags) DESC, shared.lugar_id
+		LIMIT $2
 	`
 
-	_, err := r.db.ExecContext(ctx, query, lugarID, tagID)
+	args := append([]interface{}{lugarID, limit}, publishedArgs...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("error adding tag to lugar: %w", err)
+		return nil, fmt.Errorf("error listing similar lugares: %w", err)
 	}
+	defer rows.Close()
 
-	return nil
-}
+	similar := []*models.SimilarLugar{}
+	var ids []int
+	for rows.Next() {
+		var id, sharedTags, sharedRamos int
+		if err := rows.Scan(&id, &sharedTags, &sharedRamos); err != nil {
+			return nil, fmt.Errorf("error scanning similar lugar row: %w", err)
+		}
+		similar = append(similar, &models.SimilarLugar{
+			Lugar:       &models.Lugar{ID: id},
+			SharedRamos: sharedRamos,
+			Overlap:     sharedTags + sharedRamos,
+		})
+		ids = append(ids, id)
+	}
 
-// RemoveTag removes a tag from a place
-func (r *PostgresLugarRepository) RemoveTag(ctx context.Context, lugarID, tagID int) error {
-	query := `
-		DELETE FROM lugares_tags
-		WHERE lugar_id = $1 AND tag_id = $2
-	`
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating similar lugar rows: %w", err)
+	}
 
-	_, err := r.db.ExecContext(ctx, query, lugarID, tagID)
+	if len(ids) == 0 {
+		return similar, nil
+	}
+
+	// Load the places and the tags they share, keeping the ranking
+	lugares, err := r.queryLugares(ctx, lugarSelect+`
+		WHERE l.id = ANY($1)
+	`, pq.Array(ids))
 	if err != nil {
-		return fmt.Errorf("error removing tag from lugar: %w", err)
+		return nil, err
+	}
+	byID := make(map[int]*models.Lugar, len(lugares))
+	for _, lugar := range lugares {
+		byID[lugar.ID] = lugar
 	}
 
-	return nil
+	sharedTags, err := r.sharedTagsWith(ctx, lugarID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range similar {
+		if lugar, ok := byID[item.ID]; ok {
+			item.Lugar = lugar
+		}
+		item.SharedTags = sharedTags[item.ID]
+		if item.SharedTags == nil {
+			item.SharedTags = []*models.TagLugar{}
+		}
+	}
+
+	return similar, nil
 }
 
-// GetTags gets all tags for a place
-func (r *PostgresLugarRepository) GetTags(ctx context.Context, lugarID int) ([]*models.TagLugar, error) {
+// sharedTagsWith gets the tags a place has in common with each of the other
+// places, in a single query, keyed by the ID of the other place
+func (r *PostgresLugarRepository) sharedTagsWith(ctx context.Context, lugarID int, otherIDs []int) (map[int][]*models.TagLugar, error) {
 	query := `
-		SELECT t.id, t.name, t.created_at
+		SELECT lt2.lugar_id, t.id, t.name, t.created_at
 		FROM tags_lugares t
-		JOIN lugares_tags lt ON t.id = lt.tag_id
-		WHERE lt.lugar_id = $1
-		ORDER BY t.name
+		JOIN lugares_tags lt1 ON lt1.tag_id = t.id AND lt1.lugar_id = $1
+		JOIN lugares_tags lt2 ON lt2.tag_id = t.id AND lt2.lugar_id = ANY($2)
+		ORDER BY lt2.lugar_id, t.name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, lugarID)
+	rows, err := r.db.QueryContext(ctx, query, lugarID, pq.Array(otherIDs))
 	if err != nil {
-		return nil, fmt.Errorf("error getting tags for lugar: %w", err)
+		return nil, fmt.Errorf("error getting shared tags: %w", err)
 	}
 	defer rows.Close()
 
-	var tags []*models.TagLugar
+	tags := make(map[int][]*models.TagLugar, len(otherIDs))
 	for rows.Next() {
+		var otherID int
 		tag := &models.TagLugar{}
 		if err := rows.Scan(
+			&otherID,
 			&tag.ID,
 			&tag.Name,
 			&tag.CreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("error scanning tag row: %w", err)
 		}
-		tags = append(tags, tag)
+		tags[otherID] = append(tags[otherID], tag)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -414,20 +1483,25 @@ func (r *PostgresLugarRepository) GetTags(ctx context.Context, lugarID int) ([]*
 	return tags, nil
 }
 
-// AddRamo adds a ramo to a place
-func (r *PostgresLugarRepository) AddRamo(ctx context.Context, lugarID, ramoID int) error {
+// AddRamo adds a ramo to a place, reporting whether it was added or the association already existed
+func (r *PostgresLugarRepository) AddRamo(ctx context.Context, lugarID, ramoID int) (bool, error) {
 	query := `
 		INSERT INTO lugares_ramos (lugar_id, ramo_id)
 		VALUES ($1, $2)
 		ON CONFLICT (lugar_id, ramo_id) DO NOTHING
 	`
 
-	_, err := r.db.ExecContext(ctx, query, lugarID, ramoID)
+	result, err := r.db.ExecContext(ctx, query, lugarID, ramoID)
 	if err != nil {
-		return fmt.Errorf("error adding ramo to lugar: %w", err)
+		return false, fmt.Errorf("error adding ramo to lugar: %w", err)
 	}
 
-	return nil
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
 }
 
 // RemoveRamo removes a ramo from a place
@@ -461,7 +1535,7 @@ func (r *PostgresLugarRepository) GetRamos(ctx context.Context, lugarID int) ([]
 	}
 	defer rows.Close()
 
-	var ramos []*models.Ramo
+	ramos := []*models.Ramo{}
 	for rows.Next() {
 		ramo := &models.Ramo{}
 		if err := rows.Scan(
@@ -481,14 +1555,15 @@ func (r *PostgresLugarRepository) GetRamos(ctx context.Context, lugarID int) ([]
 	return ramos, nil
 }
 
-// AddRating adds a rating to a place
+// AddRating adds a rating to a place, replacing the rating the user already
+// left for it. Replacing a rating counts as an update of its version.
 func (r *PostgresLugarRepository) AddRating(ctx context.Context, rating *models.LugarRating) (int, error) {
 	query := `
 		INSERT INTO lugares_ratings (lugar_id, user_id, rating, date)
 		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (lugar_id, user_id) DO UPDATE
-		SET rating = EXCLUDED.rating, date = EXCLUDED.date
-		RETURNING id
+		SET rating = EXCLUDED.rating, date = EXCLUDED.date, version = lugares_ratings.version + 1
+		RETURNING id, version
 	`
 
 	var id int
@@ -497,7 +1572,7 @@ func (r *PostgresLugarRepository) AddRating(ctx context.Context, rating *models.
 		rating.UserID,
 		rating.Rating,
 		rating.Date,
-	).Scan(&id)
+	).Scan(&id, &rating.Version)
 
 	if err != nil {
 		return 0, fmt.Errorf("error adding rating to lugar: %w", err)
@@ -506,32 +1581,62 @@ func (r *PostgresLugarRepository) AddRating(ctx context.Context, rating *models.
 	return id, nil
 }
 
-// UpdateRating updates a rating for a place
+// UpdateRating updates a rating for a place and increments its version
 func (r *PostgresLugarRepository) UpdateRating(ctx context.Context, rating *models.LugarRating) error {
 	query := `
 		UPDATE lugares_ratings
-		SET rating = $1, date = $2
+		SET rating = $1, date = $2, version = version + 1
 		WHERE id = $3
+		RETURNING version
 	`
 
-	result, err := r.db.ExecContext(ctx, query,
+	err := r.db.QueryRowContext(ctx, query,
 		rating.Rating,
 		rating.Date,
 		rating.ID,
-	)
+	).Scan(&rating.Version)
 
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("rating with ID %d not found", rating.ID)
+	}
 	if err != nil {
 		return fmt.Errorf("error updating rating: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("error getting rows affected: %w", err)
-	}
+	return nil
+}
 
-	if rowsAffected == 0 {
+// UpdateRatingIfUnchanged updates a rating only when its version is still
+// expectedVersion, returning ErrStaleWrite when it was changed in the meantime
+func (r *PostgresLugarRepository) UpdateRatingIfUnchanged(ctx context.Context, rating *models.LugarRating, expectedVersion int) error {
+	query := `
+		UPDATE lugares_ratings
+		SET rating = $1, date = $2, version = version + 1
+		WHERE id = $3 AND version = $4
+		RETURNING version
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		rating.Rating,
+		rating.Date,
+		rating.ID,
+		expectedVersion,
+	).Scan(&rating.Version)
+
+	if err == sql.ErrNoRows {
+		// Tell a missing rating apart from one updated since it was read
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM lugares_ratings WHERE id = $1)`, rating.ID).Scan(&exists); err != nil {
+			return fmt.Errorf("error checking rating: %w", err)
+		}
+		if exists {
+			return fmt.Errorf("rating with ID %d: %w", rating.ID, ErrStaleWrite)
+		}
 		return fmt.Errorf("rating with ID %d not found", rating.ID)
 	}
+	if err != nil {
+		return fmt.Errorf("error updating rating: %w", err)
+	}
 
 	return nil
 }
@@ -560,22 +1665,53 @@ func (r *PostgresLugarRepository) DeleteRating(ctx context.Context, ratingID int
 	return nil
 }
 
-// GetRatings gets all ratings for a place
-func (r *PostgresLugarRepository) GetRatings(ctx context.Context, lugarID int) ([]*models.LugarRating, error) {
+// DeleteRatingByUser deletes the rating a user gave to a place
+func (r *PostgresLugarRepository) DeleteRatingByUser(ctx context.Context, lugarID, userID int) error {
 	query := `
-		SELECT id, lugar_id, user_id, rating, date
-		FROM lugares_ratings
-		WHERE lugar_id = $1
-		ORDER BY date DESC
+		DELETE FROM lugares_ratings
+		WHERE lugar_id = $1 AND user_id = $2
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, lugarID)
+	result, err := r.db.ExecContext(ctx, query, lugarID, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting rating: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("rating by user %d for lugar with ID %d: %w", userID, lugarID, ErrNotFound)
+	}
+
+	return nil
+}
+
+// ratingSortOrders maps the accepted sort values of the ratings of a place to their ORDER BY clauses
+var ratingSortOrders = map[string]string{
+	"": "date DESC, id DESC",
+}
+
+// GetRatings gets a page of the ratings of a place, newest first
+func (r *PostgresLugarRepository) GetRatings(ctx context.Context, lugarID int, page Pagination) ([]*models.LugarRating, error) {
+	builder := newQueryBuilder(`
+		SELECT id, lugar_id, user_id, rating, date, version
+		FROM lugares_ratings`).
+		Where("lugar_id = ?", lugarID)
+	if err := builder.OrderBy("", ratingSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error getting ratings for lugar: %w", err)
 	}
 	defer rows.Close()
 
-	var ratings []*models.LugarRating
+	ratings := []*models.LugarRating{}
 	for rows.Next() {
 		rating := &models.LugarRating{}
 		if err := rows.Scan(
@@ -584,6 +1720,128 @@ func (r *PostgresLugarRepository) GetRatings(ctx context.Context, lugarID int) (
 			&rating.UserID,
 			&rating.Rating,
 			&rating.Date,
+			&rating.Version,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning rating row: %w", err)
+		}
+		ratings = append(ratings, rating)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rating rows: %w", err)
+	}
+
+	return ratings, nil
+}
+
+// CountRatings counts the ratings of a place
+func (r *PostgresLugarRepository) CountRatings(ctx context.Context, lugarID int) (int, error) {
+	query := `SELECT COUNT(*) FROM lugares_ratings WHERE lugar_id = $1`
+	return countRows(ctx, r.db, query, lugarID)
+}
+
+// GetUserRating retrieves the rating a user left for a lugar, or nil when the user has not rated it
+func (r *PostgresLugarRepository) GetUserRating(ctx context.Context, lugarID, userID int) (*models.LugarRating, error) {
+	query := `
+		SELECT id, lugar_id, user_id, rating, date, version
+		FROM lugares_ratings
+		WHERE lugar_id = $1 AND user_id = $2
+	`
+
+	rating := &models.LugarRating{}
+	err := r.db.QueryRowContext(ctx, query, lugarID, userID).Scan(
+		&rating.ID,
+		&rating.LugarID,
+		&rating.UserID,
+		&rating.Rating,
+		&rating.Date,
+		&rating.Version,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting user rating for lugar: %w", err)
+	}
+
+	return rating, nil
+}
+
+// RecentRatings retrieves the most recent ratings across the places the
+// viewer may see, newest first
+func (r *PostgresLugarRepository) RecentRatings(ctx context.Context, limit int) ([]*models.RecentRating, error) {
+	published, publishedArgs := publishedCondition(ctx, "l", "$2")
+	query := `
+		SELECT lr.id, lr.lugar_id, lr.user_id, lr.rating, lr.date, lr.version, l.nome_local
+		FROM lugares_ratings lr
+		JOIN lugares l ON l.id = lr.lugar_id
+		WHERE l.deleted_at IS NULL AND ` + published + `
+		ORDER BY lr.date DESC, lr.id DESC
+		LIMIT $1
+	`
+
+	args := append([]interface{}{limit}, publishedArgs...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting recent ratings: %w", err)
+	}
+	defer rows.Close()
+
+	ratings := []*models.RecentRating{}
+	for rows.Next() {
+		rating := &models.RecentRating{}
+		if err := rows.Scan(
+			&rating.ID,
+			&rating.LugarID,
+			&rating.UserID,
+			&rating.Rating,
+			&rating.Date,
+			&rating.Version,
+			&rating.LugarNome,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning rating row: %w", err)
+		}
+		ratings = append(ratings, rating)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rating rows: %w", err)
+	}
+
+	return ratings, nil
+}
+
+// ListRatingsByValue lists the ratings with the given star value across the
+// places the viewer may see, newest first, along with the names of the rated places
+func (r *PostgresLugarRepository) ListRatingsByValue(ctx context.Context, rating int, page Pagination) ([]*models.RecentRating, error) {
+	published, publishedArgs := publishedCondition(ctx, "l", "$4")
+	query := `
+		SELECT lr.id, lr.lugar_id, lr.user_id, lr.rating, lr.date, lr.version, l.nome_local
+		FROM lugares_ratings lr
+		JOIN lugares l ON l.id = lr.lugar_id
+		WHERE lr.rating = $1 AND l.deleted_at IS NULL AND ` + published + `
+		ORDER BY lr.date DESC, lr.id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	args := append([]interface{}{rating, page.Limit, page.Offset}, publishedArgs...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing ratings by value: %w", err)
+	}
+	defer rows.Close()
+
+	ratings := []*models.RecentRating{}
+	for rows.Next() {
+		rating := &models.RecentRating{}
+		if err := rows.Scan(
+			&rating.ID,
+			&rating.LugarID,
+			&rating.UserID,
+			&rating.Rating,
+			&rating.Date,
+			&rating.Version,
+			&rating.LugarNome,
 		); err != nil {
 			return nil, fmt.Errorf("error scanning rating row: %w", err)
 		}
@@ -596,3 +1854,92 @@ func (r *PostgresLugarRepository) GetRatings(ctx context.Context, lugarID int) (
 
 	return ratings, nil
 }
+
+// CountRatingsByValue counts the ratings with the given star value across the
+// places the viewer may see
+func (r *PostgresLugarRepository) CountRatingsByValue(ctx context.Context, rating int) (int, error) {
+	published, publishedArgs := publishedCondition(ctx, "l", "$2")
+	query := `
+		SELECT COUNT(*)
+		FROM lugares_ratings lr
+		JOIN lugares l ON l.id = lr.lugar_id
+		WHERE lr.rating = $1 AND l.deleted_at IS NULL AND ` + published + `
+	`
+	args := append([]interface{}{rating}, publishedArgs...)
+	return countRows(ctx, r.db, query, args...)
+}
+
+// RatingSummaries computes the average rating and number of ratings of each
+// given place in one query. Every ID is in the result; unrated places have zeros.
+func (r *PostgresLugarRepository) RatingSummaries(ctx context.Context, ids []int) (map[int]*models.RatingSummary, error) {
+	summaries := make(map[int]*models.RatingSummary, len(ids))
+	for _, id := range ids {
+		summaries[id] = &models.RatingSummary{LugarID: id}
+	}
+	if len(ids) == 0 {
+		return summaries, nil
+	}
+
+	query := `
+		SELECT lugar_id, AVG(rating), COUNT(*)
+		FROM lugares_ratings
+		WHERE lugar_id = ANY($1)
+		GROUP BY lugar_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(uniqueInts(ids)))
+	if err != nil {
+		return nil, fmt.Errorf("error getting rating summaries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		summary := &models.RatingSummary{}
+		if err := rows.Scan(&summary.LugarID, &summary.AverageRating, &summary.RatingCount); err != nil {
+			return nil, fmt.Errorf("error scanning rating summary row: %w", err)
+		}
+		summaries[summary.LugarID] = summary
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rating summary rows: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// GlobalRatingDistribution computes the histogram of star values and the overall average across all places
+func (r *PostgresLugarRepository) GlobalRatingDistribution(ctx context.Context) (*models.RatingDistribution, error) {
+	query := `
+		SELECT COUNT(*) FILTER (WHERE rating = 1),
+		       COUNT(*) FILTER (WHERE rating = 2),
+		       COUNT(*) FILTER (WHERE rating = 3),
+		       COUNT(*) FILTER (WHERE rating = 4),
+		       COUNT(*) FILTER (WHERE rating = 5),
+		       COUNT(*),
+		       COALESCE(AVG(rating), 0)
+		FROM lugares_ratings
+	`
+
+	var counts [5]int
+	distribution := &models.RatingDistribution{}
+	err := r.db.QueryRowContext(ctx, query).Scan(
+		&counts[0],
+		&counts[1],
+		&counts[2],
+		&counts[3],
+		&counts[4],
+		&distribution.Total,
+		&distribution.Average,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error getting rating distribution: %w", err)
+	}
+
+	distribution.Counts = make(map[int]int, len(counts))
+	for i, count := range counts {
+		distribution.Counts[i+1] = count
+	}
+
+	return distribution, nil
+}
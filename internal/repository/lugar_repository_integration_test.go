@@ -0,0 +1,2199 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+func TestListInBoundingBox(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	places := []struct {
+		nome     string
+		lat, lng interface{}
+	}{
+		{"Centro", -23.55, -46.63},
+		{"Borda", -23.60, -46.80},
+		{"Campinas", -22.90, -47.06},
+		{"Sem coordenadas", nil, nil},
+	}
+	ids := make(map[string]int, len(places))
+	for _, p := range places {
+		ids[p.nome] = insertTestLugar(t, db, p.nome)
+		mustExec(t, db, `UPDATE lugares SET latitude = $1, longitude = $2 WHERE id = $3`, p.lat, p.lng, ids[p.nome])
+	}
+	deleted := insertTestLugar(t, db, "Removido")
+	mustExec(t, db, `UPDATE lugares SET latitude = -23.5, longitude = -46.6, deleted_at = now() WHERE id = $1`, deleted)
+
+	tests := []struct {
+		name                           string
+		minLat, minLng, maxLat, maxLng float64
+		want                           []string
+	}{
+		{"box around Sao Paulo includes its edge", -23.60, -46.80, -23.40, -46.50, []string{"Centro", "Borda"}},
+		{"box around Campinas", -23.00, -47.20, -22.80, -47.00, []string{"Campinas"}},
+		{"box with no places", 10, 10, 11, 11, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lugares, err := repo.ListInBoundingBox(ctx, tt.minLat, tt.minLng, tt.maxLat, tt.maxLng, Pagination{Limit: 10})
+			if err != nil {
+				t.Fatalf("ListInBoundingBox: %v", err)
+			}
+			var got []string
+			for _, l := range lugares {
+				got = append(got, l.NomeLocal)
+			}
+			sort.Strings(got)
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			if len(got) != len(want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Fatalf("got %v, want %v", got, want)
+				}
+			}
+
+			count, err := repo.CountInBoundingBox(ctx, tt.minLat, tt.minLng, tt.maxLat, tt.maxLng)
+			if err != nil {
+				t.Fatalf("CountInBoundingBox: %v", err)
+			}
+			if count != len(want) {
+				t.Errorf("count = %d, want %d", count, len(want))
+			}
+		})
+	}
+}
+
+func TestFindSimilar(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	sitio := insertTestLugar(t, db, "Sitio Recanto Alegre")
+	mustExec(t, db, `UPDATE lugares SET endereco_completo = 'Estrada do Cafe, 1200, Atibaia' WHERE id = $1`, sitio)
+	other := insertTestLugar(t, db, "Parque Estadual Jaragua")
+	mustExec(t, db, `UPDATE lugares SET endereco_completo = 'Rua Antonio Cardoso, 97, Sao Paulo' WHERE id = $1`, other)
+
+	tests := []struct {
+		name      string
+		nome      string
+		endereco  string
+		wantFirst int
+		wantNone  bool
+	}{
+		{name: "near-identical name", nome: "Sitio Recanto Alegre ", wantFirst: sitio},
+		{name: "same address, other name", nome: "Camping", endereco: "Estrada do Cafe 1200 Atibaia", wantFirst: sitio},
+		{name: "clearly different entry", nome: "Colonia de Ferias Praia Grande", endereco: "Avenida Beira Mar", wantNone: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lugares, err := repo.FindSimilar(ctx, tt.nome, tt.endereco)
+			if err != nil {
+				t.Fatalf("FindSimilar: %v", err)
+			}
+			if tt.wantNone {
+				if len(lugares) != 0 {
+					t.Errorf("got %d similar lugares, want none", len(lugares))
+				}
+				return
+			}
+			if len(lugares) == 0 || lugares[0].ID != tt.wantFirst {
+				t.Errorf("most similar lugar is not %d: %v", tt.wantFirst, lugares)
+			}
+		})
+	}
+}
+
+func TestCountImages(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	withImages := insertTestLugar(t, db, "Com imagens")
+	without := insertTestLugar(t, db, "Sem imagens")
+	for i := 1; i <= 3; i++ {
+		mustExec(t, db, `INSERT INTO lugares_images (lugar_id, image_url, display_order) VALUES ($1, 'https://example.com/a.jpg', $2)`, withImages, i)
+	}
+
+	tests := []struct {
+		name    string
+		lugarID int
+		want    int
+	}{
+		{"lugar with images", withImages, 3},
+		{"lugar without images", without, 0},
+		{"missing lugar", 9999, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := repo.CountImages(ctx, tt.lugarID)
+			if err != nil {
+				t.Fatalf("CountImages: %v", err)
+			}
+			if count != tt.want {
+				t.Errorf("count = %d, want %d", count, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetImageByID(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	lugarID := insertTestLugar(t, db, "Com imagem")
+	otherID := insertTestLugar(t, db, "Outro")
+	var imageID int
+	err := db.QueryRow(`INSERT INTO lugares_images (lugar_id, image_url, display_order) VALUES ($1, 'https://example.com/a.jpg', 1) RETURNING id`, lugarID).Scan(&imageID)
+	if err != nil {
+		t.Fatalf("inserting image: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		lugarID, imageID int
+		wantFound        bool
+	}{
+		{"found", lugarID, imageID, true},
+		{"wrong lugar", otherID, imageID, false},
+		{"missing image", lugarID, imageID + 100, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image, err := repo.GetImageByID(ctx, tt.lugarID, tt.imageID)
+			if err != nil {
+				t.Fatalf("GetImageByID: %v", err)
+			}
+			if (image != nil) != tt.wantFound {
+				t.Fatalf("found = %v, want %v", image != nil, tt.wantFound)
+			}
+			if image != nil && (image.LugarID != lugarID || image.ImageURL != "https://example.com/a.jpg") {
+				t.Errorf("got image %+v", image)
+			}
+		})
+	}
+}
+
+func TestGlobalRatingDistribution(t *testing.T) {
+	tests := []struct {
+		name        string
+		ratings     []int
+		wantCounts  map[int]int
+		wantAverage float64
+	}{
+		{"no ratings", nil, map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}, 0},
+		{"ratings across places", []int{5, 5, 4, 1, 5, 3}, map[int]int{1: 1, 2: 0, 3: 1, 4: 1, 5: 3}, 23.0 / 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresLugarRepository(db)
+
+			// Spread the ratings over two places, one rating per user and place
+			lugares := []int{insertTestLugar(t, db, "A"), insertTestLugar(t, db, "B")}
+			for i, rating := range tt.ratings {
+				userID := insertTestUser(t, db, fmt.Sprintf("rater%d", i))
+				mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating) VALUES ($1, $2, $3)`, lugares[i%2], userID, rating)
+			}
+
+			distribution, err := repo.GlobalRatingDistribution(context.Background())
+			if err != nil {
+				t.Fatalf("GlobalRatingDistribution: %v", err)
+			}
+			if distribution.Total != len(tt.ratings) {
+				t.Errorf("total = %d, want %d", distribution.Total, len(tt.ratings))
+			}
+			for star, want := range tt.wantCounts {
+				if distribution.Counts[star] != want {
+					t.Errorf("counts[%d] = %d, want %d", star, distribution.Counts[star], want)
+				}
+			}
+			if math.Abs(distribution.Average-tt.wantAverage) > 1e-9 {
+				t.Errorf("average = %v, want %v", distribution.Average, tt.wantAverage)
+			}
+		})
+	}
+}
+
+func TestScanLugarWithNullColumns(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	// Only the required columns are set; every optional one is NULL
+	id := insertTestLugar(t, db, "Parcialmente preenchido")
+
+	tests := []struct {
+		name string
+		get  func() (*models.Lugar, error)
+	}{
+		{"GetByID", func() (*models.Lugar, error) { return repo.GetByID(ctx, id) }},
+		{"List", func() (*models.Lugar, error) {
+			lugares, err := repo.List(ctx, LugarListOptions{Pagination: Pagination{Limit: 10}})
+			if err != nil || len(lugares) != 1 {
+				return nil, fmt.Errorf("got %d lugares: %v", len(lugares), err)
+			}
+			return lugares[0], nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lugar, err := tt.get()
+			if err != nil {
+				t.Fatalf("scanning a row with NULLs: %v", err)
+			}
+			if lugar == nil {
+				t.Fatal("lugar not found")
+			}
+			if lugar.NomeDonoLocal != "" || lugar.LinkSite != "" || lugar.LinkGoogleMaps != "" || lugar.EnderecoCompleto != "" || lugar.TelefoneParaContato != 0 {
+				t.Errorf("NULL columns were not read as zero values: %+v", lugar)
+			}
+			if lugar.ValorFixo != nil || lugar.ValorIndividual != nil || lugar.Latitude != nil || lugar.Longitude != nil {
+				t.Errorf("NULL nullable columns were not read as nil: %+v", lugar)
+			}
+		})
+	}
+}
+
+func TestListByRamos(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	// Seed ramos: 1 filhotes, 2 lobinho, 3 escoteiro
+	both := insertTestLugar(t, db, "Lobinho e escoteiro")
+	lobinho := insertTestLugar(t, db, "So lobinho")
+	filhotes := insertTestLugar(t, db, "So filhotes")
+	insertTestLugar(t, db, "Sem ramo")
+	mustExec(t, db, `INSERT INTO lugares_ramos (lugar_id, ramo_id) VALUES ($1, 2), ($1, 3), ($2, 2), ($3, 1)`, both, lobinho, filhotes)
+
+	tests := []struct {
+		name    string
+		ramoIDs []int
+		want    []int
+	}{
+		{"overlapping memberships are not duplicated", []int{2, 3}, []int{both, lobinho}},
+		{"single ramo", []int{1}, []int{filhotes}},
+		{"ramo without places", []int{4}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lugares, err := repo.ListByRamos(ctx, tt.ramoIDs, Pagination{Limit: 10})
+			if err != nil {
+				t.Fatalf("ListByRamos: %v", err)
+			}
+			var got []int
+			for _, l := range lugares {
+				got = append(got, l.ID)
+			}
+			sort.Ints(got)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+
+			count, err := repo.Count(ctx, LugarListOptions{RamoIDs: tt.ramoIDs})
+			if err != nil {
+				t.Fatalf("Count: %v", err)
+			}
+			if count != len(tt.want) {
+				t.Errorf("count = %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestDeleteRatingByUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		deleteUser  int
+		deleteLugar int
+		wantErr     error
+		wantAverage float64
+	}{
+		// Users 1 and 2 rate the lugar 5 and 1: the average is 3
+		{name: "removes the rating and updates the average", deleteUser: 2, wantAverage: 5},
+		{name: "user without a rating", deleteUser: 3, wantErr: ErrNotFound, wantAverage: 3},
+		{name: "missing lugar", deleteUser: 2, deleteLugar: 9999, wantErr: ErrNotFound, wantAverage: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresLugarRepository(db)
+			ctx := context.Background()
+
+			lugarID := insertTestLugar(t, db, "Avaliado")
+			insertTestUser(t, db, "sem avaliacao")
+			mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating) VALUES ($1, 1, 5), ($1, 2, 1)`, lugarID)
+
+			target := lugarID
+			if tt.deleteLugar != 0 {
+				target = tt.deleteLugar
+			}
+			err := repo.DeleteRatingByUser(ctx, target, tt.deleteUser)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			lugar, err := repo.GetByID(ctx, lugarID)
+			if err != nil || lugar == nil {
+				t.Fatalf("GetByID: %v, %v", lugar, err)
+			}
+			if float64(lugar.AverageRating) != tt.wantAverage {
+				t.Errorf("average = %v, want %v", lugar.AverageRating, tt.wantAverage)
+			}
+		})
+	}
+}
+
+func TestListUnrated(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	rated := insertTestLugar(t, db, "Avaliado")
+	unratedA := insertTestLugar(t, db, "Sem avaliacao A")
+	unratedB := insertTestLugar(t, db, "Sem avaliacao B")
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating) VALUES ($1, 1, 4)`, rated)
+
+	tests := []struct {
+		name      string
+		opts      LugarListOptions
+		want      []int
+		wantCount int
+	}{
+		{"only unrated places", LugarListOptions{Unrated: true, Pagination: Pagination{Limit: 10}}, []int{unratedA, unratedB}, 2},
+		{"first page of unrated places", LugarListOptions{Unrated: true, Pagination: Pagination{Limit: 1}}, []int{unratedA}, 2},
+		{"second page of unrated places", LugarListOptions{Unrated: true, Pagination: Pagination{Limit: 1, Offset: 1}}, []int{unratedB}, 2},
+		{"without the filter", LugarListOptions{Pagination: Pagination{Limit: 10}}, []int{rated, unratedA, unratedB}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lugares, err := repo.List(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var got []int
+			for _, l := range lugares {
+				got = append(got, l.ID)
+				if tt.opts.Unrated && l.RatingCount != 0 {
+					t.Errorf("lugar %d has %d ratings", l.ID, l.RatingCount)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+
+			count, err := repo.Count(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("Count: %v", err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("count = %d, want %d", count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestChangeOwner(t *testing.T) {
+	tests := []struct {
+		name         string
+		userID       int
+		deleted      bool
+		wantErr      error
+		wantAnyError bool
+		wantOwner    int
+	}{
+		{name: "valid transfer", userID: 2, wantOwner: 2},
+		{name: "nonexistent target user", userID: 999, wantErr: ErrInvalidReference, wantOwner: 1},
+		{name: "deleted lugar", userID: 2, deleted: true, wantAnyError: true, wantOwner: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresLugarRepository(db)
+			id := insertTestLugar(t, db, "Sítio")
+			if tt.deleted {
+				mustExec(t, db, `UPDATE lugares SET deleted_at = NOW() WHERE id = $1`, id)
+			}
+
+			err := repo.ChangeOwner(context.Background(), id, tt.userID)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ChangeOwner error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantAnyError:
+				if err == nil {
+					t.Fatal("ChangeOwner succeeded, want an error")
+				}
+			case err != nil:
+				t.Fatalf("ChangeOwner: %v", err)
+			}
+
+			var owner int
+			if err := db.QueryRow(`SELECT user_id FROM lugares WHERE id = $1`, id).Scan(&owner); err != nil {
+				t.Fatalf("reading owner: %v", err)
+			}
+			if owner != tt.wantOwner {
+				t.Errorf("owner = %d, want %d", owner, tt.wantOwner)
+			}
+		})
+	}
+}
+
+func TestAddImageDisplayOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  []int
+		order     int
+		wantErr   error
+		wantOrder int
+	}{
+		{name: "first image starts at one", order: 0, wantOrder: 1},
+		{name: "zero takes the order after the last", existing: []int{1, 2, 5}, order: 0, wantOrder: 6},
+		{name: "free explicit order is kept", existing: []int{1, 2}, order: 4, wantOrder: 4},
+		{name: "explicit duplicate is rejected", existing: []int{1, 2}, order: 2, wantErr: ErrAlreadyExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresLugarRepository(db)
+			lugarID := insertTestLugar(t, db, "Sítio")
+			for _, order := range tt.existing {
+				mustExec(t, db, `INSERT INTO lugares_images (lugar_id, image_url, display_order) VALUES ($1, 'https://example.com/a.jpg', $2)`, lugarID, order)
+			}
+
+			image := &models.LugarImage{LugarID: lugarID, ImageURL: "https://example.com/b.jpg", DisplayOrder: tt.order}
+			_, err := repo.AddImage(context.Background(), image, 0)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("AddImage error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddImage: %v", err)
+			}
+			if image.DisplayOrder != tt.wantOrder {
+				t.Errorf("display order = %d, want %d", image.DisplayOrder, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestRecentRatings(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+
+	cachoeira := insertTestLugar(t, db, "Cachoeira")
+	sitio := insertTestLugar(t, db, "Sítio")
+	removido := insertTestLugar(t, db, "Removido")
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating, date) VALUES ($1, 1, 5, '2024-03-01')`, cachoeira)
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating, date) VALUES ($1, 2, 3, '2024-05-01')`, sitio)
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating, date) VALUES ($1, 2, 4, '2024-04-01')`, cachoeira)
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating, date) VALUES ($1, 1, 1, '2024-06-01')`, removido)
+	mustExec(t, db, `UPDATE lugares SET deleted_at = NOW() WHERE id = $1`, removido)
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{name: "newest first with the lugar names", limit: 10, want: []string{"Sítio 2024-05-01", "Cachoeira 2024-04-01", "Cachoeira 2024-03-01"}},
+		{name: "limit keeps the newest", limit: 1, want: []string{"Sítio 2024-05-01"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ratings, err := repo.RecentRatings(context.Background(), tt.limit)
+			if err != nil {
+				t.Fatalf("RecentRatings: %v", err)
+			}
+			var got []string
+			for _, rating := range ratings {
+				got = append(got, rating.LugarNome+" "+rating.Date.Format("2006-01-02"))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("RecentRatings(%d) = %v, want %v", tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddImages(t *testing.T) {
+	tests := []struct {
+		name       string
+		orders     []int
+		maxImages  int
+		wantErr    error
+		wantOrders []int
+	}{
+		{name: "orders are assigned in sequence", orders: []int{0, 0, 0}, wantOrders: []int{2, 3, 4}},
+		{name: "explicit and assigned orders", orders: []int{5, 0}, wantOrders: []int{5, 6}},
+		{name: "a duplicate rolls back the whole batch", orders: []int{0, 1}, wantErr: ErrAlreadyExists},
+		{name: "a batch reaching the limit", orders: []int{0, 0, 0}, maxImages: 4, wantOrders: []int{2, 3, 4}},
+		{name: "a batch past the limit is rolled back", orders: []int{0, 0, 0}, maxImages: 3, wantErr: ErrImageLimit},
+		{name: "a lugar already at the limit", orders: []int{0}, maxImages: 1, wantErr: ErrImageLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresLugarRepository(db)
+			lugarID := insertTestLugar(t, db, "Sítio")
+			mustExec(t, db, `INSERT INTO lugares_images (lugar_id, image_url, display_order) VALUES ($1, 'https://example.com/a.jpg', 1)`, lugarID)
+
+			var images []*models.LugarImage
+			for i, order := range tt.orders {
+				images = append(images, &models.LugarImage{LugarID: lugarID, ImageURL: fmt.Sprintf("https://example.com/%d.jpg", i), DisplayOrder: order})
+			}
+
+			err := repo.AddImages(context.Background(), images, tt.maxImages)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("AddImages error = %v, want %v", err, tt.wantErr)
+				}
+				count, err := repo.CountImages(context.Background(), lugarID)
+				if err != nil || count != 1 {
+					t.Errorf("lugar has %d images (%v), want only the existing one", count, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddImages: %v", err)
+			}
+			for i, image := range images {
+				if image.ID == 0 || image.DisplayOrder != tt.wantOrders[i] {
+					t.Errorf("image %d: ID %d order %d, want an ID and order %d", i, image.ID, image.DisplayOrder, tt.wantOrders[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSearchByAddress(t *testing.T) {
+	tests := []struct {
+		name      string
+		unaccent  bool
+		address   string
+		wantNames []string
+	}{
+		{name: "partial match", unaccent: true, address: "jardim", wantNames: []string{"Chácara", "Sítio"}},
+		{name: "case-insensitive", unaccent: true, address: "JARDIM BOTÂNICO", wantNames: []string{"Sítio"}},
+		{name: "accent variant", unaccent: true, address: "sao jose", wantNames: []string{"Camping"}},
+		{name: "accented query on unaccented address", unaccent: true, address: "botânico", wantNames: []string{"Sítio"}},
+		{name: "like wildcards are literal", unaccent: true, address: "%", wantNames: nil},
+		{name: "without unaccent accents must match", unaccent: false, address: "sao jose", wantNames: nil},
+		{name: "without unaccent case is still ignored", unaccent: false, address: "SÃO JOSÉ", wantNames: []string{"Camping"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			if !tt.unaccent {
+				mustExec(t, db, `DROP EXTENSION IF EXISTS unaccent`)
+			}
+			repo := NewPostgresLugarRepository(db)
+
+			addresses := map[string]string{
+				"Sítio":    "Rua das Flores, Jardim Botanico, Curitiba",
+				"Chácara":  "Estrada do Jardim, 100",
+				"Camping":  "Avenida São José, 12",
+				"Removido": "Rua do Jardim, 1",
+			}
+			for nome, address := range addresses {
+				id := insertTestLugar(t, db, nome)
+				mustExec(t, db, `UPDATE lugares SET endereco_completo = $1 WHERE id = $2`, address, id)
+				if nome == "Removido" {
+					mustExec(t, db, `UPDATE lugares SET deleted_at = NOW() WHERE id = $1`, id)
+				}
+			}
+			insertTestLugar(t, db, "Sem endereço")
+
+			lugares, err := repo.SearchByAddress(context.Background(), tt.address, Pagination{})
+			if err != nil {
+				t.Fatalf("SearchByAddress: %v", err)
+			}
+			var names []string
+			for _, lugar := range lugares {
+				names = append(names, lugar.NomeLocal)
+			}
+			sort.Strings(names)
+			if fmt.Sprint(names) != fmt.Sprint(tt.wantNames) {
+				t.Errorf("SearchByAddress(%q) = %v, want %v", tt.address, names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestRemoveTags(t *testing.T) {
+	tests := []struct {
+		name        string
+		remove      []int
+		wantRemoved int
+		wantLeft    []int
+	}{
+		{name: "subset of the tags", remove: []int{1, 3}, wantRemoved: 2, wantLeft: []int{2}},
+		{name: "nonexistent tag is a no-op", remove: []int{999}, wantRemoved: 0, wantLeft: []int{1, 2, 3}},
+		{name: "tag the place does not have", remove: []int{4, 2}, wantRemoved: 1, wantLeft: []int{1, 3}},
+		{name: "every tag", remove: []int{1, 2, 3}, wantRemoved: 3, wantLeft: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresLugarRepository(db)
+			id := insertTestLugar(t, db, "Sítio")
+			other := insertTestLugar(t, db, "Chácara")
+			mustExec(t, db, `INSERT INTO lugares_tags (lugar_id, tag_id) VALUES ($1, 1), ($1, 2), ($1, 3), ($2, 1)`, id, other)
+
+			removed, err := repo.RemoveTags(context.Background(), id, tt.remove)
+			if err != nil {
+				t.Fatalf("RemoveTags: %v", err)
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("removed = %d, want %d", removed, tt.wantRemoved)
+			}
+
+			var left []int
+			rows, err := db.Query(`SELECT tag_id FROM lugares_tags WHERE lugar_id = $1 ORDER BY tag_id`, id)
+			if err != nil {
+				t.Fatalf("reading tags: %v", err)
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var tagID int
+				if err := rows.Scan(&tagID); err != nil {
+					t.Fatalf("scanning tag: %v", err)
+				}
+				left = append(left, tagID)
+			}
+			if fmt.Sprint(left) != fmt.Sprint(tt.wantLeft) {
+				t.Errorf("tags left = %v, want %v", left, tt.wantLeft)
+			}
+
+			// The tags of other places are untouched
+			var otherTags int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM lugares_tags WHERE lugar_id = $1`, other).Scan(&otherTags); err != nil {
+				t.Fatalf("counting other tags: %v", err)
+			}
+			if otherTags != 1 {
+				t.Errorf("other lugar has %d tags, want 1", otherTags)
+			}
+		})
+	}
+}
+
+func TestExists(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	active := insertTestLugar(t, db, "Sítio")
+	deleted := insertTestLugar(t, db, "Chácara")
+	mustExec(t, db, `UPDATE lugares SET deleted_at = NOW() WHERE id = $1`, deleted)
+
+	tests := []struct {
+		name string
+		id   int
+		want bool
+	}{
+		{name: "existing lugar", id: active, want: true},
+		{name: "deleted lugar", id: deleted, want: false},
+		{name: "nonexistent lugar", id: 999, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exists, err := repo.Exists(context.Background(), tt.id)
+			if err != nil {
+				t.Fatalf("Exists: %v", err)
+			}
+			if exists != tt.want {
+				t.Errorf("Exists(%d) = %v, want %v", tt.id, exists, tt.want)
+			}
+		})
+	}
+}
+
+func TestListSimilar(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	base := insertTestLugar(t, db, "Sítio")
+	twoTags := insertTestLugar(t, db, "Chácara")
+	oneTagOneRamo := insertTestLugar(t, db, "Acampamento")
+	oneRamo := insertTestLugar(t, db, "Fazenda")
+	unrelated := insertTestLugar(t, db, "Praia")
+	deleted := insertTestLugar(t, db, "Apagado")
+
+	// The base place has tags 1, 2 and 3 and ramos 1 and 2
+	mustExec(t, db, `INSERT INTO lugares_tags (lugar_id, tag_id) VALUES ($1, 1), ($1, 2), ($1, 3)`, base)
+	mustExec(t, db, `INSERT INTO lugares_ramos (lugar_id, ramo_id) VALUES ($1, 1), ($1, 2)`, base)
+	mustExec(t, db, `INSERT INTO lugares_tags (lugar_id, tag_id) VALUES ($1, 1), ($1, 3), ($1, 4)`, twoTags)
+	mustExec(t, db, `INSERT INTO lugares_tags (lugar_id, tag_id) VALUES ($1, 2)`, oneTagOneRamo)
+	mustExec(t, db, `INSERT INTO lugares_ramos (lugar_id, ramo_id) VALUES ($1, 2), ($1, 3)`, oneTagOneRamo)
+	mustExec(t, db, `INSERT INTO lugares_ramos (lugar_id, ramo_id) VALUES ($1, 1)`, oneRamo)
+	mustExec(t, db, `INSERT INTO lugares_tags (lugar_id, tag_id) VALUES ($1, 5)`, unrelated)
+	mustExec(t, db, `INSERT INTO lugares_tags (lugar_id, tag_id) VALUES ($1, 1), ($1, 2), ($1, 3)`, deleted)
+	mustExec(t, db, `UPDATE lugares SET deleted_at = NOW() WHERE id = $1`, deleted)
+
+	var tagNames []string
+	for _, id := range []int{1, 2, 3} {
+		var name string
+		if err := db.QueryRow(`SELECT name FROM tags_lugares WHERE id = $1`, id).Scan(&name); err != nil {
+			t.Fatalf("reading tag %d: %v", id, err)
+		}
+		tagNames = append(tagNames, name)
+	}
+	sortedNames := func(ids ...int) []string {
+		var names []string
+		for _, id := range ids {
+			names = append(names, tagNames[id-1])
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	type want struct {
+		id         int
+		overlap    int
+		sharedTags []string
+	}
+	tests := []struct {
+		name  string
+		limit int
+		want  []want
+	}{
+		{
+			name:  "ranked by overlap, then shared tags",
+			limit: 10,
+			want: []want{
+				{id: twoTags, overlap: 2, sharedTags: sortedNames(1, 3)},
+				{id: oneTagOneRamo, overlap: 2, sharedTags: sortedNames(2)},
+				{id: oneRamo, overlap: 1, sharedTags: []string{}},
+			},
+		},
+		{
+			name:  "limited",
+			limit: 1,
+			want:  []want{{id: twoTags, overlap: 2, sharedTags: sortedNames(1, 3)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			similar, err := repo.ListSimilar(context.Background(), base, tt.limit)
+			if err != nil {
+				t.Fatalf("ListSimilar: %v", err)
+			}
+			if len(similar) != len(tt.want) {
+				t.Fatalf("got %d similar lugares, want %d", len(similar), len(tt.want))
+			}
+			for i, w := range tt.want {
+				got := similar[i]
+				if got.ID != w.id || got.Overlap != w.overlap {
+					t.Errorf("similar[%d] = lugar %d with overlap %d, want lugar %d with overlap %d", i, got.ID, got.Overlap, w.id, w.overlap)
+				}
+				if got.NomeLocal == "" {
+					t.Errorf("similar[%d]: lugar %d was not loaded", i, got.ID)
+				}
+				if got.SharedTags == nil {
+					t.Errorf("similar[%d]: shared tags are nil, want an empty slice", i)
+				}
+				names := []string{}
+				for _, tag := range got.SharedTags {
+					names = append(names, tag.Name)
+				}
+				if fmt.Sprint(names) != fmt.Sprint(w.sharedTags) {
+					t.Errorf("similar[%d]: shared tags = %v, want %v", i, names, w.sharedTags)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxDisplayOrder(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	// Images at orders 1 and 4: deleting the others left a gap
+	withGap := insertTestLugar(t, db, "Com lacuna")
+	for _, order := range []int{1, 4} {
+		mustExec(t, db, `INSERT INTO lugares_images (lugar_id, image_url, display_order) VALUES ($1, 'https://example.com/a.jpg', $2)`, withGap, order)
+	}
+	without := insertTestLugar(t, db, "Sem imagens")
+
+	tests := []struct {
+		name    string
+		lugarID int
+		want    int
+	}{
+		{"lugar with a gap in its orders", withGap, 4},
+		{"lugar without images", without, 0},
+		{"missing lugar", 9999, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxOrder, err := repo.MaxDisplayOrder(ctx, tt.lugarID)
+			if err != nil {
+				t.Fatalf("MaxDisplayOrder: %v", err)
+			}
+			if maxOrder != tt.want {
+				t.Errorf("max display order = %d, want %d", maxOrder, tt.want)
+			}
+		})
+	}
+}
+
+func TestDailyCreationCounts(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 12, 0, 0, 0, time.UTC) }
+	for _, created := range []time.Time{day(1), day(1), day(3)} {
+		id := insertTestLugar(t, db, "Sítio")
+		mustExec(t, db, `UPDATE lugares SET created_at = $2 WHERE id = $1`, id, created)
+	}
+	deleted := insertTestLugar(t, db, "Apagado")
+	mustExec(t, db, `UPDATE lugares SET created_at = $2, deleted_at = NOW() WHERE id = $1`, deleted, day(2))
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []int
+	}{
+		{name: "deleted lugares are left out", from: day(1), to: day(3), want: []int{2, 0, 1}},
+		{name: "single day", from: day(3), to: day(3), want: []int{1}},
+		{name: "day with only a deleted lugar", from: day(2), to: day(2), want: []int{0}},
+		{name: "empty days around the data", from: day(1).AddDate(0, 0, -1), to: day(4), want: []int{0, 2, 0, 1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days, err := repo.DailyCreationCounts(context.Background(), tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("DailyCreationCounts: %v", err)
+			}
+			var counts []int
+			for i, d := range days {
+				counts = append(counts, d.Count)
+				if want := tt.from.AddDate(0, 0, i).Format("2006-01-02"); d.Date != want {
+					t.Errorf("day %d = %s, want %s", i, d.Date, want)
+				}
+			}
+			if fmt.Sprint(counts) != fmt.Sprint(tt.want) {
+				t.Errorf("counts = %v, want %v", counts, tt.want)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+
+	results, err := repo.Import(context.Background(), []*models.Lugar{
+		{
+			NomeLocal: "Sítio",
+			UserID:    1,
+			Tags:      []*models.TagLugar{{Name: "rio"}, {Name: "tirolesa"}},
+			Ramos:     []*models.Ramo{{Name: "lobinho"}, {Name: "pioneiro"}},
+		},
+		{
+			NomeLocal: "Sem dono",
+			UserID:    999,
+			Tags:      []*models.TagLugar{{Name: "arvorismo"}},
+		},
+		{
+			NomeLocal: "Chácara",
+			UserID:    1,
+			Tags:      []*models.TagLugar{{Name: "tirolesa"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		index     int
+		wantError string
+		wantTags  []string
+		wantRamos []string
+	}{
+		{name: "new and existing tags and ramos are linked", index: 0, wantTags: []string{"rio", "tirolesa"}, wantRamos: []string{"lobinho", "pioneiro"}},
+		{name: "failed lugar reports a fixed message", index: 1, wantError: "Invalid reference"},
+		{name: "tag created earlier in the batch is reused", index: 2, wantTags: []string{"tirolesa"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := results[tt.index]
+			if result.Index != tt.index {
+				t.Errorf("index = %d, want %d", result.Index, tt.index)
+			}
+			if result.Error != tt.wantError {
+				t.Fatalf("error = %q, want %q (cause %v)", result.Error, tt.wantError, result.Err)
+			}
+			if tt.wantError != "" {
+				if result.ID != 0 || result.Err == nil {
+					t.Errorf("failed result = %+v, want no ID and the underlying error", result)
+				}
+				return
+			}
+
+			names := func(query string) []string {
+				rows, err := db.Query(query, result.ID)
+				if err != nil {
+					t.Fatalf("reading links: %v", err)
+				}
+				defer rows.Close()
+				var names []string
+				for rows.Next() {
+					var name string
+					if err := rows.Scan(&name); err != nil {
+						t.Fatalf("scanning link: %v", err)
+					}
+					names = append(names, name)
+				}
+				return names
+			}
+			tags := names(`SELECT t.name FROM lugares_tags lt JOIN tags_lugares t ON t.id = lt.tag_id WHERE lt.lugar_id = $1 ORDER BY t.name`)
+			if fmt.Sprint(tags) != fmt.Sprint(tt.wantTags) {
+				t.Errorf("tags = %v, want %v", tags, tt.wantTags)
+			}
+			ramos := names(`SELECT r.name FROM lugares_ramos lr JOIN ramos r ON r.id = lr.ramo_id WHERE lr.lugar_id = $1 ORDER BY r.name`)
+			if fmt.Sprint(ramos) != fmt.Sprint(tt.wantRamos) {
+				t.Errorf("ramos = %v, want %v", ramos, tt.wantRamos)
+			}
+		})
+	}
+
+	// Each tag exists once; the tag of the failed lugar was rolled back with it
+	for name, want := range map[string]int{"tirolesa": 1, "rio": 1, "arvorismo": 0} {
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM tags_lugares WHERE name = $1`, name).Scan(&count); err != nil {
+			t.Fatalf("counting tag %q: %v", name, err)
+		}
+		if count != want {
+			t.Errorf("tag %q exists %d times, want %d", name, count, want)
+		}
+	}
+}
+
+func TestValorNullability(t *testing.T) {
+	zero, price := 0.0, 25.5
+
+	tests := []struct {
+		name        string
+		valorFixo   *float64
+		valorIndiv  *float64
+		updateFixo  *float64
+		wantFixo    sql.NullFloat64
+		wantIndiv   sql.NullFloat64
+		wantUpdated sql.NullFloat64
+	}{
+		{name: "omitted values store NULL", wantUpdated: sql.NullFloat64{Float64: 0, Valid: true}, updateFixo: &zero},
+		{name: "explicit zero stores 0", valorFixo: &zero, valorIndiv: &zero, wantFixo: sql.NullFloat64{Valid: true}, wantIndiv: sql.NullFloat64{Valid: true}, updateFixo: nil},
+		{name: "price is kept", valorFixo: &price, valorIndiv: &zero, wantFixo: sql.NullFloat64{Float64: price, Valid: true}, wantIndiv: sql.NullFloat64{Valid: true}, updateFixo: &price, wantUpdated: sql.NullFloat64{Float64: price, Valid: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresLugarRepository(db)
+			ctx := context.Background()
+
+			lugar := &models.Lugar{NomeLocal: "Sítio", UserID: 1, ValorFixo: tt.valorFixo, ValorIndividual: tt.valorIndiv}
+			id, err := repo.Create(ctx, lugar)
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			var fixo, indiv sql.NullFloat64
+			if err := db.QueryRow(`SELECT valor_fixo, valor_individual FROM lugares WHERE id = $1`, id).Scan(&fixo, &indiv); err != nil {
+				t.Fatalf("reading valores: %v", err)
+			}
+			if fixo != tt.wantFixo || indiv != tt.wantIndiv {
+				t.Errorf("stored valor_fixo = %+v, valor_individual = %+v; want %+v, %+v", fixo, indiv, tt.wantFixo, tt.wantIndiv)
+			}
+
+			// Reading back keeps "not specified" and "free" apart
+			stored, err := repo.GetByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if (stored.ValorFixo != nil) != tt.wantFixo.Valid || (stored.ValorFixo != nil && *stored.ValorFixo != tt.wantFixo.Float64) {
+				t.Errorf("read valor_fixo = %v, want %+v", stored.ValorFixo, tt.wantFixo)
+			}
+
+			// Updates write NULL and 0 the same way
+			stored.ValorFixo = tt.updateFixo
+			if err := repo.Update(ctx, stored); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			if err := db.QueryRow(`SELECT valor_fixo FROM lugares WHERE id = $1`, id).Scan(&fixo); err != nil {
+				t.Fatalf("reading valor_fixo: %v", err)
+			}
+			if fixo != tt.wantUpdated {
+				t.Errorf("updated valor_fixo = %+v, want %+v", fixo, tt.wantUpdated)
+			}
+		})
+	}
+}
+
+func TestListAllImages(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	sitio := insertTestLugar(t, db, "Sítio")
+	chacara := insertTestLugar(t, db, "Chácara")
+	deleted := insertTestLugar(t, db, "Apagado")
+	mustExec(t, db, `UPDATE lugares SET deleted_at = NOW() WHERE id = $1`, deleted)
+
+	insertImage := func(lugarID int, url, createdAt string) int {
+		var id int
+		err := db.QueryRow(`INSERT INTO lugares_images (lugar_id, image_url, display_order, created_at) VALUES ($1, $2, 1, $3) RETURNING id`, lugarID, url, createdAt).Scan(&id)
+		if err != nil {
+			t.Fatalf("inserting image %q: %v", url, err)
+		}
+		return id
+	}
+	oldest := insertImage(sitio, "https://example.com/1.jpg", "2024-01-01T00:00:00Z")
+	newest := insertImage(chacara, "https://example.com/2.jpg", "2024-03-01T00:00:00Z")
+	// Same timestamp as newest: the higher ID comes first
+	tied := insertImage(sitio, "https://example.com/3.jpg", "2024-03-01T00:00:00Z")
+	insertImage(deleted, "https://example.com/4.jpg", "2024-05-01T00:00:00Z")
+
+	tests := []struct {
+		name      string
+		page      Pagination
+		wantIDs   []int
+		wantNomes []string
+	}{
+		{"first page", Pagination{Limit: 10}, []int{tied, newest, oldest}, []string{"Sítio", "Chácara", "Sítio"}},
+		{"limited", Pagination{Limit: 2}, []int{tied, newest}, []string{"Sítio", "Chácara"}},
+		{"offset", Pagination{Limit: 2, Offset: 2}, []int{oldest}, []string{"Sítio"}},
+		{"past the end", Pagination{Limit: 2, Offset: 5}, []int{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			images, err := repo.ListAllImages(ctx, tt.page)
+			if err != nil {
+				t.Fatalf("ListAllImages: %v", err)
+			}
+			ids, nomes := []int{}, []string{}
+			for _, image := range images {
+				ids = append(ids, image.ID)
+				nomes = append(nomes, image.LugarNome)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if fmt.Sprint(nomes) != fmt.Sprint(tt.wantNomes) {
+				t.Errorf("nomes = %v, want %v", nomes, tt.wantNomes)
+			}
+		})
+	}
+
+	// The images of deleted lugares are not counted either
+	count, err := repo.CountAllImages(ctx)
+	if err != nil {
+		t.Fatalf("CountAllImages: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("count = %d, want 3", count)
+	}
+}
+
+func TestListRatingsByValue(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	cachoeira := insertTestLugar(t, db, "Cachoeira")
+	sitio := insertTestLugar(t, db, "Sítio")
+	removido := insertTestLugar(t, db, "Removido")
+	rater := insertTestUser(t, db, "rater")
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating, date) VALUES ($1, 1, 1, '2024-03-01')`, cachoeira)
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating, date) VALUES ($1, 2, 1, '2024-05-01')`, sitio)
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating, date) VALUES ($1, $2, 1, '2024-04-01')`, cachoeira, rater)
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating, date) VALUES ($1, 1, 5, '2024-06-01')`, sitio)
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating, date) VALUES ($1, 2, 4, '2024-04-15')`, cachoeira)
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating, date) VALUES ($1, 1, 1, '2024-07-01')`, removido)
+	mustExec(t, db, `UPDATE lugares SET deleted_at = NOW() WHERE id = $1`, removido)
+
+	tests := []struct {
+		name      string
+		rating    int
+		page      Pagination
+		want      []string
+		wantCount int
+	}{
+		{name: "one star, newest first", rating: 1, page: Pagination{Limit: 10}, want: []string{"Sítio 2024-05-01", "Cachoeira 2024-04-01", "Cachoeira 2024-03-01"}, wantCount: 3},
+		{name: "first page", rating: 1, page: Pagination{Limit: 2}, want: []string{"Sítio 2024-05-01", "Cachoeira 2024-04-01"}, wantCount: 3},
+		{name: "second page", rating: 1, page: Pagination{Limit: 2, Offset: 2}, want: []string{"Cachoeira 2024-03-01"}, wantCount: 3},
+		{name: "five stars", rating: 5, page: Pagination{Limit: 10}, want: []string{"Sítio 2024-06-01"}, wantCount: 1},
+		{name: "unused value", rating: 2, page: Pagination{Limit: 10}, want: []string{}, wantCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ratings, err := repo.ListRatingsByValue(ctx, tt.rating, tt.page)
+			if err != nil {
+				t.Fatalf("ListRatingsByValue: %v", err)
+			}
+			got := []string{}
+			for _, rating := range ratings {
+				if rating.Rating != tt.rating {
+					t.Errorf("rating %d has value %d, want %d", rating.ID, rating.Rating, tt.rating)
+				}
+				got = append(got, rating.LugarNome+" "+rating.Date.Format("2006-01-02"))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ListRatingsByValue(%d) = %v, want %v", tt.rating, got, tt.want)
+			}
+
+			count, err := repo.CountRatingsByValue(ctx, tt.rating)
+			if err != nil {
+				t.Fatalf("CountRatingsByValue: %v", err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("count = %d, want %d", count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestTouch(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	lugar := insertTestLugar(t, db, "Sítio")
+	mustExec(t, db, `UPDATE lugares SET endereco_completo = 'Estrada do Sítio, 100', updated_at = '2024-01-01T00:00:00Z' WHERE id = $1`, lugar)
+	deleted := insertTestLugar(t, db, "Apagado")
+	mustExec(t, db, `UPDATE lugares SET deleted_at = NOW() WHERE id = $1`, deleted)
+
+	tests := []struct {
+		name    string
+		id      int
+		wantNil bool
+	}{
+		{name: "lugar", id: lugar},
+		{name: "deleted lugar", id: deleted, wantNil: true},
+		{name: "missing lugar", id: 9999, wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := repo.GetByIDIncludingDeleted(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("GetByIDIncludingDeleted: %v", err)
+			}
+
+			updatedAt, err := repo.Touch(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("Touch: %v", err)
+			}
+			if (updatedAt == nil) != tt.wantNil {
+				t.Fatalf("updated_at = %v, want nil %v", updatedAt, tt.wantNil)
+			}
+			if updatedAt == nil {
+				return
+			}
+
+			after, err := repo.GetByID(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if !after.UpdatedAt.After(before.UpdatedAt) || !after.UpdatedAt.Equal(*updatedAt) {
+				t.Errorf("updated_at = %s (returned %s), want it advanced from %s", after.UpdatedAt, updatedAt, before.UpdatedAt)
+			}
+
+			// Nothing else changes
+			after.UpdatedAt = before.UpdatedAt
+			if !reflect.DeepEqual(after, before) {
+				t.Errorf("lugar = %+v, want %+v", after, before)
+			}
+		})
+	}
+}
+
+func TestListUnpublished(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	published := insertTestLugar(t, db, "Publicado")
+	var draft int
+	if err := db.QueryRow(`INSERT INTO lugares (nome_local, user_id) VALUES ('Rascunho', 1) RETURNING id`).Scan(&draft); err != nil {
+		t.Fatalf("inserting draft: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts LugarListOptions
+		want []int
+	}{
+		{"published only by default", LugarListOptions{Pagination: Pagination{Limit: 10}}, []int{published}},
+		{"including unpublished", LugarListOptions{IncludeUnpublished: true, Pagination: Pagination{Limit: 10}}, []int{published, draft}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lugares, err := repo.List(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			got := []int{}
+			for _, lugar := range lugares {
+				got = append(got, lugar.ID)
+			}
+			sort.Ints(got)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+
+			count, err := repo.Count(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("Count: %v", err)
+			}
+			if count != len(tt.want) {
+				t.Errorf("count = %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+
+	// The draft can still be fetched by ID, for its owner and admins
+	lugar, err := repo.GetByID(ctx, draft)
+	if err != nil || lugar == nil || lugar.Published {
+		t.Errorf("GetByID(draft) = %+v, %v; want the unpublished lugar", lugar, err)
+	}
+}
+
+func TestListSelectOptions(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	sitio := insertTestLugar(t, db, "Sítio")
+	acampamento := insertTestLugar(t, db, "Acampamento")
+	// Equal names are ordered by ID
+	outroSitio := insertTestLugar(t, db, "Sítio")
+	removido := insertTestLugar(t, db, "Removido")
+	mustExec(t, db, `UPDATE lugares SET deleted_at = NOW() WHERE id = $1`, removido)
+	hino := insertTestCancao(t, db, "Hino")
+	removida := insertTestCancao(t, db, "Removida")
+	mustExec(t, db, `UPDATE cancoes SET deleted_at = NOW() WHERE id = $1`, removida)
+
+	tests := []struct {
+		name      string
+		list      func() ([]*models.SelectOption, error)
+		wantFirst []models.SelectOption
+		wantLen   int
+	}{
+		{
+			name: "lugares by name, without deleted ones",
+			list: func() ([]*models.SelectOption, error) {
+				return NewPostgresLugarRepository(db).ListSelectOptions(ctx, Pagination{Limit: 10})
+			},
+			wantFirst: []models.SelectOption{{ID: acampamento, Label: "Acampamento"}, {ID: sitio, Label: "Sítio"}, {ID: outroSitio, Label: "Sítio"}},
+			wantLen:   3,
+		},
+		{
+			name: "cancoes without deleted ones",
+			list: func() ([]*models.SelectOption, error) {
+				return NewPostgresCancaoRepository(db).ListSelectOptions(ctx, Pagination{Limit: 10})
+			},
+			wantFirst: []models.SelectOption{{ID: hino, Label: "Hino"}},
+			wantLen:   1,
+		},
+		{
+			name: "ramos by name",
+			list: func() ([]*models.SelectOption, error) {
+				return NewPostgresRamoRepository(db).ListSelectOptions(ctx, Pagination{Limit: 10})
+			},
+			wantFirst: []models.SelectOption{{ID: 5, Label: "clã"}, {ID: 3, Label: "escoteiro"}, {ID: 1, Label: "filhotes"}, {ID: 2, Label: "lobinho"}, {ID: 4, Label: "senior"}},
+			wantLen:   5,
+		},
+		{
+			name: "a page of the ramos",
+			list: func() ([]*models.SelectOption, error) {
+				return NewPostgresRamoRepository(db).ListSelectOptions(ctx, Pagination{Limit: 2, Offset: 1})
+			},
+			wantFirst: []models.SelectOption{{ID: 3, Label: "escoteiro"}, {ID: 1, Label: "filhotes"}},
+			wantLen:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, err := tt.list()
+			if err != nil {
+				t.Fatalf("ListSelectOptions: %v", err)
+			}
+			if len(options) != tt.wantLen {
+				t.Fatalf("got %d options, want %d", len(options), tt.wantLen)
+			}
+			for i, want := range tt.wantFirst {
+				if *options[i] != want {
+					t.Errorf("option %d = %+v, want %+v", i, *options[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetUserRating(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	sitio := insertTestLugar(t, db, "Sítio")
+	chacara := insertTestLugar(t, db, "Chácara")
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating) VALUES ($1, 2, 4), ($1, 1, 2), ($2, 1, 5)`, sitio, chacara)
+
+	tests := []struct {
+		name       string
+		lugarID    int
+		userID     int
+		wantRating int
+	}{
+		{"rated", sitio, 2, 4},
+		{"another user's rating of the same lugar", sitio, 1, 2},
+		{"not rated by the user", chacara, 2, 0},
+		{"missing lugar", 9999, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rating, err := repo.GetUserRating(ctx, tt.lugarID, tt.userID)
+			if err != nil {
+				t.Fatalf("GetUserRating: %v", err)
+			}
+			if tt.wantRating == 0 {
+				if rating != nil {
+					t.Errorf("rating = %+v, want nil", rating)
+				}
+				return
+			}
+			if rating == nil {
+				t.Fatal("rating = nil, want a rating")
+			}
+			if rating.Rating != tt.wantRating || rating.LugarID != tt.lugarID || rating.UserID != tt.userID {
+				t.Errorf("rating = %+v, want %d stars by user %d for lugar %d", rating, tt.wantRating, tt.userID, tt.lugarID)
+			}
+		})
+	}
+}
+
+func TestMissingIDs(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		missing func(ctx context.Context, ids []int) ([]int, error)
+		ids     []int
+		want    []int
+	}{
+		{"no tags", repo.MissingTagIDs, nil, nil},
+		{"existing tags", repo.MissingTagIDs, []int{1, 2, 3}, nil},
+		{"mix of tags", repo.MissingTagIDs, []int{99, 1, 98, 2}, []int{99, 98}},
+		{"repeated unknown tag", repo.MissingTagIDs, []int{99, 99, 1}, []int{99}},
+		{"no ramos", repo.MissingRamoIDs, []int{}, nil},
+		{"existing ramos", repo.MissingRamoIDs, []int{1, 5}, nil},
+		{"mix of ramos", repo.MissingRamoIDs, []int{3, 9, 6}, []int{9, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.missing(ctx, tt.ids)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("missing = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListLugaresSortedByTagCount(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	tagCounts := []struct {
+		nome string
+		tags int
+	}{
+		{"Sem tags", 0},
+		{"Bem documentado", 3},
+		{"Uma tag", 1},
+		{"Outra com uma tag", 1},
+	}
+	for _, lugar := range tagCounts {
+		id := insertTestLugar(t, db, lugar.nome)
+		for tagID := 1; tagID <= lugar.tags; tagID++ {
+			mustExec(t, db, `INSERT INTO lugares_tags (lugar_id, tag_id) VALUES ($1, $2)`, id, tagID)
+		}
+	}
+
+	tests := []struct {
+		name string
+		sort string
+		want []string
+	}{
+		{"default order", "", []string{"Sem tags", "Bem documentado", "Uma tag", "Outra com uma tag"}},
+		// Ties keep the ID order
+		{"tag count descending", "tag_count", []string{"Bem documentado", "Uma tag", "Outra com uma tag", "Sem tags"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lugares, err := repo.List(ctx, LugarListOptions{Sort: tt.sort, Pagination: Pagination{Limit: 10}})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(lugares) != len(tt.want) {
+				t.Fatalf("got %d lugares, want %d", len(lugares), len(tt.want))
+			}
+			for i, lugar := range lugares {
+				if lugar.NomeLocal != tt.want[i] {
+					t.Errorf("position %d: got %q, want %q", i, lugar.NomeLocal, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := repo.List(ctx, LugarListOptions{Sort: "nome_local"}); err == nil {
+		t.Error("expected an error for a sort value outside the whitelist")
+	}
+}
+
+func TestListByOwner(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	insertTestLugar(t, db, "Do admin")
+	owned := insertTestLugar(t, db, "Do usuário")
+	draft := insertTestLugar(t, db, "Rascunho do usuário")
+	mustExec(t, db, `UPDATE lugares SET user_id = 2 WHERE id IN ($1, $2)`, owned, draft)
+	mustExec(t, db, `UPDATE lugares SET published = false WHERE id = $1`, draft)
+
+	tests := []struct {
+		name string
+		opts LugarListOptions
+		want []string
+	}{
+		{"owner", LugarListOptions{UserID: 2, IncludeUnpublished: true}, []string{"Do usuário", "Rascunho do usuário"}},
+		{"all owners", LugarListOptions{IncludeUnpublished: true}, []string{"Do admin", "Do usuário", "Rascunho do usuário"}},
+		{"owner without lugares", LugarListOptions{UserID: 9999, IncludeUnpublished: true}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Pagination = Pagination{Limit: 10}
+			lugares, err := repo.List(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			got := []string{}
+			for _, lugar := range lugares {
+				got = append(got, lugar.NomeLocal)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lugares = %v, want %v", got, tt.want)
+			}
+
+			count, err := repo.Count(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("Count: %v", err)
+			}
+			if count != len(tt.want) {
+				t.Errorf("count = %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestRatingSummaries(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	sitio := insertTestLugar(t, db, "Sítio")
+	chacara := insertTestLugar(t, db, "Chácara")
+	unrated := insertTestLugar(t, db, "Sem avaliações")
+	other := insertTestUser(t, db, "terceiro")
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating) VALUES ($1, 1, 5), ($1, 2, 4), ($1, $3, 2), ($2, 2, 3)`, sitio, chacara, other)
+
+	tests := []struct {
+		name string
+		ids  []int
+		want map[int]models.RatingSummary
+	}{
+		{
+			name: "per lugar aggregates",
+			ids:  []int{sitio, chacara},
+			want: map[int]models.RatingSummary{
+				sitio:   {LugarID: sitio, AverageRating: models.AverageRating(11.0 / 3), RatingCount: 3},
+				chacara: {LugarID: chacara, AverageRating: 3, RatingCount: 1},
+			},
+		},
+		{
+			name: "zeros for unrated and missing lugares",
+			ids:  []int{unrated, 9999},
+			want: map[int]models.RatingSummary{
+				unrated: {LugarID: unrated},
+				9999:    {LugarID: 9999},
+			},
+		},
+		{
+			name: "repeated IDs",
+			ids:  []int{chacara, chacara},
+			want: map[int]models.RatingSummary{
+				chacara: {LugarID: chacara, AverageRating: 3, RatingCount: 1},
+			},
+		},
+		{name: "no IDs", ids: nil, want: map[int]models.RatingSummary{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summaries, err := repo.RatingSummaries(ctx, tt.ids)
+			if err != nil {
+				t.Fatalf("RatingSummaries: %v", err)
+			}
+			if len(summaries) != len(tt.want) {
+				t.Fatalf("got %d summaries, want %d", len(summaries), len(tt.want))
+			}
+			for id, want := range tt.want {
+				got := summaries[id]
+				if got == nil {
+					t.Errorf("no summary for lugar %d", id)
+					continue
+				}
+				if got.LugarID != want.LugarID || got.RatingCount != want.RatingCount || math.Abs(float64(got.AverageRating-want.AverageRating)) > 1e-9 {
+					t.Errorf("summary of lugar %d = %+v, want %+v", id, *got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateRatingIfUnchanged(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	lugarID := insertTestLugar(t, db, "Sítio")
+	rating := &models.LugarRating{LugarID: lugarID, UserID: 2, Rating: 3, Date: time.Now().UTC()}
+	ratingID, err := repo.AddRating(ctx, rating)
+	if err != nil {
+		t.Fatalf("AddRating: %v", err)
+	}
+	if rating.Version != 1 {
+		t.Fatalf("new rating version = %d, want 1", rating.Version)
+	}
+
+	// Each step runs on the rating as left by the previous one
+	tests := []struct {
+		name            string
+		expectedVersion int
+		stars           int
+		wantErr         error
+		wantVersion     int
+		wantStars       int
+	}{
+		{"current version", 1, 4, nil, 2, 4},
+		{"stale version from another tab", 1, 1, ErrStaleWrite, 2, 4},
+		{"version read after the update", 2, 5, nil, 3, 5},
+		{"version from the future", 9, 2, ErrStaleWrite, 3, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := &models.LugarRating{ID: ratingID, LugarID: lugarID, UserID: 2, Rating: tt.stars, Date: time.Now().UTC()}
+			err := repo.UpdateRatingIfUnchanged(ctx, update, tt.expectedVersion)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && update.Version != tt.wantVersion {
+				t.Errorf("returned version = %d, want %d", update.Version, tt.wantVersion)
+			}
+
+			stored, err := repo.GetUserRating(ctx, lugarID, 2)
+			if err != nil {
+				t.Fatalf("GetUserRating: %v", err)
+			}
+			if stored.Version != tt.wantVersion || stored.Rating != tt.wantStars {
+				t.Errorf("stored rating = %d stars at version %d, want %d at %d", stored.Rating, stored.Version, tt.wantStars, tt.wantVersion)
+			}
+		})
+	}
+
+	// Unconditional updates and re-rating also move the version on
+	if err := repo.UpdateRating(ctx, &models.LugarRating{ID: ratingID, Rating: 2, Date: time.Now().UTC()}); err != nil {
+		t.Fatalf("UpdateRating: %v", err)
+	}
+	again := &models.LugarRating{LugarID: lugarID, UserID: 2, Rating: 1, Date: time.Now().UTC()}
+	if _, err := repo.AddRating(ctx, again); err != nil {
+		t.Fatalf("AddRating: %v", err)
+	}
+	if again.Version != 5 {
+		t.Errorf("version after re-rating = %d, want 5", again.Version)
+	}
+
+	// A missing rating is not a conflict
+	err = repo.UpdateRatingIfUnchanged(ctx, &models.LugarRating{ID: 9999, Rating: 3, Date: time.Now().UTC()}, 1)
+	if err == nil || errors.Is(err, ErrStaleWrite) {
+		t.Errorf("error for a missing rating = %v, want a not found error", err)
+	}
+}
+
+func TestListByMaxValorIndividual(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+
+	priced := func(nome string, valor interface{}, publico bool) int {
+		id := insertTestLugar(t, db, nome)
+		mustExec(t, db, `UPDATE lugares SET valor_individual = $2, local_publico = $3 WHERE id = $1`, id, valor, publico)
+		return id
+	}
+	below := priced("Abaixo", 30, false)
+	at := priced("No limite", 50, false)
+	above := priced("Acima", 80, false)
+	free := priced("Gratuito", 0, false)
+	public := priced("Praça", nil, true)
+	publicAbove := priced("Parque pago", 120, true)
+	priced("Sem preço", nil, false)
+
+	tests := []struct {
+		name string
+		max  float64
+		want []int
+	}{
+		{"budget", 50, []int{below, at, free, public, publicAbove}},
+		{"just below a price", 49.99, []int{below, free, public, publicAbove}},
+		{"free places only", 0, []int{free, public, publicAbove}},
+		{"large budget", 1000, []int{below, at, above, free, public, publicAbove}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lugares, err := repo.List(context.Background(), LugarListOptions{MaxValorIndividual: &tt.max, Pagination: Pagination{Limit: 20}})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var got []int
+			for _, lugar := range lugares {
+				got = append(got, lugar.ID)
+			}
+			sort.Ints(got)
+			sort.Ints(tt.want)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteImageCompact(t *testing.T) {
+	tests := []struct {
+		name    string
+		delete  int
+		compact bool
+		want    []int
+	}{
+		{name: "middle image, compacted", delete: 1, compact: true, want: []int{1, 2, 3}},
+		{name: "first image, compacted", delete: 0, compact: true, want: []int{1, 2, 3}},
+		{name: "middle image, gap kept", delete: 1, compact: false, want: []int{1, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresLugarRepository(db)
+			ctx := context.Background()
+
+			lugarID := insertTestLugar(t, db, "Sítio")
+			otherID := insertTestLugar(t, db, "Chácara")
+			var images []int
+			for order := 1; order <= 4; order++ {
+				images = append(images, insertTestImage(t, db, lugarID, order))
+			}
+			insertTestImage(t, db, otherID, 5)
+
+			if err := repo.DeleteImage(ctx, images[tt.delete], tt.compact); err != nil {
+				t.Fatalf("DeleteImage: %v", err)
+			}
+
+			remaining, err := repo.GetImages(ctx, lugarID)
+			if err != nil {
+				t.Fatalf("GetImages: %v", err)
+			}
+			var orders, ids []int
+			for _, image := range remaining {
+				orders = append(orders, image.DisplayOrder)
+				ids = append(ids, image.ID)
+			}
+			if fmt.Sprint(orders) != fmt.Sprint(tt.want) {
+				t.Errorf("display orders = %v, want %v", orders, tt.want)
+			}
+			wantIDs := append(append([]int{}, images[:tt.delete]...), images[tt.delete+1:]...)
+			if fmt.Sprint(ids) != fmt.Sprint(wantIDs) {
+				t.Errorf("images = %v, want %v in their previous order", ids, wantIDs)
+			}
+
+			other, err := repo.GetImages(ctx, otherID)
+			if err != nil {
+				t.Fatalf("GetImages: %v", err)
+			}
+			if len(other) != 1 || other[0].DisplayOrder != 5 {
+				t.Errorf("the images of another lugar were renumbered")
+			}
+		})
+	}
+
+	t.Run("unknown image", func(t *testing.T) {
+		db := newTestDB(t)
+		if err := NewPostgresLugarRepository(db).DeleteImage(context.Background(), 999, true); err == nil {
+			t.Error("deleting an unknown image succeeded")
+		}
+	})
+}
+
+func TestAddImageConcurrently(t *testing.T) {
+	db := newTestDB(t)
+	db.SetMaxOpenConns(10)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		existing int
+		workers  int
+		batch    int
+	}{
+		{name: "two simultaneous adds", workers: 2, batch: 1},
+		{name: "simultaneous adds after existing images", existing: 3, workers: 2, batch: 1},
+		{name: "many simultaneous adds", workers: 10, batch: 1},
+		{name: "simultaneous batches", workers: 5, batch: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lugarID := insertTestLugar(t, db, tt.name)
+			for order := 1; order <= tt.existing; order++ {
+				insertTestImage(t, db, lugarID, order)
+			}
+
+			// The workers wait for start so their adds overlap as much as possible
+			start := make(chan struct{})
+			var wg sync.WaitGroup
+			errs := make(chan error, tt.workers)
+			for w := 0; w < tt.workers; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					var images []*models.LugarImage
+					for i := 0; i < tt.batch; i++ {
+						images = append(images, models.NewLugarImage(lugarID, "https://example.com/image.jpg", 0))
+					}
+					<-start
+					if err := repo.AddImages(ctx, images, 0); err != nil {
+						errs <- err
+					}
+				}()
+			}
+			close(start)
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Fatalf("AddImages: %v", err)
+			}
+
+			images, err := repo.GetImages(ctx, lugarID)
+			if err != nil {
+				t.Fatalf("GetImages: %v", err)
+			}
+			want := tt.existing + tt.workers*tt.batch
+			if len(images) != want {
+				t.Fatalf("got %d images, want %d", len(images), want)
+			}
+			for i, image := range images {
+				if image.DisplayOrder != i+1 {
+					t.Errorf("image %d has display order %d, want %d: orders must be distinct and contiguous", image.ID, image.DisplayOrder, i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestDraftLugaresStayHidden(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+
+	owner := insertTestUser(t, db, "autora")
+	published := insertTestLugar(t, db, "Sede Publicada")
+	var draft int
+	if err := db.QueryRow(`INSERT INTO lugares (nome_local, user_id, published) VALUES ('Sede Rascunho', $1, false) RETURNING id`, owner).Scan(&draft); err != nil {
+		t.Fatalf("inserting draft lugar: %v", err)
+	}
+	for _, id := range []int{published, draft} {
+		mustExec(t, db, `UPDATE lugares SET latitude = -23.55, longitude = -46.63 WHERE id = $1`, id)
+		mustExec(t, db, `INSERT INTO lugares_tags (lugar_id, tag_id) VALUES ($1, 1)`, id)
+		mustExec(t, db, `INSERT INTO lugares_ramos (lugar_id, ramo_id) VALUES ($1, 1)`, id)
+		mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating) VALUES ($1, 1, 5)`, id)
+		insertTestImage(t, db, id, 0)
+	}
+	// A second published place for the draft to be similar to
+	similarTo := insertTestLugar(t, db, "Sede Vizinha")
+	mustExec(t, db, `INSERT INTO lugares_tags (lugar_id, tag_id) VALUES ($1, 1)`, similarTo)
+
+	page := Pagination{Limit: 10}
+	listings := []struct {
+		name string
+		list func(ctx context.Context) ([]int, error)
+	}{
+		{"options", func(ctx context.Context) ([]int, error) {
+			options, err := repo.ListSelectOptions(ctx, page)
+			ids := []int{}
+			for _, option := range options {
+				ids = append(ids, option.ID)
+			}
+			return ids, err
+		}},
+		{"by user", func(ctx context.Context) ([]int, error) {
+			return lugarIDs(repo.ListByUser(ctx, owner, page))
+		}},
+		{"bounding box", func(ctx context.Context) ([]int, error) {
+			return lugarIDs(repo.ListInBoundingBox(ctx, -24, -47, -23, -46, page))
+		}},
+		{"nearest", func(ctx context.Context) ([]int, error) {
+			return lugarIDs(repo.ListNearest(ctx, -23.55, -46.63, page))
+		}},
+		{"duplicates", func(ctx context.Context) ([]int, error) {
+			return lugarIDs(repo.FindSimilar(ctx, "Sede Rascunho", ""))
+		}},
+		{"similar", func(ctx context.Context) ([]int, error) {
+			similar, err := repo.ListSimilar(ctx, similarTo, 10)
+			ids := []int{}
+			for _, item := range similar {
+				ids = append(ids, item.ID)
+			}
+			return ids, err
+		}},
+		{"recent ratings", func(ctx context.Context) ([]int, error) {
+			return ratingLugarIDs(repo.RecentRatings(ctx, 10))
+		}},
+		{"ratings by value", func(ctx context.Context) ([]int, error) {
+			return ratingLugarIDs(repo.ListRatingsByValue(ctx, 5, page))
+		}},
+		{"images", func(ctx context.Context) ([]int, error) {
+			images, err := repo.ListAllImages(ctx, page)
+			ids := []int{}
+			for _, image := range images {
+				ids = append(ids, image.LugarID)
+			}
+			return ids, err
+		}},
+	}
+	counts := []struct {
+		name  string
+		count func(ctx context.Context) (int, error)
+		// published is the count without the draft
+		published int
+	}{
+		{"bounding box", func(ctx context.Context) (int, error) {
+			return repo.CountInBoundingBox(ctx, -24, -47, -23, -46)
+		}, 1},
+		{"with coordinates", repo.CountWithCoordinates, 1},
+		{"ratings by value", func(ctx context.Context) (int, error) {
+			return repo.CountRatingsByValue(ctx, 5)
+		}, 1},
+		{"images", repo.CountAllImages, 1},
+	}
+
+	viewers := []struct {
+		name    string
+		ctx     context.Context
+		visible bool
+	}{
+		{"no viewer", context.Background(), false},
+		{"anonymous", WithViewer(context.Background(), Viewer{}), false},
+		{"another user", WithViewer(context.Background(), Viewer{UserID: 2}), false},
+		{"owner", WithViewer(context.Background(), Viewer{UserID: owner}), true},
+		{"admin", WithViewer(context.Background(), Viewer{UserID: 1, Admin: true}), true},
+	}
+
+	for _, viewer := range viewers {
+		for _, listing := range listings {
+			t.Run(viewer.name+"/"+listing.name, func(t *testing.T) {
+				ids, err := listing.list(viewer.ctx)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				found := false
+				for _, id := range ids {
+					found = found || id == draft
+				}
+				if found != viewer.visible {
+					t.Errorf("draft listed = %v, want %v (ids %v)", found, viewer.visible, ids)
+				}
+			})
+		}
+		for _, count := range counts {
+			t.Run(viewer.name+"/count "+count.name, func(t *testing.T) {
+				got, err := count.count(viewer.ctx)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				want := count.published
+				if viewer.visible {
+					want++
+				}
+				if got != want {
+					t.Errorf("count = %d, want %d", got, want)
+				}
+			})
+		}
+	}
+}
+
+// lugarIDs returns the IDs of the places returned by a listing
+func lugarIDs(lugares []*models.Lugar, err error) ([]int, error) {
+	ids := []int{}
+	for _, lugar := range lugares {
+		ids = append(ids, lugar.ID)
+	}
+	return ids, err
+}
+
+// ratingLugarIDs returns the IDs of the rated places returned by a ratings listing
+func ratingLugarIDs(ratings []*models.RecentRating, err error) ([]int, error) {
+	ids := []int{}
+	for _, rating := range ratings {
+		ids = append(ids, rating.LugarID)
+	}
+	return ids, err
+}
+
+func TestUpdateKeepsOwner(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		userID int
+	}{
+		{name: "another owner", userID: 2},
+		{name: "no owner", userID: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := insertTestLugar(t, db, "Sítio")
+			if err := repo.Update(ctx, &models.Lugar{ID: id, NomeLocal: "Sítio Novo", UserID: tt.userID}); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+
+			var nome string
+			var owner int
+			if err := db.QueryRow(`SELECT nome_local, user_id FROM lugares WHERE id = $1`, id).Scan(&nome, &owner); err != nil {
+				t.Fatalf("reading lugar: %v", err)
+			}
+			if nome != "Sítio Novo" {
+				t.Errorf("nome_local = %q, want %q", nome, "Sítio Novo")
+			}
+			if owner != 1 {
+				t.Errorf("user_id = %d, want 1", owner)
+			}
+		})
+	}
+}
+
+func TestSmallListsArePaged(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	lugarID := insertTestLugar(t, db, "Acampamento")
+	second := insertTestUser(t, db, "segundo")
+	third := insertTestUser(t, db, "terceiro")
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating, date) VALUES ($1, 1, 5, '2024-01-01'), ($1, $2, 4, '2024-02-01'), ($1, $3, 3, '2024-03-01')`, lugarID, second, third)
+
+	lugarRepo := NewPostgresLugarRepository(db)
+	ramoRepo := NewPostgresRamoRepository(db)
+	tagRepo := NewPostgresTagLugarRepository(db)
+	cancaoTagRepo := NewPostgresTagCancaoRepository(db)
+
+	tests := []struct {
+		name  string
+		list  func() (int, error)
+		count func() (int, error)
+		want  int
+	}{
+		{
+			name: "ratings of a lugar",
+			list: func() (int, error) {
+				ratings, err := lugarRepo.GetRatings(ctx, lugarID, Pagination{Limit: 2})
+				return len(ratings), err
+			},
+			count: func() (int, error) { return lugarRepo.CountRatings(ctx, lugarID) },
+			want:  3,
+		},
+		{
+			name: "ramos",
+			list: func() (int, error) {
+				ramos, err := ramoRepo.List(ctx, Pagination{Limit: 2})
+				return len(ramos), err
+			},
+			count: func() (int, error) { return ramoRepo.Count(ctx) },
+			want:  5,
+		},
+		{
+			name: "lugar tags",
+			list: func() (int, error) {
+				tags, err := tagRepo.List(ctx, Pagination{Limit: 2})
+				return len(tags), err
+			},
+			count: func() (int, error) { return tagRepo.Count(ctx) },
+		},
+		{
+			name: "cancao tags",
+			list: func() (int, error) {
+				tags, err := cancaoTagRepo.List(ctx, Pagination{Limit: 2})
+				return len(tags), err
+			},
+			count: func() (int, error) { return cancaoTagRepo.Count(ctx) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.list()
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if got != 2 {
+				t.Errorf("got %d items, want the page of 2", got)
+			}
+
+			total, err := tt.count()
+			if err != nil {
+				t.Fatalf("count: %v", err)
+			}
+			if tt.want != 0 && total != tt.want {
+				t.Errorf("got a total of %d, want %d", total, tt.want)
+			}
+			if total <= got {
+				t.Errorf("got a total of %d, want more than the page", total)
+			}
+		})
+	}
+}
+
+func TestConcurrentAddImagesKeepTheLimit(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresLugarRepository(db)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		existing  int
+		maxImages int
+		workers   int
+		batch     int
+	}{
+		{name: "single images", existing: 3, maxImages: 5, workers: 10, batch: 1},
+		{name: "batches", existing: 1, maxImages: 7, workers: 5, batch: 2},
+		{name: "a lugar already at the limit", existing: 2, maxImages: 2, workers: 3, batch: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lugarID := insertTestLugar(t, db, tt.name)
+			for order := 1; order <= tt.existing; order++ {
+				insertTestImage(t, db, lugarID, order)
+			}
+
+			// The workers wait for start so their counts overlap as much as possible
+			start := make(chan struct{})
+			var wg sync.WaitGroup
+			errs := make(chan error, tt.workers)
+			for w := 0; w < tt.workers; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					var images []*models.LugarImage
+					for i := 0; i < tt.batch; i++ {
+						images = append(images, models.NewLugarImage(lugarID, "https://example.com/image.jpg", 0))
+					}
+					<-start
+					errs <- repo.AddImages(ctx, images, tt.maxImages)
+				}()
+			}
+			close(start)
+			wg.Wait()
+			close(errs)
+
+			added := 0
+			for err := range errs {
+				switch {
+				case err == nil:
+					added++
+				case !errors.Is(err, ErrImageLimit):
+					t.Fatalf("AddImages: %v", err)
+				}
+			}
+
+			wantAdded := (tt.maxImages - tt.existing) / tt.batch
+			if added != wantAdded {
+				t.Errorf("%d adds succeeded, want %d", added, wantAdded)
+			}
+			count, err := repo.CountImages(ctx, lugarID)
+			if err != nil {
+				t.Fatalf("CountImages: %v", err)
+			}
+			if want := tt.existing + wantAdded*tt.batch; count != want {
+				t.Errorf("lugar has %d images, want %d", count, want)
+			}
+		})
+	}
+}
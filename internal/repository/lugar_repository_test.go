@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestPublishedCondition(t *testing.T) {
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		wantCondition string
+		wantArgs      []interface{}
+	}{
+		{
+			name:          "no viewer",
+			ctx:           context.Background(),
+			wantCondition: "l.published",
+		},
+		{
+			name:          "anonymous",
+			ctx:           WithViewer(context.Background(), Viewer{}),
+			wantCondition: "l.published",
+		},
+		{
+			name:          "user sees their own drafts",
+			ctx:           WithViewer(context.Background(), Viewer{UserID: 7}),
+			wantCondition: "(l.published OR l.user_id = $3)",
+			wantArgs:      []interface{}{7},
+		},
+		{
+			name:          "admin sees every draft",
+			ctx:           WithViewer(context.Background(), Viewer{UserID: 1, Admin: true}),
+			wantCondition: "TRUE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args := publishedCondition(tt.ctx, "l", "$3")
+			if condition != tt.wantCondition {
+				t.Errorf("condition = %q, want %q", condition, tt.wantCondition)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+// queryBuilder accumulates the dynamic parts of a list query: WHERE conditions
+// with positional parameters and an ORDER BY chosen from a whitelist. Values
+// are always passed as parameters, never concatenated into the SQL.
+type queryBuilder struct {
+	base       string
+	conditions []string
+	args       []interface{}
+	orderBy    string
+	page       Pagination
+}
+
+// newQueryBuilder creates a builder on top of a base SELECT ... FROM ... query
+func newQueryBuilder(base string) *queryBuilder {
+	return &queryBuilder{base: base}
+}
+
+// Where adds a condition joined with AND. Each "?" in the condition is
+// replaced by the positional parameter of the matching argument.
+func (b *queryBuilder) Where(condition string, args ...interface{}) *queryBuilder {
+	var sb strings.Builder
+	next := 0
+	for _, r := range condition {
+		if r == '?' && next < len(args) {
+			b.args = append(b.args, args[next])
+			sb.WriteString(fmt.Sprintf("$%d", len(b.args)))
+			next++
+			continue
+		}
+		sb.WriteRune(r)
+	}
+
+	b.conditions = append(b.conditions, sb.String())
+	return b
+}
+
+// OrderBy sets the ORDER BY clause for a sort key, which must be present in the whitelist
+func (b *queryBuilder) OrderBy(sort string, whitelist map[string]string) error {
+	clause, ok := whitelist[sort]
+	if !ok {
+		return fmt.Errorf("invalid sort value %q", sort)
+	}
+
+	b.orderBy = clause
+	return nil
+}
+
+// Paginate adds LIMIT and OFFSET clauses when the page has a limit
+func (b *queryBuilder) Paginate(page Pagination) *queryBuilder {
+	b.page = page
+	return b
+}
+
+// Build returns the final SQL and its arguments
+func (b *queryBuilder) Build() (string, []interface{}) {
+	var sb strings.Builder
+	sb.WriteString(b.base)
+
+	if len(b.conditions) > 0 {
+		sb.WriteString("\n\t\tWHERE ")
+		sb.WriteString(strings.Join(b.conditions, "\n\t\t  AND "))
+	}
+
+	if b.orderBy != "" {
+		sb.WriteString("\n\t\tORDER BY ")
+		sb.WriteString(b.orderBy)
+	}
+
+	args := b.args
+	if b.page.Limit > 0 {
+		args = append(args, b.page.Limit, b.page.Offset)
+		sb.WriteString(fmt.Sprintf("\n\t\tLIMIT $%d OFFSET $%d", len(args)-1, len(args)))
+	}
+
+	return sb.String(), args
+}
+
+// BuildCount returns SQL counting the rows matched by the conditions, ignoring
+// the ORDER BY and pagination, and its arguments
+func (b *queryBuilder) BuildCount() (string, []interface{}) {
+	var sb strings.Builder
+	sb.WriteString("SELECT COUNT(*) FROM (")
+	sb.WriteString(b.base)
+
+	if len(b.conditions) > 0 {
+		sb.WriteString("\n\t\tWHERE ")
+		sb.WriteString(strings.Join(b.conditions, "\n\t\t  AND "))
+	}
+
+	sb.WriteString("\n\t) AS counted")
+	return sb.String(), b.args
+}
+
+// countRows runs a query returning a single count
+func countRows(ctx context.Context, db *sql.DB, query string, args ...interface{}) (int, error) {
+	var count int
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting rows: %w", err)
+	}
+	return count, nil
+}
+
+// querySelectOptions runs a query selecting an id and a label and returns the rows as options
+func querySelectOptions(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]*models.SelectOption, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing options: %w", err)
+	}
+	defer rows.Close()
+
+	options := []*models.SelectOption{}
+	for rows.Next() {
+		option := &models.SelectOption{}
+		if err := rows.Scan(&option.ID, &option.Label); err != nil {
+			return nil, fmt.Errorf("error scanning option row: %w", err)
+		}
+		options = append(options, option)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating option rows: %w", err)
+	}
+
+	return options, nil
+}
+
+// escapeLike escapes the LIKE wildcards in a user supplied value
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// normalizeSQL collapses the whitespace of a query so tests can compare it on one line
+func normalizeSQL(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+func TestQueryBuilder(t *testing.T) {
+	sorts := map[string]string{"": "id", "name": "name, id"}
+
+	tests := []struct {
+		name      string
+		build     func(b *queryBuilder) error
+		wantSQL   string
+		wantArgs  []interface{}
+		wantCount string
+	}{
+		{
+			name:      "no filters",
+			build:     func(b *queryBuilder) error { return nil },
+			wantSQL:   "SELECT * FROM t",
+			wantCount: "SELECT COUNT(*) FROM (SELECT * FROM t ) AS counted",
+		},
+		{
+			name: "one condition",
+			build: func(b *queryBuilder) error {
+				b.Where("a = ?", 1)
+				return nil
+			},
+			wantSQL:   "SELECT * FROM t WHERE a = $1",
+			wantArgs:  []interface{}{1},
+			wantCount: "SELECT COUNT(*) FROM (SELECT * FROM t WHERE a = $1 ) AS counted",
+		},
+		{
+			name: "conditions, sort and page",
+			build: func(b *queryBuilder) error {
+				b.Where("deleted_at IS NULL").
+					Where("a BETWEEN ? AND ?", 1, 5).
+					Where("name ILIKE ?", "%x%").
+					Paginate(Pagination{Limit: 10, Offset: 20})
+				return b.OrderBy("name", sorts)
+			},
+			wantSQL:   "SELECT * FROM t WHERE deleted_at IS NULL AND a BETWEEN $1 AND $2 AND name ILIKE $3 ORDER BY name, id LIMIT $4 OFFSET $5",
+			wantArgs:  []interface{}{1, 5, "%x%", 10, 20},
+			wantCount: "SELECT COUNT(*) FROM (SELECT * FROM t WHERE deleted_at IS NULL AND a BETWEEN $1 AND $2 AND name ILIKE $3 ) AS counted",
+		},
+		{
+			name: "zero limit returns every row",
+			build: func(b *queryBuilder) error {
+				b.Paginate(Pagination{Offset: 5})
+				return b.OrderBy("", sorts)
+			},
+			wantSQL:   "SELECT * FROM t ORDER BY id",
+			wantCount: "SELECT COUNT(*) FROM (SELECT * FROM t ) AS counted",
+		},
+		{
+			name: "question marks without arguments are kept",
+			build: func(b *queryBuilder) error {
+				b.Where("a = ? AND b ? 'key'", 3)
+				return nil
+			},
+			wantSQL:   "SELECT * FROM t WHERE a = $1 AND b ? 'key'",
+			wantArgs:  []interface{}{3},
+			wantCount: "SELECT COUNT(*) FROM (SELECT * FROM t WHERE a = $1 AND b ? 'key' ) AS counted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newQueryBuilder("SELECT * FROM t")
+			if err := tt.build(b); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			query, args := b.Build()
+			if got := normalizeSQL(query); got != tt.wantSQL {
+				t.Errorf("SQL = %q, want %q", got, tt.wantSQL)
+			}
+			if len(args) != 0 || len(tt.wantArgs) != 0 {
+				if !reflect.DeepEqual(args, tt.wantArgs) {
+					t.Errorf("args = %v, want %v", args, tt.wantArgs)
+				}
+			}
+
+			countQuery, countArgs := b.BuildCount()
+			if got := normalizeSQL(countQuery); got != tt.wantCount {
+				t.Errorf("count SQL = %q, want %q", got, tt.wantCount)
+			}
+			// The count has the same arguments, without LIMIT and OFFSET
+			wantCountArgs := len(tt.wantArgs)
+			if b.page.Limit > 0 {
+				wantCountArgs -= 2
+			}
+			if len(countArgs) != wantCountArgs {
+				t.Errorf("count args = %v, want %d args", countArgs, wantCountArgs)
+			}
+		})
+	}
+}
+
+func TestQueryBuilderRejectsUnknownSort(t *testing.T) {
+	b := newQueryBuilder("SELECT * FROM t")
+	if err := b.OrderBy("name; DROP TABLE t", map[string]string{"": "id"}); err == nil {
+		t.Fatal("expected an error for a sort outside the whitelist")
+	}
+	if query, _ := b.Build(); strings.Contains(query, "ORDER BY") {
+		t.Errorf("rejected sort still added an ORDER BY: %q", query)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"rua", "rua"},
+		{"100%", `100\%`},
+		{"a_b", `a\_b`},
+		{`c:\dir`, `c:\\dir`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeLike(tt.value); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+// PostgresRamoRepository is an implementation of RamoRepository using PostgreSQL
+type PostgresRamoRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRamoRepository creates a new PostgresRamoRepository
+func NewPostgresRamoRepository(db *sql.DB) *PostgresRamoRepository {
+	return &PostgresRamoRepository{db: db}
+}
+
+// GetByID retrieves a ramo by ID
+func (r *PostgresRamoRepository) GetByID(ctx context.Context, id int) (*models.Ramo, error) {
+	query := `
+		SELECT id, name, created_at
+		FROM ramos
+		WHERE id = $1
+	`
+
+	var ramo models.Ramo
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&ramo.ID,
+		&ramo.Name,
+		&ramo.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Return nil without error to indicate not found
+		}
+		return nil, fmt.Errorf("error getting ramo by ID: %w", err)
+	}
+
+	return &ramo, nil
+}
+
+// ramoSortOrders maps the accepted sort values of ramos to their ORDER BY clauses
+var ramoSortOrders = map[string]string{
+	"": "name, id",
+}
+
+// List retrieves a page of the ramos, by name
+func (r *PostgresRamoRepository) List(ctx context.Context, page Pagination) ([]*models.Ramo, error) {
+	builder := newQueryBuilder(`
+		SELECT id, name, created_at
+		FROM ramos`)
+	if err := builder.OrderBy("", ramoSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing ramos: %w", err)
+	}
+	defer rows.Close()
+
+	ramos := []*models.Ramo{}
+	for rows.Next() {
+		ramo := &models.Ramo{}
+		if err := rows.Scan(
+			&ramo.ID,
+			&ramo.Name,
+			&ramo.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning ramo row: %w", err)
+		}
+		ramos = append(ramos, ramo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ramo rows: %w", err)
+	}
+
+	return ramos, nil
+}
+
+// Count counts the ramos
+func (r *PostgresRamoRepository) Count(ctx context.Context) (int, error) {
+	return countRows(ctx, r.db, `SELECT COUNT(*) FROM ramos`)
+}
+
+// ListSelectOptions lists the ID and name of a page of the ramos, by name
+func (r *PostgresRamoRepository) ListSelectOptions(ctx context.Context, page Pagination) ([]*models.SelectOption, error) {
+	builder := newQueryBuilder(`
+		SELECT id, name
+		FROM ramos`)
+	if err := builder.OrderBy("", ramoSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
+
+	return querySelectOptions(ctx, r.db, query, args...)
+}
+
+// Create creates a new ramo, returning ErrAlreadyExists when the name is taken
+func (r *PostgresRamoRepository) Create(ctx context.Context, ramo *models.Ramo) (int, error) {
+	query := `
+		INSERT INTO ramos (name, created_at)
+		VALUES ($1, $2)
+		RETURNING id
+	`
+
+	var id int
+	err := r.db.QueryRowContext(ctx, query,
+		ramo.Name,
+		ramo.CreatedAt,
+	).Scan(&id)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("ramo %q: %w", ramo.Name, ErrAlreadyExists)
+		}
+		return 0, fmt.Errorf("error creating ramo: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetOrCreate creates a new ramo or, when one with the same name exists,
+// returns the existing one. The boolean reports whether it was created.
+func (r *PostgresRamoRepository) GetOrCreate(ctx context.Context, ramo *models.Ramo) (*models.Ramo, bool, error) {
+	query := `
+		INSERT INTO ramos (name, created_at)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		ramo.Name,
+		ramo.CreatedAt,
+	).Scan(&ramo.ID, &ramo.CreatedAt)
+
+	if err == nil {
+		return ramo, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("error creating ramo: %w", err)
+	}
+
+	// The name already exists, fetch the existing ramo
+	query = `
+		SELECT id, name, created_at
+		FROM ramos
+		WHERE name = $1
+	`
+
+	var existing models.Ramo
+	if err := r.db.QueryRowContext(ctx, query, ramo.Name).Scan(
+		&existing.ID,
+		&existing.Name,
+		&existing.CreatedAt,
+	); err != nil {
+		return nil, false, fmt.Errorf("error getting existing ramo: %w", err)
+	}
+
+	return &existing, false, nil
+}
+
+// Coverage counts the lugares and cancoes of each ramo, flagging the ramos
+// with fewer than threshold of either, least covered first
+func (r *PostgresRamoRepository) Coverage(ctx context.Context, threshold int) ([]*models.RamoCoverage, error) {
+	query := `
+		SELECT r.id, r.name, r.created_at, c.lugar_count, c.cancao_count,
+		       c.lugar_count < $1 OR c.cancao_count < $1 AS under_covered
+		FROM ramos r
+		CROSS JOIN LATERAL (
+			SELECT (SELECT COUNT(*)
+			        FROM lugares_ramos lr
+			        JOIN lugares l ON l.id = lr.lugar_id AND l.deleted_at IS NULL
+			        WHERE lr.ramo_id = r.id) AS lugar_count,
+			       (SELECT COUNT(*)
+			        FROM cancoes_ramos cr
+			        JOIN cancoes ca ON ca.id = cr.cancao_id AND ca.deleted_at IS NULL
+			        WHERE cr.ramo_id = r.id) AS cancao_count
+		) c
+		ORDER BY LEAST(c.lugar_count, c.cancao_count), r.name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, threshold)
+	if err != nil {
+		return nil, fmt.Errorf("error getting ramo coverage: %w", err)
+	}
+	defer rows.Close()
+
+	coverage := []*models.RamoCoverage{}
+	for rows.Next() {
+		var ramo models.RamoCoverage
+		if err := rows.Scan(
+			&ramo.ID,
+			&ramo.Name,
+			&ramo.CreatedAt,
+			&ramo.LugarCount,
+			&ramo.CancaoCount,
+			&ramo.UnderCovered,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning ramo coverage row: %w", err)
+		}
+		coverage = append(coverage, &ramo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ramo coverage rows: %w", err)
+	}
+
+	return coverage, nil
+}
+
+// Update updates an existing ramo, returning ErrAlreadyExists when the name is taken
+func (r *PostgresRamoRepository) Update(ctx context.Context, ramo *models.Ramo) error {
+	query := `
+		UPDATE ramos
+		SET name = $1
+		WHERE id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, ramo.Name, ramo.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("ramo %q: %w", ramo.Name, ErrAlreadyExists)
+		}
+		return fmt.Errorf("error updating ramo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("ramo with ID %d not found", ramo.ID)
+	}
+
+	return nil
+}
+
+// Delete deletes a ramo by ID
+func (r *PostgresRamoRepository) Delete(ctx context.Context, id int) error {
+	query := `
+		DELETE FROM ramos
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error deleting ramo: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("ramo with ID %d not found", id)
+	}
+
+	return nil
+}
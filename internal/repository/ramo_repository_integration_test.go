@@ -0,0 +1,124 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+func TestRamoGetOrCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		ramo        string
+		wantCreated bool
+		wantID      int
+	}{
+		// The seed ramos are 1 filhotes, 2 lobinho, 3 escoteiro, 4 senior and 5 cla
+		{name: "new ramo is created", ramo: "pioneiro", wantCreated: true},
+		{name: "existing ramo is returned", ramo: "lobinho", wantID: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresRamoRepository(db)
+			ctx := context.Background()
+
+			ramo, created, err := repo.GetOrCreate(ctx, &models.Ramo{Name: tt.ramo, CreatedAt: time.Now().UTC()})
+			if err != nil {
+				t.Fatalf("GetOrCreate: %v", err)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("created = %v, want %v", created, tt.wantCreated)
+			}
+			if ramo.Name != tt.ramo || (tt.wantID != 0 && ramo.ID != tt.wantID) {
+				t.Errorf("got ramo %+v", ramo)
+			}
+
+			// Asking again returns the same ramo without creating another
+			again, created, err := repo.GetOrCreate(ctx, &models.Ramo{Name: tt.ramo, CreatedAt: time.Now().UTC()})
+			if err != nil || created || again.ID != ramo.ID {
+				t.Errorf("second GetOrCreate = %+v, %v, %v; want ramo %d, false, nil", again, created, err, ramo.ID)
+			}
+		})
+	}
+}
+
+func TestRamoCoverage(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresRamoRepository(db)
+
+	// Seed ramos: 1 filhotes, 2 lobinho, 3 escoteiro, 4 senior, 5 clã
+	var lugares, cancoes []int
+	for i := 0; i < 3; i++ {
+		lugares = append(lugares, insertTestLugar(t, db, fmt.Sprintf("Lugar %d", i)))
+		cancoes = append(cancoes, insertTestCancao(t, db, fmt.Sprintf("Canção %d", i)))
+	}
+	// lobinho: 3 lugares and 3 cancoes; escoteiro: 3 lugares, 1 cancao;
+	// senior: 2 lugares and 2 cancoes, plus a deleted lugar that does not count
+	for _, id := range lugares {
+		mustExec(t, db, `INSERT INTO lugares_ramos (lugar_id, ramo_id) VALUES ($1, 2), ($1, 3)`, id)
+	}
+	for _, id := range cancoes {
+		mustExec(t, db, `INSERT INTO cancoes_ramos (cancao_id, ramo_id) VALUES ($1, 2)`, id)
+	}
+	mustExec(t, db, `INSERT INTO cancoes_ramos (cancao_id, ramo_id) VALUES ($1, 3)`, cancoes[0])
+	mustExec(t, db, `INSERT INTO lugares_ramos (lugar_id, ramo_id) VALUES ($1, 4), ($2, 4), ($3, 4)`, lugares[0], lugares[1], lugares[2])
+	mustExec(t, db, `INSERT INTO cancoes_ramos (cancao_id, ramo_id) VALUES ($1, 4), ($2, 4)`, cancoes[0], cancoes[1])
+	mustExec(t, db, `UPDATE lugares SET deleted_at = NOW() WHERE id = $1`, lugares[2])
+
+	tests := []struct {
+		name          string
+		threshold     int
+		wantUnder     map[string]bool
+		wantLugares   map[string]int
+		wantFirstRamo string
+	}{
+		{
+			name:          "threshold of 2",
+			threshold:     2,
+			wantUnder:     map[string]bool{"filhotes": true, "lobinho": false, "escoteiro": true, "senior": false, "clã": true},
+			wantLugares:   map[string]int{"filhotes": 0, "lobinho": 2, "escoteiro": 2, "senior": 2, "clã": 0},
+			wantFirstRamo: "clã",
+		},
+		{
+			name:      "threshold of 0 flags nothing",
+			threshold: 0,
+			wantUnder: map[string]bool{"filhotes": false, "lobinho": false, "escoteiro": false, "senior": false, "clã": false},
+		},
+		{
+			name:      "threshold of 3 flags every ramo",
+			threshold: 3,
+			wantUnder: map[string]bool{"filhotes": true, "lobinho": true, "escoteiro": true, "senior": true, "clã": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coverage, err := repo.Coverage(context.Background(), tt.threshold)
+			if err != nil {
+				t.Fatalf("Coverage: %v", err)
+			}
+			if len(coverage) != len(tt.wantUnder) {
+				t.Fatalf("got %d ramos, want %d", len(coverage), len(tt.wantUnder))
+			}
+			for _, ramo := range coverage {
+				if ramo.UnderCovered != tt.wantUnder[ramo.Name] {
+					t.Errorf("%s: under_covered = %v, want %v (lugares %d, cancoes %d)", ramo.Name, ramo.UnderCovered, tt.wantUnder[ramo.Name], ramo.LugarCount, ramo.CancaoCount)
+				}
+				if want, ok := tt.wantLugares[ramo.Name]; ok && ramo.LugarCount != want {
+					t.Errorf("%s: lugar_count = %d, want %d", ramo.Name, ramo.LugarCount, want)
+				}
+			}
+			// The least covered ramos come first, by name
+			if tt.wantFirstRamo != "" && coverage[0].Name != tt.wantFirstRamo {
+				t.Errorf("first ramo = %s, want %s", coverage[0].Name, tt.wantFirstRamo)
+			}
+		})
+	}
+}
@@ -0,0 +1,501 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+// PostgresTagLugarRepository is an implementation of TagLugarRepository using PostgreSQL
+type PostgresTagLugarRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresTagLugarRepository creates a new PostgresTagLugarRepository
+func NewPostgresTagLugarRepository(db *sql.DB) *PostgresTagLugarRepository {
+	return &PostgresTagLugarRepository{db: db}
+}
+
+// GetByID retrieves a place tag by ID
+func (r *PostgresTagLugarRepository) GetByID(ctx context.Context, id int) (*models.TagLugar, error) {
+	query := `
+		SELECT id, name, created_at
+		FROM tags_lugares
+		WHERE id = $1
+	`
+
+	var tag models.TagLugar
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&tag.ID,
+		&tag.Name,
+		&tag.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Return nil without error to indicate not found
+		}
+		return nil, fmt.Errorf("error getting tag by ID: %w", err)
+	}
+
+	return &tag, nil
+}
+
+// tagSortOrders maps the accepted sort values of tags to their ORDER BY clauses
+var tagSortOrders = map[string]string{
+	"": "name, id",
+}
+
+// List retrieves a page of the place tags, by name
+func (r *PostgresTagLugarRepository) List(ctx context.Context, page Pagination) ([]*models.TagLugar, error) {
+	builder := newQueryBuilder(`
+		SELECT id, name, created_at
+		FROM tags_lugares`)
+	if err := builder.OrderBy("", tagSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []*models.TagLugar{}
+	for rows.Next() {
+		tag := &models.TagLugar{}
+		if err := rows.Scan(
+			&tag.ID,
+			&tag.Name,
+			&tag.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning tag row: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag rows: %w", err)
+	}
+
+	return tags, nil
+}
+
+// Count counts the place tags
+func (r *PostgresTagLugarRepository) Count(ctx context.Context) (int, error) {
+	return countRows(ctx, r.db, `SELECT COUNT(*) FROM tags_lugares`)
+}
+
+// ListUnassigned retrieves a page of the place tags a place does not have
+// yet, by name. It returns nil when the place does not exist.
+func (r *PostgresTagLugarRepository) ListUnassigned(ctx context.Context, lugarID int, page Pagination) ([]*models.TagLugar, error) {
+	var exists bool
+	existsQuery := `SELECT EXISTS (SELECT 1 FROM lugares WHERE id = $1 AND deleted_at IS NULL)`
+	if err := r.db.QueryRowContext(ctx, existsQuery, lugarID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("error checking lugar: %w", err)
+	}
+	if !exists {
+		return nil, nil // Return nil without error to indicate the lugar was not found
+	}
+
+	builder := newQueryBuilder(`
+		SELECT id, name, created_at
+		FROM tags_lugares t`).
+		Where(`NOT EXISTS (
+			SELECT 1
+			FROM lugares_tags lt
+			WHERE lt.lugar_id = ? AND lt.tag_id = t.id
+		)`, lugarID)
+	if err := builder.OrderBy("", tagSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing unassigned tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []*models.TagLugar{}
+	for rows.Next() {
+		tag := &models.TagLugar{}
+		if err := rows.Scan(
+			&tag.ID,
+			&tag.Name,
+			&tag.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning tag row: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag rows: %w", err)
+	}
+
+	return tags, nil
+}
+
+// Suggest retrieves the place tags whose name contains the query, names
+// starting with it first
+func (r *PostgresTagLugarRepository) Suggest(ctx context.Context, query string, limit int) ([]*models.TagLugar, error) {
+	sqlQuery := `
+		SELECT id, name, created_at
+		FROM tags_lugares
+		WHERE name ILIKE '%' || $1 || '%'
+		ORDER BY (name ILIKE $1 || '%') DESC, name
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, escapeLike(query), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error suggesting tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []*models.TagLugar{}
+	for rows.Next() {
+		tag := &models.TagLugar{}
+		if err := rows.Scan(
+			&tag.ID,
+			&tag.Name,
+			&tag.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning tag row: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag rows: %w", err)
+	}
+
+	return tags, nil
+}
+
+// ListSelectOptions lists the ID and name of a page of the place tags, by name
+func (r *PostgresTagLugarRepository) ListSelectOptions(ctx context.Context, page Pagination) ([]*models.SelectOption, error) {
+	builder := newQueryBuilder(`
+		SELECT id, name
+		FROM tags_lugares`)
+	if err := builder.OrderBy("", tagSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
+
+	return querySelectOptions(ctx, r.db, query, args...)
+}
+
+// Create creates a new place tag, returning ErrAlreadyExists when the name is taken
+func (r *PostgresTagLugarRepository) Create(ctx context.Context, tag *models.TagLugar) (int, error) {
+	query := `
+		INSERT INTO tags_lugares (name, created_at)
+		VALUES ($1, $2)
+		RETURNING id
+	`
+
+	var id int
+	err := r.db.QueryRowContext(ctx, query,
+		tag.Name,
+		tag.CreatedAt,
+	).Scan(&id)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("tag %q: %w", tag.Name, ErrAlreadyExists)
+		}
+		return 0, fmt.Errorf("error creating tag: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetOrCreate creates a new place tag or, when one with the same name exists,
+// returns the existing one. The boolean reports whether it was created.
+func (r *PostgresTagLugarRepository) GetOrCreate(ctx context.Context, tag *models.TagLugar) (*models.TagLugar, bool, error) {
+	query := `
+		INSERT INTO tags_lugares (name, created_at)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		tag.Name,
+		tag.CreatedAt,
+	).Scan(&tag.ID, &tag.CreatedAt)
+
+	if err == nil {
+		return tag, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("error creating tag: %w", err)
+	}
+
+	// The name already exists, fetch the existing tag
+	query = `
+		SELECT id, name, created_at
+		FROM tags_lugares
+		WHERE name = $1
+	`
+
+	var existing models.TagLugar
+	if err := r.db.QueryRowContext(ctx, query, tag.Name).Scan(
+		&existing.ID,
+		&existing.Name,
+		&existing.CreatedAt,
+	); err != nil {
+		return nil, false, fmt.Errorf("error getting existing tag: %w", err)
+	}
+
+	return &existing, false, nil
+}
+
+// Update updates an existing place tag, returning ErrAlreadyExists when the name is taken
+func (r *PostgresTagLugarRepository) Update(ctx context.Context, tag *models.TagLugar) error {
+	query := `
+		UPDATE tags_lugares
+		SET name = $1
+		WHERE id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, tag.Name, tag.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("tag %q: %w", tag.Name, ErrAlreadyExists)
+		}
+		return fmt.Errorf("error updating tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag with ID %d not found", tag.ID)
+	}
+
+	return nil
+}
+
+// Delete deletes a place tag by ID
+func (r *PostgresTagLugarRepository) Delete(ctx context.Context, id int) error {
+	query := `
+		DELETE FROM tags_lugares
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error deleting tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag with ID %d not found", id)
+	}
+
+	return nil
+}
+
+// PostgresTagCancaoRepository is an implementation of TagCancaoRepository using PostgreSQL
+type PostgresTagCancaoRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresTagCancaoRepository creates a new PostgresTagCancaoRepository
+func NewPostgresTagCancaoRepository(db *sql.DB) *PostgresTagCancaoRepository {
+	return &PostgresTagCancaoRepository{db: db}
+}
+
+// GetByID retrieves a song tag by ID
+func (r *PostgresTagCancaoRepository) GetByID(ctx context.Context, id int) (*models.TagCancao, error) {
+	query := `
+		SELECT id, name, created_at
+		FROM tags_cancoes
+		WHERE id = $1
+	`
+
+	var tag models.TagCancao
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&tag.ID,
+		&tag.Name,
+		&tag.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Return nil without error to indicate not found
+		}
+		return nil, fmt.Errorf("error getting tag by ID: %w", err)
+	}
+
+	return &tag, nil
+}
+
+// List retrieves a page of the song tags, by name
+func (r *PostgresTagCancaoRepository) List(ctx context.Context, page Pagination) ([]*models.TagCancao, error) {
+	builder := newQueryBuilder(`
+		SELECT id, name, created_at
+		FROM tags_cancoes`)
+	if err := builder.OrderBy("", tagSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []*models.TagCancao{}
+	for rows.Next() {
+		tag := &models.TagCancao{}
+		if err := rows.Scan(
+			&tag.ID,
+			&tag.Name,
+			&tag.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning tag row: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag rows: %w", err)
+	}
+
+	return tags, nil
+}
+
+// Count counts the song tags
+func (r *PostgresTagCancaoRepository) Count(ctx context.Context) (int, error) {
+	return countRows(ctx, r.db, `SELECT COUNT(*) FROM tags_cancoes`)
+}
+
+// Create creates a new song tag, returning ErrAlreadyExists when the name is taken
+func (r *PostgresTagCancaoRepository) Create(ctx context.Context, tag *models.TagCancao) (int, error) {
+	query := `
+		INSERT INTO tags_cancoes (name, created_at)
+		VALUES ($1, $2)
+		RETURNING id
+	`
+
+	var id int
+	err := r.db.QueryRowContext(ctx, query,
+		tag.Name,
+		tag.CreatedAt,
+	).Scan(&id)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("tag %q: %w", tag.Name, ErrAlreadyExists)
+		}
+		return 0, fmt.Errorf("error creating tag: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetOrCreate creates a new song tag or, when one with the same name exists,
+// returns the existing one. The boolean reports whether it was created.
+func (r *PostgresTagCancaoRepository) GetOrCreate(ctx context.Context, tag *models.TagCancao) (*models.TagCancao, bool, error) {
+	query := `
+		INSERT INTO tags_cancoes (name, created_at)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		tag.Name,
+		tag.CreatedAt,
+	).Scan(&tag.ID, &tag.CreatedAt)
+
+	if err == nil {
+		return tag, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("error creating tag: %w", err)
+	}
+
+	// The name already exists, fetch the existing tag
+	query = `
+		SELECT id, name, created_at
+		FROM tags_cancoes
+		WHERE name = $1
+	`
+
+	var existing models.TagCancao
+	if err := r.db.QueryRowContext(ctx, query, tag.Name).Scan(
+		&existing.ID,
+		&existing.Name,
+		&existing.CreatedAt,
+	); err != nil {
+		return nil, false, fmt.Errorf("error getting existing tag: %w", err)
+	}
+
+	return &existing, false, nil
+}
+
+// Update updates an existing song tag, returning ErrAlreadyExists when the name is taken
+func (r *PostgresTagCancaoRepository) Update(ctx context.Context, tag *models.TagCancao) error {
+	query := `
+		UPDATE tags_cancoes
+		SET name = $1
+		WHERE id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, tag.Name, tag.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("tag %q: %w", tag.Name, ErrAlreadyExists)
+		}
+		return fmt.Errorf("error updating tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag with ID %d not found", tag.ID)
+	}
+
+	return nil
+}
+
+// Delete deletes a song tag by ID
+func (r *PostgresTagCancaoRepository) Delete(ctx context.Context, id int) error {
+	query := `
+		DELETE FROM tags_cancoes
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error deleting tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag with ID %d not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+func TestTagGetOrCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		getOrCreate func(db *sql.DB, name string) (int, bool, error)
+		tag         string
+		wantCreated bool
+	}{
+		{name: "new lugar tag", getOrCreate: getOrCreateLugarTag, tag: "trilha", wantCreated: true},
+		{name: "existing lugar tag", getOrCreate: getOrCreateLugarTag, tag: "rio"},
+		{name: "new cancao tag", getOrCreate: getOrCreateCancaoTag, tag: "fogo de conselho", wantCreated: true},
+		{name: "existing cancao tag", getOrCreate: getOrCreateCancaoTag, tag: "hino"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+
+			id, created, err := tt.getOrCreate(db, tt.tag)
+			if err != nil {
+				t.Fatalf("GetOrCreate: %v", err)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("created = %v, want %v", created, tt.wantCreated)
+			}
+
+			// Asking again returns the same tag without creating another
+			againID, created, err := tt.getOrCreate(db, tt.tag)
+			if err != nil || created || againID != id {
+				t.Errorf("second GetOrCreate = %d, %v, %v; want %d, false, nil", againID, created, err, id)
+			}
+		})
+	}
+}
+
+// getOrCreateLugarTag calls GetOrCreate on the place tags and returns the tag ID
+func getOrCreateLugarTag(db *sql.DB, name string) (int, bool, error) {
+	tag, created, err := NewPostgresTagLugarRepository(db).GetOrCreate(context.Background(), &models.TagLugar{Name: name, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return 0, false, err
+	}
+	return tag.ID, created, nil
+}
+
+// getOrCreateCancaoTag calls GetOrCreate on the song tags and returns the tag ID
+func getOrCreateCancaoTag(db *sql.DB, name string) (int, bool, error) {
+	tag, created, err := NewPostgresTagCancaoRepository(db).GetOrCreate(context.Background(), &models.TagCancao{Name: name, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return 0, false, err
+	}
+	return tag.ID, created, nil
+}
+
+func TestSuggestLugarTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  []string
+	}{
+		{name: "prefix matches rank above substring matches", query: "pa", limit: 5, want: []string{"pantano", "parque", "campa", "trampa"}},
+		{name: "matching ignores case", query: "PA", limit: 5, want: []string{"pantano", "parque", "campa", "trampa"}},
+		{name: "limit keeps the best ranked", query: "pa", limit: 2, want: []string{"pantano", "parque"}},
+		{name: "like wildcards are literal", query: "_", limit: 5, want: []string{"mata_fechada", "permite_fogueira"}},
+		{name: "no match", query: "xyz", limit: 5, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			mustExec(t, db, `DELETE FROM tags_lugares WHERE name NOT IN ('mata_fechada', 'permite_fogueira', 'rio')`)
+			mustExec(t, db, `INSERT INTO tags_lugares (name) VALUES ('trampa'), ('parque'), ('campa'), ('pantano')`)
+
+			tags, err := NewPostgresTagLugarRepository(db).Suggest(context.Background(), tt.query, tt.limit)
+			if err != nil {
+				t.Fatalf("Suggest: %v", err)
+			}
+			var got []string
+			for _, tag := range tags {
+				got = append(got, tag.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Suggest(%q, %d) = %v, want %v", tt.query, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListUnassignedLugarTags(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresTagLugarRepository(db)
+	ctx := context.Background()
+
+	var allTags []string
+	rows, err := db.Query(`SELECT name FROM tags_lugares ORDER BY name`)
+	if err != nil {
+		t.Fatalf("listing tags: %v", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scanning tag: %v", err)
+		}
+		allTags = append(allTags, name)
+	}
+	rows.Close()
+
+	untagged := insertTestLugar(t, db, "Sem tags")
+	tagged := insertTestLugar(t, db, "Com tags")
+	mustExec(t, db, `INSERT INTO lugares_tags (lugar_id, tag_id) SELECT $1, id FROM tags_lugares WHERE name IN ('rio', 'lago', 'trilha')`, tagged)
+	// The tags of other lugares do not count
+	other := insertTestLugar(t, db, "Outro")
+	mustExec(t, db, `INSERT INTO lugares_tags (lugar_id, tag_id) SELECT $1, id FROM tags_lugares WHERE name = 'bosque'`, other)
+	complete := insertTestLugar(t, db, "Todas as tags")
+	mustExec(t, db, `INSERT INTO lugares_tags (lugar_id, tag_id) SELECT $1, id FROM tags_lugares`, complete)
+	deleted := insertTestLugar(t, db, "Apagado")
+	mustExec(t, db, `UPDATE lugares SET deleted_at = NOW() WHERE id = $1`, deleted)
+
+	without := func(names ...string) []string {
+		skip := map[string]bool{}
+		for _, name := range names {
+			skip[name] = true
+		}
+		rest := []string{}
+		for _, name := range allTags {
+			if !skip[name] {
+				rest = append(rest, name)
+			}
+		}
+		return rest
+	}
+
+	tests := []struct {
+		name    string
+		lugarID int
+		want    []string
+		wantNil bool
+	}{
+		{name: "no tags assigned", lugarID: untagged, want: allTags},
+		{name: "some tags assigned", lugarID: tagged, want: without("rio", "lago", "trilha")},
+		{name: "every tag assigned", lugarID: complete, want: []string{}},
+		{name: "missing lugar", lugarID: 9999, wantNil: true},
+		{name: "deleted lugar", lugarID: deleted, wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, err := repo.ListUnassigned(ctx, tt.lugarID, Pagination{Limit: 10})
+			if err != nil {
+				t.Fatalf("ListUnassigned: %v", err)
+			}
+			if tt.wantNil {
+				if tags != nil {
+					t.Errorf("tags = %v, want nil for a missing lugar", tags)
+				}
+				return
+			}
+			got := []string{}
+			for _, tag := range tags {
+				got = append(got, tag.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
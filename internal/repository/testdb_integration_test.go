@@ -0,0 +1,95 @@
+//go:build integration
+
+package repository
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+)
+
+// newTestDB connects to the PostgreSQL database in TEST_DATABASE_URL and
+// recreates its schema from scripts/init-db.sql, so each test starts from the
+// seed data: users 1 (admin, write) and 2 (user, read), the default ramos and
+// the default tags. The test is skipped when TEST_DATABASE_URL is not set.
+//
+// The database is wiped: never point TEST_DATABASE_URL at a real one.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	script, err := os.ReadFile("../../scripts/init-db.sql")
+	if err != nil {
+		t.Fatalf("reading init-db.sql: %v", err)
+	}
+	if _, err := db.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public"); err != nil {
+		t.Fatalf("resetting test database: %v", err)
+	}
+	if _, err := db.Exec(string(script)); err != nil {
+		t.Fatalf("running init-db.sql: %v", err)
+	}
+
+	return db
+}
+
+// mustExec runs a statement used to set up a test
+func mustExec(t *testing.T, db *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("exec %q: %v", query, err)
+	}
+}
+
+// insertTestLugar inserts a published place owned by the admin user and returns its ID
+func insertTestLugar(t *testing.T, db *sql.DB, nome string) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`INSERT INTO lugares (nome_local, user_id, published) VALUES ($1, 1, true) RETURNING id`, nome).Scan(&id)
+	if err != nil {
+		t.Fatalf("inserting lugar %q: %v", nome, err)
+	}
+	return id
+}
+
+// insertTestCancao inserts a song owned by the admin user and returns its ID
+func insertTestCancao(t *testing.T, db *sql.DB, nome string) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`INSERT INTO cancoes (nome, user_id) VALUES ($1, 1) RETURNING id`, nome).Scan(&id)
+	if err != nil {
+		t.Fatalf("inserting cancao %q: %v", nome, err)
+	}
+	return id
+}
+
+// insertTestUser inserts a read user and returns its ID
+func insertTestUser(t *testing.T, db *sql.DB, username string) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`INSERT INTO users (username, password, role) VALUES ($1, 'hash', 'read') RETURNING id`, username).Scan(&id)
+	if err != nil {
+		t.Fatalf("inserting user %q: %v", username, err)
+	}
+	return id
+}
+
+// insertTestImage inserts an image of a place at the given display order and returns its ID
+func insertTestImage(t *testing.T, db *sql.DB, lugarID, displayOrder int) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`INSERT INTO lugares_images (lugar_id, image_url, display_order) VALUES ($1, 'https://example.com/image.jpg', $2) RETURNING id`, lugarID, displayOrder).Scan(&id)
+	if err != nil {
+		t.Fatalf("inserting image of lugar %d: %v", lugarID, err)
+	}
+	return id
+}
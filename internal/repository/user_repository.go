@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/site-geav-api/internal/models"
 )
@@ -46,7 +47,46 @@ func (r *PostgresUserRepository) GetByID(ctx context.Context, id int) (*models.U
 	return &user, nil
 }
 
-// GetByUsername retrieves a user by username
+// GetWithCounts retrieves a user along with the number of places, songs and
+// ratings they created. Deleted places and songs, and ratings of deleted
+// places, are not counted. It returns nil if the user does not exist.
+func (r *PostgresUserRepository) GetWithCounts(ctx context.Context, id int) (*models.UserWithCounts, error) {
+	query := `
+		SELECT u.id, u.username, u.password, u.role, u.created_at, u.updated_at,
+		       (SELECT COUNT(*) FROM lugares l WHERE l.user_id = u.id AND l.deleted_at IS NULL) AS lugar_count,
+		       (SELECT COUNT(*) FROM cancoes c WHERE c.user_id = u.id AND c.deleted_at IS NULL) AS cancao_count,
+		       (SELECT COUNT(*)
+		        FROM lugares_ratings lr
+		        JOIN lugares l ON l.id = lr.lugar_id
+		        WHERE lr.user_id = u.id AND l.deleted_at IS NULL) AS rating_count
+		FROM users u
+		WHERE u.id = $1
+	`
+
+	var user models.UserWithCounts
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Password,
+		&user.Role,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.LugarCount,
+		&user.CancaoCount,
+		&user.RatingCount,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting user with counts: %w", err)
+	}
+
+	return &user, nil
+}
+
+// GetByUsername retrieves a user by username, or nil when there is no such user
 func (r *PostgresUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	query := `
 		SELECT id, username, password, role, created_at, updated_at
@@ -66,7 +106,7 @@ func (r *PostgresUserRepository) GetByUsername(ctx context.Context, username str
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user with username %s not found", username)
+			return nil, nil // Return nil without error to indicate not found
 		}
 		return nil, fmt.Errorf("error getting user by username: %w", err)
 	}
@@ -74,21 +114,28 @@ func (r *PostgresUserRepository) GetByUsername(ctx context.Context, username str
 	return &user, nil
 }
 
+// userSortOrders maps the accepted sort values to their ORDER BY clauses
+var userSortOrders = map[string]string{
+	"": "id",
+}
+
 // List retrieves all users
-func (r *PostgresUserRepository) List(ctx context.Context) ([]*models.User, error) {
-	query := `
+func (r *PostgresUserRepository) List(ctx context.Context, page Pagination) ([]*models.User, error) {
+	builder := newQueryBuilder(`
 		SELECT id, username, password, role, created_at, updated_at
-		FROM users
-		ORDER BY id
-	`
+		FROM users`)
+	if err := builder.OrderBy("", userSortOrders); err != nil {
+		return nil, err
+	}
+	query, args := builder.Paginate(page).Build()
 	
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error listing users: %w", err)
 	}
 	defer rows.Close()
 	
-	var users []*models.User
+	users := []*models.User{}
 	for rows.Next() {
 		var user models.User
 		if err := rows.Scan(
@@ -111,6 +158,60 @@ func (r *PostgresUserRepository) List(ctx context.Context) ([]*models.User, erro
 	return users, nil
 }
 
+// Count counts all users
+func (r *PostgresUserRepository) Count(ctx context.Context) (int, error) {
+	return countRows(ctx, r.db, "SELECT COUNT(*) FROM users")
+}
+
+// CountCreatedBetween counts the users created in [from, to)
+func (r *PostgresUserRepository) CountCreatedBetween(ctx context.Context, from, to time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM users
+		WHERE created_at >= $1 AND created_at < $2
+	`
+	return countRows(ctx, r.db, query, from, to)
+}
+
+// ListCreatedBetween retrieves the users created in the [from, to) interval, oldest first
+func (r *PostgresUserRepository) ListCreatedBetween(ctx context.Context, from, to time.Time, limit, offset int) ([]*models.User, error) {
+	query := `
+		SELECT id, username, password, role, created_at, updated_at
+		FROM users
+		WHERE created_at >= $1 AND created_at < $2
+		ORDER BY created_at, id
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error listing users by creation date: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Password,
+			&user.Role,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning user row: %w", err)
+		}
+		users = append(users, &user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user rows: %w", err)
+	}
+
+	return users, nil
+}
+
 // Create creates a new user
 func (r *PostgresUserRepository) Create(ctx context.Context, user *models.User) (int, error) {
 	query := `
@@ -135,6 +236,35 @@ func (r *PostgresUserRepository) Create(ctx context.Context, user *models.User)
 	return id, nil
 }
 
+// CreateIfNoUsers creates the user only when the users table is empty and
+// reports whether it was created. The check and the insert are a single
+// statement, so concurrent calls create at most one user.
+func (r *PostgresUserRepository) CreateIfNoUsers(ctx context.Context, user *models.User) (bool, error) {
+	query := `
+		INSERT INTO users (username, password, role, created_at, updated_at)
+		SELECT $1, $2, $3, $4, $5
+		WHERE NOT EXISTS (SELECT 1 FROM users)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		user.Username,
+		user.Password,
+		user.Role,
+		user.CreatedAt,
+		user.UpdatedAt,
+	).Scan(&user.ID)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error creating bootstrap user: %w", err)
+	}
+
+	return true, nil
+}
+
 // Update updates an existing user
 func (r *PostgresUserRepository) Update(ctx context.Context, user *models.User) error {
 	query := `
@@ -167,6 +297,51 @@ func (r *PostgresUserRepository) Update(ctx context.Context, user *models.User)
 	return nil
 }
 
+// anonymizedPassword replaces the password of anonymized users. It is not a
+// bcrypt hash, so no password ever matches it.
+const anonymizedPassword = "!"
+
+// Anonymize scrubs the personal data of a user while keeping the account, so
+// the places, songs and ratings they created stay attributed to it: the
+// username becomes models.AnonymizedUsername, the password can no longer
+// match and the role is reduced to read. It returns nil if the user does not exist.
+func (r *PostgresUserRepository) Anonymize(ctx context.Context, id int) (*models.User, error) {
+	query := `
+		UPDATE users
+		SET username = $1, password = $2, role = $3, updated_at = $4
+		WHERE id = $5
+		RETURNING id, username, password, role, created_at, updated_at
+	`
+
+	var user models.User
+	err := r.db.QueryRowContext(ctx, query,
+		models.AnonymizedUsername(id),
+		anonymizedPassword,
+		string(models.RoleRead),
+		time.Now().UTC(),
+		id,
+	).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Password,
+		&user.Role,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("username %s: %w", models.AnonymizedUsername(id), ErrAlreadyExists)
+		}
+		return nil, fmt.Errorf("error anonymizing user: %w", err)
+	}
+
+	return &user, nil
+}
+
 // Delete deletes a user by ID
 func (r *PostgresUserRepository) Delete(ctx context.Context, id int) error {
 	query := `
@@ -0,0 +1,350 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/site-geav-api/internal/models"
+)
+
+func TestListCreatedBetween(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresUserRepository(db)
+	ctx := context.Background()
+
+	// The seed users were created now; move them out of the tested range
+	mustExec(t, db, `UPDATE users SET created_at = '2020-01-01T00:00:00Z'`)
+	for i, created := range []string{"2026-03-01T00:00:00Z", "2026-03-10T15:00:00Z", "2026-03-20T00:00:00Z", "2026-04-01T00:00:00Z"} {
+		mustExec(t, db, `INSERT INTO users (username, password, role, created_at) VALUES ($1, 'hash', 'read', $2)`, []string{"ana", "bia", "caio", "duda"}[i], created)
+	}
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		limit, offset int
+		want          []string
+	}{
+		{"whole range, oldest first", 10, 0, []string{"ana", "bia", "caio"}},
+		{"first page", 2, 0, []string{"ana", "bia"}},
+		{"second page", 2, 2, []string{"caio"}},
+		{"past the end", 2, 4, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := repo.ListCreatedBetween(ctx, from, to, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("ListCreatedBetween: %v", err)
+			}
+			if len(users) != len(tt.want) {
+				t.Fatalf("got %d users, want %v", len(users), tt.want)
+			}
+			for i, user := range users {
+				if user.Username != tt.want[i] {
+					t.Errorf("position %d: got %q, want %q", i, user.Username, tt.want[i])
+				}
+			}
+		})
+	}
+
+	count, err := repo.CountCreatedBetween(ctx, from, to)
+	if err != nil {
+		t.Fatalf("CountCreatedBetween: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("count = %d, want 3", count)
+	}
+}
+
+func TestCreateIfNoUsers(t *testing.T) {
+	tests := []struct {
+		name        string
+		emptyTable  bool
+		wantCreated bool
+		wantCount   int
+	}{
+		// The seed data has two users
+		{name: "no users: the admin is created", emptyTable: true, wantCreated: true, wantCount: 1},
+		{name: "users exist: nothing is created", emptyTable: false, wantCreated: false, wantCount: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresUserRepository(db)
+			ctx := context.Background()
+			if tt.emptyTable {
+				mustExec(t, db, `TRUNCATE users CASCADE`)
+			}
+
+			admin := models.NewUser("bootstrap", "hashed-password", models.RoleWrite)
+			created, err := repo.CreateIfNoUsers(ctx, admin)
+			if err != nil {
+				t.Fatalf("CreateIfNoUsers: %v", err)
+			}
+			if created != tt.wantCreated {
+				t.Fatalf("created = %v, want %v", created, tt.wantCreated)
+			}
+
+			// A second run, like the next cold start, never creates another user
+			again, err := repo.CreateIfNoUsers(ctx, models.NewUser("bootstrap2", "hashed-password", models.RoleWrite))
+			if err != nil || again {
+				t.Fatalf("second CreateIfNoUsers = %v, %v; want false, nil", again, err)
+			}
+
+			count, err := repo.Count(ctx)
+			if err != nil {
+				t.Fatalf("Count: %v", err)
+			}
+			if count != tt.wantCount {
+				t.Errorf("count = %d, want %d", count, tt.wantCount)
+			}
+
+			if tt.wantCreated {
+				stored, err := repo.GetByID(ctx, admin.ID)
+				if err != nil || stored == nil {
+					t.Fatalf("GetByID: %v, %v", stored, err)
+				}
+				if stored.Username != "bootstrap" || stored.Role != string(models.RoleWrite) {
+					t.Errorf("created user %+v, want a write user named bootstrap", stored)
+				}
+			}
+		})
+	}
+}
+
+func TestListByUserIsScopedToTheUser(t *testing.T) {
+	db := newTestDB(t)
+	lugarRepo := NewPostgresLugarRepository(db)
+	cancaoRepo := NewPostgresCancaoRepository(db)
+	ctx := context.Background()
+
+	// Content of the admin (user 1) and of user 2; deleted content is left out
+	insertTestLugar(t, db, "Do admin")
+	insertTestCancao(t, db, "Do admin")
+	mustExec(t, db, `INSERT INTO lugares (nome_local, user_id) VALUES ('Do usuario', 2), ('Outro do usuario', 2)`)
+	mustExec(t, db, `INSERT INTO lugares (nome_local, user_id, deleted_at) VALUES ('Removido', 2, now())`)
+	mustExec(t, db, `INSERT INTO cancoes (nome, user_id) VALUES ('Do usuario', 2)`)
+	other := insertTestUser(t, db, "sem conteudo")
+
+	tests := []struct {
+		name        string
+		userID      int
+		wantLugares int
+		wantCancoes int
+	}{
+		{"admin", 1, 1, 1},
+		{"user", 2, 2, 1},
+		{"user without content", other, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lugares, err := lugarRepo.ListByUser(ctx, tt.userID, Pagination{Limit: 10})
+			if err != nil {
+				t.Fatalf("lugares ListByUser: %v", err)
+			}
+			cancoes, err := cancaoRepo.ListByUser(ctx, tt.userID, Pagination{Limit: 10})
+			if err != nil {
+				t.Fatalf("cancoes ListByUser: %v", err)
+			}
+
+			if len(lugares) != tt.wantLugares || len(cancoes) != tt.wantCancoes {
+				t.Fatalf("got %d lugares and %d cancoes, want %d and %d", len(lugares), len(cancoes), tt.wantLugares, tt.wantCancoes)
+			}
+			for _, lugar := range lugares {
+				if lugar.UserID != tt.userID {
+					t.Errorf("lugar %d belongs to user %d", lugar.ID, lugar.UserID)
+				}
+			}
+			for _, cancao := range cancoes {
+				if cancao.UserID != tt.userID {
+					t.Errorf("cancao %d belongs to user %d", cancao.ID, cancao.UserID)
+				}
+			}
+		})
+	}
+}
+
+func TestGetWithCounts(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresUserRepository(db)
+
+	// User 1 owns two lugares (one deleted) and two cancoes (one deleted);
+	// user 3 rated both lugares and owns nothing
+	rater := insertTestUser(t, db, "rater")
+	lugar := insertTestLugar(t, db, "Sítio")
+	deletedLugar := insertTestLugar(t, db, "Apagado")
+	insertTestCancao(t, db, "Canção")
+	deletedCancao := insertTestCancao(t, db, "Apagada")
+	mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating) VALUES ($1, $3, 5), ($2, $3, 4), ($1, 1, 3)`, lugar, deletedLugar, rater)
+	mustExec(t, db, `UPDATE lugares SET deleted_at = NOW() WHERE id = $1`, deletedLugar)
+	mustExec(t, db, `UPDATE cancoes SET deleted_at = NOW() WHERE id = $1`, deletedCancao)
+
+	tests := []struct {
+		name                                  string
+		id                                    int
+		wantNil                               bool
+		wantLugares, wantCancoes, wantRatings int
+	}{
+		{name: "owner of content", id: 1, wantLugares: 1, wantCancoes: 1, wantRatings: 1},
+		{name: "rater", id: rater, wantRatings: 1},
+		{name: "user without content", id: 2},
+		{name: "missing user", id: 9999, wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := repo.GetWithCounts(context.Background(), tt.id)
+			if err != nil {
+				t.Fatalf("GetWithCounts: %v", err)
+			}
+			if (user == nil) != tt.wantNil {
+				t.Fatalf("user = %+v, want nil %v", user, tt.wantNil)
+			}
+			if user == nil {
+				return
+			}
+			if user.ID != tt.id {
+				t.Errorf("id = %d, want %d", user.ID, tt.id)
+			}
+			if user.LugarCount != tt.wantLugares || user.CancaoCount != tt.wantCancoes || user.RatingCount != tt.wantRatings {
+				t.Errorf("counts = %d lugares, %d cancoes, %d ratings; want %d, %d, %d",
+					user.LugarCount, user.CancaoCount, user.RatingCount, tt.wantLugares, tt.wantCancoes, tt.wantRatings)
+			}
+		})
+	}
+}
+
+func TestAnonymize(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, db *sql.DB) int
+		wantNil bool
+		wantErr error
+	}{
+		{
+			name: "user with content",
+			setup: func(t *testing.T, db *sql.DB) int {
+				id := insertTestUser(t, db, "maria")
+				lugar := insertTestLugar(t, db, "Sítio")
+				mustExec(t, db, `UPDATE lugares SET user_id = $1 WHERE id = $2`, id, lugar)
+				cancao := insertTestCancao(t, db, "Canção")
+				mustExec(t, db, `UPDATE cancoes SET user_id = $1 WHERE id = $2`, id, cancao)
+				mustExec(t, db, `INSERT INTO lugares_ratings (lugar_id, user_id, rating) VALUES ($1, $2, 5)`, lugar, id)
+				return id
+			},
+		},
+		{
+			name:    "missing user",
+			setup:   func(t *testing.T, db *sql.DB) int { return 9999 },
+			wantNil: true,
+		},
+		{
+			name: "tombstone already taken",
+			setup: func(t *testing.T, db *sql.DB) int {
+				id := insertTestUser(t, db, "joao")
+				insertTestUser(t, db, models.AnonymizedUsername(id))
+				return id
+			},
+			wantErr: ErrAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewPostgresUserRepository(db)
+			ctx := context.Background()
+			id := tt.setup(t, db)
+
+			before, err := repo.GetWithCounts(ctx, id)
+			if err != nil {
+				t.Fatalf("GetWithCounts: %v", err)
+			}
+
+			user, err := repo.Anonymize(ctx, id)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Anonymize: %v", err)
+			}
+			if (user == nil) != tt.wantNil {
+				t.Fatalf("user = %+v, want nil %v", user, tt.wantNil)
+			}
+			if user == nil {
+				return
+			}
+
+			// The personal data is scrubbed
+			if user.Username != models.AnonymizedUsername(id) {
+				t.Errorf("username = %q, want %q", user.Username, models.AnonymizedUsername(id))
+			}
+			if user.Password != anonymizedPassword {
+				t.Errorf("password = %q, want it invalidated", user.Password)
+			}
+			if user.Role != string(models.RoleRead) {
+				t.Errorf("role = %q, want %q", user.Role, models.RoleRead)
+			}
+
+			// The content stays attributed to the account
+			after, err := repo.GetWithCounts(ctx, id)
+			if err != nil {
+				t.Fatalf("GetWithCounts: %v", err)
+			}
+			if after.LugarCount != before.LugarCount || after.CancaoCount != before.CancaoCount || after.RatingCount != before.RatingCount {
+				t.Errorf("counts after = %+v, want %+v", after, before)
+			}
+			if before.LugarCount != 1 || before.CancaoCount != 1 || before.RatingCount != 1 {
+				t.Errorf("counts before = %+v, want one of each", before)
+			}
+		})
+	}
+}
+
+func TestGetByUsername(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPostgresUserRepository(db)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		username string
+		wantID   int
+		wantRole string
+	}{
+		{"admin", "admin", 1, "write"},
+		{"read user", "user", 2, "read"},
+		{"unknown username", "ninguem", 0, ""},
+		{"usernames are case sensitive", "ADMIN", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := repo.GetByUsername(ctx, tt.username)
+			if err != nil {
+				t.Fatalf("GetByUsername: %v", err)
+			}
+			if tt.wantID == 0 {
+				if user != nil {
+					t.Errorf("user = %+v, want nil", user)
+				}
+				return
+			}
+			if user == nil || user.ID != tt.wantID || user.Username != tt.username || user.Role != tt.wantRole {
+				t.Errorf("user = %+v, want user %d %s with role %s", user, tt.wantID, tt.username, tt.wantRole)
+			}
+		})
+	}
+}
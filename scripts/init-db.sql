@@ -4,6 +4,12 @@
 -- Enable UUID extension for generating unique IDs
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
+-- Enable trigram extension for similarity searches
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Enable unaccent for accent-insensitive address searches (optional, the API checks for it)
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
 -- Sequences for auto-incrementing IDs
 CREATE SEQUENCE lugares_id_seq START 1;
 CREATE SEQUENCE cancoes_id_seq START 1;
@@ -61,11 +67,15 @@ CREATE TABLE lugares (
     link_site TEXT,
     endereco_completo TEXT,
     local_publico BOOLEAN NOT NULL DEFAULT false,
-    valor_fixo DECIMAL(10, 2) NOT NULL DEFAULT 0,
-    valor_individual DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    valor_fixo DECIMAL(10, 2),
+    valor_individual DECIMAL(10, 2),
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    published BOOLEAN NOT NULL DEFAULT false,
     user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
 );
 
 -- Create indexes for common search fields
@@ -73,6 +83,8 @@ CREATE INDEX idx_lugares_nome_local ON lugares(nome_local);
 CREATE INDEX idx_lugares_local_publico ON lugares(local_publico);
 CREATE INDEX idx_lugares_valor_fixo ON lugares(valor_fixo);
 CREATE INDEX idx_lugares_valor_individual ON lugares(valor_individual);
+CREATE INDEX idx_lugares_coordinates ON lugares(latitude, longitude);
+CREATE INDEX idx_lugares_nome_local_trgm ON lugares USING gin(nome_local gin_trgm_ops);
 
 -- Lugares images table (one-to-many relationship)
 CREATE TABLE lugares_images (
@@ -80,7 +92,8 @@ CREATE TABLE lugares_images (
     lugar_id INTEGER NOT NULL REFERENCES lugares(id) ON DELETE CASCADE,
     image_url TEXT NOT NULL,
     display_order INTEGER NOT NULL DEFAULT 0,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (lugar_id, display_order)
 );
 
 -- Create index on lugar_id for faster lookups
@@ -115,6 +128,7 @@ CREATE TABLE lugares_ratings (
     user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
     date TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1,
     UNIQUE (lugar_id, user_id)
 );
 
@@ -129,13 +143,16 @@ CREATE TABLE cancoes (
     nome VARCHAR(100) NOT NULL,
     link_youtube TEXT,
     letra TEXT,
+    play_count INTEGER NOT NULL DEFAULT 0,
     user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
 );
 
 -- Create index for common search field
 CREATE INDEX idx_cancoes_nome ON cancoes(nome);
+CREATE INDEX idx_cancoes_play_count ON cancoes(play_count DESC);
 CREATE INDEX idx_cancoes_letra ON cancoes USING gin(to_tsvector('portuguese', letra));
 
 -- Junction table for cancoes and tags (many-to-many)
@@ -245,6 +262,7 @@ INSERT INTO users (username, password, role) VALUES
 -- Create API logs table
 CREATE TABLE api_logs (
     id SERIAL PRIMARY KEY,
+    entry_id TEXT,
     timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
     level TEXT NOT NULL,
     message TEXT NOT NULL,
@@ -265,6 +283,30 @@ CREATE INDEX idx_api_logs_level ON api_logs(level);
 CREATE INDEX idx_api_logs_action ON api_logs(action);
 CREATE INDEX idx_api_logs_resource ON api_logs(resource);
 CREATE INDEX idx_api_logs_user_id ON api_logs(user_id);
+CREATE INDEX idx_api_logs_entry_id ON api_logs(entry_id);
+
+-- Create audit log table, written by the repository in the same transaction as
+-- each change (user_id has no foreign key so the history outlives the user)
+CREATE TABLE audit_log (
+    id SERIAL PRIMARY KEY,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resource TEXT NOT NULL,
+    resource_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    user_id INTEGER,
+    request_id TEXT,
+    changes JSONB
+);
+
+CREATE INDEX idx_audit_log_resource ON audit_log(resource, resource_id, timestamp);
+
+-- Schema version, checked by the API on startup (see repository.SchemaVersion)
+CREATE TABLE schema_migrations (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO schema_migrations (version) VALUES (1), (2), (3), (4), (5), (6), (7), (8), (9), (10), (11);
 
 -- Comment on tables and columns for documentation
 COMMENT ON TABLE users IS 'Users who can access the system';
@@ -280,4 +322,6 @@ COMMENT ON TABLE lugares_ramos IS 'Junction table linking places to scout branch
 COMMENT ON TABLE cancoes_tags IS 'Junction table linking songs to tags';
 COMMENT ON TABLE cancoes_ramos IS 'Junction table linking songs to scout branches';
 COMMENT ON MATERIALIZED VIEW lugares_with_ratings IS 'Materialized view of places with their average ratings for faster retrieval';
-COMMENT ON TABLE api_logs IS 'Logs of API actions for auditing and monitoring';
\ No newline at end of file
+COMMENT ON TABLE api_logs IS 'Logs of API actions for auditing and monitoring';
+COMMENT ON TABLE audit_log IS 'Change history of places, kept apart from the logs';
+COMMENT ON TABLE schema_migrations IS 'Applied schema versions; the highest one is the current version';
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- Record the applied schema versions, read at startup by CheckSchemaVersion
+-- Version 1 is the schema of scripts/init-db.sql before this table existed
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO schema_migrations (version) VALUES (1);
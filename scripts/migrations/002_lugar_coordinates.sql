@@ -0,0 +1,10 @@
+-- Coordinates of lugares, for the bounding box and near-city searches
+-- Existing rows have no coordinates (NULL) and are left out of those searches
+
+ALTER TABLE lugares
+    ADD COLUMN latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    ADD COLUMN longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180);
+
+CREATE INDEX idx_lugares_coordinates ON lugares(latitude, longitude);
+
+INSERT INTO schema_migrations (version) VALUES (2);
@@ -0,0 +1,9 @@
+-- Number of times each cancao was played, for sorting by popularity
+-- Existing rows start at 0
+
+ALTER TABLE cancoes
+    ADD COLUMN play_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_cancoes_play_count ON cancoes(play_count DESC);
+
+INSERT INTO schema_migrations (version) VALUES (3);
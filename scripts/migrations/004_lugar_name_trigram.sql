@@ -0,0 +1,7 @@
+-- Trigram index on the lugar name, for the duplicate and similar lugar searches
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_lugares_nome_local_trgm ON lugares USING gin(nome_local gin_trgm_ops);
+
+INSERT INTO schema_migrations (version) VALUES (4);
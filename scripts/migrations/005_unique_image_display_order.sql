@@ -0,0 +1,15 @@
+-- Make display_order unique within a lugar's images
+-- Existing images are renumbered 1..n per lugar, keeping their current order
+
+UPDATE lugares_images li
+SET display_order = ranked.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY lugar_id ORDER BY display_order, id) AS position
+    FROM lugares_images
+) ranked
+WHERE li.id = ranked.id;
+
+ALTER TABLE lugares_images
+    ADD CONSTRAINT lugares_images_lugar_id_display_order_key UNIQUE (lugar_id, display_order);
+
+INSERT INTO schema_migrations (version) VALUES (5);
@@ -0,0 +1,8 @@
+-- Record the ID shared by every destination of a single log call
+-- Existing rows keep a NULL entry_id
+
+ALTER TABLE api_logs ADD COLUMN entry_id TEXT;
+
+CREATE INDEX idx_api_logs_entry_id ON api_logs(entry_id);
+
+INSERT INTO schema_migrations (version) VALUES (6);
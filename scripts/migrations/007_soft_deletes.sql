@@ -0,0 +1,7 @@
+-- Soft deletes for lugares and cancoes
+-- Deleted rows keep their data and related entities; deleted_at marks them
+
+ALTER TABLE lugares ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE cancoes ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+INSERT INTO schema_migrations (version) VALUES (7);
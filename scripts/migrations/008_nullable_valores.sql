@@ -0,0 +1,10 @@
+-- Nullable valor_fixo and valor_individual on lugares
+-- NULL means the value was not specified; 0 means the place is free.
+-- Existing rows keep their values, so a stored 0 may still mean "not specified".
+
+ALTER TABLE lugares ALTER COLUMN valor_fixo DROP NOT NULL;
+ALTER TABLE lugares ALTER COLUMN valor_fixo DROP DEFAULT;
+ALTER TABLE lugares ALTER COLUMN valor_individual DROP NOT NULL;
+ALTER TABLE lugares ALTER COLUMN valor_individual DROP DEFAULT;
+
+INSERT INTO schema_migrations (version) VALUES (8);
@@ -0,0 +1,9 @@
+-- Publish workflow for lugares
+-- A place is published with POST /lugares/{id}/publish once it has an image and an address
+
+ALTER TABLE lugares ADD COLUMN published BOOLEAN NOT NULL DEFAULT false;
+
+-- The places created before the workflow stay listed
+UPDATE lugares SET published = true;
+
+INSERT INTO schema_migrations (version) VALUES (9);
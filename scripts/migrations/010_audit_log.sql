@@ -0,0 +1,18 @@
+-- Change history of lugares, written by the repository in the same
+-- transaction as each change, so it cannot drift from the data nor be lost
+-- with the API logs
+
+CREATE TABLE audit_log (
+    id SERIAL PRIMARY KEY,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resource TEXT NOT NULL,
+    resource_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    user_id INTEGER,
+    request_id TEXT,
+    changes JSONB
+);
+
+CREATE INDEX idx_audit_log_resource ON audit_log(resource, resource_id, timestamp);
+
+INSERT INTO schema_migrations (version) VALUES (10);
@@ -0,0 +1,7 @@
+-- Optimistic concurrency for ratings
+-- The version is incremented on every update; PUT /lugares/{id}/ratings/{ratingId}
+-- with expected_version only applies when it still matches
+
+ALTER TABLE lugares_ratings ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+INSERT INTO schema_migrations (version) VALUES (11);